@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var referencesExportCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Export all voice references to a tar.gz archive",
+	Long: `Export downloads every reference visible to the API key, with its transcript
+and metadata, as a tar.gz archive that "references import" can load into
+another server. Use - to write the archive to stdout.
+
+References whose audio the server has no copy of, such as those added before
+it kept one, are listed as missing and left out.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReferencesExport,
+}
+
+var referencesImportCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import voice references from an export archive",
+	Long: `Import adds the references of an archive written by "references export".
+Each reference is checked like "references add"; references that are rejected,
+for example because they are locked, are reported and skipped. Use - to read
+the archive from stdin.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReferencesImport,
+}
+
+func init() {
+	referencesCmd.AddCommand(referencesExportCmd)
+	referencesCmd.AddCommand(referencesImportCmd)
+
+	referencesExportCmd.Flags().Duration("timeout", 10*time.Minute, "Timeout for the export")
+	referencesImportCmd.Flags().Duration("timeout", 10*time.Minute, "Timeout for the import")
+	referencesImportCmd.Flags().Bool("force", false, "Overwrite locked references (requires an admin key)")
+}
+
+// importResult is the server's report of an import.
+type importResult struct {
+	Success  bool     `json:"success"`
+	Message  string   `json:"message"`
+	Imported []string `json:"imported"`
+	Skipped  []struct {
+		ReferenceID string `json:"reference_id"`
+		Reason      string `json:"reason"`
+	} `json:"skipped"`
+}
+
+func runReferencesExport(cmd *cobra.Command, args []string) error {
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	resp, err := doArchiveRequest(http.MethodGet, "/v1/references/export", nil, timeout)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var dst io.Writer = cmd.OutOrStdout()
+	if args[0] != "-" {
+		f, err := os.Create(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to create archive: %w", err)
+		}
+		defer f.Close()
+		dst = f
+	}
+
+	n, err := io.Copy(dst, resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to download archive: %w", err)
+	}
+	if args[0] != "-" {
+		fmt.Fprintf(cmd.ErrOrStderr(), "✓ Exported references to %s (%d bytes)\n", args[0], n)
+	}
+	return nil
+}
+
+func runReferencesImport(cmd *cobra.Command, args []string) error {
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	var src io.Reader = cmd.InOrStdin()
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to open archive: %w", err)
+		}
+		defer f.Close()
+		src = f
+	}
+
+	path := "/v1/references/import"
+	if force, _ := cmd.Flags().GetBool("force"); force {
+		path += "?force=true"
+	}
+	resp, err := doArchiveRequest(http.MethodPost, path, src, timeout)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if output == "json" {
+		fmt.Fprintln(cmd.OutOrStdout(), string(body))
+		return nil
+	}
+
+	var result importResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("unexpected import response: %w", err)
+	}
+	for _, id := range result.Imported {
+		fmt.Fprintf(cmd.OutOrStdout(), "✓ %s\n", id)
+	}
+	for _, skip := range result.Skipped {
+		fmt.Fprintf(cmd.OutOrStdout(), "✗ %s: %s\n", skip.ReferenceID, skip.Reason)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Imported %d references, skipped %d\n", len(result.Imported), len(result.Skipped))
+	if len(result.Skipped) > 0 {
+		return errors.New(result.Message)
+	}
+	return nil
+}
+
+// doArchiveRequest sends a request whose body or response is an archive,
+// which may be too large to buffer, and returns the successful response.
+func doArchiveRequest(method, path string, body io.Reader, timeout time.Duration) (*http.Response, error) {
+	req, err := http.NewRequest(method, strings.TrimSuffix(serverURL, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/gzip")
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("server error (status %d): %s", resp.StatusCode, string(respBody))
+	}
+	return resp, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferencesExportImport(t *testing.T) {
+	archive := []byte("\x1f\x8barchive")
+	var imported []byte
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/v1/references/export":
+			w.Header().Set("Content-Type", "application/gzip")
+			w.Write(archive)
+		case "/v1/references/import":
+			assert.Equal(t, "application/gzip", r.Header.Get("Content-Type"))
+			query = r.URL.RawQuery
+			imported, _ = io.ReadAll(r.Body)
+			w.Write([]byte(`{"success":true,"message":"Some references were not imported","imported":["narrator"],` +
+				`"skipped":[{"reference_id":"calm","reason":"Reference is locked"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	prevURL, prevKey := serverURL, apiKey
+	serverURL, apiKey = srv.URL, "secret"
+	defer func() { serverURL, apiKey = prevURL, prevKey }()
+
+	path := filepath.Join(t.TempDir(), "references.tar.gz")
+	var stderr bytes.Buffer
+	referencesExportCmd.SetErr(&stderr)
+	require.NoError(t, runReferencesExport(referencesExportCmd, []string{path}))
+	saved, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, archive, saved)
+	assert.Contains(t, stderr.String(), "Exported references")
+
+	var stdout bytes.Buffer
+	referencesImportCmd.SetOut(&stdout)
+	require.NoError(t, referencesImportCmd.Flags().Set("force", "true"))
+	err = runReferencesImport(referencesImportCmd, []string{path})
+	assert.EqualError(t, err, "Some references were not imported")
+	assert.Equal(t, archive, imported)
+	assert.Equal(t, "force=true", query)
+	assert.Contains(t, stdout.String(), "✓ narrator")
+	assert.Contains(t, stdout.String(), "✗ calm: Reference is locked")
+	assert.Contains(t, stdout.String(), "Imported 1 references, skipped 1")
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the server's TTS response cache",
+}
+
+var cacheWarmCmd = &cobra.Command{
+	Use:   "warm",
+	Short: "Pre-generate and cache a list of prompts",
+	Long: `Warm sends one TTS request per prompt so that the server caches the audio
+before clients ask for it. The input has one prompt per line; blank lines and
+lines starting with # are skipped.
+
+Only identical requests share a cache entry, so pass the same --reference-id,
+--format and --seed that clients will send. The server must run with
+cache.enabled.`,
+	Args: cobra.NoArgs,
+	RunE: runCacheWarm,
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheWarmCmd)
+
+	cacheWarmCmd.Flags().StringP("input", "i", "", "File with one prompt per line (- for stdin)")
+	cacheWarmCmd.Flags().String("reference-id", "", "Reference voice to synthesize with")
+	cacheWarmCmd.Flags().String("format", "wav", "Audio format: wav, mp3, or pcm")
+	cacheWarmCmd.Flags().Int("seed", 0, "Seed to synthesize with (unset sends none)")
+	cacheWarmCmd.Flags().Int("concurrency", 1, "Prompts to synthesize at once")
+	cacheWarmCmd.Flags().Duration("timeout", 120*time.Second, "Timeout for each prompt")
+	_ = cacheWarmCmd.MarkFlagRequired("input")
+}
+
+// warmResult reports how one prompt was warmed.
+type warmResult struct {
+	Line int    `json:"line"`
+	Text string `json:"text"`
+	// Cache is the server's X-Cache header: MISS when the audio was
+	// generated, HIT when it was already cached.
+	Cache   string  `json:"cache,omitempty"`
+	Latency float64 `json:"latency_ms"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// warmPrompt is a prompt and the input line it came from.
+type warmPrompt struct {
+	Line int
+	Text string
+}
+
+func runCacheWarm(cmd *cobra.Command, args []string) error {
+	input, _ := cmd.Flags().GetString("input")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	if concurrency < 1 {
+		return errors.New("concurrency must be at least 1")
+	}
+
+	var r io.Reader = cmd.InOrStdin()
+	if input != "-" {
+		f, err := os.Open(input)
+		if err != nil {
+			return fmt.Errorf("failed to open prompts: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+	prompts, err := readWarmPrompts(r)
+	if err != nil {
+		return err
+	}
+
+	format, _ := cmd.Flags().GetString("format")
+	body := map[string]interface{}{"format": format}
+	if id, _ := cmd.Flags().GetString("reference-id"); id != "" {
+		body["reference_id"] = id
+	}
+	if cmd.Flags().Changed("seed") {
+		seed, _ := cmd.Flags().GetInt("seed")
+		body["seed"] = seed
+	}
+
+	client := &http.Client{Timeout: timeout}
+	start := time.Now()
+	results := warmPrompts(prompts, concurrency, func(p warmPrompt) warmResult {
+		return warmRequest(client, body, p)
+	})
+
+	if output == "json" {
+		for _, result := range results {
+			line, _ := json.Marshal(result)
+			fmt.Println(string(line))
+		}
+		return nil
+	}
+	return printWarmSummary(results, time.Since(start))
+}
+
+// readWarmPrompts reads one prompt per line, skipping blank lines and comments.
+func readWarmPrompts(r io.Reader) ([]warmPrompt, error) {
+	var prompts []warmPrompt
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		prompts = append(prompts, warmPrompt{Line: line, Text: text})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read prompts: %w", err)
+	}
+	if len(prompts) == 0 {
+		return nil, errors.New("no prompts found in input")
+	}
+	return prompts, nil
+}
+
+// warmPrompts runs warm for every prompt with up to concurrency at once and
+// returns the results in input order.
+func warmPrompts(prompts []warmPrompt, concurrency int, warm func(warmPrompt) warmResult) []warmResult {
+	results := make([]warmResult, len(prompts))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, p := range prompts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, p warmPrompt) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = warm(p)
+		}(i, p)
+	}
+	wg.Wait()
+	return results
+}
+
+func warmRequest(client *http.Client, base map[string]interface{}, p warmPrompt) warmResult {
+	result := warmResult{Line: p.Line, Text: p.Text}
+
+	body := map[string]interface{}{"text": p.Text}
+	for k, v := range base {
+		body[k] = v
+	}
+	data, _ := json.Marshal(body)
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, strings.TrimSuffix(serverURL, "/")+"/v1/tts", bytes.NewReader(data))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	result.Latency = float64(time.Since(start).Microseconds()) / 1000
+	switch {
+	case err != nil:
+		result.Error = err.Error()
+	case resp.StatusCode >= 400:
+		result.Error = fmt.Sprintf("server error (status %d): %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	default:
+		result.Cache = resp.Header.Get("X-Cache")
+	}
+	return result
+}
+
+func printWarmSummary(results []warmResult, elapsed time.Duration) error {
+	var generated, cached, failed, uncached int
+	for _, r := range results {
+		switch {
+		case r.Error != "":
+			failed++
+			fmt.Printf("✗ line %d: %s\n", r.Line, r.Error)
+		case r.Cache == "HIT":
+			cached++
+		case r.Cache == "MISS":
+			generated++
+		default:
+			uncached++
+		}
+	}
+
+	fmt.Printf("Warmed %d prompts in %s: %d generated, %d already cached, %d failed\n",
+		len(results), elapsed.Round(time.Millisecond), generated, cached, failed)
+	if uncached > 0 {
+		return errors.New("server did not report X-Cache; is cache.enabled set?")
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d prompts failed", failed)
+	}
+	return nil
+}
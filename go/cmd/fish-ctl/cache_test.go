@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadWarmPrompts(t *testing.T) {
+	prompts, err := readWarmPrompts(strings.NewReader("# launch phrases\nWelcome back!\n\n  Your order has shipped.  \n"))
+	require.NoError(t, err)
+	assert.Equal(t, []warmPrompt{{Line: 2, Text: "Welcome back!"}, {Line: 4, Text: "Your order has shipped."}}, prompts)
+
+	_, err = readWarmPrompts(strings.NewReader("# nothing\n\n"))
+	assert.Error(t, err)
+}
+
+func TestWarmPrompts(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string]bool{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/tts", r.URL.Path)
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "voice", body["reference_id"])
+		assert.Equal(t, float64(7), body["seed"])
+
+		text := body["text"].(string)
+		if text == "bad" {
+			http.Error(w, `{"detail":"boom"}`, http.StatusBadGateway)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if seen[text] {
+			w.Header().Set("X-Cache", "HIT")
+		} else {
+			w.Header().Set("X-Cache", "MISS")
+		}
+		seen[text] = true
+		w.Write([]byte("audio"))
+	}))
+	defer srv.Close()
+
+	prev := serverURL
+	serverURL = srv.URL
+	defer func() { serverURL = prev }()
+
+	base := map[string]interface{}{"reference_id": "voice", "seed": 7}
+	prompts := []warmPrompt{{1, "hello"}, {2, "bad"}, {3, "bye"}}
+	warm := func(p warmPrompt) warmResult { return warmRequest(srv.Client(), base, p) }
+
+	results := warmPrompts(prompts, 2, warm)
+	require.Len(t, results, 3)
+	assert.Equal(t, "MISS", results[0].Cache)
+	assert.Contains(t, results[1].Error, "status 502")
+	assert.Equal(t, 3, results[2].Line)
+
+	results = warmPrompts(prompts[:1], 1, warm)
+	assert.Equal(t, "HIT", results[0].Cache)
+}
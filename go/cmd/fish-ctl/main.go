@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -26,7 +27,9 @@ var rootCmd = &cobra.Command{
 
 Commands:
   health      Check server health
-  references  Manage voice references`,
+  references  Manage voice references
+  replay      Re-issue recorded requests
+  cache       Warm the TTS response cache`,
 }
 
 var healthCmd = &cobra.Command{
@@ -49,15 +52,22 @@ var referencesListCmd = &cobra.Command{
 var referencesAddCmd = &cobra.Command{
 	Use:   "add [id] [audio-file] [text]",
 	Short: "Add a voice reference",
-	Args:  cobra.ExactArgs(3),
-	RunE:  runReferencesAdd,
+	Long: `Add a voice reference from an audio file and its transcript.
+
+With --auto-transcribe the transcript may be omitted: the audio is transcribed
+by the backend's ASR endpoint (WAV only), or by the Whisper-compatible endpoint
+given with --whisper-url, and the result is shown for confirmation before the
+reference is uploaded.`,
+	Args: cobra.RangeArgs(2, 3),
+	RunE: runReferencesAdd,
 }
 
 var referencesDeleteCmd = &cobra.Command{
-	Use:   "delete [id]",
-	Short: "Delete a voice reference",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runReferencesDelete,
+	Use:   "delete [id...]",
+	Short: "Delete voice references",
+	Long: `Delete removes the given references, and with --prefix every reference whose
+ID starts with the prefix, in one request. Locked references are skipped.`,
+	RunE: runReferencesDelete,
 }
 
 func init() {
@@ -73,6 +83,17 @@ func init() {
 	referencesCmd.AddCommand(referencesDeleteCmd)
 
 	healthCmd.Flags().Bool("detailed", false, "Show detailed health information")
+
+	referencesDeleteCmd.Flags().String("prefix", "", "Also delete every reference whose ID starts with this prefix")
+	referencesDeleteCmd.Flags().Bool("dry-run", false, "List the references that would be deleted without deleting them")
+
+	referencesAddCmd.Flags().Bool("auto-transcribe", false, "Transcribe the audio when no transcript is given")
+	referencesAddCmd.Flags().BoolP("yes", "y", false, "Accept the automatic transcript without prompting")
+	referencesAddCmd.Flags().String("backend", "http://127.0.0.1:8081", "Fish-Speech backend URL used for transcription")
+	referencesAddCmd.Flags().String("whisper-url", "", "OpenAI-compatible transcription endpoint to use instead of the backend")
+	referencesAddCmd.Flags().String("whisper-model", "whisper-1", "Model requested from --whisper-url")
+	referencesAddCmd.Flags().String("whisper-api-key", "", "API key for --whisper-url")
+	referencesAddCmd.Flags().Duration("asr-timeout", 120*time.Second, "Timeout for transcription")
 }
 
 func runHealth(cmd *cobra.Command, args []string) error {
@@ -144,13 +165,32 @@ func runReferencesList(cmd *cobra.Command, args []string) error {
 func runReferencesAdd(cmd *cobra.Command, args []string) error {
 	id := args[0]
 	audioFile := args[1]
-	text := args[2]
+	text := ""
+	if len(args) == 3 {
+		text = args[2]
+	}
+
+	autoTranscribe, _ := cmd.Flags().GetBool("auto-transcribe")
+	if text == "" && !autoTranscribe {
+		return errors.New("a transcript is required; pass it as the third argument or use --auto-transcribe")
+	}
 
 	audioData, err := os.ReadFile(audioFile)
 	if err != nil {
 		return fmt.Errorf("failed to read audio file: %w", err)
 	}
 
+	if text == "" {
+		if text, err = transcribeReference(cmd, audioFile, audioData); err != nil {
+			return err
+		}
+		if yes, _ := cmd.Flags().GetBool("yes"); yes {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Transcript: %s\n", text)
+		} else if text, err = confirmTranscript(cmd.InOrStdin(), cmd.ErrOrStderr(), text); err != nil {
+			return err
+		}
+	}
+
 	reqBody := map[string]interface{}{
 		"id":    id,
 		"audio": audioData,
@@ -184,6 +224,14 @@ func runReferencesAdd(cmd *cobra.Command, args []string) error {
 }
 
 func runReferencesDelete(cmd *cobra.Command, args []string) error {
+	prefix, _ := cmd.Flags().GetString("prefix")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	if len(args) == 0 && prefix == "" {
+		return errors.New("give reference IDs or --prefix")
+	}
+	if len(args) != 1 || prefix != "" || dryRun {
+		return runBulkDelete(args, prefix, dryRun)
+	}
 	id := args[0]
 
 	resp, err := makeRequest(http.MethodDelete, serverURL+"/v1/references/"+id, nil)
@@ -211,6 +259,42 @@ func runReferencesDelete(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runBulkDelete(ids []string, prefix string, dryRun bool) error {
+	body, err := json.Marshal(map[string]any{"ids": ids, "prefix": prefix, "dry_run": dryRun})
+	if err != nil {
+		return err
+	}
+	resp, err := makeRequest(http.MethodDelete, serverURL+"/v1/references", body)
+	if err != nil {
+		return err
+	}
+
+	if output == "json" {
+		fmt.Println(string(resp))
+		return nil
+	}
+
+	var result struct {
+		Message string   `json:"message"`
+		Deleted []string `json:"deleted"`
+		Skipped []struct {
+			ReferenceID string `json:"reference_id"`
+			Reason      string `json:"reason"`
+		} `json:"skipped"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return fmt.Errorf("unexpected delete response: %w", err)
+	}
+	for _, id := range result.Deleted {
+		fmt.Printf("✓ %s\n", id)
+	}
+	for _, skip := range result.Skipped {
+		fmt.Printf("✗ %s: %s\n", skip.ReferenceID, skip.Reason)
+	}
+	fmt.Println(result.Message)
+	return nil
+}
+
 func makeRequest(method, url string, body []byte) ([]byte, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay [requests.ndjson]",
+	Short: "Re-issue recorded requests against a server",
+	Long: `Replay reads recorded requests, one JSON object per line, and sends them to
+the server given by --server, preserving the recorded gaps between requests.
+
+Each line has the form:
+  {"timestamp": "2024-05-01T12:00:00.123Z", "method": "POST", "path": "/v1/tts", "body": {...}}
+
+method defaults to POST and body may be omitted. Use --speed to accelerate
+(2 replays twice as fast) or 0 to send requests back to back.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReplay,
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+
+	replayCmd.Flags().Float64("speed", 1, "Pacing multiplier relative to the recording (0 = no delay)")
+	replayCmd.Flags().Duration("timeout", 120*time.Second, "Timeout for each replayed request")
+}
+
+// replayRecord is one recorded request.
+type replayRecord struct {
+	Timestamp time.Time       `json:"timestamp"`
+	Method    string          `json:"method"`
+	Path      string          `json:"path"`
+	Body      json.RawMessage `json:"body,omitempty"`
+}
+
+// replayResult summarizes the outcome of a replayed request.
+type replayResult struct {
+	Line    int     `json:"line"`
+	Method  string  `json:"method"`
+	Path    string  `json:"path"`
+	Status  int     `json:"status"`
+	Latency float64 `json:"latency_ms"`
+	Error   string  `json:"error,omitempty"`
+}
+
+func runReplay(cmd *cobra.Command, args []string) error {
+	speed, _ := cmd.Flags().GetFloat64("speed")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	if speed < 0 {
+		return fmt.Errorf("speed must not be negative")
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open recording: %w", err)
+	}
+	defer f.Close()
+
+	records, err := readReplayRecords(f)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: timeout}
+	results := make([]replayResult, 0, len(records))
+	start := time.Now()
+	for i, rec := range records {
+		if delay := replayDelay(records[0].Timestamp, rec.Timestamp, speed); delay > 0 {
+			time.Sleep(time.Until(start.Add(delay)))
+		}
+
+		result := replayRequest(client, rec)
+		result.Line = i + 1
+		results = append(results, result)
+
+		if output == "json" {
+			line, _ := json.Marshal(result)
+			fmt.Println(string(line))
+		}
+	}
+
+	if output != "json" {
+		printReplaySummary(results, time.Since(start))
+	}
+	return nil
+}
+
+// readReplayRecords parses NDJSON records, skipping blank lines.
+func readReplayRecords(r io.Reader) ([]replayRecord, error) {
+	var records []replayRecord
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		var rec replayRecord
+		if err := json.Unmarshal(raw, &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if rec.Path == "" {
+			return nil, fmt.Errorf("line %d: path is required", line)
+		}
+		if rec.Method == "" {
+			rec.Method = http.MethodPost
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+	return records, nil
+}
+
+// replayDelay returns when a record should be sent, relative to the start of
+// the replay. Records without timestamps are sent immediately.
+func replayDelay(first, at time.Time, speed float64) time.Duration {
+	if speed == 0 || first.IsZero() || at.IsZero() || at.Before(first) {
+		return 0
+	}
+	return time.Duration(float64(at.Sub(first)) / speed)
+}
+
+func replayRequest(client *http.Client, rec replayRecord) replayResult {
+	result := replayResult{Method: rec.Method, Path: rec.Path}
+
+	var body io.Reader
+	if len(rec.Body) > 0 {
+		body = bytes.NewReader(rec.Body)
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), rec.Method, strings.TrimSuffix(serverURL, "/")+rec.Path, body)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		result.Latency = float64(time.Since(start).Microseconds()) / 1000
+		return result
+	}
+	_, err = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	result.Status = resp.StatusCode
+	result.Latency = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func printReplaySummary(results []replayResult, elapsed time.Duration) {
+	statuses := map[string]int{}
+	latencies := make([]float64, 0, len(results))
+	for _, r := range results {
+		key := fmt.Sprintf("%d", r.Status)
+		if r.Error != "" && r.Status == 0 {
+			key = "error"
+		}
+		statuses[key]++
+		latencies = append(latencies, r.Latency)
+	}
+
+	fmt.Printf("Replayed %d requests in %s\n", len(results), elapsed.Round(time.Millisecond))
+	if len(results) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(statuses))
+	for k := range statuses {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Printf("  %s: %d\n", k, statuses[k])
+	}
+
+	sort.Float64s(latencies)
+	fmt.Printf("Latency p50: %.0fms  p95: %.0fms  max: %.0fms\n",
+		percentile(latencies, 0.5), percentile(latencies, 0.95), latencies[len(latencies)-1])
+}
+
+// percentile returns the q-th quantile of sorted values.
+func percentile(sorted []float64, q float64) float64 {
+	return sorted[int(q*float64(len(sorted)-1))]
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadReplayRecords(t *testing.T) {
+	input := `{"timestamp":"2024-05-01T12:00:00Z","path":"/v1/tts","body":{"text":"a"}}
+
+{"timestamp":"2024-05-01T12:00:03Z","method":"GET","path":"/v1/health"}
+`
+	records, err := readReplayRecords(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "POST", records[0].Method)
+	assert.JSONEq(t, `{"text":"a"}`, string(records[0].Body))
+	assert.Equal(t, "GET", records[1].Method)
+
+	assert.Equal(t, 3*time.Second, replayDelay(records[0].Timestamp, records[1].Timestamp, 1))
+	assert.Equal(t, 1500*time.Millisecond, replayDelay(records[0].Timestamp, records[1].Timestamp, 2))
+	assert.Zero(t, replayDelay(records[0].Timestamp, records[1].Timestamp, 0))
+
+	_, err = readReplayRecords(strings.NewReader(`{"method":"GET"}`))
+	assert.Error(t, err)
+}
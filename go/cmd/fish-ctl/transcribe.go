@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/audio"
+	"github.com/fish-speech-go/fish-speech-go/internal/backend"
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+// errTranscriptRejected is returned when the user cancels at the confirmation
+// prompt.
+var errTranscriptRejected = errors.New("transcript not confirmed; reference not added")
+
+// transcribeReference transcribes reference audio for references add
+// --auto-transcribe. A Whisper-compatible endpoint is used when --whisper-url
+// is set, otherwise the ASR endpoint of the Fish-Speech backend.
+func transcribeReference(cmd *cobra.Command, filename string, data []byte) (string, error) {
+	timeout, _ := cmd.Flags().GetDuration("asr-timeout")
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var (
+		text string
+		err  error
+	)
+	if whisperURL, _ := cmd.Flags().GetString("whisper-url"); whisperURL != "" {
+		model, _ := cmd.Flags().GetString("whisper-model")
+		key, _ := cmd.Flags().GetString("whisper-api-key")
+		text, err = transcribeWhisper(ctx, whisperURL, model, key, filename, data)
+	} else {
+		backendURL, _ := cmd.Flags().GetString("backend")
+		text, err = transcribeBackend(ctx, backendURL, timeout, data)
+	}
+	if err != nil {
+		return "", fmt.Errorf("auto-transcribe failed: %w", err)
+	}
+	if text = strings.TrimSpace(text); text == "" {
+		return "", errors.New("auto-transcribe failed: empty transcript")
+	}
+	return text, nil
+}
+
+// transcribeBackend sends WAV audio to the backend's /v1/asr endpoint, which
+// takes mono float16 samples rather than an encoded file.
+func transcribeBackend(ctx context.Context, backendURL string, timeout time.Duration, data []byte) (string, error) {
+	pcm, err := audio.DecodeWAV(data)
+	if err != nil {
+		return "", errors.New("backend ASR needs WAV audio; convert the file or use --whisper-url")
+	}
+	mono := audio.ToMono(pcm)
+
+	client := backend.NewBackendClient(&config.BackendConfig{URL: backendURL, Timeout: timeout})
+	resp, err := client.ASR(ctx, &schema.ServeASRRequest{
+		Audios:     [][]byte{audio.EncodeFloat16(mono.Samples)},
+		SampleRate: mono.SampleRate,
+		Language:   "auto",
+	})
+	if err != nil {
+		return "", err
+	}
+
+	parts := make([]string, 0, len(resp.Transcriptions))
+	for _, t := range resp.Transcriptions {
+		parts = append(parts, strings.TrimSpace(t.Text))
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// transcribeWhisper posts the audio file to an OpenAI-compatible
+// /v1/audio/transcriptions endpoint.
+func transcribeWhisper(ctx context.Context, url, model, key, filename string, data []byte) (string, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", filepath.Base(filename))
+	if err != nil {
+		return "", err
+	}
+	fw.Write(data)
+	mw.WriteField("model", model)
+	mw.WriteField("response_format", "json")
+	if err := mw.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("whisper error (status %d): %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return "", fmt.Errorf("invalid whisper response: %w", err)
+	}
+	return result.Text, nil
+}
+
+// confirmTranscript shows the transcript and reads the user's answer from in:
+// an empty line accepts it, "n" cancels, and anything else replaces it.
+func confirmTranscript(in io.Reader, out io.Writer, transcript string) (string, error) {
+	fmt.Fprintf(out, "Transcript: %s\n", transcript)
+	fmt.Fprint(out, "Press Enter to use it, type a corrected transcript, or n to cancel: ")
+
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", errors.New("no confirmation received; use --yes to accept the transcript without prompting")
+	}
+
+	switch answer := strings.TrimSpace(line); strings.ToLower(answer) {
+	case "", "y", "yes":
+		return transcript, nil
+	case "n", "no":
+		return "", errTranscriptRejected
+	default:
+		return answer, nil
+	}
+}
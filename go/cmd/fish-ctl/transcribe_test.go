@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/audio"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func TestConfirmTranscript(t *testing.T) {
+	var out strings.Builder
+	text, err := confirmTranscript(strings.NewReader("\n"), &out, "hello world")
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", text)
+	assert.Contains(t, out.String(), "Transcript: hello world")
+
+	text, err = confirmTranscript(strings.NewReader("hello, world!\n"), io.Discard, "hello world")
+	require.NoError(t, err)
+	assert.Equal(t, "hello, world!", text)
+
+	_, err = confirmTranscript(strings.NewReader("n\n"), io.Discard, "hello world")
+	assert.ErrorIs(t, err, errTranscriptRejected)
+
+	_, err = confirmTranscript(strings.NewReader(""), io.Discard, "hello world")
+	assert.ErrorContains(t, err, "--yes")
+}
+
+func TestTranscribeWhisper(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+		file, header, err := r.FormFile("file")
+		require.NoError(t, err)
+		data, _ := io.ReadAll(file)
+		assert.Equal(t, "voice.mp3", header.Filename)
+		assert.Equal(t, "mp3 data", string(data))
+		assert.Equal(t, "whisper-1", r.FormValue("model"))
+		w.Write([]byte(`{"text":" Hello there. "}`))
+	}))
+	defer srv.Close()
+
+	text, err := transcribeWhisper(context.Background(), srv.URL, "whisper-1", "sk-test", "clips/voice.mp3", []byte("mp3 data"))
+	require.NoError(t, err)
+	assert.Equal(t, " Hello there. ", text)
+}
+
+func TestTranscribeBackend(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/asr", r.URL.Path)
+		var req schema.ServeASRRequest
+		require.NoError(t, msgpack.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, 16000, req.SampleRate)
+		require.Len(t, req.Audios, 1)
+		assert.Len(t, req.Audios[0], 16000*2)
+
+		w.Header().Set("Content-Type", "application/msgpack")
+		msgpack.NewEncoder(w).Encode(schema.ServeASRResponse{Transcriptions: []schema.ServeASRTranscription{{Text: "Hello"}, {Text: " there."}}})
+	}))
+	defer srv.Close()
+
+	wav := audio.EncodeWAV(&audio.PCM{SampleRate: 16000, Channels: 1, Samples: make([]float32, 16000)})
+	text, err := transcribeBackend(context.Background(), srv.URL, time.Minute, wav)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello there.", text)
+
+	_, err = transcribeBackend(context.Background(), srv.URL, time.Minute, []byte("ID3 mp3"))
+	assert.ErrorContains(t, err, "--whisper-url")
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/sockaddr"
+)
+
+var rootCmd = &cobra.Command{
+	Use:   "fish-mock-backend",
+	Short: "Emulate the Python Fish-Speech backend for development",
+	Long: `fish-mock-backend serves the Python backend API used by fish-server without a
+GPU. /v1/tts returns a sine tone whose length follows the input text, and
+references are held in memory.
+
+Examples:
+  # Run on the default backend port
+  fish-mock-backend
+
+  # Point fish-server at it
+  fish-server --backend http://127.0.0.1:8081
+
+  # Serve on a Unix domain socket instead
+  fish-mock-backend --listen unix:///tmp/fish-backend.sock
+  fish-server --backend unix:///tmp/fish-backend.sock
+
+  # Inject 500ms of latency and fail 10% of TTS requests
+  fish-mock-backend --latency 500ms --error-rate 0.1`,
+	RunE: runMock,
+}
+
+func init() {
+	rootCmd.Flags().String("listen", "127.0.0.1:8081", "Address to listen on (host:port or unix:///path/to.sock)")
+	rootCmd.Flags().Duration("latency", 0, "Delay added before every response")
+	rootCmd.Flags().Float64("error-rate", 0, "Fraction of TTS requests answered with a 500 error (0-1)")
+	rootCmd.Flags().Int("sample-rate", 44100, "Sample rate of generated audio")
+}
+
+func runMock(cmd *cobra.Command, args []string) error {
+	listen, _ := cmd.Flags().GetString("listen")
+	latency, _ := cmd.Flags().GetDuration("latency")
+	errorRate, _ := cmd.Flags().GetFloat64("error-rate")
+	sampleRate, _ := cmd.Flags().GetInt("sample-rate")
+
+	if errorRate < 0 || errorRate > 1 {
+		return fmt.Errorf("error-rate must be between 0 and 1")
+	}
+	if sampleRate <= 0 {
+		return fmt.Errorf("sample-rate must be positive")
+	}
+
+	logger := zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout}).With().Timestamp().Logger()
+	mock := newMockBackend(mockOptions{
+		Latency:    latency,
+		ErrorRate:  errorRate,
+		SampleRate: sampleRate,
+	}, logger)
+
+	ln, err := sockaddr.Listen(listen, 0)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Handler:           mock.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	logger.Info().
+		Str("addr", listen).
+		Dur("latency", latency).
+		Float64("error_rate", errorRate).
+		Msg("Mock backend listening")
+	return srv.Serve(ln)
+}
+
+func main() {
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
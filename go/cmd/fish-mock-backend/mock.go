@@ -0,0 +1,300 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/audio"
+	"github.com/fish-speech-go/fish-speech-go/internal/backend"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+	"github.com/fish-speech-go/fish-speech-go/internal/text"
+)
+
+const (
+	toneFrequency    = 220.0
+	toneAmplitude    = 0.3
+	minToneDuration  = 500 * time.Millisecond
+	streamChunkSize  = 4096
+	mockTranscript   = "mock transcription"
+	mockTokensPerSec = 21
+)
+
+// mockOptions configures the behavior of the emulated backend.
+type mockOptions struct {
+	Latency    time.Duration
+	ErrorRate  float64
+	SampleRate int
+}
+
+// mockBackend emulates the Python backend's HTTP API.
+type mockBackend struct {
+	opts   mockOptions
+	logger zerolog.Logger
+
+	mu         sync.Mutex
+	references map[string]string
+}
+
+func newMockBackend(opts mockOptions, logger zerolog.Logger) *mockBackend {
+	return &mockBackend{opts: opts, logger: logger, references: map[string]string{}}
+}
+
+func (m *mockBackend) routes() http.Handler {
+	r := chi.NewRouter()
+	r.Use(m.delay)
+
+	r.Get("/v1/health", m.handleHealth)
+	r.Post("/v1/health", m.handleHealth)
+	r.Post("/v1/tts", m.handleTTS)
+	r.Post("/v1/vqgan/encode", m.handleVQGANEncode)
+	r.Post("/v1/vqgan/decode", m.handleVQGANDecode)
+	r.Post("/v1/asr", m.handleASR)
+	r.Post("/v1/references/add", m.handleAddReference)
+	r.Get("/v1/references", m.handleListReferences)
+	r.Delete("/v1/references/{id}", m.handleDeleteReference)
+	return r
+}
+
+// delay applies the configured latency to every request.
+func (m *mockBackend) delay(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.opts.Latency > 0 {
+			select {
+			case <-time.After(m.opts.Latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (m *mockBackend) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (m *mockBackend) handleTTS(w http.ResponseWriter, r *http.Request) {
+	var req schema.ServeTTSRequest
+	if !decodeMsgpack(w, r, &req) {
+		return
+	}
+
+	if m.opts.ErrorRate > 0 && rand.Float64() < m.opts.ErrorRate {
+		m.logger.Info().Msg("Injecting TTS error")
+		writeDetail(w, http.StatusInternalServerError, "Injected mock backend error")
+		return
+	}
+
+	if req.ReferenceID != nil && *req.ReferenceID != "" {
+		m.mu.Lock()
+		_, ok := m.references[*req.ReferenceID]
+		m.mu.Unlock()
+		if !ok {
+			writeDetail(w, http.StatusNotFound, fmt.Sprintf("Reference %s not found", *req.ReferenceID))
+			return
+		}
+	}
+
+	if req.Format == "" {
+		req.Format = "wav"
+	}
+	if req.Format != "wav" && req.Format != "pcm" {
+		writeDetail(w, http.StatusBadRequest, "Mock backend only supports wav and pcm formats")
+		return
+	}
+
+	pcm := m.tone(req.Text)
+	m.logger.Info().
+		Int("text_length", len(req.Text)).
+		Bool("streaming", req.Streaming).
+		Dur("duration", pcm.Duration()).
+		Msg("TTS request")
+
+	if req.Streaming {
+		m.streamTone(w, pcm)
+		return
+	}
+
+	if req.Format == "pcm" {
+		w.Header().Set("Content-Type", "audio/pcm")
+		data := make([]byte, 2*len(pcm.Samples))
+		audio.PutPCM16(data, pcm.Samples)
+		w.Write(data)
+		return
+	}
+
+	w.Header().Set("Content-Type", "audio/wav")
+	w.Write(audio.EncodeWAV(pcm))
+}
+
+// tone generates a sine wave lasting roughly as long as text would take to read.
+func (m *mockBackend) tone(s string) *audio.PCM {
+	duration := text.EstimateSpeechDuration(s)
+	if duration < minToneDuration {
+		duration = minToneDuration
+	}
+
+	frames := int(duration.Seconds() * float64(m.opts.SampleRate))
+	pcm := &audio.PCM{SampleRate: m.opts.SampleRate, Channels: 1, Samples: make([]float32, frames)}
+	for i := range pcm.Samples {
+		pcm.Samples[i] = float32(toneAmplitude * math.Sin(2*math.Pi*toneFrequency*float64(i)/float64(m.opts.SampleRate)))
+	}
+	return pcm
+}
+
+// streamTone writes a WAV header followed by PCM chunks, like the Python
+// backend's streaming mode.
+func (m *mockBackend) streamTone(w http.ResponseWriter, pcm *audio.PCM) {
+	w.Header().Set("Content-Type", "audio/wav")
+	flusher, _ := w.(http.Flusher)
+
+	w.Write(audio.WAVHeader16(pcm.SampleRate, pcm.Channels, 0))
+	for start := 0; start < len(pcm.Samples); start += streamChunkSize {
+		end := start + streamChunkSize
+		if end > len(pcm.Samples) {
+			end = len(pcm.Samples)
+		}
+		chunk := make([]byte, 2*(end-start))
+		audio.PutPCM16(chunk, pcm.Samples[start:end])
+		if _, err := w.Write(chunk); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+func (m *mockBackend) handleVQGANEncode(w http.ResponseWriter, r *http.Request) {
+	var req schema.ServeVQGANEncodeRequest
+	if !decodeMsgpack(w, r, &req) {
+		return
+	}
+
+	resp := schema.ServeVQGANEncodeResponse{Tokens: make([][][]int, len(req.Audios))}
+	for i := range req.Audios {
+		resp.Tokens[i] = [][]int{make([]int, mockTokensPerSec)}
+	}
+	writeMsgpack(w, resp)
+}
+
+func (m *mockBackend) handleVQGANDecode(w http.ResponseWriter, r *http.Request) {
+	var req schema.ServeVQGANDecodeRequest
+	if !decodeMsgpack(w, r, &req) {
+		return
+	}
+
+	resp := schema.ServeVQGANDecodeResponse{Audios: make([][]byte, len(req.Tokens))}
+	for i := range req.Tokens {
+		resp.Audios[i] = audio.EncodeWAV(m.tone(""))
+	}
+	writeMsgpack(w, resp)
+}
+
+func (m *mockBackend) handleASR(w http.ResponseWriter, r *http.Request) {
+	var req schema.ServeASRRequest
+	if !decodeMsgpack(w, r, &req) {
+		return
+	}
+
+	resp := schema.ServeASRResponse{Transcriptions: make([]schema.ServeASRTranscription, len(req.Audios))}
+	for i := range req.Audios {
+		resp.Transcriptions[i] = schema.ServeASRTranscription{Text: mockTranscript}
+	}
+	writeMsgpack(w, resp)
+}
+
+func (m *mockBackend) handleAddReference(w http.ResponseWriter, r *http.Request) {
+	var req schema.AddReferenceRequest
+	if !decodeMsgpack(w, r, &req) {
+		return
+	}
+
+	m.mu.Lock()
+	m.references[req.ID] = req.Text
+	m.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, schema.AddReferenceResponse{
+		Success:     true,
+		Message:     fmt.Sprintf("Reference voice '%s' added successfully", req.ID),
+		ReferenceID: req.ID,
+	})
+}
+
+func (m *mockBackend) handleListReferences(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	ids := make([]string, 0, len(m.references))
+	for id := range m.references {
+		ids = append(ids, id)
+	}
+	m.mu.Unlock()
+	sort.Strings(ids)
+
+	writeJSON(w, http.StatusOK, schema.ListReferencesResponse{
+		Success:      true,
+		ReferenceIDs: ids,
+		Message:      fmt.Sprintf("Found %d reference voices", len(ids)),
+	})
+}
+
+func (m *mockBackend) handleDeleteReference(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	m.mu.Lock()
+	_, ok := m.references[id]
+	delete(m.references, id)
+	m.mu.Unlock()
+
+	if !ok {
+		writeDetail(w, http.StatusNotFound, fmt.Sprintf("Reference %s not found", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, schema.DeleteReferenceResponse{
+		Success:     true,
+		Message:     fmt.Sprintf("Reference voice '%s' deleted successfully", id),
+		ReferenceID: id,
+	})
+}
+
+func decodeMsgpack(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeDetail(w, http.StatusBadRequest, "Failed to read request body")
+		return false
+	}
+	if err := backend.DecodeMsgpack(body, v); err != nil {
+		writeDetail(w, http.StatusBadRequest, "Invalid msgpack body")
+		return false
+	}
+	return true
+}
+
+func writeMsgpack(w http.ResponseWriter, v interface{}) {
+	data, err := backend.EncodeMsgpack(v)
+	if err != nil {
+		writeDetail(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/msgpack")
+	w.Write(data)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeDetail(w http.ResponseWriter, status int, detail string) {
+	writeJSON(w, status, schema.ErrorResponse{Detail: detail})
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/audio"
+	"github.com/fish-speech-go/fish-speech-go/internal/backend"
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func newTestClient(t *testing.T, opts mockOptions) *backend.BackendClient {
+	t.Helper()
+	srv := httptest.NewServer(newMockBackend(opts, zerolog.Nop()).routes())
+	t.Cleanup(srv.Close)
+	return backend.NewBackendClient(&config.BackendConfig{URL: srv.URL, Timeout: 10 * time.Second})
+}
+
+func TestMockBackend_TTS(t *testing.T) {
+	client := newTestClient(t, mockOptions{SampleRate: 16000})
+	ctx := context.Background()
+
+	require.NoError(t, client.Health(ctx))
+
+	data, _, err := client.TTS(ctx, &schema.ServeTTSRequest{Text: "Hello from the mock backend."})
+	require.NoError(t, err)
+	pcm, err := audio.DecodeWAV(data)
+	require.NoError(t, err)
+	assert.Equal(t, 16000, pcm.SampleRate)
+	assert.Greater(t, pcm.Duration(), time.Second)
+
+	stream, err := client.TTSStream(ctx, &schema.ServeTTSRequest{Text: "Hello"})
+	require.NoError(t, err)
+	streamed, err := io.ReadAll(stream)
+	stream.Close()
+	require.NoError(t, err)
+	header, err := audio.ParseWAVHeader(streamed)
+	require.NoError(t, err)
+	assert.Equal(t, 16000, header.SampleRate)
+}
+
+func TestMockBackend_References(t *testing.T) {
+	client := newTestClient(t, mockOptions{SampleRate: 16000})
+	ctx := context.Background()
+
+	_, err := client.AddReference(ctx, &schema.AddReferenceRequest{ID: "voice", Audio: []byte("audio"), Text: "text"})
+	require.NoError(t, err)
+
+	refs, err := client.ListReferences(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"voice"}, refs.ReferenceIDs)
+
+	id := "voice"
+	_, _, err = client.TTS(ctx, &schema.ServeTTSRequest{Text: "Hello", ReferenceID: &id})
+	require.NoError(t, err)
+
+	_, err = client.DeleteReference(ctx, "voice")
+	require.NoError(t, err)
+
+	_, _, err = client.TTS(ctx, &schema.ServeTTSRequest{Text: "Hello", ReferenceID: &id})
+	var backendErr *backend.BackendError
+	require.ErrorAs(t, err, &backendErr)
+	assert.Equal(t, 404, backendErr.StatusCode)
+}
+
+func TestMockBackend_ErrorInjection(t *testing.T) {
+	client := newTestClient(t, mockOptions{SampleRate: 16000, ErrorRate: 1})
+
+	_, _, err := client.TTS(context.Background(), &schema.ServeTTSRequest{Text: "Hello"})
+	var backendErr *backend.BackendError
+	require.ErrorAs(t, err, &backendErr)
+	assert.Equal(t, 500, backendErr.StatusCode)
+}
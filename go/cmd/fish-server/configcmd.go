@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Check and print the server configuration",
+	Long: `The config commands load the configuration exactly as the server would, from
+flags, environment variables (FISH_*), the config file and defaults, in that
+order of precedence. Pass them the same flags and environment as the server.`,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the configuration and exit non-zero if it has problems",
+	Long: `Validate loads the configuration and reports every problem found: settings the
+server would reject, numbers out of range, and keys in the config file that name
+no setting, such as misspellings, which the server itself silently ignores.
+It exits with status 1 if there are any.
+
+  fish-server config validate --config /etc/fish/config.yaml`,
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          runConfigValidate,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration and where each value came from",
+	Long: `Show prints every setting after flags, environment, config file and defaults
+are merged, with the source of each value (flag, env, file or default). Secrets
+are redacted.
+
+  fish-server config show --changed
+  FISH_LISTEN=:9000 fish-server config show -o json`,
+	Args: cobra.NoArgs,
+	RunE: runConfigShow,
+}
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configShowCmd)
+
+	configShowCmd.Flags().StringP("output", "o", "text", "Output format: text, json")
+	configShowCmd.Flags().Bool("changed", false, "Only print settings not left at their default")
+}
+
+// configProblems returns every problem with the configuration: the error
+// loadConfig rejects it with, or else settings out of range, along with keys in
+// the config file that name no setting.
+func configProblems(cmd *cobra.Command) []error {
+	var problems []error
+	if path := viper.ConfigFileUsed(); path != "" {
+		file := viper.New()
+		file.SetConfigFile(path)
+		if err := file.ReadInConfig(); err != nil {
+			return []error{fmt.Errorf("failed to read %s: %w", path, err)}
+		}
+		for _, key := range config.UnknownKeys(file.AllSettings()) {
+			problems = append(problems, fmt.Errorf("%s: unknown setting in %s", key, path))
+		}
+	}
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return append([]error{err}, problems...)
+	}
+	return append(cfg.CheckRanges(), problems...)
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	out := cmd.OutOrStdout()
+	problems := configProblems(cmd)
+	if len(problems) == 0 {
+		fmt.Fprintln(out, "Configuration is valid")
+		return nil
+	}
+	for _, problem := range problems {
+		fmt.Fprintln(out, "error:", problem)
+	}
+	return fmt.Errorf("configuration has %d problem(s)", len(problems))
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	output, _ := cmd.Flags().GetString("output")
+	changed, _ := cmd.Flags().GetBool("changed")
+	if output != "text" && output != "json" {
+		return fmt.Errorf("unknown output format %q (want text or json)", output)
+	}
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	settings := cfg.Dump()
+	if changed {
+		kept := settings[:0]
+		for _, s := range settings {
+			if s.Source != config.SourceDefault {
+				kept = append(kept, s)
+			}
+		}
+		settings = kept
+	}
+
+	out := cmd.OutOrStdout()
+	if output == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(map[string][]config.Setting{"settings": settings})
+	}
+	return printSettings(out, settings)
+}
+
+// printSettings writes one aligned line per setting. Lists and nested values
+// are printed as JSON.
+func printSettings(w io.Writer, settings []config.Setting) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, s := range settings {
+		value := fmt.Sprint(s.Value)
+		switch s.Value.(type) {
+		case string, bool, int, int64, float64:
+		default:
+			raw, err := json.Marshal(s.Value)
+			if err != nil {
+				return fmt.Errorf("failed to print %s: %w", s.Key, err)
+			}
+			value = string(raw)
+		}
+		if value == "" {
+			value = `""`
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Key, value, s.Source)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+// useConfigFile loads config from a file with contents, as --config would.
+func useConfigFile(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+	cfgFile = path
+	t.Cleanup(func() { cfgFile = "" })
+	viper.Reset()
+	initConfig()
+	return path
+}
+
+func TestConfigValidate(t *testing.T) {
+	useConfigFile(t, `
+server:
+  listen: 127.0.0.1:9000
+limits:
+  max_text_lenght: 500
+  bulk_shed_ratio: 1.5
+`)
+
+	var out bytes.Buffer
+	configValidateCmd.SetOut(&out)
+	defer configValidateCmd.SetOut(nil)
+	err := runConfigValidate(configValidateCmd, nil)
+	require.Error(t, err)
+	assert.Contains(t, out.String(), "limits.max_text_lenght: unknown setting")
+	assert.Contains(t, out.String(), "limits.bulk_shed_ratio: must be between 0 and 1")
+
+	useConfigFile(t, "server:\n  listen: 127.0.0.1:9000\n")
+	out.Reset()
+	require.NoError(t, runConfigValidate(configValidateCmd, nil))
+	assert.Contains(t, out.String(), "Configuration is valid")
+}
+
+func TestConfigValidate_RejectedByServer(t *testing.T) {
+	useConfigFile(t, "logging:\n  access:\n    format: xml\n")
+
+	var out bytes.Buffer
+	configValidateCmd.SetOut(&out)
+	defer configValidateCmd.SetOut(nil)
+	assert.Error(t, runConfigValidate(configValidateCmd, nil))
+	assert.Contains(t, out.String(), "logging.access.format")
+	assert.NotContains(t, out.String(), "unknown setting")
+}
+
+func TestUnknownKeys(t *testing.T) {
+	unknown := config.UnknownKeys(map[string]interface{}{
+		"server": map[string]interface{}{"listen": ":8080", "lisen": ":8080"},
+		"auth": map[string]interface{}{
+			"keys": []interface{}{map[string]interface{}{"nmae": "ci"}},
+		},
+		"extra": true,
+	})
+	assert.Equal(t, []string{"auth.keys[0].nmae", "extra", "server.lisen"}, unknown)
+}
+
+func TestConfigShow(t *testing.T) {
+	useConfigFile(t, "server:\n  listen: 127.0.0.1:9000\n")
+	os.Setenv("FISH_BACKEND", "http://backend:8081")
+	defer os.Unsetenv("FISH_BACKEND")
+	viper.Reset()
+	initConfig()
+
+	var out bytes.Buffer
+	configShowCmd.SetOut(&out)
+	defer configShowCmd.SetOut(nil)
+	require.NoError(t, configShowCmd.Flags().Set("changed", "true"))
+	require.NoError(t, configShowCmd.Flags().Set("output", "json"))
+	defer configShowCmd.Flags().Set("changed", "false")
+	defer configShowCmd.Flags().Set("output", "text")
+
+	require.NoError(t, runConfigShow(configShowCmd, nil))
+	var resp struct {
+		Settings []config.Setting `json:"settings"`
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &resp))
+	sources := map[string]config.Source{}
+	for _, s := range resp.Settings {
+		sources[s.Key] = s.Source
+	}
+	assert.Equal(t, map[string]config.Source{
+		"server.listen": config.SourceFile,
+		"backend.url":   config.SourceEnv,
+	}, sources)
+
+	out.Reset()
+	require.NoError(t, configShowCmd.Flags().Set("output", "text"))
+	require.NoError(t, runConfigShow(configShowCmd, nil))
+	assert.Regexp(t, `server\.listen\s+127\.0\.0\.1:9000\s+file`, out.String())
+}
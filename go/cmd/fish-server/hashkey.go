@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/apikey"
+)
+
+var hashKeyCmd = &cobra.Command{
+	Use:   "hash-key",
+	Short: "Hash an API key for auth.keys[].hash or auth.api_key_hash",
+	Long: `Reads an API key from stdin and prints its salted hash and prefix, so the
+config file never has to hold the plain key. With --generate a new random key
+is created and printed once as well.
+
+  echo -n "$KEY" | fish-server hash-key
+  fish-server hash-key --generate`,
+	Args: cobra.NoArgs,
+	RunE: runHashKey,
+}
+
+func init() {
+	hashKeyCmd.Flags().Bool("generate", false, "generate a new random key")
+}
+
+func runHashKey(cmd *cobra.Command, args []string) error {
+	generate, _ := cmd.Flags().GetBool("generate")
+
+	var key string
+	if generate {
+		buf := make([]byte, 24)
+		if _, err := rand.Read(buf); err != nil {
+			return fmt.Errorf("failed to generate key: %w", err)
+		}
+		key = "fsk_" + hex.EncodeToString(buf)
+	} else {
+		line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read key: %w", err)
+		}
+		key = strings.TrimSpace(line)
+		if key == "" {
+			return errors.New("no key on stdin")
+		}
+	}
+
+	hash, err := apikey.New(key)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if generate {
+		fmt.Fprintf(out, "# key: %s (shown once; give it to the client)\n", key)
+	}
+	fmt.Fprintf(out, "hash: %q\nprefix: %q\n", hash.String(), apikey.Prefix(key))
+	return nil
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// advertiseHTTP3 adds an Alt-Svc header to TCP responses so clients can switch
+// to the HTTP/3 listener for later requests.
+func advertiseHTTP3(h3 *http3.Server, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fails only until the QUIC listener is up; there is nothing to announce yet.
+		_ = h3.SetQuicHeaders(w.Header())
+		next.ServeHTTP(w, r)
+	})
+}
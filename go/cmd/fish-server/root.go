@@ -7,6 +7,8 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
 )
 
 var (
@@ -50,46 +52,79 @@ func init() {
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default: ./config.yaml)")
 
-	rootCmd.Flags().String("listen", "0.0.0.0:8080", "Server listen address")
-	rootCmd.Flags().Duration("read-timeout", 30*time.Second, "HTTP read timeout")
-	rootCmd.Flags().Duration("write-timeout", 120*time.Second, "HTTP write timeout")
+	// Persistent so that the config commands see the same settings.
+	flags := rootCmd.PersistentFlags()
+	flags.String("listen", "0.0.0.0:8080", "Server listen address")
+	flags.Duration("read-timeout", 30*time.Second, "HTTP read timeout")
+	flags.Duration("write-timeout", 120*time.Second, "HTTP write timeout")
 
-	rootCmd.Flags().String("backend", "http://127.0.0.1:8081", "Python backend URL")
-	rootCmd.Flags().Duration("backend-timeout", 60*time.Second, "Backend request timeout")
+	flags.String("backend", "http://127.0.0.1:8081", "Python backend URL")
+	flags.Duration("backend-timeout", 60*time.Second, "Backend request timeout")
 
-	rootCmd.Flags().String("api-key", "", "API key for authentication (empty = no auth)")
-	rootCmd.Flags().Int("max-text-length", 0, "Maximum text length (0 = unlimited)")
+	flags.String("api-key", "", "API key for authentication (empty = no auth)")
+	flags.Int("max-text-length", 0, "Maximum text length (0 = unlimited)")
 
-	rootCmd.Flags().String("log-level", "info", "Log level (debug, info, warn, error)")
-	rootCmd.Flags().String("log-format", "json", "Log format (json, text)")
+	flags.String("log-level", "info", "Log level (debug, info, warn, error)")
+	flags.String("log-format", "json", "Log format (json, text)")
 
 	bindFlags()
 
 	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(hashKeyCmd)
+	rootCmd.AddCommand(configCmd)
 }
 
-func bindFlags() {
-	bindings := []struct {
-		key  string
-		flag string
-	}{
-		{"server.listen", "listen"},
-		{"server.read_timeout", "read-timeout"},
-		{"server.write_timeout", "write-timeout"},
-		{"backend.url", "backend"},
-		{"backend.timeout", "backend-timeout"},
-		{"auth.api_key", "api-key"},
-		{"limits.max_text_length", "max-text-length"},
-		{"logging.level", "log-level"},
-		{"logging.format", "log-format"},
-	}
+// flagBindings maps config keys to the command-line flags that set them.
+var flagBindings = map[string]string{
+	"server.listen":          "listen",
+	"server.read_timeout":    "read-timeout",
+	"server.write_timeout":   "write-timeout",
+	"backend.url":            "backend",
+	"backend.timeout":        "backend-timeout",
+	"auth.api_key":           "api-key",
+	"limits.max_text_length": "max-text-length",
+	"logging.level":          "log-level",
+	"logging.format":         "log-format",
+}
 
-	for _, b := range bindings {
-		flag := rootCmd.Flags().Lookup(b.flag)
+// envBindings maps config keys to the environment variables that set them.
+var envBindings = map[string]string{
+	"server.listen":                    "FISH_LISTEN",
+	"server.read_timeout":              "FISH_READ_TIMEOUT",
+	"server.write_timeout":             "FISH_WRITE_TIMEOUT",
+	"backend.url":                      "FISH_BACKEND",
+	"backend.timeout":                  "FISH_BACKEND_TIMEOUT",
+	"backend.max_connections":          "FISH_BACKEND_MAX_CONNECTIONS",
+	"auth.api_key":                     "FISH_API_KEY",
+	"auth.api_key_hash":                "FISH_API_KEY_HASH",
+	"auth.api_key_file":                "FISH_API_KEY_FILE",
+	"auth.key_dir":                     "FISH_KEY_DIR",
+	"auth.keys_file":                   "FISH_KEYS_FILE",
+	"auth.basic.htpasswd_file":         "FISH_HTPASSWD_FILE",
+	"auth.introspection.url":           "FISH_INTROSPECTION_URL",
+	"auth.introspection.client_secret": "FISH_INTROSPECTION_CLIENT_SECRET",
+	"auth.jwt.issuer":                  "FISH_JWT_ISSUER",
+	"auth.jwt.audience":                "FISH_JWT_AUDIENCE",
+	"auth.api_key_secret":              "FISH_API_KEY_SECRET",
+	"auth.usage_file":                  "FISH_USAGE_FILE",
+	"secrets.provider":                 "FISH_SECRETS_PROVIDER",
+	"limits.max_text_length":           "FISH_MAX_TEXT_LENGTH",
+	"references.store_path":            "FISH_REFERENCE_STORE",
+	"redis.url":                        "FISH_REDIS_URL",
+	"redis.password":                   "FISH_REDIS_PASSWORD",
+	"logging.level":                    "FISH_LOG_LEVEL",
+	"logging.format":                   "FISH_LOG_FORMAT",
+	"logging.access.output":            "FISH_ACCESS_LOG",
+	"logging.access.format":            "FISH_ACCESS_LOG_FORMAT",
+}
+
+func bindFlags() {
+	for key, name := range flagBindings {
+		flag := rootCmd.PersistentFlags().Lookup(name)
 		if flag == nil {
 			continue
 		}
-		_ = viper.BindPFlag(b.key, flag)
+		_ = viper.BindPFlag(key, flag)
 	}
 }
 
@@ -106,24 +141,132 @@ func initConfig() {
 	viper.SetEnvPrefix("FISH")
 	viper.AutomaticEnv()
 
-	viper.BindEnv("server.listen", "FISH_LISTEN")
-	viper.BindEnv("backend.url", "FISH_BACKEND")
-	viper.BindEnv("backend.timeout", "FISH_BACKEND_TIMEOUT")
-	viper.BindEnv("auth.api_key", "FISH_API_KEY")
-	viper.BindEnv("limits.max_text_length", "FISH_MAX_TEXT_LENGTH")
-	viper.BindEnv("logging.level", "FISH_LOG_LEVEL")
-	viper.BindEnv("logging.format", "FISH_LOG_FORMAT")
+	for key, env := range envBindings {
+		viper.BindEnv(key, env)
+	}
 
 	viper.SetDefault("server.listen", "0.0.0.0:8080")
 	viper.SetDefault("server.read_timeout", 30*time.Second)
 	viper.SetDefault("server.write_timeout", 120*time.Second)
+	viper.SetDefault("server.stream_idle_timeout", 30*time.Second)
+	viper.SetDefault("server.stream_keepalive", 0)
+	viper.SetDefault("server.cors.max_age", 10*time.Minute)
+	viper.SetDefault("server.cors.allow_private_network", false)
+	viper.SetDefault("server.socket_mode", "")
+	viper.SetDefault("server.tls_cert_file", "")
+	viper.SetDefault("server.tls_key_file", "")
+	viper.SetDefault("server.tls_client_ca_file", "")
+	viper.SetDefault("server.tls_client_auth", "require")
+	viper.SetDefault("server.tls_reload_interval", 10*time.Second)
+	viper.SetDefault("server.http3.enabled", false)
+	viper.SetDefault("server.http3.listen", "")
 	viper.SetDefault("backend.url", "http://127.0.0.1:8081")
+	viper.SetDefault("backend.balance", "round_robin")
 	viper.SetDefault("backend.timeout", 60*time.Second)
 	viper.SetDefault("backend.max_connections", 100)
+	viper.SetDefault("backend.model", "")
+	viper.SetDefault("backend.record_dir", "")
+	viper.SetDefault("backend.replay_dir", "")
+	viper.SetDefault("backend.max_queue_depth", 0)
+	viper.SetDefault("backend.stats_cache_ttl", 5*time.Second)
+	viper.SetDefault("backend.retry.max_attempts", 3)
+	viper.SetDefault("backend.retry.backoff", 100*time.Millisecond)
+	viper.SetDefault("backend.retry.max_backoff", 2*time.Second)
+	viper.SetDefault("backend.retry.status_codes", []int{502, 503})
 	viper.SetDefault("auth.api_key", "")
+	viper.SetDefault("auth.api_key_hash", "")
+	viper.SetDefault("auth.api_key_file", "")
+	viper.SetDefault("auth.key_dir", "")
+	viper.SetDefault("auth.keys_file", "")
+	viper.SetDefault("auth.reload_interval", 10*time.Second)
+	viper.SetDefault("auth.api_key_secret", "")
+	viper.SetDefault("auth.key_secrets", []string{})
+	viper.SetDefault("auth.usage_file", "")
+	viper.SetDefault("auth.basic.htpasswd_file", "")
+	viper.SetDefault("auth.basic.realm", "fish-speech")
+	viper.SetDefault("auth.introspection.url", "")
+	viper.SetDefault("auth.introspection.client_id", "")
+	viper.SetDefault("auth.introspection.client_secret", "")
+	viper.SetDefault("auth.introspection.cache_ttl", time.Minute)
+	viper.SetDefault("auth.introspection.timeout", 5*time.Second)
+	viper.SetDefault("auth.introspection.admin_scope", "")
+	viper.SetDefault("auth.jwt.issuer", "")
+	viper.SetDefault("auth.jwt.audience", "")
+	viper.SetDefault("auth.jwt.jwks_url", "")
+	viper.SetDefault("auth.jwt.refresh_interval", time.Hour)
+	viper.SetDefault("auth.jwt.timeout", 5*time.Second)
+	viper.SetDefault("auth.jwt.leeway", time.Minute)
+	viper.SetDefault("auth.jwt.username_claim", "sub")
+	viper.SetDefault("auth.jwt.namespace_claim", "")
+	viper.SetDefault("auth.jwt.scope_claim", "scope")
+	viper.SetDefault("auth.jwt.admin_scope", "")
+	viper.SetDefault("auth.signing.window", 5*time.Minute)
+	viper.SetDefault("auth.signing.max_body_bytes", 1<<20)
 	viper.SetDefault("limits.max_text_length", 0)
+	viper.SetDefault("limits.max_memory_mb", 0)
+	viper.SetDefault("limits.max_goroutines", 0)
+	viper.SetDefault("limits.bulk_shed_ratio", 0.8)
+	viper.SetDefault("limits.max_concurrent", 0)
+	viper.SetDefault("limits.acquire_timeout", 30*time.Second)
+	viper.SetDefault("limits.adaptive_concurrency", false)
+	viper.SetDefault("limits.min_concurrent", 1)
+	viper.SetDefault("limits.target_latency", 10*time.Second)
+	viper.SetDefault("limits.chars_per_slot", 0)
+	viper.SetDefault("references.store_path", "")
+	viper.SetDefault("references.duplicates", "warn")
+	viper.SetDefault("references.duplicate_threshold", 0.98)
+	viper.SetDefault("references.transcript_threshold", 0.7)
+	viper.SetDefault("references.upload_dir", "")
+	viper.SetDefault("references.upload_ttl", 24*time.Hour)
+	viper.SetDefault("references.audio_dir", "")
+	viper.SetDefault("references.use_memory_cache", true)
+	viper.SetDefault("references.max_audio_bytes", 200<<20)
+	viper.SetDefault("references.min_audio_duration", 0)
+	viper.SetDefault("references.max_audio_duration", 0)
+	viper.SetDefault("references.allowed_formats", []string{})
+	viper.SetDefault("references.transcode", true)
+	viper.SetDefault("tts.default_voice", "")
+	viper.SetDefault("audio.transcode", []string{})
+	viper.SetDefault("audio.mp3_bitrate", 128)
+	viper.SetDefault("audio.opus_bitrate", 32)
+	viper.SetDefault("cache.enabled", false)
+	viper.SetDefault("cache.ttl", 24*time.Hour)
+	viper.SetDefault("cache.max_entries", 10000)
+	viper.SetDefault("cache.max_bytes", 512<<20)
+	viper.SetDefault("cache.disk_dir", "")
+	viper.SetDefault("cache.disk_max_bytes", 10<<30)
+	viper.SetDefault("cache.spill_bytes", 1<<20)
+	viper.SetDefault("jobs.enabled", false)
+	viper.SetDefault("jobs.workers", 2)
+	viper.SetDefault("jobs.max_queued", 100)
+	viper.SetDefault("jobs.bulk_queue_ratio", 0.8)
+	viper.SetDefault("jobs.retention", time.Hour)
+	viper.SetDefault("jobs.max_retained_bytes", 512<<20)
+	viper.SetDefault("redis.url", "")
+	viper.SetDefault("redis.password", "")
+	viper.SetDefault("redis.key_prefix", "fish:")
+	viper.SetDefault("redis.timeout", 500*time.Millisecond)
+	viper.SetDefault("redis.cache", true)
+	viper.SetDefault("redis.rate_limits", true)
+	viper.SetDefault("redis.reference_list_ttl", 10*time.Second)
 	viper.SetDefault("logging.level", "info")
 	viper.SetDefault("logging.format", "json")
+	viper.SetDefault("logging.access.output", "")
+	viper.SetDefault("logging.access.format", "")
+	viper.SetDefault("tracing.enabled", false)
+	viper.SetDefault("tracing.endpoint", "")
+	viper.SetDefault("tracing.sample_ratio", 1.0)
+	viper.SetDefault("tracing.slow_threshold", 0)
+	viper.SetDefault("tracing.service_name", "fish-speech-go")
+	viper.SetDefault("docs.enabled", true)
+	viper.SetDefault("docs.script_url", config.DefaultDocsScriptURL)
+	viper.SetDefault("secrets.provider", "")
+	viper.SetDefault("secrets.refresh_interval", 5*time.Minute)
+	viper.SetDefault("secrets.vault.address", "")
+	viper.SetDefault("secrets.vault.mount", "secret")
+	viper.SetDefault("secrets.vault.token_file", "")
+	viper.SetDefault("secrets.aws.region", "")
+	viper.SetDefault("secrets.aws.endpoint", "")
 
 	bindFlags()
 
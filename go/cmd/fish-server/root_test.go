@@ -3,9 +3,12 @@ package main
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
 )
 
 func TestConfigDefaults(t *testing.T) {
@@ -49,3 +52,262 @@ func TestConfigFromEnv(t *testing.T) {
 	assert.Equal(t, 5000, cfg.Limits.MaxTextLength)
 	assert.Equal(t, "debug", cfg.Logging.Level)
 }
+
+func TestConfigHTTP3RequiresTLS(t *testing.T) {
+	viper.Reset()
+	initConfig()
+	viper.Set("server.http3.enabled", true)
+
+	_, err := loadConfig(rootCmd)
+	assert.Error(t, err)
+
+	viper.Set("server.tls_cert_file", "cert.pem")
+	viper.Set("server.tls_key_file", "key.pem")
+	cfg, err := loadConfig(rootCmd)
+	assert.NoError(t, err)
+	assert.Equal(t, cfg.Server.Listen, cfg.Server.HTTP3.Listen)
+}
+
+func TestConfigUnixSocket(t *testing.T) {
+	viper.Reset()
+	initConfig()
+	viper.Set("server.listen", "unix:///run/fish/fish.sock")
+	viper.Set("server.socket_mode", "0660")
+	viper.Set("backend.url", "unix:///run/fish/backend.sock")
+
+	cfg, err := loadConfig(rootCmd)
+	assert.NoError(t, err)
+	assert.Equal(t, "unix:///run/fish/fish.sock", cfg.Server.Listen)
+	assert.Equal(t, "unix:///run/fish/backend.sock", cfg.Backend.URL)
+	assert.Empty(t, cfg.Server.HTTP3.Listen, "a socket is no UDP address")
+
+	viper.Set("server.socket_mode", "rw-rw----")
+	_, err = loadConfig(rootCmd)
+	assert.Error(t, err)
+	viper.Set("server.socket_mode", "")
+
+	viper.Set("server.tls_cert_file", "cert.pem")
+	viper.Set("server.tls_key_file", "key.pem")
+	viper.Set("server.http3.enabled", true)
+	_, err = loadConfig(rootCmd)
+	assert.Error(t, err, "HTTP/3 needs its own address")
+	viper.Set("server.http3.listen", "0.0.0.0:8443")
+	_, err = loadConfig(rootCmd)
+	assert.NoError(t, err)
+}
+
+func TestConfigTLSClientCA(t *testing.T) {
+	viper.Reset()
+	initConfig()
+	viper.Set("server.tls_client_ca_file", "ca.pem")
+
+	_, err := loadConfig(rootCmd)
+	assert.Error(t, err, "client CAs require TLS")
+
+	viper.Set("server.tls_cert_file", "cert.pem")
+	viper.Set("server.tls_key_file", "key.pem")
+	viper.Set("server.tls_client_auth", "sometimes")
+	_, err = loadConfig(rootCmd)
+	assert.Error(t, err)
+
+	viper.Set("server.tls_client_auth", "optional")
+	cfg, err := loadConfig(rootCmd)
+	assert.NoError(t, err)
+	assert.Equal(t, "optional", cfg.Server.TLSClientAuth)
+	assert.Equal(t, 10*time.Second, cfg.Server.TLSReloadInterval)
+}
+
+func TestConfigSources(t *testing.T) {
+	viper.Reset()
+	os.Setenv("FISH_BACKEND", "http://backend:8081")
+	defer os.Unsetenv("FISH_BACKEND")
+	initConfig()
+
+	cfg, err := loadConfig(rootCmd)
+	assert.NoError(t, err)
+
+	assert.Equal(t, config.SourceEnv, cfg.Sources["backend.url"])
+	assert.Equal(t, config.SourceDefault, cfg.Sources["server.listen"])
+	assert.Len(t, cfg.Sources, len(config.Keys()))
+}
+
+func TestConfigRejectsInvalidKeyHash(t *testing.T) {
+	viper.Reset()
+	initConfig()
+	viper.Set("auth.api_key_hash", "plain-key")
+
+	_, err := loadConfig(rootCmd)
+	assert.Error(t, err)
+}
+
+func TestConfigRejectsAmbiguousNamespace(t *testing.T) {
+	for key, value := range map[string]interface{}{
+		"auth.keys":         []map[string]interface{}{{"key": "k", "namespace": "acme__b"}},
+		"auth.basic.users":  []map[string]interface{}{{"username": "u", "password": "p", "namespace": "acme_"}},
+		"auth.signing.keys": []map[string]interface{}{{"id": "s", "secret": "0123456789abcdef", "namespace": "acme__b"}},
+	} {
+		viper.Reset()
+		initConfig()
+		viper.Set(key, value)
+
+		_, err := loadConfig(rootCmd)
+		assert.ErrorContains(t, err, "namespace", key)
+	}
+}
+
+func TestConfigRejectsUnknownAccessLogFormat(t *testing.T) {
+	viper.Reset()
+	initConfig()
+	viper.Set("logging.access.format", "apache")
+
+	_, err := loadConfig(rootCmd)
+	assert.Error(t, err)
+
+	viper.Set("logging.access.format", "combined")
+	cfg, err := loadConfig(rootCmd)
+	assert.NoError(t, err)
+	assert.Equal(t, "combined", cfg.Logging.Access.Format)
+}
+
+func TestConfigBackendReplicas(t *testing.T) {
+	viper.Reset()
+	initConfig()
+	viper.Set("backend.balance", "random")
+
+	_, err := loadConfig(rootCmd)
+	assert.Error(t, err)
+
+	viper.Set("backend.balance", "least_connections")
+	viper.Set("backend.urls", []string{"http://127.0.0.1:8091", "http://127.0.0.1:8092"})
+	cfg, err := loadConfig(rootCmd)
+	assert.NoError(t, err)
+	assert.Equal(t, "least_connections", cfg.Backend.Balance)
+	assert.Equal(t, []string{"http://127.0.0.1:8091", "http://127.0.0.1:8092"}, cfg.Backend.URLs)
+}
+
+func TestConfigRedis(t *testing.T) {
+	viper.Reset()
+	initConfig()
+	t.Setenv("FISH_REDIS_URL", "redis://redis:6379/0")
+
+	cfg, err := loadConfig(rootCmd)
+	assert.NoError(t, err)
+	assert.Equal(t, "redis://redis:6379/0", cfg.Redis.URL)
+	assert.Equal(t, "fish:", cfg.Redis.KeyPrefix)
+	assert.Equal(t, 500*time.Millisecond, cfg.Redis.Timeout)
+	assert.True(t, cfg.Redis.Cache)
+	assert.True(t, cfg.Redis.RateLimits)
+	assert.Equal(t, 10*time.Second, cfg.Redis.ReferenceListTTL)
+
+	viper.Set("redis.reference_list_ttl", 0)
+	cfg, err = loadConfig(rootCmd)
+	assert.NoError(t, err)
+	assert.Zero(t, cfg.Redis.ReferenceListTTL)
+}
+
+func TestConfigRejectsInvalidBitrate(t *testing.T) {
+	viper.Reset()
+	initConfig()
+	viper.Set("audio.mp3_bitrate", 100)
+
+	_, err := loadConfig(rootCmd)
+	assert.ErrorContains(t, err, "audio.mp3_bitrate")
+
+	viper.Set("audio.mp3_bitrate", 192)
+	viper.Set("audio.opus_bitrate", 1000)
+	_, err = loadConfig(rootCmd)
+	assert.ErrorContains(t, err, "audio.opus_bitrate")
+
+	viper.Set("audio.opus_bitrate", 64)
+	cfg, err := loadConfig(rootCmd)
+	assert.NoError(t, err)
+	assert.Equal(t, 192, cfg.Audio.MP3Bitrate)
+	assert.Equal(t, 64, cfg.Audio.OpusBitrate)
+}
+
+func TestConfigVoices(t *testing.T) {
+	viper.Reset()
+	initConfig()
+	viper.Set("references.voices", []map[string]any{
+		{"name": "narrator", "reference_id": "narrator-v2", "temperature": 0.6, "seed": 7},
+	})
+
+	cfg, err := loadConfig(rootCmd)
+	assert.NoError(t, err)
+	seed := 7
+	assert.Equal(t, []config.VoiceConfig{{Name: "narrator", ReferenceID: "narrator-v2", Temperature: 0.6, Seed: &seed}}, cfg.References.Voices)
+
+	viper.Set("references.voices", []map[string]any{{"name": "narrator", "reference_id": "narrator-v2", "temperature": 3}})
+	_, err = loadConfig(rootCmd)
+	assert.ErrorContains(t, err, "references.voices[0]: temperature")
+}
+
+func TestConfigPresets(t *testing.T) {
+	viper.Reset()
+	initConfig()
+	viper.Set("tts.default_voice", "narrator")
+	viper.Set("tts.presets", []map[string]any{
+		{"name": "calm", "voice": "narrator", "format": "mp3", "temperature": 0.5},
+	})
+
+	cfg, err := loadConfig(rootCmd)
+	assert.NoError(t, err)
+	assert.Equal(t, "narrator", cfg.TTS.DefaultVoice)
+	assert.Equal(t, []config.PresetConfig{{Name: "calm", Voice: "narrator", Format: "mp3", Temperature: 0.5}}, cfg.TTS.Presets)
+
+	viper.Set("tts.presets", []map[string]any{{"name": "calm", "voice": "narrator", "reference_id": "narrator-v1"}})
+	_, err = loadConfig(rootCmd)
+	assert.ErrorContains(t, err, "tts.presets[0]: voice and reference_id")
+}
+
+func TestConfigJWT(t *testing.T) {
+	viper.Reset()
+	initConfig()
+	t.Setenv("FISH_JWT_ISSUER", "https://login.example.com/")
+	viper.Set("auth.jwt.namespace_claim", "tenant")
+	viper.Set("auth.jwt.scopes", []map[string]any{{"scope": "tts", "routes": []string{"/v1/tts"}}})
+
+	cfg, err := loadConfig(rootCmd)
+	assert.NoError(t, err)
+	assert.True(t, cfg.Auth.Enabled())
+	assert.Equal(t, "https://login.example.com/", cfg.Auth.JWT.Issuer)
+	assert.Equal(t, "sub", cfg.Auth.JWT.UsernameClaim)
+	assert.Equal(t, "tenant", cfg.Auth.JWT.NamespaceClaim)
+	assert.Equal(t, time.Hour, cfg.Auth.JWT.RefreshInterval)
+	assert.Equal(t, []config.ScopeConfig{{Scope: "tts", Routes: []string{"/v1/tts"}}}, cfg.Auth.JWT.Scopes)
+
+	viper.Set("auth.jwt.jwks_url", "keys.json")
+	_, err = loadConfig(rootCmd)
+	assert.ErrorContains(t, err, "auth.jwt.jwks_url")
+}
+
+func TestConfigTracing(t *testing.T) {
+	viper.Reset()
+	initConfig()
+	viper.Set("tracing.enabled", true)
+	viper.Set("tracing.endpoint", "http://otel-collector:4318")
+	viper.Set("tracing.headers", map[string]string{"authorization": "Bearer token"})
+	viper.Set("tracing.route_ratios", map[string]float64{"/v1/health": 0})
+	viper.Set("tracing.slow_threshold", "10s")
+
+	cfg, err := loadConfig(rootCmd)
+	assert.NoError(t, err)
+	assert.Equal(t, config.TracingConfig{
+		Enabled:       true,
+		Endpoint:      "http://otel-collector:4318",
+		Headers:       map[string]string{"authorization": "Bearer token"},
+		SampleRatio:   1,
+		RouteRatios:   map[string]float64{"/v1/health": 0},
+		SlowThreshold: 10 * time.Second,
+		ServiceName:   "fish-speech-go",
+	}, cfg.Tracing)
+
+	viper.Set("tracing.sample_ratio", 1.5)
+	_, err = loadConfig(rootCmd)
+	assert.ErrorContains(t, err, "tracing.sample_ratio")
+
+	viper.Set("tracing.sample_ratio", 1)
+	viper.Set("tracing.route_ratios", map[string]float64{"/v1/tts": 2})
+	_, err = loadConfig(rootCmd)
+	assert.ErrorContains(t, err, "tracing.route_ratios")
+}
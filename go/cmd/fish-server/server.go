@@ -2,21 +2,39 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/quic-go/quic-go/http3"
 	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	"github.com/fish-speech-go/fish-speech-go/internal/api"
+	"github.com/fish-speech-go/fish-speech-go/internal/apikey"
+	"github.com/fish-speech-go/fish-speech-go/internal/audio"
 	"github.com/fish-speech-go/fish-speech-go/internal/backend"
+	"github.com/fish-speech-go/fish-speech-go/internal/cache"
+	"github.com/fish-speech-go/fish-speech-go/internal/certs"
 	"github.com/fish-speech-go/fish-speech-go/internal/config"
+	"github.com/fish-speech-go/fish-speech-go/internal/metrics"
+	"github.com/fish-speech-go/fish-speech-go/internal/queue"
+	"github.com/fish-speech-go/fish-speech-go/internal/refaudio"
+	"github.com/fish-speech-go/fish-speech-go/internal/refstore"
+	"github.com/fish-speech-go/fish-speech-go/internal/secrets"
+	"github.com/fish-speech-go/fish-speech-go/internal/shared"
+	"github.com/fish-speech-go/fish-speech-go/internal/sockaddr"
+	"github.com/fish-speech-go/fish-speech-go/internal/tracing"
+	"github.com/fish-speech-go/fish-speech-go/internal/upload"
+	"github.com/fish-speech-go/fish-speech-go/internal/usage"
 )
 
 func runServer(cmd *cobra.Command, args []string) error {
@@ -26,6 +44,13 @@ func runServer(cmd *cobra.Command, args []string) error {
 	}
 
 	logger := setupLogger(cfg.Logging)
+	accessLog, accessLogFile, err := setupAccessLog(cfg.Logging)
+	if err != nil {
+		return err
+	}
+	if accessLogFile != nil {
+		defer accessLogFile.Close()
+	}
 
 	logger.Info().
 		Str("listen", cfg.Server.Listen).
@@ -33,7 +58,26 @@ func runServer(cmd *cobra.Command, args []string) error {
 		Str("log_level", cfg.Logging.Level).
 		Msg("Starting Fish-Speech-Go server")
 
-	backendClient := backend.NewBackendClient(&cfg.Backend)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, Version)
+	if err != nil {
+		return fmt.Errorf("invalid tracing config: %w", err)
+	}
+	if cfg.Tracing.Enabled {
+		logger.Info().Str("endpoint", cfg.Tracing.Endpoint).Float64("sample_ratio", cfg.Tracing.SampleRatio).Msg("Tracing enabled")
+	}
+
+	if cfg.Chaos.Enabled {
+		logger.Warn().
+			Float64("error_rate", cfg.Chaos.ErrorRate).
+			Float64("drop_rate", cfg.Chaos.DropRate).
+			Float64("latency_rate", cfg.Chaos.LatencyRate).
+			Msg("Fault injection is enabled - do not use in production")
+	}
+
+	backendClient, err := backend.New(&cfg.Backend)
+	if err != nil {
+		return fmt.Errorf("invalid backend config: %w", err)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	if err := backendClient.Health(ctx); err != nil {
@@ -43,22 +87,174 @@ func runServer(cmd *cobra.Command, args []string) error {
 	}
 	cancel()
 
-	router := api.NewRouter(cfg, backendClient, logger)
+	refStore, err := refstore.Open(cfg.References.StorePath)
+	if err != nil {
+		return fmt.Errorf("failed to open reference store: %w", err)
+	}
+
+	uploads, err := upload.Open(cfg.References.UploadPath(), cfg.References.UploadTTL)
+	if err != nil {
+		return fmt.Errorf("failed to open upload directory: %w", err)
+	}
+
+	refAudio, err := refaudio.Open(cfg.References.AudioPath())
+	if err != nil {
+		return fmt.Errorf("failed to open reference audio directory: %w", err)
+	}
+
+	meter, err := usage.Open(cfg.Auth.UsageFile)
+	if err != nil {
+		return fmt.Errorf("failed to open usage file: %w", err)
+	}
+
+	serverMetrics := metrics.New()
+	drainer := api.NewDrainer()
+	opts := []api.Option{
+		api.WithReferenceStore(refStore), api.WithReferenceAudio(refAudio), api.WithUploadStore(uploads),
+		api.WithMetrics(serverMetrics), api.WithAccessLog(accessLog), api.WithUsageMeter(meter),
+		api.WithDrainer(drainer),
+	}
+	var store *shared.Store
+	if cfg.Redis.URL != "" {
+		store, err = shared.Open(cfg.Redis)
+		if err != nil {
+			return fmt.Errorf("failed to connect to redis: %w", err)
+		}
+		defer store.Close()
+		logger.Info().Bool("cache", cfg.Redis.Cache).Bool("rate_limits", cfg.Redis.RateLimits).Msg("Sharing state through Redis")
+		opts = append(opts, api.WithSharedStore(store))
+	}
+	var responses *cache.Cache
+	if cfg.Cache.Enabled {
+		cacheCfg := cache.Config{
+			TTL:        cfg.Cache.TTL,
+			MaxEntries: cfg.Cache.MaxEntries,
+			MaxBytes:   cfg.Cache.MaxBytes,
+			SpillBytes: cfg.Cache.SpillBytes,
+			OnEvict:    serverMetrics.RecordCacheEviction,
+		}
+		if cfg.Cache.DiskDir != "" {
+			cacheCfg.Disk, err = cache.OpenDisk(cfg.Cache.DiskDir, cfg.Cache.DiskMaxBytes)
+			if err != nil {
+				return fmt.Errorf("failed to open cache directory: %w", err)
+			}
+		}
+		if store != nil && cfg.Redis.Cache {
+			cacheCfg.Remote = store.Cache()
+		}
+		responses = cache.New(cacheCfg)
+		if cacheCfg.Remote != nil {
+			invalidations, stopInvalidations := context.WithCancel(context.Background())
+			defer stopInvalidations()
+			go store.Cache().Subscribe(invalidations, func(inv cache.Invalidation) {
+				responses.ApplyInvalidation(inv)
+			})
+		}
+		opts = append(opts, api.WithCache(responses))
+	}
+	if cfg.Jobs.Enabled {
+		jobs := queue.New(queue.Config{
+			Workers:          cfg.Jobs.Workers,
+			MaxQueued:        cfg.Jobs.MaxQueued,
+			LowQueueRatio:    cfg.Jobs.BulkQueueRatio,
+			Retention:        cfg.Jobs.Retention,
+			MaxRetainedBytes: cfg.Jobs.MaxRetainedBytes,
+		})
+		defer jobs.Close()
+		opts = append(opts, api.WithJobs(jobs))
+	}
+
+	secretsCtx, stopSecrets := context.WithCancel(context.Background())
+	defer stopSecrets()
+	if names := cfg.Auth.SecretNames(); len(names) > 0 {
+		provider, err := secrets.New(cfg.Secrets)
+		if err != nil {
+			return fmt.Errorf("invalid secrets config: %w", err)
+		}
+		cache := secrets.NewCache(provider, names)
+
+		ctx, cancel := context.WithTimeout(secretsCtx, 30*time.Second)
+		err = cache.Refresh(ctx)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to fetch API keys from %s: %w", cfg.Secrets.Provider, err)
+		}
+		logger.Info().Str("provider", cfg.Secrets.Provider).Int("secrets", len(names)).Msg("API keys loaded from secret manager")
+
+		go cache.Run(secretsCtx, cfg.Secrets.RefreshInterval, func(err error) {
+			logger.Warn().Err(err).Msg("Secret refresh failed - keeping previous values")
+		})
+		opts = append(opts, api.WithSecrets(cache))
+	}
+
+	router := api.NewRouter(cfg, backendClient, logger, opts...)
+
+	var tlsConfig *tls.Config
+	if cfg.Server.TLSEnabled() {
+		reloader, err := certs.New(cfg.Server)
+		if err != nil {
+			return err
+		}
+		tlsConfig = reloader.TLSConfig()
+
+		reloadCtx, stopReload := context.WithCancel(context.Background())
+		defer stopReload()
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		defer signal.Stop(hup)
+		go reloader.Run(reloadCtx, cfg.Server.TLSReloadInterval, hup, func(err error) {
+			if err != nil {
+				logger.Warn().Err(err).Msg("TLS certificate reload failed - keeping previous certificate")
+				return
+			}
+			logger.Info().Msg("TLS certificate reloaded")
+		})
+	}
+
+	var handler http.Handler = router
+	var h3 *http3.Server
+	if cfg.Server.HTTP3.Enabled {
+		h3 = &http3.Server{Addr: cfg.Server.HTTP3.Listen, Handler: router, TLSConfig: tlsConfig}
+		handler = advertiseHTTP3(h3, router)
+	}
 
 	srv := &http.Server{
 		Addr:         cfg.Server.Listen,
-		Handler:      router,
+		Handler:      handler,
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
+		TLSConfig:    tlsConfig,
 	}
 
-	serverErr := make(chan error, 1)
+	// Validated in loadConfig.
+	socketMode, _ := strconv.ParseUint(cfg.Server.SocketMode, 8, 32)
+	ln, err := sockaddr.Listen(cfg.Server.Listen, os.FileMode(socketMode))
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", cfg.Server.Listen, err)
+	}
+
+	serverErr := make(chan error, 2)
 	go func() {
-		logger.Info().Str("addr", cfg.Server.Listen).Msg("Server listening")
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logger.Info().Str("addr", cfg.Server.Listen).Bool("tls", cfg.Server.TLSEnabled()).Bool("mtls", cfg.Server.TLSClientCAFile != "").Msg("Server listening")
+		var err error
+		if cfg.Server.TLSEnabled() {
+			// The certificate comes from TLSConfig, so it can be reloaded.
+			err = srv.ServeTLS(ln, "", "")
+		} else {
+			err = srv.Serve(ln)
+		}
+		if err != nil && err != http.ErrServerClosed {
 			serverErr <- err
 		}
 	}()
+	if h3 != nil {
+		go func() {
+			logger.Info().Str("addr", cfg.Server.HTTP3.Listen).Msg("HTTP/3 server listening")
+			if err := h3.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				serverErr <- fmt.Errorf("http3: %w", err)
+			}
+		}()
+	}
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -68,14 +264,26 @@ func runServer(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("server error: %w", err)
 	case sig := <-quit:
 		logger.Info().Str("signal", sig.String()).Msg("Shutting down server...")
+	case <-drainer.Drained():
+		logger.Info().Msg("Drained - shutting down server...")
 	}
 
 	ctx, cancel = context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	if h3 != nil {
+		_ = h3.Close()
+	}
 	if err := srv.Shutdown(ctx); err != nil {
 		return fmt.Errorf("server shutdown error: %w", err)
 	}
+	if responses != nil {
+		// Keep the cached responses for the next start.
+		responses.Persist()
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Warn().Err(err).Msg("Failed to flush traces")
+	}
 
 	logger.Info().Msg("Server stopped")
 	return nil
@@ -89,24 +297,209 @@ func loadConfig(cmd *cobra.Command) (*config.Config, error) {
 			Listen:       viper.GetString("server.listen"),
 			ReadTimeout:  viper.GetDuration("server.read_timeout"),
 			WriteTimeout: viper.GetDuration("server.write_timeout"),
+
+			StreamIdleTimeout: viper.GetDuration("server.stream_idle_timeout"),
+			StreamKeepAlive:   viper.GetDuration("server.stream_keepalive"),
+			CORS: config.CORSConfig{
+				MaxAge:              viper.GetDuration("server.cors.max_age"),
+				AllowPrivateNetwork: viper.GetBool("server.cors.allow_private_network"),
+			},
+			SocketMode:        viper.GetString("server.socket_mode"),
+			TLSCertFile:       viper.GetString("server.tls_cert_file"),
+			TLSKeyFile:        viper.GetString("server.tls_key_file"),
+			TLSClientCAFile:   viper.GetString("server.tls_client_ca_file"),
+			TLSClientAuth:     viper.GetString("server.tls_client_auth"),
+			TLSReloadInterval: viper.GetDuration("server.tls_reload_interval"),
+			HTTP3: config.HTTP3Config{
+				Enabled: viper.GetBool("server.http3.enabled"),
+				Listen:  viper.GetString("server.http3.listen"),
+			},
 		},
 		Backend: config.BackendConfig{
 			URL:            viper.GetString("backend.url"),
+			URLs:           viper.GetStringSlice("backend.urls"),
+			Balance:        viper.GetString("backend.balance"),
 			Timeout:        viper.GetDuration("backend.timeout"),
 			MaxConnections: viper.GetInt("backend.max_connections"),
+			Model:          viper.GetString("backend.model"),
+			RecordDir:      viper.GetString("backend.record_dir"),
+			ReplayDir:      viper.GetString("backend.replay_dir"),
+			MaxQueueDepth:  viper.GetInt("backend.max_queue_depth"),
+			StatsCacheTTL:  viper.GetDuration("backend.stats_cache_ttl"),
+			Retry: config.BackendRetryConfig{
+				MaxAttempts: viper.GetInt("backend.retry.max_attempts"),
+				Backoff:     viper.GetDuration("backend.retry.backoff"),
+				MaxBackoff:  viper.GetDuration("backend.retry.max_backoff"),
+				StatusCodes: viper.GetIntSlice("backend.retry.status_codes"),
+			},
 		},
 		Auth: config.AuthConfig{
-			APIKey: viper.GetString("auth.api_key"),
+			APIKey:         viper.GetString("auth.api_key"),
+			APIKeyHash:     viper.GetString("auth.api_key_hash"),
+			APIKeyFile:     viper.GetString("auth.api_key_file"),
+			KeyDir:         viper.GetString("auth.key_dir"),
+			KeysFile:       viper.GetString("auth.keys_file"),
+			ReloadInterval: viper.GetDuration("auth.reload_interval"),
+			APIKeySecret:   viper.GetString("auth.api_key_secret"),
+			KeySecrets:     viper.GetStringSlice("auth.key_secrets"),
+			UsageFile:      viper.GetString("auth.usage_file"),
+			Basic: config.BasicAuthConfig{
+				HtpasswdFile: viper.GetString("auth.basic.htpasswd_file"),
+				Realm:        viper.GetString("auth.basic.realm"),
+			},
+			Introspection: config.IntrospectionConfig{
+				URL:          viper.GetString("auth.introspection.url"),
+				ClientID:     viper.GetString("auth.introspection.client_id"),
+				ClientSecret: viper.GetString("auth.introspection.client_secret"),
+				CacheTTL:     viper.GetDuration("auth.introspection.cache_ttl"),
+				Timeout:      viper.GetDuration("auth.introspection.timeout"),
+				AdminScope:   viper.GetString("auth.introspection.admin_scope"),
+			},
+			JWT: config.JWTConfig{
+				Issuer:          viper.GetString("auth.jwt.issuer"),
+				Audience:        viper.GetString("auth.jwt.audience"),
+				JWKSURL:         viper.GetString("auth.jwt.jwks_url"),
+				RefreshInterval: viper.GetDuration("auth.jwt.refresh_interval"),
+				Timeout:         viper.GetDuration("auth.jwt.timeout"),
+				Leeway:          viper.GetDuration("auth.jwt.leeway"),
+				UsernameClaim:   viper.GetString("auth.jwt.username_claim"),
+				NamespaceClaim:  viper.GetString("auth.jwt.namespace_claim"),
+				ScopeClaim:      viper.GetString("auth.jwt.scope_claim"),
+				AdminScope:      viper.GetString("auth.jwt.admin_scope"),
+			},
+			Signing: config.SigningConfig{
+				Window:       viper.GetDuration("auth.signing.window"),
+				MaxBodyBytes: viper.GetInt64("auth.signing.max_body_bytes"),
+			},
 		},
 		Limits: config.LimitsConfig{
 			MaxTextLength: viper.GetInt("limits.max_text_length"),
+			MaxMemoryMB:   viper.GetInt("limits.max_memory_mb"),
+			MaxGoroutines: viper.GetInt("limits.max_goroutines"),
+			BulkShedRatio: viper.GetFloat64("limits.bulk_shed_ratio"),
+
+			MaxConcurrent:       viper.GetInt("limits.max_concurrent"),
+			AcquireTimeout:      viper.GetDuration("limits.acquire_timeout"),
+			AdaptiveConcurrency: viper.GetBool("limits.adaptive_concurrency"),
+			MinConcurrent:       viper.GetInt("limits.min_concurrent"),
+			TargetLatency:       viper.GetDuration("limits.target_latency"),
+			CharsPerSlot:        viper.GetInt("limits.chars_per_slot"),
+		},
+		References: config.ReferencesConfig{
+			StorePath:           viper.GetString("references.store_path"),
+			Duplicates:          viper.GetString("references.duplicates"),
+			DuplicateThreshold:  viper.GetFloat64("references.duplicate_threshold"),
+			TranscriptThreshold: viper.GetFloat64("references.transcript_threshold"),
+			UploadDir:           viper.GetString("references.upload_dir"),
+			UploadTTL:           viper.GetDuration("references.upload_ttl"),
+			AudioDir:            viper.GetString("references.audio_dir"),
+			UseMemoryCache:      viper.GetBool("references.use_memory_cache"),
+			MaxAudioBytes:       viper.GetInt64("references.max_audio_bytes"),
+			MinAudioDuration:    viper.GetDuration("references.min_audio_duration"),
+			MaxAudioDuration:    viper.GetDuration("references.max_audio_duration"),
+			AllowedFormats:      viper.GetStringSlice("references.allowed_formats"),
+			Transcode:           viper.GetBool("references.transcode"),
+		},
+		TTS: config.TTSConfig{
+			DefaultVoice: viper.GetString("tts.default_voice"),
+		},
+		Audio: config.AudioConfig{
+			Transcode:   viper.GetStringSlice("audio.transcode"),
+			MP3Bitrate:  viper.GetInt("audio.mp3_bitrate"),
+			OpusBitrate: viper.GetInt("audio.opus_bitrate"),
+		},
+		Cache: config.CacheConfig{
+			Enabled:      viper.GetBool("cache.enabled"),
+			TTL:          viper.GetDuration("cache.ttl"),
+			MaxEntries:   viper.GetInt("cache.max_entries"),
+			MaxBytes:     viper.GetInt64("cache.max_bytes"),
+			DiskDir:      viper.GetString("cache.disk_dir"),
+			DiskMaxBytes: viper.GetInt64("cache.disk_max_bytes"),
+			SpillBytes:   viper.GetInt64("cache.spill_bytes"),
+		},
+		Jobs: config.JobsConfig{
+			Enabled:          viper.GetBool("jobs.enabled"),
+			Workers:          viper.GetInt("jobs.workers"),
+			MaxQueued:        viper.GetInt("jobs.max_queued"),
+			BulkQueueRatio:   viper.GetFloat64("jobs.bulk_queue_ratio"),
+			Retention:        viper.GetDuration("jobs.retention"),
+			MaxRetainedBytes: viper.GetInt64("jobs.max_retained_bytes"),
+		},
+		Redis: config.RedisConfig{
+			URL:              viper.GetString("redis.url"),
+			Password:         viper.GetString("redis.password"),
+			KeyPrefix:        viper.GetString("redis.key_prefix"),
+			Timeout:          viper.GetDuration("redis.timeout"),
+			Cache:            viper.GetBool("redis.cache"),
+			RateLimits:       viper.GetBool("redis.rate_limits"),
+			ReferenceListTTL: viper.GetDuration("redis.reference_list_ttl"),
 		},
 		Logging: config.LoggingConfig{
 			Level:  viper.GetString("logging.level"),
 			Format: viper.GetString("logging.format"),
+			Access: config.AccessLogConfig{
+				Output: viper.GetString("logging.access.output"),
+				Format: viper.GetString("logging.access.format"),
+			},
+		},
+		Tracing: config.TracingConfig{
+			Enabled:       viper.GetBool("tracing.enabled"),
+			Endpoint:      viper.GetString("tracing.endpoint"),
+			Headers:       viper.GetStringMapString("tracing.headers"),
+			SampleRatio:   viper.GetFloat64("tracing.sample_ratio"),
+			SlowThreshold: viper.GetDuration("tracing.slow_threshold"),
+			ServiceName:   viper.GetString("tracing.service_name"),
+		},
+		Docs: config.DocsConfig{
+			Enabled:   viper.GetBool("docs.enabled"),
+			ScriptURL: viper.GetString("docs.script_url"),
+		},
+		Secrets: config.SecretsConfig{
+			Provider:        viper.GetString("secrets.provider"),
+			RefreshInterval: viper.GetDuration("secrets.refresh_interval"),
+			Vault: config.VaultConfig{
+				Address:   viper.GetString("secrets.vault.address"),
+				Mount:     viper.GetString("secrets.vault.mount"),
+				TokenFile: viper.GetString("secrets.vault.token_file"),
+			},
+			AWS: config.AWSConfig{
+				Region:   viper.GetString("secrets.aws.region"),
+				Endpoint: viper.GetString("secrets.aws.endpoint"),
+			},
 		},
 	}
 
+	if err := viper.UnmarshalKey("backend.routes", &cfg.Backend.Routes); err != nil {
+		return nil, fmt.Errorf("invalid backend.routes: %w", err)
+	}
+	if err := viper.UnmarshalKey("auth.keys", &cfg.Auth.Keys); err != nil {
+		return nil, fmt.Errorf("invalid auth.keys: %w", err)
+	}
+	if err := viper.UnmarshalKey("auth.basic.users", &cfg.Auth.Basic.Users); err != nil {
+		return nil, fmt.Errorf("invalid auth.basic.users: %w", err)
+	}
+	if err := viper.UnmarshalKey("auth.introspection.scopes", &cfg.Auth.Introspection.Scopes); err != nil {
+		return nil, fmt.Errorf("invalid auth.introspection.scopes: %w", err)
+	}
+	if err := viper.UnmarshalKey("auth.jwt.scopes", &cfg.Auth.JWT.Scopes); err != nil {
+		return nil, fmt.Errorf("invalid auth.jwt.scopes: %w", err)
+	}
+	if err := viper.UnmarshalKey("auth.signing.keys", &cfg.Auth.Signing.Keys); err != nil {
+		return nil, fmt.Errorf("invalid auth.signing.keys: %w", err)
+	}
+	if err := viper.UnmarshalKey("references.voices", &cfg.References.Voices); err != nil {
+		return nil, fmt.Errorf("invalid references.voices: %w", err)
+	}
+	if err := viper.UnmarshalKey("tracing.route_ratios", &cfg.Tracing.RouteRatios); err != nil {
+		return nil, fmt.Errorf("invalid tracing.route_ratios: %w", err)
+	}
+	if err := viper.UnmarshalKey("tts.presets", &cfg.TTS.Presets); err != nil {
+		return nil, fmt.Errorf("invalid tts.presets: %w", err)
+	}
+	if err := viper.UnmarshalKey("chaos", &cfg.Chaos); err != nil {
+		return nil, fmt.Errorf("invalid chaos config: %w", err)
+	}
+
 	if env := os.Getenv("FISH_LISTEN"); env != "" {
 		cfg.Server.Listen = env
 	}
@@ -141,6 +534,9 @@ func loadConfig(cmd *cobra.Command) (*config.Config, error) {
 			cfg.Limits.MaxTextLength = n
 		}
 	}
+	if env := os.Getenv("FISH_REFERENCE_STORE"); env != "" {
+		cfg.References.StorePath = env
+	}
 	if env := os.Getenv("FISH_LOG_LEVEL"); env != "" {
 		cfg.Logging.Level = env
 	}
@@ -157,15 +553,102 @@ func loadConfig(cmd *cobra.Command) (*config.Config, error) {
 	if cfg.Server.WriteTimeout == 0 {
 		cfg.Server.WriteTimeout = defaults.Server.WriteTimeout
 	}
+	if cfg.Server.StreamIdleTimeout == 0 {
+		cfg.Server.StreamIdleTimeout = defaults.Server.StreamIdleTimeout
+	}
+	if _, unix := sockaddr.UnixPath(cfg.Server.Listen); cfg.Server.HTTP3.Listen == "" && !unix {
+		cfg.Server.HTTP3.Listen = cfg.Server.Listen
+	}
 	if cfg.Backend.URL == "" {
 		cfg.Backend.URL = defaults.Backend.URL
 	}
+	if cfg.Backend.Balance == "" {
+		cfg.Backend.Balance = defaults.Backend.Balance
+	}
 	if cfg.Backend.Timeout == 0 {
 		cfg.Backend.Timeout = defaults.Backend.Timeout
 	}
 	if cfg.Backend.MaxConnections == 0 {
 		cfg.Backend.MaxConnections = defaults.Backend.MaxConnections
 	}
+	if cfg.Backend.StatsCacheTTL == 0 {
+		cfg.Backend.StatsCacheTTL = defaults.Backend.StatsCacheTTL
+	}
+	if cfg.Backend.Retry.MaxAttempts == 0 {
+		cfg.Backend.Retry.MaxAttempts = defaults.Backend.Retry.MaxAttempts
+	}
+	if cfg.Backend.Retry.Backoff == 0 {
+		cfg.Backend.Retry.Backoff = defaults.Backend.Retry.Backoff
+	}
+	if cfg.Backend.Retry.MaxBackoff == 0 {
+		cfg.Backend.Retry.MaxBackoff = defaults.Backend.Retry.MaxBackoff
+	}
+	if cfg.Auth.ReloadInterval == 0 {
+		cfg.Auth.ReloadInterval = defaults.Auth.ReloadInterval
+	}
+	if cfg.Auth.Basic.Realm == "" {
+		cfg.Auth.Basic.Realm = defaults.Auth.Basic.Realm
+	}
+	if cfg.Auth.Introspection.CacheTTL == 0 {
+		cfg.Auth.Introspection.CacheTTL = defaults.Auth.Introspection.CacheTTL
+	}
+	if cfg.Auth.Introspection.Timeout == 0 {
+		cfg.Auth.Introspection.Timeout = defaults.Auth.Introspection.Timeout
+	}
+	if cfg.References.UploadTTL == 0 {
+		cfg.References.UploadTTL = defaults.References.UploadTTL
+	}
+	if cfg.Auth.Signing.Window == 0 {
+		cfg.Auth.Signing.Window = defaults.Auth.Signing.Window
+	}
+	if cfg.Limits.BulkShedRatio == 0 {
+		cfg.Limits.BulkShedRatio = defaults.Limits.BulkShedRatio
+	}
+	if cfg.Jobs.BulkQueueRatio == 0 {
+		cfg.Jobs.BulkQueueRatio = defaults.Jobs.BulkQueueRatio
+	}
+	if cfg.Limits.AcquireTimeout == 0 {
+		cfg.Limits.AcquireTimeout = defaults.Limits.AcquireTimeout
+	}
+	if cfg.Limits.MinConcurrent == 0 {
+		cfg.Limits.MinConcurrent = defaults.Limits.MinConcurrent
+	}
+	if cfg.Limits.TargetLatency == 0 {
+		cfg.Limits.TargetLatency = defaults.Limits.TargetLatency
+	}
+	if cfg.References.Duplicates == "" {
+		cfg.References.Duplicates = defaults.References.Duplicates
+	}
+	if cfg.References.DuplicateThreshold == 0 {
+		cfg.References.DuplicateThreshold = defaults.References.DuplicateThreshold
+	}
+	if cfg.References.TranscriptThreshold == 0 {
+		cfg.References.TranscriptThreshold = defaults.References.TranscriptThreshold
+	}
+	if cfg.Secrets.RefreshInterval == 0 {
+		cfg.Secrets.RefreshInterval = defaults.Secrets.RefreshInterval
+	}
+	if cfg.Secrets.Vault.Mount == "" {
+		cfg.Secrets.Vault.Mount = defaults.Secrets.Vault.Mount
+	}
+	if cfg.Audio.MP3Bitrate == 0 {
+		cfg.Audio.MP3Bitrate = defaults.Audio.MP3Bitrate
+	}
+	if cfg.Audio.OpusBitrate == 0 {
+		cfg.Audio.OpusBitrate = defaults.Audio.OpusBitrate
+	}
+	if cfg.Redis.KeyPrefix == "" {
+		cfg.Redis.KeyPrefix = defaults.Redis.KeyPrefix
+	}
+	if cfg.Redis.Timeout == 0 {
+		cfg.Redis.Timeout = defaults.Redis.Timeout
+	}
+	if cfg.Docs.ScriptURL == "" {
+		cfg.Docs.ScriptURL = defaults.Docs.ScriptURL
+	}
+	if cfg.Tracing.ServiceName == "" {
+		cfg.Tracing.ServiceName = defaults.Tracing.ServiceName
+	}
 	if cfg.Logging.Level == "" {
 		cfg.Logging.Level = defaults.Logging.Level
 	}
@@ -221,9 +704,166 @@ func loadConfig(cmd *cobra.Command) (*config.Config, error) {
 		}
 	}
 
+	if (cfg.Server.TLSCertFile == "") != (cfg.Server.TLSKeyFile == "") {
+		return nil, errors.New("server.tls_cert_file and server.tls_key_file must be set together")
+	}
+	if cfg.Server.HTTP3.Enabled && !cfg.Server.TLSEnabled() {
+		return nil, errors.New("server.http3 requires server.tls_cert_file and server.tls_key_file")
+	}
+	if cfg.Server.HTTP3.Enabled && cfg.Server.HTTP3.Listen == "" {
+		return nil, errors.New("server.http3.listen is required when server.listen is a unix socket")
+	}
+	if path, ok := sockaddr.UnixPath(cfg.Server.Listen); ok && path == "" {
+		return nil, fmt.Errorf("server.listen names no socket path: %q", cfg.Server.Listen)
+	}
+	if cfg.Server.SocketMode != "" {
+		if _, err := strconv.ParseUint(cfg.Server.SocketMode, 8, 32); err != nil {
+			return nil, fmt.Errorf("server.socket_mode must be an octal file mode such as 0660, got %q", cfg.Server.SocketMode)
+		}
+	}
+	if path, ok := sockaddr.UnixPath(cfg.Backend.URL); ok && path == "" {
+		return nil, fmt.Errorf("backend.url names no socket path: %q", cfg.Backend.URL)
+	}
+	if cfg.Server.TLSClientCAFile != "" && !cfg.Server.TLSEnabled() {
+		return nil, errors.New("server.tls_client_ca_file requires server.tls_cert_file and server.tls_key_file")
+	}
+	switch cfg.Server.TLSClientAuth {
+	case "", certs.ClientAuthRequire, certs.ClientAuthOptional:
+	default:
+		return nil, fmt.Errorf("server.tls_client_auth: unknown mode %q (want require or optional)", cfg.Server.TLSClientAuth)
+	}
+	if cfg.Auth.APIKeyHash != "" {
+		if _, err := apikey.Parse(cfg.Auth.APIKeyHash); err != nil {
+			return nil, fmt.Errorf("auth.api_key_hash: %w", err)
+		}
+	}
+	if err := config.ValidateKeys(cfg.Auth.Keys); err != nil {
+		return nil, fmt.Errorf("auth.keys: %w", err)
+	}
+	if cfg.Auth.KeysFile != "" {
+		if _, err := config.LoadKeysFile(cfg.Auth.KeysFile); err != nil {
+			return nil, err
+		}
+	}
+	if err := api.ValidateBasicAuth(cfg.Auth.Basic); err != nil {
+		return nil, err
+	}
+	if raw := cfg.Auth.Introspection.URL; raw != "" {
+		if u, err := url.Parse(raw); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("auth.introspection.url must be an http(s) URL, got %q", raw)
+		}
+	}
+	for key, raw := range map[string]string{"auth.jwt.issuer": cfg.Auth.JWT.Issuer, "auth.jwt.jwks_url": cfg.Auth.JWT.JWKSURL} {
+		if raw == "" {
+			continue
+		}
+		if u, err := url.Parse(raw); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("%s must be an http(s) URL, got %q", key, raw)
+		}
+	}
+	for i, k := range cfg.Auth.Signing.Keys {
+		if k.ID == "" || len(k.Secret) < 16 {
+			return nil, fmt.Errorf("auth.signing.keys[%d]: id and a secret of at least 16 bytes are required", i)
+		}
+		if err := config.ValidateNamespace(k.Namespace); err != nil {
+			return nil, fmt.Errorf("auth.signing.keys[%d]: %w", i, err)
+		}
+	}
+	for _, format := range cfg.References.AllowedFormats {
+		switch format {
+		case audio.FormatWAV, audio.FormatMP3, audio.FormatFLAC, audio.FormatOGG:
+		case audio.FormatM4A:
+			// The backend cannot decode M4A, so it is only accepted transcoded.
+			if !cfg.References.Transcode || !audio.CanDecode(format) {
+				return nil, errors.New("references.allowed_formats: m4a needs references.transcode and a build with -tags fdkaac")
+			}
+		default:
+			return nil, fmt.Errorf("references.allowed_formats: unknown format %q (want wav, mp3, flac, ogg, or m4a)", format)
+		}
+	}
+	if err := api.ValidateVoices(cfg.References.Voices); err != nil {
+		return nil, err
+	}
+	if err := api.ValidateTTSDefaults(cfg.TTS); err != nil {
+		return nil, err
+	}
+	for _, format := range cfg.Audio.Transcode {
+		if format != audio.FormatMP3 {
+			return nil, fmt.Errorf("audio.transcode: unsupported format %q (want mp3)", format)
+		}
+		if !audio.CanEncode(format) {
+			return nil, fmt.Errorf("audio.transcode: this build has no %s encoder (build with -tags lame)", format)
+		}
+	}
+	if err := audio.CheckBitrate(audio.FormatMP3, cfg.Audio.MP3Bitrate); err != nil {
+		return nil, fmt.Errorf("audio.mp3_bitrate: %w", err)
+	}
+	if err := audio.CheckBitrate(audio.FormatOpus, cfg.Audio.OpusBitrate); err != nil {
+		return nil, fmt.Errorf("audio.opus_bitrate: %w", err)
+	}
+	if cfg.Backend.Retry.MaxAttempts < 0 {
+		return nil, errors.New("backend.retry.max_attempts must not be negative")
+	}
+	if !backend.ValidBalance(cfg.Backend.Balance) {
+		return nil, fmt.Errorf("backend.balance: unknown policy %q (want round_robin or least_connections)", cfg.Backend.Balance)
+	}
+	if f := cfg.Logging.Access.Format; f != "" && !api.ValidAccessLogFormat(f) {
+		return nil, fmt.Errorf("logging.access.format: unknown format %q (want json, text, common, or combined)", f)
+	}
+	if r := cfg.Tracing.SampleRatio; r < 0 || r > 1 {
+		return nil, fmt.Errorf("tracing.sample_ratio: must be between 0 and 1, got %v", r)
+	}
+	for route, r := range cfg.Tracing.RouteRatios {
+		if r < 0 || r > 1 {
+			return nil, fmt.Errorf("tracing.route_ratios: %s must be between 0 and 1, got %v", route, r)
+		}
+	}
+	if refs := cfg.References; refs.MaxAudioDuration > 0 && refs.MinAudioDuration > refs.MaxAudioDuration {
+		return nil, errors.New("references.min_audio_duration must not exceed references.max_audio_duration")
+	}
+	if len(cfg.Auth.SecretNames()) > 0 && cfg.Secrets.Provider == "" {
+		return nil, errors.New("auth.api_key_secret and auth.key_secrets require secrets.provider")
+	}
+	for _, path := range []string{cfg.Auth.APIKeyFile, cfg.Auth.KeyDir} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("invalid auth key source: %w", err)
+		}
+	}
+
+	cfg.Sources = configSources(cmd)
 	return cfg, nil
 }
 
+// configSources reports which source supplied each config key, following the
+// precedence loadConfig applies: flags, then environment, then the config file.
+func configSources(cmd *cobra.Command) map[string]config.Source {
+	sources := make(map[string]config.Source)
+	for _, key := range config.Keys() {
+		switch {
+		case cmd != nil && flagChanged(cmd, flagBindings[key]):
+			sources[key] = config.SourceFlag
+		case envBindings[key] != "" && os.Getenv(envBindings[key]) != "":
+			sources[key] = config.SourceEnv
+		case viper.InConfig(key):
+			sources[key] = config.SourceFile
+		default:
+			sources[key] = config.SourceDefault
+		}
+	}
+	return sources
+}
+
+func flagChanged(cmd *cobra.Command, name string) bool {
+	if name == "" {
+		return false
+	}
+	flag := cmd.Flags().Lookup(name)
+	return flag != nil && flag.Changed
+}
+
 func setupLogger(cfg config.LoggingConfig) zerolog.Logger {
 	level, err := zerolog.ParseLevel(cfg.Level)
 	if err != nil {
@@ -237,3 +877,28 @@ func setupLogger(cfg config.LoggingConfig) zerolog.Logger {
 
 	return zerolog.New(os.Stdout).With().Timestamp().Logger()
 }
+
+// setupAccessLog opens the access log configured by cfg.Access, returning the
+// file to close on shutdown when it writes to one. It returns a nil log when
+// access logging is off.
+func setupAccessLog(cfg config.LoggingConfig) (*api.AccessLog, *os.File, error) {
+	format := cfg.Access.Format
+	if format == "" {
+		format = cfg.Format
+	}
+
+	switch cfg.Access.Output {
+	case "off":
+		return nil, nil, nil
+	case "", "stdout":
+		return api.NewAccessLog(os.Stdout, format), nil, nil
+	case "stderr":
+		return api.NewAccessLog(os.Stderr, format), nil, nil
+	}
+
+	f, err := os.OpenFile(cfg.Access.Output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open access log: %w", err)
+	}
+	return api.NewAccessLog(f, format), f, nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/audio"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+// similarityBins is the envelope resolution used to compare waveforms.
+const similarityBins = 200
+
+var compareCmd = &cobra.Command{
+	Use:   "compare [text]",
+	Short: "Synthesize the same request on two servers and compare the results",
+	Long: `Compare sends the same TTS request, with a fixed seed, to two servers one
+after the other, saves both outputs, and reports latency and size. With
+--similarity it also compares the loudness envelopes of the two WAV outputs
+(1.0 means identical).
+
+Example:
+  fish-tts compare --server-a http://localhost:8080 --server-b http://localhost:8081 \
+    --similarity "Hello, world!"`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCompare,
+}
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+
+	compareCmd.Flags().String("server-a", "", "First server URL")
+	compareCmd.Flags().String("server-b", "", "Second server URL")
+	compareCmd.Flags().String("api-key-a", "", "API key for the first server")
+	compareCmd.Flags().String("api-key-b", "", "API key for the second server")
+	compareCmd.Flags().String("prefix", "compare", "Outputs are saved as <prefix>-a.<format> and <prefix>-b.<format>")
+	compareCmd.Flags().StringP("format", "f", "wav", "Audio format: wav, mp3, pcm")
+	compareCmd.Flags().String("reference-id", "", "Reference voice known to both servers")
+	compareCmd.Flags().Int("seed", 42, "Seed sent to both servers")
+	compareCmd.Flags().Float64("temperature", 0.8, "Generation temperature (0.1-1.0)")
+	compareCmd.Flags().Float64("top-p", 0.8, "Top-p sampling (0.1-1.0)")
+	compareCmd.Flags().Bool("similarity", false, "Report waveform similarity (WAV only)")
+	_ = compareCmd.MarkFlagRequired("server-a")
+	_ = compareCmd.MarkFlagRequired("server-b")
+}
+
+// compareResult is the outcome of the request on one server.
+type compareResult struct {
+	Server  string
+	Output  string
+	Latency time.Duration
+	Audio   []byte
+	Err     error
+}
+
+func runCompare(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+	format, _ := flags.GetString("format")
+	prefix, _ := flags.GetString("prefix")
+	withSimilarity, _ := flags.GetBool("similarity")
+	if withSimilarity && format != "wav" {
+		return errors.New("--similarity needs --format wav")
+	}
+
+	seed, _ := flags.GetInt("seed")
+	temperature, _ := flags.GetFloat64("temperature")
+	topP, _ := flags.GetFloat64("top-p")
+	req := schema.ServeTTSRequest{
+		Text:        args[0],
+		Format:      format,
+		Seed:        &seed,
+		Temperature: temperature,
+		TopP:        topP,
+	}
+	if id, _ := flags.GetString("reference-id"); id != "" {
+		req.ReferenceID = &id
+	}
+
+	results := make([]compareResult, 0, 2)
+	for _, side := range []string{"a", "b"} {
+		server, _ := flags.GetString("server-" + side)
+		key, _ := flags.GetString("api-key-" + side)
+		result := compareResult{
+			Server: strings.TrimSuffix(server, "/"),
+			Output: fmt.Sprintf("%s-%s.%s", prefix, side, format),
+		}
+
+		start := time.Now()
+		result.Audio, result.Err = postTTS(result.Server, key, &req)
+		result.Latency = time.Since(start)
+		if result.Err == nil {
+			if err := os.WriteFile(result.Output, result.Audio, 0o644); err != nil {
+				return fmt.Errorf("failed to write output file: %w", err)
+			}
+		}
+		results = append(results, result)
+	}
+
+	printComparison(cmd.OutOrStdout(), results[0], results[1], withSimilarity)
+	for _, r := range results {
+		if r.Err != nil {
+			return fmt.Errorf("%s: %w", r.Server, r.Err)
+		}
+	}
+	return nil
+}
+
+func printComparison(w io.Writer, a, b compareResult, withSimilarity bool) {
+	row := func(label, va, vb string) {
+		fmt.Fprintf(w, "%-10s %-32s %s\n", label, va, vb)
+	}
+	column := func(r compareResult, value func(compareResult) string) string {
+		if r.Err != nil {
+			return "-"
+		}
+		return value(r)
+	}
+
+	row("", "A", "B")
+	row("Server", a.Server, b.Server)
+	latency := func(r compareResult) string { return r.Latency.Round(time.Millisecond).String() }
+	row("Latency", latency(a), latency(b))
+	size := func(r compareResult) string { return fmt.Sprintf("%d bytes", len(r.Audio)) }
+	row("Size", column(a, size), column(b, size))
+	duration := func(r compareResult) string {
+		if pcm, err := audio.DecodeWAV(r.Audio); err == nil {
+			return pcm.Duration().Round(time.Millisecond).String()
+		}
+		return "-"
+	}
+	row("Duration", column(a, duration), column(b, duration))
+	output := func(r compareResult) string { return r.Output }
+	row("Output", column(a, output), column(b, output))
+	for _, r := range []compareResult{a, b} {
+		if r.Err != nil {
+			fmt.Fprintf(w, "Error from %s: %v\n", r.Server, r.Err)
+		}
+	}
+
+	if !withSimilarity || a.Err != nil || b.Err != nil {
+		return
+	}
+	similarity, err := waveformSimilarity(a.Audio, b.Audio)
+	if err != nil {
+		fmt.Fprintf(w, "Waveform similarity: unavailable (%v)\n", err)
+		return
+	}
+	fmt.Fprintf(w, "Waveform similarity: %.3f\n", similarity)
+}
+
+// waveformSimilarity compares the loudness envelopes of two WAV files. It is
+// insensitive to sample rate and format, but not to timing differences.
+func waveformSimilarity(a, b []byte) (float64, error) {
+	pa, err := audio.DecodeWAV(a)
+	if err != nil {
+		return 0, fmt.Errorf("output A: %w", err)
+	}
+	pb, err := audio.DecodeWAV(b)
+	if err != nil {
+		return 0, fmt.Errorf("output B: %w", err)
+	}
+	return audio.CosineSimilarity(audio.Envelope(pa, similarityBins), audio.Envelope(pb, similarityBins)), nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/audio"
+)
+
+// toneWAV returns a WAV file of a 440 Hz tone fading in over its length.
+func toneWAV(sampleRate int, d time.Duration) []byte {
+	frames := int(d.Seconds() * float64(sampleRate))
+	samples := make([]float32, frames)
+	for i := range samples {
+		t := float64(i) / float64(sampleRate)
+		samples[i] = float32(float64(i) / float64(frames) * math.Sin(2*math.Pi*440*t))
+	}
+	return audio.EncodeWAV(&audio.PCM{SampleRate: sampleRate, Channels: 1, Samples: samples})
+}
+
+func TestWaveformSimilarity(t *testing.T) {
+	similarity, err := waveformSimilarity(toneWAV(44100, time.Second), toneWAV(24000, time.Second))
+	require.NoError(t, err)
+	assert.Greater(t, similarity, 0.99)
+
+	silence := audio.EncodeWAV(&audio.PCM{SampleRate: 24000, Channels: 1, Samples: make([]float32, 24000)})
+	similarity, err = waveformSimilarity(toneWAV(24000, time.Second), silence)
+	require.NoError(t, err)
+	assert.Zero(t, similarity)
+
+	_, err = waveformSimilarity([]byte("ID3 mp3"), silence)
+	assert.ErrorContains(t, err, "output A")
+}
+
+func TestPrintComparison(t *testing.T) {
+	wav := toneWAV(24000, time.Second)
+	a := compareResult{Server: "http://a", Output: "compare-a.wav", Latency: 1500 * time.Millisecond, Audio: wav}
+	b := compareResult{Server: "http://b", Output: "compare-b.wav", Latency: 2 * time.Second, Audio: wav}
+
+	var out strings.Builder
+	printComparison(&out, a, b, true)
+	assert.Contains(t, out.String(), "1.5s")
+	assert.Contains(t, out.String(), "compare-b.wav")
+	assert.Contains(t, out.String(), "Waveform similarity: 1.000")
+
+	b.Err = errors.New("server error (status 500)")
+	out.Reset()
+	printComparison(&out, a, b, true)
+	assert.Contains(t, out.String(), "Error from http://b")
+	assert.NotContains(t, out.String(), "Waveform similarity")
+}
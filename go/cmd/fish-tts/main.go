@@ -47,7 +47,10 @@ Examples:
   fish-tts --reference voice.wav --reference-text "Sample text" "Hello in cloned voice"
 
   # Adjust generation parameters
-  fish-tts --temperature 0.7 --top-p 0.9 "Hello, world!"`,
+  fish-tts --temperature 0.7 --top-p 0.9 "Hello, world!"
+
+  # Compare two servers on the same request
+  fish-tts compare --server-a http://localhost:8080 --server-b http://localhost:8081 "Hello, world!"`,
 	Args: cobra.MinimumNArgs(1),
 	RunE: runTTS,
 }
@@ -116,6 +119,11 @@ func runTTS(cmd *cobra.Command, args []string) error {
 }
 
 func makeTTSRequest(req *schema.ServeTTSRequest) ([]byte, error) {
+	return postTTS(serverURL, apiKey, req)
+}
+
+// postTTS sends req to the /v1/tts endpoint of server and returns the audio.
+func postTTS(server, key string, req *schema.ServeTTSRequest) ([]byte, error) {
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to encode request: %w", err)
@@ -124,14 +132,14 @@ func makeTTSRequest(req *schema.ServeTTSRequest) ([]byte, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
 	defer cancel()
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, serverURL+"/v1/tts", bytes.NewReader(body))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, server+"/v1/tts", bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
-	if apiKey != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	if key != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+key)
 	}
 
 	client := &http.Client{Timeout: 120 * time.Second}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Access log formats.
+const (
+	AccessLogJSON     = "json"
+	AccessLogText     = "text"
+	AccessLogCommon   = "common"
+	AccessLogCombined = "combined"
+)
+
+// clfTime is the timestamp layout of the NCSA Common Log Format.
+const clfTime = "02/Jan/2006:15:04:05 -0700"
+
+// AccessLog writes one line per request. The json and text formats are
+// zerolog events; common and combined are NCSA Common and Combined Log
+// Format lines as written by Apache and nginx.
+type AccessLog struct {
+	format string
+	logger zerolog.Logger
+
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// NewAccessLog returns an access log writing to out in format, which is one of
+// the AccessLog* constants. Unknown formats are treated as json.
+func NewAccessLog(out io.Writer, format string) *AccessLog {
+	l := &AccessLog{format: format, out: out}
+	switch format {
+	case AccessLogCommon, AccessLogCombined:
+	case AccessLogText:
+		l.logger = zerolog.New(zerolog.ConsoleWriter{Out: out}).With().Timestamp().Logger()
+	default:
+		l.format = AccessLogJSON
+		l.logger = zerolog.New(out).With().Timestamp().Logger()
+	}
+	return l
+}
+
+// ValidAccessLogFormat reports whether format names an access log format.
+func ValidAccessLogFormat(format string) bool {
+	switch format {
+	case AccessLogJSON, AccessLogText, AccessLogCommon, AccessLogCombined:
+		return true
+	}
+	return false
+}
+
+// accessRecord is what the access log knows about a finished request.
+type accessRecord struct {
+	start    time.Time
+	status   int
+	bytes    int64
+	duration time.Duration
+	fields   *logFields
+}
+
+func (l *AccessLog) log(r *http.Request, rec accessRecord) {
+	switch l.format {
+	case AccessLogCommon, AccessLogCombined:
+		l.writeCLF(r, rec)
+		return
+	}
+
+	event := l.logger.Info().
+		Str("event", EventHTTPRequest).
+		Str("request_id", requestIDFromContext(r.Context())).
+		Str("method", r.Method).
+		Str("path", r.URL.Path).
+		Int("status", rec.status).
+		Int64("bytes", rec.bytes).
+		Float64("duration_ms", durationMS(rec.duration))
+	if rec.fields.keyPrefix != "" {
+		event = event.Str("key_prefix", rec.fields.keyPrefix)
+	}
+	if rec.fields.user != "" {
+		event = event.Str("user", rec.fields.user)
+	}
+	event.Msg("request")
+}
+
+// writeCLF writes a Common Log Format line, followed by the referer and user
+// agent in the combined format. The authenticated user, or else the API key
+// prefix, fills the authuser field.
+func (l *AccessLog) writeCLF(r *http.Request, rec accessRecord) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	user := rec.fields.user
+	if user == "" {
+		user = rec.fields.keyPrefix
+	}
+	size := "-"
+	if rec.bytes > 0 {
+		size = strconv.FormatInt(rec.bytes, 10)
+	}
+
+	line := fmt.Sprintf("%s - %s [%s] %s %d %s",
+		clfField(host),
+		clfField(user),
+		rec.start.Format(clfTime),
+		strconv.Quote(r.Method+" "+r.RequestURI+" "+r.Proto),
+		rec.status,
+		size,
+	)
+	if l.format == AccessLogCombined {
+		line += " " + clfQuoted(r.Referer()) + " " + clfQuoted(r.UserAgent())
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = io.WriteString(l.out, line+"\n")
+}
+
+// clfField returns value, or "-" when it is empty.
+func clfField(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// clfQuoted returns value quoted and escaped, or "-" when it is empty.
+func clfQuoted(value string) string {
+	if value == "" {
+		return `"-"`
+	}
+	return strconv.Quote(value)
+}
+
+// AccessLogMiddleware writes a line to l for every request. A nil l disables
+// access logging.
+func AccessLogMiddleware(l *AccessLog) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if l == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			fields := &logFields{}
+
+			next.ServeHTTP(rw, r.WithContext(withLogFields(r.Context(), fields)))
+
+			l.log(r, accessRecord{
+				start:    start,
+				status:   rw.status,
+				bytes:    rw.bytes,
+				duration: time.Since(start),
+				fields:   fields,
+			})
+		})
+	}
+}
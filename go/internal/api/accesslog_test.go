@@ -0,0 +1,68 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveLogged(l *AccessLog, req *http.Request) {
+	handler := AccessLogMiddleware(l)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		setLogPrincipal(r.Context(), &Principal{KeyPrefix: "fsk_abcd"})
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestAccessLog_Combined(t *testing.T) {
+	var out bytes.Buffer
+	req := httptest.NewRequest(http.MethodPost, "/v1/tts?x=1", nil)
+	req.RemoteAddr = "192.0.2.7:51234"
+	req.Header.Set("Referer", "https://example.com/")
+	req.Header.Set("User-Agent", `curl/8.0 "test"`)
+
+	serveLogged(NewAccessLog(&out, AccessLogCombined), req)
+
+	pattern := `^192\.0\.2\.7 - fsk_abcd \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "POST /v1/tts\?x=1 HTTP/1\.1" 201 5 "https://example\.com/" "curl/8\.0 \\"test\\""\n$`
+	assert.Regexp(t, regexp.MustCompile(pattern), out.String())
+}
+
+func TestAccessLog_Common(t *testing.T) {
+	var out bytes.Buffer
+	req := httptest.NewRequest(http.MethodGet, "/v1/health", nil)
+	req.Header.Set("User-Agent", "curl/8.0")
+
+	serveLogged(NewAccessLog(&out, AccessLogCommon), req)
+
+	assert.Regexp(t, `"GET /v1/health HTTP/1\.1" 201 5\n$`, out.String())
+	assert.NotContains(t, out.String(), "curl")
+}
+
+func TestAccessLog_JSON(t *testing.T) {
+	var out bytes.Buffer
+	serveLogged(NewAccessLog(&out, AccessLogJSON), httptest.NewRequest(http.MethodGet, "/v1/health", nil))
+
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &line))
+	assert.Equal(t, "request", line["message"])
+	assert.Equal(t, "/v1/health", line["path"])
+	assert.Equal(t, float64(http.StatusCreated), line["status"])
+	assert.Equal(t, float64(5), line["bytes"])
+	assert.Equal(t, "fsk_abcd", line["key_prefix"])
+}
+
+func TestAccessLog_Disabled(t *testing.T) {
+	called := false
+	handler := AccessLogMiddleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.True(t, called)
+}
@@ -0,0 +1,14 @@
+package api
+
+import "net/http"
+
+// AdminMiddleware rejects callers without the admin role.
+func AdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isAdminRequest(r.Context()) {
+			WriteError(w, http.StatusForbidden, "Admin endpoints require an admin key")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
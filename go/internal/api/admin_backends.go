@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/backend"
+)
+
+// BackendTargetStatus reports the routing weight and traffic of one backend target.
+type BackendTargetStatus struct {
+	Name         string  `json:"name"`
+	Weight       int     `json:"weight"`
+	Requests     uint64  `json:"requests"`
+	Errors       uint64  `json:"errors"`
+	ErrorRate    float64 `json:"error_rate"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	QueueDepth   int64   `json:"queue_depth"`
+}
+
+// ListBackendsResponse lists the backend routing targets.
+type ListBackendsResponse struct {
+	Backends []BackendTargetStatus `json:"backends"`
+}
+
+// SetBackendWeightRequest changes the canary weight of a route.
+type SetBackendWeightRequest struct {
+	Weight *int `json:"weight"`
+}
+
+// HandleListBackends returns per-target routing statistics.
+func (h *Handler) HandleListBackends(w http.ResponseWriter, r *http.Request) {
+	router, ok := h.backend.(*backend.Router)
+	if !ok {
+		WriteError(w, http.StatusNotFound, "Backend routing is not configured")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, ListBackendsResponse{Backends: backendStatuses(router)})
+}
+
+// HandleSetBackendWeight adjusts the share of default traffic diverted to a
+// canary route. A weight of zero acts as a kill switch.
+func (h *Handler) HandleSetBackendWeight(w http.ResponseWriter, r *http.Request) {
+	router, ok := h.backend.(*backend.Router)
+	if !ok {
+		WriteError(w, http.StatusNotFound, "Backend routing is not configured")
+		return
+	}
+
+	var req SetBackendWeightRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Weight == nil {
+		WriteError(w, http.StatusBadRequest, "weight is required")
+		return
+	}
+
+	name := chi.URLParam(r, "name")
+	if err := router.SetWeight(name, *req.Weight); err != nil {
+		if errors.Is(err, backend.ErrUnknownTarget) {
+			WriteError(w, http.StatusNotFound, "Backend not found")
+			return
+		}
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.logger.Info().Str("backend", name).Int("weight", *req.Weight).Msg("Backend weight changed")
+	WriteJSON(w, http.StatusOK, ListBackendsResponse{Backends: backendStatuses(router)})
+}
+
+func backendStatuses(router *backend.Router) []BackendTargetStatus {
+	stats := router.Stats()
+	statuses := make([]BackendTargetStatus, 0, len(stats))
+	for _, s := range stats {
+		status := BackendTargetStatus{
+			Name:         s.Name,
+			Weight:       s.Weight,
+			Requests:     s.Requests,
+			Errors:       s.Errors,
+			AvgLatencyMs: float64(s.AvgLatency.Microseconds()) / 1000,
+			QueueDepth:   s.QueueDepth,
+		}
+		if s.Requests > 0 {
+			status.ErrorRate = float64(s.Errors) / float64(s.Requests)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
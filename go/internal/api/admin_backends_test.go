@@ -0,0 +1,63 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/backend"
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+func TestAdminBackends(t *testing.T) {
+	backends, err := backend.NewRouter(&mockBackend{}, "", backend.Route{Name: "canary", Weight: 5, Backend: &mockBackend{}})
+	require.NoError(t, err)
+
+	cfg := testConfig()
+	cfg.Auth = config.AuthConfig{Keys: []config.APIKeyConfig{
+		{Key: "user-key"},
+		{Key: "admin-key", Role: RoleAdmin},
+	}}
+	router := NewRouter(cfg, backends, testLogger())
+
+	do := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/admin/backends", "user-key", "").Code)
+
+	w := do(http.MethodGet, "/admin/backends", "admin-key", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp ListBackendsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Backends, 2)
+	assert.Equal(t, "canary", resp.Backends[1].Name)
+	assert.Equal(t, 5, resp.Backends[1].Weight)
+
+	w = do(http.MethodPut, "/admin/backends/canary/weight", "admin-key", `{"weight": 0}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 0, resp.Backends[1].Weight)
+
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPut, "/admin/backends/missing/weight", "admin-key", `{"weight": 1}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/admin/backends/canary/weight", "admin-key", `{}`).Code)
+}
+
+func TestAdminBackends_NotConfigured(t *testing.T) {
+	router := NewRouter(testConfig(), &mockBackend{}, testLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/backends", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, asAdmin(req))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,18 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+// ConfigResponse lists the effective configuration.
+type ConfigResponse struct {
+	Settings []config.Setting `json:"settings"`
+}
+
+// HandleGetConfig returns the merged configuration the server is running with,
+// with secrets redacted and the source of each value annotated.
+func (h *Handler) HandleGetConfig(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, ConfigResponse{Settings: h.config.Dump()})
+}
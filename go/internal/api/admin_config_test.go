@@ -0,0 +1,56 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+func TestAdminConfig(t *testing.T) {
+	cfg := testConfig()
+	cfg.Auth = config.AuthConfig{APIKey: "secret-key", Keys: []config.APIKeyConfig{
+		{Key: "user-key"},
+		{Key: "admin-key", Role: RoleAdmin},
+	}}
+	cfg.Sources = map[string]config.Source{"limits.max_text_length": config.SourceFlag}
+	router := NewRouter(cfg, &mockBackend{}, testLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/config", nil)
+	req.Header.Set("Authorization", "Bearer admin-key")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "secret-key")
+	assert.NotContains(t, w.Body.String(), "admin-key")
+
+	var resp ConfigResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	settings := map[string]config.Setting{}
+	for _, s := range resp.Settings {
+		settings[s.Key] = s
+	}
+
+	assert.Equal(t, config.Redacted, settings["auth.api_key"].Value)
+	assert.Equal(t, float64(10000), settings["limits.max_text_length"].Value)
+	assert.Equal(t, config.SourceFlag, settings["limits.max_text_length"].Source)
+	assert.Equal(t, config.SourceDefault, settings["server.listen"].Source)
+	keys, ok := settings["auth.keys"].Value.([]interface{})
+	require.True(t, ok)
+	require.Len(t, keys, 2)
+	for i, role := range []string{"", RoleAdmin} {
+		key := keys[i].(map[string]interface{})
+		assert.Equal(t, config.Redacted, key["key"])
+		assert.Equal(t, role, key["role"])
+	}
+
+	req.Header.Set("Authorization", "Bearer user-key")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/refstore"
+)
+
+// ReferenceAlias maps a stable alias to the reference ID it currently resolves to.
+type ReferenceAlias struct {
+	Alias       string `json:"alias"`
+	ReferenceID string `json:"reference_id"`
+}
+
+// ListAliasesResponse is returned by GET /v1/references/aliases.
+type ListAliasesResponse struct {
+	Success bool             `json:"success"`
+	Aliases []ReferenceAlias `json:"aliases"`
+	Message string           `json:"message"`
+}
+
+// AliasResponse is returned when an alias is created, updated, or deleted.
+type AliasResponse struct {
+	Success     bool   `json:"success"`
+	Message     string `json:"message"`
+	Alias       string `json:"alias"`
+	ReferenceID string `json:"reference_id,omitempty"`
+}
+
+// HandleListAliases lists the aliases visible to the caller.
+func (h *Handler) HandleListAliases(w http.ResponseWriter, r *http.Request) {
+	namespace := namespaceFromContext(r.Context())
+
+	aliases := []ReferenceAlias{}
+	for _, a := range h.refs.Aliases() {
+		if ids := filterNamespace(namespace, []string{a.Name}); len(ids) == 1 {
+			aliases = append(aliases, ReferenceAlias{Alias: ids[0], ReferenceID: unscopeReferenceID(namespace, a.Target)})
+		}
+	}
+
+	WriteJSON(w, http.StatusOK, ListAliasesResponse{Success: true, Aliases: aliases, Message: "Success"})
+}
+
+// HandleSetAlias creates or repoints an alias at an existing reference.
+func (h *Handler) HandleSetAlias(w http.ResponseWriter, r *http.Request) {
+	var req ReferenceAlias
+	if err := ParseRequestBody(r, &req); err != nil {
+		h.handleParseError(w, err)
+		return
+	}
+
+	if err := validateReferenceID("alias", req.Alias); err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateReferenceID("reference_id", req.ReferenceID); err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Alias == req.ReferenceID {
+		WriteError(w, http.StatusBadRequest, "alias must differ from reference_id")
+		return
+	}
+
+	namespace := namespaceFromContext(r.Context())
+	alias := scopeReferenceID(namespace, req.Alias)
+	target := scopeReferenceID(namespace, req.ReferenceID)
+
+	if _, ok := h.refs.Alias(target); ok {
+		WriteError(w, http.StatusBadRequest, "reference_id must not be an alias")
+		return
+	}
+
+	start := time.Now()
+	refs, err := h.backend.ListReferences(r.Context())
+	h.logBackendCall(r.Context(), OpListReferences, start, err)
+	if err != nil {
+		h.handleBackendError(w, err)
+		return
+	}
+	if !containsString(refs.ReferenceIDs, target) {
+		WriteError(w, http.StatusNotFound, "Reference not found")
+		return
+	}
+	if containsString(refs.ReferenceIDs, alias) {
+		// The alias would hide the reference of the same ID from TTS requests.
+		WriteError(w, http.StatusConflict, "alias is already a reference ID")
+		return
+	}
+
+	if err := h.refs.SetAlias(alias, target); err != nil {
+		h.logger.Error().Err(err).Msg("Set alias error")
+		WriteError(w, http.StatusInternalServerError, "Failed to save alias")
+		return
+	}
+
+	h.referenceMutation(r.Context(), ActionSetAlias, target).Str("alias", alias).Msg("Reference alias updated")
+	WriteJSON(w, http.StatusOK, AliasResponse{Success: true, Message: "Alias saved successfully", Alias: req.Alias, ReferenceID: req.ReferenceID})
+}
+
+// HandleDeleteAlias removes an alias without touching the reference it points at.
+func (h *Handler) HandleDeleteAlias(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "alias")
+	if name == "" {
+		WriteError(w, http.StatusBadRequest, "Alias required")
+		return
+	}
+
+	alias := scopeReferenceID(namespaceFromContext(r.Context()), name)
+	if err := h.refs.DeleteAlias(alias); err != nil {
+		if errors.Is(err, refstore.ErrNotFound) {
+			WriteError(w, http.StatusNotFound, "Alias not found")
+			return
+		}
+		h.logger.Error().Err(err).Msg("Delete alias error")
+		WriteError(w, http.StatusInternalServerError, "Failed to delete alias")
+		return
+	}
+	h.referenceMutation(r.Context(), ActionDeleteAlias, "").Str("alias", alias).Msg("Reference alias deleted")
+
+	WriteJSON(w, http.StatusOK, AliasResponse{Success: true, Message: "Alias deleted successfully", Alias: name})
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func TestAliases_ResolvedInTTS(t *testing.T) {
+	mock := &mockBackend{
+		ttsResponse: []byte("audio"),
+		listRefResp: &schema.ListReferencesResponse{Success: true, ReferenceIDs: []string{"voice-v3-2024"}},
+	}
+	router := NewRouter(testConfig(), mock, testLogger())
+
+	body, _ := json.Marshal(ReferenceAlias{Alias: "narrator-prod", ReferenceID: "voice-v3-2024"})
+	req := httptest.NewRequest(http.MethodPost, "/v1/references/aliases", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/references/aliases", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var list ListAliasesResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, []ReferenceAlias{{Alias: "narrator-prod", ReferenceID: "voice-v3-2024"}}, list.Aliases)
+
+	body, _ = json.Marshal(map[string]interface{}{"text": "hi", "reference_id": "narrator-prod"})
+	req = httptest.NewRequest(http.MethodPost, "/v1/tts", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "voice-v3-2024", *mock.lastTTSReq.ReferenceID)
+
+	req = httptest.NewRequest(http.MethodDelete, "/v1/references/aliases/narrator-prod", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest(http.MethodDelete, "/v1/references/aliases/narrator-prod", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAliases_UnknownReference(t *testing.T) {
+	mock := &mockBackend{listRefResp: &schema.ListReferencesResponse{Success: true, ReferenceIDs: []string{}}}
+	h := NewHandler(mock, testConfig(), testLogger())
+
+	body, _ := json.Marshal(ReferenceAlias{Alias: "narrator", ReferenceID: "missing"})
+	req := httptest.NewRequest(http.MethodPost, "/v1/references/aliases", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	h.HandleSetAlias(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAliases_ConflictWithReferenceID(t *testing.T) {
+	mock := &mockBackend{
+		listRefResp: &schema.ListReferencesResponse{Success: true, ReferenceIDs: []string{"voice-v2", "voice-v3"}},
+		addRefResp:  &schema.AddReferenceResponse{Success: true, ReferenceID: "narrator"},
+	}
+	h := NewHandler(mock, testConfig(), testLogger())
+
+	setAlias := func(alias, target string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(ReferenceAlias{Alias: alias, ReferenceID: target})
+		req := httptest.NewRequest(http.MethodPost, "/v1/references/aliases", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.HandleSetAlias(w, req)
+		return w
+	}
+	w := setAlias("voice-v2", "voice-v3")
+	assert.Equal(t, http.StatusConflict, w.Code, "an alias must not shadow a reference")
+	assert.Contains(t, w.Body.String(), "alias is already a reference ID")
+
+	require.Equal(t, http.StatusOK, setAlias("narrator", "voice-v3").Code)
+	body, _ := json.Marshal(schema.AddReferenceRequest{ID: "narrator", Audio: []byte("fake audio data"), Text: "Hello"})
+	req := httptest.NewRequest(http.MethodPost, "/v1/references/add", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	h.HandleAddReference(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code, "a reference must not be hidden by an alias")
+	assert.Contains(t, w.Body.String(), "reference_id is already an alias")
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/apikey"
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+// basicUser is an HTTP Basic user whose password is held only as a hash.
+type basicUser struct {
+	verify    func(password string) bool
+	principal *Principal
+}
+
+// errUnsupportedHash is returned for htpasswd entries in a format other than
+// bcrypt or {SHA}.
+var errUnsupportedHash = errors.New("unsupported password hash (use bcrypt, e.g. htpasswd -B)")
+
+// passwordVerifier returns a constant-time check of password against hash,
+// which is a bcrypt hash, an htpasswd {SHA} digest, or when plain is set a
+// plain password that is salted and hashed here.
+func passwordVerifier(hash string, plain bool) (func(string) bool, error) {
+	switch {
+	case strings.HasPrefix(hash, "$2"):
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("invalid bcrypt hash: %w", err)
+		}
+		return func(password string) bool {
+			return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+		}, nil
+	case strings.HasPrefix(hash, "{SHA}"):
+		want, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(hash, "{SHA}"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid {SHA} hash: %w", err)
+		}
+		return func(password string) bool {
+			got := sha1.Sum([]byte(password))
+			return subtle.ConstantTimeCompare(got[:], want) == 1
+		}, nil
+	case plain:
+		h, err := apikey.New(hash)
+		if err != nil {
+			return nil, err
+		}
+		return h.Matches, nil
+	default:
+		return nil, errUnsupportedHash
+	}
+}
+
+// configUsers converts the Basic users from config. Invalid entries are skipped;
+// loadConfig rejects them at startup.
+func configUsers(users []config.BasicUserConfig) map[string]basicUser {
+	out := make(map[string]basicUser, len(users))
+	for _, u := range users {
+		verify, err := passwordVerifier(u.Password, true)
+		if err != nil || u.Username == "" {
+			continue
+		}
+		out[u.Username] = basicUser{verify: verify, principal: &Principal{
+			Namespace: u.Namespace, Role: u.Role, Username: u.Username,
+		}}
+	}
+	return out
+}
+
+// ValidateBasicAuth checks the configured Basic users and htpasswd file.
+func ValidateBasicAuth(cfg config.BasicAuthConfig) error {
+	for i, u := range cfg.Users {
+		if u.Username == "" {
+			return fmt.Errorf("auth.basic.users[%d]: username is required", i)
+		}
+		if _, err := passwordVerifier(u.Password, true); err != nil {
+			return fmt.Errorf("auth.basic.users[%d]: %w", i, err)
+		}
+		if err := config.ValidateNamespace(u.Namespace); err != nil {
+			return fmt.Errorf("auth.basic.users[%d]: %w", i, err)
+		}
+	}
+	if cfg.HtpasswdFile != "" {
+		if _, err := loadHtpasswd(cfg.HtpasswdFile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadHtpasswd reads an htpasswd file of "user:hash" lines. Users from it are
+// regular, unscoped principals.
+func loadHtpasswd(path string) (map[string]basicUser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read htpasswd file: %w", err)
+	}
+	defer f.Close()
+
+	users := make(map[string]basicUser)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		name, hash, ok := strings.Cut(text, ":")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%s:%d: expected user:hash", path, line)
+		}
+		verify, err := passwordVerifier(hash, false)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		users[name] = basicUser{verify: verify, principal: &Principal{Username: name}}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read htpasswd file: %w", err)
+	}
+	return users, nil
+}
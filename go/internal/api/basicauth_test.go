@@ -0,0 +1,106 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+func TestKeyAuth_Basic(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("file-pass"), bcrypt.MinCost)
+	require.NoError(t, err)
+	htpasswd := filepath.Join(t.TempDir(), "htpasswd")
+	require.NoError(t, os.WriteFile(htpasswd, []byte("# users\nalice:"+string(hash)+"\n"+
+		// "sha-pass" hashed with htpasswd -s.
+		"bob:{SHA}xO2etOilyqtV8o1RvvnmkeBx7QI=\n"), 0o600))
+
+	cfg := config.AuthConfig{
+		APIKey: "admin-key",
+		Basic: config.BasicAuthConfig{
+			Users: []config.BasicUserConfig{
+				{Username: "tenant", Password: "tenant-pass", Namespace: "acme"},
+			},
+			HtpasswdFile: htpasswd,
+			Realm:        "fish-speech",
+		},
+	}
+	var principal *Principal
+	handler := KeyAuthMiddleware(cfg, nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal = PrincipalFromContext(r.Context())
+		}))
+
+	do := func(user, pass string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/health", nil)
+		req.SetBasicAuth(user, pass)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, do("tenant", "tenant-pass").Code)
+	assert.Equal(t, "acme", principal.Namespace)
+	assert.Equal(t, "tenant", principal.Username)
+	assert.Equal(t, http.StatusOK, do("alice", "file-pass").Code)
+	assert.Equal(t, "alice", principal.Username)
+	assert.Equal(t, http.StatusOK, do("bob", "sha-pass").Code)
+
+	w := do("alice", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, `Basic realm="fish-speech"`, w.Header().Get("WWW-Authenticate"))
+	assert.Equal(t, http.StatusUnauthorized, do("nobody", "file-pass").Code)
+
+	// Bearer keys keep working alongside Basic credentials.
+	req := httptest.NewRequest(http.MethodGet, "/v1/health", nil)
+	req.Header.Set("Authorization", "Bearer admin-key")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Edits to the htpasswd file take effect without a restart.
+	require.NoError(t, os.WriteFile(htpasswd, []byte("carol:"+string(hash)+"\n"), 0o600))
+	assert.Equal(t, http.StatusUnauthorized, do("alice", "file-pass").Code)
+	assert.Equal(t, http.StatusOK, do("carol", "file-pass").Code)
+	assert.Equal(t, http.StatusOK, do("tenant", "tenant-pass").Code)
+
+	// An invalid file keeps the previous users.
+	require.NoError(t, os.WriteFile(htpasswd, []byte("dave:$apr1$abc$def\n"), 0o600))
+	assert.Equal(t, http.StatusOK, do("carol", "file-pass").Code)
+}
+
+func TestKeyAuth_BasicDisabled(t *testing.T) {
+	handler := KeyAuthMiddleware(config.AuthConfig{APIKey: "admin-key"}, nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/health", nil)
+	req.SetBasicAuth("admin", "admin-key")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, w.Header().Get("WWW-Authenticate"))
+}
+
+func TestValidateBasicAuth(t *testing.T) {
+	assert.NoError(t, ValidateBasicAuth(config.BasicAuthConfig{
+		Users: []config.BasicUserConfig{{Username: "a", Password: "plain"}},
+	}))
+	assert.Error(t, ValidateBasicAuth(config.BasicAuthConfig{
+		Users: []config.BasicUserConfig{{Password: "plain"}},
+	}))
+	assert.Error(t, ValidateBasicAuth(config.BasicAuthConfig{
+		Users: []config.BasicUserConfig{{Username: "a", Password: "$2y$invalid"}},
+	}))
+
+	file := filepath.Join(t.TempDir(), "htpasswd")
+	require.NoError(t, os.WriteFile(file, []byte("user:$apr1$abc$def\n"), 0o600))
+	assert.ErrorIs(t, ValidateBasicAuth(config.BasicAuthConfig{HtpasswdFile: file}), errUnsupportedHash)
+	assert.Error(t, ValidateBasicAuth(config.BasicAuthConfig{HtpasswdFile: file + ".missing"}))
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/cache"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+// CacheStatus reports TTS response cache usage.
+type CacheStatus struct {
+	cache.Stats
+	HitRate float64 `json:"hit_rate"`
+}
+
+// CacheInvalidateResponse reports how many cached responses were removed.
+type CacheInvalidateResponse struct {
+	Success bool `json:"success"`
+	Removed int  `json:"removed"`
+}
+
+// cacheControl reports the no-cache and no-store directives of the request's
+// Cache-Control header.
+func cacheControl(r *http.Request) (noCache, noStore bool) {
+	for _, value := range r.Header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			switch strings.ToLower(strings.TrimSpace(directive)) {
+			case "no-cache":
+				noCache = true
+			case "no-store":
+				noStore = true
+			}
+		}
+	}
+	return noCache, noStore
+}
+
+// cacheKeys returns the key to look req up under and the key to store its
+// response under, each empty when that step is skipped: both when caching is
+// off, the lookup for Cache-Control: no-cache and storing for no-store.
+func (h *Handler) cacheKeys(r *http.Request, req *schema.ServeTTSRequest) (lookup, store string) {
+	if h.cache == nil {
+		return "", ""
+	}
+	key, err := cache.Key(req)
+	if err != nil {
+		h.logger.Warn().Err(err).Msg("TTS response not cached")
+		return "", ""
+	}
+	noCache, noStore := cacheControl(r)
+	lookup, store = key, key
+	if noCache {
+		lookup = ""
+	}
+	if noStore {
+		store = ""
+	}
+	return lookup, store
+}
+
+// serveCachedTTS answers req from the cache when possible and sets X-Cache. It
+// returns the key to store the synthesized response under, empty when it must
+// not be stored, the size of the cached audio served, and whether the request
+// was answered.
+func (h *Handler) serveCachedTTS(w http.ResponseWriter, r *http.Request, req *schema.ServeTTSRequest) (string, int64, bool) {
+	if h.cache == nil {
+		return "", 0, false
+	}
+	lookup, storeAs := h.cacheKeys(r, req)
+	w.Header().Set("X-Cache", "MISS")
+	if lookup == "" {
+		return storeAs, 0, false
+	}
+
+	entry, ok := h.cache.Get(lookup)
+	if !ok {
+		return storeAs, 0, false
+	}
+	if entry.Path == "" {
+		w.Header().Set("X-Cache", "HIT")
+		WriteAudio(w, entry.Format, entry.Audio)
+		return "", int64(len(entry.Audio)), true
+	}
+
+	f, err := os.Open(entry.Path)
+	if err != nil {
+		// Removed from the disk tier since the lookup; synthesize instead.
+		h.logger.Warn().Err(err).Msg("Cached TTS response unreadable")
+		return storeAs, 0, false
+	}
+	defer f.Close()
+	var served int64
+	if info, err := f.Stat(); err == nil {
+		served = info.Size()
+	}
+	w.Header().Set("X-Cache", "HIT")
+	WriteAudioFile(w, entry.Format, entry.SHA256, f)
+	return "", served, true
+}
+
+// cachedAudio returns the audio and format cached under key, reading entries
+// of the disk tier into memory.
+func (h *Handler) cachedAudio(key string) ([]byte, string, bool) {
+	if h.cache == nil || key == "" {
+		return nil, "", false
+	}
+	entry, ok := h.cache.Get(key)
+	if !ok {
+		return nil, "", false
+	}
+	if entry.Path == "" {
+		return entry.Audio, entry.Format, true
+	}
+	data, err := os.ReadFile(entry.Path)
+	if err != nil {
+		h.logger.Warn().Err(err).Msg("Cached TTS response unreadable")
+		return nil, "", false
+	}
+	return data, entry.Format, true
+}
+
+func (h *Handler) storeCachedTTS(key string, req *schema.ServeTTSRequest, format string, audioData []byte) {
+	if key == "" {
+		return
+	}
+	entry := &cache.Entry{
+		Audio:    audioData,
+		Format:   format,
+		SHA256:   ContentSHA256(audioData),
+		TextHash: cache.TextHash(req.Text),
+	}
+	if req.ReferenceID != nil {
+		entry.ReferenceID = *req.ReferenceID
+	}
+	h.cache.Set(key, entry)
+}
+
+// invalidateCachedReference drops responses synthesized with a reference that
+// was added, replaced or deleted, and the shared reference list.
+func (h *Handler) invalidateCachedReference(backendID string) {
+	if h.shared != nil && h.config.Redis.ReferenceListTTL > 0 {
+		if err := h.shared.InvalidateReferences(); err != nil {
+			h.logger.Warn().Err(err).Msg("Failed to invalidate shared reference list")
+		}
+	}
+	if h.cache == nil {
+		return
+	}
+	if removed := h.cache.InvalidateReference(backendID); removed > 0 {
+		h.logger.Debug().Str("reference_id", backendID).Int("removed", removed).Msg("Invalidated cached TTS responses")
+	}
+}
+
+// HandleCacheStatus reports TTS response cache usage.
+func (h *Handler) HandleCacheStatus(w http.ResponseWriter, r *http.Request) {
+	if h.cache == nil {
+		WriteError(w, http.StatusNotFound, "Response caching is not configured")
+		return
+	}
+	stats := h.cache.Stats()
+	WriteJSON(w, http.StatusOK, CacheStatus{Stats: stats, HitRate: stats.HitRate()})
+}
+
+// HandleCacheInvalidate removes the cached responses for ?reference_id= or
+// ?text_hash=, or every response when neither is given.
+func (h *Handler) HandleCacheInvalidate(w http.ResponseWriter, r *http.Request) {
+	if h.cache == nil {
+		WriteError(w, http.StatusNotFound, "Response caching is not configured")
+		return
+	}
+
+	query := r.URL.Query()
+	referenceID, textHash := query.Get("reference_id"), query.Get("text_hash")
+	var removed int
+	switch {
+	case referenceID != "" && textHash != "":
+		WriteError(w, http.StatusBadRequest, "Specify either reference_id or text_hash, not both")
+		return
+	case referenceID != "":
+		removed = h.cache.InvalidateReference(h.refs.Resolve(scopeReferenceID(namespaceFromContext(r.Context()), referenceID)))
+	case textHash != "":
+		removed = h.cache.InvalidateText(textHash)
+	default:
+		removed = h.cache.Flush()
+	}
+
+	h.logger.Info().
+		Str("reference_id", referenceID).
+		Str("text_hash", textHash).
+		Int("removed", removed).
+		Msg("TTS response cache invalidated")
+	WriteJSON(w, http.StatusOK, CacheInvalidateResponse{Success: true, Removed: removed})
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/cache"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func cacheStatus(t *testing.T, router http.Handler) CacheStatus {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, asAdmin(httptest.NewRequest(http.MethodGet, "/admin/cache", nil)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var status CacheStatus
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	return status
+}
+
+func invalidateCache(t *testing.T, router http.Handler, query string) int {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, asAdmin(httptest.NewRequest(http.MethodDelete, "/admin/cache"+query, nil)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp CacheInvalidateResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp.Removed
+}
+
+func TestTTS_Cache(t *testing.T) {
+	mock := &mockBackend{
+		ttsResponse:   []byte("audio"),
+		deleteRefResp: &schema.DeleteReferenceResponse{Success: true, ReferenceID: "voice"},
+	}
+	router := NewRouter(testConfig(), mock, testLogger(), WithCache(cache.New(cache.Config{})))
+
+	tts := func(body string) {
+		mock.lastTTSReq = nil
+		req := httptest.NewRequest(http.MethodPost, "/v1/tts", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "audio", w.Body.String())
+	}
+
+	tts(`{"text":"hello","reference_id":"voice"}`)
+	assert.NotNil(t, mock.lastTTSReq)
+	tts(`{"text":"hello","reference_id":"voice"}`)
+	assert.Nil(t, mock.lastTTSReq, "second request should be served from the cache")
+	tts(`{"text":" bye\r\n"}`)
+
+	status := cacheStatus(t, router)
+	assert.Equal(t, int64(1), status.Hits)
+	assert.Equal(t, int64(2), status.Misses)
+	assert.Equal(t, 2, status.Entries)
+	assert.Equal(t, int64(10), status.Bytes)
+	assert.InDelta(t, 1.0/3, status.HitRate, 0.001)
+
+	// Entries are tagged with the hash of their text as normalized for the key.
+	assert.Equal(t, 1, invalidateCache(t, router, "?text_hash="+cache.TextHash("bye")))
+
+	// Deleting the reference drops the responses synthesized with it.
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/v1/references/voice", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, 0, cacheStatus(t, router).Entries)
+
+	tts(`{"text":"hello","reference_id":"voice"}`)
+	assert.Equal(t, 1, invalidateCache(t, router, ""))
+}
+
+func TestAdminCache(t *testing.T) {
+	router := NewRouter(testConfig(), &mockBackend{}, testLogger())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, asAdmin(httptest.NewRequest(http.MethodGet, "/admin/cache", nil)))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	c := cache.New(cache.Config{})
+	c.Set("1", &cache.Entry{Audio: []byte("a"), ReferenceID: "voice"})
+	c.Set("2", &cache.Entry{Audio: []byte("b"), ReferenceID: "other"})
+	router = NewRouter(testConfig(), &mockBackend{}, testLogger(), WithCache(c))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, asAdmin(httptest.NewRequest(http.MethodDelete, "/admin/cache?reference_id=voice&text_hash=abc", nil)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	assert.Equal(t, 1, invalidateCache(t, router, "?reference_id=voice"))
+	assert.Equal(t, 1, cacheStatus(t, router).Entries)
+}
+
+func TestTTS_CacheDiskHit(t *testing.T) {
+	disk, err := cache.OpenDisk(t.TempDir(), 0)
+	require.NoError(t, err)
+	mock := &mockBackend{ttsResponse: []byte("large audio")}
+	router := NewRouter(testConfig(), mock, testLogger(), WithCache(cache.New(cache.Config{Disk: disk, SpillBytes: 4})))
+
+	var first *httptest.ResponseRecorder
+	for i := 0; i < 2; i++ {
+		mock.lastTTSReq = nil
+		req := httptest.NewRequest(http.MethodPost, "/v1/tts", strings.NewReader(`{"text":"hello"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "large audio", w.Body.String())
+		if first == nil {
+			first = w
+			continue
+		}
+		assert.Nil(t, mock.lastTTSReq)
+		assert.Equal(t, first.Header().Get("X-Content-SHA256"), w.Header().Get("X-Content-SHA256"))
+		assert.Equal(t, "11", w.Header().Get("Content-Length"))
+		assert.Equal(t, "audio/wav", w.Header().Get("Content-Type"))
+	}
+	assert.Equal(t, 1, cacheStatus(t, router).DiskEntries)
+}
+
+func TestTTS_CacheControl(t *testing.T) {
+	mock := &mockBackend{ttsResponse: []byte("audio")}
+	router := NewRouter(testConfig(), mock, testLogger(), WithCache(cache.New(cache.Config{})))
+
+	tts := func(text, cacheControl string) (string, bool) {
+		mock.lastTTSReq = nil
+		req := httptest.NewRequest(http.MethodPost, "/v1/tts", strings.NewReader(`{"text":"`+text+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if cacheControl != "" {
+			req.Header.Set("Cache-Control", cacheControl)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return w.Header().Get("X-Cache"), mock.lastTTSReq != nil
+	}
+
+	tests := []struct {
+		text, cacheControl string
+		xCache             string
+		synthesized        bool
+	}{
+		{"one", "no-store", "MISS", true},
+		{"one", "", "MISS", true},
+		{"one", "", "HIT", false},
+		{"one", "max-age=0, No-Cache", "MISS", true},
+		{"one", "no-store", "HIT", false},
+	}
+	for i, tt := range tests {
+		xCache, synthesized := tts(tt.text, tt.cacheControl)
+		assert.Equal(t, tt.xCache, xCache, "request %d", i)
+		assert.Equal(t, tt.synthesized, synthesized, "request %d", i)
+	}
+	assert.Equal(t, 1, cacheStatus(t, router).Entries)
+
+	// Without a cache there is no X-Cache header.
+	router = NewRouter(testConfig(), mock, testLogger())
+	xCache, _ := tts("one", "")
+	assert.Empty(t, xCache)
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+// Fault kinds reported in logs and the X-Fault-Injected response header.
+const (
+	faultLatency = "latency"
+	faultError   = "error"
+	faultDrop    = "drop"
+)
+
+// ChaosMiddleware injects latency, error responses, and dropped responses into a
+// fraction of requests for resilience testing. It must only be installed when
+// explicitly enabled in the config.
+func ChaosMiddleware(cfg config.ChaosConfig, logger zerolog.Logger) func(http.Handler) http.Handler {
+	status := cfg.ErrorStatus
+	if status == 0 {
+		status = http.StatusServiceUnavailable
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !chaosApplies(cfg.Paths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			log := func(fault string) {
+				logger.Warn().
+					Str("fault", fault).
+					Str("request_id", r.Header.Get("X-Request-ID")).
+					Str("path", r.URL.Path).
+					Msg("Injected fault")
+			}
+
+			if cfg.Latency > 0 && rand.Float64() < cfg.LatencyRate {
+				log(faultLatency)
+				w.Header().Add("X-Fault-Injected", faultLatency)
+				select {
+				case <-time.After(cfg.Latency):
+				case <-r.Context().Done():
+					return
+				}
+			}
+
+			if rand.Float64() < cfg.ErrorRate {
+				log(faultError)
+				w.Header().Add("X-Fault-Injected", faultError)
+				WriteError(w, status, "Injected fault")
+				return
+			}
+
+			if rand.Float64() < cfg.DropRate {
+				log(faultDrop)
+				w.Header().Add("X-Fault-Injected", faultDrop)
+				w = &droppingWriter{ResponseWriter: w}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func chaosApplies(prefixes []string, path string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, p := range prefixes {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// droppingWriter sends half of the first body write and then aborts the
+// connection, emulating a stream that dies mid-response.
+type droppingWriter struct {
+	http.ResponseWriter
+}
+
+func (d *droppingWriter) Write(p []byte) (int, error) {
+	d.ResponseWriter.Write(p[:len(p)/2])
+	d.Flush()
+	panic(http.ErrAbortHandler)
+}
+
+func (d *droppingWriter) Flush() {
+	if f, ok := d.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (d *droppingWriter) Unwrap() http.ResponseWriter {
+	return d.ResponseWriter
+}
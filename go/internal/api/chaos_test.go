@@ -0,0 +1,46 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+func TestChaosMiddleware(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("audio", 100)))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		h := ChaosMiddleware(config.ChaosConfig{Enabled: true, ErrorRate: 1, Paths: []string{"/v1/tts"}}, testLogger())(ok)
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/tts", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "error", w.Header().Get("X-Fault-Injected"))
+
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/health", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		srv := httptest.NewServer(ChaosMiddleware(config.ChaosConfig{Enabled: true, DropRate: 1}, testLogger())(ok))
+		defer srv.Close()
+
+		resp, err := http.Get(srv.URL + "/v1/tts")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, "drop", resp.Header.Get("X-Fault-Injected"))
+		_, err = io.ReadAll(resp.Body)
+		assert.Error(t, err)
+	})
+}
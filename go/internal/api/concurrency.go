@@ -0,0 +1,212 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/backend"
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+	"github.com/fish-speech-go/fish-speech-go/internal/limiter"
+	"github.com/fish-speech-go/fish-speech-go/internal/metrics"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+// LimiterStatus reports the backend concurrency limiter state.
+type LimiterStatus struct {
+	Enabled  bool `json:"enabled"`
+	Adaptive bool `json:"adaptive"`
+	Limit    int  `json:"limit"`
+	InFlight int  `json:"in_flight"`
+	Waiting  int  `json:"waiting"`
+	// MaxConcurrent and AcquireTimeoutMs are the current settings, which
+	// PUT /admin/limiter changes without a restart.
+	MaxConcurrent    int   `json:"max_concurrent"`
+	AcquireTimeoutMs int64 `json:"acquire_timeout_ms"`
+}
+
+// SetLimiterRequest changes the limiter settings; omitted fields are kept.
+type SetLimiterRequest struct {
+	MaxConcurrent    *int   `json:"max_concurrent,omitempty"`
+	AcquireTimeoutMs *int64 `json:"acquire_timeout_ms,omitempty"`
+}
+
+// newLimiter builds the backend concurrency limiter from the limits config, or
+// returns nil when concurrency is unbounded.
+func newLimiter(cfg config.LimitsConfig) *limiter.Limiter {
+	if cfg.MaxConcurrent <= 0 {
+		return nil
+	}
+	return limiter.New(limiter.Config{
+		MaxConcurrent:  cfg.MaxConcurrent,
+		AcquireTimeout: cfg.AcquireTimeout,
+		Adaptive:       cfg.AdaptiveConcurrency,
+		MinConcurrent:  cfg.MinConcurrent,
+		TargetLatency:  cfg.TargetLatency,
+	})
+}
+
+// slotCost is the number of backend slots req takes: one per
+// limits.chars_per_slot characters of text, so long narrations count for more
+// against the limit than short prompts.
+func (h *Handler) slotCost(req *schema.ServeTTSRequest) int {
+	per := h.config.Limits.CharsPerSlot
+	if per <= 0 {
+		return 1
+	}
+	return (utf8.RuneCountInString(req.Text) + per - 1) / per
+}
+
+// acquireSlot waits for the backend slots req takes and reports the wait in
+// the X-Queue-Wait-Ms header. It writes an error response and returns false
+// when they do not become available. The returned release must be called with
+// the latency and error of the backend call.
+func (h *Handler) acquireSlot(w http.ResponseWriter, r *http.Request, req *schema.ServeTTSRequest) (release func(latency time.Duration, err error), wait time.Duration, ok bool) {
+	if h.limiter == nil {
+		return func(time.Duration, error) {}, 0, true
+	}
+
+	// HandleTTS has already validated the header.
+	priority, _ := requestPriority(r)
+	release, wait, err := h.acquire(r.Context(), limiterPriority(priority), limiterFlow(r.Context()), h.slotCost(req))
+	w.Header().Set("X-Queue-Wait-Ms", strconv.FormatInt(wait.Milliseconds(), 10))
+	if err != nil {
+		if errors.Is(err, limiter.ErrTimeout) {
+			WriteRetryError(w, http.StatusServiceUnavailable, CodeQueueFull, "Server is busy, please retry later", h.limiter.EstimatedWait())
+			return nil, wait, false
+		}
+		h.handleBackendError(w, err)
+		return nil, wait, false
+	}
+	return release, wait, true
+}
+
+// acquire waits for cost backend slots and records the limiter metrics. The
+// limiter must be configured.
+func (h *Handler) acquire(ctx context.Context, priority limiter.Priority, flow limiter.Flow, cost int) (release func(latency time.Duration, err error), wait time.Duration, err error) {
+	start := time.Now()
+	_, span := startSpan(ctx, "limiter.acquire", attribute.Int("cost", cost))
+	h.metrics.LimiterQueueDepth.Inc()
+	releaseSlot, err := h.limiter.AcquireCost(ctx, priority, flow, cost)
+	h.metrics.LimiterQueueDepth.Dec()
+	wait = time.Since(start)
+	outcome := acquireOutcome(err)
+	h.metrics.LimiterWaitSeconds.WithLabelValues(outcome).Observe(wait.Seconds())
+	span.SetAttributes(attribute.String("outcome", outcome))
+	span.End()
+	if err != nil {
+		return nil, wait, err
+	}
+
+	h.recordLimiterUtilization()
+	acquired := time.Now()
+	return func(latency time.Duration, err error) {
+		releaseSlot(limiter.Outcome{Latency: latency, Overloaded: isOverloadError(err)})
+		h.metrics.LimiterHoldSeconds.Observe(time.Since(acquired).Seconds())
+		h.recordLimiterUtilization()
+	}, wait, nil
+}
+
+// acquireOutcome maps a limiter acquire error to its metrics outcome.
+func acquireOutcome(err error) string {
+	switch {
+	case err == nil:
+		return metrics.AcquireGranted
+	case errors.Is(err, limiter.ErrTimeout):
+		return metrics.AcquireTimeout
+	default:
+		return metrics.AcquireCanceled
+	}
+}
+
+// recordLimiterUtilization updates the utilization gauge after slots are
+// taken, released or resized.
+func (h *Handler) recordLimiterUtilization() {
+	stats := h.limiter.Stats()
+	h.metrics.LimiterUtilization.Set(float64(stats.InFlight) / float64(stats.Limit))
+}
+
+// isOverloadError reports whether a backend error suggests the backend is saturated.
+func isOverloadError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, backend.ErrBackendTimeout) || errors.Is(err, backend.ErrBackendUnavailable) ||
+		errors.Is(err, backend.ErrBackendSaturated) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var backendErr *backend.BackendError
+	return errors.As(err, &backendErr) && (backendErr.StatusCode == http.StatusTooManyRequests || backendErr.StatusCode == http.StatusServiceUnavailable)
+}
+
+// HandleLimiterStatus reports the backend concurrency limiter state.
+func (h *Handler) HandleLimiterStatus(w http.ResponseWriter, r *http.Request) {
+	if h.limiter == nil {
+		WriteJSON(w, http.StatusOK, LimiterStatus{})
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, limiterStatus(h.limiter))
+}
+
+// HandleSetLimiter changes max_concurrent and acquire_timeout_ms at runtime.
+// Lowering max_concurrent never interrupts calls holding a slot; new calls
+// queue until enough of them finish. Whether the limiter exists at all is
+// fixed at startup.
+func (h *Handler) HandleSetLimiter(w http.ResponseWriter, r *http.Request) {
+	if h.limiter == nil {
+		WriteError(w, http.StatusNotFound, "Concurrency limiting is not configured")
+		return
+	}
+
+	var req SetLimiterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.MaxConcurrent == nil && req.AcquireTimeoutMs == nil {
+		WriteError(w, http.StatusBadRequest, "max_concurrent or acquire_timeout_ms is required")
+		return
+	}
+	if req.MaxConcurrent != nil && *req.MaxConcurrent < 1 {
+		WriteError(w, http.StatusBadRequest, "max_concurrent must be at least 1")
+		return
+	}
+	if req.AcquireTimeoutMs != nil && *req.AcquireTimeoutMs < 0 {
+		WriteError(w, http.StatusBadRequest, "acquire_timeout_ms must not be negative")
+		return
+	}
+
+	event := h.logger.Info()
+	if req.MaxConcurrent != nil {
+		h.limiter.SetMaxConcurrent(*req.MaxConcurrent)
+		event = event.Int("max_concurrent", *req.MaxConcurrent)
+	}
+	if req.AcquireTimeoutMs != nil {
+		h.limiter.SetAcquireTimeout(time.Duration(*req.AcquireTimeoutMs) * time.Millisecond)
+		event = event.Int64("acquire_timeout_ms", *req.AcquireTimeoutMs)
+	}
+	event.Msg("Concurrency limiter changed")
+	h.recordLimiterUtilization()
+
+	WriteJSON(w, http.StatusOK, limiterStatus(h.limiter))
+}
+
+func limiterStatus(l *limiter.Limiter) LimiterStatus {
+	stats := l.Stats()
+	return LimiterStatus{
+		Enabled:          true,
+		Adaptive:         stats.Adaptive,
+		Limit:            stats.Limit,
+		InFlight:         stats.InFlight,
+		Waiting:          stats.Waiting,
+		MaxConcurrent:    stats.MaxConcurrent,
+		AcquireTimeoutMs: stats.AcquireTimeout.Milliseconds(),
+	}
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/limiter"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func TestTTS_ConcurrencyLimit(t *testing.T) {
+	cfg := testConfig()
+	cfg.Limits.MaxConcurrent = 1
+	cfg.Limits.AcquireTimeout = 10 * time.Millisecond
+	mock := &mockBackend{ttsResponse: []byte("audio")}
+	h := NewHandler(mock, cfg, testLogger())
+
+	release, err := h.limiter.Acquire(context.Background(), limiter.PriorityNormal)
+	require.NoError(t, err)
+
+	w := postTTS(t, h, schema.ServeTTSRequest{Text: "Hello"})
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	waited, err := strconv.Atoi(w.Header().Get("X-Queue-Wait-Ms"))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, waited, 10)
+
+	release(limiter.Outcome{})
+	w = postTTS(t, h, schema.ServeTTSRequest{Text: "Hello"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0", w.Header().Get("X-Queue-Wait-Ms"))
+
+	status := httptest.NewRecorder()
+	h.HandleLimiterStatus(status, httptest.NewRequest(http.MethodGet, "/admin/limiter", nil))
+	var resp LimiterStatus
+	require.NoError(t, json.Unmarshal(status.Body.Bytes(), &resp))
+	assert.Equal(t, LimiterStatus{Enabled: true, Limit: 1, MaxConcurrent: 1, AcquireTimeoutMs: 10}, resp)
+}
+
+func TestTTS_QueueFullEstimatedWait(t *testing.T) {
+	cfg := testConfig()
+	cfg.Limits.MaxConcurrent = 1
+	cfg.Limits.AcquireTimeout = 10 * time.Millisecond
+	h := NewHandler(&mockBackend{ttsResponse: []byte("audio")}, cfg, testLogger())
+
+	release, err := h.limiter.Acquire(context.Background(), limiter.PriorityNormal)
+	require.NoError(t, err)
+	release(limiter.Outcome{Latency: 2500 * time.Millisecond})
+	release, err = h.limiter.Acquire(context.Background(), limiter.PriorityNormal)
+	require.NoError(t, err)
+	defer release(limiter.Outcome{})
+
+	body, _ := json.Marshal(schema.ServeTTSRequest{Text: "Hello"})
+	req := httptest.NewRequest(http.MethodPost, "/v2/tts", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	VersionMiddleware(http.HandlerFunc(h.HandleTTS)).ServeHTTP(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "3", w.Header().Get("Retry-After"))
+	var resp V2ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, CodeQueueFull, resp.Error.Code)
+	require.NotNil(t, resp.Error.EstimatedWaitMs)
+	assert.Equal(t, int64(2500), *resp.Error.EstimatedWaitMs)
+}
+
+func TestSetLimiter(t *testing.T) {
+	cfg := testConfig()
+	cfg.Limits.MaxConcurrent = 1
+	h := NewHandler(&mockBackend{}, cfg, testLogger())
+
+	held, err := h.limiter.Acquire(context.Background(), limiter.PriorityNormal)
+	require.NoError(t, err)
+	defer held(limiter.Outcome{})
+
+	put := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.HandleSetLimiter(w, httptest.NewRequest(http.MethodPut, "/admin/limiter", strings.NewReader(body)))
+		return w
+	}
+
+	w := put(`{"max_concurrent": 3, "acquire_timeout_ms": 250}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp LimiterStatus
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, LimiterStatus{Enabled: true, Limit: 3, InFlight: 1, MaxConcurrent: 3, AcquireTimeoutMs: 250}, resp)
+
+	w = put(`{"acquire_timeout_ms": 0}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 3, h.limiter.Stats().MaxConcurrent)
+	assert.Zero(t, h.limiter.Stats().AcquireTimeout)
+
+	for _, body := range []string{`{}`, `{"max_concurrent": 0}`, `{"acquire_timeout_ms": -1}`, `not json`} {
+		assert.Equal(t, http.StatusBadRequest, put(body).Code, body)
+	}
+
+	unlimited := NewHandler(&mockBackend{}, testConfig(), testLogger())
+	w = httptest.NewRecorder()
+	unlimited.HandleSetLimiter(w, httptest.NewRequest(http.MethodPut, "/admin/limiter", strings.NewReader(`{"max_concurrent": 2}`)))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestTTS_SlotCost(t *testing.T) {
+	cfg := testConfig()
+	cfg.Limits.MaxConcurrent = 2
+	cfg.Limits.AcquireTimeout = 10 * time.Millisecond
+	cfg.Limits.CharsPerSlot = 10
+	h := NewHandler(&mockBackend{ttsResponse: []byte("audio")}, cfg, testLogger())
+
+	assert.Equal(t, 1, h.slotCost(&schema.ServeTTSRequest{Text: "Hello"}))
+	assert.Equal(t, 2, h.slotCost(&schema.ServeTTSRequest{Text: "Hello, world"}))
+	assert.Equal(t, 1, h.slotCost(&schema.ServeTTSRequest{Text: "ひらがなカタカナ漢字"}), "counts characters, not bytes")
+
+	release, err := h.limiter.Acquire(context.Background(), limiter.PriorityNormal)
+	require.NoError(t, err)
+	defer release(limiter.Outcome{})
+
+	// One slot is free: enough for a short prompt but not a two-slot one.
+	assert.Equal(t, http.StatusOK, postTTS(t, h, schema.ServeTTSRequest{Text: "Hello"}).Code)
+	assert.Equal(t, http.StatusServiceUnavailable, postTTS(t, h, schema.ServeTTSRequest{Text: "Hello, world"}).Code)
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+func TestCORSConfigMiddleware_Preflight(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	preflight := func(cfg config.CORSConfig, privateNetwork bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, "/v1/tts", nil)
+		req.Header.Set("Origin", "https://example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		if privateNetwork {
+			req.Header.Set("Access-Control-Request-Private-Network", "true")
+		}
+		w := httptest.NewRecorder()
+		CORSConfigMiddleware(cfg)(next).ServeHTTP(w, req)
+		return w
+	}
+
+	w := preflight(config.CORSConfig{}, true)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Max-Age"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Private-Network"))
+
+	cfg := config.CORSConfig{MaxAge: 10 * time.Minute, AllowPrivateNetwork: true}
+	w = preflight(cfg, false)
+	assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Private-Network"))
+
+	w = preflight(cfg, true)
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Private-Network"))
+
+	allowed := w.Header().Get("Access-Control-Allow-Headers")
+	for _, header := range []string{"Cache-Control", HeaderSignature, HeaderTimestamp, "Range", "If-Range"} {
+		assert.Contains(t, allowed, header)
+	}
+	assert.Contains(t, w.Header().Get("Access-Control-Expose-Headers"), "Retry-After")
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/tts", nil)
+	rec := httptest.NewRecorder()
+	CORSConfigMiddleware(cfg)(next).ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+	assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, rec.Header().Get("Access-Control-Max-Age"))
+}
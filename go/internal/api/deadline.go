@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/backend"
+)
+
+// parseDeadline interprets an X-Request-Deadline value, either an absolute RFC 3339
+// timestamp or a duration relative to now such as "1500ms" or "30s".
+func parseDeadline(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, errors.New("must be an RFC 3339 timestamp or a duration")
+	}
+	if d <= 0 {
+		return time.Time{}, errors.New("duration must be positive")
+	}
+	return now.Add(d), nil
+}
+
+// DeadlineMiddleware bounds the request context by the caller's X-Request-Deadline
+// header. Backend calls made with that context carry the remaining budget along
+// and are cancelled when it runs out.
+func DeadlineMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value := r.Header.Get(backend.DeadlineHeader)
+		if value == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		deadline, err := parseDeadline(value, time.Now())
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, "Invalid X-Request-Deadline header: "+err.Error())
+			return
+		}
+		if !deadline.After(time.Now()) {
+			WriteErrorCode(w, http.StatusGatewayTimeout, CodeDeadlineExceeded, "Request deadline exceeded")
+			return
+		}
+
+		ctx, cancel := context.WithDeadline(r.Context(), deadline)
+		defer cancel()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
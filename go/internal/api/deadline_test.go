@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDeadline(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "1500ms", want: now.Add(1500 * time.Millisecond)},
+		{value: " 30s ", want: now.Add(30 * time.Second)},
+		{value: "2024-01-01T12:00:05Z", want: now.Add(5 * time.Second)},
+		{value: "2024-01-01T13:00:05.5+01:00", want: now.Add(5500 * time.Millisecond)},
+		{value: "0s", wantErr: true},
+		{value: "-5s", wantErr: true},
+		{value: "soon", wantErr: true},
+		{value: "1500", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			got, err := parseDeadline(tc.value, now)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tc.want.Equal(got), "got %v, want %v", got, tc.want)
+		})
+	}
+}
+
+func TestDeadlineMiddleware(t *testing.T) {
+	var deadline time.Time
+	var hasDeadline bool
+	handler := DeadlineMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, hasDeadline = r.Context().Deadline()
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	do := func(value string) int {
+		hasDeadline = false
+		req := httptest.NewRequest(http.MethodPost, "/v1/tts", nil)
+		if value != "" {
+			req.Header.Set("X-Request-Deadline", value)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, do(""))
+	assert.False(t, hasDeadline)
+
+	assert.Equal(t, http.StatusOK, do("10s"))
+	require.True(t, hasDeadline)
+	assert.WithinDuration(t, time.Now().Add(10*time.Second), deadline, time.Second)
+
+	assert.Equal(t, http.StatusBadRequest, do("whenever"))
+	assert.Equal(t, http.StatusGatewayTimeout, do(time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)))
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/backend"
+)
+
+// recentErrorsSize is how many errors /admin/diagnostics keeps.
+const recentErrorsSize = 50
+
+// DiagnosticsResponse is a snapshot of the server's runtime state for
+// debugging a misbehaving instance.
+type DiagnosticsResponse struct {
+	Goroutines int              `json:"goroutines"`
+	Memory     MemoryDiagnostic `json:"memory"`
+	GC         GCDiagnostic     `json:"gc"`
+	// BackendConnections counts open connections, idle or in use, by backend URL.
+	BackendConnections map[string]int64 `json:"backend_connections,omitempty"`
+	// RecentErrors holds the last backend and streaming errors, newest first.
+	RecentErrors []RecentError `json:"recent_errors"`
+}
+
+// MemoryDiagnostic summarizes runtime.MemStats.
+type MemoryDiagnostic struct {
+	HeapAllocBytes  uint64 `json:"heap_alloc_bytes"`
+	HeapInuseBytes  uint64 `json:"heap_inuse_bytes"`
+	HeapObjects     uint64 `json:"heap_objects"`
+	StackInuseBytes uint64 `json:"stack_inuse_bytes"`
+	SysBytes        uint64 `json:"sys_bytes"`
+	TotalAllocBytes uint64 `json:"total_alloc_bytes"`
+}
+
+// GCDiagnostic summarizes garbage collection. The pause percentiles cover the
+// last (up to 256) collections.
+type GCDiagnostic struct {
+	NumGC        uint32    `json:"num_gc"`
+	LastGC       time.Time `json:"last_gc"`
+	NextGCBytes  uint64    `json:"next_gc_bytes"`
+	CPUFraction  float64   `json:"cpu_fraction"`
+	PauseTotalMs float64   `json:"pause_total_ms"`
+	PauseP50Ms   float64   `json:"pause_p50_ms"`
+	PauseP99Ms   float64   `json:"pause_p99_ms"`
+	PauseMaxMs   float64   `json:"pause_max_ms"`
+	RecentPauses int       `json:"recent_pauses"`
+}
+
+// RecentError is an error recorded for /admin/diagnostics.
+type RecentError struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error"`
+}
+
+// errorLog keeps the most recent errors in a ring.
+type errorLog struct {
+	mu      sync.Mutex
+	entries []RecentError
+	next    int
+}
+
+func (l *errorLog) add(err error) {
+	entry := RecentError{Time: time.Now(), Error: err.Error()}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.entries) < recentErrorsSize {
+		l.entries = append(l.entries, entry)
+		return
+	}
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % recentErrorsSize
+}
+
+// recent returns the recorded errors, newest first.
+func (l *errorLog) recent() []RecentError {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	recent := make([]RecentError, 0, len(l.entries))
+	for i := len(l.entries) - 1; i >= 0; i-- {
+		recent = append(recent, l.entries[(l.next+i)%len(l.entries)])
+	}
+	return recent
+}
+
+// HandleDiagnostics reports goroutines, memory, GC pauses, backend connections
+// and recent errors. With ?stacks=true it instead dumps every goroutine's
+// stack as text, like SIGQUIT but without stopping the process.
+func (h *Handler) HandleDiagnostics(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("stacks") == "true" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_ = pprof.Lookup("goroutine").WriteTo(w, 2)
+		return
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	resp := DiagnosticsResponse{
+		Goroutines: runtime.NumGoroutine(),
+		Memory: MemoryDiagnostic{
+			HeapAllocBytes:  mem.HeapAlloc,
+			HeapInuseBytes:  mem.HeapInuse,
+			HeapObjects:     mem.HeapObjects,
+			StackInuseBytes: mem.StackInuse,
+			SysBytes:        mem.Sys,
+			TotalAllocBytes: mem.TotalAlloc,
+		},
+		GC:           gcDiagnostic(&mem),
+		RecentErrors: h.recentErrors.recent(),
+	}
+	if reporter, ok := h.backend.(backend.ConnectionReporter); ok {
+		resp.BackendConnections = reporter.OpenConnections()
+	}
+
+	WriteJSON(w, http.StatusOK, resp)
+}
+
+func gcDiagnostic(mem *runtime.MemStats) GCDiagnostic {
+	gc := GCDiagnostic{
+		NumGC:        mem.NumGC,
+		NextGCBytes:  mem.NextGC,
+		CPUFraction:  mem.GCCPUFraction,
+		PauseTotalMs: float64(mem.PauseTotalNs) / 1e6,
+	}
+	if mem.LastGC > 0 {
+		gc.LastGC = time.Unix(0, int64(mem.LastGC))
+	}
+
+	n := int(mem.NumGC)
+	if n > len(mem.PauseNs) {
+		n = len(mem.PauseNs)
+	}
+	if n == 0 {
+		return gc
+	}
+	pauses := make([]float64, 0, n)
+	for i := 0; i < n; i++ {
+		pauses = append(pauses, float64(mem.PauseNs[(int(mem.NumGC)-1-i)%len(mem.PauseNs)])/1e6)
+	}
+	sort.Float64s(pauses)
+	gc.RecentPauses = n
+	gc.PauseP50Ms = pauses[int(0.5*float64(n-1))]
+	gc.PauseP99Ms = pauses[int(0.99*float64(n-1))]
+	gc.PauseMaxMs = pauses[n-1]
+	return gc
+}
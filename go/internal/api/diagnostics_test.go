@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/backend"
+)
+
+func TestErrorLog(t *testing.T) {
+	var l errorLog
+	assert.Empty(t, l.recent())
+
+	for i := 0; i < recentErrorsSize+5; i++ {
+		l.add(fmt.Errorf("error %d", i))
+	}
+	recent := l.recent()
+	require.Len(t, recent, recentErrorsSize)
+	assert.Equal(t, fmt.Sprintf("error %d", recentErrorsSize+4), recent[0].Error)
+	assert.Equal(t, "error 5", recent[len(recent)-1].Error)
+}
+
+func TestAdminDiagnostics(t *testing.T) {
+	mock := &mockBackend{ttsErr: backend.ErrBackendUnavailable}
+	router := NewRouter(testConfig(), mock, testLogger())
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/tts", strings.NewReader(`{"text":"hello"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, asAdmin(httptest.NewRequest(http.MethodGet, "/admin/diagnostics", nil)))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp DiagnosticsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Positive(t, resp.Goroutines)
+	assert.Positive(t, resp.Memory.HeapAllocBytes)
+	require.Len(t, resp.RecentErrors, 1)
+	assert.Equal(t, backend.ErrBackendUnavailable.Error(), resp.RecentErrors[0].Error)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, asAdmin(httptest.NewRequest(http.MethodGet, "/admin/diagnostics?stacks=true", nil)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, w.Body.String(), "goroutine ")
+	assert.Contains(t, w.Body.String(), "HandleDiagnostics")
+}
+
+func TestErrorLog_SkipsCancelled(t *testing.T) {
+	h := NewHandler(&mockBackend{}, testConfig(), testLogger())
+	h.handleBackendError(httptest.NewRecorder(), fmt.Errorf("tts: %w", errors.New("boom")))
+	h.handleBackendError(httptest.NewRecorder(), fmt.Errorf("tts: %w", context.Canceled))
+	assert.Len(t, h.recentErrors.recent(), 1)
+}
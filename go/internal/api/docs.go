@@ -0,0 +1,24 @@
+package api
+
+import (
+	_ "embed"
+	"html/template"
+	"net/http"
+)
+
+//go:embed docs.html
+var docsHTML string
+
+var docsTemplate = template.Must(template.New("docs").Parse(docsHTML))
+
+// HandleDocs serves a Redoc page rendering the OpenAPI document.
+func (h *Handler) HandleDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = docsTemplate.Execute(w, struct {
+		SpecURL   string
+		ScriptURL string
+	}{
+		SpecURL:   "/openapi.json",
+		ScriptURL: h.config.Docs.ScriptURL,
+	})
+}
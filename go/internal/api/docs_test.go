@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+func TestDocs(t *testing.T) {
+	cfg := testConfig()
+	cfg.Auth = config.AuthConfig{APIKey: "secret"}
+
+	get := func(cfg *config.Config, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		NewRouter(cfg, &mockBackend{}, testLogger()).ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	assert.Equal(t, http.StatusNotFound, get(cfg, "/docs").Code)
+
+	cfg.Docs = config.DocsConfig{Enabled: true, ScriptURL: "/static/redoc.js"}
+	w := get(cfg, "/docs")
+	assert.Equal(t, http.StatusOK, w.Code, "docs are served without a key")
+	assert.Contains(t, w.Body.String(), `spec-url="/openapi.json"`)
+	assert.Contains(t, w.Body.String(), `src="/static/redoc.js"`)
+
+	assert.Equal(t, http.StatusOK, get(cfg, "/openapi.json").Code)
+	assert.Equal(t, http.StatusUnauthorized, get(cfg, "/v1/references").Code)
+}
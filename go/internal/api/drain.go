@@ -0,0 +1,224 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Server states reported by GET /admin/status.
+const (
+	StateServing     = "serving"
+	StateMaintenance = "maintenance"
+	StateDraining    = "draining"
+	StateDrained     = "drained"
+)
+
+const (
+	// defaultMaintenanceMessage is the error detail of requests rejected in
+	// maintenance mode unless the operator gives one.
+	defaultMaintenanceMessage = "Server is under maintenance, please retry later"
+	// defaultMaintenanceRetryAfter is their Retry-After.
+	defaultMaintenanceRetryAfter = 30 * time.Second
+)
+
+// Drainer admits TTS requests and jobs and counts those in flight. In
+// maintenance mode it turns new ones away until maintenance ends; once
+// draining it turns them away for good and reports when the last one has
+// finished, so the server can shut down without cutting streams off. It is
+// safe for concurrent use.
+type Drainer struct {
+	mu            sync.Mutex
+	inFlight      int
+	maintenance   bool
+	message       string
+	retryAfter    time.Duration
+	drainingSince time.Time
+	drained       chan struct{}
+}
+
+// NewDrainer returns a Drainer admitting every request.
+func NewDrainer() *Drainer {
+	return &Drainer{drained: make(chan struct{})}
+}
+
+// acquire admits a TTS request or job, or returns the error to reject it
+// with. Admitted work must call release when it is done.
+func (d *Drainer) acquire() *policyError {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch {
+	case !d.drainingSince.IsZero():
+		// Another instance can take the request right away.
+		return &policyError{status: http.StatusServiceUnavailable, code: CodeDraining, message: "Server is shutting down, please retry", retryAfter: time.Second}
+	case d.maintenance:
+		return &policyError{status: http.StatusServiceUnavailable, code: CodeMaintenance, message: d.message, retryAfter: d.retryAfter}
+	}
+	d.inFlight++
+	return nil
+}
+
+// hold counts further work on behalf of an admitted request, such as the job
+// it submitted, until release.
+func (d *Drainer) hold() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.inFlight++
+}
+
+func (d *Drainer) release() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.inFlight--
+	d.closeIfDrainedLocked()
+}
+
+// Drain stops admitting TTS requests and jobs. Drained is closed once those
+// in flight have finished.
+func (d *Drainer) Drain() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.drainingSince.IsZero() {
+		d.drainingSince = time.Now()
+	}
+	d.closeIfDrainedLocked()
+}
+
+func (d *Drainer) closeIfDrainedLocked() {
+	if d.drainingSince.IsZero() || d.inFlight > 0 {
+		return
+	}
+	select {
+	case <-d.drained:
+	default:
+		close(d.drained)
+	}
+}
+
+// Drained is closed once a drain has finished.
+func (d *Drainer) Drained() <-chan struct{} {
+	return d.drained
+}
+
+// SetMaintenance turns maintenance mode on or off. While it is on, new TTS
+// requests and jobs are rejected with message and a Retry-After of
+// retryAfter; defaults are used for empty values.
+func (d *Drainer) SetMaintenance(enabled bool, message string, retryAfter time.Duration) {
+	if message == "" {
+		message = defaultMaintenanceMessage
+	}
+	if retryAfter <= 0 {
+		retryAfter = defaultMaintenanceRetryAfter
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.maintenance, d.message, d.retryAfter = enabled, message, retryAfter
+}
+
+// state returns the server state and the maintenance message, if any.
+func (d *Drainer) state() (string, string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch {
+	case !d.drainingSince.IsZero() && d.inFlight == 0:
+		return StateDrained, ""
+	case !d.drainingSince.IsZero():
+		return StateDraining, ""
+	case d.maintenance:
+		return StateMaintenance, d.message
+	}
+	return StateServing, ""
+}
+
+// DrainMiddleware admits requests through the drainer, rejecting them with
+// 503 in maintenance mode or while draining.
+func DrainMiddleware(d *Drainer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if perr := d.acquire(); perr != nil {
+				perr.write(w)
+				return
+			}
+			defer d.release()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AdminStatus reports whether the server takes new TTS requests and the work
+// it has in flight.
+type AdminStatus struct {
+	// State is serving, maintenance, draining or drained.
+	State string `json:"state"`
+	// Message is the detail of requests rejected in maintenance mode.
+	Message string `json:"message,omitempty"`
+	// InFlight counts TTS requests, streams included, and jobs not yet
+	// finished.
+	InFlight      int            `json:"in_flight"`
+	DrainingSince *time.Time     `json:"draining_since,omitempty"`
+	StartedAt     time.Time      `json:"started_at"`
+	UptimeSeconds float64        `json:"uptime_seconds"`
+	Goroutines    int            `json:"goroutines"`
+	Limiter       *LimiterStatus `json:"limiter,omitempty"`
+}
+
+// SetMaintenanceRequest turns maintenance mode on or off.
+type SetMaintenanceRequest struct {
+	Enabled *bool `json:"enabled"`
+	// Message and RetryAfterSeconds are reported to rejected clients.
+	Message           string `json:"message,omitempty"`
+	RetryAfterSeconds int    `json:"retry_after_seconds,omitempty"`
+}
+
+func (h *Handler) adminStatus() AdminStatus {
+	d := h.drain
+	status := AdminStatus{StartedAt: h.started, UptimeSeconds: time.Since(h.started).Seconds(), Goroutines: runtime.NumGoroutine()}
+	status.State, status.Message = d.state()
+	d.mu.Lock()
+	status.InFlight = d.inFlight
+	if !d.drainingSince.IsZero() {
+		since := d.drainingSince
+		status.DrainingSince = &since
+	}
+	d.mu.Unlock()
+	if h.limiter != nil {
+		limiter := limiterStatus(h.limiter)
+		status.Limiter = &limiter
+	}
+	return status
+}
+
+// HandleAdminStatus reports the server state and its work in flight.
+func (h *Handler) HandleAdminStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	WriteJSON(w, http.StatusOK, h.adminStatus())
+}
+
+// HandleSetMaintenance turns maintenance mode on or off. Requests already in
+// flight are not affected.
+func (h *Handler) HandleSetMaintenance(w http.ResponseWriter, r *http.Request) {
+	var req SetMaintenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+		WriteError(w, http.StatusBadRequest, "enabled is required")
+		return
+	}
+	if req.RetryAfterSeconds < 0 {
+		WriteError(w, http.StatusBadRequest, "retry_after_seconds must not be negative")
+		return
+	}
+
+	h.drain.SetMaintenance(*req.Enabled, req.Message, time.Duration(req.RetryAfterSeconds)*time.Second)
+	h.logger.Info().Bool("enabled", *req.Enabled).Str("message", req.Message).Msg("Maintenance mode changed")
+	WriteJSON(w, http.StatusOK, h.adminStatus())
+}
+
+// HandleDrain stops the server taking TTS requests and jobs; it shuts down
+// once those in flight have finished. Draining cannot be undone.
+func (h *Handler) HandleDrain(w http.ResponseWriter, r *http.Request) {
+	h.drain.Drain()
+	status := h.adminStatus()
+	h.logger.Warn().Int("in_flight", status.InFlight).Msg("Draining - no longer accepting TTS requests")
+	WriteJSON(w, http.StatusAccepted, status)
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/queue"
+)
+
+func TestDrainer_WaitsForInFlight(t *testing.T) {
+	d := NewDrainer()
+	require.Nil(t, d.acquire())
+	d.hold()
+
+	d.Drain()
+	perr := d.acquire()
+	require.NotNil(t, perr)
+	assert.Equal(t, CodeDraining, perr.code)
+
+	d.release()
+	select {
+	case <-d.Drained():
+		t.Fatal("drained with work in flight")
+	default:
+	}
+	state, _ := d.state()
+	assert.Equal(t, StateDraining, state)
+
+	d.release()
+	<-d.Drained()
+	state, _ = d.state()
+	assert.Equal(t, StateDrained, state)
+	d.Drain()
+}
+
+func TestAdmin_MaintenanceAndDrain(t *testing.T) {
+	drainer := NewDrainer()
+	router := NewRouter(testConfig(), &mockBackend{ttsResponse: []byte("RIFF")}, testLogger(), WithDrainer(drainer))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, asAdmin(req))
+		return w
+	}
+
+	// Without auth configured nobody may use the admin API.
+	for _, path := range []string{"/admin/drain", "/admin/maintenance", "/admin/status"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"enabled": true}`)))
+		assert.Equal(t, http.StatusForbidden, w.Code, path)
+	}
+	state, _ := drainer.state()
+	assert.Equal(t, StateServing, state)
+
+	require.Equal(t, http.StatusOK, do(http.MethodPut, "/admin/maintenance", `{"enabled": true, "message": "Upgrading models", "retry_after_seconds": 120}`).Code)
+	w := do(http.MethodPost, "/v2/tts", `{"text": "Hello"}`)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "120", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "Upgrading models")
+	assert.Contains(t, w.Body.String(), CodeMaintenance)
+	w = do(http.MethodGet, "/v1/health", "")
+	assert.Equal(t, http.StatusOK, w.Code, "maintenance keeps the server in rotation")
+	assert.Contains(t, w.Body.String(), StateMaintenance)
+
+	require.Equal(t, http.StatusOK, do(http.MethodPut, "/admin/maintenance", `{"enabled": false}`).Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/v1/tts", `{"text": "Hello"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/admin/maintenance", `{}`).Code)
+
+	w = do(http.MethodPost, "/admin/drain", "")
+	require.Equal(t, http.StatusAccepted, w.Code)
+	<-drainer.Drained()
+
+	w = do(http.MethodPost, "/v2/tts", `{"text": "Hello"}`)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), CodeDraining)
+	assert.Equal(t, http.StatusServiceUnavailable, do(http.MethodGet, "/v1/health", "").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/v1/tts/estimate", `{"text": "Hello"}`).Code, "only synthesis is turned away")
+
+	w = do(http.MethodGet, "/admin/status", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var status AdminStatus
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, StateDrained, status.State)
+	assert.Zero(t, status.InFlight)
+	assert.NotNil(t, status.DrainingSince)
+}
+
+func TestDrainer_QueuedJobFailedByClose(t *testing.T) {
+	drainer := NewDrainer()
+	jobs := queue.New(queue.Config{Workers: 1})
+	router := NewRouter(testConfig(), &mockBackend{ttsResponse: []byte("RIFF")}, testLogger(), WithDrainer(drainer), WithJobs(jobs))
+
+	running, err := jobs.Submit("", func(ctx context.Context) (queue.Result, error) {
+		<-ctx.Done()
+		return queue.Result{}, ctx.Err()
+	})
+	require.NoError(t, err)
+	for job, _ := jobs.Get(running.ID); job.State != queue.StateRunning; job, _ = jobs.Get(running.ID) {
+		time.Sleep(time.Millisecond)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/v1/tts/jobs", strings.NewReader(`{"text": "Hello"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+
+	drainer.Drain()
+	jobs.Close()
+	select {
+	case <-drainer.Drained():
+	case <-time.After(time.Second):
+		t.Fatal("a job failed without running kept the drain waiting")
+	}
+}
@@ -0,0 +1,58 @@
+package api
+
+import "net/http"
+
+// Error codes returned in the "code" field of error responses. Clients should
+// branch on these rather than on the human-readable detail message.
+const (
+	CodeInvalidRequest       = "invalid_request"
+	CodeTextTooLong          = "text_too_long"
+	CodeUnknownModel         = "unknown_model"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeReferenceLocked      = "reference_locked"
+	CodeUploadOffsetMismatch = "upload_offset_mismatch"
+	CodeAudioTooLarge        = "audio_too_large"
+	CodeAudioDuration        = "audio_duration"
+	CodeUnsupportedAudio     = "unsupported_audio"
+	CodeRequestCancelled     = "request_cancelled"
+	CodeRateLimited          = "rate_limited"
+	CodeQuotaExceeded        = "quota_exceeded"
+	CodeQueueFull            = "queue_full"
+	CodeJobNotFinished       = "job_not_finished"
+	CodeJobFailed            = "job_failed"
+	CodeOverloaded           = "overloaded"
+	CodeMaintenance          = "maintenance"
+	CodeDraining             = "draining"
+	CodeDeadlineExceeded     = "deadline_exceeded"
+	CodeBackendTimeout       = "backend_timeout"
+	CodeBackendError         = "backend_error"
+	CodeBackendUnavailable   = "backend_unavailable"
+	CodeInternal             = "internal_error"
+)
+
+// defaultErrorCode returns the catalog code used for status when a handler does
+// not report a more specific one.
+func defaultErrorCode(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusLocked:
+		return CodeReferenceLocked
+	case http.StatusServiceUnavailable:
+		return CodeOverloaded
+	case http.StatusGatewayTimeout:
+		return CodeBackendTimeout
+	case http.StatusBadGateway:
+		return CodeBackendError
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeInvalidRequest
+}
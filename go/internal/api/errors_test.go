@@ -0,0 +1,122 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/backend"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func TestWriteError_DefaultCodes(t *testing.T) {
+	testCases := []struct {
+		status int
+		code   string
+	}{
+		{http.StatusBadRequest, CodeInvalidRequest},
+		{http.StatusUnauthorized, CodeUnauthorized},
+		{http.StatusForbidden, CodeForbidden},
+		{http.StatusNotFound, CodeNotFound},
+		{http.StatusLocked, CodeReferenceLocked},
+		{http.StatusInternalServerError, CodeInternal},
+		{http.StatusBadGateway, CodeBackendError},
+		{http.StatusServiceUnavailable, CodeOverloaded},
+		{http.StatusGatewayTimeout, CodeBackendTimeout},
+	}
+
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		WriteError(&versionWriter{ResponseWriter: w, version: APIVersion2}, tc.status, "message")
+
+		var resp V2ErrorResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "message", resp.Error.Message)
+		assert.Equal(t, tc.code, resp.Error.Code, "status %d", tc.status)
+	}
+}
+
+func TestWriteError_V1DetailOnly(t *testing.T) {
+	for _, write := range []func(http.ResponseWriter){
+		func(w http.ResponseWriter) { WriteError(w, http.StatusNotFound, "message") },
+		func(w http.ResponseWriter) { WriteErrorCode(w, http.StatusBadRequest, CodeTextTooLong, "message") },
+		func(w http.ResponseWriter) {
+			WriteRetryError(w, http.StatusServiceUnavailable, CodeQueueFull, "message", time.Second)
+		},
+	} {
+		w := httptest.NewRecorder()
+		write(w)
+		assert.Equal(t, "{\"detail\":\"message\"}\n", w.Body.String())
+	}
+}
+
+func TestHandleTTS_ErrorCodes(t *testing.T) {
+	testCases := []struct {
+		name   string
+		mock   *mockBackend
+		text   string
+		status int
+		code   string
+	}{
+		{
+			name:   "text too long",
+			mock:   &mockBackend{},
+			text:   "this text is longer than the limit",
+			status: http.StatusBadRequest,
+			code:   CodeTextTooLong,
+		},
+		{
+			name:   "unknown model",
+			mock:   &mockBackend{ttsErr: &backend.UnknownModelError{Model: "large"}},
+			text:   "hi",
+			status: http.StatusBadRequest,
+			code:   CodeUnknownModel,
+		},
+		{
+			name:   "backend timeout",
+			mock:   &mockBackend{ttsErr: backend.ErrBackendTimeout},
+			text:   "hi",
+			status: http.StatusGatewayTimeout,
+			code:   CodeBackendTimeout,
+		},
+		{
+			name:   "backend unavailable",
+			mock:   &mockBackend{ttsErr: backend.ErrBackendUnavailable},
+			text:   "hi",
+			status: http.StatusBadGateway,
+			code:   CodeBackendUnavailable,
+		},
+		{
+			name:   "backend saturated",
+			mock:   &mockBackend{ttsErr: backend.ErrBackendSaturated},
+			text:   "hi",
+			status: http.StatusServiceUnavailable,
+			code:   CodeOverloaded,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.Limits.MaxTextLength = 10
+			h := NewHandler(tc.mock, cfg, testLogger())
+
+			body, _ := json.Marshal(schema.ServeTTSRequest{Text: tc.text})
+			req := httptest.NewRequest(http.MethodPost, "/v2/tts", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			VersionMiddleware(http.HandlerFunc(h.HandleTTS)).ServeHTTP(w, req)
+
+			require.Equal(t, tc.status, w.Code)
+			var resp V2ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tc.code, resp.Error.Code)
+		})
+	}
+}
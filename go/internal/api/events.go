@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/backend"
+	"github.com/fish-speech-go/fish-speech-go/internal/metrics"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+// Structured log events. Every line about one of these carries an "event"
+// field with its name, the request_id of the request it belongs to (and its
+// trace_id when the request is traced), and the fields listed below, so
+// alerts can match on stable keys instead of messages.
+// Durations are always duration_ms and sizes always bytes.
+//
+//	http_request        method, path, status, bytes, duration_ms, key_prefix, user
+//	tts_request         reference_id, format, streaming, text_length, cache, outcome, bytes, queue_wait_ms, duration_ms, error
+//	tts_stream_chunk    chunk, bytes, offset, keepalive (debug level)
+//	backend_call        op, duration_ms, error (debug level unless it failed)
+//	reference_mutation  action, reference_id, alias
+const (
+	EventHTTPRequest       = "http_request"
+	EventTTSRequest        = "tts_request"
+	EventTTSStreamChunk    = "tts_stream_chunk"
+	EventBackendCall       = "backend_call"
+	EventReferenceMutation = "reference_mutation"
+)
+
+// Backend operations reported as the op of backend_call events.
+const (
+	OpTTS             = "tts"
+	OpTTSStream       = "tts_stream"
+	OpVQGANEncode     = "vqgan_encode"
+	OpVQGANDecode     = "vqgan_decode"
+	OpASR             = "asr"
+	OpAddReference    = "add_reference"
+	OpListReferences  = "list_references"
+	OpDeleteReference = "delete_reference"
+	OpRuntimeStats    = "runtime_stats"
+)
+
+// Reference changes reported as the action of reference_mutation events.
+const (
+	ActionAdd          = "add"
+	ActionUpdate       = "update"
+	ActionDelete       = "delete"
+	ActionLock         = "lock"
+	ActionUnlock       = "unlock"
+	ActionLockOverride = "lock_override"
+	ActionSetAlias     = "set_alias"
+	ActionDeleteAlias  = "delete_alias"
+	ActionSetVoice     = "set_voice"
+	ActionDeleteVoice  = "delete_voice"
+)
+
+// requestIDFromContext returns the ID set by RequestIDMiddleware, which
+// backend calls made with ctx forward to the backend.
+func requestIDFromContext(ctx context.Context) string {
+	return backend.RequestIDFromContext(ctx)
+}
+
+// event starts a structured log event at level.
+func (h *Handler) event(ctx context.Context, level zerolog.Level, name string) *zerolog.Event {
+	event := h.logger.WithLevel(level)
+	if !event.Enabled() {
+		return event
+	}
+	event = event.
+		Str("event", name).
+		Str("request_id", requestIDFromContext(ctx))
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		event = event.Str("trace_id", sc.TraceID().String())
+	}
+	return event
+}
+
+// durationMS converts d to the fractional milliseconds logged as duration_ms.
+func durationMS(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// ttsResult is how a synthesis request ended.
+type ttsResult struct {
+	// outcome is one of the metrics.Stream* outcomes.
+	outcome string
+	bytes   int64
+	// queueWait is the time spent waiting for a backend slot.
+	queueWait time.Duration
+	err       error
+}
+
+// logTTSRequest logs the tts_request event that ends a synthesis request.
+func (h *Handler) logTTSRequest(w http.ResponseWriter, r *http.Request, req *schema.ServeTTSRequest, start time.Time, res ttsResult) {
+	level := zerolog.InfoLevel
+	switch res.outcome {
+	case metrics.StreamBackendError:
+		level = zerolog.ErrorLevel
+	case metrics.StreamTimeout, metrics.StreamClientAborted:
+		level = zerolog.WarnLevel
+	}
+
+	var referenceID string
+	if req.ReferenceID != nil {
+		referenceID = *req.ReferenceID
+	}
+	event := h.event(r.Context(), level, EventTTSRequest).
+		Str("reference_id", referenceID).
+		Str("format", req.Format).
+		Bool("streaming", req.Streaming).
+		Int("text_length", len(req.Text)).
+		Str("outcome", res.outcome).
+		Int64("bytes", res.bytes).
+		Float64("queue_wait_ms", durationMS(res.queueWait)).
+		Float64("duration_ms", durationMS(time.Since(start)))
+	if cache := w.Header().Get("X-Cache"); cache != "" {
+		event = event.Str("cache", cache)
+	}
+	if res.err != nil {
+		event = event.Err(res.err)
+	}
+	event.Msg("TTS request")
+}
+
+// logBackendCall logs a backend_call event for op, at error level if it failed.
+func (h *Handler) logBackendCall(ctx context.Context, op string, start time.Time, err error) {
+	h.backendCallEvent(ctx, zerolog.ErrorLevel, op, start, err)
+}
+
+// backendCallEvent logs a backend_call event, at level if it failed, and
+// records the call's latency.
+func (h *Handler) backendCallEvent(ctx context.Context, level zerolog.Level, op string, start time.Time, err error) {
+	duration := time.Since(start)
+	outcome := metrics.BackendError
+	if err == nil {
+		level = zerolog.DebugLevel
+		outcome = metrics.BackendOK
+	}
+	h.metrics.BackendRequestSeconds.WithLabelValues(op, outcome).Observe(duration.Seconds())
+
+	event := h.event(ctx, level, EventBackendCall).
+		Str("op", op).
+		Float64("duration_ms", durationMS(duration))
+	if err != nil {
+		event.Err(err).Msg("Backend call failed")
+		return
+	}
+	event.Msg("Backend call")
+}
+
+// referenceMutation starts a reference_mutation event.
+func (h *Handler) referenceMutation(ctx context.Context, action, referenceID string) *zerolog.Event {
+	level := zerolog.InfoLevel
+	if action == ActionLockOverride {
+		level = zerolog.WarnLevel
+	}
+	return h.event(ctx, level, EventReferenceMutation).
+		Str("action", action).
+		Str("reference_id", referenceID)
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/backend"
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+// logEvents returns the structured events logged to buf, keyed by name.
+func logEvents(t *testing.T, buf *bytes.Buffer) map[string][]map[string]interface{} {
+	t.Helper()
+	events := map[string][]map[string]interface{}{}
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		if name, ok := line["event"].(string); ok {
+			events[name] = append(events[name], line)
+		}
+	}
+	return events
+}
+
+func TestEvents_TTSRequest(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf).Level(zerolog.DebugLevel)
+	router := NewRouter(testConfig(), &mockBackend{ttsResponse: []byte("audio")}, logger)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/tts", strings.NewReader(`{"text":"hello","format":"wav"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-ID", "req-1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	events := logEvents(t, &buf)
+	require.Len(t, events[EventTTSRequest], 1)
+	tts := events[EventTTSRequest][0]
+	assert.Equal(t, "req-1", tts["request_id"])
+	assert.Equal(t, "completed", tts["outcome"])
+	assert.Equal(t, float64(len("audio")), tts["bytes"])
+	assert.Equal(t, float64(len("hello")), tts["text_length"])
+	assert.Equal(t, false, tts["streaming"])
+	assert.Contains(t, tts, "duration_ms")
+
+	require.Len(t, events[EventBackendCall], 1)
+	assert.Equal(t, OpTTS, events[EventBackendCall][0]["op"])
+	assert.Equal(t, "debug", events[EventBackendCall][0]["level"])
+	assert.Equal(t, "req-1", events[EventBackendCall][0]["request_id"])
+
+	require.Len(t, events[EventHTTPRequest], 1)
+	assert.Equal(t, "req-1", events[EventHTTPRequest][0]["request_id"])
+	assert.Equal(t, float64(http.StatusOK), events[EventHTTPRequest][0]["status"])
+}
+
+func TestEvents_RequestIDForwardedToBackend(t *testing.T) {
+	var forwarded []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = append(forwarded, r.Header.Get(backend.RequestIDHeader))
+		w.Write(silentWAV(time.Second))
+	}))
+	defer server.Close()
+	client := backend.NewBackendClient(&config.BackendConfig{URL: server.URL, Timeout: 5 * time.Second})
+	router := NewRouter(testConfig(), client, testLogger())
+
+	for _, streaming := range []bool{false, true} {
+		body, _ := json.Marshal(schema.ServeTTSRequest{Text: "hello", Streaming: streaming})
+		req := httptest.NewRequest(http.MethodPost, "/v1/tts", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Request-ID", "req-1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// Requests without an ID get a generated one, which is forwarded too.
+	req := httptest.NewRequest(http.MethodPost, "/v1/tts", strings.NewReader(`{"text":"hello"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	assert.Equal(t, []string{"req-1", "req-1", w.Header().Get("X-Request-ID")}, forwarded)
+}
+
+func TestEvents_BackendFailure(t *testing.T) {
+	var buf bytes.Buffer
+	router := NewRouter(testConfig(), &mockBackend{ttsErr: backend.ErrBackendUnavailable}, zerolog.New(&buf))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/tts", strings.NewReader(`{"text":"hello"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	events := logEvents(t, &buf)
+	require.Len(t, events[EventBackendCall], 1)
+	call := events[EventBackendCall][0]
+	assert.Equal(t, "error", call["level"])
+	assert.Equal(t, OpTTS, call["op"])
+	assert.NotEmpty(t, call["error"])
+
+	require.Len(t, events[EventTTSRequest], 1)
+	assert.Equal(t, "backend_error", events[EventTTSRequest][0]["outcome"])
+}
+
+func TestEvents_ReferenceMutation(t *testing.T) {
+	var buf bytes.Buffer
+	mock := &mockBackend{deleteRefResp: &schema.DeleteReferenceResponse{Success: true, ReferenceID: "voice"}}
+	router := NewRouter(testConfig(), mock, zerolog.New(&buf))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/v1/references/voice", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	events := logEvents(t, &buf)
+	require.Len(t, events[EventReferenceMutation], 1)
+	assert.Equal(t, ActionDelete, events[EventReferenceMutation][0]["action"])
+	assert.Equal(t, "voice", events[EventReferenceMutation][0]["reference_id"])
+}
@@ -2,26 +2,41 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"regexp"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog"
 
+	"github.com/fish-speech-go/fish-speech-go/internal/audio"
 	"github.com/fish-speech-go/fish-speech-go/internal/backend"
+	"github.com/fish-speech-go/fish-speech-go/internal/cache"
 	"github.com/fish-speech-go/fish-speech-go/internal/config"
+	"github.com/fish-speech-go/fish-speech-go/internal/limiter"
+	"github.com/fish-speech-go/fish-speech-go/internal/metrics"
+	"github.com/fish-speech-go/fish-speech-go/internal/queue"
+	"github.com/fish-speech-go/fish-speech-go/internal/refaudio"
+	"github.com/fish-speech-go/fish-speech-go/internal/refstore"
 	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+	"github.com/fish-speech-go/fish-speech-go/internal/shared"
+	"github.com/fish-speech-go/fish-speech-go/internal/upload"
+	"github.com/fish-speech-go/fish-speech-go/internal/usage"
 )
 
 // HealthResponse represents the health payload including optional backend status.
 type HealthResponse struct {
 	Status  string         `json:"status"`
 	Backend *BackendHealth `json:"backend,omitempty"`
+	// ReferenceStore reports the Go-side reference store used for aliases,
+	// locks and fingerprints.
+	ReferenceStore *ReferenceStoreHealth `json:"reference_store,omitempty"`
 }
 
 // BackendHealth captures backend health diagnostics.
@@ -29,6 +44,28 @@ type BackendHealth struct {
 	Status    string `json:"status"`
 	LatencyMs int64  `json:"latency_ms,omitempty"`
 	Error     string `json:"error,omitempty"`
+	// Stats holds the backend's runtime statistics when it reports them.
+	Stats json.RawMessage `json:"stats,omitempty"`
+}
+
+// ReferenceStoreHealth captures reference store health diagnostics.
+type ReferenceStoreHealth struct {
+	Status    string `json:"status"`
+	Reachable bool   `json:"reachable"`
+	Writable  bool   `json:"writable"`
+	LatencyMs int64  `json:"latency_ms,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// AddReferenceResult extends the upstream add-reference response with
+// server-side checks performed by the Go layer.
+type AddReferenceResult struct {
+	schema.AddReferenceResponse
+	DuplicateOf          string   `json:"duplicate_of,omitempty"`
+	Similarity           float64  `json:"similarity,omitempty"`
+	Transcription        string   `json:"transcription,omitempty"`
+	TranscriptSimilarity *float64 `json:"transcript_similarity,omitempty"`
+	Warnings             []string `json:"warnings,omitempty"`
 }
 
 // Handler encapsulates dependencies for HTTP handlers.
@@ -36,16 +73,158 @@ type Handler struct {
 	backend backend.Backend
 	config  *config.Config
 	logger  zerolog.Logger
+	refs    *refstore.Store
+	archive *refaudio.Store
+	limiter *limiter.Limiter
+	metrics *metrics.Metrics
+	secrets SecretSource
+	uploads *upload.Store
+	jobs    *queue.Manager
+	stats   *statsCache
+	cache   *cache.Cache
+	shared  *shared.Store
+	usage   *usage.Meter
+	drain   *Drainer
+	started time.Time
+
+	accessLog    *AccessLog
+	recentErrors *errorLog
+}
+
+// Option configures optional Handler dependencies.
+type Option func(*Handler)
+
+// WithMetrics sets the metrics the handler records to and serves on /metrics.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(h *Handler) {
+		h.metrics = m
+	}
+}
+
+// WithSecrets sets the source of API keys named by auth.api_key_secret and
+// auth.key_secrets.
+func WithSecrets(secrets SecretSource) Option {
+	return func(h *Handler) {
+		h.secrets = secrets
+	}
+}
+
+// WithReferenceStore sets the store used for Go-side reference state such as aliases.
+func WithReferenceStore(store *refstore.Store) Option {
+	return func(h *Handler) {
+		h.refs = store
+	}
+}
+
+// WithReferenceAudio keeps a copy of reference audio in store, enabling
+// reference export and import.
+func WithReferenceAudio(store *refaudio.Store) Option {
+	return func(h *Handler) {
+		h.archive = store
+	}
+}
+
+// WithUploadStore enables resumable reference uploads kept in store.
+func WithUploadStore(store *upload.Store) Option {
+	return func(h *Handler) {
+		h.uploads = store
+	}
+}
+
+// WithJobs enables the asynchronous TTS job API, running jobs on m.
+func WithJobs(m *queue.Manager) Option {
+	return func(h *Handler) {
+		h.jobs = m
+	}
+}
+
+// WithCache enables caching of non-streaming TTS responses in c.
+func WithCache(c *cache.Cache) Option {
+	return func(h *Handler) {
+		h.cache = c
+	}
+}
+
+// WithSharedStore shares API key rate limits and the backend's reference list
+// with other servers through s, as enabled by the redis config.
+func WithSharedStore(s *shared.Store) Option {
+	return func(h *Handler) {
+		h.shared = s
+	}
+}
+
+// WithAccessLog writes request lines to l instead of the application logger.
+// A nil l disables access logging.
+func WithAccessLog(l *AccessLog) Option {
+	return func(h *Handler) {
+		h.accessLog = l
+	}
+}
+
+// WithUsageMeter counts the usage of each API key in m, enforcing their
+// daily and monthly usage quotas.
+func WithUsageMeter(m *usage.Meter) Option {
+	return func(h *Handler) {
+		h.usage = m
+	}
+}
+
+// WithDrainer admits TTS requests and jobs through d, so that the server can
+// be put into maintenance or drained before shutting down.
+func WithDrainer(d *Drainer) Option {
+	return func(h *Handler) {
+		h.drain = d
+	}
 }
 
 // NewHandler constructs a Handler.
-func NewHandler(backend backend.Backend, cfg *config.Config, logger zerolog.Logger) *Handler {
-	return &Handler{backend: backend, config: cfg, logger: logger}
+func NewHandler(backend backend.Backend, cfg *config.Config, logger zerolog.Logger, opts ...Option) *Handler {
+	h := &Handler{
+		backend: backend,
+		config:  cfg,
+		logger:  logger,
+		limiter: newLimiter(cfg.Limits),
+		stats:   &statsCache{ttl: cfg.Backend.StatsCacheTTL},
+		started: time.Now(),
+
+		accessLog:    &AccessLog{format: AccessLogJSON, logger: logger},
+		recentErrors: &errorLog{},
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.refs == nil {
+		h.refs = refstore.New()
+	}
+	if h.metrics == nil {
+		h.metrics = metrics.New()
+	}
+	if h.usage == nil {
+		h.usage = usage.New()
+	}
+	if h.drain == nil {
+		h.drain = NewDrainer()
+	}
+	return h
+}
+
+// healthStatus returns the health status and response status code: ok, or
+// maintenance, which load balancers may still route to, or draining with 503
+// so they stop routing to a server about to shut down.
+func (h *Handler) healthStatus() (string, int) {
+	switch state, _ := h.drain.state(); state {
+	case StateMaintenance:
+		return StateMaintenance, http.StatusOK
+	case StateDraining, StateDrained:
+		return StateDraining, http.StatusServiceUnavailable
+	}
+	return "ok", http.StatusOK
 }
 
 // Health Handlers
 func (h *Handler) HandleHealthGet(w http.ResponseWriter, r *http.Request) {
-	response := HealthResponse{Status: "ok"}
+	status, code := h.healthStatus()
+	response := HealthResponse{Status: status}
 
 	if r.URL.Query().Get("detailed") == "true" {
 		start := time.Now()
@@ -56,65 +235,200 @@ func (h *Handler) HandleHealthGet(w http.ResponseWriter, r *http.Request) {
 			response.Backend = &BackendHealth{Status: "unhealthy", LatencyMs: latency, Error: err.Error()}
 		} else {
 			response.Backend = &BackendHealth{Status: "healthy", LatencyMs: latency}
+			response.Backend.Stats, _, _ = h.backendRuntimeStats(r.Context())
 		}
+		response.ReferenceStore = h.referenceStoreHealth()
 	}
 
-	WriteJSON(w, http.StatusOK, response)
+	WriteJSON(w, code, response)
+}
+
+func (h *Handler) referenceStoreHealth() *ReferenceStoreHealth {
+	start := time.Now()
+	err := h.refs.Check()
+	health := &ReferenceStoreHealth{Status: "healthy", Reachable: true, Writable: true, LatencyMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		health.Status = "unhealthy"
+		health.Reachable = errors.Is(err, refstore.ErrNotWritable)
+		health.Writable = false
+		health.Error = err.Error()
+	}
+	return health
 }
 
 func (h *Handler) HandleHealthPost(w http.ResponseWriter, r *http.Request) {
-	WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	status, code := h.healthStatus()
+	WriteJSON(w, code, map[string]string{"status": status})
 }
 
 // TTS Handler
 func (h *Handler) HandleTTS(w http.ResponseWriter, r *http.Request) {
-	req, err := ParseTTSRequest(r)
+	req, ok := h.parseTTSRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if req.Streaming {
+		h.handleStreamingTTS(w, r, req)
+		return
+	}
+
+	h.handleNonStreamingTTS(w, r, req)
+}
+
+// parseTTSRequest parses and validates a TTS request, resolving its reference
+// alias. It writes an error response and returns false if the request is
+// invalid.
+func (h *Handler) parseTTSRequest(w http.ResponseWriter, r *http.Request) (*schema.ServeTTSRequest, bool) {
+	priority, err := requestPriority(r)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	if err := authorizePriority(r.Context(), priority); err != nil {
+		WriteError(w, http.StatusForbidden, err.Error())
+		return nil, false
+	}
+
+	req, err := parseTTSRequestWith(r, func(req *schema.ServeTTSRequest) error {
+		return h.prepareTTSRequest(r.Context(), req)
+	})
 	if err != nil {
 		h.handleParseError(w, err)
-		return
+		return nil, false
 	}
 
 	if h.config.Limits.MaxTextLength > 0 && len(req.Text) > h.config.Limits.MaxTextLength {
-		WriteError(w, http.StatusBadRequest, fmt.Sprintf("Text is too long, max length is %d", h.config.Limits.MaxTextLength))
-		return
+		WriteErrorCode(w, http.StatusBadRequest, CodeTextTooLong, fmt.Sprintf("Text is too long, max length is %d", h.config.Limits.MaxTextLength))
+		return nil, false
 	}
 
-	if req.Streaming && req.Format != "wav" {
-		WriteError(w, http.StatusBadRequest, "Streaming only supports WAV format")
-		return
+	if err := checkFormat(req); err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return nil, false
 	}
 
-	if req.Streaming {
-		h.handleStreamingTTS(w, r, req)
-		return
+	if err := checkStreamingFormat(req); err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return nil, false
 	}
 
-	h.handleNonStreamingTTS(w, r, req)
+	if err := validatePostProcessing(req); err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+
+	if err := validateEncoding(req); err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+
+	if req.ReferenceID != nil {
+		referenceID := h.refs.Resolve(scopeReferenceID(namespaceFromContext(r.Context()), *req.ReferenceID))
+		req.ReferenceID = &referenceID
+	}
+
+	if perr := h.checkUsageQuota(r.Context(), req.Text, time.Now()); perr != nil {
+		perr.write(w)
+		return nil, false
+	}
+
+	return req, true
 }
 
 func (h *Handler) handleNonStreamingTTS(w http.ResponseWriter, r *http.Request, req *schema.ServeTTSRequest) {
-	audioData, format, err := h.backend.TTS(r.Context(), req)
+	requestStart := time.Now()
+	cacheKey, served, ok := h.serveCachedTTS(w, r, req)
+	if ok {
+		h.recordUsage(PrincipalFromContext(r.Context()), req, estimateTTS(req).DurationSeconds)
+		h.logTTSRequest(w, r, req, requestStart, ttsResult{outcome: metrics.StreamCompleted, bytes: served})
+		return
+	}
+
+	release, queueWait, ok := h.acquireSlot(w, r, req)
+	if !ok {
+		return
+	}
+
+	start := time.Now()
+	audioData, format, err := h.backend.TTS(r.Context(), h.backendRequest(req))
+	release(time.Since(start), err)
+	h.logBackendCall(r.Context(), OpTTS, start, err)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("TTS backend error")
+		h.logTTSRequest(w, r, req, requestStart, ttsResult{outcome: streamOutcome(r.Context(), err, false), queueWait: queueWait, err: err})
 		h.handleBackendError(w, err)
 		return
 	}
 
+	raw := audioData
+	audioData, format, err = h.finishAudio(req, audioData, format)
+	if err != nil {
+		h.logTTSRequest(w, r, req, requestStart, ttsResult{outcome: metrics.StreamBackendError, queueWait: queueWait, err: fmt.Errorf("post-processing: %w", err)})
+		WriteError(w, http.StatusBadGateway, "Backend returned audio that could not be processed")
+		return
+	}
+
+	h.recordUsage(PrincipalFromContext(r.Context()), req, audioSeconds(req, raw, audioData))
+	h.storeCachedTTS(cacheKey, req, format, audioData)
 	WriteAudio(w, format, audioData)
+	h.logTTSRequest(w, r, req, requestStart, ttsResult{outcome: metrics.StreamCompleted, bytes: int64(len(audioData)), queueWait: queueWait})
 }
 
 func (h *Handler) handleStreamingTTS(w http.ResponseWriter, r *http.Request, req *schema.ServeTTSRequest) {
-	stream, err := h.backend.TTSStream(r.Context(), req)
+	requestStart := time.Now()
+	release, queueWait, ok := h.acquireSlot(w, r, req)
+	if !ok {
+		return
+	}
+
+	// Streams outlive the server WriteTimeout; instead, the backend request is
+	// cancelled and the write deadline expires once no audio moved for idle.
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	idle := h.config.Server.StreamIdleTimeout
+	var stalled atomic.Bool
+	var watchdog *time.Timer
+	if idle > 0 {
+		watchdog = time.AfterFunc(idle, func() {
+			stalled.Store(true)
+			cancel()
+		})
+		defer watchdog.Stop()
+	}
+
+	start := time.Now()
+	stream, err := h.backend.TTSStream(ctx, h.backendRequest(req))
+	h.logBackendCall(r.Context(), OpTTSStream, start, err)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("TTS streaming backend error")
+		release(time.Since(start), err)
+		h.recordStream(r.Context(), err, stalled.Load(), 0)
+		h.logTTSRequest(w, r, req, requestStart, ttsResult{outcome: streamOutcome(r.Context(), err, stalled.Load()), queueWait: queueWait, err: err})
+		if stalled.Load() {
+			WriteErrorCode(w, http.StatusGatewayTimeout, CodeBackendTimeout, "Request timeout")
+			return
+		}
 		h.handleBackendError(w, err)
 		return
 	}
+	// The slot is held for the whole stream, but only the time until the
+	// backend responded feeds the adaptive limit.
+	latency := time.Since(start)
+	defer release(latency, nil)
 	defer stream.Close()
+	h.metrics.ActiveStreams.Inc()
+	defer h.metrics.ActiveStreams.Dec()
+
+	meter := &wavMeter{r: stream}
+	var body io.Reader = meter
+	if h.transcodes(req) || req.HasPostProcessing() {
+		encoded := audio.TranscodeWAVStream(meter, req.Format, processOptions(req), h.encodeOptions(req))
+		defer encoded.Close()
+		body = encoded
+	}
 
-	w.Header().Set("Content-Type", "audio/wav")
+	w.Header().Set("Content-Type", GetAudioContentType(req.Format))
 	w.Header().Set("Transfer-Encoding", "chunked")
-	w.Header().Set("Content-Disposition", "attachment; filename=audio.wav")
+	w.Header().Set("Content-Disposition", "attachment; filename=audio."+req.Format)
 
 	flusher, ok := w.(http.Flusher)
 	if !ok {
@@ -122,21 +436,23 @@ func (h *Handler) handleStreamingTTS(w http.ResponseWriter, r *http.Request, req
 		return
 	}
 
-	buf := make([]byte, 4096)
-	for {
-		n, err := stream.Read(buf)
-		if n > 0 {
-			if _, writeErr := w.Write(buf[:n]); writeErr == nil {
-				flusher.Flush()
-			}
-		}
-
-		if err == io.EOF {
-			break
+	written, err := h.copyStream(ctx, w, flusher, body, watchdog)
+	h.recordStream(r.Context(), err, stalled.Load(), written)
+	if written > 0 {
+		// Audio cut short is metered for what the backend produced.
+		seconds := estimateTTS(req).DurationSeconds
+		if d, ok := meter.duration(); ok {
+			seconds = speedSeconds(d, req.Speed)
 		}
-		if err != nil {
-			h.logger.Error().Err(err).Msg("Error streaming audio")
-			break
+		h.recordUsage(PrincipalFromContext(r.Context()), req, seconds)
+	}
+	if err != nil && stalled.Load() {
+		err = fmt.Errorf("no audio for %s: %w", idle, err)
+	}
+	h.logTTSRequest(w, r, req, requestStart, ttsResult{outcome: streamOutcome(r.Context(), err, stalled.Load()), bytes: written, queueWait: queueWait, err: err})
+	if err != nil {
+		if !errors.Is(err, errClientWrite) && !errors.Is(r.Context().Err(), context.Canceled) {
+			h.recentErrors.add(fmt.Errorf("stream: %w", err))
 		}
 	}
 }
@@ -153,10 +469,17 @@ func (h *Handler) HandleVQGANEncode(w http.ResponseWriter, r *http.Request) {
 		WriteError(w, http.StatusBadRequest, "No audio provided")
 		return
 	}
+	for i, data := range req.Audios {
+		if _, err := audio.Sniff(data, ""); err != nil {
+			WriteErrorCode(w, http.StatusUnsupportedMediaType, CodeUnsupportedAudio, fmt.Sprintf("audios[%d]: %v", i, err))
+			return
+		}
+	}
 
+	start := time.Now()
 	resp, err := h.backend.VQGANEncode(r.Context(), &req)
+	h.logBackendCall(r.Context(), OpVQGANEncode, start, err)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("VQGAN encode error")
 		h.handleBackendError(w, err)
 		return
 	}
@@ -176,9 +499,10 @@ func (h *Handler) HandleVQGANDecode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	start := time.Now()
 	resp, err := h.backend.VQGANDecode(r.Context(), &req)
+	h.logBackendCall(r.Context(), OpVQGANDecode, start, err)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("VQGAN decode error")
 		h.handleBackendError(w, err)
 		return
 	}
@@ -189,61 +513,179 @@ func (h *Handler) HandleVQGANDecode(w http.ResponseWriter, r *http.Request) {
 // Reference handlers
 func (h *Handler) HandleAddReference(w http.ResponseWriter, r *http.Request) {
 	var req schema.AddReferenceRequest
+	var declared string
 
 	contentType := r.Header.Get("Content-Type")
 	if strings.HasPrefix(contentType, "multipart/form-data") {
-		if err := r.ParseMultipartForm(32 << 20); err != nil {
-			WriteError(w, http.StatusBadRequest, "Failed to parse form data")
-			return
-		}
-
-		req.ID = r.FormValue("id")
-		req.Text = r.FormValue("text")
-
-		file, _, err := r.FormFile("audio")
-		if err != nil {
-			WriteError(w, http.StatusBadRequest, "Audio file required")
-			return
-		}
-		defer file.Close()
-
-		audioBytes, err := io.ReadAll(file)
-		if err != nil {
-			WriteError(w, http.StatusBadRequest, "Failed to read audio file")
+		var err error
+		if declared, err = parseAddReferenceForm(r, &req, h.config.References.MaxAudioBytes); err != nil {
+			h.handleParseError(w, err)
 			return
 		}
-		req.Audio = audioBytes
 	} else {
+		if limit := h.referenceBodyLimit(); limit > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
 		if err := ParseRequestBody(r, &req); err != nil {
+			// MaxBytesReader keeps failing once the limit is hit.
+			if _, readErr := r.Body.Read(nil); isMaxBytesError(readErr) {
+				h.checkReferenceSize(w, h.config.References.MaxAudioBytes+1)
+				return
+			}
 			h.handleParseError(w, err)
 			return
 		}
 	}
 
-	if err := validateAddReferenceRequest(&req); err != nil {
+	h.addReference(w, r, &req, declared)
+}
+
+// addReference validates and adds a reference whose audio has been received,
+// reporting duplicates and, on request, transcript mismatches. declared is the
+// audio format claimed by the upload, if any. It writes the response and
+// reports whether the reference was added.
+func (h *Handler) addReference(w http.ResponseWriter, r *http.Request, req *schema.AddReferenceRequest, declared string) bool {
+	if err := validateAddReferenceRequest(req); err != nil {
 		WriteError(w, http.StatusBadRequest, err.Error())
-		return
+		return false
 	}
+	data, ok := h.checkReferenceAudio(w, req.Audio, declared)
+	if !ok {
+		return false
+	}
+	req.Audio = data
+
+	namespace := namespaceFromContext(r.Context())
+	req.ID = scopeReferenceID(namespace, req.ID)
 
-	resp, err := h.backend.AddReference(r.Context(), &req)
+	if _, ok := h.refs.Alias(req.ID); ok {
+		// TTS requests for the ID would resolve to the alias's target instead.
+		WriteError(w, http.StatusConflict, "reference_id is already an alias; delete the alias first")
+		return false
+	}
+	if !h.checkReferenceLock(w, r, req.ID) {
+		return false
+	}
+
+	fingerprint := referenceFingerprint(req.Audio)
+	duplicate, ok := h.findDuplicateReference(w, namespace, req.ID, fingerprint)
+	if !ok {
+		return false
+	}
+
+	start := time.Now()
+	resp, err := h.backend.AddReference(r.Context(), req)
+	h.logBackendCall(r.Context(), OpAddReference, start, err)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("Add reference error")
 		h.handleBackendError(w, err)
-		return
+		return false
 	}
+	resp.ReferenceID = unscopeReferenceID(namespace, resp.ReferenceID)
 
-	WriteJSON(w, http.StatusOK, resp)
+	if err := h.refs.SetFingerprint(req.ID, fingerprint); err != nil {
+		h.logger.Warn().Err(err).Str("reference_id", req.ID).Msg("Failed to store reference fingerprint")
+	}
+	h.saveReferenceMetadata(req.ID, req.ReferenceMetadata)
+	h.saveReferenceAudio(req.ID, req.Audio, req.Text)
+	h.invalidateCachedReference(req.ID)
+	h.referenceMutation(r.Context(), ActionAdd, req.ID).Msg("Reference added")
+
+	result := AddReferenceResult{AddReferenceResponse: *resp}
+	if duplicate != nil {
+		result.DuplicateOf = unscopeReferenceID(namespace, duplicate.ID)
+		result.Similarity = duplicate.Similarity
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Audio duplicates existing reference '%s'", result.DuplicateOf))
+	}
+	if wantsTranscriptVerification(r) {
+		h.verifyTranscript(r.Context(), req, &result)
+	}
+
+	WriteJSON(w, http.StatusOK, result)
+	return true
+}
+
+// maxFormValueBytes bounds the non-file fields of a reference upload.
+const maxFormValueBytes = 1 << 20
+
+// parseAddReferenceForm reads a multipart reference upload part by part, so
+// the audio is read into memory once instead of first being spooled by
+// ParseMultipartForm. At most maxAudio+1 bytes of audio are read when maxAudio
+// is positive, enough for the caller to reject it as too large. It returns the
+// audio format declared by the part's Content-Type or file name.
+func parseAddReferenceForm(r *http.Request, req *schema.AddReferenceRequest, maxAudio int64) (string, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return "", NewParseError(http.StatusBadRequest, "Failed to parse form data")
+	}
+
+	hasAudio := false
+	declared := ""
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", NewParseError(http.StatusBadRequest, "Failed to parse form data")
+		}
+
+		switch part.FormName() {
+		case "id", "text":
+			value, err := io.ReadAll(io.LimitReader(part, maxFormValueBytes))
+			if err != nil {
+				return "", NewParseError(http.StatusBadRequest, "Failed to parse form data")
+			}
+			if part.FormName() == "id" {
+				req.ID = string(value)
+			} else {
+				req.Text = string(value)
+			}
+		case "display_name", "language", "gender", "description", "tags", "created_at":
+			value, err := io.ReadAll(io.LimitReader(part, maxFormValueBytes))
+			if err != nil {
+				return "", NewParseError(http.StatusBadRequest, "Failed to parse form data")
+			}
+			if err := setReferenceFormField(&req.ReferenceMetadata, part.FormName(), string(value)); err != nil {
+				return "", NewParseError(http.StatusBadRequest, err.Error())
+			}
+		case "audio":
+			var src io.Reader = part
+			if maxAudio > 0 {
+				src = io.LimitReader(part, maxAudio+1)
+			}
+			declared = audio.DeclaredFormat(part.FileName(), part.Header.Get("Content-Type"))
+			if req.Audio, err = io.ReadAll(src); err != nil {
+				return "", NewParseError(http.StatusBadRequest, "Failed to read audio file")
+			}
+			hasAudio = true
+		}
+		part.Close()
+	}
+
+	if !hasAudio {
+		return "", NewParseError(http.StatusBadRequest, "Audio file required")
+	}
+	if req.ID == "" {
+		req.ID = r.URL.Query().Get("id")
+	}
+	if req.Text == "" {
+		req.Text = r.URL.Query().Get("text")
+	}
+	return declared, nil
 }
 
 func (h *Handler) HandleListReferences(w http.ResponseWriter, r *http.Request) {
-	resp, err := h.backend.ListReferences(r.Context())
+	resp, err := h.listReferences(r.Context())
 	if err != nil {
-		h.logger.Error().Err(err).Msg("List references error")
 		h.handleBackendError(w, err)
 		return
 	}
+	namespace := namespaceFromContext(r.Context())
+	result := ListReferencesResult{ListReferencesResponse: *resp}
+	result.ReferenceIDs = filterNamespace(namespace, resp.ReferenceIDs)
+	result.References = h.referenceInfos(namespace, result.ReferenceIDs)
 
-	WriteJSON(w, http.StatusOK, resp)
+	WriteJSON(w, http.StatusOK, result)
 }
 
 func (h *Handler) HandleDeleteReference(w http.ResponseWriter, r *http.Request) {
@@ -253,27 +695,51 @@ func (h *Handler) HandleDeleteReference(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	resp, err := h.backend.DeleteReference(r.Context(), id)
+	namespace := namespaceFromContext(r.Context())
+	backendID := scopeReferenceID(namespace, id)
+
+	if !h.checkReferenceLock(w, r, backendID) {
+		return
+	}
+
+	start := time.Now()
+	resp, err := h.backend.DeleteReference(r.Context(), backendID)
+	h.logBackendCall(r.Context(), OpDeleteReference, start, err)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("Delete reference error")
 		h.handleBackendError(w, err)
 		return
 	}
+	if err := h.refs.Forget(backendID); err != nil {
+		h.logger.Warn().Err(err).Str("reference_id", backendID).Msg("Failed to clear reference state")
+	}
+	h.deleteReferenceAudio(backendID)
+	h.invalidateCachedReference(backendID)
+	h.referenceMutation(r.Context(), ActionDelete, backendID).Msg("Reference deleted")
+	resp.ReferenceID = unscopeReferenceID(namespace, resp.ReferenceID)
 
 	WriteJSON(w, http.StatusOK, resp)
 }
 
-func validateAddReferenceRequest(req *schema.AddReferenceRequest) error {
-	if req.ID == "" {
-		return errors.New("id is required")
+var referenceIDPattern = regexp.MustCompile(`^[a-zA-Z0-9\-_ ]+$`)
+
+func validateReferenceID(field, id string) error {
+	if id == "" {
+		return fmt.Errorf("%s is required", field)
 	}
-	if len(req.ID) > 255 {
-		return errors.New("id must be 255 characters or less")
+	if len(id) > 255 {
+		return fmt.Errorf("%s must be 255 characters or less", field)
 	}
 
-	validID := regexp.MustCompile(`^[a-zA-Z0-9\-_ ]+$`)
-	if !validID.MatchString(req.ID) {
-		return errors.New("id must contain only alphanumeric characters, dashes, underscores, and spaces")
+	if !referenceIDPattern.MatchString(id) {
+		return fmt.Errorf("%s must contain only alphanumeric characters, dashes, underscores, and spaces", field)
+	}
+
+	return nil
+}
+
+func validateAddReferenceRequest(req *schema.AddReferenceRequest) error {
+	if err := validateReferenceID("id", req.ID); err != nil {
+		return err
 	}
 
 	if len(req.Audio) == 0 {
@@ -284,23 +750,39 @@ func validateAddReferenceRequest(req *schema.AddReferenceRequest) error {
 		return errors.New("text is required")
 	}
 
-	return nil
+	return validateReferenceMetadata(&req.ReferenceMetadata)
 }
 
 func (h *Handler) handleBackendError(w http.ResponseWriter, err error) {
 	if errors.Is(err, context.DeadlineExceeded) {
-		WriteError(w, http.StatusGatewayTimeout, "Request timeout")
+		WriteErrorCode(w, http.StatusGatewayTimeout, CodeDeadlineExceeded, "Request timeout")
 		return
 	}
 	if errors.Is(err, context.Canceled) {
-		WriteError(w, http.StatusBadRequest, "Request cancelled")
+		WriteErrorCode(w, http.StatusBadRequest, CodeRequestCancelled, "Request cancelled")
 		return
 	}
+	h.recentErrors.add(err)
 
 	if errors.Is(err, backend.ErrBackendTimeout) {
 		WriteError(w, http.StatusGatewayTimeout, "Request timeout")
 		return
 	}
+	if errors.Is(err, backend.ErrBackendSaturated) {
+		var wait time.Duration
+		var saturated *backend.SaturatedError
+		if errors.As(err, &saturated) {
+			wait = saturated.EstimatedWait
+		}
+		WriteRetryError(w, http.StatusServiceUnavailable, CodeOverloaded, "Backend queue is full, please retry later", wait)
+		return
+	}
+
+	var modelErr *backend.UnknownModelError
+	if errors.As(err, &modelErr) {
+		WriteErrorCode(w, http.StatusBadRequest, CodeUnknownModel, fmt.Sprintf("Unknown model: %s", modelErr.Model))
+		return
+	}
 
 	var backendErr *backend.BackendError
 	if errors.As(err, &backendErr) {
@@ -315,7 +797,7 @@ func (h *Handler) handleBackendError(w http.ResponseWriter, err error) {
 		return
 	}
 
-	WriteError(w, http.StatusBadGateway, "Backend service unavailable")
+	WriteErrorCode(w, http.StatusBadGateway, CodeBackendUnavailable, "Backend service unavailable")
 }
 
 func (h *Handler) handleParseError(w http.ResponseWriter, err error) {
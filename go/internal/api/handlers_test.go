@@ -3,19 +3,26 @@ package api
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/fish-speech-go/fish-speech-go/internal/backend"
 	"github.com/fish-speech-go/fish-speech-go/internal/config"
+	"github.com/fish-speech-go/fish-speech-go/internal/refstore"
 	"github.com/fish-speech-go/fish-speech-go/internal/schema"
 )
 
@@ -34,6 +41,12 @@ type mockBackend struct {
 	listRefErr      error
 	deleteRefResp   *schema.DeleteReferenceResponse
 	deleteRefErr    error
+	asrResp         *schema.ServeASRResponse
+	asrErr          error
+
+	lastTTSReq    *schema.ServeTTSRequest
+	lastAddRefReq *schema.AddReferenceRequest
+	lastDeleteID  string
 }
 
 func (m *mockBackend) Health(ctx context.Context) error {
@@ -41,6 +54,7 @@ func (m *mockBackend) Health(ctx context.Context) error {
 }
 
 func (m *mockBackend) TTS(ctx context.Context, req *schema.ServeTTSRequest) ([]byte, string, error) {
+	m.lastTTSReq = req
 	if m.ttsErr != nil {
 		return nil, "", m.ttsErr
 	}
@@ -62,7 +76,12 @@ func (m *mockBackend) VQGANDecode(ctx context.Context, req *schema.ServeVQGANDec
 	return m.vqganDecodeResp, m.vqganDecodeErr
 }
 
+func (m *mockBackend) ASR(ctx context.Context, req *schema.ServeASRRequest) (*schema.ServeASRResponse, error) {
+	return m.asrResp, m.asrErr
+}
+
 func (m *mockBackend) AddReference(ctx context.Context, req *schema.AddReferenceRequest) (*schema.AddReferenceResponse, error) {
+	m.lastAddRefReq = req
 	return m.addRefResp, m.addRefErr
 }
 
@@ -71,6 +90,7 @@ func (m *mockBackend) ListReferences(ctx context.Context) (*schema.ListReference
 }
 
 func (m *mockBackend) DeleteReference(ctx context.Context, id string) (*schema.DeleteReferenceResponse, error) {
+	m.lastDeleteID = id
 	return m.deleteRefResp, m.deleteRefErr
 }
 
@@ -124,6 +144,32 @@ func TestHealthGet_Detailed_BackendUnhealthy(t *testing.T) {
 	assert.Equal(t, "unhealthy", resp.Backend.Status)
 }
 
+func TestHealthGet_Detailed_ReferenceStore(t *testing.T) {
+	store, err := refstore.Open(filepath.Join(t.TempDir(), "missing", "references.json"))
+	require.NoError(t, err)
+	h := NewHandler(&mockBackend{}, testConfig(), testLogger(), WithReferenceStore(store))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/health?detailed=true", nil)
+	w := httptest.NewRecorder()
+
+	h.HandleHealthGet(w, req)
+
+	var resp HealthResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	require.NotNil(t, resp.ReferenceStore)
+	assert.Equal(t, "unhealthy", resp.ReferenceStore.Status)
+	assert.False(t, resp.ReferenceStore.Reachable)
+	assert.NotEmpty(t, resp.ReferenceStore.Error)
+
+	h = NewHandler(&mockBackend{}, testConfig(), testLogger())
+	w = httptest.NewRecorder()
+	h.HandleHealthGet(w, req)
+	resp = HealthResponse{}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Equal(t, "healthy", resp.ReferenceStore.Status)
+	assert.True(t, resp.ReferenceStore.Writable)
+}
+
 // VQGAN tests
 func TestVQGANEncode_Success(t *testing.T) {
 	mock := &mockBackend{vqganEncodeResp: &schema.ServeVQGANEncodeResponse{Tokens: [][][]int{{{1, 2, 3}}}}}
@@ -204,6 +250,52 @@ func TestAddReference_Success(t *testing.T) {
 	assert.Equal(t, "test-voice", resp.ReferenceID)
 }
 
+func TestAddReference_Multipart(t *testing.T) {
+	mock := &mockBackend{addRefResp: &schema.AddReferenceResponse{Success: true, ReferenceID: "test-voice"}}
+	h := NewHandler(mock, testConfig(), testLogger())
+
+	// The audio part comes first; fields are read in any order.
+	wav := silentWAV(time.Second)
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("audio", "voice.wav")
+	fw.Write(wav)
+	mw.WriteField("id", "test-voice")
+	mw.WriteField("text", "This is a test transcript")
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/references/add", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+
+	h.HandleAddReference(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, mock.lastAddRefReq)
+	assert.Equal(t, "test-voice", mock.lastAddRefReq.ID)
+	assert.Equal(t, "This is a test transcript", mock.lastAddRefReq.Text)
+	assert.Equal(t, wav, mock.lastAddRefReq.Audio)
+}
+
+func TestAddReference_MultipartWithoutAudio(t *testing.T) {
+	h := NewHandler(&mockBackend{}, testConfig(), testLogger())
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("id", "test-voice")
+	mw.WriteField("text", "transcript")
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/references/add", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+
+	h.HandleAddReference(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Audio file required")
+}
+
 func TestAddReference_InvalidID(t *testing.T) {
 	h := NewHandler(&mockBackend{}, testConfig(), testLogger())
 
@@ -264,6 +356,25 @@ func TestDeleteReference_Success(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 }
 
+// TTS tests
+func TestTTS_Success_ChecksumHeader(t *testing.T) {
+	audio := []byte("fake audio data")
+	mock := &mockBackend{ttsResponse: audio}
+	h := NewHandler(mock, testConfig(), testLogger())
+
+	reqBody, _ := json.Marshal(schema.ServeTTSRequest{Text: "Hello"})
+	req := httptest.NewRequest(http.MethodPost, "/v1/tts", bytes.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	h.HandleTTS(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, audio, w.Body.Bytes())
+	sum := sha256.Sum256(audio)
+	assert.Equal(t, hex.EncodeToString(sum[:]), w.Header().Get("X-Content-SHA256"))
+}
+
 // Backend error handling tests
 func TestTTS_BackendTimeout(t *testing.T) {
 	mock := &mockBackend{ttsErr: context.DeadlineExceeded}
@@ -283,6 +394,24 @@ func TestTTS_BackendTimeout(t *testing.T) {
 	assert.Equal(t, "Request timeout", resp.Detail)
 }
 
+func TestTTS_UnknownModel(t *testing.T) {
+	mock := &mockBackend{ttsErr: &backend.UnknownModelError{Model: "missing"}}
+	h := NewHandler(mock, testConfig(), testLogger())
+
+	reqBody, _ := json.Marshal(schema.ServeTTSRequest{Text: "Hello", Model: "missing"})
+	req := httptest.NewRequest(http.MethodPost, "/v1/tts", bytes.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	h.HandleTTS(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var resp schema.ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Equal(t, "Unknown model: missing", resp.Detail)
+}
+
 func TestTTS_BackendUnavailable(t *testing.T) {
 	mock := &mockBackend{ttsErr: errors.New("connection refused")}
 	h := NewHandler(mock, testConfig(), testLogger())
@@ -358,6 +487,94 @@ func TestAuthMiddleware_MissingHeader(t *testing.T) {
 	assert.Equal(t, "{\"detail\":\"Invalid token\"}\n", rr.Body.String())
 }
 
+func TestKeyAuthMiddleware_NamespaceScoping(t *testing.T) {
+	mock := &mockBackend{
+		ttsResponse:   []byte("audio"),
+		listRefResp:   &schema.ListReferencesResponse{Success: true, ReferenceIDs: []string{"shared", "acme__voice", "other__voice"}},
+		addRefResp:    &schema.AddReferenceResponse{Success: true, ReferenceID: "acme__new"},
+		deleteRefResp: &schema.DeleteReferenceResponse{Success: true, ReferenceID: "acme__voice"},
+	}
+	cfg := testConfig()
+	cfg.Auth = config.AuthConfig{
+		APIKey: "admin",
+		Keys:   []config.APIKeyConfig{{Key: "acme-key", Namespace: "acme"}},
+	}
+	router := NewRouter(cfg, mock, testLogger())
+
+	do := func(method, path, key string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	var list schema.ListReferencesResponse
+	w := do(http.MethodGet, "/v1/references", "acme-key", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, []string{"voice"}, list.ReferenceIDs)
+
+	mock.listRefResp.ReferenceIDs = []string{"shared", "acme__voice", "other__voice"}
+	w = do(http.MethodGet, "/v1/references", "admin", nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Len(t, list.ReferenceIDs, 3)
+
+	addBody, _ := json.Marshal(schema.AddReferenceRequest{ID: "new", Audio: []byte("a"), Text: "t"})
+	w = do(http.MethodPost, "/v1/references/add", "acme-key", addBody)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "acme__new", mock.lastAddRefReq.ID)
+	assert.Contains(t, w.Body.String(), `"reference_id":"new"`)
+
+	w = do(http.MethodDelete, "/v1/references/voice", "acme-key", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "acme__voice", mock.lastDeleteID)
+
+	ttsBody, _ := json.Marshal(map[string]interface{}{"text": "hi", "reference_id": "voice"})
+	w = do(http.MethodPost, "/v1/tts", "acme-key", ttsBody)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "acme__voice", *mock.lastTTSReq.ReferenceID)
+}
+
+func TestNamespaceIsolation_Separator(t *testing.T) {
+	assert.Error(t, config.ValidateKeys([]config.APIKeyConfig{{Key: "k", Namespace: "acme__b"}}), "a namespace nested in acme")
+	assert.Error(t, config.ValidateKeys([]config.APIKeyConfig{{Key: "k", Namespace: "acme_"}}), "acme_ + __x is acme + ___x")
+	require.NoError(t, config.ValidateKeys([]config.APIKeyConfig{{Key: "k", Namespace: "acme_b"}}))
+
+	mock := &mockBackend{
+		ttsResponse: []byte("audio"),
+		listRefResp: &schema.ListReferencesResponse{Success: true, ReferenceIDs: []string{"acme__voice", "acme_b__voice", "acme_b__secret"}},
+	}
+	cfg := testConfig()
+	cfg.Auth = config.AuthConfig{Keys: []config.APIKeyConfig{
+		{Key: "acme-key", Namespace: "acme"},
+		{Key: "acme-b-key", Namespace: "acme_b"},
+	}}
+	router := NewRouter(cfg, mock, testLogger())
+
+	do := func(method, path, key string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for key, want := range map[string][]string{"acme-key": {"voice"}, "acme-b-key": {"voice", "secret"}} {
+		var list schema.ListReferencesResponse
+		w := do(http.MethodGet, "/v1/references", key, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+		assert.Equal(t, want, list.ReferenceIDs, key)
+	}
+
+	ttsBody, _ := json.Marshal(map[string]interface{}{"text": "hi", "reference_id": "_b__secret"})
+	do(http.MethodPost, "/v1/tts", "acme-key", ttsBody)
+	assert.Equal(t, "acme___b__secret", *mock.lastTTSReq.ReferenceID, "acme cannot reach acme_b's references")
+}
+
 // Helper functions
 func testConfig() *config.Config {
 	return &config.Config{Limits: config.LimitsConfig{MaxTextLength: 10000}}
@@ -366,3 +583,9 @@ func testConfig() *config.Config {
 func testLogger() zerolog.Logger {
 	return zerolog.Nop()
 }
+
+// asAdmin returns req as sent by a caller authenticated with the admin role,
+// which the admin API requires.
+func asAdmin(req *http.Request) *http.Request {
+	return req.WithContext(WithPrincipal(req.Context(), &Principal{Role: RoleAdmin}))
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+// maxIntrospectionCache bounds the number of cached token results; expired
+// entries are swept when it is reached.
+const maxIntrospectionCache = 10000
+
+// errNoScope is returned for active tokens that hold none of the configured
+// scopes.
+var errNoScope = errors.New("token has no scope that allows this API")
+
+// introspectionResponse is the subset of an RFC 7662 response that is used.
+type introspectionResponse struct {
+	Active   bool   `json:"active"`
+	Scope    string `json:"scope"`
+	Subject  string `json:"sub"`
+	Username string `json:"username"`
+	ClientID string `json:"client_id"`
+	Exp      int64  `json:"exp"`
+}
+
+// introspectionResult is a cached outcome: a principal for an active token, an
+// error for an active token without a usable scope, or neither for an inactive
+// token.
+type introspectionResult struct {
+	principal *Principal
+	err       error
+	expires   time.Time
+}
+
+// introspector validates opaque bearer tokens against an introspection
+// endpoint, caching results by token hash so the endpoint is not called for
+// every request.
+type introspector struct {
+	cfg    config.IntrospectionConfig
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]introspectionResult
+}
+
+func newIntrospector(cfg config.IntrospectionConfig) *introspector {
+	return &introspector{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		cache:  make(map[[sha256.Size]byte]introspectionResult),
+	}
+}
+
+// lookup returns the principal for token, or nil when the token is not active.
+// errNoScope is returned for tokens without a configured scope; other errors
+// mean the endpoint could not be asked.
+func (in *introspector) lookup(ctx context.Context, token string) (*Principal, error) {
+	id := sha256.Sum256([]byte(token))
+	now := time.Now()
+
+	in.mu.Lock()
+	cached, ok := in.cache[id]
+	in.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.principal, cached.err
+	}
+
+	resp, err := in.introspect(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	result := introspectionResult{expires: now.Add(in.cfg.CacheTTL)}
+	if resp.Active {
+		result.principal, result.err = in.principal(resp)
+		if resp.Exp > 0 {
+			if exp := time.Unix(resp.Exp, 0); exp.Before(result.expires) {
+				result.expires = exp
+			}
+		}
+	}
+
+	in.mu.Lock()
+	if len(in.cache) >= maxIntrospectionCache {
+		for k, r := range in.cache {
+			if !now.Before(r.expires) {
+				delete(in.cache, k)
+			}
+		}
+	}
+	if len(in.cache) < maxIntrospectionCache {
+		in.cache[id] = result
+	}
+	in.mu.Unlock()
+
+	return result.principal, result.err
+}
+
+func (in *introspector) introspect(ctx context.Context, token string) (*introspectionResponse, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, in.cfg.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create introspection request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if in.cfg.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(in.cfg.ClientID), url.QueryEscape(in.cfg.ClientSecret))
+	}
+
+	resp, err := in.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("introspection endpoint unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("introspection endpoint returned status %d", resp.StatusCode)
+	}
+	var out introspectionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode introspection response: %w", err)
+	}
+	return &out, nil
+}
+
+// principal maps an active token's scopes to a role and allowed routes.
+func (in *introspector) principal(resp *introspectionResponse) (*Principal, error) {
+	p := &Principal{Username: resp.Subject}
+	if p.Username == "" {
+		p.Username = resp.Username
+	}
+	if p.Username == "" {
+		p.Username = resp.ClientID
+	}
+	return scopePrincipal(p, strings.Fields(resp.Scope), in.cfg.AdminScope, in.cfg.Scopes)
+}
+
+// scopePrincipal grants p the admin role when its token holds adminScope, and
+// otherwise limits it to the routes allowed by its token's scopes. errNoScope
+// is returned when allowed is not empty and none of the scopes is listed.
+func scopePrincipal(p *Principal, scopes []string, adminScope string, allowed []config.ScopeConfig) (*Principal, error) {
+	for _, scope := range scopes {
+		if adminScope != "" && scope == adminScope {
+			p.Role = RoleAdmin
+			return p, nil
+		}
+	}
+	if len(allowed) == 0 {
+		return p, nil
+	}
+
+	var routes []string
+	for _, sc := range allowed {
+		for _, scope := range scopes {
+			if scope == sc.Scope {
+				routes = append(routes, sc.Routes...)
+			}
+		}
+	}
+	if len(routes) == 0 {
+		return nil, errNoScope
+	}
+	p.policy = &keyPolicy{name: p.Username, routes: routes}
+	return p, nil
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+func TestKeyAuth_Introspection(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "fish", user)
+		assert.Equal(t, "client-secret", pass)
+		require.NoError(t, r.ParseForm())
+
+		resp := map[string]interface{}{"active": false}
+		switch r.PostForm.Get("token") {
+		case "tts-token":
+			resp = map[string]interface{}{"active": true, "scope": "openid tts", "sub": "svc-a",
+				"exp": time.Now().Add(time.Hour).Unix()}
+		case "admin-token":
+			resp = map[string]interface{}{"active": true, "scope": "fish:admin", "client_id": "ops"}
+		case "other-token":
+			resp = map[string]interface{}{"active": true, "scope": "openid"}
+		case "broken-token":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	cfg := config.AuthConfig{
+		APIKey: "admin-key",
+		Introspection: config.IntrospectionConfig{
+			URL:          srv.URL,
+			ClientID:     "fish",
+			ClientSecret: "client-secret",
+			CacheTTL:     time.Minute,
+			Timeout:      time.Second,
+			Scopes:       []config.ScopeConfig{{Scope: "tts", Routes: []string{"/v1/tts"}}},
+			AdminScope:   "fish:admin",
+		},
+	}
+	var principal *Principal
+	handler := KeyAuthMiddleware(cfg, nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal = PrincipalFromContext(r.Context())
+		}))
+
+	do := func(path, token string) int {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, do("/v1/tts", "tts-token"))
+	assert.Equal(t, "svc-a", principal.Username)
+	assert.False(t, principal.IsAdmin())
+	assert.Equal(t, http.StatusForbidden, do("/v1/references/add", "tts-token"))
+	assert.Equal(t, int32(1), calls.Load(), "results are cached")
+
+	assert.Equal(t, http.StatusOK, do("/admin/config", "admin-token"))
+	assert.True(t, principal.IsAdmin())
+	assert.Equal(t, "ops", principal.Username)
+
+	assert.Equal(t, http.StatusForbidden, do("/v1/tts", "other-token"))
+	assert.Equal(t, http.StatusUnauthorized, do("/v1/tts", "revoked-token"))
+	assert.Equal(t, http.StatusServiceUnavailable, do("/v1/tts", "broken-token"))
+
+	// API keys are matched locally without calling the endpoint.
+	before := calls.Load()
+	assert.Equal(t, http.StatusOK, do("/v1/tts", "admin-key"))
+	assert.Equal(t, before, calls.Load())
+}
+
+func TestIntrospector_CacheHonoursExpiry(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		json.NewEncoder(w).Encode(map[string]interface{}{"active": true, "exp": time.Now().Add(-time.Second).Unix()})
+	}))
+	defer srv.Close()
+
+	in := newIntrospector(config.IntrospectionConfig{URL: srv.URL, CacheTTL: time.Hour, Timeout: time.Second})
+	for i := 0; i < 2; i++ {
+		p, err := in.lookup(httptest.NewRequest(http.MethodGet, "/", nil).Context(), "token")
+		require.NoError(t, err)
+		assert.NotNil(t, p)
+	}
+	assert.Equal(t, int32(2), calls.Load(), "expired tokens are introspected again")
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/backend"
+	"github.com/fish-speech-go/fish-speech-go/internal/limiter"
+	"github.com/fish-speech-go/fish-speech-go/internal/queue"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+// JobResponse describes an asynchronous TTS job.
+type JobResponse struct {
+	JobID string `json:"job_id"`
+	// State is one of queued, running, done or failed.
+	State string `json:"state"`
+	// Priority is interactive, normal or bulk. Queued jobs are picked up
+	// in order of priority.
+	Priority   string     `json:"priority"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// ExpiresAt is when a finished job and its result are removed.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// ResultURL is where the audio of a done job can be downloaded.
+	ResultURL string `json:"result_url,omitempty"`
+	// SHA256 is the hex-encoded SHA-256 digest of the audio of a done job,
+	// the X-Content-SHA256 of its result.
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+func newJobResponse(r *http.Request, job queue.Job) JobResponse {
+	resp := JobResponse{
+		JobID:     job.ID,
+		State:     string(job.State),
+		Priority:  jobPriority(job.Priority),
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt,
+	}
+	if !job.StartedAt.IsZero() {
+		resp.StartedAt = &job.StartedAt
+	}
+	if job.State.Finished() {
+		resp.FinishedAt = &job.FinishedAt
+		resp.ExpiresAt = &job.ExpiresAt
+	}
+	if job.State == queue.StateDone {
+		resp.ResultURL = jobURL(r, job.ID) + "/result"
+		resp.SHA256 = job.ResultSHA256
+	}
+	return resp
+}
+
+// jobURL returns the path of job id under the API version of r.
+func jobURL(r *http.Request, id string) string {
+	path := r.URL.Path
+	if i := strings.Index(path, "/tts/jobs"); i >= 0 {
+		path = path[:i]
+	} else if i := strings.Index(path, "/jobs/"); i >= 0 {
+		path = path[:i]
+	}
+	return path + "/jobs/" + id
+}
+
+// HandleCreateTTSJob accepts the same body as /v1/tts and queues the synthesis
+// as a job, answering 202 at once. Clients poll the job and download its
+// result when it is done, rather than holding a connection open for long
+// texts.
+func (h *Handler) HandleCreateTTSJob(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.parseTTSRequest(w, r)
+	if !ok {
+		return
+	}
+	if req.Streaming {
+		WriteError(w, http.StatusBadRequest, "Jobs do not support streaming")
+		return
+	}
+
+	// parseTTSRequest has already validated the header.
+	priority, _ := requestPriority(r)
+	lookup, store := h.cacheKeys(r, req)
+	run := h.ttsJob(req, ttsJobOptions{
+		requestID: requestIDFromContext(r.Context()),
+		priority:  limiterPriority(priority),
+		flow:      limiterFlow(r.Context()),
+		principal: PrincipalFromContext(r.Context()),
+		cacheKey:  lookup,
+		storeAs:   store,
+	})
+	// The job keeps a drain waiting until it finishes, even if it is failed
+	// without running.
+	h.drain.hold()
+	job, err := h.jobs.SubmitPriority(namespaceFromContext(r.Context()), queuePriority(priority), run, h.drain.release)
+	if err != nil {
+		h.drain.release()
+	}
+	switch {
+	case errors.Is(err, queue.ErrQueueFull):
+		w.Header().Set("Retry-After", "1")
+		WriteErrorCode(w, http.StatusServiceUnavailable, CodeQueueFull, "Job queue is full, please retry later")
+		return
+	case errors.Is(err, queue.ErrClosed):
+		w.Header().Set("Retry-After", "1")
+		WriteError(w, http.StatusServiceUnavailable, "Server is shutting down")
+		return
+	case err != nil:
+		h.logger.Error().Err(err).Msg("Submit job error")
+		WriteError(w, http.StatusInternalServerError, "Failed to submit job")
+		return
+	}
+
+	h.logger.Info().Str("job_id", job.ID).Str("request_id", requestIDFromContext(r.Context())).Int("text_length", len(req.Text)).Msg("TTS job queued")
+	w.Header().Set("Location", jobURL(r, job.ID))
+	WriteJSON(w, http.StatusAccepted, newJobResponse(r, job))
+}
+
+// HandleGetJob reports the state of a job.
+func (h *Handler) HandleGetJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.jobs.Get(chi.URLParam(r, "id"))
+	if err != nil || job.Owner != namespaceFromContext(r.Context()) {
+		WriteError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	WriteJSON(w, http.StatusOK, newJobResponse(r, job))
+}
+
+// HandleGetJobResult returns the audio of a done job, answering Range requests
+// so that large results can be downloaded in parts or resumed. Until then it
+// answers 409 with the job_not_finished or job_failed code.
+func (h *Handler) HandleGetJobResult(w http.ResponseWriter, r *http.Request) {
+	job, result, err := h.jobs.Result(chi.URLParam(r, "id"))
+	if err != nil || job.Owner != namespaceFromContext(r.Context()) {
+		WriteError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+
+	switch job.State {
+	case queue.StateDone:
+		ServeAudio(w, r, result.Format, result.Data, job.FinishedAt)
+	case queue.StateFailed:
+		WriteErrorCode(w, http.StatusConflict, CodeJobFailed, "Job failed: "+job.Error)
+	default:
+		WriteErrorCode(w, http.StatusConflict, CodeJobNotFinished, "Job is "+string(job.State))
+	}
+}
+
+// ttsJobOptions carries what a TTS job keeps from the request that submitted
+// it.
+type ttsJobOptions struct {
+	requestID string
+	priority  limiter.Priority
+	flow      limiter.Flow
+	// principal is the submitter the job's usage is metered to.
+	principal *Principal
+	// cacheKey and storeAs are the response cache keys to look the job up
+	// under and to store its audio under, empty to skip either.
+	cacheKey string
+	storeAs  string
+}
+
+// ttsJob returns the work of a TTS job: the same backend call as a
+// non-streaming request, answered from the response cache when possible and
+// otherwise run under the limiter with the submitter's priority and flow.
+func (h *Handler) ttsJob(req *schema.ServeTTSRequest, opts ttsJobOptions) queue.Func {
+	return func(ctx context.Context) (queue.Result, error) {
+		ctx = backend.WithRequestID(ctx, opts.requestID)
+		if data, format, ok := h.cachedAudio(opts.cacheKey); ok {
+			h.recordUsage(opts.principal, req, audioSeconds(req, nil, data))
+			return queue.Result{Data: data, Format: format}, nil
+		}
+
+		release := func(time.Duration, error) {}
+		if h.limiter != nil {
+			var err error
+			// Nobody is waiting on the connection, so a job keeps waiting
+			// for a slot through acquire timeouts.
+			for {
+				release, _, err = h.acquire(ctx, opts.priority, opts.flow, h.slotCost(req))
+				if !errors.Is(err, limiter.ErrTimeout) {
+					break
+				}
+			}
+			if err != nil {
+				return queue.Result{}, jobError(err)
+			}
+		}
+
+		start := time.Now()
+		data, format, err := h.backend.TTS(ctx, h.backendRequest(req))
+		release(time.Since(start), err)
+		h.logBackendCall(ctx, OpTTS, start, err)
+		if err != nil {
+			if ctx.Err() == nil {
+				h.recentErrors.add(err)
+			}
+			return queue.Result{}, jobError(err)
+		}
+
+		raw := data
+		data, format, err = h.finishAudio(req, data, format)
+		if err != nil {
+			h.logger.Error().Err(err).Str("request_id", opts.requestID).Msg("Job post-processing error")
+			return queue.Result{}, errors.New("backend returned audio that could not be processed")
+		}
+		h.recordUsage(opts.principal, req, audioSeconds(req, raw, data))
+		h.storeCachedTTS(opts.storeAs, req, format, data)
+		return queue.Result{Data: data, Format: format}, nil
+	}
+}
+
+// jobError turns a backend error into the message reported to the client,
+// matching what handleBackendError would have written.
+func jobError(err error) error {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return errors.New("server shutting down")
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, backend.ErrBackendTimeout):
+		return errors.New("request timeout")
+	case errors.Is(err, backend.ErrBackendSaturated):
+		return errors.New("backend queue is full")
+	}
+
+	var modelErr *backend.UnknownModelError
+	if errors.As(err, &modelErr) {
+		return fmt.Errorf("unknown model: %s", modelErr.Model)
+	}
+	var backendErr *backend.BackendError
+	if errors.As(err, &backendErr) {
+		if backendErr.StatusCode == http.StatusBadRequest || backendErr.StatusCode == http.StatusNotFound {
+			return errors.New(backendErr.Message)
+		}
+		return errors.New("backend error")
+	}
+	return errors.New("backend service unavailable")
+}
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/backend"
+	"github.com/fish-speech-go/fish-speech-go/internal/cache"
+	"github.com/fish-speech-go/fish-speech-go/internal/queue"
+)
+
+func newJobsRouter(t *testing.T, mock *mockBackend) http.Handler {
+	t.Helper()
+	jobs := queue.New(queue.Config{})
+	t.Cleanup(jobs.Close)
+	return NewRouter(testConfig(), mock, testLogger(), WithJobs(jobs))
+}
+
+func doJobs(ctx context.Context, router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body)).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// waitJob polls the job at location until it has finished.
+func waitJob(t *testing.T, router http.Handler, location string) JobResponse {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		w := doJobs(context.Background(), router, http.MethodGet, location, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var job JobResponse
+		if strings.HasPrefix(location, "/v2/") {
+			var envelope struct{ Data JobResponse }
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &envelope))
+			job = envelope.Data
+		} else {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+		}
+		if job.State == string(queue.StateDone) || job.State == string(queue.StateFailed) {
+			return job
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", location)
+	return JobResponse{}
+}
+
+func TestTTSJob(t *testing.T) {
+	router := newJobsRouter(t, &mockBackend{ttsResponse: []byte("audio data")})
+
+	w := doJobs(context.Background(), router, http.MethodPost, "/v1/tts/jobs", `{"text":"A long narration","format":"wav"}`)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var created JobResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	location := w.Header().Get("Location")
+	assert.Equal(t, "/v1/jobs/"+created.JobID, location)
+	assert.Equal(t, string(queue.StateQueued), created.State)
+	assert.Equal(t, PriorityNormal, created.Priority)
+
+	job := waitJob(t, router, location)
+	assert.Equal(t, string(queue.StateDone), job.State)
+	assert.Equal(t, location+"/result", job.ResultURL)
+	assert.Equal(t, ContentSHA256([]byte("audio data")), job.SHA256)
+	require.NotNil(t, job.ExpiresAt)
+
+	w = doJobs(context.Background(), router, http.MethodGet, job.ResultURL, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "audio/wav", w.Header().Get("Content-Type"))
+	assert.Equal(t, "audio data", w.Body.String())
+	assert.Equal(t, job.SHA256, w.Header().Get("X-Content-SHA256"))
+
+	w = doJobs(context.Background(), router, http.MethodGet, "/v1/jobs/missing", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestTTSJob_Priority(t *testing.T) {
+	router := newJobsRouter(t, &mockBackend{ttsResponse: []byte("audio data")})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/tts/jobs", strings.NewReader(`{"text":"Chapter one"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Priority", PriorityBulk)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var created JobResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, PriorityBulk, created.Priority)
+
+	job := waitJob(t, router, w.Header().Get("Location"))
+	assert.Equal(t, PriorityBulk, job.Priority)
+}
+
+func TestTTSJob_ResultRange(t *testing.T) {
+	router := newJobsRouter(t, &mockBackend{ttsResponse: []byte("0123456789")})
+
+	w := doJobs(context.Background(), router, http.MethodPost, "/v1/tts/jobs", `{"text":"A long narration","format":"wav"}`)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	job := waitJob(t, router, w.Header().Get("Location"))
+	require.Equal(t, string(queue.StateDone), job.State)
+
+	get := func(headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, job.ResultURL, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w = get(nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "bytes", w.Header().Get("Accept-Ranges"))
+	etag := w.Header().Get("ETag")
+	assert.Equal(t, `"`+ContentSHA256([]byte("0123456789"))+`"`, etag)
+
+	w = get(map[string]string{"Range": "bytes=4-"})
+	require.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "456789", w.Body.String())
+	assert.Equal(t, "bytes 4-9/10", w.Header().Get("Content-Range"))
+	assert.Equal(t, "audio/wav", w.Header().Get("Content-Type"))
+
+	w = get(map[string]string{"Range": "bytes=20-"})
+	assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, w.Code)
+
+	w = get(map[string]string{"Range": "bytes=0-3", "If-Range": etag})
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	w = get(map[string]string{"Range": "bytes=0-3", "If-Range": `"stale"`})
+	assert.Equal(t, http.StatusOK, w.Code, "a changed result is sent whole")
+	assert.Equal(t, "0123456789", w.Body.String())
+
+	w = get(map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+}
+
+func TestTTSJob_Cache(t *testing.T) {
+	mock := &mockBackend{ttsResponse: []byte("audio data")}
+	jobs := queue.New(queue.Config{})
+	t.Cleanup(jobs.Close)
+	c := cache.New(cache.Config{})
+	router := NewRouter(testConfig(), mock, testLogger(), WithJobs(jobs), WithCache(c))
+
+	// A job stores its audio for later requests and jobs with the same text.
+	w := doJobs(context.Background(), router, http.MethodPost, "/v1/tts/jobs", `{"text":"Cached narration"}`)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	job := waitJob(t, router, w.Header().Get("Location"))
+	require.Equal(t, string(queue.StateDone), job.State)
+	require.NotNil(t, mock.lastTTSReq)
+
+	mock.lastTTSReq = nil
+	w = doJobs(context.Background(), router, http.MethodPost, "/v1/tts", `{"text":"Cached narration"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "HIT", w.Header().Get("X-Cache"))
+
+	w = doJobs(context.Background(), router, http.MethodPost, "/v1/tts/jobs", `{"text":"Cached narration "}`)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	job = waitJob(t, router, w.Header().Get("Location"))
+	require.Equal(t, string(queue.StateDone), job.State)
+	assert.Nil(t, mock.lastTTSReq, "job should be served from the cache")
+
+	w = doJobs(context.Background(), router, http.MethodGet, job.ResultURL, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "audio data", w.Body.String())
+	assert.Equal(t, int64(2), c.Stats().Hits)
+}
+
+func TestTTSJob_Failed(t *testing.T) {
+	router := newJobsRouter(t, &mockBackend{ttsErr: &backend.BackendError{StatusCode: http.StatusInternalServerError, Message: "CUDA out of memory"}})
+
+	w := doJobs(context.Background(), router, http.MethodPost, "/v2/tts/jobs", `{"text":"Hello"}`)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	location := w.Header().Get("Location")
+	assert.True(t, strings.HasPrefix(location, "/v2/jobs/"))
+
+	job := waitJob(t, router, location)
+	assert.Equal(t, string(queue.StateFailed), job.State)
+	assert.Equal(t, "backend error", job.Error, "backend details are not exposed")
+	assert.Empty(t, job.SHA256)
+	assert.Empty(t, job.ResultURL)
+
+	w = doJobs(context.Background(), router, http.MethodGet, location+"/result", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), CodeJobFailed)
+}
+
+func TestTTSJob_Validation(t *testing.T) {
+	router := newJobsRouter(t, &mockBackend{})
+
+	w := doJobs(context.Background(), router, http.MethodPost, "/v1/tts/jobs", `{"text":"Hello","streaming":true}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doJobs(context.Background(), router, http.MethodPost, "/v1/tts/jobs", `not json`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestTTSJob_NotFinished(t *testing.T) {
+	jobs := queue.New(queue.Config{})
+	defer jobs.Close()
+	block := make(chan struct{})
+	defer close(block)
+	job, err := jobs.Submit("", func(ctx context.Context) (queue.Result, error) {
+		<-block
+		return queue.Result{}, nil
+	})
+	require.NoError(t, err)
+
+	router := NewRouter(testConfig(), &mockBackend{}, testLogger(), WithJobs(jobs))
+	w := doJobs(context.Background(), router, http.MethodGet, "/v2/jobs/"+job.ID+"/result", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), CodeJobNotFinished)
+}
+
+func TestTTSJob_Namespace(t *testing.T) {
+	router := newJobsRouter(t, &mockBackend{ttsResponse: []byte("audio")})
+	tenant := WithPrincipal(context.Background(), &Principal{Namespace: "tenant"})
+
+	w := doJobs(tenant, router, http.MethodPost, "/v1/tts/jobs", `{"text":"Hello"}`)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	location := w.Header().Get("Location")
+
+	w = doJobs(context.Background(), router, http.MethodGet, location, "")
+	assert.Equal(t, http.StatusNotFound, w.Code, "jobs are only visible to their namespace")
+	w = doJobs(tenant, router, http.MethodGet, location, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
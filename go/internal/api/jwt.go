@@ -0,0 +1,388 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256" // registers SHA-256 for crypto.Hash
+	_ "crypto/sha512" // registers SHA-384 and SHA-512 for crypto.Hash
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+// jwksMinRefresh is how long a token signed with an unknown key waits for
+// the signing keys to be fetched again, so that bogus key IDs cannot make the
+// server hammer the identity provider.
+const jwksMinRefresh = 30 * time.Second
+
+// maxJWKSResponse bounds the discovery and JWKS documents read.
+const maxJWKSResponse = 1 << 20
+
+// errKeysUnavailable is returned when the signing keys could not be fetched
+// and none are known from an earlier fetch.
+var errKeysUnavailable = errors.New("token signing keys unavailable")
+
+// jwtAlgorithms are the accepted signature algorithms. HMAC and "none" are not
+// accepted, as the keys come from the identity provider.
+var jwtAlgorithms = map[string]crypto.Hash{
+	"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+	"PS256": crypto.SHA256, "PS384": crypto.SHA384, "PS512": crypto.SHA512,
+	"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+}
+
+// jwtHeader is the subset of a JOSE header that is used.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// jwk is the subset of an RFC 7517 JSON Web Key that is used.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// jwtValidator validates bearer tokens that are JWTs signed by an identity
+// provider, fetching its signing keys from the JWKS endpoint, found through
+// OpenID Connect discovery unless configured.
+type jwtValidator struct {
+	cfg    config.JWTConfig
+	client *http.Client
+
+	mu      sync.Mutex
+	jwksURL string
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+	tried   time.Time
+}
+
+func newJWTValidator(cfg config.JWTConfig) *jwtValidator {
+	if cfg.UsernameClaim == "" {
+		cfg.UsernameClaim = "sub"
+	}
+	if cfg.ScopeClaim == "" {
+		cfg.ScopeClaim = "scope"
+	}
+	return &jwtValidator{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}, jwksURL: cfg.JWKSURL}
+}
+
+// looksLikeJWT reports whether token has the three segments of a signed JWT,
+// as opposed to an API key or opaque token.
+func looksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// validate checks the signature and claims of token and returns its principal.
+// errNoScope is returned for tokens without a configured scope and
+// errKeysUnavailable when the signing keys cannot be fetched; other errors
+// describe why the token is invalid.
+func (v *jwtValidator) validate(ctx context.Context, token string, now time.Time) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed header: %w", err)
+	}
+	hash, ok := jwtAlgorithms[header.Alg]
+	if !ok {
+		return nil, fmt.Errorf("unsupported algorithm %q", header.Alg)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed signature")
+	}
+
+	key, err := v.key(ctx, header.Kid, now)
+	if err != nil {
+		return nil, err
+	}
+	h := hash.New()
+	h.Write([]byte(parts[0] + "." + parts[1]))
+	if !verifySignature(header.Alg, hash, key, h.Sum(nil), sig) {
+		return nil, errors.New("bad signature")
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed claims: %w", err)
+	}
+	if err := v.checkClaims(claims, now); err != nil {
+		return nil, err
+	}
+	return v.principal(claims)
+}
+
+// checkClaims checks the token's lifetime, issuer and audience.
+func (v *jwtValidator) checkClaims(claims map[string]any, now time.Time) error {
+	exp, ok := numericClaim(claims, "exp")
+	if !ok {
+		return errors.New("token has no exp claim")
+	}
+	if now.Add(-v.cfg.Leeway).After(time.Unix(exp, 0)) {
+		return errors.New("token is expired")
+	}
+	if nbf, ok := numericClaim(claims, "nbf"); ok && now.Add(v.cfg.Leeway).Before(time.Unix(nbf, 0)) {
+		return errors.New("token is not valid yet")
+	}
+	if v.cfg.Issuer != "" && claims["iss"] != v.cfg.Issuer {
+		return errors.New("unexpected issuer")
+	}
+	if v.cfg.Audience != "" && !containsClaim(claims["aud"], v.cfg.Audience) {
+		return errors.New("unexpected audience")
+	}
+	return nil
+}
+
+// principal maps the token's claims to a username, namespace and, through its
+// scopes, a role and allowed routes.
+func (v *jwtValidator) principal(claims map[string]any) (*Principal, error) {
+	p := &Principal{}
+	p.Username, _ = claims[v.cfg.UsernameClaim].(string)
+	if v.cfg.NamespaceClaim != "" {
+		p.Namespace, _ = claims[v.cfg.NamespaceClaim].(string)
+		if p.Namespace == "" {
+			return nil, fmt.Errorf("token has no %s claim", v.cfg.NamespaceClaim)
+		}
+		if err := config.ValidateNamespace(p.Namespace); err != nil {
+			return nil, fmt.Errorf("token %s claim: %w", v.cfg.NamespaceClaim, err)
+		}
+	}
+
+	var scopes []string
+	switch s := claims[v.cfg.ScopeClaim].(type) {
+	case string:
+		scopes = strings.Fields(s)
+	case []any:
+		for _, scope := range s {
+			if scope, ok := scope.(string); ok {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	return scopePrincipal(p, scopes, v.cfg.AdminScope, v.cfg.Scopes)
+}
+
+// key returns the signing key kid, fetching the keys when they are older than
+// the refresh interval or kid is unknown. A token without a kid may use the
+// only key. Previously fetched keys stay in use while the endpoint fails.
+func (v *jwtValidator) key(ctx context.Context, kid string, now time.Time) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	key, found := v.lookupLocked(kid)
+	stale := v.keys == nil || now.Sub(v.fetched) >= v.cfg.RefreshInterval
+	if (stale || !found) && now.Sub(v.tried) >= jwksMinRefresh {
+		v.tried = now
+		keys, err := v.fetchKeysLocked(ctx)
+		if err != nil && v.keys == nil {
+			return nil, fmt.Errorf("%w: %v", errKeysUnavailable, err)
+		}
+		if err == nil {
+			v.keys, v.fetched = keys, now
+		}
+		key, found = v.lookupLocked(kid)
+	}
+	if v.keys == nil {
+		return nil, errKeysUnavailable
+	}
+	if !found {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+func (v *jwtValidator) lookupLocked(kid string) (crypto.PublicKey, bool) {
+	if key, ok := v.keys[kid]; ok {
+		return key, true
+	}
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// fetchKeysLocked fetches the signing keys, discovering the JWKS URL from the
+// issuer first if needed. Keys of other types or uses are skipped.
+func (v *jwtValidator) fetchKeysLocked(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	if v.jwksURL == "" {
+		var doc struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := v.getJSON(ctx, strings.TrimSuffix(v.cfg.Issuer, "/")+"/.well-known/openid-configuration", &doc); err != nil {
+			return nil, fmt.Errorf("discovery: %w", err)
+		}
+		if doc.Issuer != v.cfg.Issuer || doc.JWKSURI == "" {
+			return nil, errors.New("discovery: issuer mismatch or no jwks_uri")
+		}
+		v.jwksURL = doc.JWKSURI
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := v.getJSON(ctx, v.jwksURL, &set); err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+func (v *jwtValidator) getJSON(ctx context.Context, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxJWKSResponse)).Decode(out)
+}
+
+// publicKey converts an RSA or EC key. Other key types are not supported.
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		var point ecdh.Curve
+		switch k.Crv {
+		case "P-256":
+			curve, point = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, point = elliptic.P384(), ecdh.P384()
+		case "P-521":
+			curve, point = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(k.X)
+		y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+		size := (curve.Params().BitSize + 7) / 8
+		if errX != nil || errY != nil || len(x) != size || len(y) != size {
+			return nil, errors.New("invalid EC coordinates")
+		}
+		// NewPublicKey rejects points that are not on the curve.
+		if _, err := point.NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// verifySignature checks sig over digest with key, which must suit alg.
+func verifySignature(alg string, hash crypto.Hash, key crypto.PublicKey, digest, sig []byte) bool {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "RS":
+			return rsa.VerifyPKCS1v15(key, hash, digest, sig) == nil
+		case "PS":
+			return rsa.VerifyPSS(key, hash, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+		}
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if alg[:2] != "ES" || len(sig) != 2*size || jwtCurveHash(key.Curve) != hash {
+			return false
+		}
+		r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+		return ecdsa.Verify(key, digest, r, s)
+	}
+	return false
+}
+
+// jwtCurveHash returns the hash the ES algorithm for curve uses, so that a key
+// is only accepted with its own algorithm.
+func jwtCurveHash(curve elliptic.Curve) crypto.Hash {
+	switch curve {
+	case elliptic.P256():
+		return crypto.SHA256
+	case elliptic.P384():
+		return crypto.SHA384
+	default:
+		return crypto.SHA512
+	}
+}
+
+func decodeSegment(segment string, out any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	return dec.Decode(out)
+}
+
+// numericClaim returns a NumericDate claim in Unix seconds.
+func numericClaim(claims map[string]any, name string) (int64, bool) {
+	n, ok := claims[name].(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return 0, false
+	}
+	return int64(f), true
+}
+
+// containsClaim reports whether claim, a string or list of strings, holds want.
+func containsClaim(claim any, want string) bool {
+	switch c := claim.(type) {
+	case string:
+		return c == want
+	case []any:
+		for _, v := range c {
+			if v == want {
+				return true
+			}
+		}
+	}
+	return false
+}
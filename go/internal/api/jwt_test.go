@@ -0,0 +1,182 @@
+package api
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+// signJWT returns a compact JWT over claims signed with key.
+func signJWT(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]any) string {
+	t.Helper()
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(signed))
+	var sig []byte
+	if ec, ok := key.(*ecdsa.PrivateKey); ok {
+		// JWS uses the fixed-size r||s form rather than ASN.1.
+		r, s, err := ecdsa.Sign(rand.Reader, ec, digest[:])
+		require.NoError(t, err)
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	} else {
+		sig, err = key.Sign(rand.Reader, digest[:], crypto.SHA256)
+		require.NoError(t, err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA", "kid": kid, "use": "sig",
+		"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "EC", "kid": kid, "crv": "P-256",
+		"x": base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		"y": base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+// testIssuer serves OpenID Connect discovery and a JWKS holding keys.
+func testIssuer(t *testing.T, keys *atomic.Value) (*httptest.Server, *atomic.Int32) {
+	var fetches atomic.Int32
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{"issuer": srv.URL, "jwks_uri": srv.URL + "/keys"})
+		case "/keys":
+			fetches.Add(1)
+			json.NewEncoder(w).Encode(map[string]any{"keys": keys.Load()})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &fetches
+}
+
+func TestKeyAuth_JWT(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	var keys atomic.Value
+	keys.Store([]map[string]string{rsaJWK("rsa-1", &rsaKey.PublicKey), ecJWK("ec-1", &ecKey.PublicKey)})
+	srv, fetches := testIssuer(t, &keys)
+
+	cfg := config.AuthConfig{JWT: config.JWTConfig{
+		Issuer:          srv.URL,
+		Audience:        "fish-speech",
+		RefreshInterval: time.Hour,
+		Timeout:         time.Second,
+		Leeway:          time.Minute,
+		NamespaceClaim:  "tenant",
+		Scopes:          []config.ScopeConfig{{Scope: "tts", Routes: []string{"/v1/tts"}}},
+		AdminScope:      "fish:admin",
+	}}
+	var principal *Principal
+	handler := KeyAuthMiddleware(cfg, nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal = PrincipalFromContext(r.Context())
+		}))
+
+	do := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	claims := func(extra map[string]any) map[string]any {
+		c := map[string]any{"iss": srv.URL, "aud": []string{"fish-speech"}, "sub": "svc-a", "tenant": "acme",
+			"scope": "openid tts", "exp": time.Now().Add(time.Hour).Unix()}
+		for k, v := range extra {
+			c[k] = v
+		}
+		return c
+	}
+
+	w := do("/v1/tts", signJWT(t, "RS256", "rsa-1", rsaKey, claims(nil)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "svc-a", principal.Username)
+	assert.Equal(t, "acme", principal.Namespace)
+	assert.False(t, principal.IsAdmin())
+	assert.Equal(t, http.StatusForbidden, do("/v1/references/add", signJWT(t, "RS256", "rsa-1", rsaKey, claims(nil))).Code)
+
+	assert.Equal(t, http.StatusOK, do("/admin/config", signJWT(t, "ES256", "ec-1", ecKey, claims(map[string]any{"scope": []string{"fish:admin"}}))).Code)
+	assert.True(t, principal.IsAdmin())
+	assert.Equal(t, int32(1), fetches.Load(), "keys are cached")
+
+	for name, token := range map[string]string{
+		"expired":      signJWT(t, "RS256", "rsa-1", rsaKey, claims(map[string]any{"exp": time.Now().Add(-time.Hour).Unix()})),
+		"audience":     signJWT(t, "RS256", "rsa-1", rsaKey, claims(map[string]any{"aud": "other"})),
+		"issuer":       signJWT(t, "RS256", "rsa-1", rsaKey, claims(map[string]any{"iss": "https://evil.example"})),
+		"no tenant":    signJWT(t, "RS256", "rsa-1", rsaKey, claims(map[string]any{"tenant": nil})),
+		"tenant":       signJWT(t, "RS256", "rsa-1", rsaKey, claims(map[string]any{"tenant": "acme__b"})),
+		"key mismatch": signJWT(t, "RS256", "ec-1", rsaKey, claims(nil)),
+		"tampered":     signJWT(t, "RS256", "rsa-1", rsaKey, claims(nil)) + "x",
+	} {
+		assert.Equal(t, http.StatusUnauthorized, do("/v1/tts", token).Code, name)
+	}
+	assert.Equal(t, http.StatusForbidden, do("/v1/tts", signJWT(t, "RS256", "rsa-1", rsaKey, claims(map[string]any{"scope": "openid"}))).Code)
+}
+
+func TestJWTValidator_KeyRotation(t *testing.T) {
+	oldKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	newKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	var keys atomic.Value
+	keys.Store([]map[string]string{ecJWK("old", &oldKey.PublicKey)})
+	srv, fetches := testIssuer(t, &keys)
+
+	v := newJWTValidator(config.JWTConfig{JWKSURL: srv.URL + "/keys", RefreshInterval: time.Hour, Timeout: time.Second})
+	now := time.Now()
+	token := func(kid string, key *ecdsa.PrivateKey) string {
+		return signJWT(t, "ES256", kid, key, map[string]any{"sub": "svc-a", "exp": now.Add(3 * time.Hour).Unix()})
+	}
+
+	_, err = v.validate(context.Background(), token("old", oldKey), now)
+	require.NoError(t, err)
+
+	keys.Store([]map[string]string{ecJWK("old", &oldKey.PublicKey), ecJWK("new", &newKey.PublicKey)})
+	_, err = v.validate(context.Background(), token("new", newKey), now.Add(time.Second))
+	assert.Error(t, err, "unknown keys are not fetched again right away")
+	_, err = v.validate(context.Background(), token("new", newKey), now.Add(jwksMinRefresh))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), fetches.Load())
+
+	srv.Close()
+	_, err = v.validate(context.Background(), token("old", oldKey), now.Add(2*time.Hour))
+	assert.NoError(t, err, "known keys stay in use while the endpoint is down")
+
+	down := newJWTValidator(config.JWTConfig{JWKSURL: srv.URL + "/keys", Timeout: time.Second})
+	_, err = down.validate(context.Background(), token("old", oldKey), now)
+	assert.ErrorIs(t, err, errKeysUnavailable)
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+	"github.com/fish-speech-go/fish-speech-go/internal/shared"
+)
+
+const defaultQuotaPeriod = 24 * time.Hour
+
+// keyPolicy holds the limits configured for one API key.
+type keyPolicy struct {
+	name     string
+	rate     float64
+	burst    float64
+	quota    int64
+	period   time.Duration
+	routes   []string
+	priority string
+	weight   int
+	usage    *keyUsage
+	// quotas caps the characters and audio seconds metered for the key.
+	quotas usageQuotas
+	// shared, when set, counts usage in Redis so every server enforces the
+	// limits together; usage falls back to this server's counters while
+	// Redis is unreachable.
+	shared *shared.Store
+}
+
+// keyUsage tracks a key's rate-limit bucket and quota window. It is shared by
+// every policy of the same name so that usage survives key reloads.
+type keyUsage struct {
+	mu          sync.Mutex
+	tokens      float64
+	last        time.Time
+	windowStart time.Time
+	count       int64
+}
+
+// newKeyPolicy returns the policy configured for k, or nil when k sets none.
+func newKeyPolicy(name string, k config.APIKeyConfig, usage *keyUsage) *keyPolicy {
+	quotas := usageQuotas{
+		dailyCharacters:     k.DailyCharacters,
+		monthlyCharacters:   k.MonthlyCharacters,
+		dailyAudioSeconds:   k.DailyAudioSeconds,
+		monthlyAudioSeconds: k.MonthlyAudioSeconds,
+	}
+	if k.RateLimit <= 0 && k.Quota <= 0 && len(k.Routes) == 0 && k.Priority == "" && k.Weight <= 0 && quotas.none() {
+		return nil
+	}
+
+	p := &keyPolicy{
+		name:     name,
+		rate:     k.RateLimit,
+		burst:    float64(k.Burst),
+		quota:    k.Quota,
+		period:   k.QuotaPeriod,
+		routes:   k.Routes,
+		priority: k.Priority,
+		weight:   k.Weight,
+		usage:    usage,
+		quotas:   quotas,
+	}
+	if p.burst <= 0 {
+		p.burst = math.Max(1, math.Ceil(p.rate))
+	}
+	if p.period <= 0 {
+		p.period = defaultQuotaPeriod
+	}
+	return p
+}
+
+// policyError is a request rejected by a key policy.
+type policyError struct {
+	status     int
+	code       string
+	message    string
+	retryAfter time.Duration
+}
+
+func (e *policyError) write(w http.ResponseWriter) {
+	if e.retryAfter > 0 {
+		WriteRetryError(w, e.status, e.code, e.message, e.retryAfter)
+		return
+	}
+	WriteErrorCode(w, e.status, e.code, e.message)
+}
+
+// admit checks the request path against the allowed routes, then charges the
+// request to the key's rate limit and quota. Rejected requests are not charged.
+func (p *keyPolicy) admit(path string, now time.Time) *policyError {
+	if !p.allowsRoute(path) {
+		return &policyError{status: http.StatusForbidden, code: CodeForbidden, message: "This key may not call " + path}
+	}
+	if p.rate <= 0 && p.quota <= 0 {
+		return nil
+	}
+	if p.shared != nil {
+		if perr, err := p.admitShared(now); err == nil {
+			return perr
+		}
+	}
+
+	u := p.usage
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if p.rate > 0 {
+		if u.last.IsZero() {
+			u.tokens = p.burst
+		} else {
+			u.tokens = math.Min(p.burst, u.tokens+now.Sub(u.last).Seconds()*p.rate)
+		}
+		u.last = now
+		if u.tokens < 1 {
+			return p.rateLimited(time.Duration((1 - u.tokens) / p.rate * float64(time.Second)))
+		}
+	}
+	if p.quota > 0 {
+		if u.windowStart.IsZero() || now.Sub(u.windowStart) >= p.period {
+			u.windowStart = now
+			u.count = 0
+		}
+		if u.count >= p.quota {
+			return p.quotaExceeded(u.windowStart.Add(p.period).Sub(now))
+		}
+		u.count++
+	}
+	if p.rate > 0 {
+		u.tokens--
+	}
+	return nil
+}
+
+// admitShared charges the request to the key's counters in Redis.
+func (p *keyPolicy) admitShared(now time.Time) (*policyError, error) {
+	limit := shared.Limit{Rate: p.rate, Burst: p.burst, Quota: p.quota, Period: p.period}
+	decision, err := p.shared.Admit(p.name, limit, now)
+	if err != nil {
+		return nil, err
+	}
+	switch decision.Outcome {
+	case shared.RateLimited:
+		return p.rateLimited(decision.RetryAfter), nil
+	case shared.QuotaExceeded:
+		return p.quotaExceeded(decision.RetryAfter), nil
+	}
+	return nil, nil
+}
+
+func (p *keyPolicy) rateLimited(wait time.Duration) *policyError {
+	return &policyError{status: http.StatusTooManyRequests, code: CodeRateLimited,
+		message: fmt.Sprintf("Rate limit of %g requests per second exceeded", p.rate), retryAfter: wait}
+}
+
+func (p *keyPolicy) quotaExceeded(wait time.Duration) *policyError {
+	return &policyError{status: http.StatusTooManyRequests, code: CodeQuotaExceeded,
+		message: fmt.Sprintf("Quota of %d requests per %s exhausted", p.quota, p.period), retryAfter: wait}
+}
+
+// allowsRoute reports whether path is under one of the allowed route prefixes.
+func (p *keyPolicy) allowsRoute(path string) bool {
+	if len(p.routes) == 0 {
+		return true
+	}
+	for _, route := range p.routes {
+		route = strings.TrimSuffix(route, "/")
+		if path == route || strings.HasPrefix(path, route+"/") {
+			return true
+		}
+	}
+	return false
+}
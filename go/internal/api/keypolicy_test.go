@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+	"github.com/fish-speech-go/fish-speech-go/internal/shared"
+)
+
+func TestKeyPolicy_RateLimit(t *testing.T) {
+	p := newKeyPolicy("k", config.APIKeyConfig{RateLimit: 2, Burst: 2}, &keyUsage{})
+	now := time.Unix(1000, 0)
+
+	assert.Nil(t, p.admit("/v1/tts", now))
+	assert.Nil(t, p.admit("/v1/tts", now))
+	err := p.admit("/v1/tts", now)
+	require.NotNil(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, err.status)
+	assert.Equal(t, CodeRateLimited, err.code)
+	assert.Equal(t, 500*time.Millisecond, err.retryAfter)
+
+	assert.Nil(t, p.admit("/v1/tts", now.Add(500*time.Millisecond)))
+}
+
+func TestKeyPolicy_Quota(t *testing.T) {
+	p := newKeyPolicy("k", config.APIKeyConfig{Quota: 2, QuotaPeriod: time.Hour}, &keyUsage{})
+	now := time.Unix(1000, 0)
+
+	assert.Nil(t, p.admit("/v1/tts", now))
+	assert.Nil(t, p.admit("/v1/tts", now.Add(time.Minute)))
+	err := p.admit("/v1/tts", now.Add(2*time.Minute))
+	require.NotNil(t, err)
+	assert.Equal(t, CodeQuotaExceeded, err.code)
+	assert.Equal(t, 58*time.Minute, err.retryAfter)
+
+	assert.Nil(t, p.admit("/v1/tts", now.Add(time.Hour)), "a new period resets the quota")
+}
+
+func TestKeyPolicy_SharedRateLimit(t *testing.T) {
+	mr := miniredis.RunT(t)
+	store, err := shared.Open(config.RedisConfig{URL: "redis://" + mr.Addr()})
+	require.NoError(t, err)
+	defer store.Close()
+
+	// Two servers, each with its own local usage, share the counters.
+	key := config.APIKeyConfig{RateLimit: 1, Burst: 2}
+	a := newKeyPolicy("k", key, &keyUsage{})
+	b := newKeyPolicy("k", key, &keyUsage{})
+	a.shared, b.shared = store, store
+	now := time.Unix(1000, 0)
+
+	assert.Nil(t, a.admit("/v1/tts", now))
+	assert.Nil(t, b.admit("/v1/tts", now))
+	perr := a.admit("/v1/tts", now)
+	require.NotNil(t, perr)
+	assert.Equal(t, CodeRateLimited, perr.code)
+	assert.Equal(t, time.Second, perr.retryAfter)
+
+	mr.Close()
+	assert.Nil(t, b.admit("/v1/tts", now), "falls back to local usage while Redis is down")
+}
+
+func TestKeyPolicy_Routes(t *testing.T) {
+	p := newKeyPolicy("k", config.APIKeyConfig{Routes: []string{"/v1/tts", "/v1/health/"}}, &keyUsage{})
+
+	assert.Nil(t, p.admit("/v1/tts", time.Now()))
+	assert.Nil(t, p.admit("/v1/tts/plan", time.Now()))
+	assert.Nil(t, p.admit("/v1/health", time.Now()))
+	err := p.admit("/v1/references", time.Now())
+	require.NotNil(t, err)
+	assert.Equal(t, http.StatusForbidden, err.status)
+	assert.NotNil(t, p.admit("/v1/ttsx", time.Now()), "prefixes match whole segments")
+}
+
+func TestKeyPolicy_PriorityTier(t *testing.T) {
+	bulk := &Principal{policy: newKeyPolicy("bulk", config.APIKeyConfig{Priority: PriorityBulk}, &keyUsage{})}
+	ctx := WithPrincipal(context.Background(), bulk)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/tts", nil).WithContext(ctx)
+	priority, err := requestPriority(req)
+	require.NoError(t, err)
+	assert.Equal(t, PriorityBulk, priority)
+	assert.NoError(t, authorizePriority(ctx, PriorityBulk))
+	assert.ErrorIs(t, authorizePriority(ctx, PriorityNormal), errPriorityAboveTier)
+
+	interactive := &Principal{policy: newKeyPolicy("i", config.APIKeyConfig{Priority: PriorityInteractive}, &keyUsage{})}
+	assert.NoError(t, authorizePriority(WithPrincipal(context.Background(), interactive), PriorityInteractive))
+}
+
+func TestKeyAuth_KeysFileHotReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+keys:
+  - name: team-a
+    key: team-a-key
+    quota: 1
+    routes: ["/v1/tts"]
+`), 0o600))
+
+	handler := KeyAuthMiddleware(config.AuthConfig{KeysFile: path}, nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	do := func(path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusForbidden, do("/v1/references/add", "team-a-key").Code)
+	assert.Equal(t, http.StatusOK, do("/v1/tts", "team-a-key").Code)
+	w := do("/v1/tts", "team-a-key")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// Raising the quota takes effect on reload; usage so far is kept.
+	require.NoError(t, os.WriteFile(path, []byte(`
+keys:
+  - name: team-a
+    key: team-a-key
+    quota: 2
+`), 0o600))
+	assert.Equal(t, http.StatusOK, do("/v1/references/add", "team-a-key").Code)
+	assert.Equal(t, http.StatusTooManyRequests, do("/v1/tts", "team-a-key").Code)
+}
@@ -0,0 +1,359 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/apikey"
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+	"github.com/fish-speech-go/fish-speech-go/internal/shared"
+)
+
+// SecretSource supplies secret values fetched at runtime, such as API keys held
+// in a secret manager. Version changes whenever any value changes.
+type SecretSource interface {
+	Get(name string) (string, bool)
+	Version() uint64
+}
+
+// keyEntry is one accepted API key, held only as a salted hash.
+type keyEntry struct {
+	hash      apikey.Hashed
+	principal *Principal
+}
+
+// keySet is an immutable set of API keys indexed by key prefix.
+type keySet struct {
+	byPrefix map[string][]keyEntry
+	// unprefixed holds configured hashes without a prefix; every key is
+	// compared against them.
+	unprefixed []keyEntry
+	// users holds HTTP Basic credentials by username.
+	users map[string]basicUser
+	size  int
+}
+
+func newKeySet(users map[string]basicUser, entries ...[]keyEntry) *keySet {
+	s := &keySet{byPrefix: make(map[string][]keyEntry), users: users, size: len(users)}
+	for _, list := range entries {
+		for _, e := range list {
+			if e.principal.KeyPrefix == "" {
+				s.unprefixed = append(s.unprefixed, e)
+			} else {
+				s.byPrefix[e.principal.KeyPrefix] = append(s.byPrefix[e.principal.KeyPrefix], e)
+			}
+			s.size++
+		}
+	}
+	return s
+}
+
+// lookup returns the principal of token, comparing it against every candidate
+// hash in constant time.
+func (s *keySet) lookup(token string) (*Principal, bool) {
+	var found *Principal
+	for _, candidates := range [][]keyEntry{s.byPrefix[apikey.Prefix(token)], s.unprefixed} {
+		for _, e := range candidates {
+			if e.hash.Matches(token) && found == nil {
+				found = e.principal
+			}
+		}
+	}
+	return found, found != nil
+}
+
+// lookupUser returns the principal of a Basic auth user whose password matches.
+func (s *keySet) lookupUser(username, password string) (*Principal, bool) {
+	u, ok := s.users[username]
+	if !ok || !u.verify(password) {
+		return nil, false
+	}
+	return u.principal, true
+}
+
+// plainKey hashes a plain-text key for principal p, recording its prefix.
+func plainKey(key string, p Principal) (keyEntry, bool) {
+	h, err := apikey.New(key)
+	if err != nil {
+		return keyEntry{}, false
+	}
+	p.KeyPrefix = apikey.Prefix(key)
+	return keyEntry{hash: h, principal: &p}, true
+}
+
+// hashedKey parses a configured key hash for principal p.
+func hashedKey(hash, prefix string, p Principal) (keyEntry, bool) {
+	h, err := apikey.Parse(hash)
+	if err != nil {
+		return keyEntry{}, false
+	}
+	p.KeyPrefix = prefix
+	return keyEntry{hash: h, principal: &p}, true
+}
+
+// keyring holds the accepted API keys and Basic credentials. Keys from config
+// are fixed; auth.api_key_file, auth.key_dir, auth.keys_file and
+// auth.basic.htpasswd_file are re-read when the files change, checked at most
+// once per reload interval on the request path; keys named by secret are taken
+// from the SecretSource whenever it changes. Plain-text keys and passwords are
+// hashed as they are loaded and not retained.
+type keyring struct {
+	cfg     config.AuthConfig
+	secrets SecretSource
+	// rates, when set, is where key policies count usage.
+	rates *shared.Store
+
+	current atomic.Pointer[keySet]
+
+	mu             sync.Mutex
+	usage          map[string]*keyUsage
+	static         []keyEntry
+	staticUsers    map[string]basicUser
+	checkedAt      time.Time
+	stamp          string
+	fileKeys       []keyEntry
+	fileUsers      map[string]basicUser
+	secretsVersion uint64
+}
+
+func newKeyring(cfg config.AuthConfig, secrets SecretSource, rates *shared.Store) *keyring {
+	k := &keyring{cfg: cfg, secrets: secrets, rates: rates, usage: make(map[string]*keyUsage)}
+	k.mu.Lock()
+	k.static = k.staticKeysLocked()
+	k.staticUsers = configUsers(cfg.Basic.Users)
+	k.reloadFilesLocked(time.Now())
+	k.rebuildLocked()
+	k.mu.Unlock()
+	return k
+}
+
+func (k *keyring) staticKeysLocked() []keyEntry {
+	var entries []keyEntry
+	if k.cfg.APIKey != "" {
+		if e, ok := plainKey(k.cfg.APIKey, Principal{Role: RoleAdmin}); ok {
+			entries = append(entries, e)
+		}
+	}
+	if k.cfg.APIKeyHash != "" {
+		if e, ok := hashedKey(k.cfg.APIKeyHash, "", Principal{Role: RoleAdmin}); ok {
+			entries = append(entries, e)
+		}
+	}
+	return append(entries, k.configKeysLocked(k.cfg.Keys)...)
+}
+
+// configKeysLocked converts configured keys, attaching their policies. Callers
+// must hold k.mu.
+func (k *keyring) configKeysLocked(keys []config.APIKeyConfig) []keyEntry {
+	var entries []keyEntry
+	for _, key := range keys {
+		p := Principal{Namespace: key.Namespace, Role: key.Role}
+
+		var e keyEntry
+		var ok bool
+		switch {
+		case key.Hash != "":
+			e, ok = hashedKey(key.Hash, key.Prefix, p)
+		case key.Key != "":
+			e, ok = plainKey(key.Key, p)
+		}
+		if !ok {
+			continue
+		}
+
+		name := key.Name
+		if name == "" {
+			name = e.principal.KeyPrefix
+		}
+		if name == "" {
+			name = key.Hash
+		}
+		e.principal.policy = k.policyLocked(name, key)
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// policyLocked returns the policy of key, counting usage under name. Callers
+// must hold k.mu.
+func (k *keyring) policyLocked(name string, key config.APIKeyConfig) *keyPolicy {
+	usage, found := k.usage[name]
+	if !found {
+		usage = &keyUsage{}
+		k.usage[name] = usage
+	}
+	p := newKeyPolicy(name, key, usage)
+	if p != nil {
+		p.shared = k.rates
+	}
+	return p
+}
+
+// namedPolicy returns the policy of the configured key called name, or nil
+// when there is none.
+func (k *keyring) namedPolicy(name string) *keyPolicy {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for _, key := range k.cfg.Keys {
+		if key.Name == name {
+			return k.policyLocked(name, key)
+		}
+	}
+	return nil
+}
+
+// dynamic reports whether keys may change at runtime.
+func (k *keyring) dynamic() bool {
+	return k.cfg.KeyFilesEnabled() || k.secrets != nil
+}
+
+// keys returns the current key set, reloading key files and secrets if they
+// changed.
+func (k *keyring) keys() *keySet {
+	if k.dynamic() && k.mu.TryLock() {
+		changed := false
+		if now := time.Now(); now.Sub(k.checkedAt) >= k.cfg.ReloadInterval {
+			changed = k.reloadFilesLocked(now)
+		}
+		if k.secrets != nil && k.secrets.Version() != k.secretsVersion {
+			changed = true
+		}
+		if changed {
+			k.rebuildLocked()
+		}
+		k.mu.Unlock()
+	}
+	return k.current.Load()
+}
+
+// reloadFilesLocked re-reads the key files if they changed, reporting whether
+// they did. If a key source cannot be read the previous keys stay in effect.
+// Callers must hold k.mu.
+func (k *keyring) reloadFilesLocked(now time.Time) bool {
+	k.checkedAt = now
+	if !k.cfg.KeyFilesEnabled() {
+		return false
+	}
+
+	stamp, files, err := k.keyFiles()
+	if err != nil || stamp == k.stamp {
+		return false
+	}
+
+	var entries []keyEntry
+	var users map[string]basicUser
+	for _, f := range files {
+		if f.path == k.cfg.Basic.HtpasswdFile {
+			if users, err = loadHtpasswd(f.path); err != nil {
+				return false
+			}
+			continue
+		}
+		if f.path == k.cfg.KeysFile {
+			keys, err := config.LoadKeysFile(f.path)
+			if err != nil {
+				return false
+			}
+			entries = append(entries, k.configKeysLocked(keys)...)
+			continue
+		}
+
+		raw, err := os.ReadFile(f.path)
+		if err != nil {
+			return false
+		}
+		if key := strings.TrimSpace(string(raw)); key != "" {
+			if e, ok := plainKey(key, Principal{Role: f.role}); ok {
+				entries = append(entries, e)
+			}
+		}
+	}
+
+	k.stamp = stamp
+	k.fileKeys = entries
+	k.fileUsers = users
+	return true
+}
+
+// rebuildLocked publishes the union of config, file and secret keys. Callers
+// must hold k.mu.
+func (k *keyring) rebuildLocked() {
+	var secretKeys []keyEntry
+	if k.secrets != nil {
+		k.secretsVersion = k.secrets.Version()
+		for _, name := range k.cfg.SecretNames() {
+			key, ok := k.secrets.Get(name)
+			if !ok || key == "" {
+				continue
+			}
+			role := ""
+			if name == k.cfg.APIKeySecret {
+				role = RoleAdmin
+			}
+			if e, ok := plainKey(key, Principal{Role: role}); ok {
+				secretKeys = append(secretKeys, e)
+			}
+		}
+	}
+	users := make(map[string]basicUser, len(k.staticUsers)+len(k.fileUsers))
+	for name, u := range k.fileUsers {
+		users[name] = u
+	}
+	for name, u := range k.staticUsers {
+		users[name] = u
+	}
+	k.current.Store(newKeySet(users, k.static, k.fileKeys, secretKeys))
+}
+
+type keyFile struct {
+	path string
+	role string
+}
+
+// keyFiles lists the key files with the role their key grants, and a stamp
+// that changes whenever any of them is added, removed or modified.
+func (k *keyring) keyFiles() (string, []keyFile, error) {
+	var files []keyFile
+	if k.cfg.APIKeyFile != "" {
+		files = append(files, keyFile{path: k.cfg.APIKeyFile, role: RoleAdmin})
+	}
+	if k.cfg.KeysFile != "" {
+		files = append(files, keyFile{path: k.cfg.KeysFile})
+	}
+	if k.cfg.Basic.HtpasswdFile != "" {
+		files = append(files, keyFile{path: k.cfg.Basic.HtpasswdFile})
+	}
+	if k.cfg.KeyDir != "" {
+		entries, err := os.ReadDir(k.cfg.KeyDir)
+		if err != nil {
+			return "", nil, err
+		}
+		for _, e := range entries {
+			// Kubernetes secret volumes keep their data in hidden ..data entries.
+			if strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+			files = append(files, keyFile{path: filepath.Join(k.cfg.KeyDir, e.Name())})
+		}
+	}
+
+	var stamp strings.Builder
+	regular := files[:0]
+	for _, f := range files {
+		info, err := os.Stat(f.path)
+		if err != nil {
+			return "", nil, err
+		}
+		if info.IsDir() {
+			continue
+		}
+		regular = append(regular, f)
+		fmt.Fprintf(&stamp, "%s:%d:%d;", f.path, info.Size(), info.ModTime().UnixNano())
+	}
+	return stamp.String(), regular, nil
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/apikey"
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+func TestKeyAuth_KeyFiles(t *testing.T) {
+	dir := t.TempDir()
+	adminFile := filepath.Join(dir, "admin-key")
+	keyDir := filepath.Join(dir, "keys")
+	require.NoError(t, os.WriteFile(adminFile, []byte("admin-secret\n"), 0o600))
+	require.NoError(t, os.Mkdir(keyDir, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(keyDir, "tenant-a"), []byte("key-a"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(keyDir, "..data"), []byte("ignored"), 0o600))
+
+	var principal *Principal
+	handler := KeyAuthMiddleware(config.AuthConfig{APIKeyFile: adminFile, KeyDir: keyDir}, nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal = PrincipalFromContext(r.Context())
+		}))
+
+	do := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/v1/health", nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, do("admin-secret"))
+	assert.True(t, principal.IsAdmin())
+	assert.Equal(t, http.StatusOK, do("key-a"))
+	assert.False(t, principal.IsAdmin())
+	assert.Equal(t, http.StatusUnauthorized, do("ignored"))
+
+	// Rotate: the old key stops working and the new one is accepted.
+	require.NoError(t, os.WriteFile(filepath.Join(keyDir, "tenant-a"), []byte("key-a-rotated"), 0o600))
+	assert.Equal(t, http.StatusUnauthorized, do("key-a"))
+	assert.Equal(t, http.StatusOK, do("key-a-rotated"))
+
+	// An unreadable source keeps the previous keys.
+	require.NoError(t, os.RemoveAll(keyDir))
+	assert.Equal(t, http.StatusOK, do("key-a-rotated"))
+}
+
+func TestKeyAuth_EmptyKeyFileRejectsAll(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "api-key")
+	require.NoError(t, os.WriteFile(file, nil, 0o600))
+
+	handler := KeyAuthMiddleware(config.AuthConfig{APIKeyFile: file}, nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/health", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+type fakeSecrets struct {
+	values  map[string]string
+	version uint64
+}
+
+func (s *fakeSecrets) Get(name string) (string, bool) {
+	v, ok := s.values[name]
+	return v, ok
+}
+
+func (s *fakeSecrets) Version() uint64 {
+	return s.version
+}
+
+func TestKeyAuth_Secrets(t *testing.T) {
+	secrets := &fakeSecrets{values: map[string]string{"admin": "admin-secret", "tenant": "key-a"}}
+	cfg := config.AuthConfig{APIKeySecret: "admin", KeySecrets: []string{"tenant"}}
+
+	var principal *Principal
+	handler := KeyAuthMiddleware(cfg, secrets)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal = PrincipalFromContext(r.Context())
+	}))
+
+	do := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/v1/health", nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, do("admin-secret"))
+	assert.True(t, principal.IsAdmin())
+	assert.Equal(t, http.StatusOK, do("key-a"))
+	assert.False(t, principal.IsAdmin())
+
+	secrets.values["tenant"] = "key-b"
+	secrets.version++
+	assert.Equal(t, http.StatusUnauthorized, do("key-a"))
+	assert.Equal(t, http.StatusOK, do("key-b"))
+}
+
+func TestKeyAuth_HashedKeys(t *testing.T) {
+	tenant, err := apikey.New("fsk_tenant_secret")
+	require.NoError(t, err)
+	admin, err := apikey.New("fsk_admin_secret")
+	require.NoError(t, err)
+
+	cfg := config.AuthConfig{
+		APIKeyHash: admin.String(),
+		Keys: []config.APIKeyConfig{
+			{Hash: tenant.String(), Prefix: apikey.Prefix("fsk_tenant_secret"), Namespace: "tenant"},
+		},
+	}
+	var principal *Principal
+	handler := KeyAuthMiddleware(cfg, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal = PrincipalFromContext(r.Context())
+	}))
+
+	do := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/v1/health", nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, do("fsk_tenant_secret"))
+	assert.Equal(t, "tenant", principal.Namespace)
+	assert.Equal(t, "fsk_tena", principal.KeyPrefix)
+
+	assert.Equal(t, http.StatusOK, do("fsk_admin_secret"))
+	assert.True(t, principal.IsAdmin())
+
+	assert.Equal(t, http.StatusUnauthorized, do("fsk_tenant_other"))
+	assert.Equal(t, http.StatusUnauthorized, do(tenant.String()))
+}
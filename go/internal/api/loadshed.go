@@ -0,0 +1,109 @@
+package api
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/metrics"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+const (
+	// resourceSampleInterval bounds how often process resources are measured.
+	resourceSampleInterval = time.Second
+	// defaultBulkShedRatio is the fraction of each limit at which bulk traffic is shed.
+	defaultBulkShedRatio = 0.8
+)
+
+// resourceUsage is a snapshot of the process resources load shedding watches.
+type resourceUsage struct {
+	MemoryBytes uint64
+	Goroutines  int
+}
+
+// resourceSampler caches resource measurements so they are taken at most once
+// per interval regardless of request rate.
+type resourceSampler struct {
+	mu       sync.Mutex
+	sampled  time.Time
+	usage    resourceUsage
+	interval time.Duration
+	read     func() resourceUsage
+}
+
+func (s *resourceSampler) current() resourceUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now := time.Now(); now.Sub(s.sampled) >= s.interval {
+		s.usage = s.read()
+		s.sampled = now
+	}
+	return s.usage
+}
+
+// readResourceUsage measures the memory the Go runtime holds from the OS and
+// the current goroutine count.
+func readResourceUsage() resourceUsage {
+	samples := []metrics.Sample{
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/released:bytes"},
+	}
+	metrics.Read(samples)
+
+	var memory uint64
+	if samples[0].Value.Kind() == metrics.KindUint64 && samples[1].Value.Kind() == metrics.KindUint64 {
+		memory = samples[0].Value.Uint64() - samples[1].Value.Uint64()
+	}
+	return resourceUsage{MemoryBytes: memory, Goroutines: runtime.NumGoroutine()}
+}
+
+// LoadShedMiddleware rejects new requests with 503 while process memory or the
+// goroutine count exceeds the configured limits. Bulk-priority requests are shed
+// earlier, once usage passes BulkShedRatio of a limit.
+func LoadShedMiddleware(cfg config.LimitsConfig, logger zerolog.Logger) func(http.Handler) http.Handler {
+	return loadShedMiddleware(cfg, logger, &resourceSampler{interval: resourceSampleInterval, read: readResourceUsage})
+}
+
+func loadShedMiddleware(cfg config.LimitsConfig, logger zerolog.Logger, sampler *resourceSampler) func(http.Handler) http.Handler {
+	ratio := cfg.BulkShedRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = defaultBulkShedRatio
+	}
+	maxMemory := uint64(cfg.MaxMemoryMB) * 1024 * 1024
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			priority, err := requestPriority(r)
+			if err != nil {
+				WriteError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+
+			scale := 1.0
+			if priority == PriorityBulk {
+				scale = ratio
+			}
+
+			usage := sampler.current()
+			overMemory := maxMemory > 0 && float64(usage.MemoryBytes) > float64(maxMemory)*scale
+			overGoroutines := cfg.MaxGoroutines > 0 && float64(usage.Goroutines) > float64(cfg.MaxGoroutines)*scale
+			if overMemory || overGoroutines {
+				logger.Warn().
+					Str("priority", priority).
+					Uint64("memory_bytes", usage.MemoryBytes).
+					Int("goroutines", usage.Goroutines).
+					Msg("Shedding request under resource pressure")
+				w.Header().Set("Retry-After", "1")
+				WriteError(w, http.StatusServiceUnavailable, "Server is overloaded, please retry later")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+func TestLoadShedMiddleware(t *testing.T) {
+	usage := resourceUsage{Goroutines: 85}
+	sampler := &resourceSampler{interval: time.Nanosecond, read: func() resourceUsage { return usage }}
+	cfg := config.LimitsConfig{MaxGoroutines: 100, BulkShedRatio: 0.8}
+
+	h := loadShedMiddleware(cfg, testLogger(), sampler)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	do := func(priority string) int {
+		req := httptest.NewRequest(http.MethodPost, "/v1/tts", nil)
+		if priority != "" {
+			req.Header.Set("X-Priority", priority)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, do(""))
+	assert.Equal(t, http.StatusOK, do(PriorityInteractive))
+	assert.Equal(t, http.StatusServiceUnavailable, do(PriorityBulk))
+	assert.Equal(t, http.StatusBadRequest, do("urgent"))
+
+	usage.Goroutines = 120
+	assert.Equal(t, http.StatusServiceUnavailable, do(PriorityInteractive))
+
+	usage.Goroutines = 10
+	assert.Equal(t, http.StatusOK, do(PriorityBulk))
+}
+
+func TestReadResourceUsage(t *testing.T) {
+	usage := readResourceUsage()
+	assert.Greater(t, usage.MemoryBytes, uint64(0))
+	assert.Greater(t, usage.Goroutines, 0)
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// LockResponse is returned when a reference is locked or unlocked.
+type LockResponse struct {
+	Success     bool   `json:"success"`
+	Message     string `json:"message"`
+	ReferenceID string `json:"reference_id"`
+	Locked      bool   `json:"locked"`
+}
+
+// HandleLockReference marks a reference as locked so it cannot be deleted or
+// overwritten without an explicit admin force.
+func (h *Handler) HandleLockReference(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	backendID := scopeReferenceID(namespaceFromContext(r.Context()), id)
+
+	start := time.Now()
+	refs, err := h.backend.ListReferences(r.Context())
+	h.logBackendCall(r.Context(), OpListReferences, start, err)
+	if err != nil {
+		h.handleBackendError(w, err)
+		return
+	}
+	if !containsString(refs.ReferenceIDs, backendID) {
+		WriteError(w, http.StatusNotFound, "Reference not found")
+		return
+	}
+
+	if err := h.refs.SetLocked(backendID, true); err != nil {
+		h.logger.Error().Err(err).Msg("Lock reference error")
+		WriteError(w, http.StatusInternalServerError, "Failed to lock reference")
+		return
+	}
+
+	h.referenceMutation(r.Context(), ActionLock, backendID).Msg("Reference locked")
+	WriteJSON(w, http.StatusOK, LockResponse{Success: true, Message: "Reference locked", ReferenceID: id, Locked: true})
+}
+
+// HandleUnlockReference clears the locked flag. Only admin keys may unlock.
+func (h *Handler) HandleUnlockReference(w http.ResponseWriter, r *http.Request) {
+	if !isAdminRequest(r.Context()) {
+		WriteError(w, http.StatusForbidden, "Unlocking a reference requires an admin key")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	backendID := scopeReferenceID(namespaceFromContext(r.Context()), id)
+
+	if err := h.refs.SetLocked(backendID, false); err != nil {
+		h.logger.Error().Err(err).Msg("Unlock reference error")
+		WriteError(w, http.StatusInternalServerError, "Failed to unlock reference")
+		return
+	}
+
+	h.referenceMutation(r.Context(), ActionUnlock, backendID).Msg("Reference unlocked")
+	WriteJSON(w, http.StatusOK, LockResponse{Success: true, Message: "Reference unlocked", ReferenceID: id, Locked: false})
+}
+
+// checkReferenceLock rejects destructive operations on a locked reference unless
+// the caller is an admin and passed force=true. It reports whether to proceed.
+func (h *Handler) checkReferenceLock(w http.ResponseWriter, r *http.Request, backendID string) bool {
+	if !h.refs.IsLocked(backendID) {
+		return true
+	}
+
+	if r.URL.Query().Get("force") != "true" || !isAdminRequest(r.Context()) {
+		WriteError(w, http.StatusLocked, "Reference is locked; force=true with an admin key is required")
+		return false
+	}
+
+	h.referenceMutation(r.Context(), ActionLockOverride, backendID).Msg("Overriding reference lock")
+	return true
+}
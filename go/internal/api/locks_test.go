@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func TestLockedReference_DeleteRequiresAdminForce(t *testing.T) {
+	mock := &mockBackend{
+		listRefResp:   &schema.ListReferencesResponse{Success: true, ReferenceIDs: []string{"prod-voice"}},
+		deleteRefResp: &schema.DeleteReferenceResponse{Success: true, ReferenceID: "prod-voice"},
+	}
+	cfg := testConfig()
+	cfg.Auth = config.AuthConfig{Keys: []config.APIKeyConfig{
+		{Key: "user-key"},
+		{Key: "admin-key", Role: RoleAdmin},
+	}}
+	router := NewRouter(cfg, mock, testLogger())
+
+	do := func(method, path, key string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusOK, do(http.MethodPut, "/v1/references/prod-voice/lock", "user-key"))
+
+	assert.Equal(t, http.StatusLocked, do(http.MethodDelete, "/v1/references/prod-voice", "user-key"))
+	assert.Equal(t, http.StatusLocked, do(http.MethodDelete, "/v1/references/prod-voice?force=true", "user-key"))
+	assert.Equal(t, http.StatusLocked, do(http.MethodDelete, "/v1/references/prod-voice", "admin-key"))
+	assert.Empty(t, mock.lastDeleteID)
+
+	assert.Equal(t, http.StatusForbidden, do(http.MethodDelete, "/v1/references/prod-voice/lock", "user-key"))
+
+	assert.Equal(t, http.StatusOK, do(http.MethodDelete, "/v1/references/prod-voice?force=true", "admin-key"))
+	assert.Equal(t, "prod-voice", mock.lastDeleteID)
+}
+
+func TestLockReference_NotFound(t *testing.T) {
+	mock := &mockBackend{listRefResp: &schema.ListReferencesResponse{Success: true}}
+	router := NewRouter(testConfig(), mock, testLogger())
+
+	req := httptest.NewRequest(http.MethodPut, "/v1/references/missing/lock", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/metrics"
+)
+
+// unmatchedRoute labels requests that matched no route, so unknown paths do
+// not each create a time series or span name.
+const unmatchedRoute = "unmatched"
+
+// HandleMetrics serves Prometheus metrics.
+func (h *Handler) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	h.metrics.Handler().ServeHTTP(w, r)
+}
+
+// MetricsMiddleware counts requests and observes their latency in m, labelled
+// with the route pattern they matched rather than their path.
+func MetricsMiddleware(m *metrics.Metrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+
+			next.ServeHTTP(rw, r)
+
+			route := routePattern(r)
+			status := strconv.Itoa(rw.status)
+			m.HTTPRequestsTotal.WithLabelValues(route, r.Method, status).Inc()
+			m.HTTPRequestSeconds.WithLabelValues(route, r.Method, status).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// routePattern returns the route pattern r matched once it has been served,
+// or unmatchedRoute.
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return unmatchedRoute
+	}
+	// Requests under /v1 and /v2 that match no route keep the pattern of the
+	// version's mount, "/v1/*".
+	pattern := rctx.RoutePattern()
+	if pattern == "" || strings.HasSuffix(pattern, "/*") {
+		return unmatchedRoute
+	}
+	return pattern
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/backend"
+	"github.com/fish-speech-go/fish-speech-go/internal/limiter"
+	"github.com/fish-speech-go/fish-speech-go/internal/metrics"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func scrapeMetrics(t *testing.T, router http.Handler) string {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	return w.Body.String()
+}
+
+func TestMetrics_StreamOutcomes(t *testing.T) {
+	router := NewRouter(testConfig(), &mockBackend{ttsResponse: []byte("audio data")}, testLogger())
+
+	body, _ := json.Marshal(schema.ServeTTSRequest{Text: "Hello", Format: "wav", Streaming: true})
+	req := httptest.NewRequest(http.MethodPost, "/v1/tts", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	out := scrapeMetrics(t, router)
+	assert.Contains(t, out, `fish_tts_streams_total{outcome="completed"} 1`)
+	assert.Contains(t, out, `fish_tts_streams_total{outcome="client_aborted"} 0`)
+	assert.Contains(t, out, `fish_tts_stream_bytes_sum{outcome="completed"} 10`)
+	assert.True(t, strings.Contains(out, "go_goroutines"))
+}
+
+func TestMetrics_Limiter(t *testing.T) {
+	cfg := testConfig()
+	cfg.Limits.MaxConcurrent = 4
+	cfg.Limits.AcquireTimeout = 10 * time.Millisecond
+	m := metrics.New()
+	h := NewHandler(&mockBackend{ttsResponse: []byte("audio data")}, cfg, testLogger(), WithMetrics(m))
+
+	assert.Equal(t, http.StatusOK, postTTS(t, h, schema.ServeTTSRequest{Text: "Hello"}).Code)
+	held, err := h.limiter.AcquireCost(context.Background(), limiter.PriorityNormal, limiter.Flow{}, 4)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, postTTS(t, h, schema.ServeTTSRequest{Text: "Hello"}).Code)
+	held(limiter.Outcome{})
+
+	// A second handler with a fresh limiter shows a three-slot request in use.
+	cfg.Limits.CharsPerSlot = 5
+	handler := NewHandler(&mockBackend{ttsResponse: []byte("audio data")}, cfg, testLogger(), WithMetrics(m))
+	release, _, ok := handler.acquireSlot(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/tts", nil), &schema.ServeTTSRequest{Text: "Hello, world"})
+	require.True(t, ok)
+	out := scrapeMetrics(t, m.Handler())
+	assert.Contains(t, out, "fish_limiter_utilization 0.75")
+	release(0, nil)
+
+	out = scrapeMetrics(t, m.Handler())
+	assert.Contains(t, out, `fish_limiter_wait_seconds_count{outcome="acquired"} 2`)
+	assert.Contains(t, out, `fish_limiter_wait_seconds_count{outcome="timeout"} 1`)
+	assert.Contains(t, out, `fish_limiter_wait_seconds_count{outcome="canceled"} 0`)
+	assert.Contains(t, out, "fish_limiter_hold_seconds_count 2")
+	assert.Contains(t, out, "fish_limiter_utilization 0\n")
+	assert.Contains(t, out, "fish_limiter_queue_depth 0\n")
+}
+
+func TestMetrics_Requests(t *testing.T) {
+	router := NewRouter(testConfig(), &mockBackend{ttsResponse: []byte("audio data")}, testLogger())
+
+	for _, body := range []string{`{"text": "Hello"}`, `{"text": "Hello", "streaming": true}`, `{"text": "Hello", "temperature": 5}`} {
+		req := httptest.NewRequest(http.MethodPost, "/v2/tts", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/no-such-route", nil))
+
+	out := scrapeMetrics(t, router)
+	assert.Contains(t, out, `fish_http_requests_total{method="POST",route="/v2/tts",status="200"} 2`)
+	assert.Contains(t, out, `fish_http_requests_total{method="POST",route="/v2/tts",status="400"} 1`)
+	assert.Contains(t, out, `fish_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, out, `fish_http_request_duration_seconds_count{method="POST",route="/v2/tts",status="200"} 2`)
+	assert.Contains(t, out, `fish_backend_request_duration_seconds_count{op="tts",outcome="ok"} 1`)
+	assert.Contains(t, out, `fish_backend_request_duration_seconds_count{op="tts_stream",outcome="ok"} 1`)
+	assert.Contains(t, out, "fish_tts_active_streams 0\n")
+}
+
+func TestAcquireOutcome(t *testing.T) {
+	assert.Equal(t, metrics.AcquireGranted, acquireOutcome(nil))
+	assert.Equal(t, metrics.AcquireTimeout, acquireOutcome(limiter.ErrTimeout))
+	assert.Equal(t, metrics.AcquireCanceled, acquireOutcome(context.Canceled))
+}
+
+func TestStreamOutcome(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithTimeout(context.Background(), 0)
+	defer cancelExpired()
+	<-expired.Done()
+
+	testCases := []struct {
+		name    string
+		ctx     context.Context
+		err     error
+		stalled bool
+		want    string
+	}{
+		{"completed", context.Background(), nil, false, metrics.StreamCompleted},
+		{"client write failed", context.Background(), errClientWrite, false, metrics.StreamClientAborted},
+		{"client disconnected", cancelled, io.ErrUnexpectedEOF, false, metrics.StreamClientAborted},
+		{"stalled", cancelled, context.Canceled, true, metrics.StreamTimeout},
+		{"deadline", expired, io.ErrUnexpectedEOF, false, metrics.StreamTimeout},
+		{"backend timeout", context.Background(), backend.ErrBackendTimeout, false, metrics.StreamTimeout},
+		{"backend error", context.Background(), errors.New("connection reset"), false, metrics.StreamBackendError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, streamOutcome(tc.ctx, tc.err, tc.stalled))
+		})
+	}
+}
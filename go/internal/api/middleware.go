@@ -1,95 +1,242 @@
 package api
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/backend"
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+	"github.com/fish-speech-go/fish-speech-go/internal/shared"
 )
 
 // AuthMiddleware enforces bearer token authentication when an API key is configured.
 func AuthMiddleware(apiKey string) func(http.Handler) http.Handler {
+	return KeyAuthMiddleware(config.AuthConfig{APIKey: apiKey}, nil)
+}
+
+// KeyAuthMiddleware enforces bearer token authentication against the global API key
+// and any per-tenant keys, attaching the caller's Principal to the request context.
+// When Basic users are configured, HTTP Basic credentials are accepted as well,
+// and bearer tokens that are not API keys are validated as JWTs or checked with
+// the introspection endpoint when either is configured. Requests carrying
+// X-Signature are checked against the signing secrets instead.
+// Keys read from files or from secrets take effect when they change, without a
+// restart; secrets may be nil when no secret manager is configured.
+func KeyAuthMiddleware(cfg config.AuthConfig, secrets SecretSource) func(http.Handler) http.Handler {
+	return keyAuthMiddleware(cfg, secrets, nil)
+}
+
+// keyAuthMiddleware is KeyAuthMiddleware counting key rate limits and quotas
+// in rates when it is not nil.
+func keyAuthMiddleware(cfg config.AuthConfig, secrets SecretSource, rates *shared.Store) func(http.Handler) http.Handler {
+	keys := newKeyring(cfg, secrets, rates)
+	var tokens *introspector
+	if cfg.Introspection.URL != "" {
+		tokens = newIntrospector(cfg.Introspection)
+	}
+	var jwts *jwtValidator
+	if cfg.JWT.Enabled() {
+		jwts = newJWTValidator(cfg.JWT)
+	}
+	var signer *requestSigner
+	if len(cfg.Signing.Keys) > 0 {
+		signer = newRequestSigner(cfg.Signing, keys)
+	}
+	challenge := ""
+	if cfg.Basic.Enabled() {
+		challenge = fmt.Sprintf("Basic realm=%q", cfg.Basic.Realm)
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if apiKey == "" {
+			set := keys.keys()
+			if set.size == 0 && !keys.dynamic() && tokens == nil && jwts == nil && signer == nil {
 				next.ServeHTTP(w, r)
 				return
 			}
 
+			var principal *Principal
+			var ok bool
 			auth := r.Header.Get("Authorization")
-			if !strings.HasPrefix(auth, "Bearer ") {
-				WriteError(w, http.StatusUnauthorized, "Invalid token")
-				return
+			if signer != nil && r.Header.Get(HeaderSignature) != "" {
+				var err error
+				if principal, err = signer.verify(w, r, time.Now()); err != nil {
+					var tooLarge *http.MaxBytesError
+					if errors.As(err, &tooLarge) {
+						WriteError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Signed request body exceeds the %d byte limit", tooLarge.Limit))
+						return
+					}
+					WriteErrorCode(w, http.StatusUnauthorized, CodeUnauthorized, "Invalid signature: "+err.Error())
+					return
+				}
+				ok = true
+			} else if username, password, isBasic := r.BasicAuth(); isBasic && challenge != "" {
+				principal, ok = set.lookupUser(username, password)
+			} else if strings.HasPrefix(auth, "Bearer ") {
+				token := strings.TrimPrefix(auth, "Bearer ")
+				principal, ok = set.lookup(token)
+				if !ok && jwts != nil && looksLikeJWT(token) {
+					var err error
+					principal, err = jwts.validate(r.Context(), token, time.Now())
+					switch {
+					case errors.Is(err, errNoScope):
+						WriteErrorCode(w, http.StatusForbidden, CodeForbidden, "Token has no scope that allows this API")
+						return
+					case errors.Is(err, errKeysUnavailable):
+						w.Header().Set("Retry-After", "1")
+						WriteErrorCode(w, http.StatusServiceUnavailable, CodeInternal, "Token signing keys unavailable")
+						return
+					case err != nil:
+						WriteErrorCode(w, http.StatusUnauthorized, CodeUnauthorized, "Invalid token: "+err.Error())
+						return
+					}
+					ok = true
+				} else if !ok && tokens != nil {
+					var err error
+					principal, err = tokens.lookup(r.Context(), token)
+					switch {
+					case errors.Is(err, errNoScope):
+						WriteErrorCode(w, http.StatusForbidden, CodeForbidden, "Token has no scope that allows this API")
+						return
+					case err != nil:
+						w.Header().Set("Retry-After", "1")
+						WriteErrorCode(w, http.StatusServiceUnavailable, CodeInternal, "Token introspection unavailable")
+						return
+					}
+					ok = principal != nil
+				}
 			}
-
-			token := strings.TrimPrefix(auth, "Bearer ")
-			if token != apiKey {
+			if !ok {
+				if challenge != "" {
+					w.Header().Set("WWW-Authenticate", challenge)
+				}
 				WriteError(w, http.StatusUnauthorized, "Invalid token")
 				return
 			}
+			setLogPrincipal(r.Context(), principal)
+			if principal.policy != nil {
+				if perr := principal.policy.admit(r.URL.Path, time.Now()); perr != nil {
+					perr.write(w)
+					return
+				}
+			}
 
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
 		})
 	}
 }
 
-// LoggingMiddleware logs request method, path, status, and duration using zerolog.
+// LoggingMiddleware logs request method, path, status, and duration to the
+// application logger.
 func LoggingMiddleware(logger zerolog.Logger) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			start := time.Now()
-			rw := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+	return AccessLogMiddleware(&AccessLog{format: AccessLogJSON, logger: logger})
+}
 
-			next.ServeHTTP(rw, r)
+type logFieldsKey struct{}
 
-			logger.Info().
-				Str("method", r.Method).
-				Str("path", r.URL.Path).
-				Int("status", rw.status).
-				Dur("duration", time.Since(start)).
-				Msg("request")
-		})
+// logFields carries values learned by inner handlers to the request log line.
+type logFields struct {
+	keyPrefix string
+	user      string
+}
+
+func withLogFields(ctx context.Context, f *logFields) context.Context {
+	return context.WithValue(ctx, logFieldsKey{}, f)
+}
+
+// setLogPrincipal records the authenticated key's prefix or Basic username for
+// the request log.
+func setLogPrincipal(ctx context.Context, p *Principal) {
+	if f, ok := ctx.Value(logFieldsKey{}).(*logFields); ok {
+		f.keyPrefix = p.KeyPrefix
+		f.user = p.Username
 	}
 }
 
-// RequestIDMiddleware injects a X-Request-ID header when missing.
+// RequestIDMiddleware injects a X-Request-ID header when missing, and puts the
+// ID in the request context, from which it is logged and sent on to the
+// backend.
 func RequestIDMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		requestID := r.Header.Get("X-Request-ID")
+		requestID := r.Header.Get(backend.RequestIDHeader)
 		if requestID == "" {
 			requestID = generateRequestID()
-			r.Header.Set("X-Request-ID", requestID)
+			r.Header.Set(backend.RequestIDHeader, requestID)
 		}
-		w.Header().Set("X-Request-ID", requestID)
+		w.Header().Set(backend.RequestIDHeader, requestID)
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(backend.WithRequestID(r.Context(), requestID)))
 	})
 }
 
 // CORSMiddleware allows cross-origin requests similar to upstream behavior.
 func CORSMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, X-Request-ID")
-		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
-
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
+	return CORSConfigMiddleware(config.CORSConfig{})(next)
+}
 
-		next.ServeHTTP(w, r)
-	})
+// CORSConfigMiddleware allows cross-origin requests, adding preflight caching and
+// Private Network Access support as configured.
+func CORSConfigMiddleware(cfg config.CORSConfig) func(http.Handler) http.Handler {
+	maxAge := strconv.Itoa(int(cfg.MaxAge / time.Second))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, Cache-Control, X-Request-ID, X-Priority, X-Request-Deadline, X-Signature, X-Timestamp, Upload-Offset, Upload-Length, Range, If-Range")
+			w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, X-Content-SHA256, X-Cache, X-Queue-Wait-Ms, Location, Upload-Offset, Upload-Length, ETag, Content-Range, Retry-After")
+
+			if r.Method == http.MethodOptions {
+				if cfg.MaxAge >= time.Second {
+					w.Header().Set("Access-Control-Max-Age", maxAge)
+				}
+				if cfg.AllowPrivateNetwork && r.Header.Get("Access-Control-Request-Private-Network") == "true" {
+					w.Header().Set("Access-Control-Allow-Private-Network", "true")
+				}
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
-// responseRecorder captures status codes for logging.
+// responseRecorder captures status codes and response sizes for logging.
 type responseRecorder struct {
 	http.ResponseWriter
 	status int
+	bytes  int64
+}
+
+func (rr *responseRecorder) Write(p []byte) (int, error) {
+	n, err := rr.ResponseWriter.Write(p)
+	rr.bytes += int64(n)
+	return n, err
+}
+
+// ReadFrom passes through to the underlying writer so file responses can still
+// use sendfile behind the logger.
+func (rr *responseRecorder) ReadFrom(src io.Reader) (int64, error) {
+	var n int64
+	var err error
+	if rf, ok := rr.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(src)
+	} else {
+		n, err = io.Copy(rr.ResponseWriter, src)
+	}
+	rr.bytes += n
+	return n, err
 }
 
 func (rr *responseRecorder) WriteHeader(statusCode int) {
@@ -97,6 +244,18 @@ func (rr *responseRecorder) WriteHeader(statusCode int) {
 	rr.ResponseWriter.WriteHeader(statusCode)
 }
 
+// Flush passes through to the underlying writer so streaming works behind the logger.
+func (rr *responseRecorder) Flush() {
+	if f, ok := rr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
+
 func generateRequestID() string {
 	b := make([]byte, 16)
 	if _, err := rand.Read(b); err != nil {
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"strings"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+// namespaceSeparator joins a tenant namespace and a reference ID in the backend's
+// flat reference store. Namespaces are validated with config.ValidateNamespace,
+// so a scoped ID belongs to exactly one namespace.
+const namespaceSeparator = config.NamespaceSeparator
+
+// namespaceFromContext returns the reference namespace of the caller, if any.
+func namespaceFromContext(ctx context.Context) string {
+	if p := PrincipalFromContext(ctx); p != nil {
+		return p.Namespace
+	}
+	return ""
+}
+
+// scopeReferenceID maps a client-visible reference ID to its backend ID.
+func scopeReferenceID(namespace, id string) string {
+	if namespace == "" {
+		return id
+	}
+	return namespace + namespaceSeparator + id
+}
+
+// unscopeReferenceID maps a backend reference ID back to the client-visible ID.
+func unscopeReferenceID(namespace, id string) string {
+	if namespace == "" {
+		return id
+	}
+	return strings.TrimPrefix(id, namespace+namespaceSeparator)
+}
+
+// inNamespace reports whether the backend reference ID belongs to namespace. Every
+// ID belongs to the empty (unscoped) namespace.
+func inNamespace(namespace, backendID string) bool {
+	return namespace == "" || strings.HasPrefix(backendID, namespace+namespaceSeparator)
+}
+
+// filterNamespace returns the client-visible IDs of the references owned by namespace.
+func filterNamespace(namespace string, ids []string) []string {
+	if namespace == "" {
+		return ids
+	}
+
+	prefix := namespace + namespaceSeparator
+	filtered := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if strings.HasPrefix(id, prefix) {
+			filtered = append(filtered, strings.TrimPrefix(id, prefix))
+		}
+	}
+	return filtered
+}
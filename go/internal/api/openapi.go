@@ -0,0 +1,286 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/openapi"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+var (
+	openAPIOnce sync.Once
+	openAPIJSON []byte
+)
+
+// HandleOpenAPI serves the OpenAPI document describing the HTTP API.
+func (h *Handler) HandleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	openAPIOnce.Do(func() {
+		openAPIJSON, _ = json.Marshal(BuildOpenAPI())
+	})
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(openAPIJSON)
+}
+
+// BuildOpenAPI describes the routes registered by NewRouter. Request and
+// response schemas are derived from the handler and schema types.
+func BuildOpenAPI() *openapi.Document {
+	doc := openapi.New("Fish-Speech-Go API", "1.0.0")
+	doc.Info.Description = "/v1 mirrors the Python Fish-Speech server. /v2 serves the same operations " +
+		"with JSON bodies wrapped in {\"data\": ...} and structured errors."
+
+	for _, version := range []int{APIVersion1, APIVersion2} {
+		b := specBuilder{doc: doc, version: version}
+		b.addCommon()
+	}
+
+	v1 := specBuilder{doc: doc, version: APIVersion1}
+	v1.add(http.MethodPost, "/references/add", "Add a reference voice", "references",
+		v1.body(schema.AddReferenceRequest{}), v1.json(AddReferenceResult{}))
+	v1.add(http.MethodGet, "/references", "List reference voices", "references",
+		nil, v1.json(ListReferencesResult{}))
+
+	v2 := specBuilder{doc: doc, version: APIVersion2}
+	v2.add(http.MethodPost, "/references", "Add a reference voice", "references",
+		v2.body(schema.AddReferenceRequest{}), v2.json(AddReferenceResult{}))
+	listRefs := v2.op("List reference voices in pages", "references", nil, openapi.Response{
+		Description: "OK",
+		Content: map[string]openapi.MediaType{"application/json": {Schema: openapi.Schema{
+			"type": "object",
+			"properties": openapi.Schema{
+				"data":       doc.SchemaOf([]string{}),
+				"pagination": doc.SchemaOf(Pagination{}),
+			},
+		}}},
+	})
+	listRefs.Parameters = []openapi.Parameter{
+		{Name: "limit", In: "query", Description: "Page size (1-500, default 50)", Schema: openapi.Schema{"type": "integer"}},
+		{Name: "cursor", In: "query", Description: "next_cursor of the previous page", Schema: openapi.Schema{"type": "string"}},
+	}
+	doc.Add(http.MethodGet, "/v2/references", listRefs)
+
+	admin := specBuilder{doc: doc}
+	admin.addAt(http.MethodGet, "/admin/backends", "List backend routing targets", "admin", nil, admin.json(ListBackendsResponse{}))
+	admin.addAt(http.MethodPut, "/admin/backends/{name}/weight", "Set a canary backend's traffic weight", "admin",
+		admin.body(SetBackendWeightRequest{}), admin.json(ListBackendsResponse{}))
+	admin.addAt(http.MethodGet, "/admin/limiter", "Backend concurrency limiter state", "admin", nil, admin.json(LimiterStatus{}))
+	admin.addAt(http.MethodPut, "/admin/limiter", "Change the concurrency limit and acquire timeout", "admin",
+		admin.body(SetLimiterRequest{}), admin.json(LimiterStatus{}))
+	admin.addAt(http.MethodGet, "/admin/config", "Effective configuration with value sources", "admin", nil, admin.json(ConfigResponse{}))
+	admin.addAt(http.MethodGet, "/admin/cache", "TTS response cache statistics", "admin", nil, admin.json(CacheStatus{}))
+	admin.addAt(http.MethodGet, "/admin/usage", "Usage of every API key", "admin", nil, admin.json(ListUsageResponse{}))
+	invalidate := admin.op("Invalidate cached TTS responses", "admin", nil, admin.json(CacheInvalidateResponse{}))
+	invalidate.Parameters = []openapi.Parameter{
+		{Name: "reference_id", In: "query", Description: "Only remove responses synthesized with this reference", Schema: openapi.Schema{"type": "string"}},
+		{Name: "text_hash", In: "query", Description: "Only remove responses for the text with this SHA-256 hex digest", Schema: openapi.Schema{"type": "string"}},
+	}
+	doc.Add(http.MethodDelete, "/admin/cache", invalidate)
+	diagnostics := admin.op("Runtime diagnostics", "admin", nil, admin.json(DiagnosticsResponse{}))
+	diagnostics.Parameters = []openapi.Parameter{
+		{Name: "stacks", In: "query", Description: "true returns a text dump of every goroutine's stack instead", Schema: openapi.Schema{"type": "boolean"}},
+	}
+	doc.Add(http.MethodGet, "/admin/diagnostics", diagnostics)
+	admin.addAt(http.MethodGet, "/admin/status", "Server state and work in flight", "admin", nil, admin.json(AdminStatus{}))
+	admin.addAt(http.MethodPut, "/admin/maintenance", "Turn maintenance mode on or off", "admin",
+		admin.body(SetMaintenanceRequest{}), admin.json(AdminStatus{}))
+	admin.addAt(http.MethodPost, "/admin/drain", "Stop taking TTS requests and shut down once those in flight finish", "admin",
+		nil, admin.json(AdminStatus{}))
+
+	doc.Add(http.MethodGet, "/openapi.json", &openapi.Operation{
+		Summary: "This OpenAPI document",
+		Tags:    []string{"operations"},
+		Responses: map[string]openapi.Response{"200": {
+			Description: "OK",
+			Content:     map[string]openapi.MediaType{"application/json": {Schema: openapi.Schema{"type": "object"}}},
+		}},
+	})
+	doc.Add(http.MethodGet, "/metrics", &openapi.Operation{
+		Summary: "Prometheus metrics",
+		Tags:    []string{"operations"},
+		Responses: map[string]openapi.Response{"200": {
+			Description: "Prometheus text exposition format",
+			Content:     map[string]openapi.MediaType{"text/plain": {Schema: openapi.Schema{"type": "string"}}},
+		}},
+	})
+
+	return doc
+}
+
+// specBuilder adds operations for one API version.
+type specBuilder struct {
+	doc     *openapi.Document
+	version int
+}
+
+func (b specBuilder) addCommon() {
+	b.add(http.MethodGet, "/health", "Health check", "health", nil, b.json(HealthResponse{}))
+	b.add(http.MethodPost, "/health", "Health check", "health", nil, b.json(HealthResponse{}))
+	b.add(http.MethodGet, "/stats", "Backend runtime statistics", "health", nil, b.json(StatsResponse{}))
+
+	b.add(http.MethodPost, "/tts", "Synthesize speech", "tts", b.body(schema.ServeTTSRequest{}), openapi.Response{
+		Description: "Audio in the requested format; chunked WAV when streaming",
+		Content: map[string]openapi.MediaType{
+			"audio/wav":          {Schema: openapi.Schema{"type": "string", "format": "binary"}},
+			"audio/mpeg":         {Schema: openapi.Schema{"type": "string", "format": "binary"}},
+			"audio/pcm":          {Schema: openapi.Schema{"type": "string", "format": "binary"}},
+			"audio/ogg":          {Schema: openapi.Schema{"type": "string", "format": "binary"}},
+			"audio/flac":         {Schema: openapi.Schema{"type": "string", "format": "binary"}},
+			"audio/basic":        {Schema: openapi.Schema{"type": "string", "format": "binary"}},
+			"audio/x-alaw-basic": {Schema: openapi.Schema{"type": "string", "format": "binary"}},
+		},
+	})
+	b.add(http.MethodPost, "/tts/plan", "Preview text normalization and chunking", "tts",
+		b.body(schema.ServeTTSRequest{}), b.json(TTSPlanResponse{}))
+	b.add(http.MethodPost, "/tts/estimate", "Estimate tokens and duration", "tts",
+		b.body(schema.ServeTTSRequest{}), b.json(TTSEstimateResponse{}))
+	b.add(http.MethodPost, "/tts/jobs", "Queue speech synthesis as a job", "tts",
+		b.body(schema.ServeTTSRequest{}), b.json(JobResponse{}))
+	b.add(http.MethodGet, "/jobs/{id}", "Get a TTS job", "tts", nil, b.json(JobResponse{}))
+	b.add(http.MethodGet, "/jobs/{id}/result", "Download the audio of a done TTS job", "tts", nil, openapi.Response{
+		Description: "Audio in the requested format",
+		Content: map[string]openapi.MediaType{
+			"audio/wav":          {Schema: openapi.Schema{"type": "string", "format": "binary"}},
+			"audio/mpeg":         {Schema: openapi.Schema{"type": "string", "format": "binary"}},
+			"audio/pcm":          {Schema: openapi.Schema{"type": "string", "format": "binary"}},
+			"audio/ogg":          {Schema: openapi.Schema{"type": "string", "format": "binary"}},
+			"audio/flac":         {Schema: openapi.Schema{"type": "string", "format": "binary"}},
+			"audio/basic":        {Schema: openapi.Schema{"type": "string", "format": "binary"}},
+			"audio/x-alaw-basic": {Schema: openapi.Schema{"type": "string", "format": "binary"}},
+		},
+	})
+
+	b.add(http.MethodPost, "/vqgan/encode", "Encode audio to VQGAN tokens", "vqgan",
+		b.body(schema.ServeVQGANEncodeRequest{}), b.msgpack(schema.ServeVQGANEncodeResponse{}))
+	b.add(http.MethodPost, "/vqgan/decode", "Decode VQGAN tokens to audio", "vqgan",
+		b.body(schema.ServeVQGANDecodeRequest{}), b.msgpack(schema.ServeVQGANDecodeResponse{}))
+
+	b.add(http.MethodDelete, "/references/{id}", "Delete a reference voice", "references", nil, b.json(schema.DeleteReferenceResponse{}))
+	b.add(http.MethodDelete, "/references", "Delete reference voices by ID or prefix", "references",
+		b.body(BulkDeleteRequest{}), b.json(BulkDeleteResponse{}))
+	b.add(http.MethodPost, "/references/delete", "Delete reference voices by ID or prefix", "references",
+		b.body(BulkDeleteRequest{}), b.json(BulkDeleteResponse{}))
+	b.add(http.MethodGet, "/references/aliases", "List reference aliases", "references", nil, b.json(ListAliasesResponse{}))
+	b.add(http.MethodPost, "/references/aliases", "Create or repoint an alias", "references",
+		b.body(ReferenceAlias{}), b.json(AliasResponse{}))
+	b.add(http.MethodDelete, "/references/aliases/{alias}", "Delete an alias", "references", nil, b.json(AliasResponse{}))
+	b.add(http.MethodGet, "/references/{id}", "Get a reference voice", "references", nil, b.json(ReferenceDetail{}))
+	b.add(http.MethodPatch, "/references/{id}", "Update the transcript or metadata of a reference", "references",
+		b.body(UpdateReferenceRequest{}), b.json(ReferenceDetail{}))
+	b.add(http.MethodPut, "/references/{id}/lock", "Lock a reference against deletion", "references", nil, b.json(LockResponse{}))
+	b.add(http.MethodDelete, "/references/{id}/lock", "Unlock a reference", "references", nil, b.json(LockResponse{}))
+	b.add(http.MethodGet, "/voices", "List the voice catalog", "voices", nil, b.json(ListVoicesResponse{}))
+	b.add(http.MethodGet, "/voices/{name}", "Get a voice of the catalog", "voices", nil, b.json(VoiceInfo{}))
+	b.add(http.MethodPut, "/voices/{name}", "Create or replace a voice", "voices",
+		b.body(schema.Voice{}), b.json(VoiceInfo{}))
+	b.add(http.MethodDelete, "/voices/{name}", "Delete a voice", "voices", nil, b.json(VoiceResponse{}))
+	b.add(http.MethodGet, "/presets", "List parameter presets and the default voice", "voices", nil, b.json(ListPresetsResponse{}))
+	b.add(http.MethodGet, "/usage", "Usage and usage quotas of the caller's key", "operations", nil, b.json(UsageResponse{}))
+	b.add(http.MethodGet, "/references/export", "Export references as a tar.gz archive", "references", nil, openapi.Response{
+		Description: "manifest.json, then the audio of each reference",
+		Content:     map[string]openapi.MediaType{"application/gzip": {Schema: openapi.Schema{"type": "string", "format": "binary"}}},
+	})
+	b.add(http.MethodPost, "/references/import", "Import references from an export archive", "references",
+		&openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{
+			"application/gzip": {Schema: openapi.Schema{"type": "string", "format": "binary"}},
+		}}, b.json(ImportReferencesResponse{}))
+	b.add(http.MethodGet, "/references/{id}/audio", "Download the audio of a reference", "references", nil, openapi.Response{
+		Description: "The reference audio as added, or as transcoded to WAV",
+		Content: map[string]openapi.MediaType{
+			"audio/wav":  {Schema: openapi.Schema{"type": "string", "format": "binary"}},
+			"audio/mpeg": {Schema: openapi.Schema{"type": "string", "format": "binary"}},
+			"audio/ogg":  {Schema: openapi.Schema{"type": "string", "format": "binary"}},
+			"audio/flac": {Schema: openapi.Schema{"type": "string", "format": "binary"}},
+		},
+	})
+
+	b.add(http.MethodPost, "/references/uploads", "Start a resumable reference upload", "references",
+		b.body(CreateUploadRequest{}), b.json(UploadResponse{}))
+	b.add(http.MethodHead, "/references/uploads/{upload_id}", "Get the offset of a resumable upload", "references",
+		nil, openapi.Response{Description: "Upload-Offset and Upload-Length headers"})
+	b.add(http.MethodGet, "/references/uploads/{upload_id}", "Get a resumable upload", "references", nil, b.json(UploadResponse{}))
+	b.add(http.MethodPatch, "/references/uploads/{upload_id}", "Append a chunk at Upload-Offset", "references",
+		&openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{
+			"application/offset+octet-stream": {Schema: openapi.Schema{"type": "string", "format": "binary"}},
+		}}, openapi.Response{Description: "Chunk stored; Upload-Offset holds the new offset"})
+	b.add(http.MethodPost, "/references/uploads/{upload_id}/complete", "Add the reference from a complete upload", "references",
+		nil, b.json(AddReferenceResult{}))
+	b.add(http.MethodDelete, "/references/uploads/{upload_id}", "Abandon a resumable upload", "references",
+		nil, openapi.Response{Description: "Upload removed"})
+}
+
+// add registers an operation under the builder's version prefix.
+func (b specBuilder) add(method, path, summary, tag string, body *openapi.RequestBody, ok openapi.Response) {
+	prefix := "/v1"
+	if b.version == APIVersion2 {
+		prefix = "/v2"
+	}
+	b.addAt(method, prefix+path, summary, tag, body, ok)
+}
+
+// addAt registers an operation at an absolute path.
+func (b specBuilder) addAt(method, path, summary, tag string, body *openapi.RequestBody, ok openapi.Response) {
+	op := b.op(summary, tag, body, ok)
+	for _, name := range pathParams(path) {
+		op.Parameters = append(op.Parameters, openapi.Parameter{
+			Name: name, In: "path", Required: true, Schema: openapi.Schema{"type": "string"},
+		})
+	}
+	b.doc.Add(method, path, op)
+}
+
+func (b specBuilder) op(summary, tag string, body *openapi.RequestBody, ok openapi.Response) *openapi.Operation {
+	errSchema := b.doc.SchemaOf(schema.ErrorResponse{})
+	if b.version == APIVersion2 {
+		errSchema = b.doc.SchemaOf(V2ErrorResponse{})
+	}
+	return &openapi.Operation{
+		Summary:     summary,
+		Tags:        []string{tag},
+		RequestBody: body,
+		Responses: map[string]openapi.Response{
+			"200": ok,
+			"default": {
+				Description: "Error",
+				Content:     map[string]openapi.MediaType{"application/json": {Schema: errSchema}},
+			},
+		},
+	}
+}
+
+// body describes a request accepted as JSON or MessagePack.
+func (b specBuilder) body(v interface{}) *openapi.RequestBody {
+	s := b.doc.SchemaOf(v)
+	return &openapi.RequestBody{
+		Required: true,
+		Content: map[string]openapi.MediaType{
+			"application/json":    {Schema: s},
+			"application/msgpack": {Schema: s},
+		},
+	}
+}
+
+// json describes a JSON response, enveloped on /v2.
+func (b specBuilder) json(v interface{}) openapi.Response {
+	s := b.doc.SchemaOf(v)
+	if b.version == APIVersion2 {
+		s = openapi.Schema{"type": "object", "properties": openapi.Schema{"data": s}}
+	}
+	return openapi.Response{Description: "OK", Content: map[string]openapi.MediaType{"application/json": {Schema: s}}}
+}
+
+func (b specBuilder) msgpack(v interface{}) openapi.Response {
+	return openapi.Response{Description: "OK", Content: map[string]openapi.MediaType{"application/msgpack": {Schema: b.doc.SchemaOf(v)}}}
+}
+
+// pathParams returns the {name} segments of a route pattern.
+func pathParams(path string) []string {
+	var names []string
+	for _, seg := range strings.Split(path, "/") {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			names = append(names, seg[1:len(seg)-1])
+		}
+	}
+	return names
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/queue"
+	"github.com/fish-speech-go/fish-speech-go/internal/refaudio"
+	"github.com/fish-speech-go/fish-speech-go/internal/upload"
+)
+
+func TestOpenAPI_CoversAllRoutes(t *testing.T) {
+	doc := BuildOpenAPI()
+	uploads, err := upload.Open(t.TempDir(), time.Hour)
+	require.NoError(t, err)
+	archive, err := refaudio.Open(t.TempDir())
+	require.NoError(t, err)
+	jobs := queue.New(queue.Config{})
+	defer jobs.Close()
+	router := NewRouter(testConfig(), &mockBackend{}, testLogger(), WithUploadStore(uploads), WithReferenceAudio(archive), WithJobs(jobs))
+
+	routes := 0
+	err = chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routes++
+		route = strings.TrimSuffix(route, "/")
+		item, ok := doc.Paths[route]
+		if assert.True(t, ok, "route %s missing from spec", route) {
+			assert.Contains(t, item, strings.ToLower(method), "%s %s missing from spec", method, route)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	operations := 0
+	for _, item := range doc.Paths {
+		operations += len(item)
+	}
+	assert.Equal(t, routes, operations, "spec documents routes that do not exist")
+}
+
+func TestOpenAPI_Served(t *testing.T) {
+	router := NewRouter(testConfig(), &mockBackend{}, testLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var doc struct {
+		OpenAPI    string `json:"openapi"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+
+	tts := doc.Components.Schemas["ServeTTSRequest"].Properties
+	for _, field := range []string{"text", "reference_id", "streaming", "sample_rate", "gain_db"} {
+		assert.Contains(t, tts, field)
+	}
+}
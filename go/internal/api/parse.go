@@ -2,7 +2,6 @@ package api
 
 import (
 	"encoding/json"
-	"io"
 	"net/http"
 	"strings"
 
@@ -32,11 +31,9 @@ func ParseRequestBody(r *http.Request, v interface{}) error {
 
 	switch {
 	case strings.HasPrefix(contentType, "application/msgpack"):
-		body, err := io.ReadAll(r.Body)
-		if err != nil {
-			return NewParseError(http.StatusBadRequest, "Failed to read request body")
-		}
-		if err := msgpack.Unmarshal(body, v); err != nil {
+		// Decode as the body is read rather than buffering it first; audio
+		// payloads can be large.
+		if err := msgpack.NewDecoder(r.Body).Decode(v); err != nil {
 			return NewParseError(http.StatusBadRequest, "Invalid MessagePack body")
 		}
 		return nil
@@ -59,11 +56,22 @@ func ParseRequestBody(r *http.Request, v interface{}) error {
 
 // ParseTTSRequest parses and validates a ServeTTSRequest from the HTTP request.
 func ParseTTSRequest(r *http.Request) (*schema.ServeTTSRequest, error) {
+	return parseTTSRequestWith(r, nil)
+}
+
+// parseTTSRequestWith is ParseTTSRequest calling prepare, when set, on the
+// request as sent, before defaults are applied and it is validated.
+func parseTTSRequestWith(r *http.Request, prepare func(*schema.ServeTTSRequest) error) (*schema.ServeTTSRequest, error) {
 	var req schema.ServeTTSRequest
 
 	if err := ParseRequestBody(r, &req); err != nil {
 		return nil, err
 	}
+	if prepare != nil {
+		if err := prepare(&req); err != nil {
+			return nil, err
+		}
+	}
 
 	if err := req.Validate(0); err != nil {
 		return nil, NewParseError(http.StatusBadRequest, err.Error())
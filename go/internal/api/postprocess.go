@@ -0,0 +1,84 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/audio"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+const (
+	minOutputSampleRate = 8000
+	maxOutputSampleRate = 192000
+	maxGainDB           = 12
+	minLoudnessLUFS     = -50
+	maxLoudnessLUFS     = -5
+	minSpeed            = 0.5
+	maxSpeed            = 2.0
+)
+
+// validatePostProcessing checks the Go-side post-processing options of a TTS request.
+func validatePostProcessing(req *schema.ServeTTSRequest) error {
+	if req.SampleRate != 0 && (req.SampleRate < minOutputSampleRate || req.SampleRate > maxOutputSampleRate) {
+		return fmt.Errorf("sample_rate must be between %d and %d", minOutputSampleRate, maxOutputSampleRate)
+	}
+
+	switch req.Channels {
+	case "", "mono", "stereo":
+	default:
+		return errors.New("channels must be one of: mono, stereo")
+	}
+
+	if req.Format == audio.FormatULaw || req.Format == audio.FormatALaw {
+		if (req.SampleRate != 0 && req.SampleRate != audio.G711Rate) || req.Channels == "stereo" {
+			return fmt.Errorf("%s output is %d Hz mono", req.Format, audio.G711Rate)
+		}
+	}
+
+	if math.IsNaN(req.GainDB) || math.Abs(req.GainDB) > maxGainDB {
+		return fmt.Errorf("gain_db must be between -%d and %d", maxGainDB, maxGainDB)
+	}
+
+	if lufs := req.LoudnessTargetLUFS; lufs != 0 {
+		if math.IsNaN(lufs) || lufs < minLoudnessLUFS || lufs > maxLoudnessLUFS {
+			return fmt.Errorf("loudness_target_lufs must be between %d and %d", minLoudnessLUFS, maxLoudnessLUFS)
+		}
+		if req.GainDB != 0 {
+			return errors.New("gain_db and loudness_target_lufs cannot be combined")
+		}
+	}
+
+	if req.Speed != 0 && !(req.Speed >= minSpeed && req.Speed <= maxSpeed) {
+		return fmt.Errorf("speed must be between %g and %g", minSpeed, maxSpeed)
+	}
+
+	if !req.HasPostProcessing() {
+		return nil
+	}
+	if req.Streaming && !processOptions(req).Streamable() {
+		return errors.New("gain_db, loudness_target_lufs and speed need the whole audio and are not supported with streaming")
+	}
+	if req.Format != "wav" && !audio.CanEncode(req.Format) {
+		return errors.New("Audio post-processing only supports WAV format")
+	}
+	return nil
+}
+
+// processOptions maps the request's post-processing fields to audio pipeline options.
+func processOptions(req *schema.ServeTTSRequest) audio.ProcessOptions {
+	opts := audio.ProcessOptions{
+		SampleRate:   req.SampleRate,
+		GainDB:       req.GainDB,
+		LoudnessLUFS: req.LoudnessTargetLUFS,
+		Speed:        req.Speed,
+	}
+	switch req.Channels {
+	case "mono":
+		opts.Channels = 1
+	case "stereo":
+		opts.Channels = 2
+	}
+	return opts
+}
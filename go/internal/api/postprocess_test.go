@@ -0,0 +1,149 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/audio"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func postTTS(t *testing.T, h *Handler, req schema.ServeTTSRequest) *httptest.ResponseRecorder {
+	t.Helper()
+
+	body, err := json.Marshal(req)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/tts", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.HandleTTS(w, r)
+	return w
+}
+
+func TestTTS_SampleRate(t *testing.T) {
+	wav := audio.EncodeWAV(&audio.PCM{SampleRate: 44100, Channels: 1, Samples: make([]float32, 4410)})
+	mock := &mockBackend{ttsResponse: wav}
+	h := NewHandler(mock, testConfig(), testLogger())
+
+	w := postTTS(t, h, schema.ServeTTSRequest{Text: "Hello", SampleRate: 16000})
+
+	require.Equal(t, http.StatusOK, w.Code)
+	header, err := audio.ParseWAVHeader(w.Body.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, 16000, header.SampleRate)
+	assert.Equal(t, ContentSHA256(w.Body.Bytes()), w.Header().Get("X-Content-SHA256"))
+}
+
+func TestTTS_Channels(t *testing.T) {
+	wav := audio.EncodeWAV(&audio.PCM{SampleRate: 44100, Channels: 1, Samples: make([]float32, 441)})
+	mock := &mockBackend{ttsResponse: wav}
+	h := NewHandler(mock, testConfig(), testLogger())
+
+	w := postTTS(t, h, schema.ServeTTSRequest{Text: "Hello", Channels: "stereo"})
+
+	require.Equal(t, http.StatusOK, w.Code)
+	header, err := audio.ParseWAVHeader(w.Body.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, 2, header.Channels)
+	assert.Equal(t, 44100, header.SampleRate)
+}
+
+func TestTTS_LoudnessTarget(t *testing.T) {
+	pcm := &audio.PCM{SampleRate: 24000, Channels: 1, Samples: make([]float32, 24000)}
+	for i := range pcm.Samples {
+		pcm.Samples[i] = float32(0.05 * math.Sin(2*math.Pi*1000*float64(i)/24000))
+	}
+	mock := &mockBackend{ttsResponse: audio.EncodeWAV(pcm)}
+	h := NewHandler(mock, testConfig(), testLogger())
+
+	w := postTTS(t, h, schema.ServeTTSRequest{Text: "Hello", LoudnessTargetLUFS: -16})
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	out, err := audio.DecodeWAV(w.Body.Bytes())
+	require.NoError(t, err)
+	assert.InDelta(t, -16, audio.Loudness(out), 0.1)
+}
+
+func TestTTS_Speed(t *testing.T) {
+	wav := audio.EncodeWAV(&audio.PCM{SampleRate: 24000, Channels: 1, Samples: make([]float32, 24000)})
+	mock := &mockBackend{ttsResponse: wav}
+	h := NewHandler(mock, testConfig(), testLogger())
+
+	w := postTTS(t, h, schema.ServeTTSRequest{Text: "Hello", Speed: 1.25})
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	out, err := audio.DecodeWAV(w.Body.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, 800*time.Millisecond, out.Duration())
+}
+
+func TestTTS_PCMPostProcessing(t *testing.T) {
+	wav := audio.EncodeWAV(&audio.PCM{SampleRate: 24000, Channels: 1, Samples: make([]float32, 2400)})
+	mock := &mockBackend{ttsResponse: wav}
+	h := NewHandler(mock, testConfig(), testLogger())
+
+	w := postTTS(t, h, schema.ServeTTSRequest{Text: "Hello", Format: "pcm", SampleRate: 8000})
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "wav", mock.lastTTSReq.Format, "the backend's PCM has no rate to convert from")
+	assert.Equal(t, "audio/pcm", w.Header().Get("Content-Type"))
+	assert.Len(t, w.Body.Bytes(), 800*2)
+}
+
+func TestStreamingTTS_PostProcessing(t *testing.T) {
+	pcm := &audio.PCM{SampleRate: 24000, Channels: 1, Samples: make([]float32, 2400)}
+	stream := append(audio.WAVHeader16(24000, 1, 0), audio.EncodeWAV(pcm)[44:]...)
+	h := NewHandler(&mockBackend{ttsResponse: stream}, testConfig(), testLogger())
+
+	w := postTTS(t, h, schema.ServeTTSRequest{Text: "Hello", Streaming: true, SampleRate: 8000})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	header, err := audio.ParseWAVHeader(w.Body.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, 8000, header.SampleRate)
+	assert.Equal(t, 1, header.Channels)
+	assert.Len(t, w.Body.Bytes()[header.DataOffset:], 800*2)
+
+	w = postTTS(t, h, schema.ServeTTSRequest{Text: "Hello", Streaming: true, Format: "pcm", SampleRate: 48000, Channels: "stereo"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "audio/pcm", w.Header().Get("Content-Type"))
+	assert.Len(t, w.Body.Bytes(), 4800*2*2)
+}
+
+func TestTTS_PostProcessingValidation(t *testing.T) {
+	testCases := []struct {
+		name string
+		req  schema.ServeTTSRequest
+	}{
+		{name: "too low", req: schema.ServeTTSRequest{Text: "Hello", SampleRate: 4000}},
+		{name: "too high", req: schema.ServeTTSRequest{Text: "Hello", SampleRate: 384000}},
+		{name: "streaming gain", req: schema.ServeTTSRequest{Text: "Hello", GainDB: 3, Streaming: true}},
+		{name: "mp3", req: schema.ServeTTSRequest{Text: "Hello", SampleRate: 16000, Format: "mp3"}},
+		{name: "unknown channels", req: schema.ServeTTSRequest{Text: "Hello", Channels: "quad"}},
+		{name: "gain too high", req: schema.ServeTTSRequest{Text: "Hello", GainDB: 18}},
+		{name: "gain too low", req: schema.ServeTTSRequest{Text: "Hello", GainDB: -12.5}},
+		{name: "loudness too low", req: schema.ServeTTSRequest{Text: "Hello", LoudnessTargetLUFS: -60}},
+		{name: "loudness too high", req: schema.ServeTTSRequest{Text: "Hello", LoudnessTargetLUFS: 0.5}},
+		{name: "loudness with gain", req: schema.ServeTTSRequest{Text: "Hello", LoudnessTargetLUFS: -16, GainDB: 3}},
+		{name: "streaming loudness", req: schema.ServeTTSRequest{Text: "Hello", LoudnessTargetLUFS: -16, Streaming: true}},
+		{name: "speed too slow", req: schema.ServeTTSRequest{Text: "Hello", Speed: 0.25}},
+		{name: "speed too fast", req: schema.ServeTTSRequest{Text: "Hello", Speed: 3}},
+		{name: "streaming speed", req: schema.ServeTTSRequest{Text: "Hello", Speed: 1.25, Streaming: true}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(&mockBackend{}, testConfig(), testLogger())
+			w := postTTS(t, h, tc.req)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+// ListPresetsResponse is returned by GET /v1/presets.
+type ListPresetsResponse struct {
+	DefaultVoice string          `json:"default_voice,omitempty"`
+	Presets      []schema.Preset `json:"presets"`
+}
+
+// ValidateTTSDefaults checks the default voice and presets of the server
+// configuration.
+func ValidateTTSDefaults(cfg config.TTSConfig) error {
+	if cfg.DefaultVoice != "" {
+		if err := validateReferenceID("tts.default_voice", cfg.DefaultVoice); err != nil {
+			return err
+		}
+	}
+	names := make(map[string]bool, len(cfg.Presets))
+	for i, c := range cfg.Presets {
+		p := presetFromConfig(c)
+		if err := validatePreset(&p); err != nil {
+			return fmt.Errorf("tts.presets[%d]: %w", i, err)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("tts.presets[%d]: duplicate name %q", i, p.Name)
+		}
+		names[p.Name] = true
+	}
+	return nil
+}
+
+func presetFromConfig(c config.PresetConfig) schema.Preset {
+	return schema.Preset{
+		Name:              c.Name,
+		Voice:             c.Voice,
+		ReferenceID:       c.ReferenceID,
+		Format:            c.Format,
+		Model:             c.Model,
+		ChunkLength:       c.ChunkLength,
+		MaxNewTokens:      c.MaxNewTokens,
+		TopP:              c.TopP,
+		RepetitionPenalty: c.RepetitionPenalty,
+		Temperature:       c.Temperature,
+		Seed:              c.Seed,
+		Speed:             c.Speed,
+	}
+}
+
+// validatePreset checks the name, voice and reference of a preset, and its
+// parameters against the rules of a TTS request.
+func validatePreset(p *schema.Preset) error {
+	if err := validateReferenceID("name", p.Name); err != nil {
+		return err
+	}
+	if p.Voice != "" && p.ReferenceID != "" {
+		return errors.New("voice and reference_id cannot both be set")
+	}
+	if p.Voice != "" {
+		if err := validateReferenceID("voice", p.Voice); err != nil {
+			return err
+		}
+	}
+	if p.ReferenceID != "" {
+		if err := validateReferenceID("reference_id", p.ReferenceID); err != nil {
+			return err
+		}
+	}
+	if p.MaxNewTokens < 0 {
+		return errors.New("max_new_tokens must not be negative")
+	}
+	req := schema.ServeTTSRequest{Text: p.Name}
+	p.Apply(&req)
+	if err := req.Validate(0); err != nil {
+		return err
+	}
+	if err := checkFormat(&req); err != nil {
+		return err
+	}
+	return validatePostProcessing(&req)
+}
+
+// preset returns the configured preset name.
+func (h *Handler) preset(name string) (schema.Preset, bool) {
+	for _, c := range h.config.TTS.Presets {
+		if c.Name == name {
+			return presetFromConfig(c), true
+		}
+	}
+	return schema.Preset{}, false
+}
+
+// prepareTTSRequest applies the server-side defaults of a TTS request before
+// it is validated: those of its preset, then those of its voice, which is the
+// default voice when it names neither a voice nor a reference. Parameters the
+// request sets itself always win.
+func (h *Handler) prepareTTSRequest(ctx context.Context, req *schema.ServeTTSRequest) error {
+	if req.Preset != "" {
+		p, ok := h.preset(req.Preset)
+		if !ok {
+			return NewParseError(http.StatusBadRequest, fmt.Sprintf("Unknown preset '%s'", req.Preset))
+		}
+		p.Apply(req)
+	}
+	if req.Voice == "" && req.ReferenceID == nil && len(req.References) == 0 {
+		req.Voice = h.config.TTS.DefaultVoice
+	}
+	if err := h.applyVoice(ctx, req); err != nil {
+		return err
+	}
+	h.useReferenceMemoryCache(req)
+	return nil
+}
+
+// useReferenceMemoryCache asks the backend to keep its encoding of the
+// reference named by req in memory, so that later requests for it skip
+// encoding the reference again. A use_memory_cache sent by the client is
+// left alone.
+func (h *Handler) useReferenceMemoryCache(req *schema.ServeTTSRequest) {
+	if h.config.References.UseMemoryCache && req.ReferenceID != nil && req.UseMemoryCache == "" {
+		req.UseMemoryCache = "on"
+	}
+}
+
+// HandleListPresets lists the configured presets and the default voice.
+func (h *Handler) HandleListPresets(w http.ResponseWriter, r *http.Request) {
+	presets := make([]schema.Preset, 0, len(h.config.TTS.Presets))
+	for _, c := range h.config.TTS.Presets {
+		presets = append(presets, presetFromConfig(c))
+	}
+	WriteJSON(w, http.StatusOK, ListPresetsResponse{DefaultVoice: h.config.TTS.DefaultVoice, Presets: presets})
+}
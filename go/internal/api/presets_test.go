@@ -0,0 +1,118 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func TestPresets(t *testing.T) {
+	mock := &mockBackend{
+		listRefResp: &schema.ListReferencesResponse{Success: true, ReferenceIDs: []string{"narrator-v1", "host"}},
+		ttsResponse: silentWAV(time.Second),
+	}
+	cfg := testConfig()
+	cfg.References.Voices = []config.VoiceConfig{{Name: "narrator", ReferenceID: "narrator-v1", Temperature: 0.5}}
+	cfg.TTS = config.TTSConfig{
+		DefaultVoice: "narrator",
+		Presets: []config.PresetConfig{
+			{Name: "podcast", ReferenceID: "host", Temperature: 0.9, TopP: 0.6},
+		},
+	}
+	router := NewRouter(cfg, mock, testLogger())
+
+	tts := func(body string) *schema.ServeTTSRequest {
+		req := httptest.NewRequest(http.MethodPost, "/v1/tts", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return mock.lastTTSReq
+	}
+
+	req := tts(`{"text": "Hello"}`)
+	require.NotNil(t, req.ReferenceID)
+	assert.Equal(t, "narrator-v1", *req.ReferenceID, "the default voice is used")
+	assert.Equal(t, 0.5, req.Temperature)
+
+	req = tts(`{"text": "Hello", "preset": "podcast", "top_p": 0.8}`)
+	assert.Equal(t, "host", *req.ReferenceID)
+	assert.Equal(t, 0.9, req.Temperature, "unset parameters come from the preset")
+	assert.Equal(t, 0.8, req.TopP, "parameters the request sets are kept")
+
+	req = tts(`{"text": "Hello", "preset": "podcast", "reference_id": "narrator-v1"}`)
+	assert.Equal(t, "narrator-v1", *req.ReferenceID, "the request's reference wins over the preset's")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/presets", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var list ListPresetsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, "narrator", list.DefaultVoice)
+	require.Len(t, list.Presets, 1)
+	assert.Equal(t, "podcast", list.Presets[0].Name)
+}
+
+func TestPresets_Unknown(t *testing.T) {
+	router := NewRouter(testConfig(), &mockBackend{}, testLogger())
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/tts", bytes.NewBufferString(`{"text": "Hello", "preset": "missing"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Unknown preset")
+}
+
+func TestValidateTTSDefaults(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.TTSConfig
+		err  string
+	}{
+		{"valid", config.TTSConfig{DefaultVoice: "narrator", Presets: []config.PresetConfig{{Name: "calm", Format: "mp3"}}}, ""},
+		{"bad default voice", config.TTSConfig{DefaultVoice: "../x"}, "tts.default_voice"},
+		{"duplicate", config.TTSConfig{Presets: []config.PresetConfig{{Name: "calm"}, {Name: "calm"}}}, "tts.presets[1]: duplicate"},
+		{"bad format", config.TTSConfig{Presets: []config.PresetConfig{{Name: "calm", Format: "xyz"}}}, "tts.presets[0]"},
+		{"bad temperature", config.TTSConfig{Presets: []config.PresetConfig{{Name: "calm", Temperature: 3}}}, "tts.presets[0]: temperature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTTSDefaults(tt.cfg)
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestTTS_ReferenceMemoryCache(t *testing.T) {
+	mock := &mockBackend{ttsResponse: silentWAV(time.Second)}
+	cfg := testConfig()
+	cfg.References.UseMemoryCache = true
+	router := NewRouter(cfg, mock, testLogger())
+
+	tts := func(body string) string {
+		req := httptest.NewRequest(http.MethodPost, "/v1/tts", bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return mock.lastTTSReq.UseMemoryCache
+	}
+	assert.Equal(t, "on", tts(`{"text": "Hello", "reference_id": "voice"}`), "the backend keeps its encoding of the reference")
+	assert.Equal(t, "off", tts(`{"text": "Hello", "reference_id": "voice", "use_memory_cache": "off"}`), "the client's choice is kept")
+	assert.Equal(t, "off", tts(`{"text": "Hello"}`))
+}
@@ -0,0 +1,66 @@
+package api
+
+import "context"
+
+// RoleAdmin is the key role allowed to perform privileged operations such as
+// force-deleting locked references.
+const RoleAdmin = "admin"
+
+// RoleInteractive is the key role allowed to send X-Priority: interactive
+// requests, which are granted backend slots ahead of other traffic.
+const RoleInteractive = "interactive"
+
+type principalKey struct{}
+
+// Principal identifies the caller authenticated by KeyAuthMiddleware.
+type Principal struct {
+	// Namespace scopes the references the caller can see. Empty means unscoped.
+	Namespace string
+	// Role grants additional privileges; see RoleAdmin.
+	Role string
+	// KeyPrefix identifies the key the caller authenticated with in logs. It
+	// is empty for configured hashes without a prefix.
+	KeyPrefix string
+	// Username is set for callers authenticated with HTTP Basic credentials, an
+	// introspected OAuth2 token or a request signing key.
+	Username string
+
+	// policy holds the key's rate limit, quota, routes and priority tier.
+	policy *keyPolicy
+}
+
+// IsAdmin reports whether the principal holds the admin role.
+func (p *Principal) IsAdmin() bool {
+	return p != nil && p.Role == RoleAdmin
+}
+
+// account names the principal in usage metering and fair queueing: the
+// key's configured name, its prefix, or the username.
+func (p *Principal) account() string {
+	switch {
+	case p.policy != nil:
+		return p.policy.name
+	case p.KeyPrefix != "":
+		return p.KeyPrefix
+	default:
+		return p.Username
+	}
+}
+
+// WithPrincipal returns a copy of ctx carrying the given principal.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal attached to ctx, or nil when auth is disabled.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// isAdminRequest reports whether the request may perform admin operations:
+// only callers authenticated with the admin role may. When authentication is
+// disabled nobody is an admin.
+func isAdminRequest(ctx context.Context) bool {
+	return PrincipalFromContext(ctx).IsAdmin()
+}
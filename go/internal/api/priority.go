@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/limiter"
+	"github.com/fish-speech-go/fish-speech-go/internal/queue"
+)
+
+// Request priorities accepted in the X-Priority header.
+const (
+	PriorityInteractive = "interactive"
+	PriorityNormal      = "normal"
+	PriorityBulk        = "bulk"
+)
+
+// priorityRank orders priorities from lowest to highest.
+var priorityRank = map[string]int{PriorityBulk: 0, PriorityNormal: 1, PriorityInteractive: 2}
+
+// requestPriority returns the priority declared in the X-Priority header,
+// defaulting to the caller's key tier or else normal.
+func requestPriority(r *http.Request) (string, error) {
+	switch p := r.Header.Get("X-Priority"); p {
+	case "":
+		if tier := keyTier(r.Context()); tier != "" {
+			return tier, nil
+		}
+		return PriorityNormal, nil
+	case PriorityInteractive, PriorityNormal, PriorityBulk:
+		return p, nil
+	default:
+		return "", fmt.Errorf("X-Priority must be one of: %s, %s, %s", PriorityInteractive, PriorityNormal, PriorityBulk)
+	}
+}
+
+// errPriorityNotAllowed is returned when the caller's role may not use the
+// requested priority.
+var errPriorityNotAllowed = errors.New("X-Priority interactive requires an admin or interactive key")
+
+// errPriorityAboveTier is returned when a key requests a priority above its tier.
+var errPriorityAboveTier = errors.New("X-Priority exceeds this key's priority tier")
+
+// authorizePriority checks the priority against the caller's role and tier.
+// Interactive priority is reserved for admin and interactive keys and keys of
+// the interactive tier; everyone may use normal or bulk unless their key's tier
+// is lower. When authentication is disabled nobody holds those keys, so only
+// normal and bulk are allowed.
+func authorizePriority(ctx context.Context, priority string) error {
+	p := PrincipalFromContext(ctx)
+	if p.IsAdmin() {
+		return nil
+	}
+	if tier := keyTier(ctx); tier != "" {
+		if priorityRank[priority] > priorityRank[tier] {
+			return errPriorityAboveTier
+		}
+		return nil
+	}
+	if priority == PriorityInteractive && (p == nil || p.Role != RoleInteractive) {
+		return errPriorityNotAllowed
+	}
+	return nil
+}
+
+// keyTier returns the priority tier of the caller's key, if it has one.
+func keyTier(ctx context.Context) string {
+	if p := PrincipalFromContext(ctx); p != nil && p.policy != nil {
+		return p.policy.priority
+	}
+	return ""
+}
+
+// limiterFlow identifies the caller's key for fair queueing between keys.
+// Callers without a key share one flow.
+func limiterFlow(ctx context.Context) limiter.Flow {
+	p := PrincipalFromContext(ctx)
+	switch {
+	case p == nil:
+		return limiter.Flow{}
+	case p.policy != nil:
+		return limiter.Flow{Key: p.policy.name, Weight: p.policy.weight}
+	default:
+		return limiter.Flow{Key: p.account()}
+	}
+}
+
+// limiterPriority maps a request priority to a limiter priority.
+func limiterPriority(priority string) limiter.Priority {
+	switch priority {
+	case PriorityInteractive:
+		return limiter.PriorityHigh
+	case PriorityBulk:
+		return limiter.PriorityLow
+	default:
+		return limiter.PriorityNormal
+	}
+}
+
+// queuePriority maps a request priority to a job queue priority.
+func queuePriority(priority string) queue.Priority {
+	switch priority {
+	case PriorityInteractive:
+		return queue.PriorityHigh
+	case PriorityBulk:
+		return queue.PriorityLow
+	default:
+		return queue.PriorityNormal
+	}
+}
+
+// jobPriority is the request priority a job was submitted with.
+func jobPriority(priority queue.Priority) string {
+	switch priority {
+	case queue.PriorityHigh:
+		return PriorityInteractive
+	case queue.PriorityLow:
+		return PriorityBulk
+	default:
+		return PriorityNormal
+	}
+}
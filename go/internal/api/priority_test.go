@@ -0,0 +1,55 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+	"github.com/fish-speech-go/fish-speech-go/internal/limiter"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func TestTTS_PriorityAuthorization(t *testing.T) {
+	cfg := testConfig()
+	cfg.Auth = config.AuthConfig{Keys: []config.APIKeyConfig{
+		{Key: "user-key"},
+		{Key: "interactive-key", Role: RoleInteractive},
+	}}
+	router := NewRouter(cfg, &mockBackend{ttsResponse: []byte("audio")}, testLogger())
+
+	do := func(key, priority string) int {
+		body, _ := json.Marshal(schema.ServeTTSRequest{Text: "Hello"})
+		req := httptest.NewRequest(http.MethodPost, "/v1/tts", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+key)
+		req.Header.Set("X-Priority", priority)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, do("user-key", PriorityBulk))
+	assert.Equal(t, http.StatusForbidden, do("user-key", PriorityInteractive))
+	assert.Equal(t, http.StatusOK, do("interactive-key", PriorityInteractive))
+	assert.Equal(t, http.StatusBadRequest, do("user-key", "urgent"))
+
+	// Without auth nobody holds a key allowed interactive priority.
+	assert.ErrorIs(t, authorizePriority(context.Background(), PriorityInteractive), errPriorityNotAllowed)
+	assert.NoError(t, authorizePriority(context.Background(), PriorityBulk))
+}
+
+func TestLimiterFlow(t *testing.T) {
+	assert.Equal(t, limiter.Flow{}, limiterFlow(context.Background()))
+
+	p := &Principal{KeyPrefix: "fsk_abcd"}
+	assert.Equal(t, limiter.Flow{Key: "fsk_abcd"}, limiterFlow(WithPrincipal(context.Background(), p)))
+
+	p.policy = newKeyPolicy("team-a", config.APIKeyConfig{Weight: 3}, &keyUsage{})
+	assert.Equal(t, limiter.Flow{Key: "team-a", Weight: 3}, limiterFlow(WithPrincipal(context.Background(), p)))
+}
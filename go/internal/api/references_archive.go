@@ -0,0 +1,303 @@
+package api
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/audio"
+	"github.com/fish-speech-go/fish-speech-go/internal/refaudio"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+const (
+	// referenceArchiveVersion is the manifest version written by exports and
+	// accepted by imports.
+	referenceArchiveVersion = 1
+	// referenceManifestName is the first file of a reference archive.
+	referenceManifestName = "manifest.json"
+	// maxManifestBytes bounds the manifest read by an import.
+	maxManifestBytes = 64 << 20
+)
+
+// ReferenceManifest describes the references in an export archive. The
+// archive is a gzip-compressed tar file holding the manifest, then the audio
+// of each reference.
+type ReferenceManifest struct {
+	Version    int                      `json:"version"`
+	ExportedAt time.Time                `json:"exported_at"`
+	References []ReferenceManifestEntry `json:"references"`
+	// Missing lists the references left out because fish-server has no copy
+	// of their audio, such as those added before it kept one.
+	Missing []string `json:"missing,omitempty"`
+}
+
+// ReferenceManifestEntry describes one reference of an archive.
+type ReferenceManifestEntry struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+	// Audio is the path of the reference audio in the archive.
+	Audio string `json:"audio"`
+	schema.ReferenceMetadata
+}
+
+// ImportSkip reports a reference of an archive that was not imported.
+type ImportSkip struct {
+	ReferenceID string `json:"reference_id"`
+	Reason      string `json:"reason"`
+}
+
+// ImportReferencesResponse lists the references added from an archive.
+type ImportReferencesResponse struct {
+	Success  bool         `json:"success"`
+	Message  string       `json:"message"`
+	Imported []string     `json:"imported"`
+	Skipped  []ImportSkip `json:"skipped"`
+}
+
+// HandleExportReferences writes the caller's references, with their
+// transcripts and metadata, as a gzip-compressed tar archive.
+func (h *Handler) HandleExportReferences(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.listReferences(r.Context())
+	if err != nil {
+		h.handleBackendError(w, err)
+		return
+	}
+	namespace := namespaceFromContext(r.Context())
+	ids := filterNamespace(namespace, resp.ReferenceIDs)
+	sort.Strings(ids)
+
+	manifest := ReferenceManifest{Version: referenceArchiveVersion, ExportedAt: time.Now().UTC(), References: []ReferenceManifestEntry{}}
+	for _, id := range ids {
+		backendID := scopeReferenceID(namespace, id)
+		entry, err := h.manifestEntry(id, backendID)
+		if err != nil {
+			if !errors.Is(err, refaudio.ErrNotFound) {
+				h.logger.Warn().Err(err).Str("reference_id", backendID).Msg("Failed to read reference audio")
+			}
+			manifest.Missing = append(manifest.Missing, id)
+			continue
+		}
+		manifest.References = append(manifest.References, entry)
+	}
+	raw, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to encode manifest")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="references.tar.gz"`)
+	w.WriteHeader(http.StatusOK)
+
+	// Errors past this point can only be reported by cutting the archive short.
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	err = writeTarFile(tw, referenceManifestName, int64(len(raw)), manifest.ExportedAt, bytes.NewReader(raw))
+	for _, entry := range manifest.References {
+		if err != nil {
+			break
+		}
+		err = h.writeReferenceAudio(tw, entry, scopeReferenceID(namespace, entry.ID), manifest.ExportedAt)
+	}
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		h.logger.Warn().Err(err).Msg("Reference export aborted")
+		return
+	}
+
+	h.logger.Info().
+		Int("references", len(manifest.References)).
+		Int("missing", len(manifest.Missing)).
+		Msg("References exported")
+}
+
+// manifestEntry describes the reference id, kept as backendID, for an
+// export. The audio is named after the reference and its detected format.
+func (h *Handler) manifestEntry(id, backendID string) (ReferenceManifestEntry, error) {
+	text, err := h.archive.Text(backendID)
+	if err != nil {
+		return ReferenceManifestEntry{}, err
+	}
+	f, err := h.archive.Open(backendID)
+	if err != nil {
+		return ReferenceManifestEntry{}, err
+	}
+	defer f.Close()
+	header := make([]byte, 12)
+	n, _ := io.ReadFull(f, header)
+
+	name := "audio/" + id
+	if format := audio.DetectFormat(header[:n]); format != "" {
+		name += "." + format
+	}
+	md, _ := h.refs.Metadata(backendID)
+	return ReferenceManifestEntry{ID: id, Text: text, Audio: name, ReferenceMetadata: md}, nil
+}
+
+func (h *Handler) writeReferenceAudio(tw *tar.Writer, entry ReferenceManifestEntry, backendID string, modTime time.Time) error {
+	f, err := h.archive.Open(backendID)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return writeTarFile(tw, entry.Audio, info.Size(), modTime, f)
+}
+
+func writeTarFile(tw *tar.Writer, name string, size int64, modTime time.Time, r io.Reader) error {
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: size, ModTime: modTime}); err != nil {
+		return err
+	}
+	_, err := io.CopyN(tw, r, size)
+	return err
+}
+
+// HandleImportReferences adds the references of an archive written by
+// HandleExportReferences. Each reference goes through the same checks as
+// /v1/references/add; those that fail them are skipped and reported.
+func (h *Handler) HandleImportReferences(w http.ResponseWriter, r *http.Request) {
+	gz, err := gzip.NewReader(r.Body)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "Archive must be a gzip-compressed tar file")
+		return
+	}
+	tr := tar.NewReader(gz)
+
+	hdr, err := tr.Next()
+	if err != nil || path.Clean(hdr.Name) != referenceManifestName {
+		WriteError(w, http.StatusBadRequest, "Archive must start with "+referenceManifestName)
+		return
+	}
+	var manifest ReferenceManifest
+	if err := json.NewDecoder(io.LimitReader(tr, maxManifestBytes)).Decode(&manifest); err != nil {
+		WriteError(w, http.StatusBadRequest, "Failed to parse "+referenceManifestName)
+		return
+	}
+	if manifest.Version != referenceArchiveVersion {
+		WriteError(w, http.StatusBadRequest, fmt.Sprintf("Unsupported archive version %d", manifest.Version))
+		return
+	}
+
+	entries := make(map[string]ReferenceManifestEntry, len(manifest.References))
+	for _, entry := range manifest.References {
+		entries[path.Clean(entry.Audio)] = entry
+	}
+
+	resp := ImportReferencesResponse{Success: true, Imported: []string{}, Skipped: []ImportSkip{}}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, fmt.Sprintf("Failed to read archive after importing %d references", len(resp.Imported)))
+			return
+		}
+		name := path.Clean(hdr.Name)
+		entry, ok := entries[name]
+		if !ok {
+			continue
+		}
+		delete(entries, name)
+
+		var src io.Reader = tr
+		if limit := h.config.References.MaxAudioBytes; limit > 0 {
+			src = io.LimitReader(tr, limit+1)
+		}
+		data, err := io.ReadAll(src)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, fmt.Sprintf("Failed to read archive after importing %d references", len(resp.Imported)))
+			return
+		}
+
+		req := schema.AddReferenceRequest{ID: entry.ID, Audio: data, Text: entry.Text, ReferenceMetadata: entry.ReferenceMetadata}
+		rec := &referenceRecorder{header: http.Header{}}
+		if h.addReference(rec, r, &req, "") {
+			resp.Imported = append(resp.Imported, entry.ID)
+		} else {
+			resp.Skipped = append(resp.Skipped, ImportSkip{ReferenceID: entry.ID, Reason: rec.reason()})
+		}
+	}
+
+	for _, entry := range entries {
+		resp.Skipped = append(resp.Skipped, ImportSkip{ReferenceID: entry.ID, Reason: "audio missing from archive"})
+	}
+	for _, id := range manifest.Missing {
+		resp.Skipped = append(resp.Skipped, ImportSkip{ReferenceID: id, Reason: "audio was not exported"})
+	}
+	sort.Slice(resp.Skipped, func(i, j int) bool { return resp.Skipped[i].ReferenceID < resp.Skipped[j].ReferenceID })
+
+	if len(resp.Skipped) > 0 {
+		resp.Message = "Some references were not imported"
+	} else {
+		resp.Message = "References imported successfully"
+	}
+
+	h.logger.Info().
+		Int("imported", len(resp.Imported)).
+		Int("skipped", len(resp.Skipped)).
+		Msg("References imported")
+
+	WriteJSON(w, http.StatusOK, resp)
+}
+
+// referenceRecorder captures the response addReference writes for one
+// reference of an import, so that a rejected reference is skipped instead of
+// failing the whole import.
+type referenceRecorder struct {
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (rr *referenceRecorder) Header() http.Header { return rr.header }
+
+func (rr *referenceRecorder) Write(b []byte) (int, error) { return rr.body.Write(b) }
+
+func (rr *referenceRecorder) WriteHeader(int) {}
+
+// reason returns the error detail of a rejected reference.
+func (rr *referenceRecorder) reason() string {
+	var resp schema.ErrorResponse
+	if err := json.Unmarshal(rr.body.Bytes(), &resp); err != nil || resp.Detail == "" {
+		return "rejected"
+	}
+	return resp.Detail
+}
+
+// saveReferenceAudio keeps a copy of the audio and transcript of a reference
+// that was just added, for exports.
+func (h *Handler) saveReferenceAudio(backendID string, data []byte, text string) {
+	if h.archive == nil {
+		return
+	}
+	if err := h.archive.Put(backendID, data, text); err != nil {
+		h.logger.Warn().Err(err).Str("reference_id", backendID).Msg("Failed to store reference audio")
+	}
+}
+
+// deleteReferenceAudio removes the copy of a deleted reference's audio.
+func (h *Handler) deleteReferenceAudio(backendID string) {
+	if h.archive == nil {
+		return
+	}
+	if err := h.archive.Delete(backendID); err != nil {
+		h.logger.Warn().Err(err).Str("reference_id", backendID).Msg("Failed to remove reference audio")
+	}
+}
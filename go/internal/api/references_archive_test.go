@@ -0,0 +1,179 @@
+package api
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/refaudio"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func archiveHandler(t *testing.T, mock *mockBackend) *Handler {
+	t.Helper()
+	archive, err := refaudio.Open(t.TempDir())
+	require.NoError(t, err)
+	return NewHandler(mock, testConfig(), testLogger(), WithReferenceAudio(archive))
+}
+
+// readArchive returns the files of a tar.gz archive in order.
+func readArchive(t *testing.T, data []byte) (names []string, files map[string][]byte) {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	files = map[string][]byte{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return names, files
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		names = append(names, hdr.Name)
+		files[hdr.Name] = content
+	}
+}
+
+func importArchive(t *testing.T, h *Handler, target string, archive []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, target, bytes.NewReader(archive))
+	req.Header.Set("Content-Type", "application/gzip")
+	w := httptest.NewRecorder()
+	h.HandleImportReferences(w, req)
+	return w
+}
+
+func TestReferenceArchive_RoundTrip(t *testing.T) {
+	source := &mockBackend{
+		addRefResp:  &schema.AddReferenceResponse{Success: true},
+		listRefResp: &schema.ListReferencesResponse{Success: true, ReferenceIDs: []string{"narrator", "legacy", "calm"}},
+	}
+	h := archiveHandler(t, source)
+
+	narratorAudio := silentWAV(time.Second)
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, req := range []schema.AddReferenceRequest{
+		{ID: "narrator", Audio: narratorAudio, Text: "Once upon a time", ReferenceMetadata: schema.ReferenceMetadata{
+			DisplayName: "Narrator", Tags: []string{"audiobook"}, CreatedAt: &created,
+		}},
+		{ID: "calm", Audio: []byte("calm audio"), Text: "Breathe in"},
+	} {
+		body, _ := json.Marshal(req)
+		r := httptest.NewRequest(http.MethodPost, "/v1/references/add", bytes.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.HandleAddReference(w, r)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+
+	w := httptest.NewRecorder()
+	h.HandleExportReferences(w, httptest.NewRequest(http.MethodGet, "/v1/references/export", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/gzip", w.Header().Get("Content-Type"))
+	exported := w.Body.Bytes()
+
+	names, files := readArchive(t, exported)
+	assert.Equal(t, []string{referenceManifestName, "audio/calm", "audio/narrator.wav"}, names)
+	assert.Equal(t, narratorAudio, files["audio/narrator.wav"])
+
+	var manifest ReferenceManifest
+	require.NoError(t, json.Unmarshal(files[referenceManifestName], &manifest))
+	assert.Equal(t, referenceArchiveVersion, manifest.Version)
+	assert.Equal(t, []string{"legacy"}, manifest.Missing, "references without a copy of their audio")
+	require.Len(t, manifest.References, 2)
+	assert.Equal(t, "Once upon a time", manifest.References[1].Text)
+	assert.Equal(t, "Narrator", manifest.References[1].DisplayName)
+
+	target := &mockBackend{
+		addRefResp:  &schema.AddReferenceResponse{Success: true},
+		listRefResp: &schema.ListReferencesResponse{Success: true, ReferenceIDs: []string{"narrator"}},
+	}
+	h2 := archiveHandler(t, target)
+	w = importArchive(t, h2, "/v1/references/import", exported)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp ImportReferencesResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []string{"calm", "narrator"}, resp.Imported)
+	assert.Equal(t, []ImportSkip{{ReferenceID: "legacy", Reason: "audio was not exported"}}, resp.Skipped)
+	require.NotNil(t, target.lastAddRefReq)
+	assert.Equal(t, narratorAudio, target.lastAddRefReq.Audio)
+	assert.Equal(t, "Once upon a time", target.lastAddRefReq.Text)
+
+	result := listReferences(t, h2)
+	require.Len(t, result.References, 1)
+	assert.Equal(t, "Narrator", result.References[0].DisplayName)
+	require.NotNil(t, result.References[0].CreatedAt)
+	assert.True(t, created.Equal(*result.References[0].CreatedAt), "created_at survives the migration")
+
+	// The imported references can be exported again.
+	w = httptest.NewRecorder()
+	h2.HandleExportReferences(w, httptest.NewRequest(http.MethodGet, "/v1/references/export", nil))
+	_, files = readArchive(t, w.Body.Bytes())
+	assert.Equal(t, narratorAudio, files["audio/narrator.wav"])
+}
+
+func TestReferenceArchive_ImportSkipsRejected(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	manifest, _ := json.Marshal(ReferenceManifest{Version: referenceArchiveVersion, References: []ReferenceManifestEntry{
+		{ID: "locked", Text: "transcript", Audio: "audio/locked.wav"},
+		{ID: "bad id!", Text: "transcript", Audio: "audio/bad.wav"},
+		{ID: "absent", Text: "transcript", Audio: "audio/absent.wav"},
+	}})
+	require.NoError(t, writeTarFile(tw, referenceManifestName, int64(len(manifest)), time.Now(), bytes.NewReader(manifest)))
+	wav := silentWAV(time.Second)
+	for _, name := range []string{"audio/locked.wav", "audio/bad.wav"} {
+		require.NoError(t, writeTarFile(tw, name, int64(len(wav)), time.Now(), bytes.NewReader(wav)))
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	mock := &mockBackend{addRefResp: &schema.AddReferenceResponse{Success: true}}
+	h := archiveHandler(t, mock)
+	require.NoError(t, h.refs.SetLocked("locked", true))
+
+	w := importArchive(t, h, "/v1/references/import", buf.Bytes())
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp ImportReferencesResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Empty(t, resp.Imported)
+	assert.Equal(t, "Some references were not imported", resp.Message)
+	require.Len(t, resp.Skipped, 3)
+	assert.Equal(t, ImportSkip{ReferenceID: "absent", Reason: "audio missing from archive"}, resp.Skipped[0])
+	assert.Equal(t, "bad id!", resp.Skipped[1].ReferenceID)
+	assert.Contains(t, resp.Skipped[1].Reason, "id must contain only")
+	assert.Equal(t, "locked", resp.Skipped[2].ReferenceID)
+	assert.Contains(t, resp.Skipped[2].Reason, "locked")
+	assert.Nil(t, mock.lastAddRefReq)
+}
+
+func TestReferenceArchive_ImportInvalid(t *testing.T) {
+	h := archiveHandler(t, &mockBackend{})
+
+	w := importArchive(t, h, "/v1/references/import", []byte("not an archive"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, writeTarFile(tw, "audio/voice.wav", 4, time.Now(), bytes.NewReader([]byte("RIFF"))))
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	w = importArchive(t, h, "/v1/references/import", buf.Bytes())
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "manifest.json")
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BulkDeleteRequest selects references to delete by explicit ID list and/or ID prefix.
+type BulkDeleteRequest struct {
+	IDs    []string `json:"ids" msgpack:"ids"`
+	Prefix string   `json:"prefix" msgpack:"prefix"`
+	DryRun bool     `json:"dry_run" msgpack:"dry_run"`
+	Force  bool     `json:"force" msgpack:"force"`
+}
+
+// BulkDeleteSkip reports a reference that matched but was not deleted.
+type BulkDeleteSkip struct {
+	ReferenceID string `json:"reference_id"`
+	Reason      string `json:"reason"`
+}
+
+// BulkDeleteResponse lists deleted references, or the references that would be
+// deleted when dry_run is set.
+type BulkDeleteResponse struct {
+	Success bool             `json:"success"`
+	Message string           `json:"message"`
+	DryRun  bool             `json:"dry_run"`
+	Deleted []string         `json:"deleted"`
+	Skipped []BulkDeleteSkip `json:"skipped"`
+}
+
+// HandleBulkDeleteReferences deletes every reference matching the request filter.
+func (h *Handler) HandleBulkDeleteReferences(w http.ResponseWriter, r *http.Request) {
+	var req BulkDeleteRequest
+	if err := ParseRequestBody(r, &req); err != nil {
+		h.handleParseError(w, err)
+		return
+	}
+
+	if len(req.IDs) == 0 && req.Prefix == "" {
+		WriteError(w, http.StatusBadRequest, "ids or prefix is required")
+		return
+	}
+	if req.Force && !isAdminRequest(r.Context()) {
+		WriteError(w, http.StatusForbidden, "force requires an admin key")
+		return
+	}
+
+	namespace := namespaceFromContext(r.Context())
+
+	start := time.Now()
+	refs, err := h.backend.ListReferences(r.Context())
+	h.logBackendCall(r.Context(), OpListReferences, start, err)
+	if err != nil {
+		h.handleBackendError(w, err)
+		return
+	}
+	visible := filterNamespace(namespace, refs.ReferenceIDs)
+
+	resp := BulkDeleteResponse{Success: true, DryRun: req.DryRun, Deleted: []string{}, Skipped: []BulkDeleteSkip{}}
+	for _, id := range matchReferences(visible, req.IDs, req.Prefix) {
+		if !containsString(visible, id) {
+			resp.Skipped = append(resp.Skipped, BulkDeleteSkip{ReferenceID: id, Reason: "not found"})
+			continue
+		}
+
+		backendID := scopeReferenceID(namespace, id)
+		if h.refs.IsLocked(backendID) && !req.Force {
+			resp.Skipped = append(resp.Skipped, BulkDeleteSkip{ReferenceID: id, Reason: "locked"})
+			continue
+		}
+
+		if req.DryRun {
+			resp.Deleted = append(resp.Deleted, id)
+			continue
+		}
+
+		start = time.Now()
+		_, err := h.backend.DeleteReference(r.Context(), backendID)
+		h.logBackendCall(r.Context(), OpDeleteReference, start, err)
+		if err != nil {
+			resp.Skipped = append(resp.Skipped, BulkDeleteSkip{ReferenceID: id, Reason: err.Error()})
+			continue
+		}
+		if err := h.refs.Forget(backendID); err != nil {
+			h.logger.Warn().Err(err).Str("reference_id", backendID).Msg("Failed to clear reference state")
+		}
+		h.deleteReferenceAudio(backendID)
+		h.invalidateCachedReference(backendID)
+		h.referenceMutation(r.Context(), ActionDelete, backendID).Msg("Reference deleted")
+		resp.Deleted = append(resp.Deleted, id)
+	}
+
+	switch {
+	case req.DryRun:
+		resp.Message = "Dry run: no references were deleted"
+	case len(resp.Skipped) > 0:
+		resp.Message = "Some references were not deleted"
+	default:
+		resp.Message = "References deleted successfully"
+	}
+
+	h.logger.Info().
+		Bool("dry_run", req.DryRun).
+		Int("deleted", len(resp.Deleted)).
+		Int("skipped", len(resp.Skipped)).
+		Msg("Bulk reference deletion")
+
+	WriteJSON(w, http.StatusOK, resp)
+}
+
+// matchReferences returns the sorted, de-duplicated union of the explicit IDs and
+// the visible IDs starting with prefix.
+func matchReferences(visible, ids []string, prefix string) []string {
+	selected := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		selected[id] = struct{}{}
+	}
+	if prefix != "" {
+		for _, id := range visible {
+			if strings.HasPrefix(id, prefix) {
+				selected[id] = struct{}{}
+			}
+		}
+	}
+
+	matched := make([]string, 0, len(selected))
+	for id := range selected {
+		matched = append(matched, id)
+	}
+	sort.Strings(matched)
+	return matched
+}
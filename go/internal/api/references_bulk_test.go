@@ -0,0 +1,90 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func TestBulkDeleteReferences(t *testing.T) {
+	newMock := func() *mockBackend {
+		return &mockBackend{
+			listRefResp:   &schema.ListReferencesResponse{Success: true, ReferenceIDs: []string{"test-1", "test-2", "test-locked", "prod"}},
+			deleteRefResp: &schema.DeleteReferenceResponse{Success: true},
+		}
+	}
+
+	bulkDelete := func(h *Handler, req BulkDeleteRequest) BulkDeleteResponse {
+		body, _ := json.Marshal(req)
+		httpReq := httptest.NewRequest(http.MethodPost, "/v1/references/delete", bytes.NewReader(body))
+		httpReq.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.HandleBulkDeleteReferences(w, httpReq)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp BulkDeleteResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	t.Run("dry run", func(t *testing.T) {
+		mock := newMock()
+		h := NewHandler(mock, testConfig(), testLogger())
+		require.NoError(t, h.refs.SetLocked("test-locked", true))
+
+		resp := bulkDelete(h, BulkDeleteRequest{Prefix: "test-", IDs: []string{"missing"}, DryRun: true})
+
+		assert.True(t, resp.DryRun)
+		assert.Equal(t, []string{"test-1", "test-2"}, resp.Deleted)
+		assert.Equal(t, []BulkDeleteSkip{{"missing", "not found"}, {"test-locked", "locked"}}, resp.Skipped)
+		assert.Empty(t, mock.lastDeleteID)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		mock := newMock()
+		h := NewHandler(mock, testConfig(), testLogger())
+
+		resp := bulkDelete(h, BulkDeleteRequest{IDs: []string{"prod"}})
+
+		assert.Equal(t, []string{"prod"}, resp.Deleted)
+		assert.Equal(t, "prod", mock.lastDeleteID)
+	})
+}
+
+func TestBulkDeleteReferences_RequiresFilter(t *testing.T) {
+	h := NewHandler(&mockBackend{}, testConfig(), testLogger())
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/references/delete", bytes.NewReader([]byte(`{}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.HandleBulkDeleteReferences(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestBulkDeleteReferences_DeleteRoute(t *testing.T) {
+	for _, path := range []string{"/v1/references", "/v2/references"} {
+		t.Run(path, func(t *testing.T) {
+			mock := &mockBackend{
+				listRefResp:   &schema.ListReferencesResponse{Success: true, ReferenceIDs: []string{"stale-1", "stale-2", "keep"}},
+				deleteRefResp: &schema.DeleteReferenceResponse{Success: true},
+			}
+			router := NewRouter(testConfig(), mock, testLogger())
+
+			req := httptest.NewRequest(http.MethodDelete, path, bytes.NewReader([]byte(`{"prefix": "stale-"}`)))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), `"deleted":["stale-1","stale-2"]`)
+			assert.Equal(t, "stale-2", mock.lastDeleteID)
+		})
+	}
+}
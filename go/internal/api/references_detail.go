@@ -0,0 +1,134 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/audio"
+	"github.com/fish-speech-go/fish-speech-go/internal/refaudio"
+)
+
+// ReferenceDetail describes one reference with the state fish-server keeps
+// for it.
+type ReferenceDetail struct {
+	ReferenceInfo
+	// Text is the transcript, known when fish-server keeps a copy of the
+	// reference audio.
+	Text    string   `json:"text,omitempty"`
+	Locked  bool     `json:"locked"`
+	Aliases []string `json:"aliases"`
+	// Audio describes the copy of the reference audio, if any.
+	Audio *ReferenceAudioInfo `json:"audio,omitempty"`
+}
+
+// ReferenceAudioInfo describes the copy fish-server keeps of a reference's
+// audio, which GET /references/{id}/audio downloads.
+type ReferenceAudioInfo struct {
+	Format string `json:"format,omitempty"`
+	Bytes  int64  `json:"bytes"`
+}
+
+// HandleGetReference describes a single reference, given by id or alias.
+func (h *Handler) HandleGetReference(w http.ResponseWriter, r *http.Request) {
+	namespace := namespaceFromContext(r.Context())
+	backendID := h.refs.Resolve(scopeReferenceID(namespace, chi.URLParam(r, "id")))
+
+	resp, err := h.listReferences(r.Context())
+	if err != nil {
+		h.handleBackendError(w, err)
+		return
+	}
+	if !containsString(resp.ReferenceIDs, backendID) {
+		WriteError(w, http.StatusNotFound, "Reference not found")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, h.referenceDetail(namespace, backendID))
+}
+
+// referenceDetail describes the reference backendID, given in namespace.
+func (h *Handler) referenceDetail(namespace, backendID string) ReferenceDetail {
+	md, _ := h.refs.Metadata(backendID)
+	detail := ReferenceDetail{
+		ReferenceInfo: ReferenceInfo{ID: unscopeReferenceID(namespace, backendID), ReferenceMetadata: md},
+		Locked:        h.refs.IsLocked(backendID),
+		Aliases:       []string{},
+	}
+	for _, a := range h.refs.Aliases() {
+		if a.Target == backendID {
+			detail.Aliases = append(detail.Aliases, unscopeReferenceID(namespace, a.Name))
+		}
+	}
+	sort.Strings(detail.Aliases)
+
+	if h.archive != nil {
+		if text, err := h.archive.Text(backendID); err == nil {
+			detail.Text = text
+		}
+		detail.Audio = h.referenceAudioInfo(backendID)
+	}
+	return detail
+}
+
+// referenceAudioInfo describes the copy of the reference's audio, or returns
+// nil when there is none.
+func (h *Handler) referenceAudioInfo(backendID string) *ReferenceAudioInfo {
+	f, err := h.archive.Open(backendID)
+	if err != nil {
+		if !errors.Is(err, refaudio.ErrNotFound) {
+			h.logger.Warn().Err(err).Str("reference_id", backendID).Msg("Failed to read reference audio")
+		}
+		return nil
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil
+	}
+	header := make([]byte, 12)
+	n, _ := io.ReadFull(f, header)
+	return &ReferenceAudioInfo{
+		Format: audio.DetectFormat(header[:n]),
+		Bytes:  info.Size(),
+	}
+}
+
+// HandleGetReferenceAudio downloads the copy fish-server keeps of a
+// reference's audio, as it was added or transcoded to WAV.
+func (h *Handler) HandleGetReferenceAudio(w http.ResponseWriter, r *http.Request) {
+	namespace := namespaceFromContext(r.Context())
+	backendID := h.refs.Resolve(scopeReferenceID(namespace, chi.URLParam(r, "id")))
+
+	f, err := h.archive.Open(backendID)
+	if errors.Is(err, refaudio.ErrNotFound) {
+		WriteError(w, http.StatusNotFound, "Reference audio not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("reference_id", backendID).Msg("Read reference audio error")
+		WriteError(w, http.StatusInternalServerError, "Failed to read reference audio")
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, "Failed to read reference audio")
+		return
+	}
+
+	header := make([]byte, 12)
+	n, _ := io.ReadFull(f, header)
+	format := audio.DetectFormat(header[:n])
+	name := unscopeReferenceID(namespace, backendID)
+	if format != "" {
+		name += "." + format
+	}
+	w.Header().Set("Content-Type", GetAudioContentType(format))
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	// ServeContent seeks back to the start and answers Range requests.
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
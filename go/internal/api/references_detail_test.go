@@ -0,0 +1,77 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/refaudio"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func TestGetReference(t *testing.T) {
+	mock := &mockBackend{
+		addRefResp:  &schema.AddReferenceResponse{Success: true, ReferenceID: "voice"},
+		listRefResp: &schema.ListReferencesResponse{Success: true, ReferenceIDs: []string{"voice"}},
+	}
+	archive, err := refaudio.Open(t.TempDir())
+	require.NoError(t, err)
+	router := NewRouter(testConfig(), mock, testLogger(), WithReferenceAudio(archive))
+
+	data := silentWAV(time.Second)
+	require.Equal(t, http.StatusOK, addReferenceJSON(t, router, data).Code)
+	body, _ := json.Marshal(ReferenceAlias{Alias: "narrator", ReferenceID: "voice"})
+	req := httptest.NewRequest(http.MethodPost, "/v1/references/aliases", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/references/narrator", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var detail ReferenceDetail
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &detail))
+	assert.Equal(t, "voice", detail.ID)
+	assert.Equal(t, "transcript", detail.Text)
+	assert.False(t, detail.Locked)
+	assert.Equal(t, []string{"narrator"}, detail.Aliases)
+	require.NotNil(t, detail.Audio)
+	assert.Equal(t, ReferenceAudioInfo{Format: "wav", Bytes: int64(len(data))}, *detail.Audio)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/references/missing", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetReferenceAudio(t *testing.T) {
+	mock := &mockBackend{addRefResp: &schema.AddReferenceResponse{Success: true, ReferenceID: "voice"}}
+	archive, err := refaudio.Open(t.TempDir())
+	require.NoError(t, err)
+	router := NewRouter(testConfig(), mock, testLogger(), WithReferenceAudio(archive))
+
+	data := silentWAV(time.Second)
+	require.Equal(t, http.StatusOK, addReferenceJSON(t, router, data).Code)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/references/voice/audio", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "audio/wav", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="voice.wav"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, data, w.Body.Bytes())
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/references/voice/audio", nil)
+	req.Header.Set("Range", "bytes=0-11")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, data[:12], w.Body.Bytes())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/references/missing/audio", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/audio"
+	"github.com/fish-speech-go/fish-speech-go/internal/refstore"
+)
+
+// Duplicate detection modes for config.ReferencesConfig.Duplicates.
+const (
+	duplicatesOff    = "off"
+	duplicatesWarn   = "warn"
+	duplicatesReject = "reject"
+)
+
+// fingerprintBins is the resolution of the energy envelope stored per reference.
+const fingerprintBins = 64
+
+// DuplicateReferenceError is returned with 409 when duplicates are rejected.
+type DuplicateReferenceError struct {
+	Detail      string  `json:"detail"`
+	DuplicateOf string  `json:"duplicate_of"`
+	Similarity  float64 `json:"similarity"`
+}
+
+// referenceFingerprint fingerprints uploaded audio. Non-WAV audio is only
+// matched byte-for-byte.
+func referenceFingerprint(data []byte) refstore.Fingerprint {
+	fp := refstore.Fingerprint{SHA256: ContentSHA256(data)}
+	if pcm, err := audio.DecodeWAV(data); err == nil {
+		fp.Envelope = audio.Envelope(pcm, fingerprintBins)
+		fp.DurationMs = pcm.Duration().Milliseconds()
+	}
+	return fp
+}
+
+// findDuplicateReference looks for an existing reference in the caller's
+// namespace whose audio matches fp. When duplicates are rejected it writes a 409
+// response and reports ok=false.
+func (h *Handler) findDuplicateReference(w http.ResponseWriter, namespace, backendID string, fp refstore.Fingerprint) (dup *refstore.Duplicate, ok bool) {
+	mode := h.config.References.Duplicates
+	if mode == "" || mode == duplicatesOff {
+		return nil, true
+	}
+
+	match, found := h.refs.FindDuplicate(fp, h.config.References.DuplicateThreshold, func(id string) bool {
+		return id != backendID && inNamespace(namespace, id)
+	})
+	if !found {
+		return nil, true
+	}
+
+	existing := unscopeReferenceID(namespace, match.ID)
+	if mode == duplicatesReject {
+		WriteJSON(w, http.StatusConflict, DuplicateReferenceError{
+			Detail:      fmt.Sprintf("Audio duplicates existing reference '%s'", existing),
+			DuplicateOf: existing,
+			Similarity:  match.Similarity,
+		})
+		return nil, false
+	}
+
+	return &match, true
+}
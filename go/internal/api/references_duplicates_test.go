@@ -0,0 +1,62 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func TestAddReference_DuplicateDetection(t *testing.T) {
+	addReference := func(h *Handler, id string, audio []byte) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(schema.AddReferenceRequest{ID: id, Audio: audio, Text: "transcript"})
+		req := httptest.NewRequest(http.MethodPost, "/v1/references/add", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.HandleAddReference(w, req)
+		return w
+	}
+
+	t.Run("warn", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.References.Duplicates = duplicatesWarn
+		cfg.References.DuplicateThreshold = 0.98
+		h := NewHandler(&mockBackend{addRefResp: &schema.AddReferenceResponse{Success: true}}, cfg, testLogger())
+
+		require.Equal(t, http.StatusOK, addReference(h, "original", []byte("same audio")).Code)
+		w := addReference(h, "copy", []byte("same audio"))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var result AddReferenceResult
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.True(t, result.Success)
+		assert.Equal(t, "original", result.DuplicateOf)
+		assert.Equal(t, 1.0, result.Similarity)
+		assert.Len(t, result.Warnings, 1)
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.References.Duplicates = duplicatesReject
+		cfg.References.DuplicateThreshold = 0.98
+		mock := &mockBackend{addRefResp: &schema.AddReferenceResponse{Success: true}}
+		h := NewHandler(mock, cfg, testLogger())
+
+		require.Equal(t, http.StatusOK, addReference(h, "original", []byte("same audio")).Code)
+		require.Equal(t, http.StatusOK, addReference(h, "original", []byte("same audio")).Code, "re-adding the same ID is not a duplicate")
+
+		w := addReference(h, "copy", []byte("same audio"))
+		require.Equal(t, http.StatusConflict, w.Code)
+
+		var resp DuplicateReferenceError
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "original", resp.DuplicateOf)
+		assert.Equal(t, "original", mock.lastAddRefReq.ID)
+	})
+}
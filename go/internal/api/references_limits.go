@@ -0,0 +1,121 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/audio"
+	"github.com/fish-speech-go/fish-speech-go/internal/metrics"
+)
+
+// checkReferenceAudio enforces the size limit on reference audio, rejects audio
+// the backend cannot decode or that does not match its declared format, then
+// enforces the allowed formats and duration limits. With references.transcode,
+// audio this build can decode and that is not WAV already is converted to WAV
+// before the duration limits. It returns the audio to pass on, or writes the
+// error response and reports false when the audio is rejected.
+func (h *Handler) checkReferenceAudio(w http.ResponseWriter, data []byte, declared string) ([]byte, bool) {
+	limits := h.config.References
+
+	if !h.checkReferenceSize(w, int64(len(data))) {
+		return nil, false
+	}
+
+	sniff := audio.Sniff
+	if limits.Transcode {
+		sniff = audio.SniffDecodable
+	}
+	format, err := sniff(data, declared)
+	if err != nil {
+		h.rejectReferenceAudio(w, http.StatusUnsupportedMediaType, CodeUnsupportedAudio, metrics.RejectBadFormat,
+			"Reference audio rejected: "+err.Error())
+		return nil, false
+	}
+	if len(limits.AllowedFormats) > 0 && !slices.Contains(limits.AllowedFormats, format) {
+		detected := format
+		if detected == "" {
+			detected = "unrecognized"
+		}
+		h.rejectReferenceAudio(w, http.StatusUnsupportedMediaType, CodeUnsupportedAudio, metrics.RejectBadFormat,
+			fmt.Sprintf("Reference audio format %s is not allowed (allowed: %s)", detected, strings.Join(limits.AllowedFormats, ", ")))
+		return nil, false
+	}
+	if limits.Transcode && format != audio.FormatWAV && audio.CanDecode(format) {
+		var ok bool
+		if data, ok = h.transcodeReferenceAudio(w, data, format); !ok {
+			return nil, false
+		}
+	}
+
+	// Formats whose length is not in the header (MP3, Ogg) are not checked
+	// against the duration limits unless they were transcoded.
+	d, ok := audio.Duration(data)
+	switch {
+	case !ok:
+	case limits.MinAudioDuration > 0 && d < limits.MinAudioDuration:
+		h.rejectReferenceAudio(w, http.StatusBadRequest, CodeAudioDuration, metrics.RejectTooShort,
+			fmt.Sprintf("Reference audio is %s; the minimum is %s", d.Round(time.Millisecond), limits.MinAudioDuration))
+		return nil, false
+	case limits.MaxAudioDuration > 0 && d > limits.MaxAudioDuration:
+		h.rejectReferenceAudio(w, http.StatusBadRequest, CodeAudioDuration, metrics.RejectTooLong,
+			fmt.Sprintf("Reference audio is %s; the maximum is %s", d.Round(time.Millisecond), limits.MaxAudioDuration))
+		return nil, false
+	}
+	return data, true
+}
+
+// transcodeReferenceAudio converts reference audio of the given format to
+// WAV. Audio the decoder does not handle, such as Ogg Vorbis with only an Opus
+// decoder, is passed on unchanged when the backend decodes its format.
+func (h *Handler) transcodeReferenceAudio(w http.ResponseWriter, data []byte, format string) ([]byte, bool) {
+	wav, err := audio.TranscodeToWAV(format, data)
+	if err == nil {
+		return wav, true
+	}
+	if errors.Is(err, audio.ErrUnsupportedFormat) {
+		if _, sniffErr := audio.Sniff(data, ""); sniffErr == nil {
+			return data, true
+		}
+	}
+	h.rejectReferenceAudio(w, http.StatusUnsupportedMediaType, CodeUnsupportedAudio, metrics.RejectBadFormat,
+		fmt.Sprintf("Reference audio could not be converted from %s: %v", format, err))
+	return nil, false
+}
+
+// checkReferenceSize rejects reference audio of size bytes when it exceeds
+// max_audio_bytes. It is also used before the audio is read, when the size is
+// declared up front.
+func (h *Handler) checkReferenceSize(w http.ResponseWriter, size int64) bool {
+	limit := h.config.References.MaxAudioBytes
+	if limit > 0 && size > limit {
+		h.rejectReferenceAudio(w, http.StatusRequestEntityTooLarge, CodeAudioTooLarge, metrics.RejectTooLarge,
+			fmt.Sprintf("Reference audio exceeds the %d byte limit", limit))
+		return false
+	}
+	return true
+}
+
+func (h *Handler) rejectReferenceAudio(w http.ResponseWriter, status int, code, reason, message string) {
+	h.metrics.ReferenceRejectsTotal.WithLabelValues(reason).Inc()
+	WriteErrorCode(w, status, code, message)
+}
+
+// referenceBodyLimit bounds a JSON or MessagePack reference request, whose
+// audio may be base64 encoded, so that oversized uploads are cut off while they
+// are read rather than after. It returns 0 when audio size is unlimited.
+func (h *Handler) referenceBodyLimit() int64 {
+	limit := h.config.References.MaxAudioBytes
+	if limit <= 0 {
+		return 0
+	}
+	return (limit+2)/3*4 + maxFormValueBytes
+}
+
+func isMaxBytesError(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
+}
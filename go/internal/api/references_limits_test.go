@@ -0,0 +1,227 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/audio"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+	"github.com/fish-speech-go/fish-speech-go/internal/upload"
+)
+
+// silentWAV returns 16 kHz mono WAV audio of duration d.
+func silentWAV(d time.Duration) []byte {
+	frames := int(d.Seconds() * 16000)
+	return audio.EncodeWAV(&audio.PCM{SampleRate: 16000, Channels: 1, Samples: make([]float32, frames)})
+}
+
+func addReferenceJSON(t *testing.T, router http.Handler, data []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(schema.AddReferenceRequest{ID: "voice", Text: "transcript", Audio: data})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/v2/references", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAddReference_AudioLimits(t *testing.T) {
+	cfg := testConfig()
+	cfg.References.MinAudioDuration = time.Second
+	cfg.References.MaxAudioDuration = 3 * time.Second
+	cfg.References.AllowedFormats = []string{audio.FormatWAV, audio.FormatFLAC}
+	mock := &mockBackend{addRefResp: &schema.AddReferenceResponse{Success: true, ReferenceID: "voice"}}
+	router := NewRouter(cfg, mock, testLogger())
+
+	tests := []struct {
+		name   string
+		data   []byte
+		status int
+		code   string
+		detail string
+	}{
+		{"accepted", silentWAV(2 * time.Second), http.StatusOK, "", ""},
+		{"too short", silentWAV(500 * time.Millisecond), http.StatusBadRequest, CodeAudioDuration, "Reference audio is 500ms; the minimum is 1s"},
+		{"too long", silentWAV(4 * time.Second), http.StatusBadRequest, CodeAudioDuration, "Reference audio is 4s; the maximum is 3s"},
+		{"mp3 not allowed", []byte("ID3\x04\x00rest of an mp3"), http.StatusUnsupportedMediaType, CodeUnsupportedAudio, "format mp3 is not allowed"},
+		{"unrecognized", []byte("fake audio data"), http.StatusUnsupportedMediaType, CodeUnsupportedAudio, "format unrecognized"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := addReferenceJSON(t, router, tt.data)
+			require.Equal(t, tt.status, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), tt.code)
+			assert.Contains(t, w.Body.String(), tt.detail)
+		})
+	}
+
+	out := scrapeMetrics(t, router)
+	assert.Contains(t, out, `fish_reference_rejects_total{reason="too_short"} 1`)
+	assert.Contains(t, out, `fish_reference_rejects_total{reason="too_long"} 1`)
+	assert.Contains(t, out, `fish_reference_rejects_total{reason="format"} 2`)
+	assert.Contains(t, out, `fish_reference_rejects_total{reason="too_large"} 0`)
+}
+
+func TestAddReference_AudioTooLarge(t *testing.T) {
+	cfg := testConfig()
+	cfg.References.MaxAudioBytes = 1024
+	mock := &mockBackend{addRefResp: &schema.AddReferenceResponse{Success: true, ReferenceID: "voice"}}
+	router := NewRouter(cfg, mock, testLogger())
+
+	w := addReferenceJSON(t, router, make([]byte, 1024))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// Over the limit, but small enough to be decoded before it is checked.
+	w = addReferenceJSON(t, router, make([]byte, 1025))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), CodeAudioTooLarge)
+
+	// Cut off while the body is read.
+	w = addReferenceJSON(t, router, make([]byte, 4<<20))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), CodeAudioTooLarge)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("id", "voice")
+	mw.WriteField("text", "transcript")
+	fw, _ := mw.CreateFormFile("audio", "voice.wav")
+	fw.Write(make([]byte, 4<<20))
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/v1/references/add", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), "exceeds the 1024 byte limit")
+
+	assert.Contains(t, scrapeMetrics(t, router), `fish_reference_rejects_total{reason="too_large"} 3`)
+}
+
+func TestCreateUpload_AudioTooLarge(t *testing.T) {
+	uploads, err := upload.Open(t.TempDir(), time.Hour)
+	require.NoError(t, err)
+	cfg := testConfig()
+	cfg.References.MaxAudioBytes = 1024
+	h := NewHandler(&mockBackend{}, cfg, testLogger(), WithUploadStore(uploads))
+
+	req := httptest.NewRequest(http.MethodPost, "/v2/references/uploads", strings.NewReader(`{"id":"voice","text":"t","length":2048}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	VersionMiddleware(http.HandlerFunc(h.HandleCreateUpload)).ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), CodeAudioTooLarge)
+}
+
+func TestAddReference_SniffsAudioFormat(t *testing.T) {
+	mock := &mockBackend{addRefResp: &schema.AddReferenceResponse{Success: true, ReferenceID: "voice"}}
+	router := NewRouter(testConfig(), mock, testLogger())
+
+	post := func(filename, contentType string, data []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.WriteField("id", "voice")
+		mw.WriteField("text", "transcript")
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", `form-data; name="audio"; filename="`+filename+`"`)
+		header.Set("Content-Type", contentType)
+		fw, _ := mw.CreatePart(header)
+		fw.Write(data)
+		mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/v2/references", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("voice.wav", "audio/wav", silentWAV(time.Second))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = post("voice.wav", "application/octet-stream", []byte("ID3\x04\x00mp3 frames"))
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	assert.Contains(t, w.Body.String(), CodeUnsupportedAudio)
+	assert.Contains(t, w.Body.String(), "declared as wav but contains mp3")
+
+	w = post("voice.m4a", "audio/mp4", []byte("\x00\x00\x00\x20ftypM4A "))
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	assert.Contains(t, w.Body.String(), "m4a is not supported")
+
+	assert.Contains(t, scrapeMetrics(t, router), `fish_reference_rejects_total{reason="format"} 2`)
+}
+
+func TestAddReference_TranscodesAudio(t *testing.T) {
+	m4a := []byte("\x00\x00\x00\x20ftypM4A audio")
+	audio.RegisterDecoder(audio.FormatM4A, func(data []byte) (*audio.PCM, error) {
+		if bytes.HasSuffix(data, []byte("corrupt")) {
+			return nil, errors.New("m4a: corrupt AAC frame")
+		}
+		return &audio.PCM{SampleRate: 16000, Channels: 1, Samples: make([]float32, 8000)}, nil
+	})
+	defer audio.RegisterDecoder(audio.FormatM4A, nil)
+	// An Ogg decoder that only handles Opus.
+	audio.RegisterDecoder(audio.FormatOGG, func([]byte) (*audio.PCM, error) {
+		return nil, fmt.Errorf("%w: Ogg stream is not Opus", audio.ErrUnsupportedFormat)
+	})
+	defer audio.RegisterDecoder(audio.FormatOGG, nil)
+
+	cfg := testConfig()
+	cfg.References.Transcode = true
+	cfg.References.MaxAudioDuration = 3 * time.Second
+	mock := &mockBackend{addRefResp: &schema.AddReferenceResponse{Success: true, ReferenceID: "voice"}}
+	router := NewRouter(cfg, mock, testLogger())
+
+	w := addReferenceJSON(t, router, m4a)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NotNil(t, mock.lastAddRefReq)
+	assert.Equal(t, silentWAV(500*time.Millisecond), mock.lastAddRefReq.Audio, "the backend receives WAV")
+
+	vorbis := []byte("OggS\x00\x02vorbis stream")
+	w = addReferenceJSON(t, router, vorbis)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, vorbis, mock.lastAddRefReq.Audio, "audio the decoder does not handle is passed on")
+
+	w = addReferenceJSON(t, router, append(m4a, "corrupt"...))
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	assert.Contains(t, w.Body.String(), "could not be converted from m4a: m4a: corrupt AAC frame")
+
+	// Transcoded audio is checked against the duration limits.
+	cfg.References.MaxAudioDuration = 100 * time.Millisecond
+	w = addReferenceJSON(t, NewRouter(cfg, mock, testLogger()), m4a)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Reference audio is 500ms; the maximum is 100ms")
+
+	// Without references.transcode, M4A is rejected as before.
+	cfg.References.Transcode = false
+	w = addReferenceJSON(t, NewRouter(cfg, mock, testLogger()), m4a)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	assert.Contains(t, w.Body.String(), "m4a is not supported")
+}
+
+func TestVQGANEncode_SniffsAudioFormat(t *testing.T) {
+	mock := &mockBackend{vqganEncodeResp: &schema.ServeVQGANEncodeResponse{Tokens: [][][]int{{{1}}, {{2}}}}}
+	h := NewHandler(mock, testConfig(), testLogger())
+
+	body, _ := json.Marshal(schema.ServeVQGANEncodeRequest{Audios: [][]byte{silentWAV(time.Second), {0x1A, 0x45, 0xDF, 0xA3}}})
+	req := httptest.NewRequest(http.MethodPost, "/v1/vqgan/encode", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.HandleVQGANEncode(w, req)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	assert.Contains(t, w.Body.String(), "audios[1]")
+	assert.Contains(t, w.Body.String(), "webm is not supported")
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+// Limits on the metadata kept for a reference.
+const (
+	maxDisplayNameLength = 255
+	maxDescriptionLength = 2000
+	maxReferenceTags     = 32
+	maxTagLength         = 64
+)
+
+// languageTagPattern matches BCP 47 language tags such as "en" or "pt-BR".
+var languageTagPattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{1,8})*$`)
+
+// ReferenceInfo describes a reference and the metadata kept for it.
+type ReferenceInfo struct {
+	ID string `json:"id"`
+	schema.ReferenceMetadata
+}
+
+// ListReferencesResult extends the upstream list-references response with the
+// metadata of each reference. ReferenceIDs is kept for clients of the Python
+// server.
+type ListReferencesResult struct {
+	schema.ListReferencesResponse
+	References []ReferenceInfo `json:"references"`
+}
+
+// validateReferenceMetadata checks the metadata of an add-reference request.
+func validateReferenceMetadata(md *schema.ReferenceMetadata) error {
+	if len(md.DisplayName) > maxDisplayNameLength {
+		return fmt.Errorf("display_name must be %d characters or less", maxDisplayNameLength)
+	}
+	if md.Language != "" && !languageTagPattern.MatchString(md.Language) {
+		return errors.New("language must be a language tag such as en or pt-BR")
+	}
+	switch md.Gender {
+	case "", "female", "male", "neutral":
+	default:
+		return errors.New("gender must be one of: female, male, neutral")
+	}
+	if len(md.Description) > maxDescriptionLength {
+		return fmt.Errorf("description must be %d characters or less", maxDescriptionLength)
+	}
+	if len(md.Tags) > maxReferenceTags {
+		return fmt.Errorf("tags must have %d entries or less", maxReferenceTags)
+	}
+	for _, tag := range md.Tags {
+		if strings.TrimSpace(tag) == "" || len(tag) > maxTagLength {
+			return fmt.Errorf("tags must be non-empty and %d characters or less", maxTagLength)
+		}
+	}
+	return nil
+}
+
+// setReferenceFormField sets the metadata field named by a multipart form
+// field; tags may be repeated.
+func setReferenceFormField(md *schema.ReferenceMetadata, name, value string) error {
+	switch name {
+	case "display_name":
+		md.DisplayName = value
+	case "language":
+		md.Language = value
+	case "gender":
+		md.Gender = value
+	case "description":
+		md.Description = value
+	case "tags":
+		md.Tags = append(md.Tags, value)
+	case "created_at":
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return errors.New("created_at must be an RFC 3339 timestamp")
+		}
+		md.CreatedAt = &t
+	}
+	return nil
+}
+
+// saveReferenceMetadata records the metadata of a reference that was just
+// added, stamping it with the current time unless the client set created_at.
+func (h *Handler) saveReferenceMetadata(backendID string, md schema.ReferenceMetadata) {
+	if md.CreatedAt == nil {
+		now := time.Now().UTC()
+		md.CreatedAt = &now
+	}
+	if err := h.refs.SetMetadata(backendID, md); err != nil {
+		h.logger.Warn().Err(err).Str("reference_id", backendID).Msg("Failed to store reference metadata")
+	}
+}
+
+// referenceInfos returns the references ids, given in namespace, with their metadata.
+func (h *Handler) referenceInfos(namespace string, ids []string) []ReferenceInfo {
+	infos := make([]ReferenceInfo, 0, len(ids))
+	for _, id := range ids {
+		md, _ := h.refs.Metadata(scopeReferenceID(namespace, id))
+		infos = append(infos, ReferenceInfo{ID: id, ReferenceMetadata: md})
+	}
+	return infos
+}
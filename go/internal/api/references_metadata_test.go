@@ -0,0 +1,130 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func listReferences(t *testing.T, h *Handler) ListReferencesResult {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.HandleListReferences(w, httptest.NewRequest(http.MethodGet, "/v1/references", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var result ListReferencesResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	return result
+}
+
+func TestReferenceMetadata(t *testing.T) {
+	mock := &mockBackend{
+		addRefResp:  &schema.AddReferenceResponse{Success: true, ReferenceID: "narrator"},
+		listRefResp: &schema.ListReferencesResponse{Success: true, ReferenceIDs: []string{"narrator", "legacy"}},
+	}
+	h := NewHandler(mock, testConfig(), testLogger())
+
+	body, _ := json.Marshal(schema.AddReferenceRequest{
+		ID: "narrator", Audio: []byte("fake audio"), Text: "transcript",
+		ReferenceMetadata: schema.ReferenceMetadata{
+			DisplayName: "Narrator",
+			Language:    "en-GB",
+			Gender:      "female",
+			Description: "Warm and calm",
+			Tags:        []string{"audiobook", "calm"},
+		},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/v1/references/add", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	before := time.Now()
+	h.HandleAddReference(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	result := listReferences(t, h)
+	assert.Equal(t, []string{"narrator", "legacy"}, result.ReferenceIDs)
+	require.Len(t, result.References, 2)
+
+	narrator := result.References[0]
+	assert.Equal(t, "narrator", narrator.ID)
+	assert.Equal(t, "Narrator", narrator.DisplayName)
+	assert.Equal(t, "en-GB", narrator.Language)
+	assert.Equal(t, "female", narrator.Gender)
+	assert.Equal(t, "Warm and calm", narrator.Description)
+	assert.Equal(t, []string{"audiobook", "calm"}, narrator.Tags)
+	require.NotNil(t, narrator.CreatedAt)
+	assert.False(t, narrator.CreatedAt.Before(before.Truncate(time.Second)))
+
+	// References added before metadata existed are listed by ID alone.
+	assert.Equal(t, ReferenceInfo{ID: "legacy"}, result.References[1])
+}
+
+func TestReferenceMetadata_Multipart(t *testing.T) {
+	mock := &mockBackend{
+		addRefResp:  &schema.AddReferenceResponse{Success: true, ReferenceID: "narrator"},
+		listRefResp: &schema.ListReferencesResponse{Success: true, ReferenceIDs: []string{"narrator"}},
+	}
+	h := NewHandler(mock, testConfig(), testLogger())
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("audio", "voice.wav")
+	fw.Write(silentWAV(time.Second))
+	mw.WriteField("id", "narrator")
+	mw.WriteField("text", "transcript")
+	mw.WriteField("display_name", "Narrator")
+	mw.WriteField("tags", "audiobook")
+	mw.WriteField("tags", "calm")
+	mw.WriteField("created_at", "2024-05-01T12:00:00Z")
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/references/add", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	h.HandleAddReference(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	result := listReferences(t, h)
+	require.Len(t, result.References, 1)
+	assert.Equal(t, "Narrator", result.References[0].DisplayName)
+	assert.Equal(t, []string{"audiobook", "calm"}, result.References[0].Tags)
+	require.NotNil(t, result.References[0].CreatedAt)
+	assert.True(t, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC).Equal(*result.References[0].CreatedAt))
+}
+
+func TestReferenceMetadata_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		md   schema.ReferenceMetadata
+	}{
+		{name: "long display name", md: schema.ReferenceMetadata{DisplayName: string(make([]byte, maxDisplayNameLength+1))}},
+		{name: "invalid language", md: schema.ReferenceMetadata{Language: "english!"}},
+		{name: "unknown gender", md: schema.ReferenceMetadata{Gender: "robot"}},
+		{name: "empty tag", md: schema.ReferenceMetadata{Tags: []string{"calm", " "}}},
+		{name: "too many tags", md: schema.ReferenceMetadata{Tags: make([]string, maxReferenceTags+1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockBackend{addRefResp: &schema.AddReferenceResponse{Success: true}}
+			h := NewHandler(mock, testConfig(), testLogger())
+
+			body, _ := json.Marshal(schema.AddReferenceRequest{ID: "voice", Audio: []byte("fake audio"), Text: "transcript", ReferenceMetadata: tt.md})
+			req := httptest.NewRequest(http.MethodPost, "/v1/references/add", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			h.HandleAddReference(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Nil(t, mock.lastAddRefReq)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/audio"
+	"github.com/fish-speech-go/fish-speech-go/internal/backend"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+	"github.com/fish-speech-go/fish-speech-go/internal/text"
+)
+
+// wantsTranscriptVerification reports whether the client opted into ASR
+// verification via the verify_transcript query parameter or form field.
+func wantsTranscriptVerification(r *http.Request) bool {
+	return r.URL.Query().Get("verify_transcript") == "true" || r.FormValue("verify_transcript") == "true"
+}
+
+// verifyTranscript transcribes the reference audio with the backend's ASR
+// endpoint and records how closely it matches the provided transcript. Failures
+// are reported as warnings; they never block the upload.
+func (h *Handler) verifyTranscript(ctx context.Context, req *schema.AddReferenceRequest, result *AddReferenceResult) {
+	pcm, err := audio.DecodeWAV(req.Audio)
+	if err != nil {
+		result.Warnings = append(result.Warnings, "Transcript verification skipped: only WAV audio can be transcribed")
+		return
+	}
+	mono := audio.ToMono(pcm)
+
+	start := time.Now()
+	resp, err := h.backend.ASR(ctx, &schema.ServeASRRequest{
+		Audios:     [][]byte{audio.EncodeFloat16(mono.Samples)},
+		SampleRate: mono.SampleRate,
+		Language:   "auto",
+	})
+	if err != nil {
+		var backendErr *backend.BackendError
+		if errors.As(err, &backendErr) && backendErr.StatusCode == http.StatusNotFound {
+			result.Warnings = append(result.Warnings, "Transcript verification unavailable: backend has no ASR endpoint")
+			return
+		}
+		h.backendCallEvent(ctx, zerolog.WarnLevel, OpASR, start, err)
+		result.Warnings = append(result.Warnings, "Transcript verification failed: ASR request error")
+		return
+	}
+
+	h.backendCallEvent(ctx, zerolog.WarnLevel, OpASR, start, nil)
+
+	parts := make([]string, 0, len(resp.Transcriptions))
+	for _, t := range resp.Transcriptions {
+		parts = append(parts, t.Text)
+	}
+	transcription := strings.Join(parts, " ")
+	similarity := text.Similarity(transcription, req.Text)
+
+	result.Transcription = transcription
+	result.TranscriptSimilarity = &similarity
+	if similarity < h.config.References.TranscriptThreshold {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Transcript differs from the audio (similarity %.2f)", similarity))
+	}
+}
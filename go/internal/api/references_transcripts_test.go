@@ -0,0 +1,87 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/audio"
+	"github.com/fish-speech-go/fish-speech-go/internal/backend"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func TestAddReference_VerifyTranscript(t *testing.T) {
+	wav := audio.EncodeWAV(&audio.PCM{SampleRate: 16000, Channels: 1, Samples: make([]float32, 1600)})
+
+	testCases := []struct {
+		name         string
+		audio        []byte
+		asrResp      *schema.ServeASRResponse
+		asrErr       error
+		similarity   *float64
+		warningCount int
+	}{
+		{
+			name:       "matching transcript",
+			audio:      wav,
+			asrResp:    &schema.ServeASRResponse{Transcriptions: []schema.ServeASRTranscription{{Text: "Hello world."}}},
+			similarity: floatPtr(1),
+		},
+		{
+			name:         "mismatched transcript",
+			audio:        wav,
+			asrResp:      &schema.ServeASRResponse{Transcriptions: []schema.ServeASRTranscription{{Text: "something else entirely"}}},
+			warningCount: 1,
+		},
+		{
+			name:         "asr unavailable",
+			audio:        wav,
+			asrErr:       &backend.BackendError{StatusCode: http.StatusNotFound, Message: "Not Found"},
+			warningCount: 1,
+		},
+		{
+			name:         "non wav audio",
+			audio:        []byte("ID3 mp3 data"),
+			warningCount: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &mockBackend{
+				addRefResp: &schema.AddReferenceResponse{Success: true, ReferenceID: "voice"},
+				asrResp:    tc.asrResp,
+				asrErr:     tc.asrErr,
+			}
+			cfg := testConfig()
+			cfg.References.TranscriptThreshold = 0.7
+			h := NewHandler(mock, cfg, testLogger())
+
+			body, _ := json.Marshal(schema.AddReferenceRequest{ID: "voice", Audio: tc.audio, Text: "hello world"})
+			req := httptest.NewRequest(http.MethodPost, "/v1/references/add?verify_transcript=true", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			h.HandleAddReference(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			var result AddReferenceResult
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+			assert.True(t, result.Success)
+			assert.Len(t, result.Warnings, tc.warningCount)
+			if tc.similarity != nil {
+				require.NotNil(t, result.TranscriptSimilarity)
+				assert.Equal(t, *tc.similarity, *result.TranscriptSimilarity)
+			}
+		})
+	}
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
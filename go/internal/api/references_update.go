@@ -0,0 +1,150 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/refaudio"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+// UpdateReferenceRequest is the body of PATCH /v1/references/{id}. Omitted
+// fields are left as they are; an empty string or tags list clears the field.
+type UpdateReferenceRequest struct {
+	Text        *string  `json:"text,omitempty" msgpack:"text,omitempty"`
+	DisplayName *string  `json:"display_name,omitempty" msgpack:"display_name,omitempty"`
+	Language    *string  `json:"language,omitempty" msgpack:"language,omitempty"`
+	Gender      *string  `json:"gender,omitempty" msgpack:"gender,omitempty"`
+	Description *string  `json:"description,omitempty" msgpack:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty" msgpack:"tags,omitempty"`
+}
+
+// updatesMetadata reports whether the request changes any metadata.
+func (req *UpdateReferenceRequest) updatesMetadata() bool {
+	return req.DisplayName != nil || req.Language != nil || req.Gender != nil ||
+		req.Description != nil || req.Tags != nil
+}
+
+// apply sets the fields of md the request changes.
+func (req *UpdateReferenceRequest) apply(md *schema.ReferenceMetadata) {
+	if req.DisplayName != nil {
+		md.DisplayName = *req.DisplayName
+	}
+	if req.Language != nil {
+		md.Language = *req.Language
+	}
+	if req.Gender != nil {
+		md.Gender = *req.Gender
+	}
+	if req.Description != nil {
+		md.Description = *req.Description
+	}
+	if req.Tags != nil {
+		md.Tags = req.Tags
+	}
+}
+
+// HandleUpdateReference changes the transcript or metadata of a reference
+// without uploading its audio again. Metadata is kept by fish-server alone.
+// The backend keeps the transcript with the audio, so a new transcript is
+// sent to it with fish-server's copy of the audio, as adding the reference
+// again would.
+func (h *Handler) HandleUpdateReference(w http.ResponseWriter, r *http.Request) {
+	var req UpdateReferenceRequest
+	if err := ParseRequestBody(r, &req); err != nil {
+		h.handleParseError(w, err)
+		return
+	}
+	if req.Text == nil && !req.updatesMetadata() {
+		WriteError(w, http.StatusBadRequest, "No fields to update")
+		return
+	}
+	if req.Text != nil && *req.Text == "" {
+		WriteError(w, http.StatusBadRequest, "text must not be empty")
+		return
+	}
+
+	namespace := namespaceFromContext(r.Context())
+	backendID := h.refs.Resolve(scopeReferenceID(namespace, chi.URLParam(r, "id")))
+
+	md, _ := h.refs.Metadata(backendID)
+	req.apply(&md)
+	if err := validateReferenceMetadata(&md); err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	refs, err := h.listReferences(r.Context())
+	if err != nil {
+		h.handleBackendError(w, err)
+		return
+	}
+	if !containsString(refs.ReferenceIDs, backendID) {
+		WriteError(w, http.StatusNotFound, "Reference not found")
+		return
+	}
+
+	if req.Text != nil {
+		if !h.checkReferenceLock(w, r, backendID) {
+			return
+		}
+		if !h.updateReferenceText(w, r, backendID, *req.Text) {
+			return
+		}
+	}
+	if req.updatesMetadata() {
+		if err := h.refs.SetMetadata(backendID, md); err != nil {
+			h.logger.Error().Err(err).Str("reference_id", backendID).Msg("Store reference metadata error")
+			WriteError(w, http.StatusInternalServerError, "Failed to store reference metadata")
+			return
+		}
+	}
+
+	h.referenceMutation(r.Context(), ActionUpdate, backendID).Msg("Reference updated")
+	WriteJSON(w, http.StatusOK, h.referenceDetail(namespace, backendID))
+}
+
+// updateReferenceText adds the reference backendID to the backend again with
+// fish-server's copy of its audio and a new transcript. It reports whether to
+// proceed.
+func (h *Handler) updateReferenceText(w http.ResponseWriter, r *http.Request, backendID, text string) bool {
+	if h.archive == nil {
+		WriteError(w, http.StatusConflict, "Reference audio is not kept; add the reference again to change its text")
+		return false
+	}
+	f, err := h.archive.Open(backendID)
+	if errors.Is(err, refaudio.ErrNotFound) {
+		WriteError(w, http.StatusConflict, "Reference audio is not kept; add the reference again to change its text")
+		return false
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("reference_id", backendID).Msg("Read reference audio error")
+		WriteError(w, http.StatusInternalServerError, "Failed to read reference audio")
+		return false
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		h.logger.Error().Err(err).Str("reference_id", backendID).Msg("Read reference audio error")
+		WriteError(w, http.StatusInternalServerError, "Failed to read reference audio")
+		return false
+	}
+
+	start := time.Now()
+	_, err = h.backend.AddReference(r.Context(), &schema.AddReferenceRequest{ID: backendID, Audio: data, Text: text})
+	h.logBackendCall(r.Context(), OpAddReference, start, err)
+	if err != nil {
+		h.handleBackendError(w, err)
+		return false
+	}
+
+	if err := h.archive.SetText(backendID, text); err != nil {
+		h.logger.Warn().Err(err).Str("reference_id", backendID).Msg("Failed to store reference transcript")
+	}
+	h.invalidateCachedReference(backendID)
+	return true
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/refaudio"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func patchReference(t *testing.T, router http.Handler, id, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPatch, "/v1/references/"+id, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestUpdateReference_Metadata(t *testing.T) {
+	mock := &mockBackend{listRefResp: &schema.ListReferencesResponse{Success: true, ReferenceIDs: []string{"voice"}}}
+	router := NewRouter(testConfig(), mock, testLogger())
+
+	w := patchReference(t, router, "voice", `{"display_name": "Narrator", "tags": ["audiobook"]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = patchReference(t, router, "voice", `{"language": "en"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var detail ReferenceDetail
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &detail))
+	assert.Equal(t, "Narrator", detail.DisplayName, "omitted fields are kept")
+	assert.Equal(t, "en", detail.Language)
+	assert.Equal(t, []string{"audiobook"}, detail.Tags)
+	assert.Nil(t, mock.lastAddRefReq, "metadata is not sent to the backend")
+
+	tests := []struct {
+		name   string
+		id     string
+		body   string
+		status int
+	}{
+		{"no fields", "voice", `{}`, http.StatusBadRequest},
+		{"empty text", "voice", `{"text": ""}`, http.StatusBadRequest},
+		{"invalid metadata", "voice", `{"gender": "other"}`, http.StatusBadRequest},
+		{"not found", "missing", `{"display_name": "Missing"}`, http.StatusNotFound},
+		{"text without audio copy", "voice", `{"text": "New transcript"}`, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := patchReference(t, router, tt.id, tt.body)
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestUpdateReference_Text(t *testing.T) {
+	mock := &mockBackend{
+		addRefResp:  &schema.AddReferenceResponse{Success: true, ReferenceID: "voice"},
+		listRefResp: &schema.ListReferencesResponse{Success: true, ReferenceIDs: []string{"voice"}},
+	}
+	archive, err := refaudio.Open(t.TempDir())
+	require.NoError(t, err)
+	router := NewRouter(testConfig(), mock, testLogger(), WithReferenceAudio(archive))
+
+	data := silentWAV(time.Second)
+	require.Equal(t, http.StatusOK, addReferenceJSON(t, router, data).Code)
+	mock.lastAddRefReq = nil
+
+	w := patchReference(t, router, "voice", `{"text": "New transcript"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var detail ReferenceDetail
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &detail))
+	assert.Equal(t, "New transcript", detail.Text)
+
+	require.NotNil(t, mock.lastAddRefReq, "the backend gets the new transcript")
+	assert.Equal(t, "voice", mock.lastAddRefReq.ID)
+	assert.Equal(t, "New transcript", mock.lastAddRefReq.Text)
+	assert.Equal(t, data, mock.lastAddRefReq.Audio)
+	assert.True(t, archive.Has("voice"), "the audio is kept")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/v1/references/voice/lock", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusLocked, patchReference(t, router, "voice", `{"text": "Locked"}`).Code)
+	assert.Equal(t, http.StatusOK, patchReference(t, router, "voice", `{"description": "Locked voices keep their audio"}`).Code)
+}
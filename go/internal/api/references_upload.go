@@ -0,0 +1,196 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+	"github.com/fish-speech-go/fish-speech-go/internal/upload"
+)
+
+// Resumable upload headers, following the tus protocol.
+const (
+	HeaderUploadOffset = "Upload-Offset"
+	HeaderUploadLength = "Upload-Length"
+)
+
+// CreateUploadRequest starts a resumable reference upload of Length bytes of
+// audio. ID and Text are those of the reference created on completion.
+type CreateUploadRequest struct {
+	ID     string `json:"id" msgpack:"id"`
+	Text   string `json:"text" msgpack:"text"`
+	Length int64  `json:"length" msgpack:"length"`
+}
+
+// UploadResponse describes a resumable upload session.
+type UploadResponse struct {
+	UploadID    string    `json:"upload_id"`
+	ReferenceID string    `json:"reference_id"`
+	Offset      int64     `json:"offset"`
+	Length      int64     `json:"length"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+func newUploadResponse(sess upload.Session) UploadResponse {
+	return UploadResponse{
+		UploadID:    sess.ID,
+		ReferenceID: sess.ReferenceID,
+		Offset:      sess.Offset,
+		Length:      sess.Length,
+		ExpiresAt:   sess.ExpiresAt,
+	}
+}
+
+func setUploadHeaders(w http.ResponseWriter, sess upload.Session) {
+	w.Header().Set(HeaderUploadOffset, strconv.FormatInt(sess.Offset, 10))
+	w.Header().Set(HeaderUploadLength, strconv.FormatInt(sess.Length, 10))
+	w.Header().Set("Cache-Control", "no-store")
+}
+
+// HandleCreateUpload starts a resumable upload. The length may also be given in
+// the Upload-Length header.
+func (h *Handler) HandleCreateUpload(w http.ResponseWriter, r *http.Request) {
+	var req CreateUploadRequest
+	if err := ParseRequestBody(r, &req); err != nil {
+		h.handleParseError(w, err)
+		return
+	}
+	if req.Length == 0 {
+		req.Length, _ = strconv.ParseInt(r.Header.Get(HeaderUploadLength), 10, 64)
+	}
+
+	if err := validateReferenceID("id", req.ID); err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Text == "" {
+		WriteError(w, http.StatusBadRequest, "text is required")
+		return
+	}
+	if req.Length <= 0 {
+		WriteError(w, http.StatusBadRequest, "length must be positive")
+		return
+	}
+	if !h.checkReferenceSize(w, req.Length) {
+		return
+	}
+
+	sess, err := h.uploads.Create(upload.Session{
+		Namespace:   namespaceFromContext(r.Context()),
+		ReferenceID: req.ID,
+		Text:        req.Text,
+		Length:      req.Length,
+	})
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Create upload error")
+		WriteError(w, http.StatusInternalServerError, "Failed to create upload")
+		return
+	}
+
+	setUploadHeaders(w, sess)
+	w.Header().Set("Location", strings.TrimSuffix(r.URL.Path, "/")+"/"+sess.ID)
+	WriteJSON(w, http.StatusCreated, newUploadResponse(sess))
+}
+
+// HandleGetUpload reports how much of an upload has been received, so that a
+// client can resume after a failed chunk.
+func (h *Handler) HandleGetUpload(w http.ResponseWriter, r *http.Request) {
+	sess, ok := h.lookupUpload(w, r)
+	if !ok {
+		return
+	}
+	setUploadHeaders(w, sess)
+	WriteJSON(w, http.StatusOK, newUploadResponse(sess))
+}
+
+// HandlePatchUpload appends the request body to an upload at Upload-Offset,
+// which must match the bytes received so far.
+func (h *Handler) HandlePatchUpload(w http.ResponseWriter, r *http.Request) {
+	sess, ok := h.lookupUpload(w, r)
+	if !ok {
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get(HeaderUploadOffset), 10, 64)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "Upload-Offset header is required")
+		return
+	}
+
+	sess, err = h.uploads.Append(sess.ID, offset, r.Body)
+	setUploadHeaders(w, sess)
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, upload.ErrOffsetMismatch):
+		WriteErrorCode(w, http.StatusConflict, CodeUploadOffsetMismatch,
+			"Upload-Offset does not match the "+strconv.FormatInt(sess.Offset, 10)+" bytes received")
+	case errors.Is(err, upload.ErrBusy):
+		WriteErrorCode(w, http.StatusConflict, CodeUploadOffsetMismatch, "Another chunk is being written to this upload")
+	case errors.Is(err, upload.ErrTooLarge):
+		WriteError(w, http.StatusRequestEntityTooLarge, "Chunk extends past the upload length")
+	case errors.Is(err, upload.ErrNotFound):
+		WriteError(w, http.StatusNotFound, "Upload not found")
+	default:
+		// The bytes received before the failure are kept; Upload-Offset tells
+		// the client where to resume.
+		h.logger.Warn().Err(err).Str("upload_id", sess.ID).Int64("offset", sess.Offset).Msg("Upload chunk interrupted")
+		WriteError(w, http.StatusBadRequest, "Failed to read chunk")
+	}
+}
+
+// HandleCompleteUpload adds the reference from a fully received upload. The
+// upload is kept if adding fails, so the request can be retried.
+func (h *Handler) HandleCompleteUpload(w http.ResponseWriter, r *http.Request) {
+	sess, ok := h.lookupUpload(w, r)
+	if !ok {
+		return
+	}
+	data, sess, err := h.uploads.Data(sess.ID)
+	if errors.Is(err, upload.ErrIncomplete) {
+		setUploadHeaders(w, sess)
+		WriteErrorCode(w, http.StatusConflict, CodeUploadOffsetMismatch,
+			"Upload is incomplete: "+strconv.FormatInt(sess.Offset, 10)+" of "+strconv.FormatInt(sess.Length, 10)+" bytes received")
+		return
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("upload_id", sess.ID).Msg("Read upload error")
+		WriteError(w, http.StatusInternalServerError, "Failed to read upload")
+		return
+	}
+
+	req := schema.AddReferenceRequest{ID: sess.ReferenceID, Text: sess.Text, Audio: data}
+	if h.addReference(w, r, &req, "") {
+		if err := h.uploads.Delete(sess.ID); err != nil && !errors.Is(err, upload.ErrNotFound) {
+			h.logger.Warn().Err(err).Str("upload_id", sess.ID).Msg("Failed to remove completed upload")
+		}
+	}
+}
+
+// HandleDeleteUpload abandons an upload.
+func (h *Handler) HandleDeleteUpload(w http.ResponseWriter, r *http.Request) {
+	sess, ok := h.lookupUpload(w, r)
+	if !ok {
+		return
+	}
+	if err := h.uploads.Delete(sess.ID); err != nil && !errors.Is(err, upload.ErrNotFound) {
+		WriteError(w, http.StatusInternalServerError, "Failed to delete upload")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// lookupUpload returns the upload named in the path if it belongs to the
+// caller's namespace, writing a 404 otherwise.
+func (h *Handler) lookupUpload(w http.ResponseWriter, r *http.Request) (upload.Session, bool) {
+	sess, err := h.uploads.Get(chi.URLParam(r, "upload_id"))
+	if err != nil || sess.Namespace != namespaceFromContext(r.Context()) {
+		WriteError(w, http.StatusNotFound, "Upload not found")
+		return upload.Session{}, false
+	}
+	return sess, true
+}
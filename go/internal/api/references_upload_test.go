@@ -0,0 +1,117 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+	"github.com/fish-speech-go/fish-speech-go/internal/upload"
+)
+
+func TestResumableUpload(t *testing.T) {
+	uploads, err := upload.Open(t.TempDir(), time.Hour)
+	require.NoError(t, err)
+	mock := &mockBackend{addRefResp: &schema.AddReferenceResponse{Success: true, ReferenceID: "voice"}}
+	router := NewRouter(testConfig(), mock, testLogger(), WithUploadStore(uploads))
+
+	do := func(method, path, body string, headers ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for i := 0; i < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/v1/references/uploads", `{"id":"voice","text":"hello","length":10}`,
+		"Content-Type", "application/json")
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created UploadResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	location := w.Header().Get("Location")
+	assert.Equal(t, "/v1/references/uploads/"+created.UploadID, location)
+
+	w = do(http.MethodPatch, location, "01234", HeaderUploadOffset, "0")
+	require.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "5", w.Header().Get(HeaderUploadOffset))
+
+	// Completing early fails and keeps the upload.
+	w = do(http.MethodPost, location+"/complete", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// A client resuming with a stale offset learns the current one.
+	w = do(http.MethodPatch, "/v2"+strings.TrimPrefix(location, "/v1"), "01234", HeaderUploadOffset, "0")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), CodeUploadOffsetMismatch)
+	assert.Equal(t, "5", w.Header().Get(HeaderUploadOffset))
+
+	w = do(http.MethodHead, location, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "5", w.Header().Get(HeaderUploadOffset))
+	assert.Equal(t, "10", w.Header().Get(HeaderUploadLength))
+
+	w = do(http.MethodPatch, location, "56789", HeaderUploadOffset, "5")
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	w = do(http.MethodPost, location+"/complete", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NotNil(t, mock.lastAddRefReq)
+	assert.Equal(t, "voice", mock.lastAddRefReq.ID)
+	assert.Equal(t, "hello", mock.lastAddRefReq.Text)
+	assert.Equal(t, []byte("0123456789"), mock.lastAddRefReq.Audio)
+
+	// The upload is removed once the reference is added.
+	w = do(http.MethodGet, location, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestResumableUpload_Validation(t *testing.T) {
+	uploads, err := upload.Open(t.TempDir(), time.Hour)
+	require.NoError(t, err)
+	h := NewHandler(&mockBackend{}, testConfig(), testLogger(), WithUploadStore(uploads))
+
+	for _, body := range []string{
+		`{"id":"","text":"t","length":1}`,
+		`{"id":"voice","text":"","length":1}`,
+		`{"id":"voice","text":"t"}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/references/uploads", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.HandleCreateUpload(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
+
+func TestResumableUpload_NamespaceIsolation(t *testing.T) {
+	uploads, err := upload.Open(t.TempDir(), time.Hour)
+	require.NoError(t, err)
+	cfg := testConfig()
+	cfg.Auth = config.AuthConfig{Keys: []config.APIKeyConfig{
+		{Key: "key-a", Namespace: "a"},
+		{Key: "key-b", Namespace: "b"},
+	}}
+	router := NewRouter(cfg, &mockBackend{}, testLogger(), WithUploadStore(uploads))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/references/uploads", strings.NewReader(`{"id":"voice","text":"t","length":4}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer key-a")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, w.Header().Get("Location"), nil)
+	req.Header.Set("Authorization", "Bearer key-b")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -1,24 +1,66 @@
 package api
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"io"
+	"math"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/vmihailenco/msgpack/v5"
 
 	"github.com/fish-speech-go/fish-speech-go/internal/schema"
 )
 
-// WriteError writes an error response using upstream format.
+// WriteError writes an error response using upstream format. On /v2 it carries
+// the catalog code for status.
 func WriteError(w http.ResponseWriter, status int, message string) {
+	WriteErrorCode(w, status, defaultErrorCode(status), message)
+}
+
+// WriteErrorCode writes an error response with an explicit catalog code, which
+// only /v2 reports.
+func WriteErrorCode(w http.ResponseWriter, status int, code, message string) {
+	writeError(w, status, code, message, nil)
+}
+
+// WriteRetryError writes an error response for a request rejected under load.
+// wait is the estimated time until a retry could be admitted; it is reported,
+// rounded up to whole seconds and at least one, as Retry-After, and on /v2 as
+// estimated_wait_ms.
+func WriteRetryError(w http.ResponseWriter, status int, code, message string, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	waitMs := wait.Milliseconds()
+	writeError(w, status, code, message, &waitMs)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string, waitMs *int64) {
+	var body interface{} = schema.ErrorResponse{Detail: message}
+	if responseVersion(w) >= APIVersion2 {
+		body = V2ErrorResponse{Error: V2Error{Code: code, Message: message, EstimatedWaitMs: waitMs}}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(schema.ErrorResponse{Detail: message})
+	_ = json.NewEncoder(w).Encode(body)
 }
 
-// WriteJSON writes the data structure as JSON.
+// WriteJSON writes the data structure as JSON, wrapped in an envelope on /v2.
 func WriteJSON(w http.ResponseWriter, status int, data interface{}) {
+	if responseVersion(w) >= APIVersion2 {
+		data = V2Envelope{Data: data}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(data)
@@ -40,14 +82,41 @@ func WriteMsgpack(w http.ResponseWriter, status int, data interface{}) {
 	_, _ = w.Write(encoded)
 }
 
-// WriteAudio writes binary audio data with the appropriate content type.
+// WriteAudio writes binary audio data with the appropriate content type and an
+// X-Content-SHA256 header so clients can verify the download.
 func WriteAudio(w http.ResponseWriter, format string, data []byte) {
 	w.Header().Set("Content-Type", GetAudioContentType(format))
+	w.Header().Set("X-Content-SHA256", ContentSHA256(data))
 	w.Header().Set("Content-Disposition", "attachment; filename=audio."+strings.ToLower(format))
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write(data)
 }
 
+// WriteAudioFile writes a successful audio response from a file whose SHA-256
+// digest is known. Copying from the file lets the server use sendfile.
+func WriteAudioFile(w http.ResponseWriter, format, sha string, f *os.File) {
+	w.Header().Set("Content-Type", GetAudioContentType(format))
+	w.Header().Set("X-Content-SHA256", sha)
+	w.Header().Set("Content-Disposition", "attachment; filename=audio."+strings.ToLower(format))
+	if info, err := f.Stat(); err == nil {
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, f)
+}
+
+// ServeAudio writes stored audio like WriteAudio, but also answers Range,
+// If-Range and conditional requests, with the SHA-256 digest as ETag, so that
+// clients can resume an interrupted download.
+func ServeAudio(w http.ResponseWriter, r *http.Request, format string, data []byte, modtime time.Time) {
+	sha := ContentSHA256(data)
+	w.Header().Set("Content-Type", GetAudioContentType(format))
+	w.Header().Set("X-Content-SHA256", sha)
+	w.Header().Set("Content-Disposition", "attachment; filename=audio."+strings.ToLower(format))
+	w.Header().Set("ETag", `"`+sha+`"`)
+	http.ServeContent(w, r, "", modtime, bytes.NewReader(data))
+}
+
 // GetAudioContentType returns the MIME type for a given audio format.
 func GetAudioContentType(format string) string {
 	switch strings.ToLower(format) {
@@ -55,9 +124,23 @@ func GetAudioContentType(format string) string {
 		return "audio/wav"
 	case "mp3":
 		return "audio/mpeg"
+	case "opus", "ogg":
+		return "audio/ogg"
+	case "flac":
+		return "audio/flac"
+	case "ulaw":
+		return "audio/basic"
+	case "alaw":
+		return "audio/x-alaw-basic"
 	case "pcm":
 		return "audio/pcm"
 	default:
 		return "application/octet-stream"
 	}
 }
+
+// ContentSHA256 returns the hex-encoded SHA-256 digest of data.
+func ContentSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
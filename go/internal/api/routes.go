@@ -9,29 +9,129 @@ import (
 )
 
 // NewRouter constructs the HTTP router with middleware and routes.
-func NewRouter(cfg *config.Config, backendClient backend.Backend, logger zerolog.Logger) chi.Router {
+func NewRouter(cfg *config.Config, backendClient backend.Backend, logger zerolog.Logger, opts ...Option) chi.Router {
 	r := chi.NewRouter()
+	h := NewHandler(backendClient, cfg, logger, opts...)
 
+	r.Use(VersionMiddleware)
 	r.Use(RequestIDMiddleware)
-	r.Use(LoggingMiddleware(logger))
-	r.Use(CORSMiddleware)
-	if cfg.Auth.APIKey != "" {
-		r.Use(AuthMiddleware(cfg.Auth.APIKey))
+	r.Use(TracingMiddleware)
+	r.Use(MetricsMiddleware(h.metrics))
+	r.Use(AccessLogMiddleware(h.accessLog))
+	r.Use(CORSConfigMiddleware(cfg.Server.CORS))
+	r.Use(DeadlineMiddleware)
+	if cfg.Chaos.Enabled {
+		r.Use(ChaosMiddleware(cfg.Chaos, logger))
 	}
 
-	h := NewHandler(backendClient, cfg, logger)
+	// The API description is public so browsers can render it without a key.
+	r.Get("/openapi.json", h.HandleOpenAPI)
+	if cfg.Docs.Enabled {
+		r.Get("/docs", h.HandleDocs)
+	}
+
+	r.Group(func(r chi.Router) {
+		if cfg.Auth.Enabled() {
+			r.Use(keyAuthMiddleware(cfg.Auth, h.secrets, h.sharedRates()))
+		}
+		h.registerRoutes(r, cfg, logger)
+	})
+
+	return r
+}
 
-	r.Get("/v1/health", h.HandleHealthGet)
-	r.Post("/v1/health", h.HandleHealthPost)
+// registerRoutes registers the authenticated routes.
+func (h *Handler) registerRoutes(r chi.Router, cfg *config.Config, logger zerolog.Logger) {
+	r.Get("/metrics", h.HandleMetrics)
 
-	r.Post("/v1/tts", h.HandleTTS)
+	// Routes shared by every API version; versions differ in response format.
+	common := func(r chi.Router) {
+		r.Get("/health", h.HandleHealthGet)
+		r.Post("/health", h.HandleHealthPost)
+		r.Get("/stats", h.HandleStats)
 
-	r.Post("/v1/vqgan/encode", h.HandleVQGANEncode)
-	r.Post("/v1/vqgan/decode", h.HandleVQGANDecode)
+		r.Group(func(r chi.Router) {
+			r.Use(DrainMiddleware(h.drain))
+			if cfg.Limits.LoadShedding() {
+				r.Use(LoadShedMiddleware(cfg.Limits, logger))
+			}
+			r.Post("/tts", h.HandleTTS)
+			if h.jobs != nil {
+				r.Post("/tts/jobs", h.HandleCreateTTSJob)
+			}
+		})
+		r.Post("/tts/plan", h.HandleTTSPlan)
+		r.Post("/tts/estimate", h.HandleTTSEstimate)
+		if h.jobs != nil {
+			r.Get("/jobs/{id}", h.HandleGetJob)
+			r.Get("/jobs/{id}/result", h.HandleGetJobResult)
+		}
 
-	r.Post("/v1/references/add", h.HandleAddReference)
-	r.Get("/v1/references", h.HandleListReferences)
-	r.Delete("/v1/references/{id}", h.HandleDeleteReference)
+		r.Post("/vqgan/encode", h.HandleVQGANEncode)
+		r.Post("/vqgan/decode", h.HandleVQGANDecode)
 
-	return r
+		r.Delete("/references/{id}", h.HandleDeleteReference)
+		r.Delete("/references", h.HandleBulkDeleteReferences)
+		r.Post("/references/delete", h.HandleBulkDeleteReferences)
+		r.Get("/references/aliases", h.HandleListAliases)
+		r.Post("/references/aliases", h.HandleSetAlias)
+		r.Delete("/references/aliases/{alias}", h.HandleDeleteAlias)
+		r.Get("/references/{id}", h.HandleGetReference)
+		r.Patch("/references/{id}", h.HandleUpdateReference)
+		r.Put("/references/{id}/lock", h.HandleLockReference)
+		r.Delete("/references/{id}/lock", h.HandleUnlockReference)
+		if h.archive != nil {
+			r.Get("/references/export", h.HandleExportReferences)
+			r.Post("/references/import", h.HandleImportReferences)
+			r.Get("/references/{id}/audio", h.HandleGetReferenceAudio)
+		}
+
+		if h.uploads != nil {
+			r.Post("/references/uploads", h.HandleCreateUpload)
+			r.Head("/references/uploads/{upload_id}", h.HandleGetUpload)
+			r.Get("/references/uploads/{upload_id}", h.HandleGetUpload)
+			r.Patch("/references/uploads/{upload_id}", h.HandlePatchUpload)
+			r.Post("/references/uploads/{upload_id}/complete", h.HandleCompleteUpload)
+			r.Delete("/references/uploads/{upload_id}", h.HandleDeleteUpload)
+		}
+
+		r.Get("/voices", h.HandleListVoices)
+		r.Get("/voices/{name}", h.HandleGetVoice)
+		r.Put("/voices/{name}", h.HandleSetVoice)
+		r.Delete("/voices/{name}", h.HandleDeleteVoice)
+		r.Get("/presets", h.HandleListPresets)
+		r.Get("/usage", h.HandleUsage)
+	}
+
+	// The /v1 routes the Python server also serves (health, tts, vqgan/encode,
+	// vqgan/decode, references/add, GET references and DELETE references/{id})
+	// must keep their paths and response shapes. Go-only routes are added here
+	// too and use the same response format.
+	r.Route("/v1", func(r chi.Router) {
+		common(r)
+		r.Post("/references/add", h.HandleAddReference)
+		r.Get("/references", h.HandleListReferences)
+	})
+
+	r.Route("/v2", func(r chi.Router) {
+		common(r)
+		r.Post("/references", h.HandleAddReference)
+		r.Get("/references", h.HandleListReferencesV2)
+	})
+
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(AdminMiddleware)
+		r.Get("/backends", h.HandleListBackends)
+		r.Put("/backends/{name}/weight", h.HandleSetBackendWeight)
+		r.Get("/limiter", h.HandleLimiterStatus)
+		r.Put("/limiter", h.HandleSetLimiter)
+		r.Get("/config", h.HandleGetConfig)
+		r.Get("/cache", h.HandleCacheStatus)
+		r.Delete("/cache", h.HandleCacheInvalidate)
+		r.Get("/usage", h.HandleListUsage)
+		r.Get("/diagnostics", h.HandleDiagnostics)
+		r.Get("/status", h.HandleAdminStatus)
+		r.Put("/maintenance", h.HandleSetMaintenance)
+		r.Post("/drain", h.HandleDrain)
+	})
 }
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+	"github.com/fish-speech-go/fish-speech-go/internal/shared"
+)
+
+// sharedRates returns the store that API key rate limits are counted in, nil
+// to count them in this server alone.
+func (h *Handler) sharedRates() *shared.Store {
+	if h.shared == nil || !h.config.Redis.RateLimits {
+		return nil
+	}
+	return h.shared
+}
+
+// listReferences returns the backend's reference list, from Redis when
+// another server listed it within redis.reference_list_ttl. Redis errors fall
+// back to asking the backend.
+func (h *Handler) listReferences(ctx context.Context) (*schema.ListReferencesResponse, error) {
+	ttl := h.config.Redis.ReferenceListTTL
+	if h.shared != nil && ttl > 0 {
+		resp, err := h.shared.References()
+		if err != nil {
+			h.logger.Warn().Err(err).Msg("Shared reference list unavailable")
+		} else if resp != nil {
+			return resp, nil
+		}
+	}
+
+	start := time.Now()
+	resp, err := h.backend.ListReferences(ctx)
+	h.logBackendCall(ctx, OpListReferences, start, err)
+	if err != nil {
+		return nil, err
+	}
+	if h.shared != nil && ttl > 0 {
+		if err := h.shared.SetReferences(resp, ttl); err != nil {
+			h.logger.Warn().Err(err).Msg("Failed to share reference list")
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+func TestKeyAuth_Signing(t *testing.T) {
+	secret := []byte("webhook-secret-0123")
+	cfg := config.AuthConfig{Signing: config.SigningConfig{
+		Keys:   []config.SigningKeyConfig{{ID: "webhooks", Secret: string(secret), Namespace: "hooks"}},
+		Window: 5 * time.Minute,
+	}}
+
+	var principal *Principal
+	var body string
+	handler := KeyAuthMiddleware(cfg, nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal = PrincipalFromContext(r.Context())
+			raw, _ := io.ReadAll(r.Body)
+			body = string(raw)
+		}))
+
+	do := func(payload string, ts time.Time, sign func(ts string) string) int {
+		req := httptest.NewRequest(http.MethodPost, "/v1/tts?format=wav", strings.NewReader(payload))
+		stamp := strconv.FormatInt(ts.Unix(), 10)
+		req.Header.Set(HeaderTimestamp, stamp)
+		req.Header.Set(HeaderSignature, sign(stamp))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+	valid := func(payload string) func(string) string {
+		return func(ts string) string {
+			return SignRequest(secret, http.MethodPost, "/v1/tts?format=wav", ts, []byte(payload))
+		}
+	}
+
+	now := time.Now()
+	assert.Equal(t, http.StatusOK, do(`{"text":"hi"}`, now, valid(`{"text":"hi"}`)))
+	assert.Equal(t, "webhooks", principal.Username)
+	assert.Equal(t, "hooks", principal.Namespace)
+	assert.Equal(t, `{"text":"hi"}`, body, "the body is still readable")
+
+	assert.Equal(t, http.StatusUnauthorized, do(`{"text":"hi"}`, now, valid(`{"text":"hi"}`)), "replayed signature")
+	assert.Equal(t, http.StatusUnauthorized, do(`{"text":"tampered"}`, now.Add(time.Second), valid(`{"text":"hi"}`)))
+	assert.Equal(t, http.StatusUnauthorized, do(`{}`, now.Add(-10*time.Minute), valid(`{}`)), "stale timestamp")
+	assert.Equal(t, http.StatusUnauthorized, do(`{}`, now.Add(2*time.Second), func(ts string) string {
+		return SignRequest([]byte("other-secret-0123456"), http.MethodPost, "/v1/tts?format=wav", ts, []byte(`{}`))
+	}))
+
+	// Unsigned requests are rejected when no other credentials are configured.
+	req := httptest.NewRequest(http.MethodGet, "/v1/health", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestKeyAuth_SigningLimits(t *testing.T) {
+	secret := []byte("webhook-secret-0123")
+	cfg := config.AuthConfig{
+		Keys: []config.APIKeyConfig{{Name: "webhooks", Key: "webhooks-bearer-key", Quota: 1}},
+		Signing: config.SigningConfig{
+			Keys:         []config.SigningKeyConfig{{ID: "webhooks", Secret: string(secret)}},
+			Window:       5 * time.Minute,
+			MaxBodyBytes: 16,
+		},
+	}
+	handler := KeyAuthMiddleware(cfg, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}))
+
+	do := func(payload string, ts time.Time) *httptest.ResponseRecorder {
+		stamp := strconv.FormatInt(ts.Unix(), 10)
+		req := httptest.NewRequest(http.MethodPost, "/v1/tts", strings.NewReader(payload))
+		req.Header.Set(HeaderTimestamp, stamp)
+		req.Header.Set(HeaderSignature, SignRequest(secret, http.MethodPost, "/v1/tts", stamp, []byte(payload)))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	now := time.Now()
+	w := do(`{"text":"a longer request body"}`, now)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), "16 byte limit")
+
+	// The signing key shares the quota of the API key of the same name.
+	assert.Equal(t, http.StatusOK, do(`{"text":"hi"}`, now).Code)
+	assert.Equal(t, http.StatusTooManyRequests, do(`{"text":"hi"}`, now.Add(time.Second)).Code)
+}
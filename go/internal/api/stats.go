@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/backend"
+)
+
+// StatsResponse reports the backend's own runtime statistics alongside the
+// traffic the server has routed to it.
+type StatsResponse struct {
+	// Backend is the document served by the backend's stats endpoint, passed
+	// through unchanged. With several backends it is keyed by target name.
+	Backend      json.RawMessage `json:"backend,omitempty"`
+	BackendError string          `json:"backend_error,omitempty"`
+	// FetchedAt is when Backend was read; it is cached for backend.stats_cache_ttl.
+	FetchedAt time.Time             `json:"fetched_at"`
+	Targets   []BackendTargetStatus `json:"targets,omitempty"`
+}
+
+// statsCache keeps the last backend runtime statistics, or the error reading
+// them, so that health checks and dashboards do not poll the backend directly.
+type statsCache struct {
+	ttl time.Duration
+
+	// mu is held while fetching, so concurrent callers share one request.
+	mu        sync.Mutex
+	stats     json.RawMessage
+	err       error
+	fetchedAt time.Time
+}
+
+func (c *statsCache) get(ctx context.Context, reporter backend.RuntimeStatsReporter) (json.RawMessage, time.Time, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.fetchedAt.IsZero() && time.Since(c.fetchedAt) < c.ttl {
+		return c.stats, c.fetchedAt, c.err
+	}
+	c.stats, c.err = reporter.RuntimeStats(ctx)
+	c.fetchedAt = time.Now()
+	return c.stats, c.fetchedAt, c.err
+}
+
+// backendRuntimeStats returns the backend's cached runtime statistics, or
+// backend.ErrStatsUnavailable when it does not report any.
+func (h *Handler) backendRuntimeStats(ctx context.Context) (json.RawMessage, time.Time, error) {
+	reporter, ok := h.backend.(backend.RuntimeStatsReporter)
+	if !ok {
+		return nil, time.Now(), backend.ErrStatsUnavailable
+	}
+	return h.stats.get(ctx, reporter)
+}
+
+// HandleStats returns the backend's runtime statistics and per-target traffic.
+func (h *Handler) HandleStats(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	stats, fetchedAt, err := h.backendRuntimeStats(r.Context())
+	resp := StatsResponse{Backend: stats, FetchedAt: fetchedAt}
+	if !errors.Is(err, backend.ErrStatsUnavailable) {
+		h.backendCallEvent(r.Context(), zerolog.WarnLevel, OpRuntimeStats, start, err)
+	}
+	if err != nil {
+		resp.BackendError = err.Error()
+	}
+	if router, ok := h.backend.(*backend.Router); ok {
+		resp.Targets = backendStatuses(router)
+	}
+
+	WriteJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/backend"
+)
+
+// statsBackend is a mock backend that reports runtime stats.
+type statsBackend struct {
+	mockBackend
+	calls int
+	stats json.RawMessage
+	err   error
+}
+
+func (b *statsBackend) RuntimeStats(ctx context.Context) (json.RawMessage, error) {
+	b.calls++
+	return b.stats, b.err
+}
+
+func TestStats_CachesBackendStats(t *testing.T) {
+	b := &statsBackend{stats: json.RawMessage(`{"gpu_memory_used":1024}`)}
+	cfg := testConfig()
+	cfg.Backend.StatsCacheTTL = time.Minute
+	router := NewRouter(cfg, b, testLogger())
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/v1/stats", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp StatsResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.JSONEq(t, `{"gpu_memory_used":1024}`, string(resp.Backend))
+		assert.Empty(t, resp.BackendError)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/health?detailed=true", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Contains(t, w.Body.String(), `"stats":{"gpu_memory_used":1024}`)
+
+	assert.Equal(t, 1, b.calls)
+}
+
+func TestStats_Unavailable(t *testing.T) {
+	router := NewRouter(testConfig(), &statsBackend{err: backend.ErrStatsUnavailable}, testLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/stats", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), backend.ErrStatsUnavailable.Error())
+	assert.NotContains(t, w.Body.String(), `"backend":`)
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/health?detailed=true", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.NotContains(t, w.Body.String(), `"stats"`)
+}
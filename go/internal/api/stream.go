@@ -0,0 +1,210 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/audio"
+	"github.com/fish-speech-go/fish-speech-go/internal/backend"
+	"github.com/fish-speech-go/fish-speech-go/internal/metrics"
+)
+
+// keepAliveSilence is the length of silence written per keep-alive tick.
+const keepAliveSilence = 50 * time.Millisecond
+
+// maxWAVHeaderSize bounds how much of a stream is buffered looking for the WAV header.
+const maxWAVHeaderSize = 4096
+
+// errClientWrite wraps failures writing a stream to the client.
+var errClientWrite = errors.New("writing to client")
+
+// copyStream relays backend audio to the client until the backend finishes and
+// returns the number of bytes written. Each
+// chunk resets the idle watchdog and extends the write deadline. With
+// StreamKeepAlive set, silent WAV frames are written whenever the backend has
+// been quiet that long, so proxies and clients keep the connection open.
+func (h *Handler) copyStream(ctx context.Context, w http.ResponseWriter, flusher http.Flusher, stream io.Reader, watchdog *time.Timer) (int64, error) {
+	idle := h.config.Server.StreamIdleTimeout
+	rc := http.NewResponseController(w)
+	if watchdog == nil {
+		// Unsupported by some writers (e.g. in tests), which have no deadline to lift.
+		_ = rc.SetWriteDeadline(time.Time{})
+	}
+
+	var written int64
+	var seq int
+	write := func(p []byte, keepAlive bool) error {
+		if watchdog != nil {
+			_ = rc.SetWriteDeadline(time.Now().Add(idle))
+		}
+		n, err := w.Write(p)
+		written += int64(n)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errClientWrite, err)
+		}
+		flusher.Flush()
+		h.event(ctx, zerolog.DebugLevel, EventTTSStreamChunk).
+			Int("chunk", seq).
+			Int("bytes", n).
+			Int64("offset", written-int64(n)).
+			Bool("keepalive", keepAlive).
+			Msg("TTS stream chunk")
+		seq++
+		return nil
+	}
+
+	chunks := make(chan []byte)
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			buf := make([]byte, 4096)
+			n, err := stream.Read(buf)
+			if n > 0 {
+				select {
+				case chunks <- buf[:n]:
+				case <-ctx.Done():
+					readErr <- ctx.Err()
+					return
+				}
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	var keepAlive <-chan time.Time
+	interval := h.config.Server.StreamKeepAlive
+	var ticker *time.Ticker
+	if interval > 0 {
+		ticker = time.NewTicker(interval)
+		defer ticker.Stop()
+		keepAlive = ticker.C
+	}
+	var tracker wavTracker
+
+	for {
+		select {
+		case chunk := <-chunks:
+			if watchdog != nil {
+				watchdog.Reset(idle)
+			}
+			if ticker != nil {
+				ticker.Reset(interval)
+			}
+			tracker.observe(chunk)
+			if err := write(chunk, false); err != nil {
+				return written, err
+			}
+		case <-keepAlive:
+			if silence := tracker.silence(keepAliveSilence); silence != nil {
+				if err := write(silence, true); err != nil {
+					return written, err
+				}
+			}
+		case err := <-readErr:
+			if errors.Is(err, io.EOF) {
+				return written, nil
+			}
+			return written, err
+		}
+	}
+}
+
+// recordStream records the outcome of a streaming TTS request. ctx is the
+// client's request context, err the error that ended the stream, and stalled
+// whether the idle watchdog fired.
+func (h *Handler) recordStream(ctx context.Context, err error, stalled bool, written int64) {
+	outcome := streamOutcome(ctx, err, stalled)
+	h.metrics.StreamsTotal.WithLabelValues(outcome).Inc()
+	h.metrics.StreamBytes.WithLabelValues(outcome).Observe(float64(written))
+}
+
+func streamOutcome(ctx context.Context, err error, stalled bool) string {
+	switch {
+	case err == nil:
+		return metrics.StreamCompleted
+	case stalled, errors.Is(ctx.Err(), context.DeadlineExceeded), errors.Is(err, backend.ErrBackendTimeout):
+		return metrics.StreamTimeout
+	case errors.Is(err, errClientWrite), errors.Is(ctx.Err(), context.Canceled):
+		return metrics.StreamClientAborted
+	default:
+		return metrics.StreamBackendError
+	}
+}
+
+// wavTracker follows a streamed WAV response so that silence can be spliced in
+// without breaking it: only after the header, only on sample frame boundaries,
+// and only when the header leaves the data size open.
+type wavTracker struct {
+	head    []byte
+	header  *audio.WAVHeader
+	written int64
+	invalid bool
+}
+
+func (t *wavTracker) observe(p []byte) {
+	t.written += int64(len(p))
+	if t.header != nil || t.invalid {
+		return
+	}
+
+	t.head = append(t.head, p...)
+	if h, err := audio.ParseWAVHeader(t.head); err == nil {
+		t.header = h
+		t.head = nil
+		if (h.DataSize != 0 && h.DataSize != math.MaxUint32) || h.BlockAlign() <= 0 {
+			t.invalid = true
+		}
+	} else if len(t.head) > maxWAVHeaderSize {
+		t.invalid = true
+		t.head = nil
+	}
+}
+
+// duration returns the length of the audio observed so far, or false before
+// a header was parsed.
+func (t *wavTracker) duration() (time.Duration, bool) {
+	if t.header == nil || t.header.SampleRate == 0 || t.header.BlockAlign() <= 0 {
+		return 0, false
+	}
+	frames := (t.written - int64(t.header.DataOffset)) / int64(t.header.BlockAlign())
+	if frames < 0 {
+		frames = 0
+	}
+	return time.Duration(frames) * time.Second / time.Duration(t.header.SampleRate), true
+}
+
+// silence returns d worth of silent sample frames, or nil when they cannot be
+// inserted at the current position. The returned bytes count as written.
+func (t *wavTracker) silence(d time.Duration) []byte {
+	if t.header == nil || t.invalid || t.written < int64(t.header.DataOffset) {
+		return nil
+	}
+	align := t.header.BlockAlign()
+	if (t.written-int64(t.header.DataOffset))%int64(align) != 0 {
+		return nil
+	}
+
+	frames := int(int64(t.header.SampleRate) * int64(d) / int64(time.Second))
+	if frames < 1 {
+		frames = 1
+	}
+	buf := make([]byte, frames*align)
+	if t.header.BitsPerSample == 8 {
+		// 8-bit PCM is unsigned, centred on 128.
+		for i := range buf {
+			buf[i] = 0x80
+		}
+	}
+	t.written += int64(len(buf))
+	return buf
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/audio"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+// slowStreamBackend streams prefix and then chunks with a pause before each
+// one, then stalls until the request context ends if stall is set.
+type slowStreamBackend struct {
+	mockBackend
+	prefix []byte
+	chunks int
+	pause  time.Duration
+	stall  bool
+}
+
+func (b *slowStreamBackend) TTSStream(ctx context.Context, req *schema.ServeTTSRequest) (io.ReadCloser, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		if len(b.prefix) > 0 {
+			if _, err := pw.Write(b.prefix); err != nil {
+				return
+			}
+		}
+		for i := 0; i < b.chunks; i++ {
+			select {
+			case <-time.After(b.pause):
+			case <-ctx.Done():
+				pw.CloseWithError(ctx.Err())
+				return
+			}
+			if _, err := pw.Write([]byte("chunk")); err != nil {
+				return
+			}
+		}
+		if b.stall {
+			<-ctx.Done()
+			pw.CloseWithError(ctx.Err())
+			return
+		}
+		pw.Close()
+	}()
+	return pr, nil
+}
+
+func streamThroughServer(t *testing.T, b *slowStreamBackend, writeTimeout, idle, keepAlive time.Duration) []byte {
+	t.Helper()
+
+	cfg := testConfig()
+	cfg.Server.StreamIdleTimeout = idle
+	cfg.Server.StreamKeepAlive = keepAlive
+	server := httptest.NewUnstartedServer(NewRouter(cfg, b, testLogger()))
+	server.Config.WriteTimeout = writeTimeout
+	server.Start()
+	defer server.Close()
+
+	body, _ := json.Marshal(schema.ServeTTSRequest{Text: "Hello", Format: "wav", Streaming: true})
+	resp, err := http.Post(server.URL+"/v1/tts", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	data, _ := io.ReadAll(resp.Body)
+	return data
+}
+
+func TestStreamingTTS_OutlivesWriteTimeout(t *testing.T) {
+	b := &slowStreamBackend{chunks: 5, pause: 60 * time.Millisecond}
+
+	data := streamThroughServer(t, b, 100*time.Millisecond, time.Second, 0)
+
+	assert.Equal(t, bytes.Repeat([]byte("chunk"), 5), data)
+}
+
+func TestStreamingTTS_AbortsWhenIdle(t *testing.T) {
+	b := &slowStreamBackend{chunks: 2, pause: 10 * time.Millisecond, stall: true}
+
+	start := time.Now()
+	data := streamThroughServer(t, b, time.Minute, 200*time.Millisecond, 0)
+
+	assert.Equal(t, bytes.Repeat([]byte("chunk"), 2), data)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestStreamingTTS_KeepAliveSilence(t *testing.T) {
+	header := audio.WAVHeader16(8000, 1, 0)
+	// "chunk" is 5 bytes, so the second one leaves the stream mid-frame.
+	b := &slowStreamBackend{prefix: header, chunks: 2, pause: 150 * time.Millisecond}
+
+	data := streamThroughServer(t, b, time.Minute, time.Second, 50*time.Millisecond)
+
+	require.True(t, bytes.HasPrefix(data, header))
+	body := data[len(header):]
+	first := bytes.Index(body, []byte("chunk"))
+	require.Greater(t, first, 0, "silence expected before the first chunk")
+	assert.Equal(t, 0, first%2, "silence must be whole 16-bit frames")
+	assert.Equal(t, make([]byte, first), body[:first])
+	assert.True(t, bytes.HasSuffix(body, []byte("chunkchunk")), "no silence once misaligned")
+}
+
+func TestWAVTracker_Silence(t *testing.T) {
+	var tracker wavTracker
+	assert.Nil(t, tracker.silence(keepAliveSilence), "no header yet")
+
+	header := audio.WAVHeader16(16000, 2, 0)
+	tracker.observe(header[:10])
+	assert.Nil(t, tracker.silence(keepAliveSilence))
+	tracker.observe(header[10:])
+
+	silence := tracker.silence(10 * time.Millisecond)
+	assert.Len(t, silence, 160*4)
+
+	tracker.observe([]byte{1, 2})
+	assert.Nil(t, tracker.silence(keepAliveSilence), "mid-frame")
+	tracker.observe([]byte{3, 4})
+	assert.NotNil(t, tracker.silence(keepAliveSilence))
+
+	var sized wavTracker
+	sized.observe(audio.WAVHeader16(16000, 1, 3200))
+	assert.Nil(t, sized.silence(keepAliveSilence), "fixed data size")
+}
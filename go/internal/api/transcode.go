@@ -0,0 +1,120 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/audio"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+// backendFormats are the formats the backend encodes itself.
+var backendFormats = map[string]bool{"wav": true, "pcm": true, "mp3": true}
+
+// transcodes reports whether fish-server encodes the response to req itself,
+// from WAV synthesized by the backend: for formats the backend lacks or is
+// configured not to produce, and for streamed, post-processed or tuned
+// responses the backend cannot produce.
+func (h *Handler) transcodes(req *schema.ServeTTSRequest) bool {
+	if req.Format == "wav" || !audio.CanEncode(req.Format) {
+		return false
+	}
+	return !backendFormats[req.Format] || req.Streaming || req.HasPostProcessing() || req.HasEncodingOptions() ||
+		slices.Contains(h.config.Audio.Transcode, req.Format)
+}
+
+// checkFormat returns an error when neither the backend nor this build can
+// produce the format of req.
+func checkFormat(req *schema.ServeTTSRequest) error {
+	if backendFormats[req.Format] || audio.CanEncode(req.Format) {
+		return nil
+	}
+	return fmt.Errorf("%s output is not available: fish-server was built without its encoder", req.Format)
+}
+
+// checkStreamingFormat returns an error when req streams a format other than
+// WAV that fish-server cannot encode itself; the backend only streams WAV.
+func checkStreamingFormat(req *schema.ServeTTSRequest) error {
+	if req.Streaming && req.Format != "wav" && !audio.CanEncode(req.Format) {
+		return errors.New("Streaming only supports WAV format")
+	}
+	return nil
+}
+
+// validateEncoding checks the bit rate and quality of a TTS request against
+// its format.
+func validateEncoding(req *schema.ServeTTSRequest) error {
+	if !req.HasEncodingOptions() {
+		return nil
+	}
+	if req.Format != audio.FormatMP3 && req.Format != audio.FormatOpus {
+		return errors.New("bitrate and quality are only supported for mp3 and opus output")
+	}
+	if req.Bitrate != 0 {
+		if err := audio.CheckBitrate(req.Format, req.Bitrate); err != nil {
+			return fmt.Errorf("bitrate: %w", err)
+		}
+	}
+	if req.Quality != nil {
+		if *req.Quality < 0 || *req.Quality > audio.MaxQuality {
+			return fmt.Errorf("quality must be between 0 and %d", audio.MaxQuality)
+		}
+		if req.Format == audio.FormatMP3 && req.Bitrate != 0 {
+			return errors.New("bitrate and quality cannot be combined for mp3: quality selects a variable bit rate")
+		}
+	}
+	if !audio.CanEncode(req.Format) {
+		return fmt.Errorf("bitrate and quality need fish-server built with its %s encoder", req.Format)
+	}
+	return nil
+}
+
+// backendRequest returns the request to send the backend for req: a copy
+// asking for WAV when fish-server encodes the response.
+func (h *Handler) backendRequest(req *schema.ServeTTSRequest) *schema.ServeTTSRequest {
+	if !h.transcodes(req) {
+		return req
+	}
+	wav := *req
+	wav.Format = "wav"
+	return &wav
+}
+
+// finishAudio applies the post-processing of req to audio the backend
+// synthesized for backendRequest(req), and encodes it in the requested
+// format. It returns the audio and its format.
+func (h *Handler) finishAudio(req *schema.ServeTTSRequest, data []byte, format string) ([]byte, string, error) {
+	if !h.transcodes(req) {
+		if !req.HasPostProcessing() {
+			return data, format, nil
+		}
+		data, err := audio.ProcessWAV(data, processOptions(req))
+		return data, format, err
+	}
+
+	pcm, err := audio.DecodeWAV(data)
+	if err != nil {
+		return nil, "", err
+	}
+	encoded, err := audio.Encode(req.Format, audio.Process(pcm, processOptions(req)), h.encodeOptions(req))
+	if err != nil {
+		return nil, "", err
+	}
+	return encoded, req.Format, nil
+}
+
+// encodeOptions returns the bit rate and quality to encode the response to
+// req with: those of the request, or the configured bit rate of its format.
+func (h *Handler) encodeOptions(req *schema.ServeTTSRequest) audio.EncodeOptions {
+	opts := audio.EncodeOptions{Bitrate: req.Bitrate, Quality: req.Quality}
+	if opts.Bitrate == 0 {
+		switch req.Format {
+		case audio.FormatMP3:
+			opts.Bitrate = h.config.Audio.MP3Bitrate
+		case audio.FormatOpus:
+			opts.Bitrate = h.config.Audio.OpusBitrate
+		}
+	}
+	return opts
+}
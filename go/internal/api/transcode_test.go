@@ -0,0 +1,162 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/audio"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+// pcm16Encoder stands in for the native Opus encoder: it writes 16-bit PCM
+// and ends the stream with a marker.
+type pcm16Encoder struct{}
+
+// lastEncodeOptions records the options the last pcm16Encoder was opened with.
+var lastEncodeOptions audio.EncodeOptions
+
+func (pcm16Encoder) Encode(samples []float32) ([]byte, error) {
+	out := make([]byte, len(samples)*2)
+	audio.PutPCM16(out, samples)
+	return out, nil
+}
+
+func (pcm16Encoder) Flush() ([]byte, error) { return []byte("END"), nil }
+func (pcm16Encoder) Close()                 {}
+
+func init() {
+	audio.RegisterEncoder(audio.FormatOpus, func(opts audio.EncodeOptions) (audio.Encoder, error) {
+		lastEncodeOptions = opts
+		return pcm16Encoder{}, nil
+	})
+}
+
+func TestTTS_Transcode(t *testing.T) {
+	wav := audio.EncodeWAV(&audio.PCM{SampleRate: 8000, Channels: 1, Samples: []float32{0, 0.5, -0.5}})
+	encoded := append(append([]byte(nil), wav[44:]...), "END"...)
+	mock := &mockBackend{ttsResponse: wav}
+	h := NewHandler(mock, testConfig(), testLogger())
+
+	w := postTTS(t, h, schema.ServeTTSRequest{Text: "Hello", Format: "opus"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "wav", mock.lastTTSReq.Format, "the backend synthesizes WAV")
+	assert.Equal(t, encoded, w.Body.Bytes())
+	assert.Equal(t, "audio/ogg", w.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=audio.opus", w.Header().Get("Content-Disposition"))
+
+	w = postTTS(t, h, schema.ServeTTSRequest{Text: "Hello", Format: "opus", GainDB: -6})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Len(t, w.Body.Bytes(), len(encoded), "post-processing applies before encoding")
+
+	// Formats the backend produces are left to it unless configured.
+	w = postTTS(t, h, schema.ServeTTSRequest{Text: "Hello", Format: "mp3"})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "mp3", mock.lastTTSReq.Format)
+
+	w = postTTS(t, h, schema.ServeTTSRequest{Text: "Hello", Format: "aac"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestTTS_FLAC(t *testing.T) {
+	pcm := &audio.PCM{SampleRate: 8000, Channels: 1, Samples: make([]float32, 4000)}
+	mock := &mockBackend{ttsResponse: audio.EncodeWAV(pcm)}
+	h := NewHandler(mock, testConfig(), testLogger())
+
+	w := postTTS(t, h, schema.ServeTTSRequest{Text: "Hello", Format: "flac"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "wav", mock.lastTTSReq.Format)
+	assert.Equal(t, "audio/flac", w.Header().Get("Content-Type"))
+	assert.Equal(t, audio.FormatFLAC, audio.DetectFormat(w.Body.Bytes()))
+	d, ok := audio.Duration(w.Body.Bytes())
+	assert.True(t, ok)
+	assert.Equal(t, pcm.Duration(), d)
+}
+
+func TestTTS_EncodingOptions(t *testing.T) {
+	wav := audio.EncodeWAV(&audio.PCM{SampleRate: 8000, Channels: 1, Samples: []float32{0, 0.5, -0.5}})
+	mock := &mockBackend{ttsResponse: wav}
+	cfg := testConfig()
+	cfg.Audio.OpusBitrate = 24
+	h := NewHandler(mock, cfg, testLogger())
+
+	w := postTTS(t, h, schema.ServeTTSRequest{Text: "Hello", Format: "opus"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, audio.EncodeOptions{SampleRate: 8000, Channels: 1, Bitrate: 24}, lastEncodeOptions)
+
+	quality := 10
+	w = postTTS(t, h, schema.ServeTTSRequest{Text: "Hello", Format: "opus", Bitrate: 96, Quality: &quality})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, 96, lastEncodeOptions.Bitrate)
+	assert.Equal(t, &quality, lastEncodeOptions.Quality)
+
+	eleven, five := 11, 5
+	tests := []struct {
+		name string
+		req  schema.ServeTTSRequest
+		want string
+	}{
+		{"bitrate out of range", schema.ServeTTSRequest{Format: "opus", Bitrate: 600}, "bitrate: opus bit rates are 6 to 510 kbit/s"},
+		{"quality out of range", schema.ServeTTSRequest{Format: "opus", Quality: &eleven}, "quality must be between 0 and 10"},
+		{"lossless format", schema.ServeTTSRequest{Format: "flac", Bitrate: 128}, "bitrate and quality are only supported for mp3 and opus output"},
+		{"wav", schema.ServeTTSRequest{Format: "wav", Quality: &five}, "bitrate and quality are only supported for mp3 and opus output"},
+		{"mp3 bitrate", schema.ServeTTSRequest{Format: "mp3", Bitrate: 100}, "bitrate: mp3 bit rates are 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 192, 224, 256, 320 kbit/s"},
+		{"mp3 bitrate and quality", schema.ServeTTSRequest{Format: "mp3", Bitrate: 128, Quality: &five}, "bitrate and quality cannot be combined for mp3: quality selects a variable bit rate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.Text = "Hello"
+			w := postTTS(t, h, tt.req)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), tt.want)
+		})
+	}
+
+	if !audio.CanEncode(audio.FormatMP3) {
+		w = postTTS(t, h, schema.ServeTTSRequest{Text: "Hello", Format: "mp3", Bitrate: 192})
+		assert.Equal(t, http.StatusBadRequest, w.Code, "the backend cannot honor a bit rate")
+	}
+}
+
+func TestStreamingTTS_Transcode(t *testing.T) {
+	pcm := &audio.PCM{SampleRate: 8000, Channels: 1, Samples: make([]float32, 800)}
+	stream := append(audio.WAVHeader16(8000, 1, 0), audio.EncodeWAV(pcm)[44:]...)
+	h := NewHandler(&mockBackend{ttsResponse: stream}, testConfig(), testLogger())
+
+	w := postTTS(t, h, schema.ServeTTSRequest{Text: "Hello", Format: "opus", Streaming: true})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "audio/ogg", w.Header().Get("Content-Type"))
+	assert.Equal(t, append(audio.EncodeWAV(pcm)[44:], "END"...), w.Body.Bytes())
+
+	if !audio.CanEncode(audio.FormatMP3) {
+		w = postTTS(t, h, schema.ServeTTSRequest{Text: "Hello", Format: "mp3", Streaming: true})
+		assert.Equal(t, http.StatusBadRequest, w.Code, "without an encoder only WAV is streamed")
+		assert.Contains(t, w.Body.String(), "Streaming only supports WAV format")
+	}
+}
+
+func TestTTS_Telephony(t *testing.T) {
+	pcm := &audio.PCM{SampleRate: 24000, Channels: 1, Samples: make([]float32, 2400)}
+	mock := &mockBackend{ttsResponse: audio.EncodeWAV(pcm)}
+	h := NewHandler(mock, testConfig(), testLogger())
+
+	w := postTTS(t, h, schema.ServeTTSRequest{Text: "Hello", Format: "ulaw"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "wav", mock.lastTTSReq.Format)
+	assert.Equal(t, "audio/basic", w.Header().Get("Content-Type"))
+	assert.Equal(t, bytes.Repeat([]byte{0xFF}, 800), w.Body.Bytes(), "100 ms of μ-law silence")
+
+	stream := append(audio.WAVHeader16(24000, 1, 0), audio.EncodeWAV(pcm)[44:]...)
+	h = NewHandler(&mockBackend{ttsResponse: stream}, testConfig(), testLogger())
+	w = postTTS(t, h, schema.ServeTTSRequest{Text: "Hello", Format: "alaw", Streaming: true})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "audio/x-alaw-basic", w.Header().Get("Content-Type"))
+	assert.Equal(t, bytes.Repeat([]byte{0xD5}, 800), w.Body.Bytes(), "100 ms of A-law silence")
+
+	w = postTTS(t, h, schema.ServeTTSRequest{Text: "Hello", Format: "ulaw", SampleRate: 16000})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "ulaw output is 8000 Hz mono")
+}
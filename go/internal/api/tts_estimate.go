@@ -0,0 +1,77 @@
+package api
+
+import (
+	"math"
+	"net/http"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+	"github.com/fish-speech-go/fish-speech-go/internal/text"
+)
+
+// semanticTokensPerSecond is the frame rate of the backend's VQGAN codec
+// (44.1 kHz audio with a hop length of 2048 samples).
+const semanticTokensPerSecond = 44100.0 / 2048.0
+
+// TTSEstimateChunk estimates the output of a single synthesis chunk.
+type TTSEstimateChunk struct {
+	Text            string  `json:"text"`
+	Tokens          int     `json:"tokens"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	// Truncated is set when max_new_tokens would cut the chunk short.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// TTSEstimateResponse estimates the semantic tokens and audio duration a TTS
+// request will produce.
+type TTSEstimateResponse struct {
+	Tokens          int                `json:"tokens"`
+	DurationSeconds float64            `json:"duration_seconds"`
+	Chunks          []TTSEstimateChunk `json:"chunks"`
+}
+
+// HandleTTSEstimate accepts the same body as /v1/tts and returns the estimated
+// token count and audio duration without synthesizing. Estimates assume a
+// neutral speaking pace and are meant for previews and progress reporting.
+// Durations account for the speed option; token counts do not, as the backend
+// produces the same tokens whatever the speed.
+func (h *Handler) HandleTTSEstimate(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.parsePreviewRequest(w, r)
+	if !ok {
+		return
+	}
+	WriteJSON(w, http.StatusOK, estimateTTS(req))
+}
+
+// estimateTTS estimates the tokens and audio duration req will produce.
+func estimateTTS(req *schema.ServeTTSRequest) TTSEstimateResponse {
+	chunks := text.Split(req.Text, req.ChunkLength)
+	resp := TTSEstimateResponse{Chunks: make([]TTSEstimateChunk, 0, len(chunks))}
+	for _, c := range chunks {
+		tokens := int(math.Ceil(text.EstimateSpeechDuration(c).Seconds() * semanticTokensPerSecond))
+		chunk := TTSEstimateChunk{Text: c, Tokens: tokens}
+		if req.MaxNewTokens > 0 && tokens > req.MaxNewTokens {
+			chunk.Tokens = req.MaxNewTokens
+			chunk.Truncated = true
+		}
+		chunk.DurationSeconds = roundSeconds(tokenSeconds(chunk.Tokens, req.Speed))
+
+		resp.Tokens += chunk.Tokens
+		resp.Chunks = append(resp.Chunks, chunk)
+	}
+	resp.DurationSeconds = roundSeconds(tokenSeconds(resp.Tokens, req.Speed))
+	return resp
+}
+
+// tokenSeconds returns the duration of the audio for tokens semantic tokens
+// played at speed (0 for unchanged).
+func tokenSeconds(tokens int, speed float64) float64 {
+	s := float64(tokens) / semanticTokensPerSecond
+	if speed > 0 {
+		s /= speed
+	}
+	return s
+}
+
+func roundSeconds(s float64) float64 {
+	return math.Round(s*100) / 100
+}
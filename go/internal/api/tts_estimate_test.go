@@ -0,0 +1,80 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func TestTTSEstimate(t *testing.T) {
+	testCases := []struct {
+		name         string
+		req          schema.ServeTTSRequest
+		chunks       int
+		minDuration  float64
+		maxDuration  float64
+		anyTruncated bool
+	}{
+		{
+			name:        "short sentence",
+			req:         schema.ServeTTSRequest{Text: "The quick brown fox jumps over the lazy dog."},
+			chunks:      1,
+			minDuration: 2.5,
+			maxDuration: 4.5,
+		},
+		{
+			name:        "multiple chunks",
+			req:         schema.ServeTTSRequest{Text: strings.Repeat("This is a fairly ordinary sentence. ", 20), ChunkLength: 100},
+			chunks:      10,
+			minDuration: 40,
+			maxDuration: 60,
+		},
+		{
+			name:         "capped by max_new_tokens",
+			req:          schema.ServeTTSRequest{Text: strings.Repeat("word ", 40), MaxNewTokens: 50},
+			chunks:       1,
+			minDuration:  2.3,
+			maxDuration:  2.4,
+			anyTruncated: true,
+		},
+		{
+			name:         "faster speed",
+			req:          schema.ServeTTSRequest{Text: strings.Repeat("word ", 40), MaxNewTokens: 50, Speed: 1.25},
+			chunks:       1,
+			minDuration:  1.8,
+			maxDuration:  1.9,
+			anyTruncated: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &mockBackend{}
+			h := NewHandler(mock, testConfig(), testLogger())
+
+			body, _ := json.Marshal(tc.req)
+			req := httptest.NewRequest(http.MethodPost, "/v1/tts/estimate", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			h.HandleTTSEstimate(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			var resp TTSEstimateResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Len(t, resp.Chunks, tc.chunks)
+			assert.GreaterOrEqual(t, resp.DurationSeconds, tc.minDuration)
+			assert.LessOrEqual(t, resp.DurationSeconds, tc.maxDuration)
+			assert.Equal(t, tc.anyTruncated, resp.Chunks[0].Truncated)
+			assert.Nil(t, mock.lastTTSReq)
+		})
+	}
+}
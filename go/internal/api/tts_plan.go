@@ -0,0 +1,68 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+	"github.com/fish-speech-go/fish-speech-go/internal/text"
+)
+
+// TTSPlanChunk is one text segment the backend synthesizes in a single pass.
+type TTSPlanChunk struct {
+	Text string `json:"text"`
+	// Bytes is the UTF-8 length the backend compares against chunk_length.
+	Bytes int `json:"bytes"`
+}
+
+// TTSPlanResponse previews how the backend will normalize and chunk a TTS request.
+type TTSPlanResponse struct {
+	Text           string         `json:"text"`
+	NormalizedText string         `json:"normalized_text"`
+	ChunkLength    int            `json:"chunk_length"`
+	Chunks         []TTSPlanChunk `json:"chunks"`
+}
+
+// HandleTTSPlan accepts the same body as /v1/tts and returns the normalized text
+// and chunk boundaries without synthesizing audio.
+func (h *Handler) HandleTTSPlan(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.parsePreviewRequest(w, r)
+	if !ok {
+		return
+	}
+
+	chunks := text.Split(req.Text, req.ChunkLength)
+	resp := TTSPlanResponse{
+		Text:           req.Text,
+		NormalizedText: text.Clean(req.Text),
+		ChunkLength:    req.ChunkLength,
+		Chunks:         make([]TTSPlanChunk, 0, len(chunks)),
+	}
+	for _, c := range chunks {
+		resp.Chunks = append(resp.Chunks, TTSPlanChunk{Text: c, Bytes: len(c)})
+	}
+
+	WriteJSON(w, http.StatusOK, resp)
+}
+
+// parsePreviewRequest parses a TTS request for the preview endpoints, applying the
+// same validation as /v1/tts. It writes the error response and returns false on failure.
+func (h *Handler) parsePreviewRequest(w http.ResponseWriter, r *http.Request) (*schema.ServeTTSRequest, bool) {
+	req, err := parseTTSRequestWith(r, func(req *schema.ServeTTSRequest) error {
+		return h.prepareTTSRequest(r.Context(), req)
+	})
+	if err != nil {
+		h.handleParseError(w, err)
+		return nil, false
+	}
+
+	if h.config.Limits.MaxTextLength > 0 && len(req.Text) > h.config.Limits.MaxTextLength {
+		WriteErrorCode(w, http.StatusBadRequest, CodeTextTooLong, fmt.Sprintf("Text is too long, max length is %d", h.config.Limits.MaxTextLength))
+		return nil, false
+	}
+	if err := checkStreamingFormat(req); err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return req, true
+}
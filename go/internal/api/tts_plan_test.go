@@ -0,0 +1,35 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func TestTTSPlan(t *testing.T) {
+	mock := &mockBackend{}
+	h := NewHandler(mock, testConfig(), testLogger())
+
+	body, _ := json.Marshal(schema.ServeTTSRequest{Text: "  It’s 3.14 o'clock. Time for tea!  ", ChunkLength: 100})
+	req := httptest.NewRequest(http.MethodPost, "/v1/tts/plan", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	h.HandleTTSPlan(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp TTSPlanResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "It's 3.14 o'clock. Time for tea!", resp.NormalizedText)
+	assert.Equal(t, 100, resp.ChunkLength)
+	require.Len(t, resp.Chunks, 1)
+	assert.Equal(t, resp.NormalizedText, resp.Chunks[0].Text)
+	assert.Nil(t, mock.lastTTSReq)
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/audio"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+	"github.com/fish-speech-go/fish-speech-go/internal/usage"
+)
+
+// UsageLimits are the usage quotas of the caller's key. Zero means unlimited.
+type UsageLimits struct {
+	DailyCharacters     int64   `json:"daily_characters,omitempty"`
+	MonthlyCharacters   int64   `json:"monthly_characters,omitempty"`
+	DailyAudioSeconds   float64 `json:"daily_audio_seconds,omitempty"`
+	MonthlyAudioSeconds float64 `json:"monthly_audio_seconds,omitempty"`
+}
+
+// UsageResponse reports the caller's usage in the current UTC day and month
+// along with the quotas of their key.
+type UsageResponse struct {
+	usage.Account
+	Limits UsageLimits `json:"limits"`
+}
+
+// ListUsageResponse reports the usage of every metered account.
+type ListUsageResponse struct {
+	Accounts []usage.Account `json:"accounts"`
+}
+
+// usageQuotas caps the characters and audio seconds metered for a key per UTC
+// day and month. Zero fields are unlimited.
+type usageQuotas struct {
+	dailyCharacters     int64
+	monthlyCharacters   int64
+	dailyAudioSeconds   float64
+	monthlyAudioSeconds float64
+}
+
+func (q usageQuotas) none() bool {
+	return q.dailyCharacters <= 0 && q.monthlyCharacters <= 0 && q.dailyAudioSeconds <= 0 && q.monthlyAudioSeconds <= 0
+}
+
+func (q usageQuotas) limits() UsageLimits {
+	return UsageLimits{
+		DailyCharacters:     q.dailyCharacters,
+		MonthlyCharacters:   q.monthlyCharacters,
+		DailyAudioSeconds:   q.dailyAudioSeconds,
+		MonthlyAudioSeconds: q.monthlyAudioSeconds,
+	}
+}
+
+// checkUsageQuota rejects a request for text when the caller's key has a usage
+// quota it would exceed: when the text takes the day's or month's characters
+// past the limit, or when the audio seconds limit is already reached, as the
+// length of the audio is only known once it is synthesized. Callers without
+// a key are not metered.
+func (h *Handler) checkUsageQuota(ctx context.Context, text string, now time.Time) *policyError {
+	p := PrincipalFromContext(ctx)
+	if p == nil || p.policy == nil || p.policy.quotas.none() {
+		return nil
+	}
+	q := p.policy.quotas
+	a := h.usage.Usage(p.account(), now)
+	chars := int64(utf8.RuneCountInString(text))
+	dayEnd, monthEnd := a.Day.Start.AddDate(0, 0, 1), a.Month.Start.AddDate(0, 1, 0)
+
+	switch {
+	case q.dailyCharacters > 0 && a.Day.Characters+chars > q.dailyCharacters:
+		return usageQuotaExceeded(fmt.Sprintf("Daily quota of %d characters exhausted", q.dailyCharacters), dayEnd.Sub(now))
+	case q.monthlyCharacters > 0 && a.Month.Characters+chars > q.monthlyCharacters:
+		return usageQuotaExceeded(fmt.Sprintf("Monthly quota of %d characters exhausted", q.monthlyCharacters), monthEnd.Sub(now))
+	case q.dailyAudioSeconds > 0 && a.Day.AudioSeconds >= q.dailyAudioSeconds:
+		return usageQuotaExceeded(fmt.Sprintf("Daily quota of %g audio seconds exhausted", q.dailyAudioSeconds), dayEnd.Sub(now))
+	case q.monthlyAudioSeconds > 0 && a.Month.AudioSeconds >= q.monthlyAudioSeconds:
+		return usageQuotaExceeded(fmt.Sprintf("Monthly quota of %g audio seconds exhausted", q.monthlyAudioSeconds), monthEnd.Sub(now))
+	}
+	return nil
+}
+
+func usageQuotaExceeded(message string, wait time.Duration) *policyError {
+	return &policyError{status: http.StatusTooManyRequests, code: CodeQuotaExceeded, message: message, retryAfter: wait}
+}
+
+// recordUsage meters the text of req and seconds of audio produced for it
+// against the account of p. Callers without a key are not metered.
+func (h *Handler) recordUsage(p *Principal, req *schema.ServeTTSRequest, seconds float64) {
+	if p == nil {
+		return
+	}
+	if err := h.usage.Record(p.account(), int64(utf8.RuneCountInString(req.Text)), seconds, time.Now()); err != nil {
+		h.logger.Warn().Err(err).Msg("Failed to persist usage")
+	}
+}
+
+// audioSeconds returns the length of the audio produced for req: that of the
+// final audio when its format declares it, else that of the backend's audio
+// adjusted for speed, else an estimate from the text.
+func audioSeconds(req *schema.ServeTTSRequest, raw, final []byte) float64 {
+	if d, ok := audio.Duration(final); ok {
+		return d.Seconds()
+	}
+	if d, ok := audio.Duration(raw); ok {
+		return speedSeconds(d, req.Speed)
+	}
+	return estimateTTS(req).DurationSeconds
+}
+
+// speedSeconds returns the length of d played at speed (0 for unchanged).
+func speedSeconds(d time.Duration, speed float64) float64 {
+	if speed > 0 {
+		return d.Seconds() / speed
+	}
+	return d.Seconds()
+}
+
+// wavMeter measures the audio of a WAV stream as it is read. The transcoder
+// reads it from its own goroutine, so the tracker is guarded.
+type wavMeter struct {
+	r       io.Reader
+	mu      sync.Mutex
+	tracker wavTracker
+}
+
+func (m *wavMeter) Read(p []byte) (int, error) {
+	n, err := m.r.Read(p)
+	m.mu.Lock()
+	m.tracker.observe(p[:n])
+	m.mu.Unlock()
+	return n, err
+}
+
+func (m *wavMeter) duration() (time.Duration, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.tracker.duration()
+}
+
+// HandleUsage reports the caller's usage and usage quotas. Usage is only
+// metered for authenticated callers.
+func (h *Handler) HandleUsage(w http.ResponseWriter, r *http.Request) {
+	p := PrincipalFromContext(r.Context())
+	if p == nil {
+		WriteError(w, http.StatusNotFound, "Usage is only metered when authentication is enabled")
+		return
+	}
+
+	resp := UsageResponse{Account: h.usage.Usage(p.account(), time.Now())}
+	if p.policy != nil {
+		resp.Limits = p.policy.quotas.limits()
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	WriteJSON(w, http.StatusOK, resp)
+}
+
+// HandleListUsage reports the usage of every metered account.
+func (h *Handler) HandleListUsage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	WriteJSON(w, http.StatusOK, ListUsageResponse{Accounts: h.usage.All(time.Now())})
+}
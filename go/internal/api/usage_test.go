@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+	"github.com/fish-speech-go/fish-speech-go/internal/usage"
+)
+
+func TestUsage_MeteredAndEnforced(t *testing.T) {
+	cfg := testConfig()
+	cfg.Auth = config.AuthConfig{Keys: []config.APIKeyConfig{
+		{Name: "alice", Key: "alice-key", DailyCharacters: 10, MonthlyAudioSeconds: 100},
+		{Key: "admin-key", Role: RoleAdmin},
+	}}
+	router := NewRouter(cfg, &mockBackend{ttsResponse: silentWAV(2 * time.Second)}, testLogger())
+
+	do := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+key)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/v1/tts", "alice-key", `{"text": "Hello"}`).Code)
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/v1/tts", "alice-key", `{"text": "Hallo", "streaming": true}`).Code)
+
+	w := do(http.MethodPost, "/v2/tts", "alice-key", `{"text": "Hello"}`)
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), CodeQuotaExceeded)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	w = do(http.MethodGet, "/v1/usage", "alice-key", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp UsageResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "alice", resp.Name)
+	assert.Equal(t, usage.Totals{Requests: 2, Characters: 10, AudioSeconds: 4}, resp.Day.Totals)
+	assert.Equal(t, UsageLimits{DailyCharacters: 10, MonthlyAudioSeconds: 100}, resp.Limits)
+
+	assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/admin/usage", "alice-key", "").Code)
+	w = do(http.MethodGet, "/admin/usage", "admin-key", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var list ListUsageResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Accounts, 1)
+	assert.Equal(t, "alice", list.Accounts[0].Name)
+}
+
+func TestUsage_AudioSecondsQuota(t *testing.T) {
+	meter := usage.New()
+	h := NewHandler(&mockBackend{}, testConfig(), testLogger(), WithUsageMeter(meter))
+	p := &Principal{policy: newKeyPolicy("bob", config.APIKeyConfig{DailyAudioSeconds: 5}, &keyUsage{})}
+	ctx := WithPrincipal(context.Background(), p)
+	now := time.Date(2026, 6, 1, 18, 0, 0, 0, time.UTC)
+
+	require.NoError(t, meter.Record("bob", 10, 4.5, now))
+	assert.Nil(t, h.checkUsageQuota(ctx, "Hello", now))
+
+	require.NoError(t, meter.Record("bob", 10, 1, now))
+	perr := h.checkUsageQuota(ctx, "Hello", now)
+	require.NotNil(t, perr)
+	assert.Equal(t, CodeQuotaExceeded, perr.code)
+	assert.Equal(t, 6*time.Hour, perr.retryAfter)
+	assert.Nil(t, h.checkUsageQuota(ctx, "Hello", now.Add(6*time.Hour)), "a new day resets the quota")
+}
+
+func TestUsage_NotMeteredWithoutAuth(t *testing.T) {
+	router := NewRouter(testConfig(), &mockBackend{}, testLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/usage", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// Pagination defaults for /v2 list endpoints.
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+// V2Envelope wraps every /v2 JSON response body.
+type V2Envelope struct {
+	Data       interface{} `json:"data"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+// Pagination describes a page of a /v2 list. NextCursor is passed as the cursor
+// query parameter to fetch the following page and is empty on the last page.
+type Pagination struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// V2ErrorResponse is the /v2 error body.
+type V2ErrorResponse struct {
+	Error V2Error `json:"error"`
+}
+
+// V2Error describes a /v2 error; Code is from the error code catalog.
+type V2Error struct {
+	Code            string `json:"code"`
+	Message         string `json:"message"`
+	EstimatedWaitMs *int64 `json:"estimated_wait_ms,omitempty"`
+}
+
+// parsePage reads the limit and cursor query parameters.
+func parsePage(r *http.Request) (limit int, cursor string, ok bool) {
+	limit = defaultPageLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageLimit {
+			return 0, "", false
+		}
+		limit = n
+	}
+	return limit, r.URL.Query().Get("cursor"), true
+}
+
+// paginate returns the page of sorted ids after cursor and the cursor for the
+// next page.
+func paginate(ids []string, limit int, cursor string) ([]string, string) {
+	sort.Strings(ids)
+	start := sort.SearchStrings(ids, cursor)
+	if start < len(ids) && ids[start] == cursor {
+		start++
+	}
+
+	end := start + limit
+	if end >= len(ids) {
+		return ids[start:], ""
+	}
+	return ids[start:end], ids[end-1]
+}
+
+// HandleListReferencesV2 lists the caller's reference IDs in pages.
+func (h *Handler) HandleListReferencesV2(w http.ResponseWriter, r *http.Request) {
+	limit, cursor, ok := parsePage(r)
+	if !ok {
+		WriteError(w, http.StatusBadRequest, "limit must be an integer between 1 and "+strconv.Itoa(maxPageLimit))
+		return
+	}
+
+	resp, err := h.listReferences(r.Context())
+	if err != nil {
+		h.handleBackendError(w, err)
+		return
+	}
+
+	ids := filterNamespace(namespaceFromContext(r.Context()), resp.ReferenceIDs)
+	page, next := paginate(ids, limit, cursor)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(V2Envelope{
+		Data:       page,
+		Pagination: &Pagination{Limit: limit, NextCursor: next},
+	})
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func TestV2_EnvelopeAndErrors(t *testing.T) {
+	router := NewRouter(testConfig(), &mockBackend{}, testLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/v2/health", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":{"status":"ok"}}`, w.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/health", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+
+	req = httptest.NewRequest(http.MethodPost, "/v2/tts", nil)
+	req.Header.Set("Content-Type", "text/plain")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	var v2Err V2ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &v2Err))
+	assert.Equal(t, CodeInvalidRequest, v2Err.Error.Code)
+	assert.NotEmpty(t, v2Err.Error.Message)
+}
+
+func TestV2_ErrorsFromMiddleware(t *testing.T) {
+	cfg := testConfig()
+	cfg.Auth = config.AuthConfig{APIKey: "secret"}
+	router := NewRouter(cfg, &mockBackend{}, testLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/v2/references", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.JSONEq(t, `{"error":{"code":"unauthorized","message":"Invalid token"}}`, w.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/references", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.JSONEq(t, `{"detail":"Invalid token"}`, w.Body.String())
+}
+
+func TestV2_ListReferencesPagination(t *testing.T) {
+	mock := &mockBackend{listRefResp: &schema.ListReferencesResponse{
+		Success:      true,
+		ReferenceIDs: []string{"e", "c", "a", "d", "b"},
+	}}
+	router := NewRouter(testConfig(), mock, testLogger())
+
+	list := func(query string) (int, V2Envelope) {
+		req := httptest.NewRequest(http.MethodGet, "/v2/references"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var env V2Envelope
+		_ = json.Unmarshal(w.Body.Bytes(), &env)
+		return w.Code, env
+	}
+
+	code, env := list("?limit=2")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []interface{}{"a", "b"}, env.Data)
+	require.NotNil(t, env.Pagination)
+	assert.Equal(t, "b", env.Pagination.NextCursor)
+
+	_, env = list("?limit=2&cursor=b")
+	assert.Equal(t, []interface{}{"c", "d"}, env.Data)
+	assert.Equal(t, "d", env.Pagination.NextCursor)
+
+	_, env = list("?limit=2&cursor=d")
+	assert.Equal(t, []interface{}{"e"}, env.Data)
+	assert.Empty(t, env.Pagination.NextCursor)
+
+	_, env = list("")
+	assert.Len(t, env.Data, 5)
+	assert.Equal(t, defaultPageLimit, env.Pagination.Limit)
+
+	code, _ = list("?limit=0")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
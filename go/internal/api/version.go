@@ -0,0 +1,60 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// API versions. /v1 keeps the Python server's response shapes, which must not
+// change for the routes it shares with upstream; /v2 may evolve, and differs in
+// response shape only: JSON bodies are wrapped in an envelope and errors are
+// structured objects.
+const (
+	APIVersion1 = 1
+	APIVersion2 = 2
+)
+
+// VersionMiddleware records the API version of the request path on the response
+// writer, so that responses written anywhere in the chain (including by
+// middleware) use that version's format. It must be installed first.
+func VersionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/v2/") {
+			w = &versionWriter{ResponseWriter: w, version: APIVersion2}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// versionWriter tags a response with its API version.
+type versionWriter struct {
+	http.ResponseWriter
+	version int
+}
+
+func (vw *versionWriter) Flush() {
+	if f, ok := vw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (vw *versionWriter) Unwrap() http.ResponseWriter {
+	return vw.ResponseWriter
+}
+
+// responseVersion returns the API version a response is written in, looking
+// through wrapping writers. Responses outside /v2 are version 1.
+func responseVersion(w http.ResponseWriter) int {
+	for w != nil {
+		if vw, ok := w.(*versionWriter); ok {
+			return vw.version
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			break
+		}
+		w = u.Unwrap()
+	}
+	return APIVersion1
+}
@@ -0,0 +1,304 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+	"github.com/fish-speech-go/fish-speech-go/internal/refstore"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+// Where a voice is defined: in the server configuration or through the API.
+// The voice catalog also lists reference aliases and references, which
+// requests can name as a voice too.
+const (
+	VoiceSourceConfig    = "config"
+	VoiceSourceAPI       = "api"
+	VoiceSourceAlias     = "alias"
+	VoiceSourceReference = "reference"
+)
+
+// VoiceInfo describes a voice of the catalog: where it is defined, the
+// metadata of the reference it speaks with and, when fish-server keeps that
+// reference's audio, a URL to download it as a sample.
+type VoiceInfo struct {
+	schema.Voice
+	Source string `json:"source"`
+	schema.ReferenceMetadata
+	SampleURL string `json:"sample_url,omitempty"`
+}
+
+// ListVoicesResponse is the voice catalog returned by GET /v1/voices.
+type ListVoicesResponse struct {
+	Voices []VoiceInfo `json:"voices"`
+}
+
+// VoiceResponse is returned when a voice is deleted.
+type VoiceResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Name    string `json:"name"`
+}
+
+// ValidateVoices checks the voices of the server configuration.
+func ValidateVoices(voices []config.VoiceConfig) error {
+	names := make(map[string]bool, len(voices))
+	for i, c := range voices {
+		v := voiceFromConfig(c)
+		if err := validateVoice(&v); err != nil {
+			return fmt.Errorf("references.voices[%d]: %w", i, err)
+		}
+		if names[v.Name] {
+			return fmt.Errorf("references.voices[%d]: duplicate name %q", i, v.Name)
+		}
+		names[v.Name] = true
+	}
+	return nil
+}
+
+func voiceFromConfig(c config.VoiceConfig) schema.Voice {
+	return schema.Voice{
+		Name:              c.Name,
+		ReferenceID:       c.ReferenceID,
+		Model:             c.Model,
+		ChunkLength:       c.ChunkLength,
+		MaxNewTokens:      c.MaxNewTokens,
+		TopP:              c.TopP,
+		RepetitionPenalty: c.RepetitionPenalty,
+		Temperature:       c.Temperature,
+		Seed:              c.Seed,
+		Speed:             c.Speed,
+	}
+}
+
+// validateVoice checks the name and reference of a voice, and its parameters
+// against the rules of a TTS request.
+func validateVoice(v *schema.Voice) error {
+	if err := validateReferenceID("name", v.Name); err != nil {
+		return err
+	}
+	if err := validateReferenceID("reference_id", v.ReferenceID); err != nil {
+		return err
+	}
+	if v.MaxNewTokens < 0 {
+		return errors.New("max_new_tokens must not be negative")
+	}
+	req := schema.ServeTTSRequest{Text: v.Name}
+	v.Apply(&req)
+	if err := req.Validate(0); err != nil {
+		return err
+	}
+	return validatePostProcessing(&req)
+}
+
+// voice returns the voice name as seen from namespace: one set through the
+// API, or else one from the server configuration.
+func (h *Handler) voice(namespace, name string) (VoiceInfo, bool) {
+	if v, ok := h.refs.Voice(scopeReferenceID(namespace, name)); ok {
+		v.Name = name
+		return VoiceInfo{Voice: v, Source: VoiceSourceAPI}, true
+	}
+	for _, c := range h.config.References.Voices {
+		if c.Name == name {
+			return VoiceInfo{Voice: voiceFromConfig(c), Source: VoiceSourceConfig}, true
+		}
+	}
+	return VoiceInfo{}, false
+}
+
+// applyVoice applies the voice a TTS request asks for, before the request is
+// validated. Other names, such as those of references and aliases in the
+// voice catalog, are used as the reference_id. Either way the reference is
+// then resolved like a reference_id.
+func (h *Handler) applyVoice(ctx context.Context, req *schema.ServeTTSRequest) error {
+	if req.Voice == "" {
+		return nil
+	}
+	if req.ReferenceID != nil {
+		return NewParseError(http.StatusBadRequest, "voice and reference_id cannot both be set")
+	}
+	if v, ok := h.voice(namespaceFromContext(ctx), req.Voice); ok {
+		v.Apply(req)
+		return nil
+	}
+	id := req.Voice
+	req.ReferenceID = &id
+	return nil
+}
+
+// catalogVoice returns the voice, reference alias or reference name as listed
+// in the voice catalog.
+func (h *Handler) catalogVoice(ctx context.Context, namespace, name string) (VoiceInfo, bool, error) {
+	if v, ok := h.voice(namespace, name); ok {
+		return v, true, nil
+	}
+	if target, ok := h.refs.Alias(scopeReferenceID(namespace, name)); ok {
+		return VoiceInfo{
+			Voice:  schema.Voice{Name: name, ReferenceID: unscopeReferenceID(namespace, target)},
+			Source: VoiceSourceAlias,
+		}, true, nil
+	}
+	refs, err := h.listReferences(ctx)
+	if err != nil {
+		return VoiceInfo{}, false, err
+	}
+	if !containsString(refs.ReferenceIDs, scopeReferenceID(namespace, name)) {
+		return VoiceInfo{}, false, nil
+	}
+	return VoiceInfo{Voice: schema.Voice{Name: name, ReferenceID: name}, Source: VoiceSourceReference}, true, nil
+}
+
+// describeVoice adds the metadata and sample URL of the reference v speaks
+// with. prefix is the path the API is served under, such as /v1.
+func (h *Handler) describeVoice(namespace, prefix string, v VoiceInfo) VoiceInfo {
+	target := h.refs.Resolve(scopeReferenceID(namespace, v.ReferenceID))
+	v.ReferenceMetadata, _ = h.refs.Metadata(target)
+	if h.archive != nil && h.archive.Has(target) {
+		v.SampleURL = prefix + "/references/" + url.PathEscape(unscopeReferenceID(namespace, target)) + "/audio"
+	}
+	return v
+}
+
+// voicesPrefix returns the path the voice endpoints of r are served under.
+func voicesPrefix(r *http.Request) string {
+	if i := strings.LastIndex(r.URL.Path, "/voices"); i >= 0 {
+		return r.URL.Path[:i]
+	}
+	return ""
+}
+
+// HandleListVoices lists the voice catalog visible to the caller: the voices,
+// then the reference aliases and references not shadowed by one, each under
+// the name a TTS request asks for it by.
+func (h *Handler) HandleListVoices(w http.ResponseWriter, r *http.Request) {
+	namespace := namespaceFromContext(r.Context())
+	refs, err := h.listReferences(r.Context())
+	if err != nil {
+		h.handleBackendError(w, err)
+		return
+	}
+
+	// Names are taken in the order a request resolves them.
+	catalog := map[string]VoiceInfo{}
+	add := func(v VoiceInfo) {
+		if _, ok := catalog[v.Name]; !ok {
+			catalog[v.Name] = v
+		}
+	}
+	for _, v := range h.refs.Voices() {
+		if names := filterNamespace(namespace, []string{v.Name}); len(names) == 1 {
+			v.Name = names[0]
+			add(VoiceInfo{Voice: v, Source: VoiceSourceAPI})
+		}
+	}
+	for _, c := range h.config.References.Voices {
+		add(VoiceInfo{Voice: voiceFromConfig(c), Source: VoiceSourceConfig})
+	}
+	for _, a := range h.refs.Aliases() {
+		if names := filterNamespace(namespace, []string{a.Name}); len(names) == 1 {
+			add(VoiceInfo{
+				Voice:  schema.Voice{Name: names[0], ReferenceID: unscopeReferenceID(namespace, a.Target)},
+				Source: VoiceSourceAlias,
+			})
+		}
+	}
+	for _, id := range filterNamespace(namespace, refs.ReferenceIDs) {
+		add(VoiceInfo{Voice: schema.Voice{Name: id, ReferenceID: id}, Source: VoiceSourceReference})
+	}
+
+	prefix := voicesPrefix(r)
+	voices := make([]VoiceInfo, 0, len(catalog))
+	for _, v := range catalog {
+		voices = append(voices, h.describeVoice(namespace, prefix, v))
+	}
+	sort.Slice(voices, func(i, j int) bool { return voices[i].Name < voices[j].Name })
+
+	WriteJSON(w, http.StatusOK, ListVoicesResponse{Voices: voices})
+}
+
+// HandleGetVoice describes a single voice of the catalog.
+func (h *Handler) HandleGetVoice(w http.ResponseWriter, r *http.Request) {
+	namespace := namespaceFromContext(r.Context())
+	v, ok, err := h.catalogVoice(r.Context(), namespace, chi.URLParam(r, "name"))
+	if err != nil {
+		h.handleBackendError(w, err)
+		return
+	}
+	if !ok {
+		WriteError(w, http.StatusNotFound, "Voice not found")
+		return
+	}
+	WriteJSON(w, http.StatusOK, h.describeVoice(namespace, voicesPrefix(r), v))
+}
+
+// HandleSetVoice creates or replaces a voice, pointing it at an existing
+// reference. It takes precedence over a configured voice of the same name.
+func (h *Handler) HandleSetVoice(w http.ResponseWriter, r *http.Request) {
+	var v schema.Voice
+	if err := ParseRequestBody(r, &v); err != nil {
+		h.handleParseError(w, err)
+		return
+	}
+	v.Name = chi.URLParam(r, "name")
+	if err := validateVoice(&v); err != nil {
+		WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	namespace := namespaceFromContext(r.Context())
+	target := h.refs.Resolve(scopeReferenceID(namespace, v.ReferenceID))
+	refs, err := h.listReferences(r.Context())
+	if err != nil {
+		h.handleBackendError(w, err)
+		return
+	}
+	if !containsString(refs.ReferenceIDs, target) {
+		WriteError(w, http.StatusNotFound, "Reference not found")
+		return
+	}
+
+	// The reference is kept as given, so a voice on an alias follows the alias.
+	stored := v
+	stored.Name = scopeReferenceID(namespace, v.Name)
+	if err := h.refs.SetVoice(stored); err != nil {
+		h.logger.Error().Err(err).Msg("Set voice error")
+		WriteError(w, http.StatusInternalServerError, "Failed to save voice")
+		return
+	}
+
+	h.referenceMutation(r.Context(), ActionSetVoice, target).Str("voice", stored.Name).Msg("Voice updated")
+	WriteJSON(w, http.StatusOK, h.describeVoice(namespace, voicesPrefix(r), VoiceInfo{Voice: v, Source: VoiceSourceAPI}))
+}
+
+// HandleDeleteVoice removes a voice set through the API. Configured voices
+// can only be removed from the configuration.
+func (h *Handler) HandleDeleteVoice(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	scoped := scopeReferenceID(namespaceFromContext(r.Context()), name)
+
+	err := h.refs.DeleteVoice(scoped)
+	if errors.Is(err, refstore.ErrNotFound) {
+		if v, ok := h.voice(namespaceFromContext(r.Context()), name); ok && v.Source == VoiceSourceConfig {
+			WriteError(w, http.StatusConflict, "Voice is defined in the server configuration")
+			return
+		}
+		WriteError(w, http.StatusNotFound, "Voice not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Delete voice error")
+		WriteError(w, http.StatusInternalServerError, "Failed to delete voice")
+		return
+	}
+
+	h.referenceMutation(r.Context(), ActionDeleteVoice, "").Str("voice", scoped).Msg("Voice deleted")
+	WriteJSON(w, http.StatusOK, VoiceResponse{Success: true, Message: "Voice deleted successfully", Name: name})
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+	"github.com/fish-speech-go/fish-speech-go/internal/refaudio"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func TestVoices(t *testing.T) {
+	mock := &mockBackend{
+		listRefResp: &schema.ListReferencesResponse{Success: true, ReferenceIDs: []string{"narrator-v1", "narrator-v2"}},
+		ttsResponse: silentWAV(time.Second),
+	}
+	cfg := testConfig()
+	cfg.References.Voices = []config.VoiceConfig{{Name: "narrator", ReferenceID: "narrator-v1", Temperature: 0.5, TopP: 0.9}}
+	router := NewRouter(cfg, mock, testLogger())
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	tts := func(body string) *schema.ServeTTSRequest {
+		w := do(http.MethodPost, "/v1/tts", body)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return mock.lastTTSReq
+	}
+
+	req := tts(`{"text": "Hello", "voice": "narrator", "top_p": 0.7}`)
+	require.NotNil(t, req.ReferenceID)
+	assert.Equal(t, "narrator-v1", *req.ReferenceID)
+	assert.Equal(t, 0.5, req.Temperature, "unset parameters come from the voice")
+	assert.Equal(t, 0.7, req.TopP, "parameters the request sets are kept")
+
+	// A voice set through the API replaces the configured one.
+	w := do(http.MethodPut, "/v1/voices/narrator", `{"reference_id": "narrator-v2", "temperature": 0.8}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	req = tts(`{"text": "Hello", "voice": "narrator"}`)
+	assert.Equal(t, "narrator-v2", *req.ReferenceID)
+	assert.Equal(t, 0.8, req.Temperature)
+
+	w = do(http.MethodGet, "/v1/voices", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var list ListVoicesResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Voices, 3, "the voice and both references")
+	assert.Equal(t, VoiceSourceAPI, list.Voices[0].Source)
+
+	require.Equal(t, http.StatusOK, do(http.MethodDelete, "/v1/voices/narrator", "").Code)
+	w = do(http.MethodGet, "/v1/voices/narrator", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var voice VoiceInfo
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &voice))
+	assert.Equal(t, VoiceSourceConfig, voice.Source, "the configured voice is used again")
+	assert.Equal(t, http.StatusConflict, do(http.MethodDelete, "/v1/voices/narrator", "").Code)
+}
+
+func TestVoices_Errors(t *testing.T) {
+	mock := &mockBackend{listRefResp: &schema.ListReferencesResponse{Success: true, ReferenceIDs: []string{"narrator-v1"}}}
+	router := NewRouter(testConfig(), mock, testLogger())
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"voice and reference_id", http.MethodPost, "/v1/tts", `{"text": "Hello", "voice": "narrator", "reference_id": "narrator-v1"}`, http.StatusBadRequest},
+		{"missing reference", http.MethodPut, "/v1/voices/narrator", `{"reference_id": "missing"}`, http.StatusNotFound},
+		{"invalid parameter", http.MethodPut, "/v1/voices/narrator", `{"reference_id": "narrator-v1", "temperature": 2}`, http.StatusBadRequest},
+		{"invalid name", http.MethodPut, "/v1/voices/bad!name", `{"reference_id": "narrator-v1"}`, http.StatusBadRequest},
+		{"not found", http.MethodGet, "/v1/voices/missing", "", http.StatusNotFound},
+		{"delete not found", http.MethodDelete, "/v1/voices/missing", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestValidateVoices(t *testing.T) {
+	assert.NoError(t, ValidateVoices([]config.VoiceConfig{{Name: "narrator", ReferenceID: "narrator-v1"}}))
+	assert.ErrorContains(t, ValidateVoices([]config.VoiceConfig{{Name: "narrator"}}), "reference_id is required")
+	assert.ErrorContains(t, ValidateVoices([]config.VoiceConfig{
+		{Name: "narrator", ReferenceID: "narrator-v1"},
+		{Name: "narrator", ReferenceID: "narrator-v2"},
+	}), "duplicate name")
+}
+
+func TestVoiceCatalog(t *testing.T) {
+	mock := &mockBackend{
+		addRefResp:  &schema.AddReferenceResponse{Success: true, ReferenceID: "voice"},
+		listRefResp: &schema.ListReferencesResponse{Success: true, ReferenceIDs: []string{"voice", "legacy"}},
+		ttsResponse: silentWAV(time.Second),
+	}
+	archive, err := refaudio.Open(t.TempDir())
+	require.NoError(t, err)
+	router := NewRouter(testConfig(), mock, testLogger(), WithReferenceAudio(archive))
+
+	body, _ := json.Marshal(schema.AddReferenceRequest{
+		ID: "voice", Text: "transcript", Audio: silentWAV(time.Second),
+		ReferenceMetadata: schema.ReferenceMetadata{DisplayName: "Narrator", Language: "en"},
+	})
+	do := func(method, path string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		return w
+	}
+	do(http.MethodPost, "/v1/references/add", body)
+	do(http.MethodPost, "/v1/references/aliases", []byte(`{"alias": "narrator", "reference_id": "voice"}`))
+
+	var list ListVoicesResponse
+	require.NoError(t, json.Unmarshal(do(http.MethodGet, "/v1/voices", nil).Body.Bytes(), &list))
+	require.Len(t, list.Voices, 3)
+	legacy, narrator, voice := list.Voices[0], list.Voices[1], list.Voices[2]
+	assert.Equal(t, VoiceSourceReference, legacy.Source)
+	assert.Empty(t, legacy.SampleURL, "no audio is kept for references added elsewhere")
+	assert.Equal(t, VoiceSourceAlias, narrator.Source)
+	assert.Equal(t, "voice", narrator.ReferenceID)
+	assert.Equal(t, "Narrator", narrator.DisplayName, "aliases describe their reference")
+	assert.Equal(t, "/v1/references/voice/audio", narrator.SampleURL)
+	assert.Equal(t, VoiceSourceReference, voice.Source)
+	assert.Equal(t, "en", voice.Language)
+
+	w := do(http.MethodGet, "/v2/voices/narrator", nil)
+	assert.Contains(t, w.Body.String(), `"sample_url":"/v2/references/voice/audio"`)
+	do(http.MethodGet, narrator.SampleURL, nil)
+
+	// Every name in the catalog can be asked for as a voice.
+	do(http.MethodPost, "/v1/tts", []byte(`{"text": "Hello", "voice": "narrator"}`))
+	assert.Equal(t, "voice", *mock.lastTTSReq.ReferenceID)
+	do(http.MethodPost, "/v1/tts", []byte(`{"text": "Hello", "voice": "legacy"}`))
+	assert.Equal(t, "legacy", *mock.lastTTSReq.ReferenceID)
+}
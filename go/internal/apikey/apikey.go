@@ -0,0 +1,82 @@
+// Package apikey hashes API keys so they can be stored and compared without
+// keeping the plain-text key.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PrefixLength is the number of leading key characters used to identify a key
+// in logs and to narrow the hashes a presented key is compared against.
+const PrefixLength = 8
+
+const (
+	scheme   = "sha256"
+	saltSize = 16
+)
+
+// ErrInvalidHash indicates a hash string is not in the format produced by Hash.
+var ErrInvalidHash = errors.New(`invalid API key hash (want "sha256:<salt hex>:<digest hex>")`)
+
+// Prefix returns the identifying prefix of key. It is safe to log.
+func Prefix(key string) string {
+	if len(key) <= PrefixLength {
+		return key
+	}
+	return key[:PrefixLength]
+}
+
+// Hashed is a salted SHA-256 digest of an API key.
+type Hashed struct {
+	salt   []byte
+	digest []byte
+}
+
+// New salts and hashes key with a random salt.
+func New(key string) (Hashed, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return Hashed{}, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return Hashed{salt: salt, digest: digest(salt, key)}, nil
+}
+
+// Parse reads a hash in the format returned by Hashed.String.
+func Parse(s string) (Hashed, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 || parts[0] != scheme {
+		return Hashed{}, ErrInvalidHash
+	}
+	salt, err := hex.DecodeString(parts[1])
+	if err != nil || len(salt) == 0 {
+		return Hashed{}, ErrInvalidHash
+	}
+	d, err := hex.DecodeString(parts[2])
+	if err != nil || len(d) != sha256.Size {
+		return Hashed{}, ErrInvalidHash
+	}
+	return Hashed{salt: salt, digest: d}, nil
+}
+
+// String formats the hash as "sha256:<salt hex>:<digest hex>".
+func (h Hashed) String() string {
+	return scheme + ":" + hex.EncodeToString(h.salt) + ":" + hex.EncodeToString(h.digest)
+}
+
+// Matches reports in constant time whether key hashes to h.
+func (h Hashed) Matches(key string) bool {
+	return subtle.ConstantTimeCompare(digest(h.salt, key), h.digest) == 1
+}
+
+func digest(salt []byte, key string) []byte {
+	sum := sha256.New()
+	sum.Write(salt)
+	sum.Write([]byte(key))
+	return sum.Sum(nil)
+}
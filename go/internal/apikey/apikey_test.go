@@ -0,0 +1,35 @@
+package apikey
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashRoundTrip(t *testing.T) {
+	h, err := New("fsk_live_abcdef123456")
+	require.NoError(t, err)
+	assert.True(t, h.Matches("fsk_live_abcdef123456"))
+	assert.False(t, h.Matches("fsk_live_abcdef123457"))
+
+	parsed, err := Parse(h.String())
+	require.NoError(t, err)
+	assert.True(t, parsed.Matches("fsk_live_abcdef123456"))
+
+	other, err := New("fsk_live_abcdef123456")
+	require.NoError(t, err)
+	assert.NotEqual(t, h.String(), other.String(), "salts differ")
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, s := range []string{"", "plain-key", "md5:00:00", "sha256:zz:00", "sha256:00:0011"} {
+		_, err := Parse(s)
+		assert.ErrorIs(t, err, ErrInvalidHash, s)
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	assert.Equal(t, "fsk_live", Prefix("fsk_live_abcdef123456"))
+	assert.Equal(t, "short", Prefix("short"))
+}
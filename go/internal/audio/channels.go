@@ -0,0 +1,43 @@
+package audio
+
+// ToMono averages all channels into a single channel.
+func ToMono(p *PCM) *PCM {
+	if p.Channels == 1 {
+		return p
+	}
+
+	frames := p.Frames()
+	out := &PCM{SampleRate: p.SampleRate, Channels: 1, Samples: make([]float32, frames)}
+	for f := 0; f < frames; f++ {
+		var sum float32
+		for c := 0; c < p.Channels; c++ {
+			sum += p.Samples[f*p.Channels+c]
+		}
+		out.Samples[f] = sum / float32(p.Channels)
+	}
+	return out
+}
+
+// ToStereo converts p to two channels. Mono audio is duplicated into both
+// channels; audio with more than two channels is down-mixed to mono first.
+func ToStereo(p *PCM) *PCM {
+	if p.Channels == 2 {
+		return p
+	}
+
+	mono := ToMono(p)
+	out := &PCM{SampleRate: p.SampleRate, Channels: 2, Samples: make([]float32, 2*len(mono.Samples))}
+	for f, s := range mono.Samples {
+		out.Samples[2*f] = s
+		out.Samples[2*f+1] = s
+	}
+	return out
+}
+
+// Remix converts p to the given channel count, which must be 1 or 2.
+func Remix(p *PCM, channels int) *PCM {
+	if channels == 1 {
+		return ToMono(p)
+	}
+	return ToStereo(p)
+}
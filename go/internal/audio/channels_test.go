@@ -0,0 +1,21 @@
+package audio
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemix(t *testing.T) {
+	mono := &PCM{SampleRate: 16000, Channels: 1, Samples: []float32{0.1, -0.2, 0.3}}
+
+	stereo := Remix(mono, 2)
+	assert.Equal(t, 2, stereo.Channels)
+	assert.Equal(t, []float32{0.1, 0.1, -0.2, -0.2, 0.3, 0.3}, stereo.Samples)
+
+	back := Remix(stereo, 1)
+	assert.Equal(t, 1, back.Channels)
+	assert.InDeltaSlice(t, mono.Samples, back.Samples, 1e-6)
+
+	assert.Same(t, mono, Remix(mono, 1))
+}
@@ -0,0 +1,67 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrNoDecoder indicates the server was built without a decoder for a format.
+var ErrNoDecoder = errors.New("no decoder for audio format")
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[string]func([]byte) (*PCM, error){}
+)
+
+// RegisterDecoder makes a decoder available for format, replacing any
+// previous one; a nil decode removes it. Like encoders, the decoders that
+// need native libraries register themselves in builds with their tag. A
+// decoder returns an error wrapping ErrUnsupportedFormat for a codec of its
+// container it cannot decode, such as Vorbis in an Ogg decoder for Opus, and
+// other errors for corrupt audio.
+func RegisterDecoder(format string, decode func([]byte) (*PCM, error)) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	if decode == nil {
+		delete(decoders, format)
+		return
+	}
+	decoders[format] = decode
+}
+
+// CanDecode reports whether this build has a decoder for format.
+func CanDecode(format string) bool {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	_, ok := decoders[format]
+	return ok
+}
+
+// Decode decodes data, encoded audio of the given format, to PCM.
+func Decode(format string, data []byte) (*PCM, error) {
+	decodersMu.RLock()
+	decode, ok := decoders[format]
+	decodersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoDecoder, format)
+	}
+	p, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if p.SampleRate <= 0 || p.Channels <= 0 {
+		return nil, fmt.Errorf("%s: decoded no audio", format)
+	}
+	return p, nil
+}
+
+// TranscodeToWAV returns data, encoded audio of the given format, decoded
+// and encoded as 16-bit PCM WAV at its own rate and channels.
+func TranscodeToWAV(format string, data []byte) ([]byte, error) {
+	p, err := Decode(format, data)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeWAV(p), nil
+}
@@ -0,0 +1,56 @@
+package audio
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecoderRegistry(t *testing.T) {
+	const format = "test"
+	assert.False(t, CanDecode(format))
+	_, err := Decode(format, nil)
+	assert.ErrorIs(t, err, ErrNoDecoder)
+
+	RegisterDecoder(format, func(data []byte) (*PCM, error) {
+		if len(data) == 0 {
+			return nil, errors.New("test: empty")
+		}
+		return &PCM{SampleRate: 8000, Channels: 1, Samples: []float32{0.5, -0.5}}, nil
+	})
+	defer RegisterDecoder(format, nil)
+	assert.True(t, CanDecode(format))
+
+	wav, err := TranscodeToWAV(format, []byte{1})
+	require.NoError(t, err)
+	p, err := DecodeWAV(wav)
+	require.NoError(t, err)
+	assert.Equal(t, 8000, p.SampleRate)
+	assert.InDeltaSlice(t, []float32{0.5, -0.5}, p.Samples, 1e-4)
+
+	_, err = TranscodeToWAV(format, nil)
+	assert.EqualError(t, err, "test: empty")
+
+	RegisterDecoder(format, nil)
+	assert.False(t, CanDecode(format))
+}
+
+func TestSniffDecodable(t *testing.T) {
+	m4a := []byte("\x00\x00\x00\x20ftypM4A ")
+
+	_, err := SniffDecodable(m4a, FormatM4A)
+	assert.ErrorIs(t, err, ErrUnsupportedFormat, "no decoder in this build")
+
+	RegisterDecoder(FormatM4A, func([]byte) (*PCM, error) { return nil, nil })
+	defer RegisterDecoder(FormatM4A, nil)
+
+	format, err := SniffDecodable(m4a, FormatM4A)
+	require.NoError(t, err)
+	assert.Equal(t, FormatM4A, format)
+	_, err = SniffDecodable(m4a, FormatMP3)
+	assert.ErrorContains(t, err, "declared as mp3 but contains m4a")
+	_, err = Sniff(m4a, "")
+	assert.ErrorContains(t, err, "m4a is not supported")
+}
@@ -0,0 +1,227 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// maxStreamHeaderSize bounds how much of a WAV stream is read looking for its
+// header.
+const maxStreamHeaderSize = 64 << 10
+
+// MaxQuality is the highest EncodeOptions.Quality.
+const MaxQuality = 10
+
+// mp3Bitrates are the bit rates of MPEG audio frames in kbit/s. Which of
+// them a stream can use depends on its sample rate; LAME picks the closest.
+var mp3Bitrates = []int{8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 192, 224, 256, 320}
+
+// The range of Opus bit rates in kbit/s.
+const (
+	minOpusBitrate = 6
+	maxOpusBitrate = 510
+)
+
+// ErrNoEncoder indicates the server was built without an encoder for a format.
+var ErrNoEncoder = errors.New("no encoder for audio format")
+
+// EncodeOptions describes the audio an Encoder receives and its output.
+type EncodeOptions struct {
+	SampleRate int
+	Channels   int
+	// Bitrate is the target bit rate in kbit/s; 0 uses the encoder's default.
+	Bitrate int
+	// Quality, from 0 to MaxQuality with higher better, selects variable bit
+	// rate MP3 in place of Bitrate, and sets the Opus encoder complexity. Nil
+	// uses the encoder's default.
+	Quality *int
+}
+
+// CheckBitrate returns an error when format cannot be encoded at kbps kbit/s.
+func CheckBitrate(format string, kbps int) error {
+	switch format {
+	case FormatMP3:
+		if !slices.Contains(mp3Bitrates, kbps) {
+			rates := make([]string, len(mp3Bitrates))
+			for i, rate := range mp3Bitrates {
+				rates[i] = fmt.Sprint(rate)
+			}
+			return fmt.Errorf("mp3 bit rates are %s kbit/s", strings.Join(rates, ", "))
+		}
+	case FormatOpus:
+		if kbps < minOpusBitrate || kbps > maxOpusBitrate {
+			return fmt.Errorf("opus bit rates are %d to %d kbit/s", minOpusBitrate, maxOpusBitrate)
+		}
+	default:
+		return fmt.Errorf("%s has no bit rate", format)
+	}
+	return nil
+}
+
+// Encoder compresses interleaved PCM samples normalized to [-1, 1]
+// incrementally, so that the output can be streamed as it is produced.
+type Encoder interface {
+	// Encode consumes samples and returns the output completed so far, which
+	// may be empty.
+	Encode(samples []float32) ([]byte, error)
+	// Flush ends the stream and returns the remaining output.
+	Flush() ([]byte, error)
+	// Close releases the encoder. It must be called, even after Flush.
+	Close()
+}
+
+// finalHeaderer is implemented by encoders whose stream header holds totals
+// only known at the end. FinalHeader returns that header completed, the same
+// length as the one written first.
+type finalHeaderer interface {
+	FinalHeader() []byte
+}
+
+var (
+	encodersMu sync.RWMutex
+	encoders   = map[string]func(EncodeOptions) (Encoder, error){}
+)
+
+// RegisterEncoder makes an encoder available for format, replacing any
+// previous one. The encoders that need native libraries register themselves
+// in builds with their tag.
+func RegisterEncoder(format string, open func(EncodeOptions) (Encoder, error)) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	encoders[format] = open
+}
+
+// CanEncode reports whether this build has an encoder for format.
+func CanEncode(format string) bool {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	_, ok := encoders[format]
+	return ok
+}
+
+// NewEncoder returns an encoder for format.
+func NewEncoder(format string, opts EncodeOptions) (Encoder, error) {
+	encodersMu.RLock()
+	open, ok := encoders[format]
+	encodersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoEncoder, format)
+	}
+	return open(opts)
+}
+
+// Encode compresses p as format with the bit rate and quality of opts, whose
+// rate and channels are taken from p.
+func Encode(format string, p *PCM, opts EncodeOptions) ([]byte, error) {
+	opts.SampleRate, opts.Channels = p.SampleRate, p.Channels
+	enc, err := NewEncoder(format, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer enc.Close()
+
+	out, err := enc.Encode(p.Samples)
+	if err != nil {
+		return nil, err
+	}
+	tail, err := enc.Flush()
+	if err != nil {
+		return nil, err
+	}
+	out = append(out, tail...)
+	// The whole output is at hand, so the header can be completed in place.
+	if f, ok := enc.(finalHeaderer); ok {
+		copy(out, f.FinalHeader())
+	}
+	return out, nil
+}
+
+// TranscodeWAVStream returns src, a WAV stream of unknown length, converted by
+// process, which must be streamable, and encoded as format with the bit rate
+// and quality of opts. Audio is converted and encoded as it arrives, so the
+// output can be relayed while src is still being produced. Closing the
+// returned reader stops the transcoding at the next read of src.
+func TranscodeWAVStream(src io.Reader, format string, process ProcessOptions, opts EncodeOptions) io.ReadCloser {
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(transcodeWAVStream(w, src, format, process, opts))
+	}()
+	return r
+}
+
+func transcodeWAVStream(dst io.Writer, src io.Reader, format string, process ProcessOptions, opts EncodeOptions) error {
+	chunk := make([]byte, 32<<10)
+	var head []byte
+	var header *WAVHeader
+	for header == nil {
+		n, err := src.Read(chunk)
+		head = append(head, chunk[:n]...)
+		h, parseErr := ParseWAVHeader(head)
+		switch {
+		case parseErr == nil:
+			header = h
+		case len(head) > maxStreamHeaderSize, errors.Is(err, io.EOF):
+			return parseErr
+		case err != nil:
+			return err
+		}
+	}
+	align := header.BlockAlign()
+	if align <= 0 {
+		return fmt.Errorf("%w: invalid block alignment", ErrInvalidWAV)
+	}
+
+	processor, rate, channels := newStreamProcessor(header.SampleRate, header.Channels, process)
+	opts.SampleRate, opts.Channels = rate, channels
+	enc, err := NewEncoder(format, opts)
+	if err != nil {
+		return err
+	}
+	defer enc.Close()
+
+	pending := head[header.DataOffset:]
+	for {
+		// Decode whole sample frames only; the rest waits for the next read.
+		whole := len(pending) - len(pending)%align
+		if whole > 0 {
+			samples, err := decodeSamples(header, pending[:whole])
+			if err != nil {
+				return err
+			}
+			out, err := enc.Encode(processor.process(samples, false))
+			if err != nil {
+				return err
+			}
+			if len(out) > 0 {
+				if _, err := dst.Write(out); err != nil {
+					return err
+				}
+			}
+			pending = append(pending[:0], pending[whole:]...)
+		}
+
+		n, err := src.Read(chunk)
+		pending = append(pending, chunk[:n]...)
+		if errors.Is(err, io.EOF) && n == 0 {
+			break
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+	}
+
+	out, err := enc.Encode(processor.process(nil, true))
+	if err != nil {
+		return err
+	}
+	tail, err := enc.Flush()
+	if err != nil {
+		return err
+	}
+	_, err = dst.Write(append(out, tail...))
+	return err
+}
@@ -0,0 +1,89 @@
+package audio
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncode(t *testing.T) {
+	assert.True(t, CanEncode(FormatPCM))
+	assert.False(t, CanEncode("unknown"))
+
+	p := &PCM{SampleRate: 8000, Channels: 2, Samples: []float32{0, 0.5, -0.5, 1}}
+	out, err := Encode(FormatPCM, p, EncodeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, EncodeWAV(p)[44:], out)
+
+	// The WAV header written for a stream is completed at the end.
+	out, err = Encode(FormatWAV, p, EncodeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, EncodeWAV(p), out)
+
+	_, err = Encode("unknown", p, EncodeOptions{})
+	assert.ErrorIs(t, err, ErrNoEncoder)
+}
+
+// streamWAV returns p as a WAV stream, with the data size left open.
+func streamWAV(p *PCM) []byte {
+	return append(WAVHeader16(p.SampleRate, p.Channels, 0), EncodeWAV(p)[44:]...)
+}
+
+func TestTranscodeWAVStream(t *testing.T) {
+	p := &PCM{SampleRate: 8000, Channels: 2, Samples: make([]float32, 1000)}
+	for i := range p.Samples {
+		p.Samples[i] = float32(i%200)/100 - 1
+	}
+	wav := streamWAV(p)
+
+	// One byte at a time splits the header and the sample frames.
+	out, err := io.ReadAll(TranscodeWAVStream(iotest.OneByteReader(bytes.NewReader(wav)), FormatPCM, ProcessOptions{}, EncodeOptions{}))
+	require.NoError(t, err)
+	decoded, err := DecodeWAV(wav)
+	require.NoError(t, err)
+	assert.Equal(t, EncodeWAV(decoded)[44:], out)
+
+	_, err = io.ReadAll(TranscodeWAVStream(bytes.NewReader(wav), "unknown", ProcessOptions{}, EncodeOptions{}))
+	assert.ErrorIs(t, err, ErrNoEncoder)
+
+	_, err = io.ReadAll(TranscodeWAVStream(bytes.NewReader([]byte("not a wav file")), FormatPCM, ProcessOptions{}, EncodeOptions{}))
+	assert.ErrorIs(t, err, ErrInvalidWAV)
+}
+
+func TestTranscodeWAVStream_Process(t *testing.T) {
+	src := sine(24000, 1, 440, 300*time.Millisecond)
+	decoded, err := DecodeWAV(EncodeWAV(src))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		opts ProcessOptions
+	}{
+		{"telephony", ProcessOptions{SampleRate: 8000, Channels: 1}},
+		{"stereo 48 kHz", ProcessOptions{SampleRate: 48000, Channels: 2}},
+		{"stereo", ProcessOptions{Channels: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := TranscodeWAVStream(iotest.HalfReader(bytes.NewReader(streamWAV(src))), FormatWAV, tt.opts, EncodeOptions{})
+			out, err := io.ReadAll(stream)
+			require.NoError(t, err)
+
+			// Streamed conversion matches converting the whole audio.
+			want := EncodeWAV(Process(decoded, tt.opts))
+			h, err := ParseWAVHeader(out)
+			require.NoError(t, err)
+			wantHeader, err := ParseWAVHeader(want)
+			require.NoError(t, err)
+			assert.Equal(t, wantHeader.SampleRate, h.SampleRate)
+			assert.Equal(t, wantHeader.Channels, h.Channels)
+			assert.Zero(t, h.DataSize, "a stream leaves its length open")
+			assert.Equal(t, want[44:], out[44:])
+		})
+	}
+}
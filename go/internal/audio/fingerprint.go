@@ -0,0 +1,64 @@
+package audio
+
+import "math"
+
+// Envelope summarizes audio as the RMS energy of bins equal-length segments of
+// the mono downmix, normalized to unit length. Re-encodings of the same clip
+// produce near-identical envelopes regardless of container or sample format.
+func Envelope(p *PCM, bins int) []float32 {
+	frames := p.Frames()
+	if frames == 0 || bins <= 0 {
+		return nil
+	}
+
+	envelope := make([]float32, bins)
+	var norm float64
+	for b := 0; b < bins; b++ {
+		start := b * frames / bins
+		end := (b + 1) * frames / bins
+		if end <= start {
+			continue
+		}
+
+		var sum float64
+		for f := start; f < end; f++ {
+			var mono float64
+			for c := 0; c < p.Channels; c++ {
+				mono += float64(p.Samples[f*p.Channels+c])
+			}
+			mono /= float64(p.Channels)
+			sum += mono * mono
+		}
+		rms := math.Sqrt(sum / float64(end-start))
+		envelope[b] = float32(rms)
+		norm += rms * rms
+	}
+
+	if norm == 0 {
+		return envelope
+	}
+	norm = math.Sqrt(norm)
+	for i := range envelope {
+		envelope[i] = float32(float64(envelope[i]) / norm)
+	}
+	return envelope
+}
+
+// CosineSimilarity returns the cosine similarity of two vectors, or 0 when their
+// lengths differ or either is all zeros.
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
@@ -0,0 +1,513 @@
+package audio
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"hash"
+)
+
+const (
+	// flacBlockSize is the number of samples per channel in a FLAC frame,
+	// the largest block of the streamable subset.
+	flacBlockSize = 4096
+	// flacBitsPerSample is the sample depth of encoded FLAC, that of the WAV
+	// fish-server writes.
+	flacBitsPerSample = 16
+	// flacMaxRiceParam is the largest Rice parameter of the 4-bit coding
+	// method; 15 is the escape code, which is not used.
+	flacMaxRiceParam = 14
+	// flacMaxPartitionOrder bounds the search for the number of Rice
+	// partitions of a residual.
+	flacMaxPartitionOrder = 6
+	// flacMaxFixedOrder is the highest order of the fixed predictors.
+	flacMaxFixedOrder = 4
+	// flacStreamInfoSize is the size of the "fLaC" marker and the STREAMINFO
+	// block, the only metadata written.
+	flacStreamInfoSize = 4 + 4 + 34
+)
+
+// Subframe types.
+const (
+	flacConstant = iota
+	flacVerbatim
+	flacFixed
+)
+
+// Channel assignments of a frame header besides independent channels, which
+// are coded as the number of channels minus one.
+const flacLeftSide = 8
+
+func init() {
+	RegisterEncoder(FormatFLAC, newFLACEncoder)
+}
+
+// flacEncoder encodes 16-bit FLAC (RFC 9639) in pure Go, with the fixed
+// predictors and Rice-coded residuals. Stereo is coded as left and side
+// channels when that is smaller.
+//
+// The STREAMINFO block written first leaves the totals unknown, so that the
+// output can be streamed; FinalHeader returns it completed.
+type flacEncoder struct {
+	channels int
+	rate     int
+	// rateCode is the sample rate code of frame headers and rateExtra the
+	// size in bits of the rate that follows them.
+	rateCode  uint64
+	rateExtra uint
+	pending   []int32
+	frame     uint64
+	total     uint64
+	minFrame  int
+	maxFrame  int
+	md5       hash.Hash
+	started   bool
+}
+
+func newFLACEncoder(opts EncodeOptions) (Encoder, error) {
+	if opts.Channels < 1 || opts.Channels > 8 {
+		return nil, fmt.Errorf("flac: %d channels are not supported", opts.Channels)
+	}
+	if opts.SampleRate <= 0 || opts.SampleRate >= 1<<20 {
+		return nil, fmt.Errorf("flac: invalid sample rate %d", opts.SampleRate)
+	}
+	e := &flacEncoder{channels: opts.Channels, rate: opts.SampleRate, md5: md5.New()}
+	e.rateCode, e.rateExtra = flacRateCode(opts.SampleRate)
+	return e, nil
+}
+
+// flacRateCode returns the frame header code of rate and the size in bits of
+// the rate written after the header, if any.
+func flacRateCode(rate int) (code uint64, extra uint) {
+	switch rate {
+	case 88200:
+		return 1, 0
+	case 176400:
+		return 2, 0
+	case 192000:
+		return 3, 0
+	case 8000:
+		return 4, 0
+	case 16000:
+		return 5, 0
+	case 22050:
+		return 6, 0
+	case 24000:
+		return 7, 0
+	case 32000:
+		return 8, 0
+	case 44100:
+		return 9, 0
+	case 48000:
+		return 10, 0
+	case 96000:
+		return 11, 0
+	}
+	switch {
+	case rate%1000 == 0 && rate/1000 < 256:
+		return 12, 8
+	case rate < 1<<16:
+		return 13, 16
+	case rate%10 == 0 && rate/10 < 1<<16:
+		return 14, 16
+	}
+	// Only in STREAMINFO.
+	return 0, 0
+}
+
+func (e *flacEncoder) Encode(samples []float32) ([]byte, error) {
+	out := e.header()
+	raw := make([]byte, 2*len(samples))
+	for i, s := range samples {
+		v := toInt16(s)
+		binary.LittleEndian.PutUint16(raw[i*2:], uint16(v))
+		e.pending = append(e.pending, int32(v))
+	}
+	// The MD5 signature covers the samples as little-endian PCM.
+	e.md5.Write(raw)
+
+	blockLen := flacBlockSize * e.channels
+	for len(e.pending) >= blockLen {
+		out = append(out, e.encodeFrame(e.pending[:blockLen])...)
+		e.pending = e.pending[blockLen:]
+	}
+	e.pending = append([]int32(nil), e.pending...)
+	return out, nil
+}
+
+func (e *flacEncoder) Flush() ([]byte, error) {
+	out := e.header()
+	if frames := len(e.pending) / e.channels; frames > 0 {
+		out = append(out, e.encodeFrame(e.pending[:frames*e.channels])...)
+	}
+	e.pending = nil
+	return out, nil
+}
+
+func (e *flacEncoder) Close() {}
+
+// FinalHeader returns the marker and STREAMINFO block with the total
+// samples, frame sizes and MD5 signature of the audio encoded so far.
+func (e *flacEncoder) FinalHeader() []byte {
+	return e.streamInfo(true)
+}
+
+// header returns the marker and STREAMINFO block the first time it is
+// called, and nil afterwards.
+func (e *flacEncoder) header() []byte {
+	if e.started {
+		return nil
+	}
+	e.started = true
+	return e.streamInfo(false)
+}
+
+func (e *flacEncoder) streamInfo(final bool) []byte {
+	var w bitWriter
+	w.buf = append(w.buf, "fLaC"...)
+	// Last metadata block, type 0 (STREAMINFO), 34 bytes.
+	w.write(1, 1)
+	w.write(0, 7)
+	w.write(34, 24)
+
+	w.write(flacBlockSize, 16)
+	w.write(flacBlockSize, 16)
+	var minFrame, maxFrame, total uint64
+	var sum []byte
+	if final {
+		minFrame, maxFrame, total = uint64(e.minFrame), uint64(e.maxFrame), e.total
+		sum = e.md5.Sum(nil)
+	}
+	w.write(minFrame, 24)
+	w.write(maxFrame, 24)
+	w.write(uint64(e.rate), 20)
+	w.write(uint64(e.channels-1), 3)
+	w.write(flacBitsPerSample-1, 5)
+	w.write(total>>32, 4)
+	w.write(total, 32)
+	if sum == nil {
+		sum = make([]byte, md5.Size)
+	}
+	w.buf = append(w.buf, sum...)
+	return w.buf
+}
+
+// encodeFrame encodes interleaved samples as one frame.
+func (e *flacEncoder) encodeFrame(samples []int32) []byte {
+	n := len(samples) / e.channels
+	channels := make([][]int32, e.channels)
+	for c := range channels {
+		channels[c] = make([]int32, n)
+		for i := range channels[c] {
+			channels[c][i] = samples[i*e.channels+c]
+		}
+	}
+
+	assignment := uint64(e.channels - 1)
+	subframes := make([]*flacSubframe, e.channels)
+	for c, ch := range channels {
+		subframes[c] = planSubframe(ch, flacBitsPerSample)
+	}
+	if e.channels == 2 {
+		side := make([]int32, n)
+		for i := range side {
+			side[i] = channels[0][i] - channels[1][i]
+		}
+		if s := planSubframe(side, flacBitsPerSample+1); s.bits < subframes[1].bits {
+			assignment = flacLeftSide
+			subframes[1] = s
+		}
+	}
+
+	var w bitWriter
+	w.write(0x3FFE, 14) // sync code
+	w.write(0, 1)
+	w.write(0, 1) // fixed block size
+	switch {
+	case n == flacBlockSize:
+		w.write(12, 4)
+	case n <= 256:
+		w.write(6, 4)
+	default:
+		w.write(7, 4)
+	}
+	w.write(e.rateCode, 4)
+	w.write(assignment, 4)
+	w.write(4, 3) // 16 bits per sample
+	w.write(0, 1)
+	w.writeUTF8(e.frame)
+	switch {
+	case n == flacBlockSize:
+	case n <= 256:
+		w.write(uint64(n-1), 8)
+	default:
+		w.write(uint64(n-1), 16)
+	}
+	switch e.rateExtra {
+	case 8:
+		w.write(uint64(e.rate/1000), 8)
+	case 16:
+		if e.rateCode == 14 {
+			w.write(uint64(e.rate/10), 16)
+		} else {
+			w.write(uint64(e.rate), 16)
+		}
+	}
+	w.buf = append(w.buf, flacCRC8(w.buf))
+
+	for _, s := range subframes {
+		s.write(&w)
+	}
+	w.align()
+	w.buf = binary.BigEndian.AppendUint16(w.buf, flacCRC16(w.buf))
+
+	e.frame++
+	e.total += uint64(n)
+	if e.minFrame == 0 || len(w.buf) < e.minFrame {
+		e.minFrame = len(w.buf)
+	}
+	e.maxFrame = max(e.maxFrame, len(w.buf))
+	return w.buf
+}
+
+// flacSubframe is the coding chosen for the samples of one channel.
+type flacSubframe struct {
+	kind    int
+	bps     uint
+	samples []int32
+	// order is the fixed predictor order, partitionOrder sets the number of
+	// Rice partitions of residual, and params their Rice parameters.
+	order          int
+	partitionOrder int
+	params         []int
+	residual       []uint32
+	// bits is the size of the subframe.
+	bits int
+}
+
+// planSubframe returns the smallest coding of samples, of bps bits each.
+func planSubframe(samples []int32, bps uint) *flacSubframe {
+	constant := true
+	for _, v := range samples[1:] {
+		if v != samples[0] {
+			constant = false
+			break
+		}
+	}
+	if constant {
+		return &flacSubframe{kind: flacConstant, bps: bps, samples: samples, bits: 8 + int(bps)}
+	}
+
+	best := &flacSubframe{kind: flacVerbatim, bps: bps, samples: samples, bits: 8 + len(samples)*int(bps)}
+	for order := 0; order <= flacMaxFixedOrder && order < len(samples); order++ {
+		residual := fixedResidual(samples, order)
+		partitionOrder, params, bits := planRice(residual, len(samples), order)
+		bits += 8 + order*int(bps) + 2 + 4
+		if bits < best.bits {
+			best = &flacSubframe{
+				kind: flacFixed, bps: bps, samples: samples,
+				order: order, partitionOrder: partitionOrder, params: params, residual: residual,
+				bits: bits,
+			}
+		}
+	}
+	return best
+}
+
+// fixedResidual returns the zigzag-folded prediction errors of the fixed
+// predictor of order for samples[order:].
+func fixedResidual(samples []int32, order int) []uint32 {
+	residual := make([]uint32, len(samples)-order)
+	for i := order; i < len(samples); i++ {
+		var r int32
+		x := samples
+		switch order {
+		case 0:
+			r = x[i]
+		case 1:
+			r = x[i] - x[i-1]
+		case 2:
+			r = x[i] - 2*x[i-1] + x[i-2]
+		case 3:
+			r = x[i] - 3*x[i-1] + 3*x[i-2] - x[i-3]
+		case 4:
+			r = x[i] - 4*x[i-1] + 6*x[i-2] - 4*x[i-3] + x[i-4]
+		}
+		residual[i-order] = uint32(r<<1) ^ uint32(r>>31)
+	}
+	return residual
+}
+
+// planRice picks the partition order and Rice parameters of residual, the
+// errors after the first order of blockSize samples, and returns them with
+// an estimate of the size of the coded partitions in bits.
+func planRice(residual []uint32, blockSize, order int) (partitionOrder int, params []int, bits int) {
+	bits = -1
+	for p := 0; p <= flacMaxPartitionOrder; p++ {
+		size := blockSize >> p
+		if blockSize%(1<<p) != 0 || size <= order {
+			break
+		}
+		ps := make([]int, 1<<p)
+		total := 0
+		start := 0
+		for j := range ps {
+			count := size
+			if j == 0 {
+				count -= order
+			}
+			var sum uint64
+			for _, u := range residual[start : start+count] {
+				sum += uint64(u)
+			}
+			start += count
+
+			k := 0
+			for k < flacMaxRiceParam && uint64(count)<<(k+1) < sum {
+				k++
+			}
+			ps[j] = k
+			total += 4 + count*(k+1) + int(sum>>k)
+		}
+		if bits < 0 || total < bits {
+			partitionOrder, params, bits = p, ps, total
+		}
+	}
+	return partitionOrder, params, bits
+}
+
+func (s *flacSubframe) write(w *bitWriter) {
+	w.write(0, 1)
+	switch s.kind {
+	case flacConstant:
+		w.write(0, 6)
+		w.write(0, 1)
+		w.write(uint64(s.samples[0]), s.bps)
+	case flacVerbatim:
+		w.write(1, 6)
+		w.write(0, 1)
+		for _, v := range s.samples {
+			w.write(uint64(v), s.bps)
+		}
+	case flacFixed:
+		w.write(uint64(8|s.order), 6)
+		w.write(0, 1)
+		for _, v := range s.samples[:s.order] {
+			w.write(uint64(v), s.bps)
+		}
+		w.write(0, 2) // Rice coding with 4-bit parameters
+		w.write(uint64(s.partitionOrder), 4)
+		residual := s.residual
+		size := len(s.samples) >> s.partitionOrder
+		for j, k := range s.params {
+			count := size
+			if j == 0 {
+				count -= s.order
+			}
+			w.write(uint64(k), 4)
+			for _, u := range residual[:count] {
+				w.writeRice(u, uint(k))
+			}
+			residual = residual[count:]
+		}
+	}
+}
+
+// bitWriter packs values most significant bit first.
+type bitWriter struct {
+	buf []byte
+	acc uint64
+	n   uint
+}
+
+// write appends the low bits of v; bits must not exceed 32.
+func (w *bitWriter) write(v uint64, bits uint) {
+	w.acc = w.acc<<bits | v&(1<<bits-1)
+	w.n += bits
+	for w.n >= 8 {
+		w.n -= 8
+		w.buf = append(w.buf, byte(w.acc>>w.n))
+	}
+}
+
+// writeRice appends u Rice-coded with parameter k: the quotient in unary,
+// zeros ended by a one, then the k low bits.
+func (w *bitWriter) writeRice(u uint32, k uint) {
+	q := uint(u >> k)
+	for q >= 32 {
+		w.write(0, 32)
+		q -= 32
+	}
+	if q+1+k <= 32 {
+		w.write(1<<k|uint64(u)&(1<<k-1), q+1+k)
+		return
+	}
+	w.write(1, q+1)
+	w.write(uint64(u), k)
+}
+
+// writeUTF8 appends v in the extended UTF-8 coding of frame numbers.
+func (w *bitWriter) writeUTF8(v uint64) {
+	if v < 0x80 {
+		w.write(v, 8)
+		return
+	}
+	// With n continuation bytes of 6 bits, the first byte holds 6-n bits.
+	n := uint(1)
+	for v >= 1<<(5*n+6) {
+		n++
+	}
+	w.write(0xFF<<(7-n)&0xFF|v>>(6*n), 8)
+	for i := int(n) - 1; i >= 0; i-- {
+		w.write(0x80|v>>(6*uint(i))&0x3F, 8)
+	}
+}
+
+// align pads the output with zeros to a whole byte.
+func (w *bitWriter) align() {
+	if w.n > 0 {
+		w.write(0, 8-w.n)
+	}
+}
+
+var (
+	flacCRC8Table  = crcTable(8, 0x07)
+	flacCRC16Table = crcTable(16, 0x8005)
+)
+
+// crcTable returns the table of a CRC of width bits with polynomial poly,
+// computed most significant bit first.
+func crcTable(width uint, poly uint32) (table [256]uint32) {
+	top := uint32(1) << (width - 1)
+	mask := uint32(1)<<width - 1
+	for i := range table {
+		r := uint32(i) << (width - 8)
+		for j := 0; j < 8; j++ {
+			if r&top != 0 {
+				r = r<<1 ^ poly
+			} else {
+				r <<= 1
+			}
+		}
+		table[i] = r & mask
+	}
+	return table
+}
+
+// flacCRC8 is the checksum of frame headers.
+func flacCRC8(data []byte) byte {
+	var crc byte
+	for _, b := range data {
+		crc = byte(flacCRC8Table[crc^b])
+	}
+	return crc
+}
+
+// flacCRC16 is the checksum of whole frames.
+func flacCRC16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc = crc<<8 ^ uint16(flacCRC16Table[byte(crc>>8)^b])
+	}
+	return crc
+}
@@ -0,0 +1,251 @@
+package audio
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bitReader reads values most significant bit first.
+type bitReader struct {
+	data []byte
+	pos  int // in bits
+}
+
+func (r *bitReader) read(bits int) uint64 {
+	var v uint64
+	for i := 0; i < bits; i++ {
+		bit := r.data[r.pos/8] >> (7 - r.pos%8) & 1
+		v = v<<1 | uint64(bit)
+		r.pos++
+	}
+	return v
+}
+
+func (r *bitReader) signed(bits int) int32 {
+	v := r.read(bits)
+	return int32(int64(v<<(64-bits)) >> (64 - bits))
+}
+
+func (r *bitReader) align() { r.pos = (r.pos + 7) / 8 * 8 }
+
+// decodeFLAC decodes the subset of FLAC the encoder writes, checking the
+// frame checksums, and returns the STREAMINFO fields and the interleaved
+// samples.
+func decodeFLAC(t *testing.T, data []byte) (rate, channels int, total uint64, sum []byte, samples []int32) {
+	t.Helper()
+	require.Equal(t, "fLaC", string(data[:4]))
+	r := &bitReader{data: data, pos: 32}
+	require.Equal(t, uint64(1), r.read(1), "STREAMINFO is the last block")
+	require.Equal(t, uint64(0), r.read(7))
+	require.Equal(t, uint64(34), r.read(24))
+	r.read(16 + 16 + 24 + 24)
+	rate = int(r.read(20))
+	channels = int(r.read(3)) + 1
+	require.Equal(t, uint64(15), r.read(5))
+	total = r.read(36)
+	sum = data[26:42]
+
+	r.pos = flacStreamInfoSize * 8
+	for frame := uint64(0); r.pos < len(data)*8; frame++ {
+		start := r.pos / 8
+		require.Equal(t, uint64(0x3FFE), r.read(14))
+		r.read(2)
+		blockCode := r.read(4)
+		rateCode := r.read(4)
+		assignment := r.read(4)
+		require.Equal(t, uint64(4), r.read(3))
+		r.read(1)
+		first := r.read(8)
+		n := 0
+		for first&0x80 != 0 {
+			n++
+			first <<= 1
+		}
+		number := (first & 0xFF) >> n
+		for i := 1; i < n; i++ {
+			number = number<<6 | r.read(8)&0x3F
+		}
+		require.Equal(t, frame, number)
+
+		size := flacBlockSize
+		switch blockCode {
+		case 6:
+			size = int(r.read(8)) + 1
+		case 7:
+			size = int(r.read(16)) + 1
+		default:
+			require.Equal(t, uint64(12), blockCode)
+		}
+		switch rateCode {
+		case 12:
+			r.read(8)
+		case 13, 14:
+			r.read(16)
+		}
+		require.Equal(t, flacCRC8(data[start:r.pos/8]), byte(r.read(8)), "header checksum")
+
+		decoded := make([][]int32, channels)
+		for c := range decoded {
+			bps := 16
+			if assignment == flacLeftSide && c == 1 {
+				bps = 17
+			}
+			decoded[c] = decodeSubframe(t, r, size, bps)
+		}
+		if assignment == flacLeftSide {
+			for i := range decoded[1] {
+				decoded[1][i] = decoded[0][i] - decoded[1][i]
+			}
+		}
+		r.align()
+		end := r.pos / 8
+		require.Equal(t, flacCRC16(data[start:end]), uint16(r.read(16)), "frame checksum")
+
+		for i := 0; i < size; i++ {
+			for c := range decoded {
+				samples = append(samples, decoded[c][i])
+			}
+		}
+	}
+	return rate, channels, total, sum, samples
+}
+
+func decodeSubframe(t *testing.T, r *bitReader, size, bps int) []int32 {
+	require.Equal(t, uint64(0), r.read(1))
+	kind := r.read(6)
+	require.Equal(t, uint64(0), r.read(1), "no wasted bits")
+	out := make([]int32, size)
+	switch {
+	case kind == 0:
+		v := r.signed(bps)
+		for i := range out {
+			out[i] = v
+		}
+	case kind == 1:
+		for i := range out {
+			out[i] = r.signed(bps)
+		}
+	case kind&0x38 == 8:
+		order := int(kind & 7)
+		for i := 0; i < order; i++ {
+			out[i] = r.signed(bps)
+		}
+		require.Equal(t, uint64(0), r.read(2))
+		partitionOrder := int(r.read(4))
+		i := order
+		for j := 0; j < 1<<partitionOrder; j++ {
+			k := int(r.read(4))
+			count := size >> partitionOrder
+			if j == 0 {
+				count -= order
+			}
+			for ; count > 0; count-- {
+				q := uint64(0)
+				for r.read(1) == 0 {
+					q++
+				}
+				u := q<<k | r.read(k)
+				res := int32(u>>1) ^ -int32(u&1)
+				x := out
+				switch order {
+				case 0:
+					x[i] = res
+				case 1:
+					x[i] = res + x[i-1]
+				case 2:
+					x[i] = res + 2*x[i-1] - x[i-2]
+				case 3:
+					x[i] = res + 3*x[i-1] - 3*x[i-2] + x[i-3]
+				case 4:
+					x[i] = res + 4*x[i-1] - 6*x[i-2] + 4*x[i-3] - x[i-4]
+				}
+				i++
+			}
+		}
+	default:
+		t.Fatalf("unexpected subframe type %d", kind)
+	}
+	return out
+}
+
+func TestFLACEncoder(t *testing.T) {
+	tests := []struct {
+		name     string
+		rate     int
+		channels int
+		frames   int
+		sample   func(i, c int) float32
+	}{
+		{"silence", 24000, 1, 5000, func(i, c int) float32 { return 0 }},
+		{"tone", 44100, 1, 10000, func(i, c int) float32 {
+			return float32(0.5 * math.Sin(float64(i)*0.05))
+		}},
+		{"identical stereo", 22050, 2, 4096, func(i, c int) float32 {
+			return float32(0.3 * math.Sin(float64(i)*0.01))
+		}},
+		{"noisy stereo", 11025, 2, 300, func(i, c int) float32 {
+			return float32((i*7919+c*104729)%2000)/1000 - 1
+		}},
+		{"full scale", 96000, 1, 1, func(i, c int) float32 { return -1 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &PCM{SampleRate: tt.rate, Channels: tt.channels, Samples: make([]float32, tt.frames*tt.channels)}
+			want := make([]int32, len(p.Samples))
+			raw := make([]byte, 2*len(p.Samples))
+			for i := range p.Samples {
+				p.Samples[i] = tt.sample(i/tt.channels, i%tt.channels)
+				want[i] = int32(toInt16(p.Samples[i]))
+				binary.LittleEndian.PutUint16(raw[i*2:], uint16(want[i]))
+			}
+
+			out, err := Encode(FormatFLAC, p, EncodeOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, FormatFLAC, DetectFormat(out))
+			d, ok := Duration(out)
+			assert.True(t, ok)
+			assert.InDelta(t, float64(p.Duration()), float64(d), float64(time.Microsecond))
+
+			rate, channels, total, sum, samples := decodeFLAC(t, out)
+			assert.Equal(t, tt.rate, rate)
+			assert.Equal(t, tt.channels, channels)
+			assert.Equal(t, uint64(tt.frames), total)
+			want16 := md5.Sum(raw)
+			assert.Equal(t, want16[:], sum)
+			assert.Equal(t, want, samples, "lossless")
+		})
+	}
+}
+
+func TestFLACEncoder_Stream(t *testing.T) {
+	p := &PCM{SampleRate: 16000, Channels: 1, Samples: make([]float32, 9000)}
+	for i := range p.Samples {
+		p.Samples[i] = float32(math.Sin(float64(i) * 0.02))
+	}
+	whole, err := Encode(FormatFLAC, p, EncodeOptions{})
+	require.NoError(t, err)
+
+	enc, err := NewEncoder(FormatFLAC, EncodeOptions{SampleRate: 16000, Channels: 1})
+	require.NoError(t, err)
+	defer enc.Close()
+	var out []byte
+	for i := 0; i < len(p.Samples); i += 1000 {
+		data, err := enc.Encode(p.Samples[i:min(i+1000, len(p.Samples))])
+		require.NoError(t, err)
+		out = append(out, data...)
+	}
+	tail, err := enc.Flush()
+	require.NoError(t, err)
+	out = append(out, tail...)
+
+	// Streamed output leaves the totals of STREAMINFO unknown.
+	_, ok := Duration(out)
+	assert.False(t, ok)
+	assert.Equal(t, whole[flacStreamInfoSize:], out[flacStreamInfoSize:])
+}
@@ -0,0 +1,19 @@
+package audio
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeFloat16(t *testing.T) {
+	encoded := EncodeFloat16([]float32{0, 1, -1, 0.5, -2, 65504, 1e6})
+
+	values := make([]uint16, len(encoded)/2)
+	for i := range values {
+		values[i] = binary.LittleEndian.Uint16(encoded[i*2:])
+	}
+
+	assert.Equal(t, []uint16{0x0000, 0x3c00, 0xbc00, 0x3800, 0xc000, 0x7bff, 0x7c00}, values)
+}
@@ -0,0 +1,194 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"path"
+	"strings"
+	"time"
+)
+
+// Container formats recognized by DetectFormat. The backend decodes WAV, MP3,
+// FLAC and Ogg; the others are recognized so they can be rejected by name.
+const (
+	FormatWAV  = "wav"
+	FormatMP3  = "mp3"
+	FormatFLAC = "flac"
+	FormatOGG  = "ogg"
+	FormatM4A  = "m4a"
+	FormatAAC  = "aac"
+	FormatWebM = "webm"
+)
+
+// ErrUnsupportedFormat is returned by Sniff for audio the backend cannot
+// decode, or whose content does not match its declared format.
+var ErrUnsupportedFormat = errors.New("unsupported audio format")
+
+// DetectFormat identifies the container format of encoded audio from its magic
+// bytes. It returns "" when the format is not recognized.
+func DetectFormat(data []byte) string {
+	switch {
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WAVE":
+		return FormatWAV
+	case bytes.HasPrefix(data, []byte("fLaC")):
+		return FormatFLAC
+	case bytes.HasPrefix(data, []byte("OggS")):
+		return FormatOGG
+	case len(data) >= 8 && string(data[4:8]) == "ftyp":
+		return FormatM4A
+	case bytes.HasPrefix(data, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		// EBML header; Matroska audio is treated as WebM.
+		return FormatWebM
+	case bytes.HasPrefix(data, []byte("ID3")):
+		return FormatMP3
+	case len(data) >= 2 && data[0] == 0xFF && data[1]&0xE0 == 0xE0:
+		// Frame sync. ADTS (raw AAC) frames have layer bits 00, MPEG audio
+		// layers I-III do not.
+		if data[1]&0x06 == 0 {
+			return FormatAAC
+		}
+		return FormatMP3
+	}
+	return ""
+}
+
+// wavCodecs names the WAV codecs the backend can decode.
+var wavCodecs = map[int]string{
+	wavFormatPCM:   "PCM",
+	wavFormatFloat: "IEEE float",
+	6:              "A-law",
+	7:              "mu-law",
+}
+
+// Sniff identifies encoded audio from its content rather than its name and
+// checks that the backend can decode it. declared is the format claimed by
+// the file name or Content-Type (see DeclaredFormat); when set, the content
+// must match it. Unrecognized data with no declared format is passed through
+// with an empty format, leaving the decision to the backend.
+//
+// The returned error wraps ErrUnsupportedFormat.
+func Sniff(data []byte, declared string) (string, error) {
+	return sniff(data, declared, false)
+}
+
+// SniffDecodable is Sniff for audio that is decoded before it reaches the
+// backend: formats the backend cannot decode are accepted too when this
+// build has a decoder for them.
+func SniffDecodable(data []byte, declared string) (string, error) {
+	return sniff(data, declared, true)
+}
+
+func sniff(data []byte, declared string, decodable bool) (string, error) {
+	format := DetectFormat(data)
+	switch format {
+	case FormatM4A, FormatAAC, FormatWebM:
+		if decodable && CanDecode(format) {
+			break
+		}
+		return format, fmt.Errorf("%w: %s is not supported, use wav, mp3, flac, or ogg", ErrUnsupportedFormat, format)
+	case FormatWAV:
+		h, err := ParseWAVHeader(data)
+		if err != nil {
+			return format, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+		}
+		if _, ok := wavCodecs[h.Format]; !ok {
+			codec := fmt.Sprintf("codec 0x%04x", h.Format)
+			if h.Format == 0x55 || h.Format == 0x50 {
+				codec = "MPEG audio"
+			}
+			return format, fmt.Errorf("%w: WAV file contains %s, not PCM", ErrUnsupportedFormat, codec)
+		}
+	}
+
+	if declared != "" && format != declared {
+		found := format
+		if found == "" {
+			found = "unrecognized data"
+		}
+		return format, fmt.Errorf("%w: declared as %s but contains %s", ErrUnsupportedFormat, declared, found)
+	}
+	return format, nil
+}
+
+var extensionFormats = map[string]string{
+	".wav":  FormatWAV,
+	".wave": FormatWAV,
+	".mp3":  FormatMP3,
+	".flac": FormatFLAC,
+	".ogg":  FormatOGG,
+	".oga":  FormatOGG,
+	".opus": FormatOGG,
+	".m4a":  FormatM4A,
+	".mp4":  FormatM4A,
+	".aac":  FormatAAC,
+	".webm": FormatWebM,
+}
+
+var mimeFormats = map[string]string{
+	"audio/wav":       FormatWAV,
+	"audio/wave":      FormatWAV,
+	"audio/x-wav":     FormatWAV,
+	"audio/vnd.wave":  FormatWAV,
+	"audio/mpeg":      FormatMP3,
+	"audio/mp3":       FormatMP3,
+	"audio/flac":      FormatFLAC,
+	"audio/x-flac":    FormatFLAC,
+	"audio/ogg":       FormatOGG,
+	"audio/opus":      FormatOGG,
+	"audio/mp4":       FormatM4A,
+	"audio/x-m4a":     FormatM4A,
+	"audio/aac":       FormatAAC,
+	"audio/webm":      FormatWebM,
+	"video/webm":      FormatWebM,
+	"application/ogg": FormatOGG,
+}
+
+// DeclaredFormat returns the format claimed by an upload's Content-Type or,
+// failing that, its file name extension. Generic types such as
+// application/octet-stream and unknown extensions declare nothing.
+func DeclaredFormat(filename, contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if format, ok := mimeFormats[mediaType]; ok {
+			return format
+		}
+	}
+	return extensionFormats[strings.ToLower(path.Ext(filename))]
+}
+
+// Duration returns the playback duration of WAV or FLAC data from its header,
+// without decoding it. ok is false for other formats or when the header does
+// not declare a length.
+func Duration(data []byte) (d time.Duration, ok bool) {
+	switch DetectFormat(data) {
+	case FormatWAV:
+		h, err := ParseWAVHeader(data)
+		if err != nil || h.SampleRate == 0 || h.BlockAlign() == 0 {
+			return 0, false
+		}
+		size := len(data) - h.DataOffset
+		if h.DataSize != 0 && h.DataSize != math.MaxUint32 && int(h.DataSize) < size {
+			size = int(h.DataSize)
+		}
+		frames := size / h.BlockAlign()
+		return time.Duration(frames) * time.Second / time.Duration(h.SampleRate), true
+	case FormatFLAC:
+		// The STREAMINFO block always comes first: a 4-byte block header, then
+		// packed sample rate (20 bits), channels (3), bits per sample (5) and
+		// total samples (36) at byte 10 of the block.
+		if len(data) < 26 || data[4]&0x7F != 0 {
+			return 0, false
+		}
+		v := binary.BigEndian.Uint64(data[18:26])
+		rate := v >> 44
+		total := v & (1<<36 - 1)
+		if rate == 0 || total == 0 {
+			return 0, false
+		}
+		return time.Duration(float64(total) / float64(rate) * float64(time.Second)), true
+	}
+	return 0, false
+}
@@ -0,0 +1,101 @@
+package audio
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flacHeader returns a FLAC stream marker and STREAMINFO block.
+func flacHeader(rate, totalSamples uint64) []byte {
+	data := append([]byte("fLaC"), 0x80, 0, 0, 34)
+	info := make([]byte, 34)
+	binary.BigEndian.PutUint64(info[10:], rate<<44|1<<41|15<<36|totalSamples)
+	return append(data, info...)
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"wav", EncodeWAV(sine(16000, 1, 440, 10*time.Millisecond)), FormatWAV},
+		{"flac", flacHeader(44100, 44100), FormatFLAC},
+		{"ogg", []byte("OggS\x00\x02"), FormatOGG},
+		{"mp3 with id3", []byte("ID3\x04\x00"), FormatMP3},
+		{"mp3 frame", []byte{0xFF, 0xFB, 0x90, 0x00}, FormatMP3},
+		{"adts aac", []byte{0xFF, 0xF1, 0x50, 0x80}, FormatAAC},
+		{"m4a", []byte("\x00\x00\x00\x20ftypM4A "), FormatM4A},
+		{"webm", []byte{0x1A, 0x45, 0xDF, 0xA3, 0x9F}, FormatWebM},
+		{"unknown", []byte("not audio"), ""},
+		{"empty", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DetectFormat(tt.data))
+		})
+	}
+}
+
+func TestDuration(t *testing.T) {
+	d, ok := Duration(EncodeWAV(sine(16000, 2, 440, 1500*time.Millisecond)))
+	assert.True(t, ok)
+	assert.Equal(t, 1500*time.Millisecond, d)
+
+	d, ok = Duration(flacHeader(48000, 48000*3))
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, d)
+
+	_, ok = Duration([]byte("ID3\x04\x00"))
+	assert.False(t, ok)
+}
+
+func TestSniff(t *testing.T) {
+	wav := EncodeWAV(sine(16000, 1, 440, 10*time.Millisecond))
+	mp3 := []byte("ID3\x04\x00mp3 frames")
+
+	// An MP3 stream wrapped in a RIFF header (format tag 0x55).
+	mp3InWAV := append([]byte(nil), wav...)
+	binary.LittleEndian.PutUint16(mp3InWAV[20:], 0x55)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		declared string
+		want     string
+		err      string
+	}{
+		{"wav", wav, "", FormatWAV, ""},
+		{"declared wav", wav, FormatWAV, FormatWAV, ""},
+		{"mp3 renamed to wav", mp3, FormatWAV, FormatMP3, "declared as wav but contains mp3"},
+		{"mp3 in wav container", mp3InWAV, FormatWAV, FormatWAV, "WAV file contains MPEG audio"},
+		{"m4a", []byte("\x00\x00\x00\x20ftypM4A "), "", FormatM4A, "m4a is not supported"},
+		{"webm", []byte{0x1A, 0x45, 0xDF, 0xA3}, FormatWebM, FormatWebM, "webm is not supported"},
+		{"unrecognized", []byte("not audio"), "", "", ""},
+		{"unrecognized declared", []byte("not audio"), FormatFLAC, "", "declared as flac but contains unrecognized data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Sniff(tt.data, tt.declared)
+			assert.Equal(t, tt.want, got)
+			if tt.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrUnsupportedFormat)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestDeclaredFormat(t *testing.T) {
+	assert.Equal(t, FormatWAV, DeclaredFormat("voice.WAV", "application/octet-stream"))
+	assert.Equal(t, FormatMP3, DeclaredFormat("voice.wav", "audio/mpeg"))
+	assert.Equal(t, FormatOGG, DeclaredFormat("", "audio/ogg; codecs=opus"))
+	assert.Equal(t, "", DeclaredFormat("voice", ""))
+	assert.Equal(t, "", DeclaredFormat("voice.txt", "text/plain"))
+}
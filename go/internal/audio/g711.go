@@ -0,0 +1,97 @@
+package audio
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// G.711 telephony formats: headerless 8 kHz mono audio, one companded byte
+// per sample, as used by SIP, Asterisk and Twilio.
+const (
+	FormatULaw = "ulaw"
+	FormatALaw = "alaw"
+	// G711Rate is the sample rate of G.711 audio.
+	G711Rate = 8000
+)
+
+func init() {
+	RegisterEncoder(FormatULaw, func(opts EncodeOptions) (Encoder, error) {
+		return newG711Encoder(opts, linearToULaw)
+	})
+	RegisterEncoder(FormatALaw, func(opts EncodeOptions) (Encoder, error) {
+		return newG711Encoder(opts, linearToALaw)
+	})
+}
+
+// g711Encoder compands audio, which it first resamples to G711Rate and mixes
+// down to mono.
+type g711Encoder struct {
+	processor *streamProcessor
+	compand   func(int16) byte
+}
+
+func newG711Encoder(opts EncodeOptions, compand func(int16) byte) (*g711Encoder, error) {
+	if opts.SampleRate <= 0 || opts.Channels <= 0 {
+		return nil, fmt.Errorf("g711: invalid audio: %d Hz, %d channels", opts.SampleRate, opts.Channels)
+	}
+	processor, _, _ := newStreamProcessor(opts.SampleRate, opts.Channels, ProcessOptions{SampleRate: G711Rate, Channels: 1})
+	return &g711Encoder{processor: processor, compand: compand}, nil
+}
+
+func (e *g711Encoder) Encode(samples []float32) ([]byte, error) {
+	return e.encode(e.processor.process(samples, false)), nil
+}
+
+func (e *g711Encoder) Flush() ([]byte, error) {
+	return e.encode(e.processor.process(nil, true)), nil
+}
+
+func (e *g711Encoder) Close() {}
+
+func (e *g711Encoder) encode(samples []float32) []byte {
+	out := make([]byte, len(samples))
+	for i, s := range samples {
+		out[i] = e.compand(toInt16(s))
+	}
+	return out
+}
+
+// linearToULaw compands a sample to μ-law (ITU-T G.711) from its 14 most
+// significant bits.
+func linearToULaw(sample int16) byte {
+	const (
+		bias = 0x84 >> 2
+		clip = 8159
+	)
+	v := int(sample) >> 2
+	mask := byte(0xFF)
+	if v < 0 {
+		v = -v
+		mask = 0x7F
+	}
+	v = min(v, clip) + bias
+
+	// The segment is the position of the highest bit above the 6 lowest.
+	segment := max(bits.Len(uint(v))-6, 0)
+	if segment > 7 {
+		return 0x7F ^ mask
+	}
+	return byte(segment<<4|(v>>(segment+1))&0x0F) ^ mask
+}
+
+// linearToALaw compands a sample to A-law (ITU-T G.711) from its 13 most
+// significant bits.
+func linearToALaw(sample int16) byte {
+	v := int(sample) >> 3
+	mask := byte(0xD5)
+	if v < 0 {
+		v = -v - 1
+		mask = 0x55
+	}
+
+	// The segment is the position of the highest bit above the 5 lowest;
+	// segments 0 and 1 share a step size.
+	segment := max(bits.Len(uint(v))-5, 0)
+	shift := max(segment, 1)
+	return byte(segment<<4|(v>>shift)&0x0F) ^ mask
+}
@@ -0,0 +1,82 @@
+package audio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ulawToLinear and alawToLinear expand G.711 bytes as decoders do.
+func ulawToLinear(b byte) int {
+	b = ^b
+	v := (int(b&0x0F)<<3 + 0x84) << (b & 0x70 >> 4)
+	if b&0x80 != 0 {
+		return 0x84 - v
+	}
+	return v - 0x84
+}
+
+func alawToLinear(b byte) int {
+	b ^= 0x55
+	segment := int(b & 0x70 >> 4)
+	v := int(b&0x0F) << 4
+	switch segment {
+	case 0:
+		v += 8
+	case 1:
+		v += 0x108
+	default:
+		v = (v + 0x108) << (segment - 1)
+	}
+	if b&0x80 != 0 {
+		return v
+	}
+	return -v
+}
+
+func TestG711Companding(t *testing.T) {
+	assert.Equal(t, byte(0xFF), linearToULaw(0))
+	assert.Equal(t, byte(0x80), linearToULaw(32767))
+	assert.Equal(t, byte(0x00), linearToULaw(-32768))
+	assert.Equal(t, byte(0xD5), linearToALaw(0))
+	assert.Equal(t, byte(0xAA), linearToALaw(32767))
+	assert.Equal(t, byte(0x2A), linearToALaw(-32768))
+
+	// The error grows with the magnitude, by at most half a step of the
+	// segment: 1/32 of the value, plus the smallest step.
+	for s := -32768; s <= 32767; s += 7 {
+		tolerance := float64(abs(s))/32 + 16
+		assert.InDelta(t, s, ulawToLinear(linearToULaw(int16(s))), tolerance, "μ-law %d", s)
+		assert.InDelta(t, s, alawToLinear(linearToALaw(int16(s))), tolerance, "A-law %d", s)
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func TestG711Encoder(t *testing.T) {
+	src := sine(24000, 2, 440, 500*time.Millisecond)
+
+	for _, format := range []string{FormatULaw, FormatALaw} {
+		t.Run(format, func(t *testing.T) {
+			out, err := Encode(format, src, EncodeOptions{})
+			require.NoError(t, err)
+			assert.Len(t, out, 4000, "8 kHz mono, a byte per sample")
+
+			expand := ulawToLinear
+			if format == FormatALaw {
+				expand = alawToLinear
+			}
+			want := Process(src, ProcessOptions{SampleRate: G711Rate, Channels: 1})
+			for i := 1000; i < 1100; i++ {
+				assert.InDelta(t, want.Samples[i], float32(expand(out[i]))/32768, 0.02)
+			}
+		})
+	}
+}
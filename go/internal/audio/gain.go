@@ -0,0 +1,37 @@
+package audio
+
+import "math"
+
+// Peak returns the largest absolute sample value in p.
+func Peak(p *PCM) float32 {
+	var peak float32
+	for _, s := range p.Samples {
+		if s < 0 {
+			s = -s
+		}
+		if s > peak {
+			peak = s
+		}
+	}
+	return peak
+}
+
+// ApplyGain scales p by db decibels. If the gain would push the peak above full
+// scale, it is reduced so the loudest sample lands exactly at full scale rather
+// than clipping.
+func ApplyGain(p *PCM, db float64) *PCM {
+	if db == 0 {
+		return p
+	}
+
+	gain := float32(math.Pow(10, db/20))
+	if peak := Peak(p); peak > 0 && peak*gain > 1 {
+		gain = 1 / peak
+	}
+
+	out := &PCM{SampleRate: p.SampleRate, Channels: p.Channels, Samples: make([]float32, len(p.Samples))}
+	for i, s := range p.Samples {
+		out.Samples[i] = s * gain
+	}
+	return out
+}
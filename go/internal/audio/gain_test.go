@@ -0,0 +1,28 @@
+package audio
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyGain(t *testing.T) {
+	p := &PCM{SampleRate: 16000, Channels: 1, Samples: []float32{0.1, -0.2}}
+
+	louder := ApplyGain(p, 6)
+	assert.InDeltaSlice(t, []float32{0.1995, -0.3991}, louder.Samples, 1e-3)
+
+	quieter := ApplyGain(p, -6)
+	assert.InDeltaSlice(t, []float32{0.0501, -0.1002}, quieter.Samples, 1e-3)
+
+	assert.Same(t, p, ApplyGain(p, 0))
+}
+
+func TestApplyGain_PreventsClipping(t *testing.T) {
+	p := &PCM{SampleRate: 16000, Channels: 1, Samples: []float32{0.5, -0.8}}
+
+	out := ApplyGain(p, 12)
+
+	assert.InDelta(t, 1, Peak(out), 1e-6)
+	assert.InDelta(t, 0.625, out.Samples[0], 1e-6)
+}
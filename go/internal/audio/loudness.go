@@ -0,0 +1,160 @@
+package audio
+
+import "math"
+
+const (
+	// loudnessBlock and loudnessStep are the gating block duration and the
+	// step between blocks, 400 ms overlapping by 75%, in seconds.
+	loudnessBlock = 0.4
+	loudnessStep  = 0.1
+	// loudnessAbsoluteGate and loudnessRelativeGate drop silent blocks, and
+	// blocks much quieter than the rest, from the measurement.
+	loudnessAbsoluteGate = -70.0
+	loudnessRelativeGate = -10.0
+	// LoudnessPeakCeiling is the highest sample peak, in dBFS, that
+	// NormalizeLoudness produces.
+	LoudnessPeakCeiling = -1.0
+)
+
+// Loudness returns the integrated loudness of p in LUFS, as defined by ITU-R
+// BS.1770-4 and EBU R128: the mean square of the K-weighted audio over gated
+// 400 ms blocks. Audio shorter than one block is measured as a whole. It is
+// -Inf for silence.
+func Loudness(p *PCM) float64 {
+	frames := p.Frames()
+	if frames == 0 {
+		return math.Inf(-1)
+	}
+
+	// power[i] is the sum over channels of the squared K-weighted samples of
+	// the first i frames.
+	power := make([]float64, frames+1)
+	for c := 0; c < p.Channels; c++ {
+		filters := kWeighting(p.SampleRate)
+		var sum float64
+		for i := 0; i < frames; i++ {
+			y := float64(p.Samples[i*p.Channels+c])
+			for f := range filters {
+				y = filters[f].process(y)
+			}
+			sum += y * y
+			power[i+1] += sum
+		}
+	}
+
+	block := int(math.Round(loudnessBlock * float64(p.SampleRate)))
+	step := int(math.Round(loudnessStep * float64(p.SampleRate)))
+	var blocks []float64
+	if frames < block {
+		blocks = append(blocks, power[frames]/float64(frames))
+	}
+	for start := 0; start+block <= frames; start += step {
+		blocks = append(blocks, (power[start+block]-power[start])/float64(block))
+	}
+
+	gated := gateBlocks(blocks, loudnessAbsoluteGate)
+	if len(gated) == 0 {
+		return math.Inf(-1)
+	}
+	gated = gateBlocks(gated, blockLoudness(mean(gated))+loudnessRelativeGate)
+	return blockLoudness(mean(gated))
+}
+
+// NormalizeLoudness scales p to the integrated loudness target in LUFS. The
+// gain is reduced where needed to keep the sample peak at or below
+// LoudnessPeakCeiling, so loud targets may be missed on peaky audio. Silence
+// is returned unchanged.
+func NormalizeLoudness(p *PCM, target float64) *PCM {
+	loudness := Loudness(p)
+	if math.IsInf(loudness, -1) {
+		return p
+	}
+
+	gain := float32(math.Pow(10, (target-loudness)/20))
+	ceiling := float32(math.Pow(10, LoudnessPeakCeiling/20))
+	if peak := Peak(p); peak*gain > ceiling {
+		gain = ceiling / peak
+	}
+
+	out := &PCM{SampleRate: p.SampleRate, Channels: p.Channels, Samples: make([]float32, len(p.Samples))}
+	for i, s := range p.Samples {
+		out.Samples[i] = s * gain
+	}
+	return out
+}
+
+// blockLoudness converts a mean square power to LUFS.
+func blockLoudness(power float64) float64 {
+	return -0.691 + 10*math.Log10(power)
+}
+
+// gateBlocks returns the block powers louder than gate LUFS.
+func gateBlocks(blocks []float64, gate float64) []float64 {
+	var kept []float64
+	for _, power := range blocks {
+		if blockLoudness(power) > gate {
+			kept = append(kept, power)
+		}
+	}
+	return kept
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// biquad is a second-order IIR filter in direct form I.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
+
+// kWeighting returns the K-weighting filter of BS.1770 at rate: a high shelf
+// modelling the head, then a high-pass. The standard gives coefficients for
+// 48 kHz only; these are derived from the analog prototypes for any rate.
+func kWeighting(rate int) [2]biquad {
+	fs := float64(rate)
+
+	const (
+		shelfFreq = 1681.974450955533
+		shelfGain = 3.999843853973347
+		shelfQ    = 0.7071752369554196
+	)
+	k := math.Tan(math.Pi * shelfFreq / fs)
+	vh := math.Pow(10, shelfGain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/shelfQ + k*k
+	shelf := biquad{
+		b0: (vh + vb*k/shelfQ + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/shelfQ + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/shelfQ + k*k) / a0,
+	}
+
+	const (
+		highPassFreq = 38.13547087602444
+		highPassQ    = 0.5003270373238773
+	)
+	k = math.Tan(math.Pi * highPassFreq / fs)
+	a0 = 1 + k/highPassQ + k*k
+	highPass := biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/highPassQ + k*k) / a0,
+	}
+	return [2]biquad{shelf, highPass}
+}
@@ -0,0 +1,57 @@
+package audio
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKWeighting_48kHz(t *testing.T) {
+	// The coefficients BS.1770-4 gives for 48 kHz.
+	f := kWeighting(48000)
+	assert.InDelta(t, 1.53512485958697, f[0].b0, 1e-9)
+	assert.InDelta(t, -2.69169618940638, f[0].b1, 1e-9)
+	assert.InDelta(t, 1.19839281085285, f[0].b2, 1e-9)
+	assert.InDelta(t, -1.69065929318241, f[0].a1, 1e-9)
+	assert.InDelta(t, 0.73248077421585, f[0].a2, 1e-9)
+	assert.InDelta(t, -1.99004745483398, f[1].a1, 1e-9)
+	assert.InDelta(t, 0.99007225036621, f[1].a2, 1e-9)
+}
+
+func TestLoudness(t *testing.T) {
+	// A 1 kHz sine at full scale in one channel reads -3.01 LUFS, so 0.5 in
+	// amplitude reads 6.02 LU lower, and in two channels 3.01 LU higher.
+	for _, rate := range []int{16000, 24000, 44100, 48000} {
+		assert.InDelta(t, -9.03, Loudness(sine(rate, 1, 1000, 2*time.Second)), 0.05, "%d Hz", rate)
+		assert.InDelta(t, -6.02, Loudness(sine(rate, 2, 1000, 2*time.Second)), 0.05, "%d Hz stereo", rate)
+	}
+
+	// Silence is gated out: tripling the length with it would lower the
+	// plain mean square by 4.8 dB, but only the blocks straddling the end of
+	// the tone lower the loudness.
+	tone := sine(24000, 1, 1000, time.Second)
+	padded := &PCM{SampleRate: 24000, Channels: 1, Samples: append(tone.Samples, make([]float32, 48000)...)}
+	assert.InDelta(t, Loudness(tone), Loudness(padded), 1)
+
+	// Audio shorter than a block is measured as a whole.
+	assert.InDelta(t, -9.03, Loudness(sine(48000, 1, 1000, 200*time.Millisecond)), 0.2)
+
+	assert.True(t, math.IsInf(Loudness(&PCM{SampleRate: 24000, Channels: 1, Samples: make([]float32, 24000)}), -1))
+}
+
+func TestNormalizeLoudness(t *testing.T) {
+	src := sine(24000, 1, 1000, time.Second)
+
+	out := NormalizeLoudness(src, -23)
+	assert.InDelta(t, -23, Loudness(out), 0.05)
+	assert.Equal(t, src.SampleRate, out.SampleRate)
+
+	// Reaching -3 LUFS would clip, so the peak stops at the ceiling.
+	out = NormalizeLoudness(src, -3)
+	assert.InDelta(t, math.Pow(10, LoudnessPeakCeiling/20), Peak(out), 1e-4)
+
+	silence := &PCM{SampleRate: 24000, Channels: 1, Samples: make([]float32, 100)}
+	assert.Same(t, silence, NormalizeLoudness(silence, -23))
+}
@@ -0,0 +1,76 @@
+//go:build fdkaac
+
+package audio
+
+/*
+#cgo LDFLAGS: -lfdk-aac
+#include <fdk-aac/aacdecoder_lib.h>
+
+// The decoder takes arrays of buffers, which cgo cannot pass from Go memory.
+static AAC_DECODER_ERROR fish_aac_config(HANDLE_AACDECODER dec, UCHAR *conf, UINT length) {
+	return aacDecoder_ConfigRaw(dec, &conf, &length);
+}
+
+static AAC_DECODER_ERROR fish_aac_fill(HANDLE_AACDECODER dec, UCHAR *buf, UINT size) {
+	UINT valid = size;
+	return aacDecoder_Fill(dec, &buf, &size, &valid);
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+)
+
+// maxAACFrame is the most samples a decoded AAC frame holds: 2048 per
+// channel with SBR, for up to 8 channels.
+const maxAACFrame = 2048 * 8
+
+func init() {
+	RegisterDecoder(FormatM4A, decodeM4A)
+}
+
+// decodeM4A decodes the AAC track of an MP4 file with libfdk-aac.
+func decodeM4A(data []byte) (*PCM, error) {
+	track, err := readMP4Audio(data)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := C.aacDecoder_Open(C.TT_MP4_RAW, 1)
+	if dec == nil {
+		return nil, errors.New("m4a: aacDecoder_Open failed")
+	}
+	defer C.aacDecoder_Close(dec)
+	if status := C.fish_aac_config(dec, (*C.UCHAR)(&track.config[0]), C.UINT(len(track.config))); status != C.AAC_DEC_OK {
+		return nil, fmt.Errorf("%w: m4a: unsupported AAC configuration (0x%x)", ErrUnsupportedFormat, int(status))
+	}
+
+	pcm := make([]C.INT_PCM, maxAACFrame)
+	p := &PCM{}
+	for _, frame := range track.frames {
+		if len(frame) == 0 {
+			continue
+		}
+		if status := C.fish_aac_fill(dec, (*C.UCHAR)(&frame[0]), C.UINT(len(frame))); status != C.AAC_DEC_OK {
+			return nil, fmt.Errorf("m4a: decoding failed (0x%x)", int(status))
+		}
+		status := C.aacDecoder_DecodeFrame(dec, &pcm[0], C.INT(len(pcm)), 0)
+		if status == C.AAC_DEC_NOT_ENOUGH_BITS {
+			continue
+		}
+		if status != C.AAC_DEC_OK {
+			return nil, fmt.Errorf("m4a: corrupt AAC frame (0x%x)", int(status))
+		}
+		info := C.aacDecoder_GetStreamInfo(dec)
+		p.SampleRate, p.Channels = int(info.sampleRate), int(info.numChannels)
+		for _, s := range pcm[:int(info.frameSize)*p.Channels] {
+			p.Samples = append(p.Samples, float32(s)/32768)
+		}
+	}
+	if p.Channels == 0 {
+		return nil, errors.New("m4a: no audio frames found")
+	}
+	return p, nil
+}
@@ -0,0 +1,19 @@
+package audio
+
+// skipID3v2 returns data without the ID3v2 tag it starts with, if any. MPEG
+// audio decoders expect frames from the first byte.
+func skipID3v2(data []byte) []byte {
+	for len(data) >= 10 && string(data[:3]) == "ID3" {
+		// The tag size is a 28-bit big endian number stored 7 bits per byte,
+		// excluding the 10-byte header and the footer, when flag 0x10 adds one.
+		size := 10 + (int(data[6]&0x7F)<<21 | int(data[7]&0x7F)<<14 | int(data[8]&0x7F)<<7 | int(data[9]&0x7F))
+		if data[5]&0x10 != 0 {
+			size += 10
+		}
+		if size > len(data) {
+			return nil
+		}
+		data = data[size:]
+	}
+	return data
+}
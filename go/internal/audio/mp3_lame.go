@@ -0,0 +1,176 @@
+//go:build lame
+
+package audio
+
+/*
+#cgo LDFLAGS: -lmp3lame
+#include <lame/lame.h>
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+)
+
+// defaultMP3Bitrate is the MP3 bit rate in kbit/s when none is given.
+const defaultMP3Bitrate = 128
+
+const (
+	// mp3FrameSize is the most samples per channel an MPEG audio frame
+	// holds.
+	mp3FrameSize = 1152
+	// mp3ChunkSize is how much MP3 data the decoder is given at a time.
+	mp3ChunkSize = 16 << 10
+)
+
+func init() {
+	RegisterEncoder(FormatMP3, newLAMEEncoder)
+	RegisterDecoder(FormatMP3, decodeMP3)
+}
+
+// lameEncoder encodes MP3 with libmp3lame, at a constant bit rate unless a
+// quality is given.
+type lameEncoder struct {
+	gf       C.lame_t
+	channels int
+	left     []float32
+	right    []float32
+	out      []byte
+}
+
+func newLAMEEncoder(opts EncodeOptions) (Encoder, error) {
+	if opts.Channels != 1 && opts.Channels != 2 {
+		return nil, fmt.Errorf("mp3: %d channels are not supported", opts.Channels)
+	}
+	bitrate := opts.Bitrate
+	if bitrate == 0 {
+		bitrate = defaultMP3Bitrate
+	}
+
+	gf := C.lame_init()
+	if gf == nil {
+		return nil, errors.New("mp3: lame_init failed")
+	}
+	C.lame_set_in_samplerate(gf, C.int(opts.SampleRate))
+	C.lame_set_num_channels(gf, C.int(opts.Channels))
+	if opts.Quality != nil {
+		// LAME's VBR quality runs from 0, the best, to 9.
+		C.lame_set_VBR(gf, C.vbr_default)
+		C.lame_set_VBR_quality(gf, C.float(float64(MaxQuality-*opts.Quality)*0.9))
+	} else {
+		C.lame_set_brate(gf, C.int(bitrate))
+		C.lame_set_VBR(gf, C.vbr_off)
+	}
+	// The Xing header frame can only be filled in by seeking back to it.
+	C.lame_set_bWriteVbrTag(gf, 0)
+	if opts.Channels == 1 {
+		C.lame_set_mode(gf, C.MONO)
+	} else {
+		C.lame_set_mode(gf, C.JOINT_STEREO)
+	}
+	if C.lame_init_params(gf) < 0 {
+		C.lame_close(gf)
+		return nil, fmt.Errorf("mp3: unsupported parameters: %d Hz at %d kbit/s", opts.SampleRate, bitrate)
+	}
+	return &lameEncoder{gf: gf, channels: opts.Channels}, nil
+}
+
+func (e *lameEncoder) Encode(samples []float32) ([]byte, error) {
+	frames := len(samples) / e.channels
+	if frames == 0 {
+		return nil, nil
+	}
+
+	// LAME takes one buffer per channel.
+	e.left = grow(e.left, frames)
+	e.right = grow(e.right, frames)
+	for i := 0; i < frames; i++ {
+		e.left[i] = samples[i*e.channels]
+		e.right[i] = samples[i*e.channels+e.channels-1]
+	}
+
+	// Worst case output size, as documented in lame.h.
+	e.out = growBytes(e.out, frames*5/4+7200)
+	n := C.lame_encode_buffer_ieee_float(e.gf,
+		(*C.float)(&e.left[0]), (*C.float)(&e.right[0]), C.int(frames),
+		(*C.uchar)(&e.out[0]), C.int(len(e.out)))
+	if n < 0 {
+		return nil, fmt.Errorf("mp3: encoding failed: %d", int(n))
+	}
+	return append([]byte(nil), e.out[:n]...), nil
+}
+
+func (e *lameEncoder) Flush() ([]byte, error) {
+	e.out = growBytes(e.out, 7200)
+	n := C.lame_encode_flush(e.gf, (*C.uchar)(&e.out[0]), C.int(len(e.out)))
+	if n < 0 {
+		return nil, fmt.Errorf("mp3: flush failed: %d", int(n))
+	}
+	return append([]byte(nil), e.out[:n]...), nil
+}
+
+func (e *lameEncoder) Close() {
+	if e.gf != nil {
+		C.lame_close(e.gf)
+		e.gf = nil
+	}
+}
+
+// decodeMP3 decodes MPEG audio with LAME's decoder, hip.
+func decodeMP3(data []byte) (*PCM, error) {
+	data = skipID3v2(data)
+	hip := C.hip_decode_init()
+	if hip == nil {
+		return nil, errors.New("mp3: hip_decode_init failed")
+	}
+	defer C.hip_decode_exit(hip)
+
+	left := make([]C.short, mp3FrameSize)
+	right := make([]C.short, mp3FrameSize)
+	var info C.mp3data_struct
+	p := &PCM{}
+	for len(data) > 0 {
+		chunk := data[:min(len(data), mp3ChunkSize)]
+		data = data[len(chunk):]
+
+		// hip buffers the input and returns one frame per call, so it is
+		// called with no more input until it has none left.
+		in, size := (*C.uchar)(&chunk[0]), C.size_t(len(chunk))
+		for {
+			n := int(C.hip_decode1_headers(hip, in, size, &left[0], &right[0], &info))
+			if n < 0 {
+				return nil, errors.New("mp3: corrupt stream")
+			}
+			if n == 0 {
+				break
+			}
+			size = 0
+			p.SampleRate, p.Channels = int(info.samplerate), int(info.stereo)
+			for i := 0; i < n; i++ {
+				p.Samples = append(p.Samples, float32(left[i])/32768)
+				if p.Channels == 2 {
+					p.Samples = append(p.Samples, float32(right[i])/32768)
+				}
+			}
+		}
+	}
+	if p.Channels == 0 {
+		return nil, errors.New("mp3: no audio frames found")
+	}
+	return p, nil
+}
+
+func grow(buf []float32, n int) []float32 {
+	if cap(buf) < n {
+		return make([]float32, n)
+	}
+	return buf[:n]
+}
+
+func growBytes(buf []byte, n int) []byte {
+	if cap(buf) < n {
+		return make([]byte, n)
+	}
+	return buf[:n]
+}
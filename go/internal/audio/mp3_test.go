@@ -0,0 +1,23 @@
+package audio
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkipID3v2(t *testing.T) {
+	frames := []byte{0xFF, 0xFB, 0x90, 0x00}
+	// A 130-byte tag: the size 0x01 0x02 is 1<<7 + 2 in 7-bit bytes.
+	tag := append([]byte("ID3\x04\x00\x00\x00\x00\x01\x02"), make([]byte, 130)...)
+
+	assert.Equal(t, frames, skipID3v2(frames))
+	assert.Equal(t, frames, skipID3v2(append(tag, frames...)))
+	assert.Equal(t, frames, skipID3v2(append(append(tag, tag...), frames...)), "repeated tags")
+
+	// The footer flag adds a 10-byte footer.
+	footer := append([]byte("ID3\x04\x00\x10\x00\x00\x00\x02"), make([]byte, 12)...)
+	assert.Equal(t, frames, skipID3v2(append(footer, frames...)))
+
+	assert.Empty(t, skipID3v2(tag[:50]), "truncated tag")
+}
@@ -0,0 +1,301 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// mp4Track is the AAC audio of an MP4 (M4A) file: the decoder configuration,
+// an MPEG-4 AudioSpecificConfig, and the raw access units in order.
+type mp4Track struct {
+	config []byte
+	frames [][]byte
+}
+
+// mp4Box is a box (ISO/IEC 14496-12) of an MP4 file.
+type mp4Box struct {
+	kind string
+	body []byte
+}
+
+// mp4Boxes splits data into the boxes it holds.
+func mp4Boxes(data []byte) ([]mp4Box, error) {
+	var boxes []mp4Box
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, errors.New("m4a: truncated box")
+		}
+		size, header := uint64(binary.BigEndian.Uint32(data)), 8
+		switch size {
+		case 0:
+			// The box extends to the end of the file.
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return nil, errors.New("m4a: truncated box")
+			}
+			size, header = binary.BigEndian.Uint64(data[8:]), 16
+		}
+		if size < uint64(header) || size > uint64(len(data)) {
+			return nil, errors.New("m4a: invalid box size")
+		}
+		boxes = append(boxes, mp4Box{kind: string(data[4:8]), body: data[header:size]})
+		data = data[size:]
+	}
+	return boxes, nil
+}
+
+// mp4Child returns the body of the first box of the given kind in data.
+func mp4Child(data []byte, kind string) ([]byte, bool) {
+	boxes, err := mp4Boxes(data)
+	if err != nil {
+		return nil, false
+	}
+	for _, box := range boxes {
+		if box.kind == kind {
+			return box.body, true
+		}
+	}
+	return nil, false
+}
+
+// mp4Path returns the body of the box at path below data, following the
+// first box of each kind.
+func mp4Path(data []byte, path ...string) ([]byte, bool) {
+	for _, kind := range path {
+		var ok bool
+		if data, ok = mp4Child(data, kind); !ok {
+			return nil, false
+		}
+	}
+	return data, true
+}
+
+// readMP4Audio returns the first sound track of an MP4 file, which must be
+// AAC.
+func readMP4Audio(data []byte) (*mp4Track, error) {
+	moov, ok := mp4Path(data, "moov")
+	if !ok {
+		return nil, errors.New("m4a: file has no moov box")
+	}
+	boxes, err := mp4Boxes(moov)
+	if err != nil {
+		return nil, err
+	}
+	for _, box := range boxes {
+		if box.kind != "trak" {
+			continue
+		}
+		// The handler type follows the version, flags and pre_defined fields.
+		hdlr, ok := mp4Path(box.body, "mdia", "hdlr")
+		if !ok || len(hdlr) < 12 || string(hdlr[8:12]) != "soun" {
+			continue
+		}
+		stbl, ok := mp4Path(box.body, "mdia", "minf", "stbl")
+		if !ok {
+			return nil, errors.New("m4a: sound track has no sample table")
+		}
+		return readMP4SampleTable(data, stbl)
+	}
+	return nil, errors.New("m4a: file has no sound track")
+}
+
+func readMP4SampleTable(data, stbl []byte) (*mp4Track, error) {
+	stsd, ok := mp4Child(stbl, "stsd")
+	if !ok || len(stsd) < 8 {
+		return nil, errors.New("m4a: sound track has no sample description")
+	}
+	entries, err := mp4Boxes(stsd[8:])
+	if err != nil || len(entries) == 0 {
+		return nil, errors.New("m4a: invalid sample description")
+	}
+	if entries[0].kind != "mp4a" {
+		return nil, fmt.Errorf("%w: m4a with %s audio, only AAC can be decoded", ErrUnsupportedFormat, entries[0].kind)
+	}
+	config, err := mp4AudioConfig(entries[0].body)
+	if err != nil {
+		return nil, err
+	}
+
+	sizes, err := mp4SampleSizes(stbl, len(data))
+	if err != nil {
+		return nil, err
+	}
+	offsets, err := mp4ChunkOffsets(stbl)
+	if err != nil {
+		return nil, err
+	}
+	stsc, ok := mp4Child(stbl, "stsc")
+	if !ok || len(stsc) < 8 {
+		return nil, errors.New("m4a: sound track has no sample-to-chunk table")
+	}
+	count := int(binary.BigEndian.Uint32(stsc[4:]))
+	if count > (len(stsc)-8)/12 {
+		return nil, errors.New("m4a: truncated sample-to-chunk table")
+	}
+
+	track := &mp4Track{config: config, frames: make([][]byte, 0, len(sizes))}
+	for i := 0; i < count && len(track.frames) < len(sizes); i++ {
+		// Each entry gives the samples per chunk from its first chunk, counted
+		// from 1, up to the first chunk of the next entry.
+		entry := stsc[8+12*i:]
+		first := int(binary.BigEndian.Uint32(entry))
+		perChunk := int(binary.BigEndian.Uint32(entry[4:]))
+		last := len(offsets)
+		if i+1 < count {
+			last = int(binary.BigEndian.Uint32(stsc[8+12*(i+1):])) - 1
+		}
+		if first < 1 {
+			return nil, errors.New("m4a: invalid sample-to-chunk table")
+		}
+		for chunk := first; chunk <= min(last, len(offsets)); chunk++ {
+			offset := offsets[chunk-1]
+			for j := 0; j < perChunk && len(track.frames) < len(sizes); j++ {
+				size := uint64(sizes[len(track.frames)])
+				if offset > uint64(len(data)) || size > uint64(len(data))-offset {
+					return nil, errors.New("m4a: sample beyond the end of the file")
+				}
+				track.frames = append(track.frames, data[offset:offset+size])
+				offset += size
+			}
+		}
+	}
+	if len(track.frames) != len(sizes) {
+		return nil, fmt.Errorf("m4a: sample table lists %d samples but locates %d", len(sizes), len(track.frames))
+	}
+	return track, nil
+}
+
+// mp4AudioConfig returns the AudioSpecificConfig of an mp4a sample entry.
+func mp4AudioConfig(entry []byte) ([]byte, error) {
+	// The entry starts with 28 bytes of fields, and QuickTime sound
+	// descriptions add 16 more in version 1 or 36 in version 2.
+	offset := 28
+	if len(entry) >= 10 {
+		switch binary.BigEndian.Uint16(entry[8:]) {
+		case 1:
+			offset += 16
+		case 2:
+			offset += 36
+		}
+	}
+	if len(entry) < offset {
+		return nil, errors.New("m4a: truncated mp4a sample entry")
+	}
+	esds, ok := mp4Child(entry[offset:], "esds")
+	if !ok || len(esds) < 4 {
+		return nil, errors.New("m4a: mp4a sample entry has no esds box")
+	}
+
+	// ES_Descriptor (tag 3), holding a DecoderConfigDescriptor (tag 4),
+	// holding the DecoderSpecificInfo (tag 5), per ISO/IEC 14496-1.
+	tag, es := mp4Descriptor(esds[4:])
+	if tag != 3 || len(es) < 3 {
+		return nil, errors.New("m4a: invalid esds box")
+	}
+	flags := es[2]
+	es = es[3:]
+	if flags&0x80 != 0 { // dependsOn_ES_ID
+		es = es[min(2, len(es)):]
+	}
+	if flags&0x40 != 0 && len(es) > 0 { // URL
+		es = es[min(1+int(es[0]), len(es)):]
+	}
+	if flags&0x20 != 0 { // OCR_ES_ID
+		es = es[min(2, len(es)):]
+	}
+	tag, dc := mp4Descriptor(es)
+	if tag != 4 || len(dc) < 13 {
+		return nil, errors.New("m4a: invalid esds box")
+	}
+	// 0x40 is MPEG-4 audio, 0x66 to 0x68 the MPEG-2 AAC profiles.
+	if object := dc[0]; object != 0x40 && (object < 0x66 || object > 0x68) {
+		return nil, fmt.Errorf("%w: m4a with object type 0x%02x, only AAC can be decoded", ErrUnsupportedFormat, object)
+	}
+	tag, asc := mp4Descriptor(dc[13:])
+	if tag != 5 || len(asc) == 0 {
+		return nil, errors.New("m4a: esds box has no AudioSpecificConfig")
+	}
+	return asc, nil
+}
+
+// mp4Descriptor returns the tag and body of the MPEG-4 descriptor data
+// starts with. The length is stored 7 bits per byte, most significant first, with
+// the high bit set on all but the last.
+func mp4Descriptor(data []byte) (tag byte, body []byte) {
+	if len(data) < 2 {
+		return 0, nil
+	}
+	tag, data = data[0], data[1:]
+	size := 0
+	for i := 0; i < 4 && len(data) > 0; i++ {
+		b := data[0]
+		data = data[1:]
+		size = size<<7 | int(b&0x7F)
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	if size > len(data) {
+		return 0, nil
+	}
+	return tag, data[:size]
+}
+
+// mp4SampleSizes returns the size of each sample from the stsz box of a
+// file of fileSize bytes.
+func mp4SampleSizes(stbl []byte, fileSize int) ([]uint32, error) {
+	stsz, ok := mp4Child(stbl, "stsz")
+	if !ok || len(stsz) < 12 {
+		return nil, errors.New("m4a: sound track has no sample size table")
+	}
+	constant := binary.BigEndian.Uint32(stsz[4:])
+	count := int(binary.BigEndian.Uint32(stsz[8:]))
+	if constant != 0 {
+		// The samples must fit in the file.
+		if uint64(constant)*uint64(count) > uint64(fileSize) {
+			return nil, errors.New("m4a: invalid sample size table")
+		}
+		sizes := make([]uint32, count)
+		for i := range sizes {
+			sizes[i] = constant
+		}
+		return sizes, nil
+	}
+	if count > (len(stsz)-12)/4 {
+		return nil, errors.New("m4a: truncated sample size table")
+	}
+	sizes := make([]uint32, count)
+	for i := range sizes {
+		sizes[i] = binary.BigEndian.Uint32(stsz[12+4*i:])
+	}
+	return sizes, nil
+}
+
+// mp4ChunkOffsets returns the file offset of each chunk from the stco or,
+// in files over 4 GiB, co64 box.
+func mp4ChunkOffsets(stbl []byte) ([]uint64, error) {
+	table, width := []byte(nil), 4
+	if stco, ok := mp4Child(stbl, "stco"); ok {
+		table = stco
+	} else if co64, ok := mp4Child(stbl, "co64"); ok {
+		table, width = co64, 8
+	}
+	if len(table) < 8 {
+		return nil, errors.New("m4a: sound track has no chunk offset table")
+	}
+	count := int(binary.BigEndian.Uint32(table[4:]))
+	if count > (len(table)-8)/width {
+		return nil, errors.New("m4a: truncated chunk offset table")
+	}
+	offsets := make([]uint64, count)
+	for i := range offsets {
+		if width == 8 {
+			offsets[i] = binary.BigEndian.Uint64(table[8+8*i:])
+		} else {
+			offsets[i] = uint64(binary.BigEndian.Uint32(table[8+4*i:]))
+		}
+	}
+	return offsets, nil
+}
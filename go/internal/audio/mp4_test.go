@@ -0,0 +1,86 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mp4TestBox(kind string, parts ...[]byte) []byte {
+	body := bytes.Join(parts, nil)
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	return append(append(box, kind...), body...)
+}
+
+// mp4TestFullBox adds the version and flags of a full box.
+func mp4TestFullBox(kind string, parts ...[]byte) []byte {
+	return mp4TestBox(kind, append([][]byte{make([]byte, 4)}, parts...)...)
+}
+
+func mp4TestUint32s(values ...uint32) []byte {
+	var out []byte
+	for _, v := range values {
+		out = binary.BigEndian.AppendUint32(out, v)
+	}
+	return out
+}
+
+// mp4TestFile builds an M4A file of ftyp, mdat and moov holding the frames
+// "ab" and "cde" in a first chunk and "fghi" in a second, with a video track
+// before the sound track.
+func mp4TestFile(codec string) []byte {
+	ftyp := mp4TestBox("ftyp", []byte("M4A \x00\x00\x00\x00"))
+	mdat := mp4TestBox("mdat", []byte("abcdefghi"))
+	payload := uint32(len(ftyp) + 8)
+
+	hdlr := func(handler string) []byte {
+		return mp4TestFullBox("hdlr", make([]byte, 4), []byte(handler), make([]byte, 13))
+	}
+	// ES_Descriptor with a four-byte length, DecoderConfigDescriptor for
+	// MPEG-4 audio and the AudioSpecificConfig of AAC LC, 44.1 kHz stereo.
+	asc := []byte{0x05, 0x02, 0x12, 0x10}
+	dc := append([]byte{0x04, byte(13 + len(asc)), 0x40, 0x15}, make([]byte, 11)...)
+	es := append([]byte{0x03, 0x80, 0x80, 0x80, byte(3 + len(dc) + len(asc)), 0x00, 0x01, 0x00}, append(dc, asc...)...)
+	entry := mp4TestBox(codec, make([]byte, 28), mp4TestFullBox("esds", es))
+
+	stbl := mp4TestBox("stbl",
+		mp4TestFullBox("stsd", mp4TestUint32s(1), entry),
+		mp4TestFullBox("stsz", mp4TestUint32s(0, 3, 2, 3, 4)),
+		mp4TestFullBox("stsc", mp4TestUint32s(2, 1, 2, 1, 2, 1, 1)),
+		mp4TestFullBox("stco", mp4TestUint32s(2, payload, payload+5)),
+	)
+	video := mp4TestBox("trak", mp4TestBox("mdia", hdlr("vide")))
+	sound := mp4TestBox("trak", mp4TestBox("mdia", hdlr("soun"), mp4TestBox("minf", stbl)))
+	moov := mp4TestBox("moov", mp4TestFullBox("mvhd", make([]byte, 96)), video, sound)
+	return bytes.Join([][]byte{ftyp, mdat, moov}, nil)
+}
+
+func TestReadMP4Audio(t *testing.T) {
+	data := mp4TestFile("mp4a")
+	assert.Equal(t, FormatM4A, DetectFormat(data))
+
+	track, err := readMP4Audio(data)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x12, 0x10}, track.config)
+	require.Len(t, track.frames, 3)
+	assert.Equal(t, "ab", string(track.frames[0]))
+	assert.Equal(t, "cde", string(track.frames[1]))
+	assert.Equal(t, "fghi", string(track.frames[2]))
+}
+
+func TestReadMP4Audio_Invalid(t *testing.T) {
+	_, err := readMP4Audio(mp4TestFile("alac"))
+	assert.ErrorIs(t, err, ErrUnsupportedFormat, "another codec")
+	assert.ErrorContains(t, err, "alac")
+
+	data := mp4TestFile("mp4a")
+	_, err = readMP4Audio(data[:len(data)-4])
+	assert.Error(t, err, "truncated")
+	assert.NotErrorIs(t, err, ErrUnsupportedFormat)
+
+	_, err = readMP4Audio(mp4TestBox("ftyp", []byte("M4A ")))
+	assert.ErrorContains(t, err, "no moov box")
+}
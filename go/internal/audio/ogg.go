@@ -0,0 +1,134 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Ogg page header flags.
+const (
+	oggFirstPage = 0x02
+	oggLastPage  = 0x04
+)
+
+// oggCRCTable is the table of the Ogg page checksum, CRC-32 with polynomial
+// 0x04c11db7 computed most significant bit first.
+var oggCRCTable = func() (table [256]uint32) {
+	for i := range table {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ 0x04c11db7
+			} else {
+				r <<= 1
+			}
+		}
+		table[i] = r
+	}
+	return table
+}()
+
+func oggCRC(data []byte) uint32 {
+	var crc uint32
+	for _, b := range data {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	return crc
+}
+
+// oggStream frames the packets of one logical bitstream into Ogg pages
+// (RFC 3533), one packet per page, so that every packet can be sent as soon
+// as it is encoded.
+type oggStream struct {
+	serial uint32
+	seq    uint32
+}
+
+// page returns a page holding packet, which must be shorter than 255*255
+// bytes. granule is the stream position at the end of the packet.
+func (s *oggStream) page(packet []byte, granule int64, flags byte) []byte {
+	// Lacing values: 255 for each full segment, then the remainder, which
+	// is 0 when the packet fills its last segment exactly.
+	segments := len(packet)/255 + 1
+	page := make([]byte, 27+segments+len(packet))
+	copy(page, "OggS")
+	page[5] = flags
+	binary.LittleEndian.PutUint64(page[6:], uint64(granule))
+	binary.LittleEndian.PutUint32(page[14:], s.serial)
+	binary.LittleEndian.PutUint32(page[18:], s.seq)
+	page[26] = byte(segments)
+	for i := 0; i < segments-1; i++ {
+		page[27+i] = 255
+	}
+	page[27+segments-1] = byte(len(packet) % 255)
+	copy(page[27+segments:], packet)
+	binary.LittleEndian.PutUint32(page[22:], oggCRC(page))
+	s.seq++
+	return page
+}
+
+// oggPacket is a packet read from an Ogg stream. granule is the position of
+// the page the packet ends on, or -1 when another packet ends after it on
+// that page.
+type oggPacket struct {
+	data    []byte
+	granule int64
+}
+
+// readOggPackets returns the packets of the first logical bitstream of data,
+// joining packets that span pages. Pages of other streams are skipped.
+func readOggPackets(data []byte) ([]oggPacket, error) {
+	var packets []oggPacket
+	var partial []byte
+	serial, seen := uint32(0), false
+	for len(data) > 0 {
+		if len(data) < 27 || string(data[:4]) != "OggS" {
+			return nil, errors.New("ogg: truncated or corrupt page")
+		}
+		segments := int(data[26])
+		if len(data) < 27+segments {
+			return nil, errors.New("ogg: truncated page")
+		}
+		lacing := data[27 : 27+segments]
+		size := 0
+		for _, l := range lacing {
+			size += int(l)
+		}
+		end := 27 + segments + size
+		if len(data) < end {
+			return nil, errors.New("ogg: truncated page")
+		}
+		page, body := data[:end], data[27+segments:end]
+		data = data[end:]
+
+		pageSerial := binary.LittleEndian.Uint32(page[14:])
+		if !seen {
+			serial, seen = pageSerial, true
+		}
+		if pageSerial != serial {
+			continue
+		}
+		granule := int64(binary.LittleEndian.Uint64(page[6:]))
+
+		last := -1
+		for i, l := range lacing {
+			if l < 255 {
+				last = i
+			}
+		}
+		for i, l := range lacing {
+			partial = append(partial, body[:l]...)
+			body = body[l:]
+			if l == 255 {
+				continue
+			}
+			packet := oggPacket{data: partial, granule: -1}
+			if i == last {
+				packet.granule = granule
+			}
+			packets = append(packets, packet)
+			partial = nil
+		}
+	}
+	return packets, nil
+}
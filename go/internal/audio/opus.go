@@ -0,0 +1,284 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// FormatOpus is Opus audio in an Ogg container. It is only produced, by
+// builds with an Opus encoder; uploaded Ogg files are detected as FormatOGG,
+// which builds with Opus decode when they hold Opus.
+const FormatOpus = "opus"
+
+const (
+	// opusRate is the rate Opus granule positions count in, and the rate
+	// audio is resampled to when Opus cannot encode it at its own rate.
+	opusRate = 48000
+	// opusFramesPerSecond sets the frame duration, 20 ms.
+	opusFramesPerSecond = 50
+	// opusVendor names the encoder in the OpusTags header.
+	opusVendor = "fish-speech-go"
+)
+
+// opusRates are the input rates Opus encodes without resampling.
+var opusRates = map[int]bool{8000: true, 12000: true, 16000: true, 24000: true, 48000: true}
+
+// opusCodec compresses single Opus frames.
+type opusCodec interface {
+	// encode compresses one frame of interleaved samples.
+	encode(frame []float32) ([]byte, error)
+	// lookahead returns the codec delay in samples at the encoding rate.
+	lookahead() int
+	close()
+}
+
+// oggOpusEncoder is an Encoder of Ogg Opus (RFC 7845) around an opusCodec.
+// It cuts the audio into frames, resampling it first when Opus does not
+// support its rate, and writes one frame per page.
+type oggOpusEncoder struct {
+	codec     opusCodec
+	ogg       oggStream
+	channels  int
+	inputRate int
+	// rate is the encoding rate and frameSize the samples per channel of a
+	// frame at that rate.
+	rate      int
+	frameSize int
+	resampler *linearResampler
+	// preSkip, input and encoded count samples per channel at opusRate:
+	// the codec delay, the audio received and the audio encoded.
+	preSkip int64
+	input   int64
+	encoded int64
+	pending []float32
+	started bool
+}
+
+// newOggOpusEncoder returns an Ogg Opus encoder using the codec returned by
+// open for the encoding rate and opts.
+func newOggOpusEncoder(opts EncodeOptions, open func(rate int, opts EncodeOptions) (opusCodec, error)) (*oggOpusEncoder, error) {
+	if opts.Channels != 1 && opts.Channels != 2 {
+		return nil, fmt.Errorf("opus: %d channels are not supported", opts.Channels)
+	}
+	if opts.SampleRate <= 0 {
+		return nil, fmt.Errorf("opus: invalid sample rate %d", opts.SampleRate)
+	}
+	e := &oggOpusEncoder{
+		ogg:       oggStream{serial: rand.Uint32()},
+		channels:  opts.Channels,
+		inputRate: opts.SampleRate,
+		rate:      opts.SampleRate,
+	}
+	if !opusRates[e.rate] {
+		e.rate = opusRate
+		e.resampler = &linearResampler{from: opts.SampleRate, to: opusRate, channels: opts.Channels}
+	}
+	e.frameSize = e.rate / opusFramesPerSecond
+
+	codec, err := open(e.rate, opts)
+	if err != nil {
+		return nil, err
+	}
+	e.codec = codec
+	e.preSkip = e.toOpusRate(codec.lookahead())
+	return e, nil
+}
+
+// toOpusRate converts a count of samples at the encoding rate to opusRate.
+func (e *oggOpusEncoder) toOpusRate(samples int) int64 {
+	return int64(samples) * opusRate / int64(e.rate)
+}
+
+func (e *oggOpusEncoder) Encode(samples []float32) ([]byte, error) {
+	out := e.headers()
+	if e.resampler != nil {
+		samples = e.resampler.resample(samples)
+	}
+	e.pending = append(e.pending, samples...)
+	e.input += e.toOpusRate(len(samples) / e.channels)
+
+	frameLen := e.frameSize * e.channels
+	for len(e.pending) >= frameLen {
+		page, err := e.encodeFrame(e.pending[:frameLen], e.encoded+e.toOpusRate(e.frameSize), 0)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, page...)
+		e.pending = e.pending[frameLen:]
+	}
+	// Keep the remainder at the start of the buffer rather than let it grow.
+	e.pending = append([]float32(nil), e.pending...)
+	return out, nil
+}
+
+// Flush encodes the remaining audio, padded with silence, and as many more
+// frames as the codec delay needs. The last page's granule position trims
+// the padding on decoding.
+func (e *oggOpusEncoder) Flush() ([]byte, error) {
+	out := e.headers()
+	frameLen := e.frameSize * e.channels
+	end := e.preSkip + e.input
+	for {
+		frame := make([]float32, frameLen)
+		copy(frame, e.pending)
+		e.pending = e.pending[min(len(e.pending), frameLen):]
+
+		granule, flags := e.encoded+e.toOpusRate(e.frameSize), byte(0)
+		if granule >= end {
+			granule, flags = end, oggLastPage
+		}
+		page, err := e.encodeFrame(frame, granule, flags)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, page...)
+		if flags == oggLastPage {
+			return out, nil
+		}
+	}
+}
+
+func (e *oggOpusEncoder) Close() {
+	e.codec.close()
+}
+
+// encodeFrame encodes one frame into a page ending at granule.
+func (e *oggOpusEncoder) encodeFrame(frame []float32, granule int64, flags byte) ([]byte, error) {
+	packet, err := e.codec.encode(frame)
+	if err != nil {
+		return nil, err
+	}
+	e.encoded += e.toOpusRate(e.frameSize)
+	return e.ogg.page(packet, granule, flags), nil
+}
+
+// headers returns the identification and comment header pages the first
+// time it is called, and nil afterwards.
+func (e *oggOpusEncoder) headers() []byte {
+	if e.started {
+		return nil
+	}
+	e.started = true
+
+	head := make([]byte, 19)
+	copy(head, "OpusHead")
+	head[8] = 1 // version
+	head[9] = byte(e.channels)
+	binary.LittleEndian.PutUint16(head[10:], uint16(e.preSkip))
+	binary.LittleEndian.PutUint32(head[12:], uint32(e.inputRate))
+	// Output gain and channel mapping family 0 stay zero.
+
+	tags := make([]byte, 8+4+len(opusVendor)+4)
+	copy(tags, "OpusTags")
+	binary.LittleEndian.PutUint32(tags[8:], uint32(len(opusVendor)))
+	copy(tags[12:], opusVendor)
+	// No user comments.
+
+	out := e.ogg.page(head, 0, oggFirstPage)
+	return append(out, e.ogg.page(tags, 0, 0)...)
+}
+
+// opusDecoder decompresses single Opus packets.
+type opusDecoder interface {
+	// decode returns the interleaved samples of one packet at opusRate.
+	decode(packet []byte) ([]float32, error)
+	close()
+}
+
+// decodeOggOpus decodes Ogg Opus (RFC 7845) with the decoder open returns
+// for the stream's channel count. The audio is returned at opusRate, the rate
+// Opus decodes at, without the pre-skip and the padding the last page's
+// granule position trims.
+func decodeOggOpus(data []byte, open func(channels int) (opusDecoder, error)) (*PCM, error) {
+	packets, err := readOggPackets(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(packets) == 0 || !bytes.HasPrefix(packets[0].data, []byte("OpusHead")) {
+		return nil, fmt.Errorf("%w: Ogg stream is not Opus", ErrUnsupportedFormat)
+	}
+	head := packets[0].data
+	if len(head) < 19 || len(packets) < 2 {
+		return nil, errors.New("opus: truncated header")
+	}
+	channels := int(head[9])
+	preSkip := int64(binary.LittleEndian.Uint16(head[10:]))
+	gain := int16(binary.LittleEndian.Uint16(head[16:]))
+	if family := head[18]; family != 0 || channels < 1 || channels > 2 {
+		return nil, fmt.Errorf("%w: opus with %d channels in mapping family %d", ErrUnsupportedFormat, channels, family)
+	}
+
+	dec, err := open(channels)
+	if err != nil {
+		return nil, err
+	}
+	defer dec.close()
+
+	// The second packet holds the comments.
+	var samples []float32
+	end := int64(-1)
+	for _, packet := range packets[2:] {
+		out, err := dec.decode(packet.data)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, out...)
+		if packet.granule >= 0 {
+			end = packet.granule
+		}
+	}
+
+	frames := int64(len(samples) / channels)
+	if end < 0 || end > frames {
+		end = frames
+	}
+	start := min(preSkip, end)
+	samples = samples[start*int64(channels) : end*int64(channels)]
+
+	// The output gain is in 1/256 dB.
+	if gain != 0 {
+		scale := float32(math.Pow(10, float64(gain)/(20*256)))
+		for i := range samples {
+			samples[i] *= scale
+		}
+	}
+	return &PCM{SampleRate: opusRate, Channels: channels, Samples: samples}, nil
+}
+
+// linearResampler converts a stream of interleaved samples between rates by
+// linear interpolation, carrying its position across calls so that chunks
+// join without clicks.
+type linearResampler struct {
+	from, to, channels int
+	// pos is the input frame of the next output frame, counted from last.
+	pos  float64
+	last []float32
+}
+
+func (r *linearResampler) resample(samples []float32) []float32 {
+	in := append(r.last, samples...)
+	frames := len(in) / r.channels
+	if frames < 2 {
+		r.last = in
+		return nil
+	}
+
+	step := float64(r.from) / float64(r.to)
+	var out []float32
+	for ; r.pos+1 < float64(frames); r.pos += step {
+		i := int(r.pos)
+		frac := float32(r.pos - float64(i))
+		for c := 0; c < r.channels; c++ {
+			a, b := in[i*r.channels+c], in[(i+1)*r.channels+c]
+			out = append(out, a+(b-a)*frac)
+		}
+	}
+	// The last frame is needed to interpolate up to the next chunk.
+	r.pos -= float64(frames - 1)
+	r.last = append([]float32(nil), in[(frames-1)*r.channels:frames*r.channels]...)
+	return out
+}
@@ -0,0 +1,142 @@
+//go:build opus
+
+package audio
+
+/*
+#cgo pkg-config: opus
+#include <opus.h>
+
+// opus_encoder_ctl is variadic, which cgo cannot call.
+static int fish_opus_set_bitrate(OpusEncoder *enc, opus_int32 bitrate) {
+	return opus_encoder_ctl(enc, OPUS_SET_BITRATE(bitrate));
+}
+
+static int fish_opus_set_complexity(OpusEncoder *enc, opus_int32 complexity) {
+	return opus_encoder_ctl(enc, OPUS_SET_COMPLEXITY(complexity));
+}
+
+static int fish_opus_get_lookahead(OpusEncoder *enc, opus_int32 *lookahead) {
+	return opus_encoder_ctl(enc, OPUS_GET_LOOKAHEAD(lookahead));
+}
+*/
+import "C"
+
+import "fmt"
+
+const (
+	// defaultOpusBitrate is the Opus bit rate in kbit/s when none is given.
+	defaultOpusBitrate = 32
+	// maxOpusPacket is the buffer size libopus recommends for one packet.
+	maxOpusPacket = 4000
+	// maxOpusFrameSize is the most samples per channel a packet decodes to,
+	// 120 ms at opusRate.
+	maxOpusFrameSize = 5760
+)
+
+func init() {
+	RegisterEncoder(FormatOpus, func(opts EncodeOptions) (Encoder, error) {
+		return newOggOpusEncoder(opts, openLibopus)
+	})
+	RegisterDecoder(FormatOGG, func(data []byte) (*PCM, error) {
+		return decodeOggOpus(data, openLibopusDecoder)
+	})
+}
+
+// libopusCodec encodes Opus frames with libopus.
+type libopusCodec struct {
+	enc       *C.OpusEncoder
+	frameSize int
+	delay     int
+	packet    []byte
+}
+
+func openLibopus(rate int, opts EncodeOptions) (opusCodec, error) {
+	bitrate := opts.Bitrate
+	if bitrate == 0 {
+		bitrate = defaultOpusBitrate
+	}
+	var status C.int
+	enc := C.opus_encoder_create(C.opus_int32(rate), C.int(opts.Channels), C.OPUS_APPLICATION_AUDIO, &status)
+	if status != C.OPUS_OK {
+		return nil, fmt.Errorf("opus: %s", C.GoString(C.opus_strerror(status)))
+	}
+	if status = C.fish_opus_set_bitrate(enc, C.opus_int32(bitrate*1000)); status != C.OPUS_OK {
+		C.opus_encoder_destroy(enc)
+		return nil, fmt.Errorf("opus: bit rate %d kbit/s: %s", bitrate, C.GoString(C.opus_strerror(status)))
+	}
+	// Opus complexity runs from 0 to 10, like Quality.
+	if opts.Quality != nil {
+		if status = C.fish_opus_set_complexity(enc, C.opus_int32(*opts.Quality)); status != C.OPUS_OK {
+			C.opus_encoder_destroy(enc)
+			return nil, fmt.Errorf("opus: quality %d: %s", *opts.Quality, C.GoString(C.opus_strerror(status)))
+		}
+	}
+	var lookahead C.opus_int32
+	if status = C.fish_opus_get_lookahead(enc, &lookahead); status != C.OPUS_OK {
+		C.opus_encoder_destroy(enc)
+		return nil, fmt.Errorf("opus: %s", C.GoString(C.opus_strerror(status)))
+	}
+	return &libopusCodec{
+		enc:       enc,
+		frameSize: rate / opusFramesPerSecond,
+		delay:     int(lookahead),
+		packet:    make([]byte, maxOpusPacket),
+	}, nil
+}
+
+func (c *libopusCodec) encode(frame []float32) ([]byte, error) {
+	n := C.opus_encode_float(c.enc, (*C.float)(&frame[0]), C.int(c.frameSize),
+		(*C.uchar)(&c.packet[0]), C.opus_int32(len(c.packet)))
+	if n < 0 {
+		return nil, fmt.Errorf("opus: %s", C.GoString(C.opus_strerror(C.int(n))))
+	}
+	return append([]byte(nil), c.packet[:n]...), nil
+}
+
+func (c *libopusCodec) lookahead() int {
+	return c.delay
+}
+
+func (c *libopusCodec) close() {
+	if c.enc != nil {
+		C.opus_encoder_destroy(c.enc)
+		c.enc = nil
+	}
+}
+
+// libopusDecoder decodes Opus packets with libopus.
+type libopusDecoder struct {
+	dec      *C.OpusDecoder
+	channels int
+	pcm      []float32
+}
+
+func openLibopusDecoder(channels int) (opusDecoder, error) {
+	var status C.int
+	dec := C.opus_decoder_create(C.opus_int32(opusRate), C.int(channels), &status)
+	if status != C.OPUS_OK {
+		return nil, fmt.Errorf("opus: %s", C.GoString(C.opus_strerror(status)))
+	}
+	return &libopusDecoder{dec: dec, channels: channels, pcm: make([]float32, maxOpusFrameSize*channels)}, nil
+}
+
+func (d *libopusDecoder) decode(packet []byte) ([]float32, error) {
+	// A missing packet is concealed from the ones before it.
+	var data *C.uchar
+	if len(packet) > 0 {
+		data = (*C.uchar)(&packet[0])
+	}
+	n := C.opus_decode_float(d.dec, data, C.opus_int32(len(packet)),
+		(*C.float)(&d.pcm[0]), C.int(maxOpusFrameSize), 0)
+	if n < 0 {
+		return nil, fmt.Errorf("opus: %s", C.GoString(C.opus_strerror(n)))
+	}
+	return append([]float32(nil), d.pcm[:int(n)*d.channels]...), nil
+}
+
+func (d *libopusDecoder) close() {
+	if d.dec != nil {
+		C.opus_decoder_destroy(d.dec)
+		d.dec = nil
+	}
+}
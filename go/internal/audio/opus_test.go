@@ -0,0 +1,289 @@
+package audio
+
+import (
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOpus "encodes" a frame as its length in samples.
+type fakeOpus struct {
+	delay  int
+	frames int
+}
+
+func (c *fakeOpus) encode(frame []float32) ([]byte, error) {
+	c.frames++
+	return binary.LittleEndian.AppendUint16(nil, uint16(len(frame))), nil
+}
+
+func (c *fakeOpus) lookahead() int { return c.delay }
+func (c *fakeOpus) close()         {}
+
+type oggPage struct {
+	flags   byte
+	granule int64
+	seq     uint32
+	packet  []byte
+}
+
+// parseOggPages splits a stream into pages, checking their checksums.
+func parseOggPages(t *testing.T, data []byte) []oggPage {
+	t.Helper()
+	var pages []oggPage
+	for len(data) > 0 {
+		require.GreaterOrEqual(t, len(data), 27)
+		require.Equal(t, "OggS", string(data[:4]))
+		segments := int(data[26])
+		size := 0
+		for _, lacing := range data[27 : 27+segments] {
+			size += int(lacing)
+		}
+		end := 27 + segments + size
+		page := append([]byte(nil), data[:end]...)
+		crc := binary.LittleEndian.Uint32(page[22:])
+		binary.LittleEndian.PutUint32(page[22:], 0)
+		require.Equal(t, oggCRC(page), crc, "page checksum")
+
+		pages = append(pages, oggPage{
+			flags:   data[5],
+			granule: int64(binary.LittleEndian.Uint64(data[6:])),
+			seq:     binary.LittleEndian.Uint32(data[18:]),
+			packet:  data[27+segments : end],
+		})
+		data = data[end:]
+	}
+	return pages
+}
+
+func TestOggCRC(t *testing.T) {
+	// The Ogg checksum of "123456789" (CRC-32/MPEG-2 without the final
+	// inversion and initial value).
+	assert.Equal(t, uint32(0x89a1897f), oggCRC([]byte("123456789")))
+}
+
+func TestOggOpusEncoder(t *testing.T) {
+	codec := &fakeOpus{delay: 104}
+	enc, err := newOggOpusEncoder(EncodeOptions{SampleRate: 16000, Channels: 2}, func(rate int, opts EncodeOptions) (opusCodec, error) {
+		assert.Equal(t, 16000, rate, "Opus encodes 16 kHz as it is")
+		return codec, nil
+	})
+	require.NoError(t, err)
+	defer enc.Close()
+
+	// 0.5 s in uneven chunks: 25 frames of 20 ms.
+	var out []byte
+	for _, n := range []int{1000, 3000, 4000} {
+		data, err := enc.Encode(make([]float32, n*2))
+		require.NoError(t, err)
+		out = append(out, data...)
+	}
+	assert.Equal(t, 25, codec.frames)
+	tail, err := enc.Flush()
+	require.NoError(t, err)
+	out = append(out, tail...)
+
+	pages := parseOggPages(t, out)
+	require.Len(t, pages, 2+26, "headers, 25 frames and one more for the codec delay")
+
+	head := pages[0]
+	assert.Equal(t, byte(oggFirstPage), head.flags)
+	assert.Equal(t, "OpusHead", string(head.packet[:8]))
+	assert.Equal(t, byte(2), head.packet[9])
+	assert.Equal(t, uint16(104*3), binary.LittleEndian.Uint16(head.packet[10:]), "pre-skip counts at 48 kHz")
+	assert.Equal(t, uint32(16000), binary.LittleEndian.Uint32(head.packet[12:]))
+	assert.Equal(t, "OpusTags", string(pages[1].packet[:8]))
+
+	for i, page := range pages {
+		assert.Equal(t, uint32(i), page.seq)
+	}
+	assert.Equal(t, int64(960), pages[2].granule)
+	assert.Equal(t, []byte{0x80, 0x02}, pages[2].packet, "frames of 320 samples per channel")
+	last := pages[len(pages)-1]
+	assert.Equal(t, byte(oggLastPage), last.flags)
+	assert.Equal(t, int64(104*3+24000), last.granule, "the end trims the padding")
+}
+
+func TestOggOpusEncoder_Resamples(t *testing.T) {
+	codec := &fakeOpus{}
+	enc, err := newOggOpusEncoder(EncodeOptions{SampleRate: 44100, Channels: 1}, func(rate int, opts EncodeOptions) (opusCodec, error) {
+		assert.Equal(t, opusRate, rate)
+		return codec, nil
+	})
+	require.NoError(t, err)
+	defer enc.Close()
+
+	out, err := enc.Encode(make([]float32, 44100))
+	require.NoError(t, err)
+	tail, err := enc.Flush()
+	require.NoError(t, err)
+	out = append(out, tail...)
+
+	pages := parseOggPages(t, out)
+	last := pages[len(pages)-1]
+	assert.InDelta(t, 48000, last.granule, 2, "one second at 48 kHz")
+	assert.Equal(t, uint32(44100), binary.LittleEndian.Uint32(pages[0].packet[12:]), "the input rate is kept in the header")
+}
+
+// fakeOpusDecoder "decodes" a fakeOpus packet to as many samples as it
+// counts.
+type fakeOpusDecoder struct{}
+
+func (fakeOpusDecoder) decode(packet []byte) ([]float32, error) {
+	samples := make([]float32, binary.LittleEndian.Uint16(packet))
+	for i := range samples {
+		samples[i] = 0.25
+	}
+	return samples, nil
+}
+
+func (fakeOpusDecoder) close() {}
+
+func TestDecodeOggOpus(t *testing.T) {
+	enc, err := newOggOpusEncoder(EncodeOptions{SampleRate: opusRate, Channels: 2}, func(int, EncodeOptions) (opusCodec, error) {
+		return &fakeOpus{delay: 312}, nil
+	})
+	require.NoError(t, err)
+	defer enc.Close()
+	data, err := enc.Encode(make([]float32, 24000*2))
+	require.NoError(t, err)
+	tail, err := enc.Flush()
+	require.NoError(t, err)
+	data = append(data, tail...)
+
+	p, err := decodeOggOpus(data, func(channels int) (opusDecoder, error) {
+		assert.Equal(t, 2, channels)
+		return fakeOpusDecoder{}, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, opusRate, p.SampleRate)
+	assert.Equal(t, 2, p.Channels)
+	assert.Equal(t, 24000, p.Frames(), "pre-skip and padding are trimmed")
+	assert.Equal(t, float32(0.25), p.Samples[0])
+
+	// Output gain of -6 dB, in 1/256 dB.
+	head := parseOggPages(t, data)[0].packet
+	binary.LittleEndian.PutUint16(head[16:], uint16(0x10000-6*256))
+	ogg := oggStream{serial: binary.LittleEndian.Uint32(data[14:])}
+	gained := append(ogg.page(head, 0, oggFirstPage), data[27+1+19:]...)
+	p, err = decodeOggOpus(gained, func(int) (opusDecoder, error) { return fakeOpusDecoder{}, nil })
+	require.NoError(t, err)
+	assert.InDelta(t, 0.125, p.Samples[0], 0.001)
+}
+
+func TestDecodeOggOpus_Vorbis(t *testing.T) {
+	var ogg oggStream
+	data := ogg.page([]byte("\x01vorbis\x00\x00\x00\x00\x01"), 0, oggFirstPage)
+	_, err := decodeOggOpus(data, func(int) (opusDecoder, error) { return fakeOpusDecoder{}, nil })
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestReadOggPackets(t *testing.T) {
+	// rawPage builds a page with the given lacing values and body.
+	rawPage := func(serial uint32, granule int64, lacing []byte, body []byte) []byte {
+		page := append([]byte("OggS\x00\x00"), binary.LittleEndian.AppendUint64(nil, uint64(granule))...)
+		page = binary.LittleEndian.AppendUint32(page, serial)
+		page = append(page, make([]byte, 8)...)
+		page = append(page, byte(len(lacing)))
+		page = append(page, lacing...)
+		return append(page, body...)
+	}
+	long := make([]byte, 300)
+	long[299] = 7
+
+	var data []byte
+	data = append(data, rawPage(1, -1, []byte{255}, long[:255])...)
+	data = append(data, rawPage(2, 0, []byte{3}, []byte("abc"))...)
+	data = append(data, rawPage(1, 960, []byte{45, 2}, append(long[255:], 'h', 'i'))...)
+
+	packets, err := readOggPackets(data)
+	require.NoError(t, err)
+	require.Len(t, packets, 2, "the other stream is skipped")
+	assert.Equal(t, long, packets[0].data, "the packet spans two pages")
+	assert.Equal(t, int64(-1), packets[0].granule)
+	assert.Equal(t, "hi", string(packets[1].data))
+	assert.Equal(t, int64(960), packets[1].granule)
+
+	_, err = readOggPackets(data[:len(data)-1])
+	assert.Error(t, err)
+}
+
+func TestLinearResampler(t *testing.T) {
+	samples := make([]float32, 1000)
+	for i := range samples {
+		samples[i] = float32(i)
+	}
+
+	whole := (&linearResampler{from: 2, to: 3, channels: 1}).resample(samples)
+	chunked := &linearResampler{from: 2, to: 3, channels: 1}
+	var joined []float32
+	for i := 0; i < len(samples); i += 7 {
+		joined = append(joined, chunked.resample(samples[i:min(i+7, len(samples))])...)
+	}
+	assert.Equal(t, whole, joined, "chunks join seamlessly")
+	assert.InDelta(t, 1500, len(whole), 2)
+	assert.InDelta(t, 2.0/3, whole[1], 1e-6)
+}
+
+func TestTranscodeWAVStream_OggOpus(t *testing.T) {
+	encodersMu.RLock()
+	previous, registered := encoders[FormatOpus]
+	encodersMu.RUnlock()
+	RegisterEncoder(FormatOpus, func(opts EncodeOptions) (Encoder, error) {
+		return newOggOpusEncoder(opts, func(rate int, opts EncodeOptions) (opusCodec, error) {
+			return &fakeOpus{delay: 120}, nil
+		})
+	})
+	defer func() {
+		encodersMu.Lock()
+		defer encodersMu.Unlock()
+		if registered {
+			encoders[FormatOpus] = previous
+		} else {
+			delete(encoders, FormatOpus)
+		}
+	}()
+
+	// 100 ms of 24 kHz mono arrives, then the backend pauses.
+	src, backend := io.Pipe()
+	stream := TranscodeWAVStream(src, FormatOpus, ProcessOptions{}, EncodeOptions{})
+	defer stream.Close()
+	go backend.Write(append(WAVHeader16(24000, 1, 0), make([]byte, 2400*2)...))
+
+	// The headers and the five frames are sent before the stream ends.
+	buf := make([]byte, 4096)
+	var out []byte
+	for len(out) == 0 || len(parseOggPages(t, out)) < 2+5 {
+		n, err := stream.Read(buf)
+		require.NoError(t, err)
+		out = append(out, buf[:n]...)
+	}
+
+	go func() {
+		backend.Write(make([]byte, 1000*2))
+		backend.Close()
+	}()
+	rest, err := io.ReadAll(stream)
+	require.NoError(t, err)
+	pages := parseOggPages(t, append(out, rest...))
+
+	assert.Equal(t, byte(oggFirstPage), pages[0].flags)
+	assert.Equal(t, "OpusHead", string(pages[0].packet[:8]))
+	assert.Equal(t, "OpusTags", string(pages[1].packet[:8]))
+	for i, page := range pages {
+		assert.Equal(t, uint32(i), page.seq, "pages are numbered without gaps")
+		if i > 2 {
+			assert.Greater(t, page.granule, pages[i-1].granule)
+		}
+		if i < len(pages)-1 {
+			assert.Zero(t, page.flags&oggLastPage)
+		}
+	}
+	assert.Equal(t, int64(960), pages[2].granule, "each page ends one 20 ms frame later")
+	last := pages[len(pages)-1]
+	assert.Equal(t, byte(oggLastPage), last.flags)
+	assert.Equal(t, int64(120*2+3400*2), last.granule, "the pre-skip and 3400 samples at 48 kHz")
+}
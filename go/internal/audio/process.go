@@ -0,0 +1,96 @@
+package audio
+
+// ProcessOptions describes the post-processing applied to synthesized audio.
+// Zero values leave the corresponding property unchanged.
+type ProcessOptions struct {
+	// SampleRate resamples the audio to this rate in Hz.
+	SampleRate int
+	// Channels up- or down-mixes the audio to 1 (mono) or 2 (stereo) channels.
+	Channels int
+	// GainDB adjusts the volume by this many decibels without clipping.
+	GainDB float64
+	// LoudnessLUFS normalizes the integrated loudness to this target.
+	LoudnessLUFS float64
+	// Speed changes the tempo by this factor without changing the pitch.
+	Speed float64
+}
+
+// IsZero reports whether the options request no processing.
+func (o ProcessOptions) IsZero() bool {
+	return o == ProcessOptions{}
+}
+
+// Streamable reports whether the options can be applied to audio as it
+// arrives. Gain and loudness normalization cannot: they depend on the peak
+// and loudness of the whole audio. Neither can the time stretching of Speed.
+func (o ProcessOptions) Streamable() bool {
+	return o.GainDB == 0 && o.LoudnessLUFS == 0 && o.Speed == 0
+}
+
+// Process applies opts to p and returns the resulting audio.
+func Process(p *PCM, opts ProcessOptions) *PCM {
+	if opts.SampleRate != 0 {
+		p = Resample(p, opts.SampleRate)
+	}
+	if opts.Channels != 0 {
+		p = Remix(p, opts.Channels)
+	}
+	if opts.Speed != 0 {
+		p = TimeStretch(p, opts.Speed)
+	}
+	if opts.LoudnessLUFS != 0 {
+		p = NormalizeLoudness(p, opts.LoudnessLUFS)
+	}
+	if opts.GainDB != 0 {
+		p = ApplyGain(p, opts.GainDB)
+	}
+	return p
+}
+
+// ProcessWAV decodes WAV data, applies opts, and re-encodes it as 16-bit PCM WAV.
+func ProcessWAV(data []byte, opts ProcessOptions) ([]byte, error) {
+	if opts.IsZero() {
+		return data, nil
+	}
+
+	pcm, err := DecodeWAV(data)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeWAV(Process(pcm, opts)), nil
+}
+
+// streamProcessor applies streamable ProcessOptions to audio received in
+// chunks.
+type streamProcessor struct {
+	opts      ProcessOptions
+	channels  int
+	resampler *streamResampler
+}
+
+// newStreamProcessor returns a processor of audio at rate with channels, and
+// the rate and channels of its output.
+func newStreamProcessor(rate, channels int, opts ProcessOptions) (p *streamProcessor, outRate, outChannels int) {
+	p = &streamProcessor{opts: opts, channels: channels}
+	outRate, outChannels = rate, channels
+	if opts.SampleRate != 0 && opts.SampleRate != rate {
+		p.resampler = newStreamResampler(rate, opts.SampleRate, channels)
+		outRate = opts.SampleRate
+	}
+	if opts.Channels != 0 {
+		outChannels = opts.Channels
+	}
+	return p, outRate, outChannels
+}
+
+// process consumes interleaved samples and returns the output available so
+// far; final marks the end of the input.
+func (p *streamProcessor) process(samples []float32, final bool) []float32 {
+	if p.resampler != nil {
+		samples = p.resampler.resample(samples, final)
+	}
+	if p.opts.Channels != 0 && p.opts.Channels != p.channels {
+		samples = Remix(&PCM{Channels: p.channels, Samples: samples}, p.opts.Channels).Samples
+	}
+	return samples
+}
@@ -0,0 +1,116 @@
+package audio
+
+import "math"
+
+// resampleZeroCrossings is the half-width of the windowed-sinc kernel measured in
+// zero crossings of the (possibly narrowed) low-pass filter.
+const resampleZeroCrossings = 16
+
+// Resample converts p to the target sample rate using band-limited windowed-sinc
+// interpolation. When downsampling, the kernel is widened so it also acts as an
+// anti-aliasing filter at the new Nyquist frequency.
+func Resample(p *PCM, rate int) *PCM {
+	if rate <= 0 || rate == p.SampleRate || p.Frames() == 0 {
+		return p
+	}
+	r := newStreamResampler(p.SampleRate, rate, p.Channels)
+	return &PCM{SampleRate: rate, Channels: p.Channels, Samples: r.resample(p.Samples, true)}
+}
+
+// streamResampler is the interpolation of Resample over audio received in
+// chunks. It holds back each output frame until the input frames its kernel
+// spans have arrived, so the output is the same as resampling the whole
+// audio at once.
+type streamResampler struct {
+	channels  int
+	ratio     float64
+	cutoff    float64
+	halfWidth float64
+	// buf holds the input frames from frame offset on, the oldest still
+	// needed; in and out count the frames received and produced.
+	buf     []float32
+	offset  int
+	in      int
+	out     int
+	weights []float64
+}
+
+func newStreamResampler(from, to, channels int) *streamResampler {
+	ratio := float64(to) / float64(from)
+	cutoff := math.Min(1, ratio)
+	return &streamResampler{
+		channels:  channels,
+		ratio:     ratio,
+		cutoff:    cutoff,
+		halfWidth: float64(resampleZeroCrossings) / cutoff,
+	}
+}
+
+// resample consumes interleaved samples and returns the output frames that
+// can be computed so far. final marks the end of the input, returning the
+// remaining frames with the kernel cut at the edge.
+func (r *streamResampler) resample(samples []float32, final bool) []float32 {
+	r.buf = append(r.buf, samples...)
+	r.in += len(samples) / r.channels
+	total := int(math.Round(float64(r.in) * r.ratio))
+
+	var out []float32
+	for ; r.out < total; r.out++ {
+		center := float64(r.out) / r.ratio
+		first := int(math.Ceil(center - r.halfWidth))
+		last := int(math.Floor(center + r.halfWidth))
+		if last >= r.in {
+			if !final {
+				break
+			}
+			last = r.in - 1
+		}
+		if first < 0 {
+			first = 0
+		}
+
+		r.weights = r.weights[:0]
+		var sum float64
+		for n := first; n <= last; n++ {
+			x := float64(n) - center
+			w := r.cutoff * sinc(r.cutoff*x) * hann(x/r.halfWidth)
+			r.weights = append(r.weights, w)
+			sum += w
+		}
+		for c := 0; c < r.channels; c++ {
+			var acc float64
+			for k, w := range r.weights {
+				acc += w * float64(r.buf[(first-r.offset+k)*r.channels+c])
+			}
+			if sum == 0 {
+				acc, sum = 0, 1
+			}
+			// Normalizing by the weight sum keeps DC gain at unity near the edges.
+			out = append(out, float32(acc/sum))
+		}
+	}
+
+	// Drop the input frames no later output frame reaches.
+	if keep := int(math.Ceil(float64(r.out)/r.ratio - r.halfWidth)); keep > r.offset {
+		drop := min(keep, r.in) - r.offset
+		r.buf = append(r.buf[:0], r.buf[drop*r.channels:]...)
+		r.offset += drop
+	}
+	return out
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	x *= math.Pi
+	return math.Sin(x) / x
+}
+
+// hann evaluates a Hann window spanning [-1, 1].
+func hann(x float64) float64 {
+	if x <= -1 || x >= 1 {
+		return 0
+	}
+	return 0.5 + 0.5*math.Cos(math.Pi*x)
+}
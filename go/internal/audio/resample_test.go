@@ -0,0 +1,45 @@
+package audio
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResample(t *testing.T) {
+	src := sine(44100, 1, 440, 500*time.Millisecond)
+
+	for _, rate := range []int{8000, 16000, 48000} {
+		out := Resample(src, rate)
+
+		assert.Equal(t, rate, out.SampleRate)
+		assert.Equal(t, 1, out.Channels)
+		assert.InDelta(t, rate/2, out.Frames(), 1)
+
+		// The tone must survive with its amplitude and frequency intact.
+		mid := out.Frames() / 2
+		for i := mid; i < mid+100; i++ {
+			want := 0.5 * math.Sin(2*math.Pi*440*float64(i)/float64(rate))
+			assert.InDelta(t, want, out.Samples[i], 0.01)
+		}
+	}
+}
+
+func TestResample_RemovesContentAboveNyquist(t *testing.T) {
+	src := sine(44100, 1, 6000, 500*time.Millisecond)
+
+	out := Resample(src, 8000)
+
+	var peak float64
+	for _, s := range out.Samples[out.Frames()/4 : 3*out.Frames()/4] {
+		peak = math.Max(peak, math.Abs(float64(s)))
+	}
+	assert.Less(t, peak, 0.05)
+}
+
+func TestResample_SameRate(t *testing.T) {
+	src := sine(16000, 2, 440, 10*time.Millisecond)
+	assert.Same(t, src, Resample(src, 16000))
+}
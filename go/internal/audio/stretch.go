@@ -0,0 +1,100 @@
+package audio
+
+import "math"
+
+const (
+	// stretchFrame is the duration of the overlapping frames TimeStretch
+	// splices, long enough to hold two pitch periods of a low voice.
+	stretchFrame = 0.02
+	// stretchTolerance is how far, in seconds, a frame may move from its
+	// nominal position to line up with the waveform already written: one
+	// pitch period of a low voice.
+	stretchTolerance = 0.01
+)
+
+// TimeStretch changes the tempo of p by speed, above 1 for faster speech,
+// without changing its pitch. It uses WSOLA (waveform similarity overlap-add):
+// the output is built from Hann-windowed input frames overlapping by half,
+// taken at the input position the speed calls for, moved within a tolerance
+// to the point where they best continue the waveform written so far.
+func TimeStretch(p *PCM, speed float64) *PCM {
+	frames := p.Frames()
+	if speed <= 0 || speed == 1 || frames == 0 {
+		return p
+	}
+
+	n := max(2*int(math.Round(stretchFrame*float64(p.SampleRate)/2)), 2)
+	hop := n / 2
+	tolerance := int(math.Round(stretchTolerance * float64(p.SampleRate)))
+	// Frames are aligned on the channels mixed down.
+	mono := ToMono(p).Samples
+
+	window := make([]float64, n)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n))
+	}
+
+	outFrames := int(math.Round(float64(frames) / speed))
+	acc := make([]float64, (outFrames+n)*p.Channels)
+	weights := make([]float64, outFrames+n)
+	prev := 0
+	for k := 0; k*hop < outFrames; k++ {
+		pos := int(math.Round(float64(k*hop) * speed))
+		if k > 0 {
+			// The natural continuation of the previous frame.
+			pos = bestAlignment(mono, prev+hop, pos, tolerance, n)
+		}
+		for i := 0; i < n; i++ {
+			src := pos + i
+			if src < 0 || src >= frames {
+				continue
+			}
+			w := window[i]
+			dst := k*hop + i
+			for c := 0; c < p.Channels; c++ {
+				acc[dst*p.Channels+c] += w * float64(p.Samples[src*p.Channels+c])
+			}
+			weights[dst] += w
+		}
+		prev = pos
+	}
+
+	out := &PCM{SampleRate: p.SampleRate, Channels: p.Channels, Samples: make([]float32, outFrames*p.Channels)}
+	for f := 0; f < outFrames; f++ {
+		if weights[f] < 1e-6 {
+			continue
+		}
+		// Dividing by the window sum also restores the edges, where only one
+		// frame contributes.
+		for c := 0; c < p.Channels; c++ {
+			out.Samples[f*p.Channels+c] = float32(acc[f*p.Channels+c] / weights[f])
+		}
+	}
+	return out
+}
+
+// bestAlignment returns the frame start within tolerance of nominal whose n
+// samples correlate best with those at target, normalized by the energy of
+// the candidate so that loud passages are not favoured.
+func bestAlignment(samples []float32, target, nominal, tolerance, n int) int {
+	best, bestScore := nominal, math.Inf(-1)
+	for start := max(nominal-tolerance, 0); start <= nominal+tolerance; start++ {
+		var corr, energy float64
+		for i := 0; i < n; i++ {
+			a, b := start+i, target+i
+			if a >= len(samples) || b >= len(samples) {
+				break
+			}
+			x := float64(samples[a])
+			corr += x * float64(samples[b])
+			energy += x * x
+		}
+		if energy == 0 {
+			continue
+		}
+		if score := corr / math.Sqrt(energy); score > bestScore {
+			best, bestScore = start, score
+		}
+	}
+	return best
+}
@@ -0,0 +1,51 @@
+package audio
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// zeroCrossingRate estimates the frequency of a tone from its rising zero
+// crossings.
+func zeroCrossingRate(p *PCM) float64 {
+	var crossings int
+	for f := 1; f < p.Frames(); f++ {
+		if p.Samples[(f-1)*p.Channels] < 0 && p.Samples[f*p.Channels] >= 0 {
+			crossings++
+		}
+	}
+	return float64(crossings) / p.Duration().Seconds()
+}
+
+func rms(samples []float32) float64 {
+	var sum float64
+	for _, s := range samples {
+		sum += float64(s) * float64(s)
+	}
+	return math.Sqrt(sum / float64(len(samples)))
+}
+
+func TestTimeStretch(t *testing.T) {
+	src := sine(24000, 2, 220, time.Second)
+
+	for _, speed := range []float64{0.8, 1.25, 1.5} {
+		out := TimeStretch(src, speed)
+
+		assert.Equal(t, src.SampleRate, out.SampleRate)
+		assert.Equal(t, 2, out.Channels)
+		assert.InDelta(t, 24000/speed, out.Frames(), 1, "speed %v", speed)
+		// The pitch and the level are kept.
+		assert.InDelta(t, 220, zeroCrossingRate(out), 5, "speed %v", speed)
+		assert.InDelta(t, 0.5/math.Sqrt2, rms(out.Samples), 0.02, "speed %v", speed)
+		for f := 0; f < out.Frames(); f++ {
+			if out.Samples[2*f] != out.Samples[2*f+1] {
+				t.Fatalf("speed %v: channels differ at frame %d", speed, f)
+			}
+		}
+	}
+
+	assert.Same(t, src, TimeStretch(src, 1))
+}
@@ -0,0 +1,275 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// WAV format tags understood by DecodeWAV.
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE
+)
+
+// ErrInvalidWAV indicates the data is not a RIFF/WAVE file this package can decode.
+var ErrInvalidWAV = errors.New("invalid WAV data")
+
+// PCM holds decoded, interleaved audio samples normalized to [-1, 1].
+type PCM struct {
+	SampleRate int
+	Channels   int
+	Samples    []float32
+}
+
+// Frames returns the number of sample frames (samples per channel).
+func (p *PCM) Frames() int {
+	if p.Channels == 0 {
+		return 0
+	}
+	return len(p.Samples) / p.Channels
+}
+
+// Duration returns the playback duration of the audio.
+func (p *PCM) Duration() time.Duration {
+	if p.SampleRate == 0 {
+		return 0
+	}
+	return time.Duration(p.Frames()) * time.Second / time.Duration(p.SampleRate)
+}
+
+// WAVHeader describes the fmt chunk of a WAV file and where its sample data begins.
+type WAVHeader struct {
+	Format        int
+	Channels      int
+	SampleRate    int
+	BitsPerSample int
+	// DataOffset is the byte offset of the first sample.
+	DataOffset int
+	// DataSize is the declared size of the data chunk; streaming encoders often
+	// leave it as zero or 0xFFFFFFFF.
+	DataSize uint32
+}
+
+// BlockAlign returns the size in bytes of one sample frame.
+func (h *WAVHeader) BlockAlign() int {
+	return h.Channels * h.BitsPerSample / 8
+}
+
+// ParseWAVHeader parses the RIFF header, fmt chunk, and locates the data chunk.
+func ParseWAVHeader(data []byte) (*WAVHeader, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, ErrInvalidWAV
+	}
+
+	var h WAVHeader
+	haveFmt := false
+	offset := 12
+	for offset+8 <= len(data) {
+		id := string(data[offset : offset+4])
+		size := binary.LittleEndian.Uint32(data[offset+4 : offset+8])
+		body := offset + 8
+
+		switch id {
+		case "fmt ":
+			if size < 16 || body+16 > len(data) {
+				return nil, fmt.Errorf("%w: truncated fmt chunk", ErrInvalidWAV)
+			}
+			h.Format = int(binary.LittleEndian.Uint16(data[body:]))
+			h.Channels = int(binary.LittleEndian.Uint16(data[body+2:]))
+			h.SampleRate = int(binary.LittleEndian.Uint32(data[body+4:]))
+			h.BitsPerSample = int(binary.LittleEndian.Uint16(data[body+14:]))
+			if h.Format == wavFormatExtensible && size >= 40 && body+26 <= len(data) {
+				h.Format = int(binary.LittleEndian.Uint16(data[body+24:]))
+			}
+			haveFmt = true
+		case "data":
+			if !haveFmt {
+				return nil, fmt.Errorf("%w: data chunk before fmt chunk", ErrInvalidWAV)
+			}
+			h.DataOffset = body
+			h.DataSize = size
+			if h.Channels == 0 || h.BitsPerSample == 0 {
+				return nil, fmt.Errorf("%w: missing channel or sample size", ErrInvalidWAV)
+			}
+			return &h, nil
+		}
+
+		offset = body + int(size) + int(size&1)
+	}
+
+	return nil, fmt.Errorf("%w: missing data chunk", ErrInvalidWAV)
+}
+
+// DecodeWAV decodes integer PCM (8/16/24/32-bit) or IEEE float WAV data.
+func DecodeWAV(data []byte) (*PCM, error) {
+	h, err := ParseWAVHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	end := len(data)
+	if h.DataSize != 0 && h.DataSize != math.MaxUint32 && h.DataOffset+int(h.DataSize) < end {
+		end = h.DataOffset + int(h.DataSize)
+	}
+
+	samples, err := decodeSamples(h, data[h.DataOffset:end])
+	if err != nil {
+		return nil, err
+	}
+	return &PCM{SampleRate: h.SampleRate, Channels: h.Channels, Samples: samples}, nil
+}
+
+func decodeSamples(h *WAVHeader, raw []byte) ([]float32, error) {
+	width := h.BitsPerSample / 8
+	if width == 0 {
+		return nil, fmt.Errorf("%w: unsupported bits per sample %d", ErrInvalidWAV, h.BitsPerSample)
+	}
+	raw = raw[:len(raw)-len(raw)%(width*h.Channels)]
+	n := len(raw) / width
+	samples := make([]float32, n)
+
+	switch {
+	case h.Format == wavFormatPCM && width == 1:
+		for i := 0; i < n; i++ {
+			samples[i] = (float32(raw[i]) - 128) / 128
+		}
+	case h.Format == wavFormatPCM && width == 2:
+		for i := 0; i < n; i++ {
+			samples[i] = float32(int16(binary.LittleEndian.Uint16(raw[i*2:]))) / 32768
+		}
+	case h.Format == wavFormatPCM && width == 3:
+		for i := 0; i < n; i++ {
+			b := raw[i*3:]
+			v := int32(b[0]) | int32(b[1])<<8 | int32(int8(b[2]))<<16
+			samples[i] = float32(v) / 8388608
+		}
+	case h.Format == wavFormatPCM && width == 4:
+		for i := 0; i < n; i++ {
+			samples[i] = float32(float64(int32(binary.LittleEndian.Uint32(raw[i*4:]))) / 2147483648)
+		}
+	case h.Format == wavFormatFloat && width == 4:
+		for i := 0; i < n; i++ {
+			samples[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[i*4:]))
+		}
+	case h.Format == wavFormatFloat && width == 8:
+		for i := 0; i < n; i++ {
+			samples[i] = float32(math.Float64frombits(binary.LittleEndian.Uint64(raw[i*8:])))
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported format %d with %d bits per sample", ErrInvalidWAV, h.Format, h.BitsPerSample)
+	}
+
+	return samples, nil
+}
+
+// EncodeWAV encodes audio as a 16-bit PCM WAV file, clipping samples to [-1, 1].
+func EncodeWAV(p *PCM) []byte {
+	dataSize := len(p.Samples) * 2
+	buf := make([]byte, 44+dataSize)
+	writeWAVHeader(buf, p.SampleRate, p.Channels, uint32(dataSize))
+	PutPCM16(buf[44:], p.Samples)
+	return buf
+}
+
+// WAVHeader16 returns a 44-byte header for 16-bit PCM audio. A dataSize of zero
+// produces a header suitable for streaming where the length is unknown.
+func WAVHeader16(sampleRate, channels int, dataSize uint32) []byte {
+	buf := make([]byte, 44)
+	writeWAVHeader(buf, sampleRate, channels, dataSize)
+	return buf
+}
+
+func writeWAVHeader(buf []byte, sampleRate, channels int, dataSize uint32) {
+	blockAlign := channels * 2
+	copy(buf[0:], "RIFF")
+	binary.LittleEndian.PutUint32(buf[4:], 36+dataSize)
+	copy(buf[8:], "WAVE")
+	copy(buf[12:], "fmt ")
+	binary.LittleEndian.PutUint32(buf[16:], 16)
+	binary.LittleEndian.PutUint16(buf[20:], wavFormatPCM)
+	binary.LittleEndian.PutUint16(buf[22:], uint16(channels))
+	binary.LittleEndian.PutUint32(buf[24:], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(buf[28:], uint32(sampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(buf[32:], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(buf[34:], 16)
+	copy(buf[36:], "data")
+	binary.LittleEndian.PutUint32(buf[40:], dataSize)
+}
+
+// PutPCM16 writes samples as little-endian 16-bit PCM into dst, which must hold
+// at least 2*len(samples) bytes.
+func PutPCM16(dst []byte, samples []float32) {
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(dst[i*2:], uint16(toInt16(s)))
+	}
+}
+
+func toInt16(s float32) int16 {
+	v := float64(s) * 32767
+	switch {
+	case v > math.MaxInt16:
+		return math.MaxInt16
+	case v < math.MinInt16:
+		return math.MinInt16
+	default:
+		return int16(math.Round(v))
+	}
+}
+
+// FormatPCM is headerless little-endian 16-bit PCM, an output format only.
+const FormatPCM = "pcm"
+
+func init() {
+	RegisterEncoder(FormatWAV, func(opts EncodeOptions) (Encoder, error) {
+		return &pcm16Encoder{header: WAVHeader16(opts.SampleRate, opts.Channels, 0)}, nil
+	})
+	RegisterEncoder(FormatPCM, func(opts EncodeOptions) (Encoder, error) {
+		return &pcm16Encoder{}, nil
+	})
+}
+
+// pcm16Encoder writes 16-bit PCM after header, if any. A WAV header is first
+// written for an unknown length; FinalHeader fills it in.
+type pcm16Encoder struct {
+	header  []byte
+	size    int
+	started bool
+}
+
+func (e *pcm16Encoder) Encode(samples []float32) ([]byte, error) {
+	out := e.start()
+	n := len(out)
+	out = append(out, make([]byte, 2*len(samples))...)
+	PutPCM16(out[n:], samples)
+	e.size += 2 * len(samples)
+	return out, nil
+}
+
+func (e *pcm16Encoder) Flush() ([]byte, error) {
+	return e.start(), nil
+}
+
+func (e *pcm16Encoder) Close() {}
+
+func (e *pcm16Encoder) FinalHeader() []byte {
+	if e.header == nil {
+		return nil
+	}
+	final := append([]byte(nil), e.header...)
+	binary.LittleEndian.PutUint32(final[4:], uint32(36+e.size))
+	binary.LittleEndian.PutUint32(final[40:], uint32(e.size))
+	return final
+}
+
+// start returns the header the first time it is called.
+func (e *pcm16Encoder) start() []byte {
+	if e.started {
+		return nil
+	}
+	e.started = true
+	return append([]byte(nil), e.header...)
+}
@@ -0,0 +1,55 @@
+package audio
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sine(sampleRate, channels int, freq float64, d time.Duration) *PCM {
+	frames := int(d.Seconds() * float64(sampleRate))
+	p := &PCM{SampleRate: sampleRate, Channels: channels, Samples: make([]float32, frames*channels)}
+	for f := 0; f < frames; f++ {
+		v := float32(0.5 * math.Sin(2*math.Pi*freq*float64(f)/float64(sampleRate)))
+		for c := 0; c < channels; c++ {
+			p.Samples[f*channels+c] = v
+		}
+	}
+	return p
+}
+
+func TestWAVRoundTrip(t *testing.T) {
+	src := sine(16000, 2, 440, 250*time.Millisecond)
+
+	decoded, err := DecodeWAV(EncodeWAV(src))
+	require.NoError(t, err)
+
+	assert.Equal(t, 16000, decoded.SampleRate)
+	assert.Equal(t, 2, decoded.Channels)
+	assert.Equal(t, src.Frames(), decoded.Frames())
+	assert.Equal(t, 250*time.Millisecond, decoded.Duration())
+	for i := range src.Samples {
+		assert.InDelta(t, src.Samples[i], decoded.Samples[i], 1.0/32767)
+	}
+}
+
+func TestDecodeWAV_Invalid(t *testing.T) {
+	_, err := DecodeWAV([]byte("ID3 not a wav file"))
+	assert.ErrorIs(t, err, ErrInvalidWAV)
+}
+
+func TestEnvelope_SimilarForReencodedAudio(t *testing.T) {
+	a := sine(16000, 1, 220, time.Second)
+	for i := len(a.Samples) / 2; i < len(a.Samples); i++ {
+		a.Samples[i] *= 0.2
+	}
+	b, err := DecodeWAV(EncodeWAV(a))
+	require.NoError(t, err)
+	c := sine(16000, 1, 220, time.Second)
+
+	assert.Greater(t, CosineSimilarity(Envelope(a, 64), Envelope(b, 64)), 0.999)
+	assert.Less(t, CosineSimilarity(Envelope(a, 64), Envelope(c, 64)), 0.95)
+}
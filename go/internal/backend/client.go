@@ -1,7 +1,6 @@
 package backend
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -11,38 +10,96 @@ import (
 	"strings"
 	"time"
 
+	"github.com/vmihailenco/msgpack/v5"
+
 	"github.com/fish-speech-go/fish-speech-go/internal/config"
 	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+	"github.com/fish-speech-go/fish-speech-go/internal/sockaddr"
 )
 
 // BackendClient handles communication with the Python Fish-Speech server.
 type BackendClient struct {
 	httpClient *http.Client
-	endpoint   string
-	timeout    time.Duration
+	// streamClient has no overall timeout, which would cut long generations off
+	// mid-audio; streams are bounded by the caller's context instead.
+	streamClient *http.Client
+	// url is the configured backend URL and endpoint the base URL requests are
+	// sent to; they differ for a Unix domain socket.
+	url      string
+	endpoint string
+	timeout  time.Duration
+	conns    *connCounter
+	retry    RetryPolicy
 }
 
-// NewBackendClient creates a new backend client with connection pooling.
+// NewBackendClient creates a new backend client with connection pooling. A
+// unix:///path/to.sock URL connects to the backend over a Unix domain socket.
 func NewBackendClient(cfg *config.BackendConfig) *BackendClient {
+	conns := newConnCounter()
+	endpoint := cfg.URL
+	if path, ok := sockaddr.UnixPath(cfg.URL); ok {
+		conns.socket = path
+		// The host only fills the Host header; every connection dials the socket.
+		endpoint = "http://unix"
+	}
 	transport := &http.Transport{
+		DialContext:         conns.DialContext,
 		MaxIdleConns:        100,
 		MaxIdleConnsPerHost: 100,
 		IdleConnTimeout:     90 * time.Second,
 		DisableCompression:  true,
 	}
 
+	var roundTripper http.RoundTripper = transport
+	if cfg.RecordDir != "" {
+		roundTripper = &RecordingTransport{Dir: cfg.RecordDir, Next: transport}
+	}
+	roundTripper = &DeadlineTransport{Next: roundTripper}
+	roundTripper = &RequestIDTransport{Next: roundTripper}
+	roundTripper = &TracingTransport{Next: roundTripper}
+
 	client := &http.Client{
-		Transport: transport,
+		Transport: roundTripper,
 		Timeout:   cfg.Timeout,
 	}
 
 	return &BackendClient{
-		httpClient: client,
-		endpoint:   cfg.URL,
-		timeout:    cfg.Timeout,
+		httpClient:   client,
+		streamClient: &http.Client{Transport: roundTripper},
+		url:          cfg.URL,
+		endpoint:     endpoint,
+		timeout:      cfg.Timeout,
+		conns:        conns,
+		retry:        newRetryPolicy(cfg.Retry),
 	}
 }
 
+// newMsgpackRequest creates a POST of v to path. The body is encoded while it is
+// sent with chunked transfer encoding rather than buffered first, and encoded
+// again if the request is retried.
+func (c *BackendClient) newMsgpackRequest(ctx context.Context, path string, v interface{}) (*http.Request, error) {
+	body := NewMsgpackReader(v)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+path, body)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	httpReq.GetBody = func() (io.ReadCloser, error) {
+		return NewMsgpackReader(v), nil
+	}
+	httpReq.Header.Set("Content-Type", "application/msgpack")
+	return httpReq, nil
+}
+
+// decodeResponse decodes a MessagePack response body as it is read, or a JSON
+// one when the backend answers with another Content-Type.
+func decodeResponse(resp *http.Response, v interface{}) error {
+	if strings.Contains(resp.Header.Get("Content-Type"), "msgpack") {
+		return msgpack.NewDecoder(resp.Body).Decode(v)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
 // Health checks if the Python backend is reachable.
 func (c *BackendClient) Health(ctx context.Context) error {
 	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+"/v1/health", nil)
@@ -65,19 +122,16 @@ func (c *BackendClient) Health(ctx context.Context) error {
 
 // TTS sends a TTS request and returns the complete audio response (non-streaming).
 func (c *BackendClient) TTS(ctx context.Context, req *schema.ServeTTSRequest) ([]byte, string, error) {
-	body, err := EncodeTTSRequest(req)
-	if err != nil {
+	if err := req.Validate(0); err != nil {
 		return nil, "", fmt.Errorf("failed to encode request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/v1/tts", bytes.NewReader(body))
+	httpReq, err := c.newMsgpackRequest(ctx, "/v1/tts", req.Upstream())
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to create request: %w", err)
 	}
 
-	httpReq.Header.Set("Content-Type", "application/msgpack")
-
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.do(c.httpClient, httpReq)
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
 			return nil, "", fmt.Errorf("%w: %v", ErrBackendTimeout, err)
@@ -102,19 +156,16 @@ func (c *BackendClient) TTS(ctx context.Context, req *schema.ServeTTSRequest) ([
 // TTSStream sends a TTS request and returns a streaming response.
 func (c *BackendClient) TTSStream(ctx context.Context, req *schema.ServeTTSRequest) (io.ReadCloser, error) {
 	req.Streaming = true
-	body, err := EncodeTTSRequest(req)
-	if err != nil {
+	if err := req.Validate(0); err != nil {
 		return nil, fmt.Errorf("failed to encode request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/v1/tts", bytes.NewReader(body))
+	httpReq, err := c.newMsgpackRequest(ctx, "/v1/tts", req.Upstream())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	httpReq.Header.Set("Content-Type", "application/msgpack")
-
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.do(c.streamClient, httpReq)
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
 			return nil, fmt.Errorf("%w: %v", ErrBackendTimeout, err)
@@ -133,18 +184,12 @@ func (c *BackendClient) TTSStream(ctx context.Context, req *schema.ServeTTSReque
 
 // VQGANEncode sends audio to be encoded to tokens.
 func (c *BackendClient) VQGANEncode(ctx context.Context, req *schema.ServeVQGANEncodeRequest) (*schema.ServeVQGANEncodeResponse, error) {
-	body, err := EncodeMsgpack(req)
+	httpReq, err := c.newMsgpackRequest(ctx, "/v1/vqgan/encode", req)
 	if err != nil {
 		return nil, err
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/v1/vqgan/encode", bytes.NewReader(body))
-	if err != nil {
-		return nil, err
-	}
-	httpReq.Header.Set("Content-Type", "application/msgpack")
-
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.do(c.httpClient, httpReq)
 	if err != nil {
 		return nil, err
 	}
@@ -155,13 +200,8 @@ func (c *BackendClient) VQGANEncode(ctx context.Context, req *schema.ServeVQGANE
 		return nil, &BackendError{StatusCode: resp.StatusCode, Message: string(bodyBytes)}
 	}
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
 	var result schema.ServeVQGANEncodeResponse
-	if err := DecodeMsgpack(respBody, &result); err != nil {
+	if err := msgpack.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return nil, err
 	}
 
@@ -170,18 +210,12 @@ func (c *BackendClient) VQGANEncode(ctx context.Context, req *schema.ServeVQGANE
 
 // VQGANDecode sends tokens to be decoded to audio.
 func (c *BackendClient) VQGANDecode(ctx context.Context, req *schema.ServeVQGANDecodeRequest) (*schema.ServeVQGANDecodeResponse, error) {
-	body, err := EncodeMsgpack(req)
+	httpReq, err := c.newMsgpackRequest(ctx, "/v1/vqgan/decode", req)
 	if err != nil {
 		return nil, err
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/v1/vqgan/decode", bytes.NewReader(body))
-	if err != nil {
-		return nil, err
-	}
-	httpReq.Header.Set("Content-Type", "application/msgpack")
-
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.do(c.httpClient, httpReq)
 	if err != nil {
 		return nil, err
 	}
@@ -192,13 +226,34 @@ func (c *BackendClient) VQGANDecode(ctx context.Context, req *schema.ServeVQGAND
 		return nil, &BackendError{StatusCode: resp.StatusCode, Message: string(bodyBytes)}
 	}
 
-	respBody, err := io.ReadAll(resp.Body)
+	var result schema.ServeVQGANDecodeResponse
+	if err := msgpack.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// ASR transcribes audio using the backend's speech recognition endpoint.
+func (c *BackendClient) ASR(ctx context.Context, req *schema.ServeASRRequest) (*schema.ServeASRResponse, error) {
+	httpReq, err := c.newMsgpackRequest(ctx, "/v1/asr", req)
 	if err != nil {
 		return nil, err
 	}
 
-	var result schema.ServeVQGANDecodeResponse
-	if err := DecodeMsgpack(respBody, &result); err != nil {
+	resp, err := c.do(c.httpClient, httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, &BackendError{StatusCode: resp.StatusCode, Message: string(bodyBytes)}
+	}
+
+	var result schema.ServeASRResponse
+	if err := decodeResponse(resp, &result); err != nil {
 		return nil, err
 	}
 
@@ -207,18 +262,12 @@ func (c *BackendClient) VQGANDecode(ctx context.Context, req *schema.ServeVQGAND
 
 // AddReference adds a new voice reference.
 func (c *BackendClient) AddReference(ctx context.Context, req *schema.AddReferenceRequest) (*schema.AddReferenceResponse, error) {
-	body, err := EncodeMsgpack(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to encode request: %w", err)
-	}
-
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/v1/references/add", bytes.NewReader(body))
+	httpReq, err := c.newMsgpackRequest(ctx, "/v1/references/add", req.Upstream())
 	if err != nil {
 		return nil, err
 	}
-	httpReq.Header.Set("Content-Type", "application/msgpack")
 
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.do(c.httpClient, httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
@@ -229,20 +278,9 @@ func (c *BackendClient) AddReference(ctx context.Context, req *schema.AddReferen
 		return nil, &BackendError{StatusCode: resp.StatusCode, Message: string(bodyBytes)}
 	}
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
 	var result schema.AddReferenceResponse
-	if strings.Contains(resp.Header.Get("Content-Type"), "msgpack") {
-		if err := DecodeMsgpack(respBody, &result); err != nil {
-			return nil, err
-		}
-	} else {
-		if err := json.Unmarshal(respBody, &result); err != nil {
-			return nil, err
-		}
+	if err := decodeResponse(resp, &result); err != nil {
+		return nil, err
 	}
 
 	return &result, nil
@@ -255,7 +293,7 @@ func (c *BackendClient) ListReferences(ctx context.Context) (*schema.ListReferen
 		return nil, err
 	}
 
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.do(c.httpClient, httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
@@ -281,7 +319,7 @@ func (c *BackendClient) DeleteReference(ctx context.Context, id string) (*schema
 		return nil, err
 	}
 
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.do(c.httpClient, httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
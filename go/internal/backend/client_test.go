@@ -1,18 +1,23 @@
 package backend
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
 
 	"github.com/fish-speech-go/fish-speech-go/internal/config"
 	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+	"github.com/fish-speech-go/fish-speech-go/internal/sockaddr"
 )
 
 func TestEncodeTTSRequest(t *testing.T) {
@@ -41,6 +46,21 @@ func TestEncodeTTSRequest(t *testing.T) {
 	assert.Contains(t, decoded, "temperature")
 }
 
+func TestEncodeTTSRequest_OmitsGoSideOptions(t *testing.T) {
+	req := &schema.ServeTTSRequest{Text: "Hello world", Model: "base", Language: "en", SampleRate: 16000}
+
+	data, err := EncodeTTSRequest(req)
+	require.NoError(t, err)
+
+	var decoded map[string]interface{}
+	require.NoError(t, DecodeMsgpack(data, &decoded))
+
+	assert.NotContains(t, decoded, "model")
+	assert.NotContains(t, decoded, "language")
+	assert.NotContains(t, decoded, "sample_rate")
+	assert.Equal(t, 16000, req.SampleRate)
+}
+
 func TestTTS_Success(t *testing.T) {
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, "/v1/tts", r.URL.Path)
@@ -127,6 +147,27 @@ func TestAddReference_SuccessJSON(t *testing.T) {
 	assert.Equal(t, "id1", resp.ReferenceID)
 }
 
+func TestAddReference_StreamsRequestBody(t *testing.T) {
+	audio := bytes.Repeat([]byte{0xAB}, 1<<20)
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, int64(-1), r.ContentLength, "body is sent chunked, not buffered")
+		var req schema.AddReferenceRequest
+		require.NoError(t, msgpack.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, audio, req.Audio)
+
+		body, _ := EncodeMsgpack(schema.AddReferenceResponse{Success: true, ReferenceID: req.ID})
+		w.Header().Set("Content-Type", "application/msgpack")
+		w.Write(body)
+	}))
+	defer mockServer.Close()
+
+	client := NewBackendClient(&config.BackendConfig{URL: mockServer.URL, Timeout: 5 * time.Second})
+
+	resp, err := client.AddReference(context.Background(), &schema.AddReferenceRequest{ID: "big", Audio: audio, Text: "t"})
+	require.NoError(t, err)
+	assert.Equal(t, "big", resp.ReferenceID)
+}
+
 func TestListReferences_Success(t *testing.T) {
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		assert.Equal(t, "/v1/references", r.URL.Path)
@@ -155,3 +196,177 @@ func TestDeleteReference_Success(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "test", resp.ReferenceID)
 }
+
+func TestASR_Success(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/asr", r.URL.Path)
+		assert.Equal(t, "application/msgpack", r.Header.Get("Content-Type"))
+		body, _ := EncodeMsgpack(schema.ServeASRResponse{Transcriptions: []schema.ServeASRTranscription{{Text: "hello", Duration: 1.5}}})
+		w.Header().Set("Content-Type", "application/msgpack")
+		w.Write(body)
+	}))
+	defer mockServer.Close()
+
+	client := NewBackendClient(&config.BackendConfig{URL: mockServer.URL, Timeout: 5 * time.Second})
+
+	resp, err := client.ASR(context.Background(), &schema.ServeASRRequest{Audios: [][]byte{{0, 0}}, SampleRate: 16000})
+	require.NoError(t, err)
+	require.Len(t, resp.Transcriptions, 1)
+	assert.Equal(t, "hello", resp.Transcriptions[0].Text)
+}
+
+func TestRuntimeStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/stats", r.URL.Path)
+		w.Write([]byte(`{"gpu_memory_used":1024,"queue_depth":2}`))
+	}))
+	defer server.Close()
+
+	client := NewBackendClient(&config.BackendConfig{URL: server.URL, Timeout: 5 * time.Second})
+	stats, err := client.RuntimeStats(context.Background())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"gpu_memory_used":1024,"queue_depth":2}`, string(stats))
+
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+	client = NewBackendClient(&config.BackendConfig{URL: missing.URL, Timeout: 5 * time.Second})
+	_, err = client.RuntimeStats(context.Background())
+	assert.ErrorIs(t, err, ErrStatsUnavailable)
+
+	router, err := NewRouter(client, "", Route{
+		Name:    "gpu-b",
+		Backend: NewBackendClient(&config.BackendConfig{URL: server.URL, Timeout: 5 * time.Second}),
+	})
+	require.NoError(t, err)
+	stats, err = router.RuntimeStats(context.Background())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"gpu-b":{"gpu_memory_used":1024,"queue_depth":2}}`, string(stats))
+}
+
+func TestOpenConnections(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := NewBackendClient(&config.BackendConfig{URL: server.URL, Timeout: 5 * time.Second})
+	assert.Equal(t, map[string]int64{server.URL: 0}, client.OpenConnections())
+
+	require.NoError(t, client.Health(context.Background()))
+	assert.Equal(t, map[string]int64{server.URL: 1}, client.OpenConnections(), "idle connection stays open")
+
+	server.CloseClientConnections()
+	client.httpClient.CloseIdleConnections()
+	assert.Eventually(t, func() bool { return client.OpenConnections()[server.URL] == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestUnixSocketBackend(t *testing.T) {
+	ln, err := sockaddr.Listen(sockaddr.Scheme+filepath.Join(t.TempDir(), "backend.sock"), 0)
+	require.NoError(t, err)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/health", r.URL.Path)
+	}))
+	server.Listener = ln
+	server.Start()
+	defer server.Close()
+
+	url := sockaddr.Scheme + ln.Addr().String()
+	client := NewBackendClient(&config.BackendConfig{URL: url, Timeout: 5 * time.Second})
+	require.NoError(t, client.Health(context.Background()))
+	assert.Equal(t, map[string]int64{url: 1}, client.OpenConnections())
+}
+
+func retryConfig(url string) *config.BackendConfig {
+	return &config.BackendConfig{
+		URL:     url,
+		Timeout: 5 * time.Second,
+		Retry: config.BackendRetryConfig{
+			MaxAttempts: 3,
+			Backoff:     time.Millisecond,
+			MaxBackoff:  10 * time.Millisecond,
+			StatusCodes: []int{502, 503},
+		},
+	}
+}
+
+func TestTTS_RetriesTransientErrors(t *testing.T) {
+	var attempts atomic.Int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		require.NoError(t, msgpack.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "Hello", req["text"])
+
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("audio"))
+	}))
+	defer mockServer.Close()
+
+	client := NewBackendClient(retryConfig(mockServer.URL))
+	data, _, err := client.TTS(context.Background(), &schema.ServeTTSRequest{Text: "Hello"})
+	require.NoError(t, err)
+	assert.Equal(t, "audio", string(data))
+	assert.Equal(t, int32(3), attempts.Load())
+}
+
+func TestTTS_RetryGivesUp(t *testing.T) {
+	var attempts atomic.Int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("bad gateway"))
+	}))
+	defer mockServer.Close()
+
+	client := NewBackendClient(retryConfig(mockServer.URL))
+	_, _, err := client.TTS(context.Background(), &schema.ServeTTSRequest{Text: "Hello"})
+	var backendErr *BackendError
+	require.ErrorAs(t, err, &backendErr)
+	assert.Equal(t, http.StatusBadGateway, backendErr.StatusCode)
+	assert.Equal(t, "bad gateway", backendErr.Message)
+	assert.Equal(t, int32(3), attempts.Load())
+}
+
+func TestTTS_DoesNotRetryClientErrors(t *testing.T) {
+	var attempts atomic.Int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer mockServer.Close()
+
+	client := NewBackendClient(retryConfig(mockServer.URL))
+	_, _, err := client.TTS(context.Background(), &schema.ServeTTSRequest{Text: "Hello"})
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), attempts.Load())
+}
+
+func TestDeleteReference_RetriesResetConnections(t *testing.T) {
+	var attempts atomic.Int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			require.NoError(t, err)
+			conn.Close()
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"success":true,"message":"deleted"}`))
+	}))
+	defer mockServer.Close()
+
+	client := NewBackendClient(retryConfig(mockServer.URL))
+	resp, err := client.DeleteReference(context.Background(), "voice")
+	require.NoError(t, err)
+	assert.True(t, resp.Success)
+	assert.Equal(t, int32(2), attempts.Load())
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	p := RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+	for n, max := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 5: 300 * time.Millisecond} {
+		d := p.delay(n)
+		assert.GreaterOrEqual(t, d, max/2)
+		assert.LessOrEqual(t, d, max)
+	}
+}
@@ -0,0 +1,78 @@
+package backend
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConnectionReporter is implemented by backends that track their open
+// connections, keyed by backend URL.
+type ConnectionReporter interface {
+	OpenConnections() map[string]int64
+}
+
+var (
+	_ ConnectionReporter = (*BackendClient)(nil)
+	_ ConnectionReporter = (*Router)(nil)
+	_ ConnectionReporter = (*Pool)(nil)
+)
+
+// connCounter dials connections and counts those not yet closed. When socket
+// is set, every connection dials that Unix domain socket instead of addr.
+type connCounter struct {
+	dialer net.Dialer
+	socket string
+	open   atomic.Int64
+}
+
+func (c *connCounter) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if c.socket != "" {
+		network, addr = "unix", c.socket
+	}
+	conn, err := c.dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	c.open.Add(1)
+	return &countedConn{Conn: conn, counter: c}, nil
+}
+
+type countedConn struct {
+	net.Conn
+	counter *connCounter
+	once    sync.Once
+}
+
+func (c *countedConn) Close() error {
+	c.once.Do(func() { c.counter.open.Add(-1) })
+	return c.Conn.Close()
+}
+
+func newConnCounter() *connCounter {
+	return &connCounter{dialer: net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}}
+}
+
+// OpenConnections returns the number of open connections to the backend,
+// idle or in use. A replay backend has none.
+func (c *BackendClient) OpenConnections() map[string]int64 {
+	if c.conns == nil {
+		return nil
+	}
+	return map[string]int64{c.url: c.conns.open.Load()}
+}
+
+// OpenConnections merges the open connections of every target.
+func (r *Router) OpenConnections() map[string]int64 {
+	conns := make(map[string]int64, len(r.routes)+1)
+	for _, t := range append([]*target{r.fallback}, r.routes...) {
+		if reporter, ok := t.route.Backend.(ConnectionReporter); ok {
+			for url, n := range reporter.OpenConnections() {
+				conns[url] += n
+			}
+		}
+	}
+	return conns
+}
@@ -0,0 +1,34 @@
+package backend
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DeadlineHeader carries a request deadline, both from clients and to the backend.
+const DeadlineHeader = "X-Request-Deadline"
+
+// DeadlineTransport forwards the remaining budget of the request context's
+// deadline (the caller's deadline or the client timeout, whichever is sooner) to
+// the backend as a relative X-Request-Deadline, which is immune to clock skew
+// between hosts.
+type DeadlineTransport struct {
+	Next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *DeadlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	deadline, ok := req.Context().Deadline()
+	if !ok {
+		return t.Next.RoundTrip(req)
+	}
+
+	remaining := time.Until(deadline).Milliseconds()
+	if remaining < 1 {
+		remaining = 1
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set(DeadlineHeader, strconv.FormatInt(remaining, 10)+"ms")
+	return t.Next.RoundTrip(req)
+}
@@ -0,0 +1,43 @@
+package backend
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+func TestDeadlineTransport_PropagatesRemainingBudget(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get(DeadlineHeader))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewBackendClient(&config.BackendConfig{URL: server.URL, Timeout: 5 * time.Second})
+
+	require.NoError(t, client.Health(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	require.NoError(t, client.Health(ctx))
+
+	require.Len(t, got, 2)
+	// Without a caller deadline the client timeout is the budget.
+	remaining, err := time.ParseDuration(got[0])
+	require.NoError(t, err)
+	assert.Greater(t, remaining, 4*time.Second)
+	assert.LessOrEqual(t, remaining, 5*time.Second)
+
+	remaining, err = time.ParseDuration(got[1])
+	require.NoError(t, err)
+	assert.Greater(t, remaining, time.Second)
+	assert.LessOrEqual(t, remaining, 2*time.Second)
+}
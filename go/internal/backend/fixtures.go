@@ -0,0 +1,153 @@
+package backend
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNoFixture is returned in replay mode when no recording matches a request.
+var ErrNoFixture = errors.New("no recorded fixture")
+
+// Fixture is a recorded backend request/response pair.
+type Fixture struct {
+	Method        string `json:"method"`
+	Path          string `json:"path"`
+	RequestSHA256 string `json:"request_sha256"`
+	Status        int    `json:"status"`
+	ContentType   string `json:"content_type,omitempty"`
+	Body          []byte `json:"body"`
+}
+
+// fixtureFile names the fixture for a request. Requests are matched on method,
+// path, and the SHA-256 of the body.
+func fixtureFile(dir, method, path string, body []byte) (string, string) {
+	sum := sha256.Sum256(body)
+	digest := hex.EncodeToString(sum[:])
+	name := strings.ToLower(method) + "_" + strings.ReplaceAll(strings.Trim(path, "/"), "/", "_") + "_" + digest[:16] + ".json"
+	return filepath.Join(dir, name), digest
+}
+
+// readRequestBody drains and restores req.Body.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// RecordingTransport forwards requests to Next and writes every exchange to Dir
+// as a Fixture. Streaming responses are buffered in full before being returned.
+type RecordingTransport struct {
+	Dir  string
+	Next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.Next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	path, digest := fixtureFile(t.Dir, req.Method, req.URL.Path, body)
+	data, err := json.MarshalIndent(Fixture{
+		Method:        req.Method,
+		Path:          req.URL.Path,
+		RequestSHA256: digest,
+		Status:        resp.StatusCode,
+		ContentType:   resp.Header.Get("Content-Type"),
+		Body:          respBody,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(t.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create fixture dir: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write fixture: %w", err)
+	}
+	return resp, nil
+}
+
+// ReplayTransport serves responses from fixtures previously written by
+// RecordingTransport, without any network access.
+type ReplayTransport struct {
+	Dir string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	path, _ := fixtureFile(t.Dir, req.Method, req.URL.Path, body)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w for %s %s", ErrNoFixture, req.Method, req.URL.Path)
+		}
+		return nil, err
+	}
+
+	var f Fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %w", path, err)
+	}
+
+	header := http.Header{}
+	if f.ContentType != "" {
+		header.Set("Content-Type", f.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Status, http.StatusText(f.Status)),
+		StatusCode:    f.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(f.Body)),
+		ContentLength: int64(len(f.Body)),
+		Request:       req,
+	}, nil
+}
+
+// NewReplayBackend returns a backend that answers every call from the fixtures
+// in dir, for tests and offline development.
+func NewReplayBackend(dir string) *BackendClient {
+	return &BackendClient{
+		httpClient: &http.Client{Transport: &ReplayTransport{Dir: dir}, Timeout: 10 * time.Second},
+		endpoint:   "http://replay.invalid",
+		timeout:    10 * time.Second,
+	}
+}
@@ -0,0 +1,51 @@
+package backend
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/tts":
+			w.Header().Set("Content-Type", "audio/wav")
+			w.Write([]byte("recorded audio"))
+		case "/v1/references":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"success":true,"reference_ids":["voice"],"message":"ok"}`))
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+
+	recorder := NewBackendClient(&config.BackendConfig{URL: mockServer.URL, Timeout: 10 * time.Second, RecordDir: dir})
+	audio, _, err := recorder.TTS(context.Background(), &schema.ServeTTSRequest{Text: "Hello"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("recorded audio"), audio)
+	_, err = recorder.ListReferences(context.Background())
+	require.NoError(t, err)
+	mockServer.Close()
+
+	replay := NewReplayBackend(dir)
+	audio, _, err = replay.TTS(context.Background(), &schema.ServeTTSRequest{Text: "Hello"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("recorded audio"), audio)
+
+	refs, err := replay.ListReferences(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"voice"}, refs.ReferenceIDs)
+
+	_, _, err = replay.TTS(context.Background(), &schema.ServeTTSRequest{Text: "Not recorded"})
+	assert.ErrorContains(t, err, ErrNoFixture.Error())
+}
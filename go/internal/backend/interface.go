@@ -14,6 +14,7 @@ type Backend interface {
 	TTSStream(ctx context.Context, req *schema.ServeTTSRequest) (io.ReadCloser, error)
 	VQGANEncode(ctx context.Context, req *schema.ServeVQGANEncodeRequest) (*schema.ServeVQGANEncodeResponse, error)
 	VQGANDecode(ctx context.Context, req *schema.ServeVQGANDecodeRequest) (*schema.ServeVQGANDecodeResponse, error)
+	ASR(ctx context.Context, req *schema.ServeASRRequest) (*schema.ServeASRResponse, error)
 	AddReference(ctx context.Context, req *schema.AddReferenceRequest) (*schema.AddReferenceResponse, error)
 	ListReferences(ctx context.Context) (*schema.ListReferencesResponse, error)
 	DeleteReference(ctx context.Context, id string) (*schema.DeleteReferenceResponse, error)
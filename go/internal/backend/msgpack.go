@@ -1,7 +1,9 @@
 package backend
 
 import (
+	"bufio"
 	"errors"
+	"io"
 
 	"github.com/vmihailenco/msgpack/v5"
 
@@ -18,6 +20,23 @@ func DecodeMsgpack(data []byte, v interface{}) error {
 	return msgpack.Unmarshal(data, v)
 }
 
+// NewMsgpackReader returns a reader of the MessagePack encoding of v. v is
+// encoded on a separate goroutine as the reader is consumed, so the encoding is
+// never held in memory in full; v must not change until the reader is drained
+// or closed.
+func NewMsgpackReader(v interface{}) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		bw := bufio.NewWriterSize(pw, 32<<10)
+		err := msgpack.NewEncoder(bw).Encode(v)
+		if err == nil {
+			err = bw.Flush()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
 // EncodeTTSRequest encodes a TTS request ensuring defaults and validation are applied.
 func EncodeTTSRequest(req *schema.ServeTTSRequest) ([]byte, error) {
 	if req == nil {
@@ -28,5 +47,5 @@ func EncodeTTSRequest(req *schema.ServeTTSRequest) ([]byte, error) {
 		return nil, err
 	}
 
-	return EncodeMsgpack(req)
+	return EncodeMsgpack(req.Upstream())
 }
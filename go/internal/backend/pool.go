@@ -0,0 +1,200 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+// Balancing policies of a Pool.
+const (
+	BalanceRoundRobin       = "round_robin"
+	BalanceLeastConnections = "least_connections"
+)
+
+// ValidBalance reports whether policy names a balancing policy.
+func ValidBalance(policy string) bool {
+	return policy == BalanceRoundRobin || policy == BalanceLeastConnections
+}
+
+// Replica is one of the interchangeable backends of a Pool.
+type Replica struct {
+	// Name identifies the replica in errors and statistics, usually its URL.
+	Name    string
+	Backend Backend
+}
+
+// Pool spreads requests across replicas serving the same model. Round robin
+// takes them in turn; least connections picks the replica with the fewest
+// requests outstanding, counting streams until they are closed. Reference
+// changes are applied to every replica so a voice is usable wherever a
+// request lands.
+type Pool struct {
+	replicas   []*target
+	leastConns bool
+	next       atomic.Uint64
+}
+
+// Ensure Pool implements Backend.
+var _ Backend = (*Pool)(nil)
+
+// NewPool creates a pool of replicas balanced by policy, one of the Balance*
+// constants (default round robin).
+func NewPool(policy string, replicas ...Replica) (*Pool, error) {
+	if len(replicas) == 0 {
+		return nil, errors.New("pool needs at least one replica")
+	}
+	if policy != "" && !ValidBalance(policy) {
+		return nil, fmt.Errorf("unknown balancing policy %q", policy)
+	}
+
+	p := &Pool{leastConns: policy == BalanceLeastConnections}
+	for _, r := range replicas {
+		p.replicas = append(p.replicas, &target{route: Route{Name: r.Name, Backend: r.Backend}})
+	}
+	return p, nil
+}
+
+// pick returns the replica to serve the next request.
+func (p *Pool) pick() *target {
+	start := int(p.next.Add(1)-1) % len(p.replicas)
+	if !p.leastConns {
+		return p.replicas[start]
+	}
+
+	// Scan from the round-robin position so ties are spread evenly.
+	best := p.replicas[start]
+	for i := 1; i < len(p.replicas); i++ {
+		t := p.replicas[(start+i)%len(p.replicas)]
+		if t.inFlight.Load() < best.inFlight.Load() {
+			best = t
+		}
+	}
+	return best
+}
+
+// acquire picks a replica and counts a request outstanding on it until done
+// is called.
+func (p *Pool) acquire() (t *target, done func()) {
+	t = p.pick()
+	t.inFlight.Add(1)
+	return t, func() { t.inFlight.Add(-1) }
+}
+
+// Health reports the first unhealthy replica, if any.
+func (p *Pool) Health(ctx context.Context) error {
+	for _, t := range p.replicas {
+		if err := t.route.Backend.Health(ctx); err != nil {
+			return fmt.Errorf("replica %s: %w", t.route.Name, err)
+		}
+	}
+	return nil
+}
+
+// TTS synthesizes on the next replica.
+func (p *Pool) TTS(ctx context.Context, req *schema.ServeTTSRequest) ([]byte, string, error) {
+	t, done := p.acquire()
+	defer done()
+	return t.route.Backend.TTS(ctx, req)
+}
+
+// TTSStream streams from the next replica, which counts the stream as
+// outstanding until it is closed.
+func (p *Pool) TTSStream(ctx context.Context, req *schema.ServeTTSRequest) (io.ReadCloser, error) {
+	t := p.pick()
+	t.inFlight.Add(1)
+	stream, err := t.route.Backend.TTSStream(ctx, req)
+	if err != nil {
+		t.inFlight.Add(-1)
+		return nil, err
+	}
+	return &trackedStream{ReadCloser: stream, target: t}, nil
+}
+
+// VQGANEncode encodes on the next replica.
+func (p *Pool) VQGANEncode(ctx context.Context, req *schema.ServeVQGANEncodeRequest) (*schema.ServeVQGANEncodeResponse, error) {
+	t, done := p.acquire()
+	defer done()
+	return t.route.Backend.VQGANEncode(ctx, req)
+}
+
+// VQGANDecode decodes on the next replica.
+func (p *Pool) VQGANDecode(ctx context.Context, req *schema.ServeVQGANDecodeRequest) (*schema.ServeVQGANDecodeResponse, error) {
+	t, done := p.acquire()
+	defer done()
+	return t.route.Backend.VQGANDecode(ctx, req)
+}
+
+// ASR transcribes on the next replica.
+func (p *Pool) ASR(ctx context.Context, req *schema.ServeASRRequest) (*schema.ServeASRResponse, error) {
+	t, done := p.acquire()
+	defer done()
+	return t.route.Backend.ASR(ctx, req)
+}
+
+// AddReference adds the reference to every replica. The first replica's
+// response is returned.
+func (p *Pool) AddReference(ctx context.Context, req *schema.AddReferenceRequest) (*schema.AddReferenceResponse, error) {
+	var resp *schema.AddReferenceResponse
+	for i, t := range p.replicas {
+		r, err := t.route.Backend.AddReference(ctx, req)
+		if err != nil {
+			if i == 0 {
+				return nil, err
+			}
+			return nil, fmt.Errorf("replica %s: %w", t.route.Name, err)
+		}
+		if i == 0 {
+			resp = r
+		}
+	}
+	return resp, nil
+}
+
+// ListReferences lists the references of the first replica.
+func (p *Pool) ListReferences(ctx context.Context) (*schema.ListReferencesResponse, error) {
+	return p.replicas[0].route.Backend.ListReferences(ctx)
+}
+
+// DeleteReference deletes the reference from every replica. The first
+// replica's response is returned.
+func (p *Pool) DeleteReference(ctx context.Context, id string) (*schema.DeleteReferenceResponse, error) {
+	var resp *schema.DeleteReferenceResponse
+	for i, t := range p.replicas {
+		r, err := t.route.Backend.DeleteReference(ctx, id)
+		if err != nil {
+			if i == 0 {
+				return nil, err
+			}
+			return nil, fmt.Errorf("replica %s: %w", t.route.Name, err)
+		}
+		if i == 0 {
+			resp = r
+		}
+	}
+	return resp, nil
+}
+
+// OpenConnections merges the open connections of every replica.
+func (p *Pool) OpenConnections() map[string]int64 {
+	conns := make(map[string]int64, len(p.replicas))
+	for _, t := range p.replicas {
+		if reporter, ok := t.route.Backend.(ConnectionReporter); ok {
+			for url, n := range reporter.OpenConnections() {
+				conns[url] += n
+			}
+		}
+	}
+	return conns
+}
+
+// RuntimeStats collects the runtime statistics of every replica into an
+// object keyed by replica name, like Router.RuntimeStats.
+func (p *Pool) RuntimeStats(ctx context.Context) (json.RawMessage, error) {
+	return collectRuntimeStats(ctx, p.replicas)
+}
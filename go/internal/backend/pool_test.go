@@ -0,0 +1,101 @@
+package backend
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func TestPool_RoundRobin(t *testing.T) {
+	hits := map[string]int{}
+	first := newNamedServer(t, "first", hits)
+	second := newNamedServer(t, "second", hits)
+
+	b, err := New(&config.BackendConfig{
+		URL:     first.URL,
+		URLs:    []string{second.URL},
+		Timeout: 10 * time.Second,
+	})
+	require.NoError(t, err)
+	require.IsType(t, &Pool{}, b)
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		audio, _, err := b.TTS(context.Background(), &schema.ServeTTSRequest{Text: "Hello"})
+		require.NoError(t, err)
+		got = append(got, string(audio))
+	}
+	assert.Equal(t, []string{"first", "second", "first", "second"}, got)
+
+	_, err = b.AddReference(context.Background(), &schema.AddReferenceRequest{ID: "voice", Audio: []byte("a"), Text: "t"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, hits["first /v1/references/add"])
+	assert.Equal(t, 1, hits["second /v1/references/add"])
+
+	conns := b.(ConnectionReporter).OpenConnections()
+	assert.Contains(t, conns, first.URL)
+	assert.Contains(t, conns, second.URL)
+}
+
+func TestPool_LeastConnections(t *testing.T) {
+	hits := map[string]int{}
+	first := newNamedServer(t, "first", hits)
+	second := newNamedServer(t, "second", hits)
+
+	b, err := New(&config.BackendConfig{
+		URL:     first.URL,
+		URLs:    []string{second.URL},
+		Balance: BalanceLeastConnections,
+		Timeout: 10 * time.Second,
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// An open stream keeps its replica busy, so requests go to the other one
+	// until it is closed.
+	stream, err := b.TTSStream(ctx, &schema.ServeTTSRequest{Text: "one"})
+	require.NoError(t, err)
+	data, _ := io.ReadAll(stream)
+	busy := string(data)
+
+	for i := 0; i < 3; i++ {
+		audio, _, err := b.TTS(ctx, &schema.ServeTTSRequest{Text: "two"})
+		require.NoError(t, err)
+		assert.NotEqual(t, busy, string(audio))
+	}
+
+	require.NoError(t, stream.Close())
+	seen := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		audio, _, err := b.TTS(ctx, &schema.ServeTTSRequest{Text: "three"})
+		require.NoError(t, err)
+		seen[string(audio)] = true
+	}
+	assert.Len(t, seen, 2)
+}
+
+func TestNew_PoolBehindRouter(t *testing.T) {
+	b, err := New(&config.BackendConfig{
+		URL:   "http://127.0.0.1:1",
+		URLs:  []string{"http://127.0.0.1:2"},
+		Model: "fish-speech-1.5",
+	})
+	require.NoError(t, err)
+	router := b.(*Router)
+	assert.IsType(t, &Pool{}, router.fallback.route.Backend)
+}
+
+func TestNewPool_RejectsUnknownPolicy(t *testing.T) {
+	_, err := NewPool("random", Replica{Name: "a", Backend: NewReplayBackend(t.TempDir())})
+	assert.Error(t, err)
+
+	_, err = NewPool(BalanceRoundRobin)
+	assert.Error(t, err)
+}
@@ -0,0 +1,43 @@
+package backend
+
+import (
+	"context"
+	"net/http"
+)
+
+// RequestIDHeader carries the ID of a client request, both from clients and to
+// the backend, so backend logs can be matched with fish-server's.
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the ID of the client request
+// that backend calls made with it serve.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the ID set by WithRequestID, or "".
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestIDTransport sends the request ID of the request context to the
+// backend in X-Request-ID. Requests without one, such as health checks, are
+// sent as they are.
+type RequestIDTransport struct {
+	Next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RequestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id := RequestIDFromContext(req.Context())
+	if id == "" || req.Header.Get(RequestIDHeader) != "" {
+		return t.Next.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set(RequestIDHeader, id)
+	return t.Next.RoundTrip(req)
+}
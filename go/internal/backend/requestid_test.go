@@ -0,0 +1,30 @@
+package backend
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+func TestRequestIDTransport(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get(RequestIDHeader))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewBackendClient(&config.BackendConfig{URL: server.URL, Timeout: 5 * time.Second})
+
+	require.NoError(t, client.Health(context.Background()))
+	require.NoError(t, client.Health(WithRequestID(context.Background(), "req-123")))
+
+	assert.Equal(t, []string{"", "req-123"}, got)
+}
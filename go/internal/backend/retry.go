@@ -0,0 +1,119 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+// maxDrainBytes bounds how much of a failed response is read so its
+// connection can be reused by the retry.
+const maxDrainBytes = 64 << 10
+
+// RetryPolicy decides which failed backend calls are repeated and how long to
+// wait in between. Connection failures, such as refused or reset connections,
+// are retried, as are responses with one of StatusCodes. Timeouts are not: the
+// backend may still be working on the request.
+type RetryPolicy struct {
+	// MaxAttempts is the number of tries, including the first. Values below 2
+	// disable retries.
+	MaxAttempts int
+	// Backoff is the wait before the first retry. It doubles for each further
+	// retry up to MaxBackoff, with jitter.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// StatusCodes lists the backend response statuses worth retrying.
+	StatusCodes []int
+}
+
+func newRetryPolicy(cfg config.BackendRetryConfig) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: cfg.MaxAttempts,
+		Backoff:     cfg.Backoff,
+		MaxBackoff:  cfg.MaxBackoff,
+		StatusCodes: cfg.StatusCodes,
+	}
+}
+
+// retryable reports whether a call that returned resp and err is worth
+// repeating.
+func (p RetryPolicy) retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		var netErr net.Error
+		if ctx.Err() != nil || (errors.As(err, &netErr) && netErr.Timeout()) {
+			return false
+		}
+		return true
+	}
+	for _, code := range p.StatusCodes {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns the wait before retry number n, counting from 1: Backoff
+// doubled n-1 times and capped at MaxBackoff, less up to half for jitter so
+// that clients retrying together spread out.
+func (p RetryPolicy) delay(n int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < n && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// do sends req with client, repeating it as the retry policy allows. A request
+// with a body must set GetBody so the body can be sent again. The response or
+// error of the last attempt is returned; a retry is not started when the
+// context would expire during the backoff.
+func (c *BackendClient) do(client *http.Client, req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		resp, err := client.Do(req)
+		if attempt >= c.retry.MaxAttempts || !c.retry.retryable(ctx, resp, err) {
+			return resp, err
+		}
+		if req.Body != nil && req.GetBody == nil {
+			return resp, err
+		}
+		wait := c.retry.delay(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return resp, err
+		}
+
+		if resp != nil {
+			_, _ = io.CopyN(io.Discard, resp.Body, maxDrainBytes)
+			resp.Body.Close()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+	}
+}
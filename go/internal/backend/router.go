@@ -0,0 +1,459 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+	"github.com/fish-speech-go/fish-speech-go/internal/text"
+)
+
+// DefaultTargetName names the default backend in router statistics.
+const DefaultTargetName = "default"
+
+// ErrUnknownTarget is returned when a routing target name does not exist.
+var ErrUnknownTarget = errors.New("unknown backend target")
+
+// ErrBackendSaturated is returned when every backend that could serve a
+// request already has the maximum queue depth of requests outstanding.
+var ErrBackendSaturated = errors.New("backend queue is full")
+
+// SaturatedError is returned, wrapping ErrBackendSaturated, when the backend
+// that must serve a request is saturated. EstimatedWait is how soon one of its
+// outstanding requests is expected to finish, zero when it has none to go by.
+type SaturatedError struct {
+	Target        string
+	EstimatedWait time.Duration
+}
+
+func (e *SaturatedError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrBackendSaturated, e.Target)
+}
+
+func (e *SaturatedError) Unwrap() error {
+	return ErrBackendSaturated
+}
+
+// Route sends TTS requests matching its criteria to an alternate backend.
+type Route struct {
+	// Name identifies the route in errors and statistics, usually its backend URL.
+	Name string
+	// Models lists the model names served by this route.
+	Models []string
+	// Languages lists the language codes (e.g. "ja") served by this route.
+	Languages []string
+	// Weight is the percentage of default-backend traffic sent to this route
+	// instead, for canary rollouts.
+	Weight  int
+	Backend Backend
+}
+
+// UnknownModelError is returned when a request names a model no backend serves.
+type UnknownModelError struct {
+	Model string
+}
+
+func (e *UnknownModelError) Error() string {
+	return fmt.Sprintf("unknown model %q", e.Model)
+}
+
+// TargetStats summarizes the TTS traffic served by one routing target.
+type TargetStats struct {
+	Name     string
+	Weight   int
+	Requests uint64
+	Errors   uint64
+	// AvgLatency is the mean time until the backend responded.
+	AvgLatency time.Duration
+	// QueueDepth is the number of TTS requests sent to the backend that have
+	// not finished, including streams still being read. The backend works
+	// through them one batch at a time, so this is its queue as seen from here.
+	QueueDepth int64
+}
+
+// target is a backend plus its live routing weight and counters.
+type target struct {
+	route     Route
+	weight    atomic.Int32
+	requests  atomic.Uint64
+	errors    atomic.Uint64
+	latencyNs atomic.Int64
+	inFlight  atomic.Int64
+}
+
+func (t *target) observe(start time.Time, err error) {
+	t.requests.Add(1)
+	t.latencyNs.Add(int64(time.Since(start)))
+	if err != nil {
+		t.errors.Add(1)
+	}
+}
+
+func (t *target) stats() TargetStats {
+	s := TargetStats{
+		Name:       t.route.Name,
+		Weight:     int(t.weight.Load()),
+		Requests:   t.requests.Load(),
+		Errors:     t.errors.Load(),
+		QueueDepth: t.inFlight.Load(),
+	}
+	if s.Requests > 0 {
+		s.AvgLatency = time.Duration(t.latencyNs.Load() / int64(s.Requests))
+	}
+	return s
+}
+
+// estimatedWait is the mean interval between finished requests at the current
+// queue depth (Little's law): mean latency divided by requests in flight.
+func (t *target) estimatedWait() time.Duration {
+	requests, inFlight := t.requests.Load(), t.inFlight.Load()
+	if requests == 0 || inFlight == 0 {
+		return 0
+	}
+	return time.Duration(t.latencyNs.Load() / int64(requests) / inFlight)
+}
+
+// Router dispatches TTS requests across several backends. Requests that match
+// no route go to the default backend, except for the share diverted to weighted
+// canary routes. Reference changes are applied to every backend so a voice is
+// usable wherever a request is routed.
+type Router struct {
+	fallback      *target
+	fallbackModel string
+	routes        []*target
+	// maxQueueDepth, when positive, is the queue depth at which a target is
+	// skipped by the weighted split and requests bound to it are refused.
+	maxQueueDepth int64
+}
+
+// Ensure Router implements Backend.
+var _ Backend = (*Router)(nil)
+
+// NewRouter creates a router that uses fallback for unmatched requests.
+// fallbackModel names the model served by fallback, if any. Route weights must
+// add up to at most 100.
+func NewRouter(fallback Backend, fallbackModel string, routes ...Route) (*Router, error) {
+	r := &Router{
+		fallback:      &target{route: Route{Name: DefaultTargetName, Backend: fallback}},
+		fallbackModel: fallbackModel,
+	}
+
+	total := 0
+	for _, route := range routes {
+		if route.Weight < 0 || route.Weight > 100 {
+			return nil, fmt.Errorf("route %s: weight must be between 0 and 100", route.Name)
+		}
+		total += route.Weight
+
+		languages := make([]string, len(route.Languages))
+		for i, lang := range route.Languages {
+			languages[i] = baseLanguage(lang)
+		}
+		route.Languages = languages
+
+		t := &target{route: route}
+		t.weight.Store(int32(route.Weight))
+		r.routes = append(r.routes, t)
+	}
+	if total > 100 {
+		return nil, fmt.Errorf("route weights add up to %d, more than 100", total)
+	}
+	return r, nil
+}
+
+// New builds the backend described by cfg: a single client, a Pool when
+// replica URLs are configured, and a Router around either when a model name or
+// additional routes are configured. When ReplayDir is set, recorded fixtures
+// are served instead and no backend is contacted.
+func New(cfg *config.BackendConfig) (Backend, error) {
+	if cfg.ReplayDir != "" {
+		return NewReplayBackend(cfg.ReplayDir), nil
+	}
+
+	var client Backend = NewBackendClient(cfg)
+	if len(cfg.URLs) > 0 {
+		replicas := []Replica{{Name: cfg.URL, Backend: client}}
+		for _, url := range cfg.URLs {
+			replicaCfg := *cfg
+			replicaCfg.URL = url
+			replicas = append(replicas, Replica{Name: url, Backend: NewBackendClient(&replicaCfg)})
+		}
+		pool, err := NewPool(cfg.Balance, replicas...)
+		if err != nil {
+			return nil, err
+		}
+		client = pool
+	}
+	if len(cfg.Routes) == 0 && cfg.Model == "" && cfg.MaxQueueDepth == 0 {
+		return client, nil
+	}
+
+	routes := make([]Route, 0, len(cfg.Routes))
+	for _, rc := range cfg.Routes {
+		routeCfg := *cfg
+		routeCfg.URL = rc.URL
+		name := rc.Name
+		if name == "" {
+			name = rc.URL
+		}
+		routes = append(routes, Route{
+			Name:      name,
+			Models:    rc.Models,
+			Languages: rc.Languages,
+			Weight:    rc.Weight,
+			Backend:   NewBackendClient(&routeCfg),
+		})
+	}
+	router, err := NewRouter(client, cfg.Model, routes...)
+	if err != nil {
+		return nil, err
+	}
+	router.maxQueueDepth = int64(cfg.MaxQueueDepth)
+	return router, nil
+}
+
+// Select returns the backend that should serve req. A named model must be
+// served by some backend; otherwise routing falls back to the request language
+// and then to the weighted canary split.
+func (r *Router) Select(req *schema.ServeTTSRequest) (Backend, error) {
+	t, err := r.selectTarget(req)
+	if err != nil {
+		return nil, err
+	}
+	return t.route.Backend, nil
+}
+
+func (r *Router) selectTarget(req *schema.ServeTTSRequest) (*target, error) {
+	t, bound, err := r.matchTarget(req)
+	if err != nil || r.maxQueueDepth <= 0 || !r.saturated(t) {
+		return t, err
+	}
+	if bound {
+		return nil, &SaturatedError{Target: t.route.Name, EstimatedWait: t.estimatedWait()}
+	}
+
+	// Move weighted traffic to the least loaded target taking part in the split.
+	best := r.fallback
+	for _, c := range r.routes {
+		if c.weight.Load() > 0 && c.inFlight.Load() < best.inFlight.Load() {
+			best = c
+		}
+	}
+	if r.saturated(best) {
+		return nil, &SaturatedError{Target: best.route.Name, EstimatedWait: best.estimatedWait()}
+	}
+	return best, nil
+}
+
+func (r *Router) saturated(t *target) bool {
+	return r.maxQueueDepth > 0 && t.inFlight.Load() >= r.maxQueueDepth
+}
+
+// matchTarget applies the routing rules to req. bound reports whether the
+// target was chosen by model or language, so no other target can serve it.
+func (r *Router) matchTarget(req *schema.ServeTTSRequest) (t *target, bound bool, err error) {
+	if req.Model != "" {
+		if req.Model == r.fallbackModel {
+			return r.fallback, true, nil
+		}
+		for _, t := range r.routes {
+			for _, m := range t.route.Models {
+				if m == req.Model {
+					return t, true, nil
+				}
+			}
+		}
+		return nil, false, &UnknownModelError{Model: req.Model}
+	}
+
+	lang := req.Language
+	if lang == "" {
+		lang = text.DetectLanguage(req.Text)
+	}
+	lang = baseLanguage(lang)
+
+	if lang != "" {
+		for _, t := range r.routes {
+			for _, l := range t.route.Languages {
+				if l == lang {
+					return t, true, nil
+				}
+			}
+		}
+	}
+
+	n := int32(rand.Intn(100))
+	for _, t := range r.routes {
+		w := t.weight.Load()
+		if n < w {
+			return t, false, nil
+		}
+		n -= w
+	}
+	return r.fallback, false, nil
+}
+
+// Stats returns per-target traffic statistics, default backend first.
+func (r *Router) Stats() []TargetStats {
+	stats := []TargetStats{r.fallback.stats()}
+	for _, t := range r.routes {
+		stats = append(stats, t.stats())
+	}
+	return stats
+}
+
+// SetWeight changes the canary weight of the named route at runtime. Setting it
+// to zero stops diverting default traffic to the route.
+func (r *Router) SetWeight(name string, weight int) error {
+	if weight < 0 || weight > 100 {
+		return errors.New("weight must be between 0 and 100")
+	}
+
+	var found *target
+	total := weight
+	for _, t := range r.routes {
+		if t.route.Name == name {
+			found = t
+			continue
+		}
+		total += int(t.weight.Load())
+	}
+	if found == nil {
+		return ErrUnknownTarget
+	}
+	if total > 100 {
+		return fmt.Errorf("route weights would add up to %d, more than 100", total)
+	}
+
+	found.weight.Store(int32(weight))
+	return nil
+}
+
+// Health reports the first unhealthy backend, if any.
+func (r *Router) Health(ctx context.Context) error {
+	if err := r.fallback.route.Backend.Health(ctx); err != nil {
+		return err
+	}
+	for _, t := range r.routes {
+		if err := t.route.Backend.Health(ctx); err != nil {
+			return fmt.Errorf("route %s: %w", t.route.Name, err)
+		}
+	}
+	return nil
+}
+
+// TTS synthesizes on the backend selected for req.
+func (r *Router) TTS(ctx context.Context, req *schema.ServeTTSRequest) ([]byte, string, error) {
+	t, err := r.selectTarget(req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	t.inFlight.Add(1)
+	defer t.inFlight.Add(-1)
+
+	start := time.Now()
+	audio, format, err := t.route.Backend.TTS(ctx, req)
+	t.observe(start, err)
+	return audio, format, err
+}
+
+// TTSStream streams from the backend selected for req. Latency covers the time
+// until the backend starts responding.
+func (r *Router) TTSStream(ctx context.Context, req *schema.ServeTTSRequest) (io.ReadCloser, error) {
+	t, err := r.selectTarget(req)
+	if err != nil {
+		return nil, err
+	}
+
+	t.inFlight.Add(1)
+	start := time.Now()
+	stream, err := t.route.Backend.TTSStream(ctx, req)
+	t.observe(start, err)
+	if err != nil {
+		t.inFlight.Add(-1)
+		return nil, err
+	}
+	return &trackedStream{ReadCloser: stream, target: t}, nil
+}
+
+// trackedStream keeps a streaming request in its target's queue depth until
+// the stream is closed.
+type trackedStream struct {
+	io.ReadCloser
+	target *target
+	once   sync.Once
+}
+
+func (s *trackedStream) Close() error {
+	s.once.Do(func() { s.target.inFlight.Add(-1) })
+	return s.ReadCloser.Close()
+}
+
+// VQGANEncode uses the default backend.
+func (r *Router) VQGANEncode(ctx context.Context, req *schema.ServeVQGANEncodeRequest) (*schema.ServeVQGANEncodeResponse, error) {
+	return r.fallback.route.Backend.VQGANEncode(ctx, req)
+}
+
+// VQGANDecode uses the default backend.
+func (r *Router) VQGANDecode(ctx context.Context, req *schema.ServeVQGANDecodeRequest) (*schema.ServeVQGANDecodeResponse, error) {
+	return r.fallback.route.Backend.VQGANDecode(ctx, req)
+}
+
+// ASR uses the default backend.
+func (r *Router) ASR(ctx context.Context, req *schema.ServeASRRequest) (*schema.ServeASRResponse, error) {
+	return r.fallback.route.Backend.ASR(ctx, req)
+}
+
+// AddReference adds the reference to every backend. The default backend's
+// response is returned.
+func (r *Router) AddReference(ctx context.Context, req *schema.AddReferenceRequest) (*schema.AddReferenceResponse, error) {
+	resp, err := r.fallback.route.Backend.AddReference(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range r.routes {
+		if _, err := t.route.Backend.AddReference(ctx, req); err != nil {
+			return nil, fmt.Errorf("route %s: %w", t.route.Name, err)
+		}
+	}
+	return resp, nil
+}
+
+// ListReferences lists the references of the default backend.
+func (r *Router) ListReferences(ctx context.Context) (*schema.ListReferencesResponse, error) {
+	return r.fallback.route.Backend.ListReferences(ctx)
+}
+
+// DeleteReference deletes the reference from every backend. The default
+// backend's response is returned.
+func (r *Router) DeleteReference(ctx context.Context, id string) (*schema.DeleteReferenceResponse, error) {
+	resp, err := r.fallback.route.Backend.DeleteReference(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range r.routes {
+		if _, err := t.route.Backend.DeleteReference(ctx, id); err != nil {
+			return nil, fmt.Errorf("route %s: %w", t.route.Name, err)
+		}
+	}
+	return resp, nil
+}
+
+// baseLanguage lowercases a language tag and strips any region, so "ja-JP"
+// matches "ja".
+func baseLanguage(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	return tag
+}
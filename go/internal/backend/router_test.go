@@ -0,0 +1,181 @@
+package backend
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func newNamedServer(t *testing.T, name string, hits map[string]int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[name+" "+r.URL.Path]++
+		switch r.URL.Path {
+		case "/v1/tts":
+			w.Header().Set("Content-Type", "audio/wav")
+			w.Write([]byte(name))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"success":true,"message":"ok","reference_id":"voice"}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRouter_LanguageRouting(t *testing.T) {
+	hits := map[string]int{}
+	general := newNamedServer(t, "general", hits)
+	japanese := newNamedServer(t, "japanese", hits)
+
+	b, err := New(&config.BackendConfig{
+		URL:     general.URL,
+		Timeout: 10 * time.Second,
+		Routes:  []config.BackendRouteConfig{{URL: japanese.URL, Languages: []string{"ja-JP"}}},
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name string
+		req  schema.ServeTTSRequest
+		want string
+	}{
+		{name: "latin text", req: schema.ServeTTSRequest{Text: "Hello"}, want: "general"},
+		{name: "detected", req: schema.ServeTTSRequest{Text: "こんにちは"}, want: "japanese"},
+		{name: "declared", req: schema.ServeTTSRequest{Text: "konnichiwa", Language: "ja"}, want: "japanese"},
+		{name: "other language", req: schema.ServeTTSRequest{Text: "你好"}, want: "general"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			audio, _, err := b.TTS(context.Background(), &tc.req)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, string(audio))
+		})
+	}
+
+	_, err = b.AddReference(context.Background(), &schema.AddReferenceRequest{ID: "voice", Audio: []byte("a"), Text: "t"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, hits["general /v1/references/add"])
+	assert.Equal(t, 1, hits["japanese /v1/references/add"])
+}
+
+func TestRouter_ModelSelection(t *testing.T) {
+	hits := map[string]int{}
+	base := newNamedServer(t, "base", hits)
+	finetune := newNamedServer(t, "finetune", hits)
+
+	b, err := New(&config.BackendConfig{
+		URL:     base.URL,
+		Timeout: 10 * time.Second,
+		Model:   "fish-speech-1.5",
+		Routes:  []config.BackendRouteConfig{{URL: finetune.URL, Models: []string{"narrator"}}},
+	})
+	require.NoError(t, err)
+
+	audio, _, err := b.TTS(context.Background(), &schema.ServeTTSRequest{Text: "Hello", Model: "narrator"})
+	require.NoError(t, err)
+	assert.Equal(t, "finetune", string(audio))
+
+	audio, _, err = b.TTS(context.Background(), &schema.ServeTTSRequest{Text: "Hello", Model: "fish-speech-1.5"})
+	require.NoError(t, err)
+	assert.Equal(t, "base", string(audio))
+
+	_, _, err = b.TTS(context.Background(), &schema.ServeTTSRequest{Text: "Hello", Model: "missing"})
+	var modelErr *UnknownModelError
+	require.ErrorAs(t, err, &modelErr)
+	assert.Equal(t, "missing", modelErr.Model)
+}
+
+func TestRouter_CanaryWeight(t *testing.T) {
+	hits := map[string]int{}
+	stable := newNamedServer(t, "stable", hits)
+	canary := newNamedServer(t, "canary", hits)
+
+	b, err := New(&config.BackendConfig{
+		URL:     stable.URL,
+		Timeout: 10 * time.Second,
+		Routes:  []config.BackendRouteConfig{{Name: "canary", URL: canary.URL, Weight: 100}},
+	})
+	require.NoError(t, err)
+	router := b.(*Router)
+
+	audio, _, err := router.TTS(context.Background(), &schema.ServeTTSRequest{Text: "Hello"})
+	require.NoError(t, err)
+	assert.Equal(t, "canary", string(audio))
+
+	require.NoError(t, router.SetWeight("canary", 0))
+	audio, _, err = router.TTS(context.Background(), &schema.ServeTTSRequest{Text: "Hello"})
+	require.NoError(t, err)
+	assert.Equal(t, "stable", string(audio))
+
+	stats := router.Stats()
+	require.Len(t, stats, 2)
+	assert.Equal(t, DefaultTargetName, stats[0].Name)
+	assert.Equal(t, uint64(1), stats[0].Requests)
+	assert.Equal(t, "canary", stats[1].Name)
+	assert.Equal(t, 0, stats[1].Weight)
+	assert.Equal(t, uint64(1), stats[1].Requests)
+
+	assert.ErrorIs(t, router.SetWeight("missing", 5), ErrUnknownTarget)
+	assert.Error(t, router.SetWeight("canary", 101))
+}
+
+func TestNewRouter_RejectsExcessiveWeights(t *testing.T) {
+	_, err := NewRouter(nil, "", Route{Name: "a", Weight: 60}, Route{Name: "b", Weight: 50})
+	assert.Error(t, err)
+}
+
+func TestRouter_QueueDepth(t *testing.T) {
+	hits := map[string]int{}
+	general := newNamedServer(t, "general", hits)
+	canary := newNamedServer(t, "canary", hits)
+
+	b, err := New(&config.BackendConfig{
+		URL:           general.URL,
+		Timeout:       10 * time.Second,
+		MaxQueueDepth: 1,
+		Routes:        []config.BackendRouteConfig{{Name: "canary", URL: canary.URL, Weight: 100}},
+	})
+	require.NoError(t, err)
+	router := b.(*Router)
+	ctx := context.Background()
+
+	// Open streams count against the queue depth until they are closed.
+	first, err := router.TTSStream(ctx, &schema.ServeTTSRequest{Text: "one"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), router.Stats()[1].QueueDepth)
+
+	// The canary is full, so weighted traffic moves to the default backend.
+	second, err := router.TTSStream(ctx, &schema.ServeTTSRequest{Text: "two"})
+	require.NoError(t, err)
+	data, _ := io.ReadAll(second)
+	assert.Equal(t, "general", string(data))
+
+	_, _, err = router.TTS(ctx, &schema.ServeTTSRequest{Text: "three"})
+	assert.ErrorIs(t, err, ErrBackendSaturated)
+	var saturated *SaturatedError
+	assert.ErrorAs(t, err, &saturated)
+
+	require.NoError(t, first.Close())
+	require.NoError(t, second.Close())
+	assert.Zero(t, router.Stats()[0].QueueDepth)
+	audio, _, err := router.TTS(ctx, &schema.ServeTTSRequest{Text: "four"})
+	require.NoError(t, err)
+	assert.Equal(t, "canary", string(audio))
+}
+
+func TestNew_QueueDepthWrapsSingleBackend(t *testing.T) {
+	b, err := New(&config.BackendConfig{URL: "http://127.0.0.1:1", MaxQueueDepth: 4})
+	require.NoError(t, err)
+	assert.IsType(t, &Router{}, b)
+}
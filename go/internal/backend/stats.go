@@ -0,0 +1,91 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrStatsUnavailable indicates the backend does not expose runtime statistics.
+var ErrStatsUnavailable = errors.New("backend does not report runtime stats")
+
+// maxStatsBytes bounds a backend's runtime statistics document.
+const maxStatsBytes = 1 << 20
+
+// RuntimeStatsReporter is implemented by backends that can report their own
+// runtime statistics, such as GPU memory, loaded models and internal queue
+// length. The document is passed through to clients as is.
+type RuntimeStatsReporter interface {
+	RuntimeStats(ctx context.Context) (json.RawMessage, error)
+}
+
+var (
+	_ RuntimeStatsReporter = (*BackendClient)(nil)
+	_ RuntimeStatsReporter = (*Router)(nil)
+	_ RuntimeStatsReporter = (*Pool)(nil)
+)
+
+// RuntimeStats fetches the JSON document served at /v1/stats by the backend.
+// It returns ErrStatsUnavailable when the backend has no such endpoint.
+func (c *BackendClient) RuntimeStats(ctx context.Context) (json.RawMessage, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+"/v1/stats", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBackendUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+		return nil, ErrStatsUnavailable
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxStatsBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &BackendError{StatusCode: resp.StatusCode, Message: string(body)}
+	}
+	if !json.Valid(body) {
+		return nil, errors.New("backend stats are not valid JSON")
+	}
+	return body, nil
+}
+
+// RuntimeStats collects the runtime statistics of every target into an object
+// keyed by target name. Targets that fail report {"error": "..."} instead.
+func (r *Router) RuntimeStats(ctx context.Context) (json.RawMessage, error) {
+	return collectRuntimeStats(ctx, append([]*target{r.fallback}, r.routes...))
+}
+
+// collectRuntimeStats returns the runtime statistics of targets keyed by name,
+// or ErrStatsUnavailable when none of them reports any.
+func collectRuntimeStats(ctx context.Context, targets []*target) (json.RawMessage, error) {
+	stats := make(map[string]json.RawMessage, len(targets))
+	available := false
+	for _, t := range targets {
+		reporter, ok := t.route.Backend.(RuntimeStatsReporter)
+		if !ok {
+			continue
+		}
+		doc, err := reporter.RuntimeStats(ctx)
+		if errors.Is(err, ErrStatsUnavailable) {
+			continue
+		}
+		available = true
+		if err != nil {
+			doc, _ = json.Marshal(map[string]string{"error": err.Error()})
+		}
+		stats[t.route.Name] = doc
+	}
+	if !available {
+		return nil, ErrStatsUnavailable
+	}
+	return json.Marshal(stats)
+}
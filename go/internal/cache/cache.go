@@ -0,0 +1,473 @@
+// Package cache keeps synthesized TTS audio so that repeated requests are
+// answered without calling the backend.
+package cache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+// Entry is a cached TTS response. Entries held by the disk tier have Path
+// set to the audio file instead of Audio.
+type Entry struct {
+	Audio  []byte
+	Path   string
+	Format string
+	// SHA256 is the hex digest of the audio.
+	SHA256 string
+	// ReferenceID and TextHash identify the entry for targeted invalidation.
+	ReferenceID string
+	TextHash    string
+	CreatedAt   time.Time
+}
+
+// Eviction reasons passed to Config.OnEvict.
+const (
+	EvictEntries = "entries"
+	EvictBytes   = "bytes"
+	EvictExpired = "expired"
+	// EvictDiskBytes entries were removed from the disk tier to stay within
+	// its size budget.
+	EvictDiskBytes = "disk_bytes"
+	// EvictDiskError entries could not be written to the disk tier.
+	EvictDiskError = "disk_error"
+)
+
+// Config configures a Cache.
+type Config struct {
+	// TTL is how long entries are kept (0 keeps them until evicted).
+	TTL time.Duration
+	// MaxEntries and MaxBytes bound the cache; the least recently used
+	// entries are evicted to stay within them, or moved to Disk when it is
+	// set. 0 disables each bound.
+	MaxEntries int
+	MaxBytes   int64
+	// Disk, if set, is a second tier for entries evicted from memory and for
+	// entries larger than SpillBytes (0 keeps every entry in memory first).
+	Disk       *Disk
+	SpillBytes int64
+	// OnEvict, if set, is called with the reason and audio size of every
+	// entry that leaves the cache. Invalidated entries, and entries moved to
+	// the disk tier, are not reported.
+	OnEvict func(reason string, bytes int)
+	// Remote, if set, is a tier shared with other servers. It is consulted
+	// when the local tiers miss, receives every stored entry, and carries
+	// invalidations to the other servers.
+	Remote Remote
+}
+
+// Remote is a cache tier shared by several servers, such as Redis. Its errors
+// are counted and otherwise treated as misses, so the local tiers keep
+// working while it is unreachable.
+type Remote interface {
+	// Get returns the entry stored under key, or nil when there is none.
+	Get(key string) (*Entry, error)
+	// Set stores entry under key for ttl (0 keeps it until the remote
+	// evicts it).
+	Set(key string, entry *Entry, ttl time.Duration) error
+	// Invalidate removes the matching entries and asks every server to
+	// remove them from its local tiers with ApplyInvalidation. It returns
+	// how many shared entries were removed.
+	Invalidate(inv Invalidation) (int, error)
+}
+
+// Invalidation selects the entries to remove: those synthesized with
+// ReferenceID, or those whose text has TextHash. When both are empty, every
+// entry is removed.
+type Invalidation struct {
+	ReferenceID string `json:"reference_id,omitempty"`
+	TextHash    string `json:"text_hash,omitempty"`
+}
+
+func (inv Invalidation) matches(e *Entry) bool {
+	switch {
+	case inv.ReferenceID != "":
+		return e.ReferenceID == inv.ReferenceID
+	case inv.TextHash != "":
+		return e.TextHash == inv.TextHash
+	}
+	return true
+}
+
+// Stats reports cache usage since the cache was created.
+type Stats struct {
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+	Entries   int   `json:"entries"`
+	Bytes     int64 `json:"bytes"`
+	// DiskEntries and DiskBytes describe the disk tier, when there is one.
+	DiskEntries int   `json:"disk_entries"`
+	DiskBytes   int64 `json:"disk_bytes"`
+	// RemoteHits counts the hits served by the remote tier, and
+	// RemoteErrors the calls to it that failed.
+	RemoteHits   int64 `json:"remote_hits,omitempty"`
+	RemoteErrors int64 `json:"remote_errors,omitempty"`
+}
+
+// HitRate returns the fraction of lookups that were hits, or 0 before any lookup.
+func (s Stats) HitRate() float64 {
+	if total := s.Hits + s.Misses; total > 0 {
+		return float64(s.Hits) / float64(total)
+	}
+	return 0
+}
+
+// Cache is a least recently used map from request keys to entries. It is
+// safe for concurrent use.
+type Cache struct {
+	cfg Config
+
+	mu sync.Mutex
+	// lru holds *item, most recently used first.
+	lru       *list.List
+	entries   map[string]*list.Element
+	bytes     int64
+	hits      int64
+	misses    int64
+	evictions int64
+	// remoteHits and remoteErrors are updated atomically, as the remote tier
+	// is called without mu held.
+	remoteHits   atomic.Int64
+	remoteErrors atomic.Int64
+	// generation counts invalidations, so that an entry moved to the disk
+	// tier after being invalidated is discarded.
+	generation uint64
+}
+
+type item struct {
+	key   string
+	entry *Entry
+}
+
+// New creates an empty cache.
+func New(cfg Config) *Cache {
+	return &Cache{cfg: cfg, lru: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// Key returns the cache key of a TTS request, a hash of its normalized form.
+// Two requests share a key only when every field that affects the audio is
+// equal. Text is compared as NormalizeText leaves it; streaming and the
+// backend's own memory cache hint are ignored. It fails only when the
+// request cannot be encoded, and then the response should not be cached.
+func Key(req *schema.ServeTTSRequest) (string, error) {
+	normalized := *req
+	normalized.Text = NormalizeText(normalized.Text)
+	normalized.Streaming = false
+	normalized.UseMemoryCache = ""
+	data, err := msgpack.Marshal(&normalized)
+	if err != nil {
+		return "", fmt.Errorf("encoding cache key: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// NormalizeText returns text as cache keys compare it: with line endings
+// unified and surrounding whitespace removed.
+func NormalizeText(text string) string {
+	return strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+}
+
+// TextHash returns the hash entries are tagged with for text invalidation,
+// that of the text normalized as in Key, so that every request sharing an
+// entry has the same hash.
+func TextHash(text string) string {
+	sum := sha256.Sum256([]byte(NormalizeText(text)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns the entry stored under key and records a hit or miss. Entries
+// found only in the remote tier are copied to the local tiers.
+func (c *Cache) Get(key string) (*Entry, bool) {
+	if entry, ok := c.getLocal(key); ok {
+		return entry, true
+	}
+	if c.cfg.Remote != nil {
+		entry, err := c.cfg.Remote.Get(key)
+		if err != nil {
+			c.remoteErrors.Add(1)
+		} else if entry != nil && !c.expired(entry) {
+			c.remoteHits.Add(1)
+			c.mu.Lock()
+			c.hits++
+			c.mu.Unlock()
+			c.setLocal(key, entry)
+			return entry, true
+		}
+	}
+	c.mu.Lock()
+	c.misses++
+	c.mu.Unlock()
+	return nil, false
+}
+
+// getLocal looks key up in memory and on disk, recording a hit when found.
+func (c *Cache) getLocal(key string) (*Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		if !c.expired(elem.Value.(*item).entry) {
+			c.hits++
+			c.lru.MoveToFront(elem)
+			return elem.Value.(*item).entry, true
+		}
+		c.evict(elem, EvictExpired)
+	}
+	if c.cfg.Disk != nil {
+		if entry, ok := c.cfg.Disk.get(key); ok {
+			if !c.expired(entry) {
+				c.hits++
+				return entry, true
+			}
+			c.evicted(EvictExpired, c.cfg.Disk.delete(key))
+		}
+	}
+	return nil, false
+}
+
+// Set stores entry under key, replacing any previous entry, and evicts the
+// least recently used entries while the cache exceeds its bounds. Entries
+// larger than MaxBytes are not kept in memory. The entry is also stored in
+// the remote tier, if any.
+func (c *Cache) Set(key string, entry *Entry) {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	c.setLocal(key, entry)
+	if c.cfg.Remote != nil && entry.Path == "" {
+		ttl := time.Duration(0)
+		if c.cfg.TTL > 0 {
+			ttl = c.cfg.TTL - time.Since(entry.CreatedAt)
+			if ttl <= 0 {
+				return
+			}
+		}
+		if err := c.cfg.Remote.Set(key, entry, ttl); err != nil {
+			c.remoteErrors.Add(1)
+		}
+	}
+}
+
+// setLocal stores entry in memory, or on disk when it is too large.
+func (c *Cache) setLocal(key string, entry *Entry) {
+	size := int64(len(entry.Audio))
+	toDisk := c.cfg.Disk != nil && c.cfg.SpillBytes > 0 && size > c.cfg.SpillBytes
+	if !toDisk && c.cfg.MaxBytes > 0 && size > c.cfg.MaxBytes {
+		if c.cfg.Disk == nil {
+			return
+		}
+		toDisk = true
+	}
+
+	c.mu.Lock()
+	if old, ok := c.entries[key]; ok {
+		c.remove(old)
+	}
+	if c.cfg.Disk != nil {
+		// A stale copy on disk would otherwise outlive this entry.
+		c.cfg.Disk.delete(key)
+	}
+	generation := c.generation
+	if toDisk {
+		c.mu.Unlock()
+		c.spill(generation, &item{key: key, entry: entry})
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&item{key: key, entry: entry})
+	c.bytes += size
+
+	var spilled []*item
+	for c.cfg.MaxEntries > 0 && c.lru.Len() > c.cfg.MaxEntries {
+		spilled = c.evictOrSpill(spilled, EvictEntries)
+	}
+	for c.cfg.MaxBytes > 0 && c.bytes > c.cfg.MaxBytes {
+		spilled = c.evictOrSpill(spilled, EvictBytes)
+	}
+	c.mu.Unlock()
+
+	for _, it := range spilled {
+		c.spill(generation, it)
+	}
+}
+
+// evictOrSpill removes the least recently used entry, queueing it for the
+// disk tier when there is one.
+func (c *Cache) evictOrSpill(spilled []*item, reason string) []*item {
+	elem := c.lru.Back()
+	if c.cfg.Disk == nil || !c.cfg.Disk.fits(int64(len(elem.Value.(*item).entry.Audio))) {
+		c.evict(elem, reason)
+		return spilled
+	}
+	return append(spilled, c.remove(elem))
+}
+
+// spill moves an entry to the disk tier unless the cache was invalidated
+// since generation. It is called without c.mu held, as it writes a file.
+func (c *Cache) spill(generation uint64, it *item) {
+	size := int64(len(it.entry.Audio))
+	if !c.cfg.Disk.fits(size) {
+		return
+	}
+	path, err := c.cfg.Disk.write(it.key, it.entry)
+	if err != nil {
+		c.mu.Lock()
+		c.evicted(EvictDiskError, size)
+		c.mu.Unlock()
+		return
+	}
+	onDisk := *it.entry
+	onDisk.Audio = nil
+	onDisk.Path = path
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation != generation {
+		removeFiles(path)
+		return
+	}
+	for _, removed := range c.cfg.Disk.add(it.key, &onDisk, size) {
+		c.evicted(EvictDiskBytes, removed)
+	}
+}
+
+// InvalidateReference removes the entries synthesized with a reference ID and
+// returns how many were removed.
+func (c *Cache) InvalidateReference(id string) int {
+	return c.Invalidate(Invalidation{ReferenceID: id})
+}
+
+// InvalidateText removes the entries whose text has the given TextHash and
+// returns how many were removed.
+func (c *Cache) InvalidateText(hash string) int {
+	return c.Invalidate(Invalidation{TextHash: hash})
+}
+
+// Flush removes every entry and returns how many were removed.
+func (c *Cache) Flush() int {
+	return c.Invalidate(Invalidation{})
+}
+
+// Invalidate removes the entries selected by inv from every tier and returns
+// how many were removed. Local entries are mostly copies of remote ones, so
+// the larger of the two counts is returned rather than their sum.
+func (c *Cache) Invalidate(inv Invalidation) int {
+	removed := c.ApplyInvalidation(inv)
+	if c.cfg.Remote != nil {
+		n, err := c.cfg.Remote.Invalidate(inv)
+		if err != nil {
+			c.remoteErrors.Add(1)
+		}
+		removed = max(removed, n)
+	}
+	return removed
+}
+
+// ApplyInvalidation removes the entries selected by inv from the local tiers
+// only, as asked by another server through the remote tier.
+func (c *Cache) ApplyInvalidation(inv Invalidation) int {
+	return c.removeMatching(inv.matches)
+}
+
+// Persist moves the entries held in memory to the disk tier, so that a
+// restart finds them there. It does nothing without a disk tier, and is meant
+// to be called at shutdown.
+func (c *Cache) Persist() {
+	if c.cfg.Disk == nil {
+		return
+	}
+	c.mu.Lock()
+	generation := c.generation
+	var moved []*item
+	// Least recently used first, so that the disk tier keeps the order.
+	for elem := c.lru.Back(); elem != nil; elem = c.lru.Back() {
+		it := c.remove(elem)
+		if !c.expired(it.entry) {
+			moved = append(moved, it)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, it := range moved {
+		c.spill(generation, it)
+	}
+}
+
+// Stats returns the current usage. Expired entries still count until they
+// are looked up.
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := Stats{
+		Hits:         c.hits,
+		Misses:       c.misses,
+		Evictions:    c.evictions,
+		Entries:      len(c.entries),
+		Bytes:        c.bytes,
+		RemoteHits:   c.remoteHits.Load(),
+		RemoteErrors: c.remoteErrors.Load(),
+	}
+	if c.cfg.Disk != nil {
+		stats.DiskEntries, stats.DiskBytes = c.cfg.Disk.stats()
+	}
+	return stats
+}
+
+func (c *Cache) removeMatching(match func(*Entry) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	removed := 0
+	if c.cfg.Disk != nil {
+		removed = c.cfg.Disk.removeMatching(match)
+	}
+	for elem := c.lru.Front(); elem != nil; {
+		next := elem.Next()
+		if match(elem.Value.(*item).entry) {
+			c.remove(elem)
+			removed++
+		}
+		elem = next
+	}
+	return removed
+}
+
+func (c *Cache) evict(elem *list.Element, reason string) {
+	it := c.remove(elem)
+	c.evicted(reason, int64(len(it.entry.Audio)))
+}
+
+// evicted records that an entry of size bytes left the cache; a negative size
+// means there was no entry.
+func (c *Cache) evicted(reason string, size int64) {
+	if size < 0 {
+		return
+	}
+	c.evictions++
+	if c.cfg.OnEvict != nil {
+		c.cfg.OnEvict(reason, int(size))
+	}
+}
+
+func (c *Cache) remove(elem *list.Element) *item {
+	it := c.lru.Remove(elem).(*item)
+	delete(c.entries, it.key)
+	c.bytes -= int64(len(it.entry.Audio))
+	return it
+}
+
+func (c *Cache) expired(entry *Entry) bool {
+	return c.cfg.TTL > 0 && time.Since(entry.CreatedAt) > c.cfg.TTL
+}
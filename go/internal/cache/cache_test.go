@@ -0,0 +1,267 @@
+package cache
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+// key returns the cache key of req.
+func key(t *testing.T, req *schema.ServeTTSRequest) string {
+	t.Helper()
+	k, err := Key(req)
+	require.NoError(t, err)
+	return k
+}
+
+func TestKey(t *testing.T) {
+	seed := 1
+	a := &schema.ServeTTSRequest{Text: "hello", Format: "wav", Seed: &seed}
+	b := *a
+	b.Streaming = true
+	assert.Equal(t, key(t, a), key(t, &b))
+
+	c := *a
+	c.GainDB = 3
+	assert.NotEqual(t, key(t, a), key(t, &c))
+
+	other := 2
+	d := *a
+	d.Seed = &other
+	assert.NotEqual(t, key(t, a), key(t, &d))
+
+	e := *a
+	e.Text = " hello\r\n"
+	e.UseMemoryCache = "on"
+	assert.Equal(t, key(t, a), key(t, &e))
+
+	f := *a
+	f.Text = "hello."
+	assert.NotEqual(t, key(t, a), key(t, &f))
+
+	assert.Equal(t, TextHash("hello"), TextHash(e.Text), "requests sharing a key share a text hash")
+}
+
+func TestCache_GetSet(t *testing.T) {
+	c := New(Config{})
+	_, ok := c.Get("k")
+	assert.False(t, ok)
+
+	c.Set("k", &Entry{Audio: []byte("audio"), Format: "wav"})
+	entry, ok := c.Get("k")
+	assert.True(t, ok)
+	assert.Equal(t, "audio", string(entry.Audio))
+
+	c.Set("k", &Entry{Audio: []byte("longer audio")})
+	assert.Equal(t, Stats{Hits: 1, Misses: 1, Entries: 1, Bytes: 12}, c.Stats())
+	assert.Equal(t, 0.5, c.Stats().HitRate())
+}
+
+func TestCache_TTL(t *testing.T) {
+	c := New(Config{TTL: time.Minute})
+	c.Set("old", &Entry{Audio: []byte("a"), CreatedAt: time.Now().Add(-2 * time.Minute)})
+	c.Set("new", &Entry{Audio: []byte("b")})
+
+	_, ok := c.Get("old")
+	assert.False(t, ok)
+	_, ok = c.Get("new")
+	assert.True(t, ok)
+	assert.Equal(t, Stats{Hits: 1, Misses: 1, Evictions: 1, Entries: 1, Bytes: 1}, c.Stats())
+}
+
+func TestCache_LRU(t *testing.T) {
+	evicted := map[string]int{}
+	c := New(Config{MaxEntries: 3, MaxBytes: 9, OnEvict: func(reason string, bytes int) {
+		evicted[reason] += bytes
+	}})
+	c.Set("a", &Entry{Audio: []byte("aa")})
+	c.Set("b", &Entry{Audio: []byte("bb")})
+	c.Set("c", &Entry{Audio: []byte("cc")})
+	c.Get("a")
+
+	// "b" is the least recently used entry.
+	c.Set("d", &Entry{Audio: []byte("dd")})
+	_, ok := c.Get("b")
+	assert.False(t, ok)
+	assert.Equal(t, map[string]int{EvictEntries: 2}, evicted)
+
+	// "e" exceeds MaxEntries, evicting "c", and then MaxBytes, evicting "a".
+	c.Set("e", &Entry{Audio: []byte("eeeeee")})
+	for key, want := range map[string]bool{"a": false, "c": false, "d": true, "e": true} {
+		_, ok := c.Get(key)
+		assert.Equal(t, want, ok, key)
+	}
+	assert.Equal(t, map[string]int{EvictEntries: 4, EvictBytes: 2}, evicted)
+	assert.Equal(t, int64(8), c.Stats().Bytes)
+	assert.Equal(t, int64(3), c.Stats().Evictions)
+
+	// An entry larger than the cache is not stored.
+	c.Set("f", &Entry{Audio: make([]byte, 10)})
+	assert.Equal(t, 2, c.Stats().Entries)
+}
+
+func TestCache_Invalidate(t *testing.T) {
+	c := New(Config{})
+	c.Set("1", &Entry{Audio: []byte("a"), ReferenceID: "voice", TextHash: TextHash("hello")})
+	c.Set("2", &Entry{Audio: []byte("b"), ReferenceID: "voice", TextHash: TextHash("bye")})
+	c.Set("3", &Entry{Audio: []byte("c"), ReferenceID: "other", TextHash: TextHash("hello")})
+
+	assert.Equal(t, 0, c.InvalidateReference("missing"))
+	assert.Equal(t, 2, c.InvalidateReference("voice"))
+	assert.Equal(t, 1, c.InvalidateText(TextHash("hello")))
+	assert.Equal(t, Stats{}, c.Stats())
+
+	c.Set("4", &Entry{Audio: []byte("d")})
+	assert.Equal(t, 1, c.Flush())
+	assert.Equal(t, 0, c.Stats().Entries)
+}
+
+func TestCache_Disk(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "old"+diskExt), []byte("stale"), 0o600))
+	disk, err := OpenDisk(dir, 10)
+	require.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(dir, "old"+diskExt))
+
+	evicted := map[string]int{}
+	c := New(Config{MaxEntries: 1, Disk: disk, SpillBytes: 4, OnEvict: func(reason string, bytes int) {
+		evicted[reason] += bytes
+	}})
+
+	// Larger than SpillBytes: written straight to disk.
+	c.Set("big", &Entry{Audio: []byte("bigger"), ReferenceID: "voice"})
+	entry, ok := c.Get("big")
+	require.True(t, ok)
+	assert.Nil(t, entry.Audio)
+	data, err := os.ReadFile(entry.Path)
+	require.NoError(t, err)
+	assert.Equal(t, "bigger", string(data))
+
+	// Evicted from memory: moved to disk.
+	c.Set("a", &Entry{Audio: []byte("aa")})
+	c.Set("b", &Entry{Audio: []byte("bb")})
+	entry, ok = c.Get("a")
+	require.True(t, ok)
+	assert.NotEmpty(t, entry.Path)
+	assert.Equal(t, Stats{Hits: 2, Entries: 1, Bytes: 2, DiskEntries: 2, DiskBytes: 8}, c.Stats())
+	assert.Empty(t, evicted)
+
+	// Over the disk budget: the least recently used file is removed.
+	c.Set("c", &Entry{Audio: []byte("cc")})
+	c.Set("d", &Entry{Audio: []byte("dd")})
+	_, ok = c.Get("big")
+	assert.False(t, ok)
+	assert.Equal(t, map[string]int{EvictDiskBytes: 6}, evicted)
+
+	assert.Equal(t, 4, c.Flush())
+	files, _ := os.ReadDir(dir)
+	assert.Empty(t, files)
+}
+
+func TestCache_DiskRestart(t *testing.T) {
+	dir := t.TempDir()
+	disk, err := OpenDisk(dir, 0)
+	require.NoError(t, err)
+	c := New(Config{MaxEntries: 1, Disk: disk})
+	c.Set("a", &Entry{Audio: []byte("aa"), Format: "wav", ReferenceID: "voice"})
+	c.Set("b", &Entry{Audio: []byte("bb")})
+	c.Set("c", &Entry{Audio: []byte("cc")})
+	c.Persist()
+	assert.Equal(t, Stats{DiskEntries: 3, DiskBytes: 6}, c.Stats())
+
+	// Leftovers of writes interrupted by a crash.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "partial"+diskExt), []byte("p"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "x"+diskExt+".tmp123"), []byte("x"), 0o600))
+
+	// The file times record the last use.
+	lastUse := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "a"+diskExt), lastUse, lastUse))
+	disk, err = OpenDisk(dir, 4)
+	require.NoError(t, err)
+	c = New(Config{Disk: disk})
+	assert.Equal(t, Stats{DiskEntries: 2, DiskBytes: 4}, c.Stats())
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	entry, ok := c.Get("c")
+	require.True(t, ok)
+	data, err := os.ReadFile(entry.Path)
+	require.NoError(t, err)
+	assert.Equal(t, "cc", string(data))
+	assert.NoFileExists(t, filepath.Join(dir, "partial"+diskExt))
+	assert.NoFileExists(t, filepath.Join(dir, "x"+diskExt+".tmp123"))
+
+	assert.Equal(t, 2, c.Flush())
+	files, _ := os.ReadDir(dir)
+	assert.Empty(t, files)
+}
+
+// fakeRemote is an in-memory Remote shared by the caches of a test.
+type fakeRemote struct {
+	entries map[string]*Entry
+	fail    bool
+}
+
+func (r *fakeRemote) Get(key string) (*Entry, error) {
+	if r.fail {
+		return nil, errors.New("unreachable")
+	}
+	return r.entries[key], nil
+}
+
+func (r *fakeRemote) Set(key string, entry *Entry, ttl time.Duration) error {
+	if r.fail {
+		return errors.New("unreachable")
+	}
+	r.entries[key] = entry
+	return nil
+}
+
+func (r *fakeRemote) Invalidate(inv Invalidation) (int, error) {
+	if r.fail {
+		return 0, errors.New("unreachable")
+	}
+	removed := 0
+	for key, entry := range r.entries {
+		if inv.matches(entry) {
+			delete(r.entries, key)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func TestCache_Remote(t *testing.T) {
+	remote := &fakeRemote{entries: make(map[string]*Entry)}
+	a := New(Config{Remote: remote})
+	b := New(Config{Remote: remote})
+
+	a.Set("k", &Entry{Audio: []byte("audio"), ReferenceID: "voice"})
+	entry, ok := b.Get("k")
+	require.True(t, ok, "entries set on one server are found by the others")
+	assert.Equal(t, "audio", string(entry.Audio))
+	assert.Equal(t, int64(1), b.Stats().RemoteHits)
+	assert.Equal(t, 1, b.Stats().Entries, "remote hits are copied locally")
+
+	assert.Equal(t, 1, a.InvalidateReference("voice"))
+	assert.Empty(t, remote.entries)
+	assert.Equal(t, 1, b.ApplyInvalidation(Invalidation{ReferenceID: "voice"}))
+	_, ok = b.Get("k")
+	assert.False(t, ok)
+
+	remote.fail = true
+	a.Set("k2", &Entry{Audio: []byte("local")})
+	entry, ok = a.Get("k2")
+	require.True(t, ok, "remote errors fall back to the local tiers")
+	assert.Equal(t, "local", string(entry.Audio))
+	_, ok = b.Get("k2")
+	assert.False(t, ok)
+	assert.Equal(t, int64(1), a.Stats().RemoteErrors)
+	assert.Equal(t, int64(1), b.Stats().RemoteErrors)
+}
@@ -0,0 +1,261 @@
+package cache
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// diskExt marks the audio files a Disk tier owns in its directory, and
+// metaExt the file beside each that holds the rest of its entry.
+const (
+	diskExt = ".audio"
+	metaExt = ".meta"
+)
+
+// Disk is a cache tier that keeps entries as files in a local directory,
+// removing the least recently used files to stay within a size budget. Its
+// entries have Path set instead of Audio. It is safe for concurrent use.
+type Disk struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+	bytes   int64
+}
+
+type diskItem struct {
+	key   string
+	entry *Entry
+	size  int64
+}
+
+// OpenDisk creates a disk tier in dir holding at most maxBytes of audio (0 is
+// unlimited). Entries left in dir by a previous run are loaded, ordered by
+// when they were last used; files of incomplete entries are removed, and so
+// are the least recently used entries beyond maxBytes.
+func OpenDisk(dir string, maxBytes int64) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create cache directory: %w", err)
+	}
+	d := &Disk{dir: dir, maxBytes: maxBytes, lru: list.New(), entries: make(map[string]*list.Element)}
+	if err := d.scan(); err != nil {
+		return nil, fmt.Errorf("scan cache directory: %w", err)
+	}
+	return d, nil
+}
+
+// found is an entry read back from the directory.
+type found struct {
+	item    *diskItem
+	lastUse time.Time
+}
+
+// scan indexes the entries in the directory.
+func (d *Disk) scan() error {
+	files, err := os.ReadDir(d.dir)
+	if err != nil {
+		return err
+	}
+	var entries []found
+	for _, file := range files {
+		name := file.Name()
+		path := filepath.Join(d.dir, name)
+		switch {
+		case strings.HasSuffix(name, diskExt):
+			key := strings.TrimSuffix(name, diskExt)
+			info, err := file.Info()
+			if err != nil {
+				return err
+			}
+			entry, err := readMeta(d.metaPath(key))
+			if err != nil {
+				// Written without its metadata, or the metadata is corrupt.
+				removeFiles(path)
+				continue
+			}
+			entry.Path = path
+			entries = append(entries, found{item: &diskItem{key: key, entry: entry, size: info.Size()}, lastUse: info.ModTime()})
+		case strings.HasSuffix(name, metaExt):
+			if _, err := os.Stat(strings.TrimSuffix(path, metaExt) + diskExt); os.IsNotExist(err) {
+				os.Remove(path)
+			}
+		case strings.Contains(name, diskExt+".tmp") || strings.Contains(name, metaExt+".tmp"):
+			// Left by a write that did not finish.
+			os.Remove(path)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].lastUse.Before(entries[j].lastUse) })
+	for _, e := range entries {
+		d.entries[e.item.key] = d.lru.PushFront(e.item)
+		d.bytes += e.item.size
+	}
+	for d.maxBytes > 0 && d.bytes > d.maxBytes {
+		d.remove(d.lru.Back())
+	}
+	return nil
+}
+
+func readMeta(path string) (*Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entry Entry
+	if err := msgpack.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (d *Disk) metaPath(key string) string {
+	return filepath.Join(d.dir, key+metaExt)
+}
+
+// removeFiles deletes the audio file at path and its metadata.
+func removeFiles(path string) {
+	os.Remove(path)
+	os.Remove(strings.TrimSuffix(path, diskExt) + metaExt)
+}
+
+// fits reports whether size bytes of audio can be stored at all.
+func (d *Disk) fits(size int64) bool {
+	return d.maxBytes == 0 || size <= d.maxBytes
+}
+
+// write stores the audio of entry in a file for key and the rest of it in a
+// metadata file, replacing both atomically, and returns the audio path. The
+// metadata is written last, so an audio file without it is incomplete. The
+// files are not indexed until add is called.
+func (d *Disk) write(key string, entry *Entry) (string, error) {
+	meta := *entry
+	meta.Audio = nil
+	meta.Path = ""
+	data, err := msgpack.Marshal(&meta)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(d.dir, key+diskExt)
+	if err := writeFile(path, entry.Audio); err != nil {
+		return "", err
+	}
+	if err := writeFile(d.metaPath(key), data); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// writeFile replaces the file at path with data atomically.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// add indexes a written entry of size bytes and returns the sizes of the
+// entries removed to stay within the budget.
+func (d *Disk) add(key string, entry *Entry, size int64) []int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if old, ok := d.entries[key]; ok {
+		// The file was already replaced by write.
+		d.unindex(old)
+	}
+	d.entries[key] = d.lru.PushFront(&diskItem{key: key, entry: entry, size: size})
+	d.bytes += size
+
+	var removed []int64
+	for d.maxBytes > 0 && d.bytes > d.maxBytes {
+		removed = append(removed, d.remove(d.lru.Back()).size)
+	}
+	return removed
+}
+
+// get returns the entry under key and marks it used. Its file's modification
+// time records the use, so that the order survives a restart.
+func (d *Disk) get(key string) (*Entry, bool) {
+	d.mu.Lock()
+	elem, ok := d.entries[key]
+	if !ok {
+		d.mu.Unlock()
+		return nil, false
+	}
+	d.lru.MoveToFront(elem)
+	entry := elem.Value.(*diskItem).entry
+	d.mu.Unlock()
+
+	now := time.Now()
+	os.Chtimes(entry.Path, now, now)
+	return entry, true
+}
+
+// delete removes the entry under key and returns its size, or -1 when there
+// is none.
+func (d *Disk) delete(key string) int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if elem, ok := d.entries[key]; ok {
+		return d.remove(elem).size
+	}
+	return -1
+}
+
+func (d *Disk) removeMatching(match func(*Entry) bool) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	removed := 0
+	for elem := d.lru.Front(); elem != nil; {
+		next := elem.Next()
+		if match(elem.Value.(*diskItem).entry) {
+			d.remove(elem)
+			removed++
+		}
+		elem = next
+	}
+	return removed
+}
+
+func (d *Disk) stats() (entries int, bytes int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.entries), d.bytes
+}
+
+// remove unindexes an entry and deletes its file. Readers that already
+// opened the file can finish reading it.
+func (d *Disk) remove(elem *list.Element) *diskItem {
+	it := d.unindex(elem)
+	removeFiles(it.entry.Path)
+	return it
+}
+
+func (d *Disk) unindex(elem *list.Element) *diskItem {
+	it := d.lru.Remove(elem).(*diskItem)
+	delete(d.entries, it.key)
+	d.bytes -= it.size
+	return it
+}
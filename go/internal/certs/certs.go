@@ -0,0 +1,168 @@
+// Package certs serves the server's TLS certificate and client CAs from files
+// that are reloaded when they change, so certificates can be rotated without
+// a restart.
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+// Client authentication modes of config.ServerConfig.TLSClientAuth.
+const (
+	ClientAuthRequire  = "require"
+	ClientAuthOptional = "optional"
+)
+
+// Reloader holds the TLS settings built from the configured certificate, key
+// and client CA files. It is safe for concurrent use.
+type Reloader struct {
+	certFile   string
+	keyFile    string
+	caFile     string
+	clientAuth tls.ClientAuthType
+
+	mu      sync.Mutex
+	stamp   string
+	current atomic.Pointer[tls.Config]
+}
+
+// New loads the certificate, key and client CAs configured in cfg.
+func New(cfg config.ServerConfig) (*Reloader, error) {
+	r := &Reloader{certFile: cfg.TLSCertFile, keyFile: cfg.TLSKeyFile, caFile: cfg.TLSClientCAFile}
+	switch cfg.TLSClientAuth {
+	case "", ClientAuthRequire:
+		r.clientAuth = tls.RequireAndVerifyClientCert
+	case ClientAuthOptional:
+		r.clientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return nil, fmt.Errorf("unknown client auth mode %q (want require or optional)", cfg.TLSClientAuth)
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// TLSConfig returns the server TLS config. Every handshake uses the files as
+// last loaded.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &r.current.Load().Certificates[0], nil
+		},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current.Load(), nil
+		},
+	}
+}
+
+// Reload loads the files again. On error the previous settings stay in use.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reloadLocked()
+}
+
+// reloadIfChanged reloads the files when any of them changed since the last
+// load, reporting whether it did.
+func (r *Reloader) reloadIfChanged() (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stamp, err := r.fileStamp()
+	if err != nil {
+		return false, err
+	}
+	if stamp == r.stamp {
+		return false, nil
+	}
+	return true, r.reloadLocked()
+}
+
+func (r *Reloader) reloadLocked() error {
+	stamp, err := r.fileStamp()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		// Handshakes use this config in place of the server's, so it must
+		// offer HTTP/2 itself.
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.New("client CA file holds no PEM certificates")
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = r.clientAuth
+	}
+
+	r.current.Store(cfg)
+	r.stamp = stamp
+	return nil
+}
+
+// fileStamp returns a stamp that changes whenever any of the files is
+// modified.
+func (r *Reloader) fileStamp() (string, error) {
+	var stamp strings.Builder
+	for _, path := range []string{r.certFile, r.keyFile, r.caFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&stamp, "%s:%d:%d;", path, info.Size(), info.ModTime().UnixNano())
+	}
+	return stamp.String(), nil
+}
+
+// Run checks the files for changes every interval (0 = never) and reloads
+// them whenever reload receives, until ctx is done. onReload is told the
+// outcome of every reload; checks that find nothing changed are not reported.
+func (r *Reloader) Run(ctx context.Context, interval time.Duration, reload <-chan os.Signal, onReload func(error)) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+			if changed, err := r.reloadIfChanged(); changed || err != nil {
+				onReload(err)
+			}
+		case <-reload:
+			onReload(r.Reload())
+		}
+	}
+}
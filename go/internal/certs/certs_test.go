@@ -0,0 +1,211 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pair tls.Certificate
+}
+
+// issue returns a certificate for name signed by parent, or self-signed when
+// parent is nil.
+func issue(t *testing.T, name string, serial int64, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCert{cert: cert, key: key, pair: tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}}
+}
+
+// write stores c as PEM files cert.pem and key.pem in dir, dated modTime.
+func (c *testCert) write(t *testing.T, dir string, modTime time.Time) {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+}
+
+func serverConfig(dir string) config.ServerConfig {
+	return config.ServerConfig{TLSCertFile: filepath.Join(dir, "cert.pem"), TLSKeyFile: filepath.Join(dir, "key.pem")}
+}
+
+func servedSerial(t *testing.T, r *Reloader) int64 {
+	t.Helper()
+	cfg, err := r.TLSConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(cfg.Certificates[0].Certificate[0])
+	require.NoError(t, err)
+	return cert.SerialNumber.Int64()
+}
+
+func TestReloader_ReloadsChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Minute)
+	issue(t, "server", 1, nil).write(t, dir, start)
+
+	r, err := New(serverConfig(dir))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), servedSerial(t, r))
+
+	changed, err := r.reloadIfChanged()
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	issue(t, "server", 2, nil).write(t, dir, start.Add(time.Second))
+	changed, err = r.reloadIfChanged()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, int64(2), servedSerial(t, r))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "key.pem"), []byte("garbage"), 0o600))
+	_, err = r.reloadIfChanged()
+	assert.Error(t, err)
+	assert.Equal(t, int64(2), servedSerial(t, r), "the previous certificate stays in use")
+}
+
+func TestReloader_RunReloadsOnSignal(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	issue(t, "server", 1, nil).write(t, dir, now)
+	r, err := New(serverConfig(dir))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hup := make(chan os.Signal)
+	reloads := make(chan error)
+	go r.Run(ctx, 0, hup, func(err error) { reloads <- err })
+
+	// Same modification time: only a forced reload picks it up.
+	issue(t, "server", 2, nil).write(t, dir, now)
+	hup <- syscall.SIGHUP
+	require.NoError(t, <-reloads)
+	assert.Equal(t, int64(2), servedSerial(t, r))
+}
+
+func TestReloader_ClientCA(t *testing.T) {
+	dir := t.TempDir()
+	ca := issue(t, "ca", 1, nil)
+	issue(t, "server", 2, ca).write(t, dir, time.Now())
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), 0o600))
+	client := issue(t, "client", 3, ca)
+	stranger := issue(t, "stranger", 4, issue(t, "other-ca", 5, nil))
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	get := func(url string, cert *testCert) (*http.Response, error) {
+		tlsConfig := &tls.Config{RootCAs: roots}
+		if cert != nil {
+			// Present the certificate even when its CA is not one the server
+			// asks for.
+			tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+				return &cert.pair, nil
+			}
+		}
+		c := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig, ForceAttemptHTTP2: true}}
+		resp, err := c.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return resp, err
+	}
+
+	for _, tc := range []struct {
+		mode     string
+		accepted map[string]*testCert
+		rejected map[string]*testCert
+	}{
+		{ClientAuthRequire, map[string]*testCert{"client": client}, map[string]*testCert{"none": nil, "stranger": stranger}},
+		{ClientAuthOptional, map[string]*testCert{"client": client, "none": nil}, map[string]*testCert{"stranger": stranger}},
+	} {
+		t.Run(tc.mode, func(t *testing.T) {
+			cfg := serverConfig(dir)
+			cfg.TLSClientCAFile = caFile
+			cfg.TLSClientAuth = tc.mode
+			r, err := New(cfg)
+			require.NoError(t, err)
+
+			srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+			srv.EnableHTTP2 = true
+			srv.TLS = r.TLSConfig()
+			srv.StartTLS()
+			defer srv.Close()
+
+			for name, cert := range tc.accepted {
+				resp, err := get(srv.URL, cert)
+				if assert.NoError(t, err, name) {
+					assert.Equal(t, "HTTP/2.0", resp.Proto, name)
+				}
+			}
+			for name, cert := range tc.rejected {
+				_, err := get(srv.URL, cert)
+				assert.Error(t, err, name)
+			}
+		})
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	dir := t.TempDir()
+	_, err := New(serverConfig(dir))
+	assert.Error(t, err, "missing files")
+
+	issue(t, "server", 1, nil).write(t, dir, time.Now())
+	cfg := serverConfig(dir)
+	cfg.TLSClientAuth = "sometimes"
+	_, err = New(cfg)
+	assert.Error(t, err)
+
+	cfg = serverConfig(dir)
+	cfg.TLSClientCAFile = filepath.Join(dir, "key.pem")
+	_, err = New(cfg)
+	assert.Error(t, err, "CA file without certificates")
+}
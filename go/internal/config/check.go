@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// fractionKeys are settings that must lie between 0 and 1.
+var fractionKeys = map[string]bool{
+	"limits.bulk_shed_ratio":          true,
+	"jobs.bulk_queue_ratio":           true,
+	"references.duplicate_threshold":  true,
+	"references.transcript_threshold": true,
+	"tracing.sample_ratio":            true,
+	"chaos.latency_rate":              true,
+	"chaos.error_rate":                true,
+	"chaos.drop_rate":                 true,
+}
+
+// CheckRanges returns an error for every numeric setting outside its range:
+// counts, sizes and durations must not be negative, and rates and thresholds
+// must lie between 0 and 1.
+func (c *Config) CheckRanges() []error {
+	var errs []error
+	walk(reflect.ValueOf(*c), "", func(key string, v reflect.Value) {
+		var n float64
+		switch v.Kind() {
+		case reflect.Int, reflect.Int32, reflect.Int64:
+			n = float64(v.Int())
+		case reflect.Float32, reflect.Float64:
+			n = v.Float()
+		default:
+			return
+		}
+		switch {
+		case n < 0:
+			errs = append(errs, fmt.Errorf("%s: must not be negative, got %v", key, dumpValue(key, v)))
+		case fractionKeys[key] && n > 1:
+			errs = append(errs, fmt.Errorf("%s: must be between 0 and 1, got %v", key, n))
+		}
+	})
+	if s := c.Chaos.ErrorStatus; s != 0 && (s < 400 || s > 599) {
+		errs = append(errs, fmt.Errorf("chaos.error_status: must be an HTTP error status (400-599), got %d", s))
+	}
+	return errs
+}
+
+// UnknownKeys returns the dotted keys of settings, as read from a config file,
+// that name no setting of Config, sorted. Entries of lists of structs are
+// checked too, as in auth.keys[0].nmae.
+func UnknownKeys(settings map[string]interface{}) []string {
+	var unknown []string
+	unknownKeys(reflect.TypeOf(Config{}), settings, "", &unknown)
+	sort.Strings(unknown)
+	return unknown
+}
+
+func unknownKeys(t reflect.Type, settings map[string]interface{}, prefix string, unknown *[]string) {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if name := t.Field(i).Tag.Get("mapstructure"); name != "" && name != "-" {
+			fields[name] = t.Field(i).Type
+		}
+	}
+
+	for name, value := range settings {
+		key := prefix + name
+		ft, ok := fields[name]
+		if !ok {
+			*unknown = append(*unknown, key)
+			continue
+		}
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		switch {
+		case ft.Kind() == reflect.Struct:
+			if m, ok := value.(map[string]interface{}); ok {
+				unknownKeys(ft, m, key+".", unknown)
+			}
+		case ft.Kind() == reflect.Slice && ft.Elem().Kind() == reflect.Struct:
+			items, _ := value.([]interface{})
+			for i, item := range items {
+				if m, ok := item.(map[string]interface{}); ok {
+					unknownKeys(ft.Elem(), m, fmt.Sprintf("%s[%d].", key, i), unknown)
+				}
+			}
+		}
+	}
+}
@@ -3,49 +3,662 @@ package config
 import (
 	"encoding/json"
 	"os"
+	"path/filepath"
 	"strconv"
 	"time"
 )
 
 // Config holds all configuration for the application.
 type Config struct {
-	Server  ServerConfig  `mapstructure:"server"`
-	Backend BackendConfig `mapstructure:"backend"`
-	Auth    AuthConfig    `mapstructure:"auth"`
-	Limits  LimitsConfig  `mapstructure:"limits"`
-	Logging LoggingConfig `mapstructure:"logging"`
+	Server     ServerConfig     `mapstructure:"server"`
+	Backend    BackendConfig    `mapstructure:"backend"`
+	Auth       AuthConfig       `mapstructure:"auth"`
+	Limits     LimitsConfig     `mapstructure:"limits"`
+	Audio      AudioConfig      `mapstructure:"audio"`
+	References ReferencesConfig `mapstructure:"references"`
+	TTS        TTSConfig        `mapstructure:"tts"`
+	Cache      CacheConfig      `mapstructure:"cache"`
+	Jobs       JobsConfig       `mapstructure:"jobs"`
+	Redis      RedisConfig      `mapstructure:"redis"`
+	Logging    LoggingConfig    `mapstructure:"logging"`
+	Tracing    TracingConfig    `mapstructure:"tracing"`
+	Chaos      ChaosConfig      `mapstructure:"chaos"`
+	Docs       DocsConfig       `mapstructure:"docs"`
+	Secrets    SecretsConfig    `mapstructure:"secrets"`
+
+	// Sources records where each setting (by dotted key) was set; see Dump.
+	Sources map[string]Source `mapstructure:"-"`
 }
 
 // ServerConfig holds HTTP server settings.
 type ServerConfig struct {
+	// Listen is a TCP host:port, or unix:///path/to.sock for a Unix domain
+	// socket.
 	Listen       string        `mapstructure:"listen"`
 	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
 	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+	// StreamIdleTimeout replaces WriteTimeout for streaming TTS: the stream is
+	// aborted only when no audio is produced or delivered for this long.
+	StreamIdleTimeout time.Duration `mapstructure:"stream_idle_timeout"`
+	// StreamKeepAlive, when set, writes short silent WAV frames into a streaming
+	// response whenever the backend has sent nothing for this long (0 = off).
+	StreamKeepAlive time.Duration `mapstructure:"stream_keepalive"`
+	CORS            CORSConfig    `mapstructure:"cors"`
+	// SocketMode sets the permissions of a Unix domain socket listener, in
+	// octal (e.g. "0660"); empty leaves them to the umask.
+	SocketMode string `mapstructure:"socket_mode"`
+	// TLSCertFile and TLSKeyFile, when set, serve HTTPS instead of plain HTTP.
+	TLSCertFile string `mapstructure:"tls_cert_file"`
+	TLSKeyFile  string `mapstructure:"tls_key_file"`
+	// TLSClientCAFile enables mutual TLS: clients must present a certificate
+	// signed by one of its CAs, or with TLSClientAuth "optional" may present
+	// none ("require" by default).
+	TLSClientCAFile string `mapstructure:"tls_client_ca_file"`
+	TLSClientAuth   string `mapstructure:"tls_client_auth"`
+	// TLSReloadInterval is how often the TLS files are checked for changes
+	// (0 = only on SIGHUP).
+	TLSReloadInterval time.Duration `mapstructure:"tls_reload_interval"`
+	HTTP3             HTTP3Config   `mapstructure:"http3"`
+}
+
+// TLSEnabled reports whether a certificate and key are configured.
+func (c ServerConfig) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// HTTP3Config holds settings for the optional HTTP/3 (QUIC) listener. It
+// requires TLS and is advertised to TCP clients with Alt-Svc.
+type HTTP3Config struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Listen is the UDP address to listen on; defaults to the server listen address.
+	Listen string `mapstructure:"listen"`
+}
+
+// CORSConfig holds cross-origin settings.
+type CORSConfig struct {
+	// MaxAge lets browsers cache preflight responses for this long (0 = no header).
+	MaxAge time.Duration `mapstructure:"max_age"`
+	// AllowPrivateNetwork answers Private Network Access preflights, letting public
+	// pages call a server on a private address.
+	AllowPrivateNetwork bool `mapstructure:"allow_private_network"`
 }
 
 // BackendConfig holds Python backend settings.
 type BackendConfig struct {
-	URL            string        `mapstructure:"url"`
+	URL string `mapstructure:"url"`
+	// URLs lists further replicas of the backend at URL. Requests for the
+	// default backend are balanced across URL and these.
+	URLs []string `mapstructure:"urls"`
+	// Balance is the policy spreading requests across replicas: round_robin
+	// or least_connections.
+	Balance        string        `mapstructure:"balance"`
 	Timeout        time.Duration `mapstructure:"timeout"`
 	MaxConnections int           `mapstructure:"max_connections"`
+	// Model names the checkpoint served by URL, selectable with the request's model field.
+	Model string `mapstructure:"model"`
+	// RecordDir, when set, saves every backend request/response pair there as a fixture.
+	RecordDir string `mapstructure:"record_dir"`
+	// ReplayDir, when set, serves backend calls from recorded fixtures instead of URL.
+	ReplayDir string `mapstructure:"replay_dir"`
+	// Routes send matching TTS requests to additional backends.
+	Routes []BackendRouteConfig `mapstructure:"routes"`
+	// MaxQueueDepth, when positive, caps the TTS requests outstanding at each
+	// backend. Weighted traffic moves to the least loaded backend and requests
+	// that only one saturated backend can serve are refused.
+	MaxQueueDepth int `mapstructure:"max_queue_depth"`
+	// StatsCacheTTL is how long the backend's runtime stats are cached for
+	// detailed health and /stats.
+	StatsCacheTTL time.Duration `mapstructure:"stats_cache_ttl"`
+	// Retry repeats backend calls that fail transiently.
+	Retry BackendRetryConfig `mapstructure:"retry"`
+}
+
+// BackendRetryConfig controls how failed backend calls are retried.
+type BackendRetryConfig struct {
+	// MaxAttempts is the number of tries per call, including the first; 1
+	// disables retries.
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// Backoff is the wait before the first retry, doubled for each further
+	// retry up to MaxBackoff.
+	Backoff    time.Duration `mapstructure:"backoff"`
+	MaxBackoff time.Duration `mapstructure:"max_backoff"`
+	// StatusCodes lists the backend response statuses that are retried.
+	// Connection failures are always retried.
+	StatusCodes []int `mapstructure:"status_codes"`
+}
+
+// BackendRouteConfig maps TTS requests to an alternate backend URL.
+type BackendRouteConfig struct {
+	// Name identifies the route in the admin API; defaults to URL.
+	Name string `mapstructure:"name"`
+	URL  string `mapstructure:"url"`
+	// Models lists the model names served by this backend.
+	Models []string `mapstructure:"models"`
+	// Languages lists the declared or detected languages served by this backend.
+	Languages []string `mapstructure:"languages"`
+	// Weight is the percentage of default-backend traffic diverted here (canary).
+	Weight int `mapstructure:"weight"`
 }
 
 // AuthConfig holds authentication settings.
 type AuthConfig struct {
 	APIKey string `mapstructure:"api_key"`
+	// APIKeyHash is an alternative to APIKey holding only a salted hash of the
+	// key, as printed by "fish-server hash-key".
+	APIKeyHash string         `mapstructure:"api_key_hash"`
+	Keys       []APIKeyConfig `mapstructure:"keys"`
+	// APIKeyFile is read for a key with the same privileges as APIKey, so the key
+	// can be mounted as a secret instead of set in the environment.
+	APIKeyFile string `mapstructure:"api_key_file"`
+	// KeyDir holds one regular key per file; hidden entries are ignored.
+	KeyDir string `mapstructure:"key_dir"`
+	// KeysFile is a YAML file whose "keys" list has the same form as Keys,
+	// so per-key policies can be managed outside the main config.
+	KeysFile string `mapstructure:"keys_file"`
+	// ReloadInterval is how often APIKeyFile, KeyDir and KeysFile are checked for changes.
+	ReloadInterval time.Duration `mapstructure:"reload_interval"`
+	// APIKeySecret and KeySecrets name keys held in the secret manager (see
+	// SecretsConfig), with the privileges of APIKey and of a regular key.
+	APIKeySecret string   `mapstructure:"api_key_secret"`
+	KeySecrets   []string `mapstructure:"key_secrets"`
+	// UsageFile persists the characters and audio seconds used per key, so
+	// daily and monthly usage quotas survive restarts. Empty keeps usage in
+	// memory.
+	UsageFile string `mapstructure:"usage_file"`
+	// Basic accepts HTTP Basic credentials in addition to bearer keys.
+	Basic BasicAuthConfig `mapstructure:"basic"`
+	// Introspection accepts opaque OAuth2 tokens validated by an authorization
+	// server, in addition to API keys.
+	Introspection IntrospectionConfig `mapstructure:"introspection"`
+	// JWT accepts JWT access tokens issued by an OpenID Connect provider.
+	JWT JWTConfig `mapstructure:"jwt"`
+	// Signing accepts requests signed with a shared HMAC secret.
+	Signing SigningConfig `mapstructure:"signing"`
+}
+
+// SigningConfig holds the shared secrets for HMAC request signing.
+type SigningConfig struct {
+	Keys []SigningKeyConfig `mapstructure:"keys"`
+	// Window is how far X-Timestamp may be from the server clock. A signature
+	// is accepted once within it.
+	Window time.Duration `mapstructure:"window"`
+	// MaxBodyBytes caps the body of a signed request, which is held in memory
+	// until its signature is checked.
+	MaxBodyBytes int64 `mapstructure:"max_body_bytes"`
+}
+
+// SigningKeyConfig is a signing secret with the namespace and role its
+// requests are made as, like APIKeyConfig. When ID is the name of an entry in
+// AuthConfig.Keys, requests signed with it are held to that key's limits.
+type SigningKeyConfig struct {
+	// ID names the caller in logs.
+	ID        string `mapstructure:"id"`
+	Secret    string `mapstructure:"secret"`
+	Namespace string `mapstructure:"namespace"`
+	Role      string `mapstructure:"role"`
+}
+
+// IntrospectionConfig validates bearer tokens against an RFC 7662 token
+// introspection endpoint.
+type IntrospectionConfig struct {
+	URL string `mapstructure:"url"`
+	// ClientID and ClientSecret authenticate this server to the endpoint with
+	// HTTP Basic auth.
+	ClientID     string `mapstructure:"client_id"`
+	ClientSecret string `mapstructure:"client_secret"`
+	// CacheTTL is how long a result is reused; active tokens are never cached
+	// past their expiry.
+	CacheTTL time.Duration `mapstructure:"cache_ttl"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+	// Scopes maps token scopes to the routes they allow. When empty, any active
+	// token may call every route.
+	Scopes []ScopeConfig `mapstructure:"scopes"`
+	// AdminScope grants the admin role.
+	AdminScope string `mapstructure:"admin_scope"`
+}
+
+// JWTConfig validates bearer tokens that are JWTs signed by an identity
+// provider, mapping their claims to a principal.
+type JWTConfig struct {
+	// Issuer is the required iss claim. Unless JWKSURL is set, the signing
+	// keys are found through the issuer's /.well-known/openid-configuration.
+	Issuer string `mapstructure:"issuer"`
+	// Audience is the required aud claim; empty accepts any audience.
+	Audience string `mapstructure:"audience"`
+	JWKSURL  string `mapstructure:"jwks_url"`
+	// RefreshInterval is how often the signing keys are fetched again. A
+	// token signed with an unknown key triggers an earlier fetch.
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"`
+	Timeout         time.Duration `mapstructure:"timeout"`
+	// Leeway allows for clock skew when checking exp and nbf.
+	Leeway time.Duration `mapstructure:"leeway"`
+	// UsernameClaim names the caller in logs and usage (default "sub").
+	// NamespaceClaim, when set, scopes the caller's references to the value of
+	// that claim, such as a tenant ID; tokens without it are rejected.
+	UsernameClaim  string `mapstructure:"username_claim"`
+	NamespaceClaim string `mapstructure:"namespace_claim"`
+	// ScopeClaim holds the token's scopes as a space-separated string or a
+	// list (default "scope"). Scopes and AdminScope work as for introspection.
+	ScopeClaim string        `mapstructure:"scope_claim"`
+	Scopes     []ScopeConfig `mapstructure:"scopes"`
+	AdminScope string        `mapstructure:"admin_scope"`
+}
+
+// Enabled reports whether JWT validation is configured.
+func (c JWTConfig) Enabled() bool {
+	return c.Issuer != "" || c.JWKSURL != ""
+}
+
+// ScopeConfig lists the path prefixes a token scope allows.
+type ScopeConfig struct {
+	Scope  string   `mapstructure:"scope"`
+	Routes []string `mapstructure:"routes"`
+}
+
+// BasicAuthConfig holds HTTP Basic credentials for clients that cannot send
+// bearer tokens.
+type BasicAuthConfig struct {
+	Users []BasicUserConfig `mapstructure:"users"`
+	// HtpasswdFile holds further users as bcrypt ($2y$) or {SHA} entries. It is
+	// reloaded on change like the key files.
+	HtpasswdFile string `mapstructure:"htpasswd_file"`
+	// Realm is sent in WWW-Authenticate so browsers prompt for credentials.
+	Realm string `mapstructure:"realm"`
+}
+
+// Enabled reports whether any Basic credentials are configured.
+func (c BasicAuthConfig) Enabled() bool {
+	return len(c.Users) > 0 || c.HtpasswdFile != ""
+}
+
+// BasicUserConfig is a Basic auth user with the namespace and role its
+// requests are made as, like APIKeyConfig.
+type BasicUserConfig struct {
+	Username string `mapstructure:"username"`
+	// Password is the plain password, or a bcrypt hash starting with "$2".
+	Password  string `mapstructure:"password"`
+	Namespace string `mapstructure:"namespace"`
+	Role      string `mapstructure:"role"`
+}
+
+// APIKeyConfig describes an additional API key, the reference namespace it is
+// scoped to, and its role ("admin" or empty for a regular key).
+type APIKeyConfig struct {
+	// Name identifies the key in logs and keeps its rate and quota usage across
+	// reloads; defaults to the key prefix.
+	Name string `mapstructure:"name"`
+	Key  string `mapstructure:"key"`
+	// Hash replaces Key with a salted hash of the key; Prefix, the key's first
+	// characters, identifies it in logs and speeds up matching.
+	Hash      string `mapstructure:"hash"`
+	Prefix    string `mapstructure:"prefix"`
+	Namespace string `mapstructure:"namespace"`
+	Role      string `mapstructure:"role"`
+
+	// RateLimit is the sustained request rate allowed per second (0 = unlimited),
+	// with bursts of up to Burst requests (default: one second's worth).
+	RateLimit float64 `mapstructure:"rate_limit"`
+	Burst     int     `mapstructure:"burst"`
+	// Quota caps the requests per QuotaPeriod (0 = unlimited; period defaults to 24h).
+	Quota       int64         `mapstructure:"quota"`
+	QuotaPeriod time.Duration `mapstructure:"quota_period"`
+	// Routes lists the path prefixes the key may call, e.g. /v1/tts; empty allows all.
+	Routes []string `mapstructure:"routes"`
+	// Priority is the key's tier: the default X-Priority of its requests and the
+	// highest one it may request ("interactive", "normal" or "bulk").
+	Priority string `mapstructure:"priority"`
+	// Weight is the key's share of backend slots relative to other keys with
+	// requests waiting at the same priority (default 1).
+	Weight int `mapstructure:"weight"`
+	// DailyCharacters and MonthlyCharacters cap the text synthesized per UTC
+	// day and month; DailyAudioSeconds and MonthlyAudioSeconds cap the audio
+	// produced (0 = unlimited). See AuthConfig.UsageFile.
+	DailyCharacters     int64   `mapstructure:"daily_characters"`
+	MonthlyCharacters   int64   `mapstructure:"monthly_characters"`
+	DailyAudioSeconds   float64 `mapstructure:"daily_audio_seconds"`
+	MonthlyAudioSeconds float64 `mapstructure:"monthly_audio_seconds"`
+}
+
+// Enabled reports whether any API key is configured.
+func (c AuthConfig) Enabled() bool {
+	return c.APIKey != "" || c.APIKeyHash != "" || len(c.Keys) > 0 || c.KeyFilesEnabled() ||
+		len(c.SecretNames()) > 0 || c.Basic.Enabled() || c.Introspection.URL != "" || c.JWT.Enabled() ||
+		len(c.Signing.Keys) > 0
+}
+
+// SecretNames lists the secrets holding API keys.
+func (c AuthConfig) SecretNames() []string {
+	var names []string
+	if c.APIKeySecret != "" {
+		names = append(names, c.APIKeySecret)
+	}
+	return append(names, c.KeySecrets...)
+}
+
+// KeyFilesEnabled reports whether keys or Basic credentials are loaded from files.
+func (c AuthConfig) KeyFilesEnabled() bool {
+	return c.APIKeyFile != "" || c.KeyDir != "" || c.KeysFile != "" || c.Basic.HtpasswdFile != ""
 }
 
 // LimitsConfig holds request limit settings.
 type LimitsConfig struct {
 	MaxTextLength int `mapstructure:"max_text_length"`
+	// MaxMemoryMB sheds new TTS requests while Go runtime memory exceeds it (0 = off).
+	MaxMemoryMB int `mapstructure:"max_memory_mb"`
+	// MaxGoroutines sheds new TTS requests while the goroutine count exceeds it (0 = off).
+	MaxGoroutines int `mapstructure:"max_goroutines"`
+	// BulkShedRatio is the fraction of the limits above which bulk-priority
+	// requests are already shed.
+	BulkShedRatio float64 `mapstructure:"bulk_shed_ratio"`
+
+	// MaxConcurrent caps concurrent backend TTS calls (0 = unlimited). With
+	// AdaptiveConcurrency it is the upper bound of the tuned limit.
+	MaxConcurrent int `mapstructure:"max_concurrent"`
+	// AcquireTimeout bounds how long a request waits for a backend slot before a 503.
+	AcquireTimeout time.Duration `mapstructure:"acquire_timeout"`
+	// AdaptiveConcurrency tunes the limit between MinConcurrent and MaxConcurrent
+	// from observed backend latency (AIMD).
+	AdaptiveConcurrency bool `mapstructure:"adaptive_concurrency"`
+	MinConcurrent       int  `mapstructure:"min_concurrent"`
+	// TargetLatency is the backend latency above which the adaptive limit shrinks.
+	TargetLatency time.Duration `mapstructure:"target_latency"`
+	// CharsPerSlot makes a TTS request take one backend slot per this many
+	// characters of text, rounded up (0 = one slot per request).
+	CharsPerSlot int `mapstructure:"chars_per_slot"`
+}
+
+// LoadShedding reports whether any resource limit is configured.
+func (c LimitsConfig) LoadShedding() bool {
+	return c.MaxMemoryMB > 0 || c.MaxGoroutines > 0
+}
+
+// ReferencesConfig holds settings for Go-side reference state (aliases, locks,
+// audio fingerprints, metadata).
+type ReferencesConfig struct {
+	StorePath string `mapstructure:"store_path"`
+	// Duplicates controls duplicate audio detection on upload: "off", "warn", or "reject".
+	Duplicates string `mapstructure:"duplicates"`
+	// DuplicateThreshold is the minimum fingerprint similarity (0-1) treated as a duplicate.
+	DuplicateThreshold float64 `mapstructure:"duplicate_threshold"`
+	// TranscriptThreshold is the minimum ASR transcript similarity (0-1) below which
+	// an opt-in transcript verification warns.
+	TranscriptThreshold float64 `mapstructure:"transcript_threshold"`
+	// UploadDir holds resumable upload sessions. When empty, an "uploads"
+	// directory next to StorePath is used, or the system temp directory.
+	UploadDir string `mapstructure:"upload_dir"`
+	// UploadTTL is how long an upload session is kept after its last chunk.
+	UploadTTL time.Duration `mapstructure:"upload_ttl"`
+	// AudioDir holds the copy of each reference's audio and transcript that
+	// exports are made of. When empty, an "audio" directory next to StorePath
+	// is used, or the system temp directory.
+	AudioDir string `mapstructure:"audio_dir"`
+	// UseMemoryCache sends TTS requests that name a reference_id with
+	// use_memory_cache on, unless the client set it, so that each backend
+	// keeps its encoding of the reference instead of encoding it again.
+	UseMemoryCache bool `mapstructure:"use_memory_cache"`
+
+	// MaxAudioBytes bounds the size of reference audio; 0 is unlimited.
+	MaxAudioBytes int64 `mapstructure:"max_audio_bytes"`
+	// MinAudioDuration and MaxAudioDuration bound the length of reference
+	// audio whose duration can be read from its header (WAV and FLAC); 0
+	// disables each check.
+	MinAudioDuration time.Duration `mapstructure:"min_audio_duration"`
+	MaxAudioDuration time.Duration `mapstructure:"max_audio_duration"`
+	// AllowedFormats lists the accepted audio formats (wav, mp3, flac, ogg,
+	// m4a), detected from the audio itself. Empty accepts any audio.
+	AllowedFormats []string `mapstructure:"allowed_formats"`
+	// Transcode converts uploaded audio the server has a decoder for (MP3,
+	// Ogg Opus and M4A in builds with the lame, opus and fdkaac tags) to WAV
+	// before it reaches the backend, which cannot decode M4A.
+	Transcode bool `mapstructure:"transcode"`
+	// Voices are named references with default synthesis parameters, which TTS
+	// requests select with voice. Voices set through the API take precedence.
+	Voices []VoiceConfig `mapstructure:"voices"`
+}
+
+// TTSConfig holds server-side defaults of TTS requests.
+type TTSConfig struct {
+	// DefaultVoice is the voice, or reference, of requests that name none.
+	DefaultVoice string `mapstructure:"default_voice"`
+	// Presets are named sets of synthesis parameters that requests select
+	// with preset.
+	Presets []PresetConfig `mapstructure:"presets"`
+}
+
+// PresetConfig defines a preset: the defaults of the synthesis parameters
+// requests for it leave unset, and the voice or reference of requests that
+// name neither. Zero values leave a parameter to the voice, the request or
+// the server default.
+type PresetConfig struct {
+	Name              string  `mapstructure:"name"`
+	Voice             string  `mapstructure:"voice"`
+	ReferenceID       string  `mapstructure:"reference_id"`
+	Format            string  `mapstructure:"format"`
+	Model             string  `mapstructure:"model"`
+	ChunkLength       int     `mapstructure:"chunk_length"`
+	MaxNewTokens      int     `mapstructure:"max_new_tokens"`
+	TopP              float64 `mapstructure:"top_p"`
+	RepetitionPenalty float64 `mapstructure:"repetition_penalty"`
+	Temperature       float64 `mapstructure:"temperature"`
+	Seed              *int    `mapstructure:"seed"`
+	Speed             float64 `mapstructure:"speed"`
+}
+
+// VoiceConfig defines a voice: a name for a reference, or reference alias,
+// and the defaults of the synthesis parameters requests for it leave unset.
+// Zero values leave a parameter to the request or the server default.
+type VoiceConfig struct {
+	Name              string  `mapstructure:"name"`
+	ReferenceID       string  `mapstructure:"reference_id"`
+	Model             string  `mapstructure:"model"`
+	ChunkLength       int     `mapstructure:"chunk_length"`
+	MaxNewTokens      int     `mapstructure:"max_new_tokens"`
+	TopP              float64 `mapstructure:"top_p"`
+	RepetitionPenalty float64 `mapstructure:"repetition_penalty"`
+	Temperature       float64 `mapstructure:"temperature"`
+	Seed              *int    `mapstructure:"seed"`
+	Speed             float64 `mapstructure:"speed"`
+}
+
+// UploadPath returns the directory for resumable upload sessions.
+func (c ReferencesConfig) UploadPath() string {
+	switch {
+	case c.UploadDir != "":
+		return c.UploadDir
+	case c.StorePath != "":
+		return filepath.Join(filepath.Dir(c.StorePath), "uploads")
+	default:
+		return filepath.Join(os.TempDir(), "fish-speech-uploads")
+	}
+}
+
+// AudioPath returns the directory for the copies of reference audio.
+func (c ReferencesConfig) AudioPath() string {
+	switch {
+	case c.AudioDir != "":
+		return c.AudioDir
+	case c.StorePath != "":
+		return filepath.Join(filepath.Dir(c.StorePath), "audio")
+	default:
+		return filepath.Join(os.TempDir(), "fish-speech-references")
+	}
+}
+
+// AudioConfig controls the audio encoding done by fish-server itself.
+type AudioConfig struct {
+	// Transcode lists the formats (mp3) that fish-server encodes from WAV
+	// synthesized by the backend, instead of asking the backend for them.
+	// Streamed and post-processed responses are encoded by fish-server
+	// whenever it was built with an encoder for the format.
+	Transcode []string `mapstructure:"transcode"`
+	// MP3Bitrate and OpusBitrate are the bit rates of encoded MP3 and Opus
+	// in kbit/s.
+	MP3Bitrate  int `mapstructure:"mp3_bitrate"`
+	OpusBitrate int `mapstructure:"opus_bitrate"`
+}
+
+// CacheConfig controls the in-memory cache of non-streaming TTS responses.
+// Requests without a seed are answered with the first audio synthesized for
+// them, so the cache is off unless Enabled is set.
+type CacheConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// TTL is how long a response is kept (0 keeps it until evicted).
+	TTL time.Duration `mapstructure:"ttl"`
+	// MaxEntries and MaxBytes bound the cache; the least recently used
+	// responses are evicted beyond them. 0 disables each bound.
+	MaxEntries int   `mapstructure:"max_entries"`
+	MaxBytes   int64 `mapstructure:"max_bytes"`
+	// DiskDir enables a disk tier in this directory for responses evicted from
+	// memory and responses larger than SpillBytes (0 spills only on eviction).
+	// DiskMaxBytes bounds it, removing the least recently used files. The
+	// tier is loaded again at startup, so the cache survives restarts.
+	DiskDir      string `mapstructure:"disk_dir"`
+	DiskMaxBytes int64  `mapstructure:"disk_max_bytes"`
+	SpillBytes   int64  `mapstructure:"spill_bytes"`
+}
+
+// JobsConfig controls the asynchronous TTS job API. Job results are held in
+// memory until they expire, so it is off unless Enabled is set.
+type JobsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Workers is the number of jobs synthesized at once. Jobs also wait for
+	// a backend slot when limits.max_concurrent is set.
+	Workers int `mapstructure:"workers"`
+	// MaxQueued bounds the jobs waiting for a worker; submissions beyond it
+	// get 503. Waiting jobs are picked up in order of priority.
+	MaxQueued int `mapstructure:"max_queued"`
+	// BulkQueueRatio is the fraction of MaxQueued above which bulk-priority
+	// jobs are already refused, keeping room for more urgent ones.
+	BulkQueueRatio float64 `mapstructure:"bulk_queue_ratio"`
+	// Retention is how long a finished job and its audio are kept.
+	Retention time.Duration `mapstructure:"retention"`
+	// MaxRetainedBytes bounds the audio kept for done jobs; beyond it the
+	// oldest are removed before their retention passes (0 = unlimited).
+	MaxRetainedBytes int64 `mapstructure:"max_retained_bytes"`
+}
+
+// RedisConfig connects replicas of the server to a shared Redis. It is off
+// unless URL is set.
+type RedisConfig struct {
+	// URL is a redis:// or rediss:// URL, e.g. redis://redis:6379/0.
+	URL string `mapstructure:"url"`
+	// Password overrides any password in URL.
+	Password string `mapstructure:"password"`
+	// KeyPrefix starts every key written, so servers of different
+	// deployments can share a Redis.
+	KeyPrefix string `mapstructure:"key_prefix"`
+	// Timeout bounds each Redis call; on errors the server falls back to its
+	// local state.
+	Timeout time.Duration `mapstructure:"timeout"`
+	// Cache shares the TTS response cache (when cache.enabled).
+	Cache bool `mapstructure:"cache"`
+	// RateLimits shares the rate-limit and quota counters of API keys.
+	RateLimits bool `mapstructure:"rate_limits"`
+	// ReferenceListTTL is how long the backend's reference list is cached;
+	// 0 disables it.
+	ReferenceListTTL time.Duration `mapstructure:"reference_list_ttl"`
+}
+
+// ChaosConfig controls fault injection for resilience testing. It is only
+// read from the config file and is off unless Enabled is set.
+type ChaosConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Paths limits injection to requests whose path has one of these prefixes; empty means all.
+	Paths []string `mapstructure:"paths"`
+	// Latency is added to a LatencyRate fraction of requests.
+	Latency     time.Duration `mapstructure:"latency"`
+	LatencyRate float64       `mapstructure:"latency_rate"`
+	// ErrorRate is the fraction of requests answered with ErrorStatus (default 503).
+	ErrorRate   float64 `mapstructure:"error_rate"`
+	ErrorStatus int     `mapstructure:"error_status"`
+	// DropRate is the fraction of responses cut off mid-body.
+	DropRate float64 `mapstructure:"drop_rate"`
+}
+
+// DocsConfig controls the interactive API documentation page at /docs.
+type DocsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// ScriptURL is where the page loads the Redoc bundle from; point it at a
+	// local copy for offline deployments.
+	ScriptURL string `mapstructure:"script_url"`
+}
+
+// SecretsConfig selects the secret manager that API keys named in AuthConfig
+// are fetched from. Secret names may end in "#field" to select one field of a
+// JSON secret.
+type SecretsConfig struct {
+	// Provider is "vault", "aws", or empty to disable the secret manager.
+	Provider string `mapstructure:"provider"`
+	// RefreshInterval is how often secrets are fetched again.
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"`
+	Vault           VaultConfig   `mapstructure:"vault"`
+	AWS             AWSConfig     `mapstructure:"aws"`
+}
+
+// VaultConfig locates a HashiCorp Vault KV version 2 secrets engine. The token
+// is read from TokenFile, or from VAULT_TOKEN when no file is set.
+type VaultConfig struct {
+	Address   string `mapstructure:"address"`
+	Mount     string `mapstructure:"mount"`
+	TokenFile string `mapstructure:"token_file"`
+}
+
+// AWSConfig locates AWS Secrets Manager. Credentials are read from the standard
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN variables.
+type AWSConfig struct {
+	Region string `mapstructure:"region"`
+	// Endpoint overrides the regional endpoint, e.g. for a VPC endpoint.
+	Endpoint string `mapstructure:"endpoint"`
 }
 
 // LoggingConfig holds logging settings.
 type LoggingConfig struct {
 	Level  string `mapstructure:"level"`
 	Format string `mapstructure:"format"`
+	// Access configures the per-request access log separately from
+	// application events.
+	Access AccessLogConfig `mapstructure:"access"`
+}
+
+// AccessLogConfig configures where and how request lines are written.
+type AccessLogConfig struct {
+	// Output is stdout, stderr, off, or a file to append to. Empty means
+	// stdout, alongside the application log.
+	Output string `mapstructure:"output"`
+	// Format is json, text, common (NCSA Common Log Format) or combined
+	// (Common plus referer and user agent). Empty uses logging.format.
+	Format string `mapstructure:"format"`
+}
+
+// TracingConfig configures OpenTelemetry tracing of requests and backend
+// calls, exported to a collector with OTLP over HTTP. The standard
+// OTEL_EXPORTER_OTLP_* environment variables apply to settings left empty.
+type TracingConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Endpoint is the collector's OTLP/HTTP URL, such as
+	// http://otel-collector:4318; an http:// URL exports without TLS. Empty
+	// uses OTEL_EXPORTER_OTLP_ENDPOINT, or https://localhost:4318.
+	Endpoint string `mapstructure:"endpoint"`
+	// Headers are sent with every export, e.g. to authenticate to the
+	// collector.
+	Headers map[string]string `mapstructure:"headers"`
+	// SampleRatio is the fraction of traces started by fish-server that are
+	// recorded. Requests continuing a sampled trace are always recorded, and
+	// so are those that fail with a 5xx status or an error.
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+	// RouteRatios overrides SampleRatio by route pattern, such as
+	// /v1/health: 0 or /v1/tts: 0.5.
+	RouteRatios map[string]float64 `mapstructure:"route_ratios"`
+	// SlowThreshold, when set, records every request taking at least this
+	// long, streams included.
+	SlowThreshold time.Duration `mapstructure:"slow_threshold"`
+	// ServiceName is the service.name spans are reported under.
+	ServiceName string `mapstructure:"service_name"`
 }
 
+// DefaultDocsScriptURL is the Redoc bundle used by the /docs page.
+const DefaultDocsScriptURL = "https://cdn.redoc.ly/redoc/v2.1.5/bundles/redoc.standalone.js"
+
 // Default returns a Config with default values.
 func Default() *Config {
 	return &Config{
@@ -53,22 +666,96 @@ func Default() *Config {
 			Listen:       "0.0.0.0:8080",
 			ReadTimeout:  30 * time.Second,
 			WriteTimeout: 120 * time.Second,
+
+			StreamIdleTimeout: 30 * time.Second,
+			CORS: CORSConfig{
+				MaxAge: 10 * time.Minute,
+			},
 		},
 		Backend: BackendConfig{
 			URL:            "http://127.0.0.1:8081",
+			Balance:        "round_robin",
 			Timeout:        60 * time.Second,
 			MaxConnections: 100,
+			StatsCacheTTL:  5 * time.Second,
+			Retry: BackendRetryConfig{
+				MaxAttempts: 3,
+				Backoff:     100 * time.Millisecond,
+				MaxBackoff:  2 * time.Second,
+				StatusCodes: []int{502, 503},
+			},
 		},
 		Auth: AuthConfig{
-			APIKey: "",
+			APIKey:         "",
+			ReloadInterval: 10 * time.Second,
+			Basic: BasicAuthConfig{
+				Realm: "fish-speech",
+			},
+			Introspection: IntrospectionConfig{
+				CacheTTL: time.Minute,
+				Timeout:  5 * time.Second,
+			},
+			Signing: SigningConfig{
+				Window: 5 * time.Minute,
+			},
 		},
 		Limits: LimitsConfig{
-			MaxTextLength: 0,
+			MaxTextLength:  0,
+			BulkShedRatio:  0.8,
+			AcquireTimeout: 30 * time.Second,
+			MinConcurrent:  1,
+			TargetLatency:  10 * time.Second,
+		},
+		References: ReferencesConfig{
+			Duplicates:          "warn",
+			DuplicateThreshold:  0.98,
+			TranscriptThreshold: 0.7,
+			UploadTTL:           24 * time.Hour,
+			MaxAudioBytes:       200 << 20,
+		},
+		Audio: AudioConfig{
+			MP3Bitrate:  128,
+			OpusBitrate: 32,
+		},
+		Cache: CacheConfig{
+			TTL:          24 * time.Hour,
+			MaxEntries:   10000,
+			MaxBytes:     512 << 20,
+			DiskMaxBytes: 10 << 30,
+			SpillBytes:   1 << 20,
+		},
+		Jobs: JobsConfig{
+			Workers:          2,
+			MaxQueued:        100,
+			BulkQueueRatio:   0.8,
+			Retention:        time.Hour,
+			MaxRetainedBytes: 512 << 20,
+		},
+		Redis: RedisConfig{
+			KeyPrefix:        "fish:",
+			Timeout:          500 * time.Millisecond,
+			Cache:            true,
+			RateLimits:       true,
+			ReferenceListTTL: 10 * time.Second,
 		},
 		Logging: LoggingConfig{
 			Level:  "info",
 			Format: "json",
 		},
+		Tracing: TracingConfig{
+			SampleRatio: 1,
+			ServiceName: "fish-speech-go",
+		},
+		Docs: DocsConfig{
+			Enabled:   true,
+			ScriptURL: DefaultDocsScriptURL,
+		},
+		Secrets: SecretsConfig{
+			RefreshInterval: 5 * time.Minute,
+			Vault: VaultConfig{
+				Mount: "secret",
+			},
+		},
 	}
 }
 
@@ -130,6 +817,9 @@ func applyEnvOverrides(cfg *Config) {
 			cfg.Limits.MaxTextLength = n
 		}
 	}
+	if v := os.Getenv("FISH_REFERENCE_STORE"); v != "" {
+		cfg.References.StorePath = v
+	}
 	if v := os.Getenv("FISH_LOG_LEVEL"); v != "" {
 		cfg.Logging.Level = v
 	}
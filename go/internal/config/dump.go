@@ -0,0 +1,128 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Source identifies where a configuration value came from.
+type Source string
+
+// Configuration sources, from lowest to highest precedence.
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// Redacted replaces secret values in configuration dumps.
+const Redacted = "[redacted]"
+
+// secretFields are mapstructure names whose values are never dumped.
+var secretFields = map[string]bool{
+	"api_key":       true,
+	"key":           true,
+	"password":      true,
+	"client_secret": true,
+	"secret":        true,
+	"headers":       true,
+}
+
+// Setting is a single effective configuration value.
+type Setting struct {
+	Key    string      `json:"key"`
+	Value  interface{} `json:"value"`
+	Source Source      `json:"source"`
+}
+
+// Keys returns the dotted key of every setting in Config, in declaration order.
+func Keys() []string {
+	var keys []string
+	walk(reflect.ValueOf(Config{}), "", func(key string, _ reflect.Value) {
+		keys = append(keys, key)
+	})
+	return keys
+}
+
+// Dump returns the effective configuration with secrets redacted, annotating
+// each value with its source from Sources (default when unknown).
+func (c *Config) Dump() []Setting {
+	var settings []Setting
+	walk(reflect.ValueOf(*c), "", func(key string, v reflect.Value) {
+		source, ok := c.Sources[key]
+		if !ok {
+			source = SourceDefault
+		}
+		settings = append(settings, Setting{Key: key, Value: dumpValue(key, v), Source: source})
+	})
+	return settings
+}
+
+// walk calls fn for each leaf field of the struct v, recursing into nested
+// structs. Slices (including slices of structs) are leaves.
+func walk(v reflect.Value, prefix string, fn func(key string, v reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Tag.Get("mapstructure")
+		if name == "" || name == "-" {
+			continue
+		}
+		key := prefix + name
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			walk(field, key+".", fn)
+			continue
+		}
+		fn(key, field)
+	}
+}
+
+// dumpValue converts a field to a JSON-friendly value, redacting secrets,
+// including the credentials of URLs such as redis://:password@host.
+func dumpValue(key string, v reflect.Value) interface{} {
+	if secretFields[key[strings.LastIndex(key, ".")+1:]] {
+		if v.IsZero() {
+			return ""
+		}
+		return Redacted
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return redactURL(v.String())
+	case reflect.Int64:
+		if d, ok := v.Interface().(time.Duration); ok {
+			return d.String()
+		}
+	case reflect.Struct:
+		out := map[string]interface{}{}
+		walk(v, "", func(name string, field reflect.Value) {
+			out[name] = dumpValue(name, field)
+		})
+		return out
+	case reflect.Slice:
+		if kind := v.Type().Elem().Kind(); kind != reflect.Struct && kind != reflect.String {
+			break
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			out[i] = dumpValue(key, v.Index(i))
+		}
+		return out
+	}
+	return v.Interface()
+}
+
+// redactURL replaces the user information of a URL, which may hold a
+// password or token, with Redacted. Other strings are returned unchanged.
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil || u.Scheme == "" {
+		return s
+	}
+	u.User = nil
+	return u.Scheme + "://" + Redacted + "@" + strings.TrimPrefix(u.String(), u.Scheme+"://")
+}
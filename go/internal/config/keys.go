@@ -0,0 +1,72 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/apikey"
+)
+
+// LoadKeysFile reads and validates the "keys" list of a keys file (see
+// AuthConfig.KeysFile).
+func LoadKeysFile(path string) ([]APIKeyConfig, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read keys file: %w", err)
+	}
+
+	var keys []APIKeyConfig
+	if err := v.UnmarshalKey("keys", &keys); err != nil {
+		return nil, fmt.Errorf("invalid keys file: %w", err)
+	}
+	if err := ValidateKeys(keys); err != nil {
+		return nil, fmt.Errorf("invalid keys file %s: %w", path, err)
+	}
+	return keys, nil
+}
+
+// NamespaceSeparator joins a namespace and a reference ID in the backend's flat
+// reference store.
+const NamespaceSeparator = "__"
+
+// ValidateNamespace checks that a reference namespace cannot be confused with
+// another once joined to a reference ID: it must not contain the separator or
+// end with an underscore, so the first separator of a scoped ID always ends
+// its namespace.
+func ValidateNamespace(namespace string) error {
+	if strings.Contains(namespace, NamespaceSeparator) || strings.HasSuffix(namespace, "_") {
+		return errors.New("namespace must not contain \"__\" or end with \"_\"")
+	}
+	return nil
+}
+
+// ValidateKeys checks the key hashes, namespaces, priority tiers and limits of
+// keys.
+func ValidateKeys(keys []APIKeyConfig) error {
+	for i, k := range keys {
+		if err := ValidateNamespace(k.Namespace); err != nil {
+			return fmt.Errorf("key %d: %w", i, err)
+		}
+		if k.Hash != "" {
+			if _, err := apikey.Parse(k.Hash); err != nil {
+				return fmt.Errorf("key %d: %w", i, err)
+			}
+		}
+		switch k.Priority {
+		case "", "interactive", "normal", "bulk":
+		default:
+			return fmt.Errorf("key %d: priority must be interactive, normal or bulk", i)
+		}
+		if k.Weight < 0 {
+			return fmt.Errorf("key %d: weight must not be negative", i)
+		}
+		if k.DailyCharacters < 0 || k.MonthlyCharacters < 0 || k.DailyAudioSeconds < 0 || k.MonthlyAudioSeconds < 0 {
+			return fmt.Errorf("key %d: usage quotas must not be negative", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package limiter
+
+import (
+	"container/list"
+	"math"
+)
+
+// Flow identifies who a request is queued for, usually an API key. Waiters of
+// the same priority are shared fairly between flows.
+type Flow struct {
+	// Key names the flow; requests with an empty key share one flow.
+	Key string
+	// Weight is the flow's share of slots relative to other waiting flows
+	// (default 1).
+	Weight int
+}
+
+// fairQueue orders the waiters of one priority by self-clocked weighted fair
+// queueing. Each waiter is tagged with a virtual finish time that advances by
+// cost/weight per request of its flow, and the lowest tag is served first, so
+// while several flows are waiting each gets slots in proportion to its weight
+// and one flow's burst, or its few expensive requests, cannot starve the
+// others. Within a flow, and between
+// equal tags, waiters are served in arrival order. A single flow is plain FIFO.
+type fairQueue struct {
+	flows map[string]*flow
+	// vtime is the tag of the last waiter served; flows that were idle
+	// restart from it rather than from their own, older, tags.
+	vtime float64
+	seq   uint64
+	n     int
+}
+
+type flow struct {
+	waiters *list.List
+	// lastTag is the tag of the flow's most recently queued waiter.
+	lastTag float64
+}
+
+type waiter struct {
+	ready chan struct{}
+	cost  int
+
+	flow *flow
+	elem *list.Element
+	tag  float64
+	seq  uint64
+}
+
+func newFairQueue() *fairQueue {
+	return &fairQueue{flows: make(map[string]*flow)}
+}
+
+func (q *fairQueue) Len() int {
+	return q.n
+}
+
+// push queues w for f.
+func (q *fairQueue) push(w *waiter, f Flow) {
+	weight := f.Weight
+	if weight <= 0 {
+		weight = 1
+	}
+	fl, ok := q.flows[f.Key]
+	if !ok {
+		fl = &flow{waiters: list.New()}
+		q.flows[f.Key] = fl
+	}
+
+	q.seq++
+	w.seq = q.seq
+	w.tag = math.Max(q.vtime, fl.lastTag) + float64(w.cost)/float64(weight)
+	w.flow = fl
+	w.elem = fl.waiters.PushBack(w)
+	fl.lastTag = w.tag
+	q.n++
+}
+
+// peek returns the next waiter to serve without removing it, or nil if none
+// is queued.
+func (q *fairQueue) peek() *waiter {
+	var next *waiter
+	for key, fl := range q.flows {
+		if fl.waiters.Len() == 0 {
+			// Forget idle flows once their tags no longer hold them back.
+			if fl.lastTag <= q.vtime {
+				delete(q.flows, key)
+			}
+			continue
+		}
+		w := fl.waiters.Front().Value.(*waiter)
+		if next == nil || w.tag < next.tag || (w.tag == next.tag && w.seq < next.seq) {
+			next = w
+		}
+	}
+	return next
+}
+
+// pop removes and returns the next waiter to serve, or nil if none is queued.
+func (q *fairQueue) pop() *waiter {
+	next := q.peek()
+	if next == nil {
+		return nil
+	}
+	q.remove(next)
+	q.vtime = next.tag
+	return next
+}
+
+// remove takes w out of the queue, e.g. when it stops waiting.
+func (q *fairQueue) remove(w *waiter) {
+	w.flow.waiters.Remove(w.elem)
+	q.n--
+}
@@ -0,0 +1,79 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// servedOrder queues one waiter per flow in turn, in the given order, behind
+// a held slot, then releases it and returns the keys in the order served.
+func servedOrder(t *testing.T, flows []Flow) []string {
+	t.Helper()
+	l := New(Config{MaxConcurrent: 1})
+	release, err := l.Acquire(context.Background(), PriorityNormal)
+	require.NoError(t, err)
+
+	order := make(chan string, len(flows))
+	for i, f := range flows {
+		go func(f Flow) {
+			r, err := l.AcquireFlow(context.Background(), PriorityNormal, f)
+			if assert.NoError(t, err) {
+				order <- f.Key
+				r(Outcome{})
+			}
+		}(f)
+		// Let each waiter enqueue before the next one arrives.
+		for l.Stats().Waiting != i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	release(Outcome{})
+	served := make([]string, 0, len(flows))
+	for range flows {
+		served = append(served, <-order)
+	}
+	return served
+}
+
+func TestLimiter_FairQueueing(t *testing.T) {
+	a, b := Flow{Key: "a"}, Flow{Key: "b"}
+
+	// b's single request is not stuck behind a's burst.
+	assert.Equal(t, []string{"a", "b", "a", "a", "a"}, servedOrder(t, []Flow{a, a, a, a, b}))
+
+	// A single flow is served in arrival order.
+	assert.Equal(t, []string{"a", "a", "a"}, servedOrder(t, []Flow{a, a, a}))
+}
+
+func TestLimiter_WeightedFairQueueing(t *testing.T) {
+	heavy, light := Flow{Key: "heavy", Weight: 2}, Flow{Key: "light"}
+
+	served := servedOrder(t, []Flow{heavy, heavy, heavy, heavy, light, light, light, light})
+	// While both flows wait, heavy gets two slots for each one of light's.
+	assert.Equal(t, []string{"heavy", "heavy", "light", "heavy", "heavy", "light", "light", "light"}, served)
+}
+
+func TestFairQueue_Cost(t *testing.T) {
+	q := newFairQueue()
+	push := func(key string, cost int) {
+		q.push(&waiter{ready: make(chan struct{}), cost: cost}, Flow{Key: key})
+	}
+	// Each of big's requests counts as four of small's, so small is served
+	// four times for each request of big's.
+	push("big", 4)
+	push("big", 4)
+	for i := 0; i < 4; i++ {
+		push("small", 1)
+	}
+
+	var served []int
+	for q.Len() > 0 {
+		served = append(served, q.pop().cost)
+	}
+	assert.Equal(t, []int{1, 1, 1, 4, 1, 4}, served)
+}
@@ -0,0 +1,317 @@
+// Package limiter bounds the number of concurrent backend calls.
+package limiter
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrTimeout is returned when no slot frees up within the acquire timeout.
+var ErrTimeout = errors.New("timed out waiting for a backend slot")
+
+// latencyWeight is the weight of each call in the mean call latency.
+const latencyWeight = 0.2
+
+// Config configures a Limiter.
+type Config struct {
+	// MaxConcurrent is the number of concurrent slots, or the upper bound when
+	// Adaptive is set.
+	MaxConcurrent int
+	// AcquireTimeout bounds how long a caller waits for a slot (0 = until the
+	// context is done).
+	AcquireTimeout time.Duration
+
+	// Adaptive tunes the limit between MinConcurrent and MaxConcurrent using
+	// AIMD: each fast, successful call raises it by 1/limit, and each slow or
+	// overloaded call multiplies it by Backoff.
+	Adaptive      bool
+	MinConcurrent int
+	// TargetLatency is the call latency above which the limit is decreased.
+	TargetLatency time.Duration
+	// Backoff is the multiplicative decrease factor, in (0, 1).
+	Backoff float64
+}
+
+// Stats is a snapshot of the limiter state.
+type Stats struct {
+	Limit int
+	// InFlight is the number of slots held, which exceeds the number of calls
+	// when some cost more than one slot.
+	InFlight int
+	Waiting  int
+	Adaptive bool
+	// MaxConcurrent and AcquireTimeout are the current settings, which may
+	// have been changed since New.
+	MaxConcurrent  int
+	AcquireTimeout time.Duration
+	// MeanLatency is a moving average of call latency, zero before the
+	// first call finishes.
+	MeanLatency time.Duration
+}
+
+// Priority orders waiters for a slot. Higher priorities are always granted
+// before lower ones; equal priorities are shared fairly between flows and
+// served in arrival order within a flow.
+type Priority int
+
+// Priorities, from most to least urgent.
+const (
+	PriorityHigh Priority = iota
+	PriorityNormal
+	PriorityLow
+
+	numPriorities
+)
+
+// Outcome describes a finished call for adaptive tuning.
+type Outcome struct {
+	Latency time.Duration
+	// Overloaded marks calls that failed in a way that indicates backend
+	// saturation, such as timeouts or connection errors.
+	Overloaded bool
+}
+
+// Limiter is a semaphore whose capacity can optionally adapt to observed
+// backend latency. Waiters are served by priority, then by weighted fair
+// queueing between flows.
+type Limiter struct {
+	cfg Config
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	waiters  [numPriorities]*fairQueue
+	// latency is the moving average of call latency in nanoseconds.
+	latency float64
+}
+
+// New creates a Limiter. It panics if MaxConcurrent is not positive.
+func New(cfg Config) *Limiter {
+	if cfg.MaxConcurrent <= 0 {
+		panic("limiter: MaxConcurrent must be positive")
+	}
+	if cfg.MinConcurrent <= 0 || cfg.MinConcurrent > cfg.MaxConcurrent {
+		cfg.MinConcurrent = 1
+	}
+	if cfg.Backoff <= 0 || cfg.Backoff >= 1 {
+		cfg.Backoff = 0.9
+	}
+
+	limit := float64(cfg.MaxConcurrent)
+	if cfg.Adaptive {
+		limit = float64(cfg.MinConcurrent)
+	}
+	l := &Limiter{cfg: cfg, limit: limit}
+	for i := range l.waiters {
+		l.waiters[i] = newFairQueue()
+	}
+	return l
+}
+
+// Acquire blocks until a slot is available and returns a function that must be
+// called exactly once to release it. A request never takes a free slot ahead of
+// waiters with the same or higher priority.
+func (l *Limiter) Acquire(ctx context.Context, priority Priority) (func(Outcome), error) {
+	return l.AcquireFlow(ctx, priority, Flow{})
+}
+
+// AcquireFlow is Acquire for a request of flow f, which shares the slots freed
+// for its priority with the other waiting flows according to their weights.
+func (l *Limiter) AcquireFlow(ctx context.Context, priority Priority, f Flow) (func(Outcome), error) {
+	return l.AcquireCost(ctx, priority, f, 1)
+}
+
+// AcquireCost is AcquireFlow for a request that takes cost slots at once, so
+// that expensive calls count for more against the limit. Costs below one are
+// treated as one. A request costing more than the whole limit runs once every
+// slot is free, alone. Waiters are granted strictly in order: a large request
+// at the head of the queue holds back smaller ones behind it rather than
+// being starved by them.
+func (l *Limiter) AcquireCost(ctx context.Context, priority Priority, f Flow, cost int) (func(Outcome), error) {
+	if priority < PriorityHigh || priority >= numPriorities {
+		priority = PriorityNormal
+	}
+	if cost < 1 {
+		cost = 1
+	}
+
+	l.mu.Lock()
+	if l.waitingAtOrAbove(priority) == 0 && l.fitsLocked(cost) {
+		l.inFlight += cost
+		l.mu.Unlock()
+		return l.releaser(cost), nil
+	}
+
+	w := &waiter{ready: make(chan struct{}), cost: cost}
+	queue := l.waiters[priority]
+	queue.push(w, f)
+	acquireTimeout := l.cfg.AcquireTimeout
+	l.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if acquireTimeout > 0 {
+		timer := time.NewTimer(acquireTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case <-w.ready:
+		return l.releaser(cost), nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeout:
+		err = ErrTimeout
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-w.ready:
+		// Granted while giving up; hand the slots on.
+		l.inFlight -= cost
+		l.grantLocked()
+	default:
+		queue.remove(w)
+		// A large waiter leaving the head of the queue may let smaller
+		// ones behind it fit.
+		l.grantLocked()
+	}
+	return nil, err
+}
+
+// Stats returns the current limiter state.
+func (l *Limiter) Stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return Stats{
+		Limit:       l.currentLimit(),
+		InFlight:    l.inFlight,
+		Waiting:     l.waitingAtOrAbove(numPriorities - 1),
+		Adaptive:    l.cfg.Adaptive,
+		MeanLatency: time.Duration(l.latency),
+
+		MaxConcurrent:  l.cfg.MaxConcurrent,
+		AcquireTimeout: l.cfg.AcquireTimeout,
+	}
+}
+
+// SetMaxConcurrent changes the number of slots, or the upper bound of an
+// adaptive limit. Growing hands the new slots to waiters at once. Shrinking
+// never takes a slot back: calls already holding one finish normally, and new
+// ones wait until enough have been released to get below the new limit.
+// It panics if n is not positive.
+func (l *Limiter) SetMaxConcurrent(n int) {
+	if n <= 0 {
+		panic("limiter: MaxConcurrent must be positive")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.cfg.MaxConcurrent = n
+	if l.cfg.Adaptive {
+		l.limit = math.Max(float64(l.minConcurrent()), math.Min(float64(n), l.limit))
+	} else {
+		l.limit = float64(n)
+	}
+	l.grantLocked()
+}
+
+// SetAcquireTimeout changes how long later callers wait for a slot (0 = until
+// the context is done). Callers already waiting keep the timeout they started
+// with.
+func (l *Limiter) SetAcquireTimeout(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cfg.AcquireTimeout = d
+}
+
+// EstimatedWait estimates how long a request arriving now would wait for a
+// slot: the waiters ahead of it, plus itself, divided by the service rate of
+// limit calls per mean call latency. It is zero until a call has finished.
+func (l *Limiter) EstimatedWait() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	queued := l.waitingAtOrAbove(numPriorities-1) + 1
+	return time.Duration(float64(queued) * l.latency / float64(l.currentLimit()))
+}
+
+func (l *Limiter) releaser(cost int) func(Outcome) {
+	var once sync.Once
+	return func(o Outcome) {
+		once.Do(func() { l.release(cost, o) })
+	}
+}
+
+func (l *Limiter) release(cost int, o Outcome) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight -= cost
+	if l.latency == 0 {
+		l.latency = float64(o.Latency)
+	} else {
+		l.latency += latencyWeight * (float64(o.Latency) - l.latency)
+	}
+	if l.cfg.Adaptive {
+		l.adjustLocked(o)
+	}
+	l.grantLocked()
+}
+
+// adjustLocked applies AIMD to the limit.
+func (l *Limiter) adjustLocked(o Outcome) {
+	if o.Overloaded || (l.cfg.TargetLatency > 0 && o.Latency > l.cfg.TargetLatency) {
+		l.limit *= l.cfg.Backoff
+	} else {
+		l.limit += 1 / l.limit
+	}
+	l.limit = math.Max(float64(l.minConcurrent()), math.Min(float64(l.cfg.MaxConcurrent), l.limit))
+}
+
+// minConcurrent is the lower bound of an adaptive limit, which a reduced
+// MaxConcurrent caps.
+func (l *Limiter) minConcurrent() int {
+	if l.cfg.MinConcurrent > l.cfg.MaxConcurrent {
+		return l.cfg.MaxConcurrent
+	}
+	return l.cfg.MinConcurrent
+}
+
+// grantLocked hands free slots to waiters, highest priority first, stopping
+// at the first waiter whose cost does not fit yet.
+func (l *Limiter) grantLocked() {
+	for _, queue := range l.waiters {
+		for queue.Len() > 0 {
+			if !l.fitsLocked(queue.peek().cost) {
+				return
+			}
+			w := queue.pop()
+			l.inFlight += w.cost
+			close(w.ready)
+		}
+	}
+}
+
+// fitsLocked reports whether a request costing cost slots can start now. One
+// costing more than the limit fits only when no slot is held.
+func (l *Limiter) fitsLocked(cost int) bool {
+	return l.inFlight+cost <= l.currentLimit() || l.inFlight == 0
+}
+
+// waitingAtOrAbove counts waiters with priority p or more urgent.
+func (l *Limiter) waitingAtOrAbove(p Priority) int {
+	n := 0
+	for i := PriorityHigh; i <= p; i++ {
+		n += l.waiters[i].Len()
+	}
+	return n
+}
+
+func (l *Limiter) currentLimit() int {
+	return int(l.limit)
+}
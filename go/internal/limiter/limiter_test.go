@@ -0,0 +1,310 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter_Fixed(t *testing.T) {
+	l := New(Config{MaxConcurrent: 2, AcquireTimeout: 20 * time.Millisecond})
+	ctx := context.Background()
+
+	r1, err := l.Acquire(ctx, PriorityNormal)
+	require.NoError(t, err)
+	_, err = l.Acquire(ctx, PriorityNormal)
+	require.NoError(t, err)
+
+	_, err = l.Acquire(ctx, PriorityNormal)
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Equal(t, Stats{Limit: 2, InFlight: 2, MaxConcurrent: 2, AcquireTimeout: 20 * time.Millisecond}, l.Stats())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		release, err := l.Acquire(ctx, PriorityNormal)
+		if assert.NoError(t, err) {
+			release(Outcome{})
+		}
+	}()
+	time.Sleep(5 * time.Millisecond)
+	r1(Outcome{})
+	<-done
+
+	assert.Equal(t, 1, l.Stats().InFlight)
+}
+
+func TestLimiter_SetMaxConcurrent(t *testing.T) {
+	l := New(Config{MaxConcurrent: 2})
+	ctx := context.Background()
+
+	r1, err := l.Acquire(ctx, PriorityNormal)
+	require.NoError(t, err)
+	r2, err := l.Acquire(ctx, PriorityNormal)
+	require.NoError(t, err)
+
+	// Shrinking keeps both held slots; new callers wait until in-flight calls
+	// drop below the new limit.
+	l.SetMaxConcurrent(1)
+	assert.Equal(t, 2, l.Stats().InFlight)
+	granted := make(chan func(Outcome))
+	go func() {
+		release, err := l.Acquire(ctx, PriorityNormal)
+		if assert.NoError(t, err) {
+			granted <- release
+		}
+	}()
+	time.Sleep(5 * time.Millisecond)
+	r1(Outcome{})
+	select {
+	case <-granted:
+		t.Fatal("granted a slot above the reduced limit")
+	case <-time.After(5 * time.Millisecond):
+	}
+	r2(Outcome{})
+	r3 := <-granted
+
+	// Growing hands the new slots to waiters at once.
+	go func() {
+		release, err := l.Acquire(ctx, PriorityNormal)
+		if assert.NoError(t, err) {
+			granted <- release
+		}
+	}()
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, 1, l.Stats().Waiting)
+	l.SetMaxConcurrent(3)
+	r4 := <-granted
+	assert.Equal(t, Stats{Limit: 3, InFlight: 2, MaxConcurrent: 3}, l.Stats())
+	r3(Outcome{})
+	r4(Outcome{})
+}
+
+func TestLimiter_SetMaxConcurrentAdaptive(t *testing.T) {
+	l := New(Config{MaxConcurrent: 10, MinConcurrent: 4, Adaptive: true})
+	assert.Equal(t, 4, l.Stats().Limit)
+
+	l.SetMaxConcurrent(2)
+	assert.Equal(t, 2, l.Stats().Limit)
+
+	// Raising the maximum restores the minimum; AIMD takes it from there.
+	l.SetMaxConcurrent(8)
+	assert.Equal(t, 4, l.Stats().Limit)
+}
+
+func TestLimiter_SetAcquireTimeout(t *testing.T) {
+	l := New(Config{MaxConcurrent: 1})
+	release, err := l.Acquire(context.Background(), PriorityNormal)
+	require.NoError(t, err)
+	defer release(Outcome{})
+
+	l.SetAcquireTimeout(10 * time.Millisecond)
+	_, err = l.Acquire(context.Background(), PriorityNormal)
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Equal(t, 10*time.Millisecond, l.Stats().AcquireTimeout)
+}
+
+func TestLimiter_AcquireCost(t *testing.T) {
+	l := New(Config{MaxConcurrent: 4})
+	ctx := context.Background()
+	full := func() bool {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		release, err := l.Acquire(ctx, PriorityNormal)
+		if err == nil {
+			release(Outcome{})
+		}
+		return err != nil
+	}
+
+	big, err := l.AcquireCost(ctx, PriorityNormal, Flow{}, 3)
+	require.NoError(t, err)
+	assert.Equal(t, 3, l.Stats().InFlight)
+	small, err := l.AcquireCost(ctx, PriorityNormal, Flow{}, 1)
+	require.NoError(t, err)
+	assert.True(t, full())
+
+	// A cost above the limit waits until every slot is free, then runs alone.
+	granted := make(chan func(Outcome))
+	go func() {
+		release, err := l.AcquireCost(ctx, PriorityNormal, Flow{}, 10)
+		if assert.NoError(t, err) {
+			granted <- release
+		}
+	}()
+	for l.Stats().Waiting != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	big(Outcome{})
+	select {
+	case <-granted:
+		t.Fatal("granted an oversized request while slots are held")
+	case <-time.After(5 * time.Millisecond):
+	}
+	small(Outcome{})
+	huge := <-granted
+	assert.Equal(t, 10, l.Stats().InFlight)
+	assert.True(t, full())
+	huge(Outcome{})
+	assert.Equal(t, 0, l.Stats().InFlight)
+}
+
+func TestLimiter_AcquireCostHeadOfLine(t *testing.T) {
+	l := New(Config{MaxConcurrent: 2})
+	ctx := context.Background()
+	held, err := l.Acquire(ctx, PriorityNormal)
+	require.NoError(t, err)
+
+	// A queued two-slot request is not overtaken by later one-slot requests,
+	// even though one slot is free.
+	order := make(chan int, 2)
+	go func() {
+		release, err := l.AcquireCost(ctx, PriorityNormal, Flow{}, 2)
+		if assert.NoError(t, err) {
+			order <- 2
+			release(Outcome{})
+		}
+	}()
+	for l.Stats().Waiting != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	go func() {
+		release, err := l.Acquire(ctx, PriorityNormal)
+		if assert.NoError(t, err) {
+			order <- 1
+			release(Outcome{})
+		}
+	}()
+	for l.Stats().Waiting != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	held(Outcome{})
+	assert.Equal(t, 2, <-order)
+	assert.Equal(t, 1, <-order)
+}
+
+func TestLimiter_AcquireCostHeadGivesUp(t *testing.T) {
+	l := New(Config{MaxConcurrent: 2, AcquireTimeout: 100 * time.Millisecond})
+	ctx := context.Background()
+	held, err := l.Acquire(ctx, PriorityNormal)
+	require.NoError(t, err)
+	defer held(Outcome{})
+
+	timedOut := make(chan error, 1)
+	go func() {
+		_, err := l.AcquireCost(ctx, PriorityNormal, Flow{}, 2)
+		timedOut <- err
+	}()
+	for l.Stats().Waiting != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	// The one-slot request waits behind the two-slot one, without a timeout
+	// of its own.
+	l.SetAcquireTimeout(0)
+	granted := make(chan struct{})
+	go func() {
+		release, err := l.Acquire(ctx, PriorityNormal)
+		if assert.NoError(t, err) {
+			close(granted)
+			release(Outcome{})
+		}
+	}()
+	for l.Stats().Waiting != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	assert.ErrorIs(t, <-timedOut, ErrTimeout)
+	select {
+	case <-granted:
+	case <-time.After(time.Second):
+		t.Fatal("small waiter not granted once the large head waiter gave up")
+	}
+}
+
+func TestLimiter_ContextCancel(t *testing.T) {
+	l := New(Config{MaxConcurrent: 1})
+	_, err := l.Acquire(context.Background(), PriorityNormal)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = l.Acquire(ctx, PriorityNormal)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 0, l.Stats().Waiting)
+}
+
+func TestLimiter_Adaptive(t *testing.T) {
+	l := New(Config{MaxConcurrent: 10, MinConcurrent: 2, Adaptive: true, TargetLatency: time.Second, Backoff: 0.5})
+	assert.Equal(t, 2, l.Stats().Limit)
+
+	for i := 0; i < 20; i++ {
+		release, err := l.Acquire(context.Background(), PriorityNormal)
+		require.NoError(t, err)
+		release(Outcome{Latency: 100 * time.Millisecond})
+	}
+	grown := l.Stats().Limit
+	assert.Greater(t, grown, 2)
+
+	release, err := l.Acquire(context.Background(), PriorityNormal)
+	require.NoError(t, err)
+	release(Outcome{Latency: 2 * time.Second})
+	assert.Less(t, l.Stats().Limit, grown)
+
+	for i := 0; i < 10; i++ {
+		release, _ := l.Acquire(context.Background(), PriorityNormal)
+		release(Outcome{Overloaded: true})
+	}
+	assert.Equal(t, 2, l.Stats().Limit)
+}
+
+func TestLimiter_PriorityOrdering(t *testing.T) {
+	l := New(Config{MaxConcurrent: 1})
+	release, err := l.Acquire(context.Background(), PriorityNormal)
+	require.NoError(t, err)
+
+	order := make(chan Priority, 3)
+	for i, p := range []Priority{PriorityLow, PriorityNormal, PriorityHigh} {
+		go func(p Priority) {
+			r, err := l.Acquire(context.Background(), p)
+			if assert.NoError(t, err) {
+				order <- p
+				r(Outcome{})
+			}
+		}(p)
+		// Let each waiter enqueue before the next one arrives.
+		for l.Stats().Waiting != i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	release(Outcome{})
+	assert.Equal(t, PriorityHigh, <-order)
+	assert.Equal(t, PriorityNormal, <-order)
+	assert.Equal(t, PriorityLow, <-order)
+}
+
+func TestLimiter_EstimatedWait(t *testing.T) {
+	l := New(Config{MaxConcurrent: 2})
+	ctx := context.Background()
+	assert.Zero(t, l.EstimatedWait())
+
+	release, err := l.Acquire(ctx, PriorityNormal)
+	require.NoError(t, err)
+	release(Outcome{Latency: 100 * time.Millisecond})
+	assert.Equal(t, 100*time.Millisecond, l.Stats().MeanLatency)
+
+	// Two slots each finishing a call per 100ms admit a new request every 50ms.
+	assert.Equal(t, 50*time.Millisecond, l.EstimatedWait())
+
+	r1, _ := l.Acquire(ctx, PriorityNormal)
+	r2, _ := l.Acquire(ctx, PriorityNormal)
+	go func() { _, _ = l.Acquire(ctx, PriorityLow) }()
+	require.Eventually(t, func() bool { return l.Stats().Waiting == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, 100*time.Millisecond, l.EstimatedWait())
+	r1(Outcome{Latency: 100 * time.Millisecond})
+	r2(Outcome{Latency: 100 * time.Millisecond})
+}
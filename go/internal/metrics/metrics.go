@@ -0,0 +1,222 @@
+// Package metrics defines the Prometheus metrics exported on /metrics.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "fish"
+
+// Stream outcomes recorded by StreamsTotal.
+const (
+	StreamCompleted     = "completed"
+	StreamClientAborted = "client_aborted"
+	StreamBackendError  = "backend_error"
+	StreamTimeout       = "timeout"
+)
+
+// Reference upload rejection reasons recorded by ReferenceRejectsTotal.
+const (
+	RejectTooLarge  = "too_large"
+	RejectTooShort  = "too_short"
+	RejectTooLong   = "too_long"
+	RejectBadFormat = "format"
+)
+
+// Backend slot acquisition outcomes recorded by LimiterWaitSeconds.
+const (
+	AcquireGranted  = "acquired"
+	AcquireTimeout  = "timeout"
+	AcquireCanceled = "canceled"
+)
+
+// Backend call outcomes recorded by BackendRequestSeconds.
+const (
+	BackendOK    = "ok"
+	BackendError = "error"
+)
+
+// Response cache eviction reasons recorded by CacheEvictionsTotal; they match
+// the cache package's Evict constants.
+const (
+	CacheEvictEntries   = "entries"
+	CacheEvictBytes     = "bytes"
+	CacheEvictExpired   = "expired"
+	CacheEvictDiskBytes = "disk_bytes"
+	CacheEvictDiskError = "disk_error"
+)
+
+// RecordCacheEviction records a response cache eviction. It has the signature
+// of cache.Config.OnEvict.
+func (m *Metrics) RecordCacheEviction(reason string, bytes int) {
+	m.CacheEvictionsTotal.WithLabelValues(reason).Inc()
+	m.CacheEvictedBytes.WithLabelValues(reason).Add(float64(bytes))
+}
+
+// Metrics holds the server's collectors and the registry they are exported from.
+type Metrics struct {
+	registry *prometheus.Registry
+	handler  http.Handler
+
+	// HTTPRequestsTotal counts HTTP requests by route pattern, method and
+	// status.
+	HTTPRequestsTotal *prometheus.CounterVec
+	// HTTPRequestSeconds observes the time taken to serve HTTP requests, by
+	// route pattern, method and status.
+	HTTPRequestSeconds *prometheus.HistogramVec
+	// BackendRequestSeconds observes the latency of backend calls, by
+	// operation and outcome.
+	BackendRequestSeconds *prometheus.HistogramVec
+	// ActiveStreams is the number of streaming TTS responses being sent.
+	ActiveStreams prometheus.Gauge
+
+	// StreamsTotal counts streaming TTS requests by outcome.
+	StreamsTotal *prometheus.CounterVec
+	// StreamBytes observes the bytes sent to the client per stream.
+	StreamBytes *prometheus.HistogramVec
+	// ReferenceRejectsTotal counts reference uploads rejected by the audio
+	// limits, by reason.
+	ReferenceRejectsTotal *prometheus.CounterVec
+	// CacheEvictionsTotal and CacheEvictedBytes count TTS responses evicted
+	// from the response cache, by reason.
+	CacheEvictionsTotal *prometheus.CounterVec
+	CacheEvictedBytes   *prometheus.CounterVec
+	// LimiterWaitSeconds observes how long TTS requests waited for a backend
+	// slot, by outcome.
+	LimiterWaitSeconds *prometheus.HistogramVec
+	// LimiterHoldSeconds observes how long backend slots were held.
+	LimiterHoldSeconds prometheus.Histogram
+	// LimiterUtilization is the fraction of the concurrency limit in use.
+	LimiterUtilization prometheus.Gauge
+	// LimiterQueueDepth is the number of TTS requests waiting for a backend
+	// slot.
+	LimiterQueueDepth prometheus.Gauge
+}
+
+// New creates the collectors and registers them, along with the Go runtime and
+// process collectors, in a fresh registry.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		HTTPRequestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route, method and status.",
+		}, []string{"route", "method", "status"}),
+		HTTPRequestSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to serve HTTP requests, by route, method and status.",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14),
+		}, []string{"route", "method", "status"}),
+		BackendRequestSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "backend_request_duration_seconds",
+			Help:      "Latency of backend calls, by operation and outcome.",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14),
+		}, []string{"op", "outcome"}),
+		ActiveStreams: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "tts_active_streams",
+			Help:      "Streaming TTS responses being sent.",
+		}),
+		StreamsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tts_streams_total",
+			Help:      "Streaming TTS requests by outcome.",
+		}, []string{"outcome"}),
+		StreamBytes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "tts_stream_bytes",
+			Help:      "Audio bytes sent to the client per streaming TTS request.",
+			Buckets:   prometheus.ExponentialBuckets(16<<10, 4, 8),
+		}, []string{"outcome"}),
+		ReferenceRejectsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reference_rejects_total",
+			Help:      "Reference uploads rejected by the audio size, duration or format limits.",
+		}, []string{"reason"}),
+		CacheEvictionsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_evictions_total",
+			Help:      "TTS responses evicted from the response cache, by reason.",
+		}, []string{"reason"}),
+		CacheEvictedBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_evicted_bytes_total",
+			Help:      "Audio bytes evicted from the response cache, by reason.",
+		}, []string{"reason"}),
+		LimiterWaitSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "limiter_wait_seconds",
+			Help:      "Time TTS requests waited for a backend slot, by outcome.",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14),
+		}, []string{"outcome"}),
+		LimiterHoldSeconds: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "limiter_hold_seconds",
+			Help:      "Time backend slots were held by TTS requests.",
+			Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+		}),
+		LimiterUtilization: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "limiter_utilization",
+			Help:      "Backend slots held as a fraction of the current concurrency limit.",
+		}),
+		LimiterQueueDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "limiter_queue_depth",
+			Help:      "TTS requests waiting for a backend slot.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.HTTPRequestsTotal,
+		m.HTTPRequestSeconds,
+		m.BackendRequestSeconds,
+		m.ActiveStreams,
+		m.StreamsTotal,
+		m.StreamBytes,
+		m.ReferenceRejectsTotal,
+		m.CacheEvictionsTotal,
+		m.CacheEvictedBytes,
+		m.LimiterWaitSeconds,
+		m.LimiterHoldSeconds,
+		m.LimiterUtilization,
+		m.LimiterQueueDepth,
+	)
+
+	m.handler = promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+
+	for _, outcome := range []string{StreamCompleted, StreamClientAborted, StreamBackendError, StreamTimeout} {
+		m.StreamsTotal.WithLabelValues(outcome)
+	}
+	for _, reason := range []string{RejectTooLarge, RejectTooShort, RejectTooLong, RejectBadFormat} {
+		m.ReferenceRejectsTotal.WithLabelValues(reason)
+	}
+	for _, reason := range []string{CacheEvictEntries, CacheEvictBytes, CacheEvictExpired, CacheEvictDiskBytes, CacheEvictDiskError} {
+		m.CacheEvictionsTotal.WithLabelValues(reason)
+		m.CacheEvictedBytes.WithLabelValues(reason)
+	}
+	for _, outcome := range []string{AcquireGranted, AcquireTimeout, AcquireCanceled} {
+		m.LimiterWaitSeconds.WithLabelValues(outcome)
+	}
+
+	return m
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return m.handler
+}
+
+// Registry returns the registry the collectors are registered in.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
@@ -0,0 +1,45 @@
+package openapi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type inner struct {
+	ID string `json:"id"`
+}
+
+type sample struct {
+	inner
+	Name     string            `json:"name"`
+	Count    *int              `json:"count,omitempty"`
+	Ratio    float64           `json:"ratio"`
+	Audio    []byte            `json:"audio"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `json:"labels"`
+	At       time.Time         `json:"at"`
+	Next     *sample           `json:"next,omitempty"`
+	Ignored  string            `json:"-"`
+	internal string
+}
+
+func TestSchemaOf(t *testing.T) {
+	doc := New("test", "1")
+
+	assert.Equal(t, Schema{"$ref": "#/components/schemas/sample"}, doc.SchemaOf(sample{}))
+
+	props := doc.Components.Schemas["sample"]["properties"].(Schema)
+	assert.Equal(t, Schema{"type": "string"}, props["id"])
+	assert.Equal(t, Schema{"type": "integer"}, props["count"])
+	assert.Equal(t, Schema{"type": "number", "format": "double"}, props["ratio"])
+	assert.Equal(t, Schema{"type": "string", "format": "byte"}, props["audio"])
+	assert.Equal(t, Schema{"type": "array", "items": Schema{"type": "string"}}, props["tags"])
+	assert.Equal(t, Schema{"type": "object", "additionalProperties": Schema{"type": "string"}}, props["labels"])
+	assert.Equal(t, Schema{"type": "string", "format": "date-time"}, props["at"])
+	assert.Equal(t, Schema{"$ref": "#/components/schemas/sample"}, props["next"])
+	assert.NotContains(t, props, "Ignored")
+	assert.NotContains(t, props, "internal")
+	assert.Len(t, props, 9)
+}
@@ -0,0 +1,388 @@
+// Package queue runs long-running jobs in the background so that clients can
+// submit work and poll for its result instead of holding a request open.
+// Waiting jobs are picked up in order of priority, then of submission.
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound indicates the job does not exist or its result has expired.
+	ErrNotFound = errors.New("job not found")
+	// ErrQueueFull indicates MaxQueued jobs are already waiting to run, or
+	// for low-priority jobs, the share of the queue they may fill.
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrClosed indicates the manager has been closed.
+	ErrClosed = errors.New("job manager is closed")
+	// ErrResultTooLarge fails a job whose result alone exceeds
+	// MaxRetainedBytes, which could not be kept for the client to fetch.
+	ErrResultTooLarge = errors.New("job result is too large to keep")
+)
+
+// State is the lifecycle stage of a job.
+type State string
+
+// Job states. A job moves from queued to running to done or failed.
+const (
+	StateQueued  State = "queued"
+	StateRunning State = "running"
+	StateDone    State = "done"
+	StateFailed  State = "failed"
+)
+
+// Finished reports whether s is a final state.
+func (s State) Finished() bool {
+	return s == StateDone || s == StateFailed
+}
+
+// Priority orders waiting jobs. Higher priorities are always picked up before
+// lower ones; equal priorities in submission order.
+type Priority int
+
+// Priorities, from most to least urgent.
+const (
+	PriorityHigh Priority = iota
+	PriorityNormal
+	PriorityLow
+
+	numPriorities
+)
+
+// Job describes a submitted job.
+type Job struct {
+	ID string
+	// Owner is the namespace of the caller that submitted the job; only
+	// callers in the same namespace can see it.
+	Owner    string
+	Priority Priority
+	State    State
+	// Error is set when the job failed.
+	Error      string
+	CreatedAt  time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+	// ExpiresAt is when a finished job and its result are forgotten, unless
+	// MaxRetainedBytes forgets it sooner.
+	ExpiresAt time.Time
+	// ResultSHA256 is the hex-encoded SHA-256 digest of the result data of a
+	// done job.
+	ResultSHA256 string
+}
+
+// Result is the output of a successful job.
+type Result struct {
+	Data   []byte
+	Format string
+}
+
+// Func does the work of a job. Its context is cancelled when the manager is
+// closed.
+type Func func(ctx context.Context) (Result, error)
+
+// Config configures a Manager.
+type Config struct {
+	// Workers is the number of jobs run at once (default 1).
+	Workers int
+	// MaxQueued bounds the jobs waiting for a worker (default 100).
+	MaxQueued int
+	// LowQueueRatio is the fraction of MaxQueued above which low-priority
+	// jobs are refused, keeping room for more urgent ones (default 1, no
+	// reserve).
+	LowQueueRatio float64
+	// Retention is how long a finished job and its result are kept (default
+	// one hour).
+	Retention time.Duration
+	// MaxRetainedBytes bounds the result data kept for done jobs; beyond it
+	// the oldest finished jobs are forgotten before their retention passes
+	// (0 = unlimited).
+	MaxRetainedBytes int64
+}
+
+type entry struct {
+	Job
+	fn     Func
+	result Result
+	// finished is called once the job is done or failed.
+	finished func()
+}
+
+// Manager queues jobs and runs them on a fixed pool of workers, keeping
+// finished jobs and their results in memory for the retention period. It is
+// safe for concurrent use.
+type Manager struct {
+	cfg Config
+	// ready holds a token for every waiting job, waking a worker.
+	ready  chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	jobs    map[string]*entry
+	pending [numPriorities][]*entry
+	queued  int
+	// retained is the size of the results of done jobs still kept.
+	retained int64
+	closed   bool
+}
+
+// New returns a Manager and starts its workers.
+func New(cfg Config) *Manager {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.MaxQueued <= 0 {
+		cfg.MaxQueued = 100
+	}
+	if cfg.LowQueueRatio <= 0 || cfg.LowQueueRatio > 1 {
+		cfg.LowQueueRatio = 1
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = time.Hour
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{
+		cfg:    cfg,
+		ready:  make(chan struct{}, cfg.MaxQueued),
+		ctx:    ctx,
+		cancel: cancel,
+		jobs:   make(map[string]*entry),
+	}
+	for i := 0; i < cfg.Workers; i++ {
+		m.wg.Add(1)
+		go m.work()
+	}
+	return m
+}
+
+// Submit queues fn as a job owned by owner at normal priority.
+func (m *Manager) Submit(owner string, fn Func) (Job, error) {
+	return m.SubmitPriority(owner, PriorityNormal, fn, nil)
+}
+
+// SubmitPriority queues fn as a job owned by owner at priority. If the job is
+// accepted, finished, when not nil, is called once it is done or failed,
+// including when Close fails it without running fn.
+func (m *Manager) SubmitPriority(owner string, priority Priority, fn Func, finished func()) (Job, error) {
+	if priority < PriorityHigh || priority >= numPriorities {
+		priority = PriorityNormal
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return Job{}, err
+	}
+	e := &entry{
+		Job: Job{
+			ID:        hex.EncodeToString(id),
+			Owner:     owner,
+			Priority:  priority,
+			State:     StateQueued,
+			CreatedAt: time.Now(),
+		},
+		fn:       fn,
+		finished: finished,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return Job{}, ErrClosed
+	}
+	m.sweepLocked(time.Now())
+	limit := m.cfg.MaxQueued
+	if priority == PriorityLow {
+		limit = int(m.cfg.LowQueueRatio * float64(m.cfg.MaxQueued))
+	}
+	if m.queued >= limit {
+		return Job{}, ErrQueueFull
+	}
+	m.pending[priority] = append(m.pending[priority], e)
+	m.queued++
+	// Never blocks: ready holds at most one token per waiting job.
+	m.ready <- struct{}{}
+	m.jobs[e.ID] = e
+	return e.Job, nil
+}
+
+// Get returns the job with id.
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.lookupLocked(id)
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return e.Job, nil
+}
+
+// Result returns the job with id and, once it is done, its result.
+func (m *Manager) Result(id string) (Job, Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.lookupLocked(id)
+	if !ok {
+		return Job{}, Result{}, ErrNotFound
+	}
+	return e.Job, e.result, nil
+}
+
+// Close stops the workers, cancelling running jobs and failing queued ones,
+// and waits for them to return.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	m.mu.Unlock()
+
+	m.cancel()
+	m.wg.Wait()
+
+	var finished []func()
+	m.mu.Lock()
+	for e := m.nextLocked(); e != nil; e = m.nextLocked() {
+		finished = append(finished, m.finishLocked(e, Result{}, ErrClosed))
+	}
+	m.mu.Unlock()
+	for _, fn := range finished {
+		fn()
+	}
+}
+
+func (m *Manager) work() {
+	defer m.wg.Done()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-m.ready:
+			m.run()
+		}
+	}
+}
+
+// nextLocked removes and returns the most urgent waiting job, or nil.
+func (m *Manager) nextLocked() *entry {
+	for p := range m.pending {
+		if q := m.pending[p]; len(q) > 0 {
+			e := q[0]
+			q[0] = nil
+			m.pending[p] = q[1:]
+			m.queued--
+			return e
+		}
+	}
+	return nil
+}
+
+func (m *Manager) run() {
+	m.mu.Lock()
+	e := m.nextLocked()
+	if e == nil {
+		m.mu.Unlock()
+		return
+	}
+	if m.ctx.Err() != nil {
+		// Closed while the job was being picked up.
+		finished := m.finishLocked(e, Result{}, ErrClosed)
+		m.mu.Unlock()
+		finished()
+		return
+	}
+	e.State = StateRunning
+	e.StartedAt = time.Now()
+	m.mu.Unlock()
+
+	result, err := e.fn(m.ctx)
+	var sum string
+	if err == nil {
+		// Hashed before taking the lock, results can be large.
+		digest := sha256.Sum256(result.Data)
+		sum = hex.EncodeToString(digest[:])
+	}
+
+	m.mu.Lock()
+	e.ResultSHA256 = sum
+	finished := m.finishLocked(e, result, err)
+	m.mu.Unlock()
+	finished()
+}
+
+// finishLocked records the outcome of a job and returns its finished
+// callback, to be called once m.mu is released.
+func (m *Manager) finishLocked(e *entry, result Result, err error) func() {
+	e.FinishedAt = time.Now()
+	e.ExpiresAt = e.FinishedAt.Add(m.cfg.Retention)
+	e.fn = nil
+	finished := e.finished
+	e.finished = nil
+	if finished == nil {
+		finished = func() {}
+	}
+	if err == nil && m.cfg.MaxRetainedBytes > 0 && int64(len(result.Data)) > m.cfg.MaxRetainedBytes {
+		err = fmt.Errorf("%w: %d bytes, the limit is %d", ErrResultTooLarge, len(result.Data), m.cfg.MaxRetainedBytes)
+	}
+	if err != nil {
+		e.State = StateFailed
+		e.Error = err.Error()
+		e.ResultSHA256 = ""
+		return finished
+	}
+	e.State = StateDone
+	e.result = result
+	m.retained += int64(len(result.Data))
+	m.evictLocked(e)
+	return finished
+}
+
+// evictLocked forgets the oldest finished jobs other than keep, the job that
+// just finished, until the results kept fit in MaxRetainedBytes.
+func (m *Manager) evictLocked(keep *entry) {
+	for m.cfg.MaxRetainedBytes > 0 && m.retained > m.cfg.MaxRetainedBytes {
+		var oldest *entry
+		for _, e := range m.jobs {
+			if e != keep && e.State == StateDone && len(e.result.Data) > 0 && (oldest == nil || e.FinishedAt.Before(oldest.FinishedAt)) {
+				oldest = e
+			}
+		}
+		if oldest == nil {
+			return
+		}
+		m.forgetLocked(oldest)
+	}
+}
+
+// forgetLocked removes a finished job and its result.
+func (m *Manager) forgetLocked(e *entry) {
+	delete(m.jobs, e.ID)
+	m.retained -= int64(len(e.result.Data))
+}
+
+// lookupLocked returns the job with id unless it has expired.
+func (m *Manager) lookupLocked(id string) (*entry, bool) {
+	e, ok := m.jobs[id]
+	if !ok || (e.State.Finished() && time.Now().After(e.ExpiresAt)) {
+		return nil, false
+	}
+	return e, true
+}
+
+// sweepLocked forgets finished jobs whose retention has passed.
+func (m *Manager) sweepLocked(now time.Time) {
+	for _, e := range m.jobs {
+		if e.State.Finished() && now.After(e.ExpiresAt) {
+			m.forgetLocked(e)
+		}
+	}
+}
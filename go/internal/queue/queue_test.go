@@ -0,0 +1,265 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitFinished polls until the job with id is done or failed.
+func waitFinished(t *testing.T, m *Manager, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		job, err := m.Get(id)
+		require.NoError(t, err)
+		if job.State.Finished() {
+			return job
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return Job{}
+}
+
+func TestManager_Lifecycle(t *testing.T) {
+	m := New(Config{Workers: 1})
+	defer m.Close()
+
+	start := make(chan struct{})
+	job, err := m.Submit("ns", func(ctx context.Context) (Result, error) {
+		<-start
+		return Result{Data: []byte("audio"), Format: "wav"}, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ns", job.Owner)
+	assert.Len(t, job.ID, 32)
+
+	for {
+		job, err = m.Get(job.ID)
+		require.NoError(t, err)
+		if job.State == StateRunning {
+			break
+		}
+		assert.Equal(t, StateQueued, job.State)
+		time.Sleep(time.Millisecond)
+	}
+	_, result, err := m.Result(job.ID)
+	require.NoError(t, err)
+	assert.Nil(t, result.Data)
+
+	close(start)
+	job = waitFinished(t, m, job.ID)
+	assert.Equal(t, StateDone, job.State)
+	assert.False(t, job.StartedAt.IsZero())
+	assert.Equal(t, time.Hour, job.ExpiresAt.Sub(job.FinishedAt))
+	assert.Equal(t, "6ed8919ce20490a5e3ad8630a4fab69475297abd07db73918dd5f36fcfaeb11b", job.ResultSHA256, "SHA-256 of the result")
+	_, result, err = m.Result(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Data: []byte("audio"), Format: "wav"}, result)
+
+	_, err = m.Get("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestManager_Failed(t *testing.T) {
+	m := New(Config{})
+	defer m.Close()
+
+	job, err := m.Submit("", func(ctx context.Context) (Result, error) {
+		return Result{}, errors.New("backend error")
+	})
+	require.NoError(t, err)
+	job = waitFinished(t, m, job.ID)
+	assert.Equal(t, StateFailed, job.State)
+	assert.Equal(t, "backend error", job.Error)
+}
+
+func TestManager_QueueFull(t *testing.T) {
+	m := New(Config{Workers: 1, MaxQueued: 1})
+	block := make(chan struct{})
+	defer func() {
+		close(block)
+		m.Close()
+	}()
+	blocked := func(ctx context.Context) (Result, error) {
+		<-block
+		return Result{}, nil
+	}
+
+	running, err := m.Submit("", blocked)
+	require.NoError(t, err)
+	for job, _ := m.Get(running.ID); job.State != StateRunning; job, _ = m.Get(running.ID) {
+		time.Sleep(time.Millisecond)
+	}
+	_, err = m.Submit("", blocked)
+	require.NoError(t, err)
+	_, err = m.Submit("", blocked)
+	assert.ErrorIs(t, err, ErrQueueFull)
+}
+
+func TestManager_Priority(t *testing.T) {
+	m := New(Config{Workers: 1})
+	block := make(chan struct{})
+	defer m.Close()
+
+	running, err := m.Submit("", func(ctx context.Context) (Result, error) {
+		<-block
+		return Result{}, nil
+	})
+	require.NoError(t, err)
+	for job, _ := m.Get(running.ID); job.State != StateRunning; job, _ = m.Get(running.ID) {
+		time.Sleep(time.Millisecond)
+	}
+
+	order := make(chan string, 4)
+	submit := func(name string, p Priority) string {
+		job, err := m.SubmitPriority("", p, func(ctx context.Context) (Result, error) {
+			order <- name
+			return Result{}, nil
+		}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, p, job.Priority)
+		return job.ID
+	}
+	submit("low", PriorityLow)
+	submit("normal", PriorityNormal)
+	submit("high 1", PriorityHigh)
+	last := submit("high 2", PriorityHigh)
+
+	close(block)
+	waitFinished(t, m, last)
+	close(order)
+	var got []string
+	for name := range order {
+		got = append(got, name)
+	}
+	assert.Equal(t, []string{"high 1", "high 2", "normal", "low"}, got)
+}
+
+func TestManager_LowQueueRatio(t *testing.T) {
+	m := New(Config{Workers: 1, MaxQueued: 4, LowQueueRatio: 0.5})
+	block := make(chan struct{})
+	defer func() {
+		close(block)
+		m.Close()
+	}()
+	blocked := func(ctx context.Context) (Result, error) {
+		<-block
+		return Result{}, nil
+	}
+
+	running, err := m.Submit("", blocked)
+	require.NoError(t, err)
+	for job, _ := m.Get(running.ID); job.State != StateRunning; job, _ = m.Get(running.ID) {
+		time.Sleep(time.Millisecond)
+	}
+	for i := 0; i < 2; i++ {
+		_, err = m.SubmitPriority("", PriorityLow, blocked, nil)
+		require.NoError(t, err)
+	}
+	_, err = m.SubmitPriority("", PriorityLow, blocked, nil)
+	assert.ErrorIs(t, err, ErrQueueFull, "low priority may only fill half the queue")
+	for i := 0; i < 2; i++ {
+		_, err = m.SubmitPriority("", PriorityHigh, blocked, nil)
+		require.NoError(t, err)
+	}
+	_, err = m.SubmitPriority("", PriorityHigh, blocked, nil)
+	assert.ErrorIs(t, err, ErrQueueFull)
+}
+
+func TestManager_MaxRetainedBytes(t *testing.T) {
+	m := New(Config{MaxRetainedBytes: 10})
+	defer m.Close()
+
+	submit := func(data string) string {
+		job, err := m.Submit("", func(ctx context.Context) (Result, error) {
+			return Result{Data: []byte(data)}, nil
+		})
+		require.NoError(t, err)
+		waitFinished(t, m, job.ID)
+		return job.ID
+	}
+	first := submit("123456")
+	second := submit("1234")
+	_, err := m.Get(first)
+	require.NoError(t, err, "10 bytes fit")
+
+	third := submit("12")
+	_, err = m.Get(first)
+	assert.ErrorIs(t, err, ErrNotFound, "the oldest result is evicted")
+	for _, id := range []string{second, third} {
+		_, result, err := m.Result(id)
+		require.NoError(t, err)
+		assert.NotEmpty(t, result.Data)
+	}
+
+	// A result larger than the limit fails its job rather than evicting it.
+	job, err := m.Submit("", func(ctx context.Context) (Result, error) {
+		return Result{Data: []byte("12345678901")}, nil
+	})
+	require.NoError(t, err)
+	job = waitFinished(t, m, job.ID)
+	assert.Equal(t, StateFailed, job.State)
+	assert.Contains(t, job.Error, ErrResultTooLarge.Error())
+	assert.Empty(t, job.ResultSHA256)
+	for _, id := range []string{second, third} {
+		_, err := m.Get(id)
+		assert.NoError(t, err, "other results are kept")
+	}
+}
+
+func TestManager_Retention(t *testing.T) {
+	m := New(Config{Retention: 10 * time.Millisecond})
+	defer m.Close()
+
+	job, err := m.Submit("", func(ctx context.Context) (Result, error) { return Result{}, nil })
+	require.NoError(t, err)
+	waitFinished(t, m, job.ID)
+
+	time.Sleep(20 * time.Millisecond)
+	_, err = m.Get(job.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, _, err = m.Result(job.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestManager_Close(t *testing.T) {
+	m := New(Config{Workers: 1})
+
+	running, err := m.Submit("", func(ctx context.Context) (Result, error) {
+		<-ctx.Done()
+		return Result{}, ctx.Err()
+	})
+	require.NoError(t, err)
+	for job, _ := m.Get(running.ID); job.State != StateRunning; job, _ = m.Get(running.ID) {
+		time.Sleep(time.Millisecond)
+	}
+	queued, err := m.Submit("", func(ctx context.Context) (Result, error) { return Result{}, nil })
+	require.NoError(t, err)
+
+	finished := make(chan struct{})
+	_, err = m.SubmitPriority("", PriorityNormal, func(ctx context.Context) (Result, error) {
+		t.Error("job run after Close")
+		return Result{}, nil
+	}, func() { close(finished) })
+	require.NoError(t, err)
+
+	m.Close()
+	select {
+	case <-finished:
+	default:
+		t.Fatal("finished not called for a queued job failed by Close")
+	}
+	for _, id := range []string{running.ID, queued.ID} {
+		job, err := m.Get(id)
+		require.NoError(t, err)
+		assert.Equal(t, StateFailed, job.State)
+	}
+	_, err = m.Submit("", func(ctx context.Context) (Result, error) { return Result{}, nil })
+	assert.ErrorIs(t, err, ErrClosed)
+}
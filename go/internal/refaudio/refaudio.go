@@ -0,0 +1,119 @@
+// Package refaudio keeps a copy of the audio and transcript of each reference
+// on disk. The backend stores references but cannot return them, so these
+// copies are what reference exports are made of.
+package refaudio
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotFound indicates no copy is kept for the reference.
+var ErrNotFound = errors.New("reference audio not found")
+
+// Store keeps reference audio in a directory, as an audio file and a
+// transcript file per reference. It is safe for concurrent use: each file is
+// replaced atomically.
+type Store struct {
+	dir string
+}
+
+// Open returns a Store in dir, creating it if needed.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create reference audio directory: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Put stores the audio and transcript of the reference id, replacing any
+// previous copy.
+func (s *Store) Put(id string, audio []byte, text string) error {
+	if err := s.write(s.audioPath(id), audio); err != nil {
+		return err
+	}
+	return s.write(s.textPath(id), []byte(text))
+}
+
+// SetText replaces the transcript of the reference id, keeping its audio.
+func (s *Store) SetText(id, text string) error {
+	if !s.Has(id) {
+		return ErrNotFound
+	}
+	return s.write(s.textPath(id), []byte(text))
+}
+
+// Text returns the transcript of the reference id.
+func (s *Store) Text(id string) (string, error) {
+	text, err := os.ReadFile(s.textPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read reference transcript: %w", err)
+	}
+	return string(text), nil
+}
+
+// Open opens the audio of the reference id for reading.
+func (s *Store) Open(id string) (*os.File, error) {
+	f, err := os.Open(s.audioPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reference audio: %w", err)
+	}
+	return f, nil
+}
+
+// Has reports whether a copy of the audio of the reference id is kept,
+// without opening it.
+func (s *Store) Has(id string) bool {
+	_, err := os.Stat(s.audioPath(id))
+	return err == nil
+}
+
+// Delete removes the copy of the reference id, if any.
+func (s *Store) Delete(id string) error {
+	for _, path := range []string{s.textPath(id), s.audioPath(id)} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove reference audio: %w", err)
+		}
+	}
+	return nil
+}
+
+// write replaces the file at path atomically.
+func (s *Store) write(path string, data []byte) error {
+	tmp, err := os.CreateTemp(s.dir, ".reference-*")
+	if err != nil {
+		return fmt.Errorf("failed to write reference audio: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write reference audio: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write reference audio: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write reference audio: %w", err)
+	}
+	return nil
+}
+
+// File names are hex encoded so that IDs differing only in case stay apart
+// on case-insensitive file systems.
+func (s *Store) audioPath(id string) string {
+	return filepath.Join(s.dir, hex.EncodeToString([]byte(id))+".audio")
+}
+
+func (s *Store) textPath(id string) string {
+	return filepath.Join(s.dir, hex.EncodeToString([]byte(id))+".txt")
+}
@@ -0,0 +1,60 @@
+package refaudio
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readAudio(t *testing.T, s *Store, id string) string {
+	t.Helper()
+	f, err := s.Open(id)
+	require.NoError(t, err)
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	require.NoError(t, err)
+
+	require.NoError(t, s.Put("Voice", []byte("upper"), "Upper case"))
+	require.NoError(t, s.Put("voice", []byte("lower"), "Lower case"))
+	require.NoError(t, s.Put("voice", []byte("replaced"), "Replaced"))
+
+	reopened, err := Open(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "replaced", readAudio(t, reopened, "voice"))
+	text, err := reopened.Text("voice")
+	require.NoError(t, err)
+	assert.Equal(t, "Replaced", text)
+	assert.Equal(t, "upper", readAudio(t, reopened, "Voice"))
+	assert.True(t, reopened.Has("voice"))
+
+	require.NoError(t, reopened.Delete("voice"))
+	require.NoError(t, reopened.Delete("voice"))
+	_, err = reopened.Open("voice")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = reopened.Text("voice")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.False(t, reopened.Has("voice"))
+}
+
+func TestStore_SetText(t *testing.T) {
+	s, err := Open(t.TempDir())
+	require.NoError(t, err)
+
+	require.NoError(t, s.Put("voice", []byte("audio"), "text"))
+	// A new transcript leaves the audio.
+	require.NoError(t, s.SetText("voice", "new text"))
+	text, err := s.Text("voice")
+	require.NoError(t, err)
+	assert.Equal(t, "new text", text)
+	assert.Equal(t, "audio", readAudio(t, s, "voice"))
+	assert.ErrorIs(t, s.SetText("other", "text"), ErrNotFound)
+}
@@ -0,0 +1,436 @@
+package refstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/audio"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+// ErrNotFound indicates the requested entry does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrNotWritable indicates a persisted store cannot be written.
+var ErrNotWritable = errors.New("reference store not writable")
+
+// Store keeps reference state that the Python backend does not track, such as
+// aliases, voices and metadata. It is safe for concurrent use and optionally persisted to a JSON file.
+type Store struct {
+	mu   sync.RWMutex
+	path string
+	data storeData
+}
+
+// Alias maps a stable name to a reference ID.
+type Alias struct {
+	Name   string
+	Target string
+}
+
+// Record holds Go-side state for a single reference.
+type Record struct {
+	// Locked references can only be deleted or overwritten by an admin using force.
+	Locked bool `json:"locked,omitempty"`
+	// Fingerprint identifies the reference audio for duplicate detection.
+	Fingerprint *Fingerprint `json:"fingerprint,omitempty"`
+	// Metadata describes the reference for clients.
+	Metadata *schema.ReferenceMetadata `json:"metadata,omitempty"`
+}
+
+func (r *Record) empty() bool {
+	return !r.Locked && r.Fingerprint == nil && r.Metadata == nil
+}
+
+// Fingerprint identifies reference audio. SHA256 matches byte-identical uploads;
+// Envelope (see audio.Envelope) matches re-encodings of the same recording.
+type Fingerprint struct {
+	SHA256     string    `json:"sha256"`
+	Envelope   []float32 `json:"envelope,omitempty"`
+	DurationMs int64     `json:"duration_ms,omitempty"`
+}
+
+// Duplicate describes an existing reference whose audio matches a fingerprint.
+type Duplicate struct {
+	ID         string
+	Similarity float64
+}
+
+type storeData struct {
+	Aliases    map[string]string       `json:"aliases"`
+	Voices     map[string]schema.Voice `json:"voices,omitempty"`
+	References map[string]*Record      `json:"references"`
+}
+
+func newStoreData() storeData {
+	return storeData{Aliases: map[string]string{}, Voices: map[string]schema.Voice{}, References: map[string]*Record{}}
+}
+
+func (d storeData) clone() storeData {
+	c := newStoreData()
+	for k, v := range d.Aliases {
+		c.Aliases[k] = v
+	}
+	for k, v := range d.Voices {
+		c.Voices[k] = cloneVoice(v)
+	}
+	for k, v := range d.References {
+		r := *v
+		if v.Fingerprint != nil {
+			fp := *v.Fingerprint
+			r.Fingerprint = &fp
+		}
+		if v.Metadata != nil {
+			md := cloneMetadata(*v.Metadata)
+			r.Metadata = &md
+		}
+		c.References[k] = &r
+	}
+	return c
+}
+
+// New returns an in-memory Store.
+func New() *Store {
+	return &Store{data: newStoreData()}
+}
+
+// Open loads a Store persisted at path, creating it on first write. An empty
+// path returns an in-memory Store.
+func Open(path string) (*Store, error) {
+	s := New()
+	if path == "" {
+		return s, nil
+	}
+	s.path = path
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reference store: %w", err)
+	}
+	if err := json.Unmarshal(raw, &s.data); err != nil {
+		return nil, fmt.Errorf("failed to parse reference store: %w", err)
+	}
+	if s.data.Aliases == nil {
+		s.data.Aliases = map[string]string{}
+	}
+	if s.data.Voices == nil {
+		s.data.Voices = map[string]schema.Voice{}
+	}
+	if s.data.References == nil {
+		s.data.References = map[string]*Record{}
+	}
+	return s, nil
+}
+
+// SetAlias points alias at the reference target, replacing any previous target.
+func (s *Store) SetAlias(alias, target string) error {
+	return s.update(func(d *storeData) error {
+		d.Aliases[alias] = target
+		return nil
+	})
+}
+
+// DeleteAlias removes alias, returning ErrNotFound if it does not exist.
+func (s *Store) DeleteAlias(alias string) error {
+	return s.update(func(d *storeData) error {
+		if _, ok := d.Aliases[alias]; !ok {
+			return ErrNotFound
+		}
+		delete(d.Aliases, alias)
+		return nil
+	})
+}
+
+// Alias returns the target of alias and whether it exists.
+func (s *Store) Alias(alias string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	target, ok := s.data.Aliases[alias]
+	return target, ok
+}
+
+// Aliases returns a snapshot of all aliases sorted by name.
+func (s *Store) Aliases() []Alias {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	aliases := make([]Alias, 0, len(s.data.Aliases))
+	for name, target := range s.data.Aliases {
+		aliases = append(aliases, Alias{Name: name, Target: target})
+	}
+	sort.Slice(aliases, func(i, j int) bool { return aliases[i].Name < aliases[j].Name })
+	return aliases
+}
+
+// Resolve returns the reference ID that id refers to, following an alias if one exists.
+func (s *Store) Resolve(id string) string {
+	if target, ok := s.Alias(id); ok {
+		return target
+	}
+	return id
+}
+
+// SetVoice stores v under its name, replacing any previous voice of that name.
+func (s *Store) SetVoice(v schema.Voice) error {
+	v = cloneVoice(v)
+	return s.update(func(d *storeData) error {
+		d.Voices[v.Name] = v
+		return nil
+	})
+}
+
+// DeleteVoice removes the voice name, returning ErrNotFound if it does not exist.
+func (s *Store) DeleteVoice(name string) error {
+	return s.update(func(d *storeData) error {
+		if _, ok := d.Voices[name]; !ok {
+			return ErrNotFound
+		}
+		delete(d.Voices, name)
+		return nil
+	})
+}
+
+// Voice returns the voice name and whether it exists.
+func (s *Store) Voice(name string) (schema.Voice, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, ok := s.data.Voices[name]
+	return cloneVoice(v), ok
+}
+
+// Voices returns a snapshot of all voices sorted by name.
+func (s *Store) Voices() []schema.Voice {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	voices := make([]schema.Voice, 0, len(s.data.Voices))
+	for _, v := range s.data.Voices {
+		voices = append(voices, cloneVoice(v))
+	}
+	sort.Slice(voices, func(i, j int) bool { return voices[i].Name < voices[j].Name })
+	return voices
+}
+
+// cloneVoice copies v so that it shares no memory with the original.
+func cloneVoice(v schema.Voice) schema.Voice {
+	if v.Seed != nil {
+		seed := *v.Seed
+		v.Seed = &seed
+	}
+	return v
+}
+
+// SetLocked sets or clears the locked flag on the reference id.
+func (s *Store) SetLocked(id string, locked bool) error {
+	return s.update(func(d *storeData) error {
+		d.recordFor(id).Locked = locked
+		d.compact(id)
+		return nil
+	})
+}
+
+// IsLocked reports whether the reference id is locked.
+func (s *Store) IsLocked(id string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.data.References[id]
+	return ok && r.Locked
+}
+
+// SetFingerprint records the audio fingerprint of the reference id.
+func (s *Store) SetFingerprint(id string, fp Fingerprint) error {
+	return s.update(func(d *storeData) error {
+		d.recordFor(id).Fingerprint = &fp
+		return nil
+	})
+}
+
+// SetMetadata replaces the metadata of the reference id.
+func (s *Store) SetMetadata(id string, md schema.ReferenceMetadata) error {
+	md = cloneMetadata(md)
+	return s.update(func(d *storeData) error {
+		d.recordFor(id).Metadata = &md
+		return nil
+	})
+}
+
+// Metadata returns the metadata of the reference id and whether it has any.
+func (s *Store) Metadata(id string) (schema.ReferenceMetadata, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.data.References[id]
+	if !ok || r.Metadata == nil {
+		return schema.ReferenceMetadata{}, false
+	}
+	return cloneMetadata(*r.Metadata), true
+}
+
+// cloneMetadata copies md so that it shares no memory with the original.
+func cloneMetadata(md schema.ReferenceMetadata) schema.ReferenceMetadata {
+	if md.Tags != nil {
+		md.Tags = append([]string(nil), md.Tags...)
+	}
+	if md.CreatedAt != nil {
+		t := *md.CreatedAt
+		md.CreatedAt = &t
+	}
+	return md
+}
+
+// FindDuplicate returns the most similar reference accepted by include whose
+// fingerprint matches fp with at least the given similarity. Identical bytes
+// always match with similarity 1.
+func (s *Store) FindDuplicate(fp Fingerprint, threshold float64, include func(id string) bool) (Duplicate, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var best Duplicate
+	found := false
+	for id, r := range s.data.References {
+		if r.Fingerprint == nil || !include(id) {
+			continue
+		}
+
+		similarity := fingerprintSimilarity(fp, *r.Fingerprint)
+		if similarity < threshold {
+			continue
+		}
+		if !found || similarity > best.Similarity || (similarity == best.Similarity && id < best.ID) {
+			best = Duplicate{ID: id, Similarity: similarity}
+			found = true
+		}
+	}
+	return best, found
+}
+
+func fingerprintSimilarity(a, b Fingerprint) float64 {
+	if a.SHA256 != "" && a.SHA256 == b.SHA256 {
+		return 1
+	}
+	if len(a.Envelope) == 0 || len(a.Envelope) != len(b.Envelope) {
+		return 0
+	}
+
+	longer, shorter := a.DurationMs, b.DurationMs
+	if shorter > longer {
+		longer, shorter = shorter, longer
+	}
+	if longer == 0 || float64(longer-shorter) > 0.02*float64(longer) {
+		return 0
+	}
+
+	return audio.CosineSimilarity(a.Envelope, b.Envelope)
+}
+
+// Forget drops all Go-side state for the reference id, typically after it is deleted.
+func (s *Store) Forget(id string) error {
+	s.mu.RLock()
+	_, ok := s.data.References[id]
+	s.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	return s.update(func(d *storeData) error {
+		delete(d.References, id)
+		return nil
+	})
+}
+
+func (d *storeData) recordFor(id string) *Record {
+	r, ok := d.References[id]
+	if !ok {
+		r = &Record{}
+		d.References[id] = r
+	}
+	return r
+}
+
+// compact drops the record for id when it no longer carries any state.
+func (d *storeData) compact(id string) {
+	if r, ok := d.References[id]; ok && r.empty() {
+		delete(d.References, id)
+	}
+}
+
+// update applies fn and persists the result, rolling back on failure.
+func (s *Store) update(fn func(*storeData) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := s.data.clone()
+	if err := fn(&s.data); err != nil {
+		s.data = previous
+		return err
+	}
+	if err := s.saveLocked(); err != nil {
+		s.data = previous
+		return err
+	}
+	return nil
+}
+
+// saveLocked persists the store atomically. Callers must hold s.mu.
+func (s *Store) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	raw, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode reference store: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".refstore-*")
+	if err != nil {
+		return fmt.Errorf("failed to write reference store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write reference store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write reference store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write reference store: %w", err)
+	}
+	return nil
+}
+
+// Check verifies that a persisted store's directory is reachable and writable by
+// creating and removing a probe file. In-memory stores are always healthy.
+func (s *Store) Check() error {
+	if s.path == "" {
+		return nil
+	}
+
+	dir := filepath.Dir(s.path)
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("reference store unreachable: %w", err)
+	}
+	probe, err := os.CreateTemp(dir, ".refstore-probe-*")
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotWritable, err)
+	}
+	name := probe.Name()
+	probe.Close()
+	if err := os.Remove(name); err != nil {
+		return fmt.Errorf("%w: %v", ErrNotWritable, err)
+	}
+	return nil
+}
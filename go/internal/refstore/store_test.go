@@ -0,0 +1,148 @@
+package refstore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func TestAliases(t *testing.T) {
+	s := New()
+
+	require.NoError(t, s.SetAlias("narrator-prod", "voice-v3"))
+	assert.Equal(t, "voice-v3", s.Resolve("narrator-prod"))
+	assert.Equal(t, "voice-v2", s.Resolve("voice-v2"))
+
+	require.NoError(t, s.SetAlias("narrator-prod", "voice-v4"))
+	assert.Equal(t, []Alias{{Name: "narrator-prod", Target: "voice-v4"}}, s.Aliases())
+
+	require.NoError(t, s.DeleteAlias("narrator-prod"))
+	assert.ErrorIs(t, s.DeleteAlias("narrator-prod"), ErrNotFound)
+	assert.Empty(t, s.Aliases())
+}
+
+func TestVoices(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "references.json")
+	s, err := Open(path)
+	require.NoError(t, err)
+
+	seed := 42
+	require.NoError(t, s.SetVoice(schema.Voice{Name: "narrator", ReferenceID: "voice-v1", Seed: &seed}))
+	require.NoError(t, s.SetVoice(schema.Voice{Name: "narrator", ReferenceID: "voice-v2", Temperature: 0.6}))
+	require.NoError(t, s.SetVoice(schema.Voice{Name: "announcer", ReferenceID: "voice-v1"}))
+
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	v, ok := reopened.Voice("narrator")
+	require.True(t, ok)
+	assert.Equal(t, schema.Voice{Name: "narrator", ReferenceID: "voice-v2", Temperature: 0.6}, v)
+	voices := reopened.Voices()
+	require.Len(t, voices, 2)
+	assert.Equal(t, "announcer", voices[0].Name)
+
+	require.NoError(t, reopened.DeleteVoice("narrator"))
+	assert.ErrorIs(t, reopened.DeleteVoice("narrator"), ErrNotFound)
+	_, ok = reopened.Voice("narrator")
+	assert.False(t, ok)
+}
+
+func TestOpen_Persists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "references.json")
+
+	s, err := Open(path)
+	require.NoError(t, err)
+	require.NoError(t, s.SetAlias("narrator", "voice-1"))
+
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	assert.Equal(t, "voice-1", reopened.Resolve("narrator"))
+}
+
+func TestLocks(t *testing.T) {
+	s := New()
+
+	require.NoError(t, s.SetLocked("voice", true))
+	assert.True(t, s.IsLocked("voice"))
+	assert.False(t, s.IsLocked("other"))
+
+	require.NoError(t, s.Forget("voice"))
+	assert.False(t, s.IsLocked("voice"))
+}
+
+func TestMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "references.json")
+	s, err := Open(path)
+	require.NoError(t, err)
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tags := []string{"warm", "narration"}
+	require.NoError(t, s.SetMetadata("voice", schema.ReferenceMetadata{DisplayName: "Narrator", Tags: tags, CreatedAt: &created}))
+	tags[0] = "changed"
+
+	md, ok := s.Metadata("voice")
+	require.True(t, ok)
+	assert.Equal(t, "Narrator", md.DisplayName)
+	assert.Equal(t, []string{"warm", "narration"}, md.Tags)
+	_, ok = s.Metadata("other")
+	assert.False(t, ok)
+
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	md, ok = reopened.Metadata("voice")
+	require.True(t, ok)
+	assert.True(t, created.Equal(*md.CreatedAt))
+
+	require.NoError(t, reopened.Forget("voice"))
+	_, ok = reopened.Metadata("voice")
+	assert.False(t, ok)
+}
+
+func TestFindDuplicate(t *testing.T) {
+	s := New()
+	envelope := []float32{0.6, 0.8}
+	require.NoError(t, s.SetFingerprint("exact", Fingerprint{SHA256: "abc"}))
+	require.NoError(t, s.SetFingerprint("similar", Fingerprint{SHA256: "def", Envelope: envelope, DurationMs: 1000}))
+	require.NoError(t, s.SetFingerprint("other-ns__similar", Fingerprint{SHA256: "def", Envelope: envelope, DurationMs: 1000}))
+	all := func(string) bool { return true }
+
+	dup, ok := s.FindDuplicate(Fingerprint{SHA256: "abc"}, 0.99, all)
+	require.True(t, ok)
+	assert.Equal(t, Duplicate{ID: "exact", Similarity: 1}, dup)
+
+	dup, ok = s.FindDuplicate(Fingerprint{SHA256: "xyz", Envelope: []float32{0.6, 0.8}, DurationMs: 1010}, 0.99, func(id string) bool { return id == "similar" })
+	require.True(t, ok)
+	assert.Equal(t, "similar", dup.ID)
+
+	_, ok = s.FindDuplicate(Fingerprint{SHA256: "xyz", Envelope: envelope, DurationMs: 2000}, 0.99, all)
+	assert.False(t, ok)
+}
+
+func TestCheck(t *testing.T) {
+	assert.NoError(t, New().Check())
+
+	dir := t.TempDir()
+	s, err := Open(filepath.Join(dir, "references.json"))
+	require.NoError(t, err)
+	assert.NoError(t, s.Check())
+
+	s, err = Open(filepath.Join(dir, "missing", "references.json"))
+	require.NoError(t, err)
+	err = s.Check()
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNotWritable)
+
+	// A regular file in place of the directory is reachable but not writable.
+	sub := filepath.Join(dir, "sub")
+	require.NoError(t, os.Mkdir(sub, 0o755))
+	s, err = Open(filepath.Join(sub, "references.json"))
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(sub))
+	require.NoError(t, os.WriteFile(sub, nil, 0o644))
+	assert.ErrorIs(t, s.Check(), ErrNotWritable)
+}
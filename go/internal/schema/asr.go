@@ -0,0 +1,22 @@
+package schema
+
+// ServeASRRequest represents a request to transcribe audio. Audios hold
+// uncompressed mono PCM float16 samples at SampleRate.
+type ServeASRRequest struct {
+	Audios           [][]byte `json:"audios" msgpack:"audios"`
+	SampleRate       int      `json:"sample_rate" msgpack:"sample_rate"`
+	Language         string   `json:"language" msgpack:"language"`
+	IgnoreTimestamps bool     `json:"ignore_timestamps" msgpack:"ignore_timestamps"`
+}
+
+// ServeASRTranscription represents the transcription of a single audio.
+type ServeASRTranscription struct {
+	Text     string  `json:"text" msgpack:"text"`
+	Duration float64 `json:"duration" msgpack:"duration"`
+	HugeGap  bool    `json:"huge_gap" msgpack:"huge_gap"`
+}
+
+// ServeASRResponse represents the transcriptions returned by the ASR endpoint.
+type ServeASRResponse struct {
+	Transcriptions []ServeASRTranscription `json:"transcriptions" msgpack:"transcriptions"`
+}
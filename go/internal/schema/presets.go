@@ -0,0 +1,63 @@
+package schema
+
+// Preset is a named set of synthesis parameters, defined in the server
+// configuration, that TTS requests select with preset instead of repeating
+// tuning values in every client.
+type Preset struct {
+	Name string `json:"name" msgpack:"name"`
+	// Voice or ReferenceID is the voice, or reference, of requests that name
+	// neither.
+	Voice       string `json:"voice,omitempty" msgpack:"voice,omitempty"`
+	ReferenceID string `json:"reference_id,omitempty" msgpack:"reference_id,omitempty"`
+
+	Format            string  `json:"format,omitempty" msgpack:"format,omitempty"`
+	Model             string  `json:"model,omitempty" msgpack:"model,omitempty"`
+	ChunkLength       int     `json:"chunk_length,omitempty" msgpack:"chunk_length,omitempty"`
+	MaxNewTokens      int     `json:"max_new_tokens,omitempty" msgpack:"max_new_tokens,omitempty"`
+	TopP              float64 `json:"top_p,omitempty" msgpack:"top_p,omitempty"`
+	RepetitionPenalty float64 `json:"repetition_penalty,omitempty" msgpack:"repetition_penalty,omitempty"`
+	Temperature       float64 `json:"temperature,omitempty" msgpack:"temperature,omitempty"`
+	Seed              *int    `json:"seed,omitempty" msgpack:"seed,omitempty"`
+	Speed             float64 `json:"speed,omitempty" msgpack:"speed,omitempty"`
+}
+
+// Apply fills in the parameters req leaves unset from the preset, and its
+// voice or reference when req names neither. Speed is not applied to
+// streaming requests, which cannot be time-stretched.
+func (p *Preset) Apply(req *ServeTTSRequest) {
+	if req.Voice == "" && req.ReferenceID == nil && len(req.References) == 0 {
+		req.Voice = p.Voice
+		if p.ReferenceID != "" {
+			id := p.ReferenceID
+			req.ReferenceID = &id
+		}
+	}
+	if req.Format == "" {
+		req.Format = p.Format
+	}
+	if req.Model == "" {
+		req.Model = p.Model
+	}
+	if req.ChunkLength == 0 {
+		req.ChunkLength = p.ChunkLength
+	}
+	if req.MaxNewTokens == 0 {
+		req.MaxNewTokens = p.MaxNewTokens
+	}
+	if req.TopP == 0 {
+		req.TopP = p.TopP
+	}
+	if req.RepetitionPenalty == 0 {
+		req.RepetitionPenalty = p.RepetitionPenalty
+	}
+	if req.Temperature == 0 {
+		req.Temperature = p.Temperature
+	}
+	if req.Seed == nil && p.Seed != nil {
+		seed := *p.Seed
+		req.Seed = &seed
+	}
+	if req.Speed == 0 && !req.Streaming {
+		req.Speed = p.Speed
+	}
+}
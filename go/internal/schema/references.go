@@ -1,10 +1,36 @@
 package schema
 
+import "time"
+
 // AddReferenceRequest represents a request to add a new voice reference.
 type AddReferenceRequest struct {
 	ID    string `json:"id" msgpack:"id"`
 	Audio []byte `json:"audio" msgpack:"audio"`
 	Text  string `json:"text" msgpack:"text"`
+
+	// Go-side metadata, kept by the Go server and not sent to the backend.
+	ReferenceMetadata
+}
+
+// ReferenceMetadata describes a voice reference for voice pickers and catalogs.
+type ReferenceMetadata struct {
+	DisplayName string `json:"display_name,omitempty" msgpack:"display_name,omitempty"`
+	// Language is the language of the reference audio, e.g. "en" or "pt-BR".
+	Language string `json:"language,omitempty" msgpack:"language,omitempty"`
+	// Gender is "female", "male" or "neutral".
+	Gender      string   `json:"gender,omitempty" msgpack:"gender,omitempty"`
+	Description string   `json:"description,omitempty" msgpack:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty" msgpack:"tags,omitempty"`
+	// CreatedAt is when the reference was added; the server sets it when empty.
+	CreatedAt *time.Time `json:"created_at,omitempty" msgpack:"created_at,omitempty"`
+}
+
+// Upstream returns a copy of the request with the Go-side metadata cleared,
+// matching the upstream schema.
+func (r *AddReferenceRequest) Upstream() *AddReferenceRequest {
+	upstream := *r
+	upstream.ReferenceMetadata = ReferenceMetadata{}
+	return &upstream
 }
 
 // AddReferenceResponse represents the response after adding a voice reference.
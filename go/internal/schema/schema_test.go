@@ -2,6 +2,7 @@ package schema
 
 import (
 	"encoding/json"
+	"reflect"
 	"testing"
 
 	"github.com/vmihailenco/msgpack/v5"
@@ -74,9 +75,9 @@ func TestServeTTSRequestValidationErrors(t *testing.T) {
 			expectedError: "repetition_penalty must be between 0. 9 and 2. 0",
 		},
 		{
-			name:          "streaming with non wav format",
-			req:           ServeTTSRequest{Text: "hi", Streaming: true, Format: "mp3"},
-			expectedError: "Streaming only supports WAV format",
+			name:          "unknown format",
+			req:           ServeTTSRequest{Text: "hi", Format: "aac"},
+			expectedError: "format must be one of: wav, pcm, mp3, opus, flac, ulaw, alaw",
 		},
 		{
 			name:          "text too long",
@@ -186,3 +187,93 @@ func TestServeTTSRequestMsgpackTags(t *testing.T) {
 		}
 	}
 }
+
+func TestAddReferenceRequestUpstream(t *testing.T) {
+	req := AddReferenceRequest{
+		ID:    "voice",
+		Audio: []byte("audio"),
+		Text:  "transcript",
+		ReferenceMetadata: ReferenceMetadata{
+			DisplayName: "Voice",
+			Tags:        []string{"calm"},
+		},
+	}
+
+	upstream := req.Upstream()
+	want := AddReferenceRequest{ID: "voice", Audio: []byte("audio"), Text: "transcript"}
+	if !reflect.DeepEqual(*upstream, want) {
+		t.Fatalf("expected metadata cleared, got %+v", *upstream)
+	}
+	if req.DisplayName != "Voice" {
+		t.Fatalf("expected the request itself unchanged")
+	}
+}
+
+func TestServeTTSRequestUpstream(t *testing.T) {
+	quality := 5
+	req := ServeTTSRequest{
+		Text:       "hello",
+		Format:     "opus",
+		Model:      "s1",
+		Language:   "en",
+		Voice:      "narrator",
+		Preset:     "audiobook",
+		SampleRate: 16000,
+		Channels:   "stereo",
+		GainDB:     -3,
+		Bitrate:    64,
+		Quality:    &quality,
+
+		LoudnessTargetLUFS: -16,
+		Speed:              1.25,
+	}
+
+	upstream := req.Upstream()
+	want := ServeTTSRequest{Text: "hello", Format: "opus"}
+	if !reflect.DeepEqual(*upstream, want) {
+		t.Fatalf("expected Go-side options cleared, got %+v", *upstream)
+	}
+	if req.Bitrate != 64 || req.Quality == nil {
+		t.Fatalf("expected the request itself unchanged")
+	}
+}
+
+func TestVoiceApply(t *testing.T) {
+	seed := 7
+	voice := Voice{Name: "narrator", ReferenceID: "narrator-v2", Temperature: 0.5, TopP: 0.9, Seed: &seed, Speed: 1.1}
+
+	req := ServeTTSRequest{Text: "hello", Temperature: 0.7}
+	voice.Apply(&req)
+	if req.ReferenceID == nil || *req.ReferenceID != "narrator-v2" {
+		t.Fatalf("expected the voice's reference, got %v", req.ReferenceID)
+	}
+	if req.Temperature != 0.7 || req.TopP != 0.9 || req.Speed != 1.1 {
+		t.Fatalf("expected unset parameters from the voice, got %+v", req)
+	}
+	if req.Seed == nil || *req.Seed != 7 || req.Seed == voice.Seed {
+		t.Fatalf("expected a copy of the voice's seed, got %v", req.Seed)
+	}
+
+	streaming := ServeTTSRequest{Text: "hello", Streaming: true}
+	voice.Apply(&streaming)
+	if streaming.Speed != 0 {
+		t.Fatalf("expected no speed on a streaming request, got %v", streaming.Speed)
+	}
+}
+
+func TestPresetApply(t *testing.T) {
+	preset := Preset{Name: "audiobook", Voice: "narrator", Format: "mp3", Temperature: 0.6}
+
+	req := ServeTTSRequest{Text: "hello", Format: "wav"}
+	preset.Apply(&req)
+	if req.Voice != "narrator" || req.Format != "wav" || req.Temperature != 0.6 {
+		t.Fatalf("expected unset parameters and the voice from the preset, got %+v", req)
+	}
+
+	id := "other"
+	withReference := ServeTTSRequest{Text: "hello", ReferenceID: &id}
+	preset.Apply(&withReference)
+	if withReference.Voice != "" || *withReference.ReferenceID != "other" {
+		t.Fatalf("expected the request's reference kept, got %+v", withReference)
+	}
+}
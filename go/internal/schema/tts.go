@@ -13,6 +13,13 @@ const (
 	defaultNormalize         = true
 )
 
+// validFormats are the output formats a request may ask for. Opus, FLAC and
+// the G.711 telephony formats are encoded by fish-server, never by the
+// backend.
+var validFormats = map[string]bool{
+	"wav": true, "pcm": true, "mp3": true, "opus": true, "flac": true, "ulaw": true, "alaw": true,
+}
+
 // ServeReferenceAudio represents an inline reference audio payload.
 type ServeReferenceAudio struct {
 	Audio []byte `json:"audio" msgpack:"audio"`
@@ -37,6 +44,67 @@ type ServeTTSRequest struct {
 	UseMemoryCache string `json:"use_memory_cache" msgpack:"use_memory_cache"`
 	Normalize      bool   `json:"normalize" msgpack:"normalize"`
 	Streaming      bool   `json:"streaming" msgpack:"streaming"`
+
+	// Go-side options. These are handled by fish-server and are never forwarded
+	// to the Python backend.
+
+	// Model selects the checkpoint to synthesize with, as named in the server's
+	// backend configuration (empty uses the default backend).
+	Model string `json:"model,omitempty" msgpack:"model,omitempty"`
+	// Language declares the language of Text for backend routing; when empty it
+	// is detected from the script.
+	Language string `json:"language,omitempty" msgpack:"language,omitempty"`
+	// Voice selects a voice, which sets ReferenceID and the defaults of the
+	// parameters left unset; see Voice.
+	Voice string `json:"voice,omitempty" msgpack:"voice,omitempty"`
+	// Preset selects a preset of the server configuration, which sets the
+	// defaults of the parameters left unset; see Preset.
+	Preset string `json:"preset,omitempty" msgpack:"preset,omitempty"`
+
+	// SampleRate resamples the output to the given rate in Hz (0 keeps the backend rate).
+	SampleRate int `json:"sample_rate,omitempty" msgpack:"sample_rate,omitempty"`
+	// Channels selects the output channel layout: "mono" or "stereo" (empty keeps the backend layout).
+	Channels string `json:"channels,omitempty" msgpack:"channels,omitempty"`
+	// GainDB adjusts the output volume in decibels, limited to avoid clipping.
+	GainDB float64 `json:"gain_db,omitempty" msgpack:"gain_db,omitempty"`
+	// LoudnessTargetLUFS normalizes the output to this integrated loudness (EBU R128), e.g. -16 (0 leaves it unchanged).
+	LoudnessTargetLUFS float64 `json:"loudness_target_lufs,omitempty" msgpack:"loudness_target_lufs,omitempty"`
+	// Speed time-stretches the output by this factor without changing its pitch, e.g. 1.25 for faster speech (0 keeps it).
+	Speed float64 `json:"speed,omitempty" msgpack:"speed,omitempty"`
+
+	// Bitrate sets the bit rate of mp3 and opus output in kbit/s (0 uses the server's setting).
+	Bitrate int `json:"bitrate,omitempty" msgpack:"bitrate,omitempty"`
+	// Quality sets the quality of mp3 and opus output from 0 to 10, higher being
+	// better: variable bit rate MP3 in place of Bitrate, or the Opus encoder complexity.
+	Quality *int `json:"quality,omitempty" msgpack:"quality,omitempty"`
+}
+
+// Upstream returns a copy of the request with the Go-side options cleared,
+// matching the upstream schema.
+func (r *ServeTTSRequest) Upstream() *ServeTTSRequest {
+	upstream := *r
+	upstream.Model = ""
+	upstream.Language = ""
+	upstream.Voice = ""
+	upstream.Preset = ""
+	upstream.SampleRate = 0
+	upstream.Channels = ""
+	upstream.GainDB = 0
+	upstream.LoudnessTargetLUFS = 0
+	upstream.Speed = 0
+	upstream.Bitrate = 0
+	upstream.Quality = nil
+	return &upstream
+}
+
+// HasEncodingOptions reports whether the request sets how its audio is encoded.
+func (r *ServeTTSRequest) HasEncodingOptions() bool {
+	return r.Bitrate != 0 || r.Quality != nil
+}
+
+// HasPostProcessing reports whether the request asks for Go-side audio processing.
+func (r *ServeTTSRequest) HasPostProcessing() bool {
+	return r.SampleRate != 0 || r.Channels != "" || r.GainDB != 0 || r.LoudnessTargetLUFS != 0 || r.Speed != 0
 }
 
 // Validate applies default values and validates the request against upstream rules.
@@ -63,8 +131,8 @@ func (r *ServeTTSRequest) Validate(maxTextLength int) error {
 		return fmt.Errorf("repetition_penalty must be between 0. 9 and 2. 0")
 	}
 
-	if r.Streaming && r.Format != "wav" {
-		return fmt.Errorf("Streaming only supports WAV format")
+	if !validFormats[r.Format] {
+		return fmt.Errorf("format must be one of: wav, pcm, mp3, opus, flac, ulaw, alaw")
 	}
 
 	return nil
@@ -0,0 +1,53 @@
+package schema
+
+// Voice names a reference together with default synthesis parameters. TTS
+// requests that ask for the voice synthesize with its reference and take each
+// parameter they leave unset from the voice, so the reference behind a name
+// can change without clients changing.
+type Voice struct {
+	Name string `json:"name" msgpack:"name"`
+	// ReferenceID is the reference, or reference alias, the voice speaks with.
+	ReferenceID string `json:"reference_id" msgpack:"reference_id"`
+
+	Model             string  `json:"model,omitempty" msgpack:"model,omitempty"`
+	ChunkLength       int     `json:"chunk_length,omitempty" msgpack:"chunk_length,omitempty"`
+	MaxNewTokens      int     `json:"max_new_tokens,omitempty" msgpack:"max_new_tokens,omitempty"`
+	TopP              float64 `json:"top_p,omitempty" msgpack:"top_p,omitempty"`
+	RepetitionPenalty float64 `json:"repetition_penalty,omitempty" msgpack:"repetition_penalty,omitempty"`
+	Temperature       float64 `json:"temperature,omitempty" msgpack:"temperature,omitempty"`
+	Seed              *int    `json:"seed,omitempty" msgpack:"seed,omitempty"`
+	Speed             float64 `json:"speed,omitempty" msgpack:"speed,omitempty"`
+}
+
+// Apply sets the reference of req to the voice's and fills in the parameters
+// req leaves unset. Speed is not applied to streaming requests, which cannot
+// be time-stretched.
+func (v *Voice) Apply(req *ServeTTSRequest) {
+	id := v.ReferenceID
+	req.ReferenceID = &id
+	if req.Model == "" {
+		req.Model = v.Model
+	}
+	if req.ChunkLength == 0 {
+		req.ChunkLength = v.ChunkLength
+	}
+	if req.MaxNewTokens == 0 {
+		req.MaxNewTokens = v.MaxNewTokens
+	}
+	if req.TopP == 0 {
+		req.TopP = v.TopP
+	}
+	if req.RepetitionPenalty == 0 {
+		req.RepetitionPenalty = v.RepetitionPenalty
+	}
+	if req.Temperature == 0 {
+		req.Temperature = v.Temperature
+	}
+	if req.Seed == nil && v.Seed != nil {
+		seed := *v.Seed
+		req.Seed = &seed
+	}
+	if req.Speed == 0 && !req.Streaming {
+		req.Speed = v.Speed
+	}
+}
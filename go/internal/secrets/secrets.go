@@ -0,0 +1,168 @@
+// Package secrets fetches credentials from an external secret manager and keeps
+// them refreshed at runtime.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+// ErrNotFound indicates the secret or the requested field does not exist.
+var ErrNotFound = errors.New("secret not found")
+
+// Provider fetches the current value of a named secret.
+type Provider interface {
+	Fetch(ctx context.Context, name string) (string, error)
+}
+
+// New returns the Provider selected by cfg.
+func New(cfg config.SecretsConfig) (Provider, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	switch cfg.Provider {
+	case "vault":
+		if cfg.Vault.Address == "" {
+			return nil, errors.New("secrets.vault.address is required")
+		}
+		return &Vault{
+			Address:   strings.TrimRight(cfg.Vault.Address, "/"),
+			Mount:     cfg.Vault.Mount,
+			TokenFile: cfg.Vault.TokenFile,
+			Client:    client,
+		}, nil
+	case "aws":
+		if cfg.AWS.Region == "" {
+			return nil, errors.New("secrets.aws.region is required")
+		}
+		return &AWS{
+			Region:      cfg.AWS.Region,
+			Endpoint:    cfg.AWS.Endpoint,
+			Credentials: EnvCredentials,
+			Client:      client,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown secrets provider %q (want vault or aws)", cfg.Provider)
+	}
+}
+
+// splitName separates a "path#field" secret name.
+func splitName(name string) (path, field string) {
+	if i := strings.LastIndex(name, "#"); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return name, ""
+}
+
+// jsonField returns field of the JSON object raw.
+func jsonField(raw, field string) (string, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object: %w", err)
+	}
+	return stringField(fields, field)
+}
+
+func stringField(fields map[string]interface{}, field string) (string, error) {
+	v, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("%w: field %q", ErrNotFound, field)
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("secret field %q is not a string", field)
+	}
+	return s, nil
+}
+
+// readToken reads a token from path, falling back to the environment variable env.
+func readToken(path, env string) (string, error) {
+	if path == "" {
+		return os.Getenv(env), nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token: %w", err)
+	}
+	return strings.TrimSpace(string(raw)), nil
+}
+
+// Cache holds the latest values of a fixed set of secrets. It is safe for
+// concurrent use.
+type Cache struct {
+	provider Provider
+	names    []string
+
+	mu      sync.RWMutex
+	values  map[string]string
+	version atomic.Uint64
+}
+
+// NewCache returns an empty Cache of the named secrets; call Refresh to load them.
+func NewCache(provider Provider, names []string) *Cache {
+	return &Cache{provider: provider, names: names, values: map[string]string{}}
+}
+
+// Get returns the last fetched value of the secret name.
+func (c *Cache) Get(name string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	v, ok := c.values[name]
+	return v, ok
+}
+
+// Version changes whenever a secret value changes.
+func (c *Cache) Version() uint64 {
+	return c.version.Load()
+}
+
+// Refresh fetches every secret. Secrets that fail keep their previous value,
+// and the first error is returned.
+func (c *Cache) Refresh(ctx context.Context) error {
+	var firstErr error
+	for _, name := range c.names {
+		v, err := c.provider.Fetch(ctx, name)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("secret %q: %w", name, err)
+			}
+			continue
+		}
+
+		c.mu.Lock()
+		if old, ok := c.values[name]; !ok || old != v {
+			c.values[name] = v
+			c.version.Add(1)
+		}
+		c.mu.Unlock()
+	}
+	return firstErr
+}
+
+// Run refreshes the secrets every interval until ctx is done, reporting
+// failures to onError.
+func (c *Cache) Run(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Refresh(ctx); err != nil && ctx.Err() == nil {
+				onError(err)
+			}
+		}
+	}
+}
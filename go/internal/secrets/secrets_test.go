@@ -0,0 +1,136 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignV4_Vector(t *testing.T) {
+	// get-vanilla from the AWS Signature Version 4 test suite.
+	req := httptest.NewRequest(http.MethodGet, "http://example.amazonaws.com/", nil)
+	creds := AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+	signV4(req, nil, creds, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+		"SignedHeaders=host;x-amz-date, "+
+		"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31", req.Header.Get("Authorization"))
+}
+
+func TestAWS_Fetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secretsmanager.GetSecretValue", r.Header.Get("X-Amz-Target"))
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/"))
+		assert.Equal(t, "session", r.Header.Get("X-Amz-Security-Token"))
+
+		var req struct{ SecretId string }
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		switch req.SecretId {
+		case "fish/api":
+			json.NewEncoder(w).Encode(map[string]string{"SecretString": `{"key":"from-json"}`})
+		case "fish/plain":
+			json.NewEncoder(w).Encode(map[string]string{"SecretString": "plain-key"})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"ResourceNotFoundException","message":"not found"}`))
+		}
+	}))
+	defer srv.Close()
+
+	a := &AWS{
+		Region:   "us-east-1",
+		Endpoint: srv.URL,
+		Credentials: func() (AWSCredentials, error) {
+			return AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "session"}, nil
+		},
+		Client: srv.Client(),
+	}
+
+	v, err := a.Fetch(context.Background(), "fish/api#key")
+	require.NoError(t, err)
+	assert.Equal(t, "from-json", v)
+
+	v, err = a.Fetch(context.Background(), "fish/plain")
+	require.NoError(t, err)
+	assert.Equal(t, "plain-key", v)
+
+	_, err = a.Fetch(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestVault_Fetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/v1/kv/data/fish/api" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"data":{"data":{"value":"v-key","admin":"a-key"}}}`))
+	}))
+	defer srv.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("vault-token\n"), 0o600))
+	v := &Vault{Address: srv.URL, Mount: "kv", TokenFile: tokenFile, Client: srv.Client()}
+
+	got, err := v.Fetch(context.Background(), "fish/api")
+	require.NoError(t, err)
+	assert.Equal(t, "v-key", got)
+
+	got, err = v.Fetch(context.Background(), "fish/api#admin")
+	require.NoError(t, err)
+	assert.Equal(t, "a-key", got)
+
+	_, err = v.Fetch(context.Background(), "fish/missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = v.Fetch(context.Background(), "fish/api#other")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+type fakeProvider map[string]string
+
+func (p fakeProvider) Fetch(ctx context.Context, name string) (string, error) {
+	v, ok := p[name]
+	if !ok {
+		return "", errors.New("unavailable")
+	}
+	return v, nil
+}
+
+func TestCache(t *testing.T) {
+	p := fakeProvider{"a": "1", "b": "2"}
+	c := NewCache(p, []string{"a", "b"})
+
+	require.NoError(t, c.Refresh(context.Background()))
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", v)
+	version := c.Version()
+
+	require.NoError(t, c.Refresh(context.Background()))
+	assert.Equal(t, version, c.Version(), "unchanged values keep the version")
+
+	p["a"] = "rotated"
+	delete(p, "b")
+	assert.Error(t, c.Refresh(context.Background()))
+	assert.NotEqual(t, version, c.Version())
+	v, _ = c.Get("a")
+	assert.Equal(t, "rotated", v)
+	v, ok = c.Get("b")
+	assert.True(t, ok, "a failed fetch keeps the previous value")
+	assert.Equal(t, "2", v)
+}
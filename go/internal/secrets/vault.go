@@ -0,0 +1,66 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Vault reads secrets from a HashiCorp Vault KV version 2 engine. Names are
+// "path#field"; the field defaults to "value".
+type Vault struct {
+	Address string
+	// Mount is the path the KV engine is mounted at.
+	Mount string
+	// TokenFile holds the Vault token; VAULT_TOKEN is used when it is empty.
+	// It is re-read on every fetch so that a renewing agent can rotate it.
+	TokenFile string
+	Client    *http.Client
+}
+
+// Fetch returns the field of the latest version of the secret.
+func (v *Vault) Fetch(ctx context.Context, name string) (string, error) {
+	path, field := splitName(name)
+	if field == "" {
+		field = "value"
+	}
+
+	token, err := readToken(v.TokenFile, "VAULT_TOKEN")
+	if err != nil {
+		return "", err
+	}
+
+	url := fmt.Sprintf("%s/v1/%s/data/%s", v.Address, strings.Trim(v.Mount, "/"), strings.TrimLeft(path, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("vault returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var secret struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return "", fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	return stringField(secret.Data.Data, field)
+}
@@ -0,0 +1,209 @@
+package shared
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/cache"
+)
+
+// scanBatch is how many keys a flush asks SCAN for at a time.
+const scanBatch = 500
+
+// CacheTier is the Redis tier of the TTS response cache. Each entry is a
+// MessagePack value with the cache TTL; sets index the entries of each
+// reference and text hash for targeted invalidation, and invalidations are
+// published so every server can drop its local copies.
+type CacheTier struct {
+	store *Store
+}
+
+// Ensure CacheTier implements cache.Remote.
+var _ cache.Remote = (*CacheTier)(nil)
+
+// Cache returns the store's response cache tier.
+func (s *Store) Cache() *CacheTier {
+	return &CacheTier{store: s}
+}
+
+func (t *CacheTier) entryKey(key string) string {
+	return t.store.key("cache", "entry", key)
+}
+
+func (t *CacheTier) referenceKey(id string) string {
+	return t.store.key("cache", "reference", id)
+}
+
+func (t *CacheTier) textKey(hash string) string {
+	return t.store.key("cache", "text", hash)
+}
+
+func (t *CacheTier) channel() string {
+	return t.store.key("cache", "invalidate")
+}
+
+// Get implements cache.Remote.
+func (t *CacheTier) Get(key string) (*cache.Entry, error) {
+	ctx, cancel := t.store.call()
+	defer cancel()
+
+	data, err := t.store.client.Get(ctx, t.entryKey(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entry cache.Entry
+	if err := msgpack.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Set implements cache.Remote. The index sets live as long as their newest
+// entry.
+func (t *CacheTier) Set(key string, entry *cache.Entry, ttl time.Duration) error {
+	data, err := msgpack.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := t.store.call()
+	defer cancel()
+	_, err = t.store.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, t.entryKey(key), data, ttl)
+		for _, index := range t.indexes(entry) {
+			pipe.SAdd(ctx, index, key)
+			if ttl > 0 {
+				pipe.PExpire(ctx, index, ttl)
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+func (t *CacheTier) indexes(entry *cache.Entry) []string {
+	var indexes []string
+	if entry.ReferenceID != "" {
+		indexes = append(indexes, t.referenceKey(entry.ReferenceID))
+	}
+	if entry.TextHash != "" {
+		indexes = append(indexes, t.textKey(entry.TextHash))
+	}
+	return indexes
+}
+
+// Invalidate implements cache.Remote.
+func (t *CacheTier) Invalidate(inv cache.Invalidation) (int, error) {
+	ctx, cancel := t.store.call()
+	defer cancel()
+
+	var removed int
+	var err error
+	switch {
+	case inv.ReferenceID != "":
+		removed, err = t.removeIndexed(ctx, t.referenceKey(inv.ReferenceID))
+	case inv.TextHash != "":
+		removed, err = t.removeIndexed(ctx, t.textKey(inv.TextHash))
+	default:
+		removed, err = t.flush(ctx)
+	}
+	if err != nil {
+		return removed, err
+	}
+
+	message, err := json.Marshal(inv)
+	if err != nil {
+		return removed, err
+	}
+	return removed, t.store.client.Publish(ctx, t.channel(), message).Err()
+}
+
+// removeIndexed deletes the entries listed in the index set and the set.
+func (t *CacheTier) removeIndexed(ctx context.Context, index string) (int, error) {
+	keys, err := t.store.client.SMembers(ctx, index).Result()
+	if err != nil {
+		return 0, err
+	}
+	entries := make([]string, len(keys))
+	for i, key := range keys {
+		entries[i] = t.entryKey(key)
+	}
+
+	var del *redis.IntCmd
+	_, err = t.store.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if len(entries) > 0 {
+			del = pipe.Del(ctx, entries...)
+		}
+		pipe.Del(ctx, index)
+		return nil
+	})
+	if err != nil || del == nil {
+		return 0, err
+	}
+	return int(del.Val()), nil
+}
+
+// flush deletes every cache key, counting the entries.
+func (t *CacheTier) flush(ctx context.Context) (int, error) {
+	removed := 0
+	iter := t.store.client.Scan(ctx, 0, t.store.key("cache", "*"), scanBatch).Iterator()
+	var batch []string
+	entryPrefix := t.entryKey("")
+	for iter.Next(ctx) {
+		key := iter.Val()
+		if strings.HasPrefix(key, entryPrefix) {
+			removed++
+		}
+		batch = append(batch, key)
+		if len(batch) == scanBatch {
+			if err := t.store.client.Unlink(ctx, batch...).Err(); err != nil {
+				return removed, err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return removed, err
+	}
+	if len(batch) > 0 {
+		if err := t.store.client.Unlink(ctx, batch...).Err(); err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// Subscribe calls apply with every invalidation published by any server,
+// including this one, until ctx is done. It reconnects by itself after Redis
+// outages; invalidations published meanwhile are missed, and the affected
+// local copies expire with the cache TTL.
+func (t *CacheTier) Subscribe(ctx context.Context, apply func(cache.Invalidation)) {
+	sub := t.store.client.Subscribe(ctx, t.channel())
+	defer sub.Close()
+
+	messages := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			var inv cache.Invalidation
+			if err := json.Unmarshal([]byte(msg.Payload), &inv); err != nil {
+				continue
+			}
+			apply(inv)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package shared
+
+import (
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Limit is the rate limit and quota of an API key. Zero Rate or Quota
+// disables that check.
+type Limit struct {
+	// Rate is the sustained requests per second, with bursts of up to Burst.
+	Rate  float64
+	Burst float64
+	// Quota is the number of requests allowed per Period.
+	Quota  int64
+	Period time.Duration
+}
+
+// Outcomes of Admit.
+const (
+	Admitted      = 0
+	RateLimited   = 1
+	QuotaExceeded = 2
+)
+
+// Decision is the result of charging a request to a Limit.
+type Decision struct {
+	// Outcome is Admitted, RateLimited or QuotaExceeded.
+	Outcome int
+	// RetryAfter is when a rejected request may succeed.
+	RetryAfter time.Duration
+}
+
+// admitScript charges one request to a token bucket and a fixed quota window
+// atomically, with the same rules as the in-process limiter: rejected
+// requests are not charged. Times are in milliseconds.
+//
+//	KEYS: bucket, quota window
+//	ARGV: now, rate, burst, quota, period
+var admitScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local rate, burst = tonumber(ARGV[2]), tonumber(ARGV[3])
+local quota, period = tonumber(ARGV[4]), tonumber(ARGV[5])
+
+local tokens = burst
+if rate > 0 then
+  local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+  if bucket[1] then
+    tokens = math.min(burst, tonumber(bucket[1]) + math.max(0, now - tonumber(bucket[2])) / 1000 * rate)
+  end
+  if tokens < 1 then
+    redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last', now)
+    redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+    return {1, math.ceil((1 - tokens) / rate * 1000)}
+  end
+end
+
+if quota > 0 then
+  local window = redis.call('HMGET', KEYS[2], 'start', 'count')
+  local start, count = tonumber(window[1]), tonumber(window[2])
+  if not start or now - start >= period then
+    start, count = now, 0
+  end
+  if count >= quota then
+    return {2, start + period - now}
+  end
+  redis.call('HSET', KEYS[2], 'start', start, 'count', count + 1)
+  redis.call('PEXPIRE', KEYS[2], start + period - now)
+end
+
+if rate > 0 then
+  redis.call('HSET', KEYS[1], 'tokens', tostring(tokens - 1), 'last', now)
+  redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+end
+return {0, 0}
+`)
+
+// Admit charges a request by the API key called name to l at now. The clocks
+// of the servers sharing Redis should be synchronized, as each passes its own
+// now.
+func (s *Store) Admit(name string, l Limit, now time.Time) (Decision, error) {
+	ctx, cancel := s.call()
+	defer cancel()
+
+	keys := []string{s.key("ratelimit", name, "bucket"), s.key("ratelimit", name, "quota")}
+	result, err := admitScript.Run(ctx, s.client, keys,
+		now.UnixMilli(), l.Rate, l.Burst, l.Quota, l.Period.Milliseconds()).Int64Slice()
+	if err != nil {
+		return Decision{}, err
+	}
+	return Decision{Outcome: int(result[0]), RetryAfter: time.Duration(result[1]) * time.Millisecond}, nil
+}
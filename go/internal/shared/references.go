@@ -0,0 +1,54 @@
+package shared
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func (s *Store) referencesKey() string {
+	return s.key("references", "list")
+}
+
+// References returns the cached reference list of the backend, or nil when
+// none is cached.
+func (s *Store) References() (*schema.ListReferencesResponse, error) {
+	ctx, cancel := s.call()
+	defer cancel()
+
+	data, err := s.client.Get(ctx, s.referencesKey()).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var resp schema.ListReferencesResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SetReferences caches the reference list of the backend for ttl.
+func (s *Store) SetReferences(resp *schema.ListReferencesResponse, ttl time.Duration) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.call()
+	defer cancel()
+	return s.client.Set(ctx, s.referencesKey(), data, ttl).Err()
+}
+
+// InvalidateReferences drops the cached reference list after a reference was
+// added or deleted.
+func (s *Store) InvalidateReferences() error {
+	ctx, cancel := s.call()
+	defer cancel()
+	return s.client.Del(ctx, s.referencesKey()).Err()
+}
@@ -0,0 +1,82 @@
+// Package shared keeps state in Redis so that several fish-server replicas
+// behind a load balancer share it: the TTS response cache, the backend's
+// reference list and API key rate-limit counters. Callers fall back to their
+// local state when Redis is unreachable.
+package shared
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+const (
+	defaultKeyPrefix = "fish:"
+	defaultTimeout   = 500 * time.Millisecond
+)
+
+// Store is a connection to the shared Redis. It is safe for concurrent use.
+type Store struct {
+	client  *redis.Client
+	prefix  string
+	timeout time.Duration
+}
+
+// Open connects to the Redis at cfg.URL and checks that it answers.
+func Open(cfg config.RedisConfig) (*Store, error) {
+	opts, err := redis.ParseURL(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+	if cfg.Password != "" {
+		opts.Password = cfg.Password
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	opts.DialTimeout = timeout
+	opts.ReadTimeout = timeout
+	opts.WriteTimeout = timeout
+
+	s := &Store{client: redis.NewClient(opts), prefix: cfg.KeyPrefix, timeout: timeout}
+	if s.prefix == "" {
+		s.prefix = defaultKeyPrefix
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*timeout)
+	defer cancel()
+	if err := s.Ping(ctx); err != nil {
+		s.client.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Ping checks that Redis answers.
+func (s *Store) Ping(ctx context.Context) error {
+	if err := s.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("redis unreachable: %w", err)
+	}
+	return nil
+}
+
+// Close closes the connections to Redis.
+func (s *Store) Close() error {
+	return s.client.Close()
+}
+
+// key returns the Redis key made of parts under the configured prefix.
+func (s *Store) key(parts ...string) string {
+	return s.prefix + strings.Join(parts, ":")
+}
+
+// call returns a context bounding one call to Redis.
+func (s *Store) call() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), s.timeout)
+}
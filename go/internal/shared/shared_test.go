@@ -0,0 +1,171 @@
+package shared
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/cache"
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+	"github.com/fish-speech-go/fish-speech-go/internal/schema"
+)
+
+func testStore(t *testing.T) (*Store, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	s, err := Open(config.RedisConfig{URL: "redis://" + mr.Addr()})
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	return s, mr
+}
+
+func TestOpen(t *testing.T) {
+	_, err := Open(config.RedisConfig{URL: "http://localhost"})
+	assert.ErrorContains(t, err, "invalid redis url")
+
+	mr := miniredis.RunT(t)
+	mr.RequireAuth("secret")
+	_, err = Open(config.RedisConfig{URL: "redis://" + mr.Addr(), Timeout: 100 * time.Millisecond})
+	assert.ErrorContains(t, err, "redis unreachable")
+
+	s, err := Open(config.RedisConfig{URL: "redis://" + mr.Addr(), Password: "secret", KeyPrefix: "test:"})
+	require.NoError(t, err)
+	defer s.Close()
+	assert.Equal(t, "test:a:b", s.key("a", "b"))
+}
+
+func TestCacheTier(t *testing.T) {
+	s, mr := testStore(t)
+	tier := s.Cache()
+
+	entry, err := tier.Get("missing")
+	require.NoError(t, err)
+	assert.Nil(t, entry)
+
+	hello := cache.TextHash("hello")
+	require.NoError(t, tier.Set("1", &cache.Entry{Audio: []byte("a"), Format: "wav", ReferenceID: "voice", TextHash: hello}, time.Minute))
+	require.NoError(t, tier.Set("2", &cache.Entry{Audio: []byte("b"), ReferenceID: "voice"}, time.Minute))
+	require.NoError(t, tier.Set("3", &cache.Entry{Audio: []byte("c"), TextHash: hello}, time.Minute))
+
+	entry, err = tier.Get("1")
+	require.NoError(t, err)
+	require.NotNil(t, entry)
+	assert.Equal(t, "a", string(entry.Audio))
+	assert.Equal(t, "wav", entry.Format)
+	assert.Equal(t, time.Minute, mr.TTL("fish:cache:entry:1"))
+
+	removed, err := tier.Invalidate(cache.Invalidation{ReferenceID: "voice"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	assert.False(t, mr.Exists("fish:cache:reference:voice"))
+
+	removed, err = tier.Invalidate(cache.Invalidation{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.Empty(t, mr.Keys())
+
+	mr.FastForward(time.Minute)
+	require.NoError(t, tier.Set("4", &cache.Entry{Audio: []byte("d")}, time.Second))
+	mr.FastForward(time.Second)
+	entry, err = tier.Get("4")
+	require.NoError(t, err)
+	assert.Nil(t, entry, "entries expire with the cache TTL")
+}
+
+func TestCacheTier_Subscribe(t *testing.T) {
+	s, _ := testStore(t)
+	tier := s.Cache()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	received := make(chan cache.Invalidation, 1)
+	go tier.Subscribe(ctx, func(inv cache.Invalidation) { received <- inv })
+
+	// The subscription is set up asynchronously; publish until it is seen.
+	deadline := time.After(2 * time.Second)
+	for {
+		_, err := tier.Invalidate(cache.Invalidation{TextHash: "abc"})
+		require.NoError(t, err)
+		select {
+		case inv := <-received:
+			assert.Equal(t, cache.Invalidation{TextHash: "abc"}, inv)
+			return
+		case <-time.After(20 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("invalidation not received")
+		}
+	}
+}
+
+func TestAdmit_RateLimit(t *testing.T) {
+	s, _ := testStore(t)
+	limit := Limit{Rate: 2, Burst: 2}
+	now := time.Unix(1000, 0)
+
+	for i := 0; i < 2; i++ {
+		d, err := s.Admit("k", limit, now)
+		require.NoError(t, err)
+		assert.Equal(t, Admitted, d.Outcome)
+	}
+	d, err := s.Admit("k", limit, now)
+	require.NoError(t, err)
+	assert.Equal(t, RateLimited, d.Outcome)
+	assert.Equal(t, 500*time.Millisecond, d.RetryAfter)
+
+	d, err = s.Admit("k", limit, now.Add(500*time.Millisecond))
+	require.NoError(t, err)
+	assert.Equal(t, Admitted, d.Outcome)
+
+	d, err = s.Admit("other", limit, now.Add(500*time.Millisecond))
+	require.NoError(t, err)
+	assert.Equal(t, Admitted, d.Outcome, "keys have separate buckets")
+}
+
+func TestAdmit_Quota(t *testing.T) {
+	s, _ := testStore(t)
+	limit := Limit{Quota: 2, Period: time.Hour}
+	now := time.Unix(1000, 0)
+
+	for i := 0; i < 2; i++ {
+		d, err := s.Admit("k", limit, now.Add(time.Duration(i)*time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, Admitted, d.Outcome)
+	}
+	d, err := s.Admit("k", limit, now.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, QuotaExceeded, d.Outcome)
+	assert.Equal(t, 58*time.Minute, d.RetryAfter)
+
+	d, err = s.Admit("k", limit, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, Admitted, d.Outcome, "a new period resets the quota")
+}
+
+func TestReferences(t *testing.T) {
+	s, mr := testStore(t)
+
+	resp, err := s.References()
+	require.NoError(t, err)
+	assert.Nil(t, resp)
+
+	list := &schema.ListReferencesResponse{Success: true, ReferenceIDs: []string{"a", "b"}}
+	require.NoError(t, s.SetReferences(list, 10*time.Second))
+	resp, err = s.References()
+	require.NoError(t, err)
+	assert.Equal(t, list, resp)
+
+	require.NoError(t, s.InvalidateReferences())
+	resp, err = s.References()
+	require.NoError(t, err)
+	assert.Nil(t, resp)
+
+	require.NoError(t, s.SetReferences(list, 10*time.Second))
+	mr.FastForward(10 * time.Second)
+	resp, err = s.References()
+	require.NoError(t, err)
+	assert.Nil(t, resp)
+}
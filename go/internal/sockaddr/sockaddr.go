@@ -0,0 +1,73 @@
+// Package sockaddr handles listen addresses and URLs that name a Unix domain
+// socket as unix:///path/to.sock instead of a TCP host and port.
+package sockaddr
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// Scheme prefixes addresses of Unix domain sockets.
+const Scheme = "unix://"
+
+// UnixPath returns the socket path of a unix:// address, and false for any
+// other address.
+func UnixPath(addr string) (string, bool) {
+	if !strings.HasPrefix(addr, Scheme) {
+		return "", false
+	}
+	return strings.TrimPrefix(addr, Scheme), true
+}
+
+// Listen listens on addr: a Unix domain socket for a unix:// address, else a
+// TCP host:port. A socket file left behind by a process that exited is
+// replaced, and mode, when non-zero, sets the socket's permissions. The socket
+// file is removed when the listener is closed.
+func Listen(addr string, mode os.FileMode) (net.Listener, error) {
+	path, ok := UnixPath(addr)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	if path == "" {
+		return nil, fmt.Errorf("%q names no socket path", addr)
+	}
+	if err := removeStale(path); err != nil {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if mode != 0 {
+		if err := os.Chmod(path, mode); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+	return ln, nil
+}
+
+// removeStale removes the socket at path unless a server still accepts
+// connections on it.
+func removeStale(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another server", path)
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,70 @@
+package sockaddr
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnixPath(t *testing.T) {
+	path, ok := UnixPath("unix:///run/fish.sock")
+	assert.True(t, ok)
+	assert.Equal(t, "/run/fish.sock", path)
+
+	_, ok = UnixPath("0.0.0.0:8080")
+	assert.False(t, ok)
+	_, ok = UnixPath("http://127.0.0.1:8081")
+	assert.False(t, ok)
+}
+
+func TestListen_Unix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fish.sock")
+
+	ln, err := Listen(Scheme+path, 0o660)
+	require.NoError(t, err)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o660), info.Mode().Perm())
+
+	_, err = Listen(Scheme+path, 0)
+	assert.Error(t, err, "a socket in use is not taken over")
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	conn.Close()
+	require.NoError(t, ln.Close())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "closing removes the socket")
+}
+
+func TestListen_ReplacesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fish.sock")
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	// Leave the file behind as a crashed server would.
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	ln, err := Listen(Scheme+path, 0)
+	require.NoError(t, err)
+	ln.Close()
+}
+
+func TestListen_Errors(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "fish.sock")
+	require.NoError(t, os.WriteFile(file, []byte("data"), 0o600))
+
+	_, err := Listen(Scheme+file, 0)
+	assert.Error(t, err, "a regular file is not replaced")
+	_, err = Listen(Scheme, 0)
+	assert.Error(t, err)
+
+	ln, err := Listen("127.0.0.1:0", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "tcp", ln.Addr().Network())
+	ln.Close()
+}
@@ -0,0 +1,37 @@
+package text
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Typical speaking rates used for duration estimates. Scripts written without
+// word spacing are timed per character, everything else per word.
+const (
+	wordsPerSecond    = 2.6
+	unspacedPerSecond = 5.0
+)
+
+// EstimateSpeechDuration approximates how long s takes to read aloud at a
+// neutral pace.
+func EstimateSpeechDuration(s string) time.Duration {
+	var words, unspaced int
+	for _, field := range strings.Fields(s) {
+		hasWord := false
+		for _, r := range field {
+			switch {
+			case isUnspaced(r):
+				unspaced++
+			case unicode.IsLetter(r) || unicode.IsNumber(r):
+				hasWord = true
+			}
+		}
+		if hasWord {
+			words++
+		}
+	}
+
+	seconds := float64(words)/wordsPerSecond + float64(unspaced)/unspacedPerSecond
+	return time.Duration(seconds * float64(time.Second))
+}
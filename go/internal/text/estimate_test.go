@@ -0,0 +1,14 @@
+package text
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateSpeechDuration(t *testing.T) {
+	assert.InDelta(t, 2*time.Second, EstimateSpeechDuration("one two three four five, six?"), float64(400*time.Millisecond))
+	assert.InDelta(t, time.Second, EstimateSpeechDuration("你好世界呀"), float64(100*time.Millisecond))
+	assert.Zero(t, EstimateSpeechDuration("... !"))
+}
@@ -0,0 +1,30 @@
+package text
+
+import "unicode"
+
+// DetectLanguage guesses the language of s from its script. It recognizes
+// Japanese ("ja"), Korean ("ko"), and Chinese ("zh"), and returns "" when the
+// script does not identify a single language (for example Latin text).
+func DetectLanguage(s string) string {
+	var han, kana, hangul int
+	for _, r := range s {
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Han, r):
+			han++
+		}
+	}
+
+	switch {
+	case kana > 0:
+		return "ja"
+	case hangul > 0 && hangul >= han:
+		return "ko"
+	case han > 0:
+		return "zh"
+	}
+	return ""
+}
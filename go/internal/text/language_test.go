@@ -0,0 +1,14 @@
+package text
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	assert.Equal(t, "ja", DetectLanguage("今日はいい天気ですね"))
+	assert.Equal(t, "zh", DetectLanguage("今天天气很好"))
+	assert.Equal(t, "ko", DetectLanguage("오늘 날씨가 좋네요"))
+	assert.Equal(t, "", DetectLanguage("The weather is nice today"))
+}
@@ -0,0 +1,72 @@
+package text
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Normalize lowercases s, drops punctuation and symbols, and collapses runs of
+// whitespace so transcripts can be compared independent of formatting. Scripts
+// written without word spacing (CJK) are joined without separators.
+func Normalize(s string) string {
+	var b strings.Builder
+	var last rune
+	space := false
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			if space && last != 0 && !isUnspaced(last) && !isUnspaced(r) {
+				b.WriteByte(' ')
+			}
+			space = false
+			last = r
+			b.WriteRune(r)
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
+
+func isUnspaced(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
+
+// Similarity returns a score in [0, 1] comparing two transcripts by character
+// edit distance after normalization. 1 means identical.
+func Similarity(a, b string) float64 {
+	ra := []rune(Normalize(a))
+	rb := []rune(Normalize(b))
+
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
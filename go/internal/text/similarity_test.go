@@ -0,0 +1,19 @@
+package text
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "hello world 42", Normalize("  Hello,   WORLD! 42. "))
+	assert.Equal(t, "你好世界", Normalize("你好，世界。"))
+}
+
+func TestSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, Similarity("Hello, world!", "hello world"))
+	assert.Equal(t, 1.0, Similarity("", "..."))
+	assert.InDelta(t, 0.9, Similarity("hello world", "hello word"), 0.01)
+	assert.Less(t, Similarity("the quick brown fox", "completely different text"), 0.5)
+}
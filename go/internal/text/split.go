@@ -0,0 +1,119 @@
+package text
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// The functions in this file mirror fish_speech/text/clean.py and
+// fish_speech/text/spliter.py so the Go server can show clients how the
+// backend will normalize and chunk their text. Keep them in sync with upstream.
+
+var (
+	symbolReplacer = strings.NewReplacer("‘", "'", "’", "'")
+	emojiPattern   = regexp.MustCompile("[\U0001F600-\U0001F64F\U0001F300-\U0001F5FF\U0001F680-\U0001F6FF\U0001F1E0-\U0001F1FF]+")
+	commaRunRegex  = regexp.MustCompile(`,{2,}`)
+	floatPattern   = regexp.MustCompile(`(\d+)\.(\d+)`)
+	protectedFloat = regexp.MustCompile(`<(\d+)_f_(\d+)>`)
+)
+
+var (
+	sentenceSplits = map[rune]bool{'.': true, '!': true, '?': true, '。': true, '！': true, '？': true}
+	clauseSplits   = map[rune]bool{',': true, '，': true}
+	wordSplits     = map[rune]bool{' ': true}
+)
+
+// Clean applies the backend's text cleanup: trimming, quote normalization,
+// emoji removal, and collapsing repeated commas.
+func Clean(s string) string {
+	s = strings.TrimSpace(s)
+	s = symbolReplacer.Replace(s)
+	s = emojiPattern.ReplaceAllString(s, "")
+	s = commaRunRegex.ReplaceAllString(s, ",")
+	return s
+}
+
+// Split cleans s and breaks it into chunks of at most length UTF-8 bytes where
+// possible, preferring sentence, then clause, then word boundaries, exactly as
+// the backend does for iterative prompting.
+func Split(s string, length int) []string {
+	texts := []string{floatPattern.ReplaceAllString(Clean(s), "<${1}_f_${2}>")}
+	texts = breakText(texts, length, sentenceSplits)
+	for i, t := range texts {
+		texts[i] = protectedFloat.ReplaceAllString(t, "${1}.${2}")
+	}
+	texts = breakText(texts, length, clauseSplits)
+	texts = breakText(texts, length, wordSplits)
+	texts = breakTextByLength(texts, length)
+
+	var segments []string
+	curr := ""
+	for _, t := range texts {
+		if len(curr)+len(t) <= length {
+			curr += t
+			continue
+		}
+		segments = appendCleaned(segments, curr)
+		curr = t
+	}
+	return appendCleaned(segments, curr)
+}
+
+func breakText(texts []string, length int, splits map[rune]bool) []string {
+	var out []string
+	for _, t := range texts {
+		if len(t) <= length {
+			out = append(out, t)
+			continue
+		}
+
+		start := 0
+		for i, r := range t {
+			if splits[r] {
+				end := i + len(string(r))
+				out = append(out, t[start:end])
+				start = end
+			}
+		}
+		if start < len(t) {
+			out = append(out, t[start:])
+		}
+	}
+	return out
+}
+
+func breakTextByLength(texts []string, length int) []string {
+	var out []string
+	for _, t := range texts {
+		if len(t) <= length {
+			out = append(out, t)
+			continue
+		}
+
+		start := 0
+		for i, r := range t {
+			if end := i + len(string(r)); end-start >= length {
+				out = append(out, t[start:end])
+				start = end
+			}
+		}
+		if start < len(t) {
+			out = append(out, t[start:])
+		}
+	}
+	return out
+}
+
+// appendCleaned adds the trimmed segment unless it is only whitespace and ASCII punctuation.
+func appendCleaned(segments []string, s string) []string {
+	s = strings.TrimSpace(s)
+	for _, r := range s {
+		if !unicode.IsSpace(r) && !strings.ContainsRune(asciiPunctuation, r) {
+			return append(segments, s)
+		}
+	}
+	return segments
+}
+
+const asciiPunctuation = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
@@ -0,0 +1,29 @@
+package text
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClean(t *testing.T) {
+	assert.Equal(t, "It's here, ok", Clean("  It’s here,,, ok😀 "))
+}
+
+func TestSplit(t *testing.T) {
+	assert.Equal(t, []string{"Hello world."}, Split("Hello world.", 100))
+
+	text := "Pi is 3.14 exactly. " + strings.Repeat("word ", 30) + "end!"
+	chunks := Split(text, 100)
+	assert.True(t, strings.HasPrefix(chunks[0], "Pi is 3.14 exactly. word"))
+	assert.Greater(t, len(chunks), 1)
+	for _, c := range chunks {
+		assert.LessOrEqual(t, len(c), 100)
+	}
+	assert.Equal(t, strings.Join(strings.Fields(text), " "), strings.Join(chunks, " "))
+}
+
+func TestSplit_SkipsPunctuationOnly(t *testing.T) {
+	assert.Empty(t, Split("...", 100))
+}
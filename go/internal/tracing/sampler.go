@@ -0,0 +1,188 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// maxPendingTraces bounds the traces held while their root span is
+	// open. Spans of further traces are dropped until some finish.
+	maxPendingTraces = 10000
+	// pendingTTL is how long spans are held for a root span that has not
+	// ended, such as that of a trace whose root ended before them.
+	pendingTTL = time.Hour
+)
+
+// Sampling decides which traces are exported once they have finished.
+type Sampling struct {
+	// Ratio is the fraction of traces exported, chosen by trace ID.
+	Ratio float64
+	// RouteRatios overrides Ratio for traces whose root span has one of
+	// these http.route attributes.
+	RouteRatios map[string]float64
+	// SlowThreshold, when positive, exports every trace whose root span
+	// took at least this long.
+	SlowThreshold time.Duration
+}
+
+// headSampler records every span, so that the tail sampler can still export
+// a trace that fails or is slow. Traces are marked sampled, and so are
+// recorded by the backend too, as Ratio would choose them, or as the client's
+// traceparent says.
+func (s Sampling) headSampler() sdktrace.Sampler {
+	recordOnly := recordOnlySampler{}
+	return sdktrace.ParentBased(recordOrSample{ratio: sdktrace.TraceIDRatioBased(s.Ratio)},
+		sdktrace.WithRemoteParentNotSampled(recordOnly),
+		sdktrace.WithLocalParentNotSampled(recordOnly),
+	)
+}
+
+// keep reports whether to export the trace whose local root span is root and
+// whose spans are spans.
+func (s Sampling) keep(root sdktrace.ReadOnlySpan, spans []sdktrace.ReadOnlySpan) bool {
+	for _, span := range spans {
+		if span.Status().Code == codes.Error {
+			return true
+		}
+	}
+	if s.SlowThreshold > 0 && root.EndTime().Sub(root.StartTime()) >= s.SlowThreshold {
+		return true
+	}
+	if parent := root.Parent(); parent.IsRemote() {
+		// The client decided whether its trace is recorded.
+		return parent.IsSampled()
+	}
+
+	ratio := s.Ratio
+	for _, attr := range root.Attributes() {
+		if attr.Key == semconv.HTTPRouteKey {
+			if r, ok := s.RouteRatios[attr.Value.AsString()]; ok {
+				ratio = r
+			}
+			break
+		}
+	}
+	decision := sdktrace.TraceIDRatioBased(ratio).ShouldSample(sdktrace.SamplingParameters{TraceID: root.SpanContext().TraceID()})
+	return decision.Decision == sdktrace.RecordAndSample
+}
+
+// recordOrSample samples what ratio samples and records the rest.
+type recordOrSample struct {
+	ratio sdktrace.Sampler
+}
+
+func (s recordOrSample) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	result := s.ratio.ShouldSample(p)
+	if result.Decision == sdktrace.Drop {
+		result.Decision = sdktrace.RecordOnly
+	}
+	return result
+}
+
+func (s recordOrSample) Description() string {
+	return "RecordOrSample{" + s.ratio.Description() + "}"
+}
+
+type recordOnlySampler struct{}
+
+func (recordOnlySampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	return sdktrace.SamplingResult{
+		Decision:   sdktrace.RecordOnly,
+		Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+	}
+}
+
+func (recordOnlySampler) Description() string {
+	return "RecordOnly"
+}
+
+// tailSampler holds the spans of each trace until its local root span ends,
+// then passes them all to next if sampling keeps the trace.
+type tailSampler struct {
+	next     sdktrace.SpanProcessor
+	sampling Sampling
+
+	mu      sync.Mutex
+	pending map[trace.TraceID]*pendingTrace
+}
+
+type pendingTrace struct {
+	since time.Time
+	spans []sdktrace.ReadOnlySpan
+}
+
+func newTailSampler(next sdktrace.SpanProcessor, sampling Sampling) *tailSampler {
+	return &tailSampler{next: next, sampling: sampling, pending: make(map[trace.TraceID]*pendingTrace)}
+}
+
+func (t *tailSampler) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {}
+
+func (t *tailSampler) OnEnd(s sdktrace.ReadOnlySpan) {
+	id := s.SpanContext().TraceID()
+	if parent := s.Parent(); parent.IsValid() && !parent.IsRemote() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		p, ok := t.pending[id]
+		if !ok {
+			if len(t.pending) >= maxPendingTraces {
+				t.sweepLocked(time.Now())
+			}
+			if len(t.pending) >= maxPendingTraces {
+				return
+			}
+			p = &pendingTrace{since: time.Now()}
+			t.pending[id] = p
+		}
+		p.spans = append(p.spans, s)
+		return
+	}
+
+	spans := []sdktrace.ReadOnlySpan{s}
+	t.mu.Lock()
+	if p, ok := t.pending[id]; ok {
+		spans = append(p.spans, s)
+		delete(t.pending, id)
+	}
+	t.mu.Unlock()
+	if !t.sampling.keep(s, spans) {
+		return
+	}
+	for _, span := range spans {
+		t.next.OnEnd(sampledSpan{span})
+	}
+}
+
+// sweepLocked drops the spans held longer than pendingTTL.
+func (t *tailSampler) sweepLocked(now time.Time) {
+	for id, p := range t.pending {
+		if now.Sub(p.since) > pendingTTL {
+			delete(t.pending, id)
+		}
+	}
+}
+
+func (t *tailSampler) Shutdown(ctx context.Context) error {
+	return t.next.Shutdown(ctx)
+}
+
+func (t *tailSampler) ForceFlush(ctx context.Context) error {
+	return t.next.ForceFlush(ctx)
+}
+
+// sampledSpan marks a span of a kept trace as sampled, which exporters
+// require, though it was only recorded.
+type sampledSpan struct {
+	sdktrace.ReadOnlySpan
+}
+
+func (s sampledSpan) SpanContext() trace.SpanContext {
+	sc := s.ReadOnlySpan.SpanContext()
+	return sc.WithTraceFlags(sc.TraceFlags().WithSampled(true))
+}
@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newSampledTracer returns a tracer sampled as sampling would and the
+// recorder of the spans it exports.
+func newSampledTracer(t *testing.T, sampling Sampling) (trace.Tracer, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(newTailSampler(recorder, sampling)),
+		sdktrace.WithSampler(sampling.headSampler()),
+	)
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+	return provider.Tracer("test"), recorder
+}
+
+// request records a server span for route with a child span, failing the
+// child if failed, and returns the root's span context.
+func request(ctx context.Context, tracer trace.Tracer, route string, took time.Duration, failed bool) trace.SpanContext {
+	start := time.Now()
+	ctx, root := tracer.Start(ctx, "GET "+route, trace.WithTimestamp(start), trace.WithAttributes(semconv.HTTPRoute(route)))
+	_, child := tracer.Start(ctx, "backend.tts")
+	if failed {
+		child.SetStatus(codes.Error, "backend error")
+	}
+	child.End()
+	root.End(trace.WithTimestamp(start.Add(took)))
+	return root.SpanContext()
+}
+
+func TestTailSampler_KeepsErrorsAndSlowRequests(t *testing.T) {
+	tracer, recorder := newSampledTracer(t, Sampling{Ratio: 0, SlowThreshold: time.Second})
+
+	request(context.Background(), tracer, "/v1/tts", time.Millisecond, false)
+	assert.Empty(t, recorder.Ended(), "ratio 0 drops a fast, successful request")
+
+	failed := request(context.Background(), tracer, "/v1/tts", time.Millisecond, true)
+	spans := recorder.Ended()
+	require.Len(t, spans, 2, "the whole trace of a failed request is kept")
+	for _, span := range spans {
+		assert.Equal(t, failed.TraceID(), span.SpanContext().TraceID())
+		assert.True(t, span.SpanContext().IsSampled(), "exporters only take sampled spans")
+	}
+
+	request(context.Background(), tracer, "/v1/tts", 2*time.Second, false)
+	assert.Len(t, recorder.Ended(), 4, "a slow request is kept")
+}
+
+func TestTailSampler_RouteRatios(t *testing.T) {
+	tracer, recorder := newSampledTracer(t, Sampling{Ratio: 1, RouteRatios: map[string]float64{"/v1/health": 0}})
+	request(context.Background(), tracer, "/v1/health", time.Millisecond, false)
+	assert.Empty(t, recorder.Ended())
+	request(context.Background(), tracer, "/v1/tts", time.Millisecond, false)
+	assert.Len(t, recorder.Ended(), 2)
+
+	tracer, recorder = newSampledTracer(t, Sampling{Ratio: 0, RouteRatios: map[string]float64{"/v1/tts": 1}})
+	request(context.Background(), tracer, "/v1/tts", time.Millisecond, false)
+	assert.Len(t, recorder.Ended(), 2)
+}
+
+func TestTailSampler_RemoteParent(t *testing.T) {
+	parent := func(sampled bool) context.Context {
+		var flags trace.TraceFlags
+		if sampled {
+			flags = trace.FlagsSampled
+		}
+		return trace.ContextWithRemoteSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{1},
+			SpanID:     trace.SpanID{1},
+			TraceFlags: flags,
+			Remote:     true,
+		}))
+	}
+
+	tracer, recorder := newSampledTracer(t, Sampling{Ratio: 0})
+	sc := request(parent(true), tracer, "/v1/tts", time.Millisecond, false)
+	assert.True(t, sc.IsSampled(), "the backend is asked to record the client's trace")
+	assert.Len(t, recorder.Ended(), 2, "a client's sampled trace is kept whatever the ratio")
+
+	tracer, recorder = newSampledTracer(t, Sampling{Ratio: 1})
+	sc = request(parent(false), tracer, "/v1/tts", time.Millisecond, false)
+	assert.False(t, sc.IsSampled())
+	assert.Empty(t, recorder.Ended(), "the client chose not to record its trace")
+	request(parent(false), tracer, "/v1/tts", time.Millisecond, true)
+	assert.Len(t, recorder.Ended(), 2, "unless it fails")
+}
@@ -0,0 +1,59 @@
+// Package tracing sets up OpenTelemetry tracing of requests and backend calls.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+
+	"github.com/fish-speech-go/fish-speech-go/internal/config"
+)
+
+// Setup installs the global tracer provider, which exports spans to the
+// configured OTLP/HTTP collector, and the W3C trace context propagator. The
+// returned shutdown flushes the spans not yet exported. With tracing disabled
+// nothing is installed and shutdown does nothing.
+func Setup(ctx context.Context, cfg config.TracingConfig, version string) (shutdown func(context.Context) error, err error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var opts []otlptracehttp.Option
+	if cfg.Endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	}
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.ServiceVersion(version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe service: %w", err)
+	}
+
+	sampling := Sampling{Ratio: cfg.SampleRatio, RouteRatios: cfg.RouteRatios, SlowThreshold: cfg.SlowThreshold}
+	provider := sdktrace.NewTracerProvider(
+		// Requests that arrive with a sampled traceparent are exported
+		// whatever the ratio, so a client's trace is never cut short;
+		// failed and slow requests are exported whatever the client said.
+		sdktrace.WithSpanProcessor(newTailSampler(sdktrace.NewBatchSpanProcessor(exporter), sampling)),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sampling.headSampler()),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
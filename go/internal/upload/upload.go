@@ -0,0 +1,265 @@
+// Package upload stores resumable, chunked uploads on disk until they are
+// complete. Each session is kept as a data file and a JSON metadata file, so
+// uploads survive server restarts when the directory is persistent.
+package upload
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound indicates the session does not exist or has expired.
+	ErrNotFound = errors.New("upload not found")
+	// ErrOffsetMismatch indicates a chunk does not start at the session offset.
+	ErrOffsetMismatch = errors.New("upload offset mismatch")
+	// ErrTooLarge indicates a chunk extends past the declared length.
+	ErrTooLarge = errors.New("upload exceeds declared length")
+	// ErrIncomplete indicates the session has not received every byte.
+	ErrIncomplete = errors.New("upload incomplete")
+	// ErrBusy indicates another chunk is being written to the session.
+	ErrBusy = errors.New("upload busy")
+)
+
+// Session describes an upload in progress.
+type Session struct {
+	ID string `json:"id"`
+	// Namespace is the namespace of the caller that created the session; only
+	// callers in the same namespace can see it.
+	Namespace   string    `json:"namespace,omitempty"`
+	ReferenceID string    `json:"reference_id"`
+	Text        string    `json:"text"`
+	Length      int64     `json:"length"`
+	Offset      int64     `json:"offset"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type entry struct {
+	Session
+	busy bool
+}
+
+// Store keeps upload sessions in a directory. It is safe for concurrent use.
+// Sessions expire ttl after they were last written to.
+type Store struct {
+	dir string
+	ttl time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*entry
+}
+
+// Open returns a Store in dir, creating it if needed and resuming the sessions
+// already in it.
+func Open(dir string, ttl time.Duration) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+	s := &Store{dir: dir, ttl: ttl, sessions: make(map[string]*entry)}
+
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		raw, err := os.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read upload session: %w", err)
+		}
+		var sess Session
+		if err := json.Unmarshal(raw, &sess); err != nil || sess.ID != strings.TrimSuffix(filepath.Base(name), ".json") {
+			continue
+		}
+		// The data file is authoritative: a crash may leave the metadata
+		// behind the bytes actually written.
+		if info, err := os.Stat(s.dataPath(sess.ID)); err == nil {
+			sess.Offset = min(info.Size(), sess.Length)
+		}
+		s.sessions[sess.ID] = &entry{Session: sess}
+	}
+	s.mu.Lock()
+	s.sweepLocked(time.Now())
+	s.mu.Unlock()
+	return s, nil
+}
+
+// Create starts a session for sess.Length bytes. The ID, offset and expiry are
+// assigned by the store.
+func (s *Store) Create(sess Session) (Session, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return Session{}, err
+	}
+	sess.ID = hex.EncodeToString(id)
+	sess.Offset = 0
+	sess.ExpiresAt = time.Now().Add(s.ttl)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweepLocked(time.Now())
+
+	f, err := os.OpenFile(s.dataPath(sess.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return Session{}, fmt.Errorf("failed to create upload: %w", err)
+	}
+	f.Close()
+	if err := s.saveLocked(sess); err != nil {
+		os.Remove(s.dataPath(sess.ID))
+		return Session{}, err
+	}
+	s.sessions[sess.ID] = &entry{Session: sess}
+	return sess, nil
+}
+
+// Get returns the session with id.
+func (s *Store) Get(id string) (Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.sessions[id]
+	if !ok || time.Now().After(e.ExpiresAt) {
+		return Session{}, ErrNotFound
+	}
+	return e.Session, nil
+}
+
+// Append writes the chunk read from r at offset, which must be the session's
+// current offset. Bytes received before r fails are kept, so the client can
+// resume from the returned offset.
+func (s *Store) Append(id string, offset int64, r io.Reader) (Session, error) {
+	s.mu.Lock()
+	e, ok := s.sessions[id]
+	switch {
+	case !ok || time.Now().After(e.ExpiresAt):
+		s.mu.Unlock()
+		return Session{}, ErrNotFound
+	case e.busy:
+		s.mu.Unlock()
+		return e.Session, ErrBusy
+	case offset != e.Offset:
+		s.mu.Unlock()
+		return e.Session, ErrOffsetMismatch
+	}
+	e.busy = true
+	remaining := e.Length - e.Offset
+	s.mu.Unlock()
+
+	n, err := s.write(id, r, remaining)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.busy = false
+	e.Offset += n
+	e.ExpiresAt = time.Now().Add(s.ttl)
+	if saveErr := s.saveLocked(e.Session); err == nil {
+		err = saveErr
+	}
+	return e.Session, err
+}
+
+// write appends at most limit bytes from r to the data file of id.
+func (s *Store) write(id string, r io.Reader, limit int64) (int64, error) {
+	f, err := os.OpenFile(s.dataPath(id), os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open upload: %w", err)
+	}
+	n, err := io.Copy(f, io.LimitReader(r, limit))
+	if syncErr := f.Sync(); err == nil {
+		err = syncErr
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return n, err
+	}
+
+	var extra [1]byte
+	if m, _ := r.Read(extra[:]); m > 0 {
+		return n, ErrTooLarge
+	}
+	return n, nil
+}
+
+// Data returns the bytes of a complete session.
+func (s *Store) Data(id string) ([]byte, Session, error) {
+	sess, err := s.Get(id)
+	if err != nil {
+		return nil, sess, err
+	}
+	if sess.Offset < sess.Length {
+		return nil, sess, ErrIncomplete
+	}
+	data, err := os.ReadFile(s.dataPath(id))
+	if err != nil {
+		return nil, sess, fmt.Errorf("failed to read upload: %w", err)
+	}
+	return data[:sess.Length], sess, nil
+}
+
+// Delete removes the session and its data.
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.sessions[id]; !ok {
+		return ErrNotFound
+	}
+	s.removeLocked(id)
+	return nil
+}
+
+// sweepLocked removes expired sessions. Callers must hold s.mu.
+func (s *Store) sweepLocked(now time.Time) {
+	for id, e := range s.sessions {
+		if !e.busy && now.After(e.ExpiresAt) {
+			s.removeLocked(id)
+		}
+	}
+}
+
+func (s *Store) removeLocked(id string) {
+	delete(s.sessions, id)
+	os.Remove(s.dataPath(id))
+	os.Remove(s.metaPath(id))
+}
+
+// saveLocked persists the session metadata atomically. Callers must hold s.mu.
+func (s *Store) saveLocked(sess Session) error {
+	raw, err := json.Marshal(sess)
+	if err != nil {
+		return fmt.Errorf("failed to encode upload session: %w", err)
+	}
+	tmp, err := os.CreateTemp(s.dir, ".session-*")
+	if err != nil {
+		return fmt.Errorf("failed to write upload session: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write upload session: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write upload session: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.metaPath(sess.ID)); err != nil {
+		return fmt.Errorf("failed to write upload session: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) dataPath(id string) string {
+	return filepath.Join(s.dir, id+".part")
+}
+
+func (s *Store) metaPath(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
@@ -0,0 +1,122 @@
+package upload
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_ChunkedUpload(t *testing.T) {
+	s, err := Open(t.TempDir(), time.Hour)
+	require.NoError(t, err)
+
+	sess, err := s.Create(Session{ReferenceID: "voice", Text: "hello", Length: 10})
+	require.NoError(t, err)
+	assert.Len(t, sess.ID, 32)
+
+	sess, err = s.Append(sess.ID, 0, strings.NewReader("01234"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), sess.Offset)
+
+	_, err = s.Append(sess.ID, 0, strings.NewReader("01234"))
+	assert.ErrorIs(t, err, ErrOffsetMismatch)
+
+	_, _, err = s.Data(sess.ID)
+	assert.ErrorIs(t, err, ErrIncomplete)
+
+	sess, err = s.Append(sess.ID, 5, strings.NewReader("56789"))
+	require.NoError(t, err)
+	data, _, err := s.Data(sess.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789", string(data))
+
+	require.NoError(t, s.Delete(sess.ID))
+	_, err = s.Get(sess.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+// failingReader returns data, then an error, like a dropped connection.
+type failingReader struct {
+	data string
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, errors.New("connection reset")
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestStore_InterruptedChunkKeepsReceivedBytes(t *testing.T) {
+	s, err := Open(t.TempDir(), time.Hour)
+	require.NoError(t, err)
+	sess, err := s.Create(Session{Length: 8})
+	require.NoError(t, err)
+
+	sess, err = s.Append(sess.ID, 0, &failingReader{data: "abc"})
+	assert.Error(t, err)
+	assert.Equal(t, int64(3), sess.Offset)
+
+	sess, err = s.Append(sess.ID, 3, strings.NewReader("defgh"))
+	require.NoError(t, err)
+	data, _, err := s.Data(sess.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "abcdefgh", string(data))
+}
+
+func TestStore_RejectsChunkPastLength(t *testing.T) {
+	s, err := Open(t.TempDir(), time.Hour)
+	require.NoError(t, err)
+	sess, err := s.Create(Session{Length: 3})
+	require.NoError(t, err)
+
+	sess, err = s.Append(sess.ID, 0, strings.NewReader("abcdef"))
+	assert.ErrorIs(t, err, ErrTooLarge)
+	assert.Equal(t, int64(3), sess.Offset)
+}
+
+func TestStore_ResumesAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, time.Hour)
+	require.NoError(t, err)
+	sess, err := s.Create(Session{Namespace: "acme", ReferenceID: "voice", Length: 6})
+	require.NoError(t, err)
+	_, err = s.Append(sess.ID, 0, strings.NewReader("abc"))
+	require.NoError(t, err)
+
+	reopened, err := Open(dir, time.Hour)
+	require.NoError(t, err)
+	got, err := reopened.Get(sess.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), got.Offset)
+	assert.Equal(t, "acme", got.Namespace)
+}
+
+func TestStore_Expiry(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, time.Millisecond)
+	require.NoError(t, err)
+	sess, err := s.Create(Session{Length: 1})
+	require.NoError(t, err)
+
+	time.Sleep(5 * time.Millisecond)
+	_, err = s.Get(sess.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = s.Append(sess.ID, 0, io.LimitReader(strings.NewReader("a"), 1))
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Expired sessions are removed from disk when the next one is created.
+	_, err = s.Create(Session{Length: 1})
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dir, sess.ID+".part"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,178 @@
+// Package usage meters the characters synthesized and audio produced per
+// account over calendar days and months in UTC.
+package usage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Totals is the usage counted in one period.
+type Totals struct {
+	Requests     int64   `json:"requests"`
+	Characters   int64   `json:"characters"`
+	AudioSeconds float64 `json:"audio_seconds"`
+}
+
+// Period is the usage counted since Start, the beginning of the current day
+// or month.
+type Period struct {
+	Start time.Time `json:"start"`
+	Totals
+}
+
+// Account is one account's usage in the current UTC day and month.
+type Account struct {
+	Name  string `json:"account"`
+	Day   Period `json:"day"`
+	Month Period `json:"month"`
+}
+
+// DayStart returns the start of the UTC day containing t.
+func DayStart(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// MonthStart returns the start of the UTC month containing t.
+func MonthStart(t time.Time) time.Time {
+	y, m, _ := t.UTC().Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Meter counts usage per account. It is safe for concurrent use and optionally
+// persisted to a JSON file so that counts survive restarts.
+type Meter struct {
+	mu       sync.Mutex
+	path     string
+	accounts map[string]*Account
+}
+
+// New returns an in-memory Meter.
+func New() *Meter {
+	return &Meter{accounts: make(map[string]*Account)}
+}
+
+// Open loads a Meter persisted at path, creating it on first write. An empty
+// path returns an in-memory Meter.
+func Open(path string) (*Meter, error) {
+	m := New()
+	if path == "" {
+		return m, nil
+	}
+	m.path = path
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage file: %w", err)
+	}
+	if err := json.Unmarshal(raw, &m.accounts); err != nil {
+		return nil, fmt.Errorf("failed to parse usage file: %w", err)
+	}
+	if m.accounts == nil {
+		m.accounts = make(map[string]*Account)
+	}
+	for name, a := range m.accounts {
+		a.Name = name
+	}
+	return m, nil
+}
+
+// Record adds a request for chars characters and seconds of audio to the
+// account's usage at now. The usage is counted even when it cannot be
+// persisted; the error reports the failed write.
+func (m *Meter) Record(account string, chars int64, seconds float64, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, ok := m.accounts[account]
+	if !ok {
+		a = &Account{Name: account}
+		m.accounts[account] = a
+	}
+	a.roll(now)
+	for _, p := range []*Period{&a.Day, &a.Month} {
+		p.Requests++
+		p.Characters += chars
+		p.AudioSeconds += seconds
+	}
+	return m.saveLocked()
+}
+
+// Usage returns the account's usage for the day and month containing now.
+func (m *Meter) Usage(account string, now time.Time) Account {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a := Account{Name: account}
+	if stored, ok := m.accounts[account]; ok {
+		a = *stored
+	}
+	a.roll(now)
+	return a
+}
+
+// All returns the usage of every account for the day and month containing
+// now, sorted by name.
+func (m *Meter) All(now time.Time) []Account {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	accounts := make([]Account, 0, len(m.accounts))
+	for _, stored := range m.accounts {
+		a := *stored
+		a.roll(now)
+		accounts = append(accounts, a)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Name < accounts[j].Name })
+	return accounts
+}
+
+// roll starts new periods when now is past the current day or month.
+func (a *Account) roll(now time.Time) {
+	if day := DayStart(now); !a.Day.Start.Equal(day) {
+		a.Day = Period{Start: day}
+	}
+	if month := MonthStart(now); !a.Month.Start.Equal(month) {
+		a.Month = Period{Start: month}
+	}
+}
+
+// saveLocked persists the meter atomically. Callers must hold m.mu.
+func (m *Meter) saveLocked() error {
+	if m.path == "" {
+		return nil
+	}
+
+	raw, err := json.MarshalIndent(m.accounts, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode usage: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(m.path), ".usage-*")
+	if err != nil {
+		return fmt.Errorf("failed to write usage file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write usage file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write usage file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), m.path); err != nil {
+		return fmt.Errorf("failed to write usage file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package usage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecord_RollsPeriods(t *testing.T) {
+	m := New()
+	now := time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC)
+
+	require.NoError(t, m.Record("alice", 100, 5, now))
+	require.NoError(t, m.Record("alice", 50, 2.5, now.Add(30*time.Minute)))
+
+	a := m.Usage("alice", now)
+	assert.Equal(t, Totals{Requests: 2, Characters: 150, AudioSeconds: 7.5}, a.Day.Totals)
+	assert.Equal(t, Totals{Requests: 2, Characters: 150, AudioSeconds: 7.5}, a.Month.Totals)
+	assert.Equal(t, time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC), a.Day.Start)
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), a.Month.Start)
+
+	// The next day starts a new day and, on the 1st, a new month.
+	a = m.Usage("alice", now.Add(2*time.Hour))
+	assert.Zero(t, a.Day.Totals)
+	assert.Zero(t, a.Month.Totals)
+
+	require.NoError(t, m.Record("alice", 10, 1, now.Add(2*time.Hour)))
+	a = m.Usage("alice", now.Add(25*time.Hour))
+	assert.Zero(t, a.Day.Totals)
+	assert.Equal(t, Totals{Requests: 1, Characters: 10, AudioSeconds: 1}, a.Month.Totals)
+
+	assert.Equal(t, Account{Name: "bob", Day: Period{Start: DayStart(now)}, Month: Period{Start: MonthStart(now)}}, m.Usage("bob", now))
+}
+
+func TestOpen_Persists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	now := time.Date(2026, 5, 12, 9, 0, 0, 0, time.UTC)
+
+	m, err := Open(path)
+	require.NoError(t, err)
+	require.NoError(t, m.Record("bob", 20, 1.5, now))
+	require.NoError(t, m.Record("alice", 10, 1, now))
+
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	accounts := reopened.All(now)
+	require.Len(t, accounts, 2)
+	assert.Equal(t, "alice", accounts[0].Name)
+	assert.Equal(t, Totals{Requests: 1, Characters: 20, AudioSeconds: 1.5}, accounts[1].Month.Totals)
+}